@@ -5,11 +5,21 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"proxmox-dashboard/internal/alerting"
+	"proxmox-dashboard/internal/auth"
 	"proxmox-dashboard/internal/config"
+	"proxmox-dashboard/internal/email"
+	"proxmox-dashboard/internal/exporter"
 	"proxmox-dashboard/internal/handlers"
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/notify"
 	"proxmox-dashboard/internal/routes"
+	"proxmox-dashboard/internal/secrets"
 	"proxmox-dashboard/internal/seeders"
+	"proxmox-dashboard/internal/services"
 	"proxmox-dashboard/internal/sse"
 	"proxmox-dashboard/internal/store"
 
@@ -30,6 +40,19 @@ func main() {
 	// Créer le store
 	store := store.NewStore(db)
 
+	// Configurer le chiffrement des secrets (connexions Proxmox, webhooks, Prometheus) avec une clé dédiée
+	if cfg.Security.EncryptionKey != "" && cfg.Security.EncryptionKey == cfg.Security.JWTSecret {
+		log.Fatal("ENCRYPTION_KEY must differ from JWT_SECRET")
+	}
+	if cipher, err := secrets.NewCipher(cfg.Security.EncryptionKey); err == nil {
+		store.SetCipher(cipher)
+	} else if cfg.Environment != "dev" {
+		log.Fatal("ENCRYPTION_KEY is required to store Proxmox, webhook and Prometheus secrets")
+	} else {
+		// En développement, le serveur démarre mais refuse d'enregistrer des secrets (store.ErrNoCipher)
+		log.Printf("⚠️  ENCRYPTION_KEY absente (%v): aucun secret ne pourra être enregistré", err)
+	}
+
 	// Exécuter les migrations
 	if err := store.Migrate(); err != nil {
 		log.Fatal("Failed to run migrations:", err)
@@ -45,18 +68,173 @@ func main() {
 		log.Println("🚀 Mode production: aucune donnée de test ne sera chargée")
 	}
 
+	// Service d'authentification et compte administrateur initial
+	authService := auth.NewService(db)
+	if admin, err := authService.BootstrapAdmin(cfg.Security.Admin.Username, cfg.Security.Admin.Email, cfg.Security.Admin.Password); err != nil {
+		log.Printf("⚠️  Compte administrateur initial non créé: %v", err)
+	} else if admin != nil {
+		log.Printf("👤 Compte administrateur initial %q créé", admin.Username)
+	}
+	if err := authService.CleanExpiredSessions(); err != nil {
+		log.Printf("⚠️  Failed to clean expired sessions: %v", err)
+	}
+
 	// Créer le hub SSE
 	hub := sse.NewHub()
 	hub.Start()
 
 	// Créer les handlers
+	authHandlers := handlers.NewAuthHandlers(authService)
 	handlers := handlers.NewHandlers(store)
+	handlers.SetHub(hub)
+
+	// Worker d'envoi des emails (file email_queue)
+	emailWorker := email.NewWorker(store, cfg.SMTP)
+	handlers.SetEmailWorker(emailWorker)
+	if cfg.SMTP.Host != "" {
+		emailWorker.Start()
+		defer emailWorker.Stop()
+	} else {
+		log.Println("⚠️  SMTP_HOST non défini: les emails restent en file d'attente")
+	}
+
+	// Livraison des alertes aux abonnements webhook, chat et email (file webhook_deliveries)
+	notifier := notify.NewDispatcher(store, cfg.Webhook)
+	notifier.Register(models.ChannelEmail, notify.NewEmailNotifier(emailWorker))
+	notifier.SetPublicURL(cfg.Server.PublicURL)
+	notifier.Start()
+	defer notifier.Stop()
+	handlers.SetNotifier(notifier)
+	handlers.SetAlertmanagerToken(cfg.Security.AlertmanagerToken)
+	// Hôtes Proxmox fictifs des environnements de développement (fetch-data ne les contacte pas)
+	handlers.SetDevProxmoxHosts(cfg.Proxmox.DevHosts)
+
+	// Requêtes Prometheus : source de données initiale depuis PROMETHEUS_URL, réponses simulées en développement
+	handlers.SetPrometheusMock(cfg.Prometheus.Mock)
+	if cfg.Prometheus.Mock {
+		log.Println("⚠️  PROMETHEUS_MOCK activé: les requêtes Prometheus retournent des données simulées")
+	}
+	if cfg.Prometheus.URL != "" {
+		if err := bootstrapPrometheusDataSource(store, cfg.Prometheus.URL); err != nil {
+			log.Printf("⚠️  Source de données Prometheus initiale non créée: %v", err)
+		}
+	}
+
+	// Historique des métriques, alimenté par le poller
+	metrics := services.NewMetricsService(store)
+	handlers.SetMetrics(metrics)
+
+	// Exporter Prometheus (/metrics), alimenté par le poller et les health checks
+	metricsExporter := exporter.NewExporter(store)
+	handlers.SetExporter(metricsExporter)
+	handlers.SetMetricsToken(cfg.Security.MetricsToken)
+
+	// Diffusion aux clients SSE et notification des alertes déclenchées ou résolues en arrière-plan
+	publishAlert := func(alert *models.Alert) {
+		if alert.Status == models.AlertStatusResolved {
+			hub.BroadcastAlertUpdate(alert)
+		} else {
+			hub.BroadcastAlert(alert)
+		}
+		if err := notifier.NotifyAlert(alert); err != nil {
+			log.Printf("⚠️  Failed to queue notifications for alert %d: %v", alert.ID, err)
+		}
+	}
+
+	// Health checks planifiés des applications (historique, uptime et alertes d'indisponibilité)
+	if cfg.HealthCheck.Enabled {
+		healthScheduler := services.NewHealthScheduler(store, cfg.HealthCheck.Interval)
+		healthScheduler.SetFailureThreshold(cfg.HealthCheck.FailureThreshold)
+		healthScheduler.OnResult(func(app *models.App, status *models.HealthStatus) {
+			metricsExporter.ObserveAppHealth(status)
+		})
+		healthScheduler.OnAlert(publishAlert)
+		healthScheduler.Start()
+		defer healthScheduler.Stop()
+		handlers.SetHealthScheduler(healthScheduler)
+	} else {
+		log.Println("⚠️  Health checks planifiés désactivés: l'état des applications n'est vérifié qu'à la demande")
+	}
+
+	// Provisionnement depuis les templates (jobs exécutés en arrière-plan)
+	provisioner := services.NewProvisioner(store)
+	provisioner.RecoverInterrupted()
+	defer provisioner.Stop()
+	handlers.SetProvisioner(provisioner)
+
+	// Politique de conformité des sauvegardes : âge maximal par défaut, par tag ou par pool
+	backupPolicy, err := models.ParseBackupPolicy(cfg.Backup.MaxAge, cfg.Backup.Policies)
+	if err != nil {
+		log.Fatalf("❌ BACKUP_POLICIES invalide: %v", err)
+	}
+	handlers.SetBackupPolicy(backupPolicy)
+
+	// Démarrer le poller d'inventaire Proxmox
+	if cfg.Poller.Enabled {
+		poller := inventory.NewPoller(store, cfg.Poller.Interval)
+		poller.SetContentSchedule(cfg.Poller.ContentInterval, cfg.Poller.ContentConcurrency)
+		metricsExporter.SetSnapshotSource(poller.Snapshot)
+		poller.OnSnapshot(metricsExporter.ObserveSnapshot)
+		// Diffuser les changements d'inventaire aux clients SSE
+		poller.OnSnapshot(func(prev, next *models.ProxmoxSnapshot) {
+			for _, event := range inventory.Diff(prev, next, cfg.Poller.StorageThreshold) {
+				hub.Broadcast(event)
+			}
+		})
+		poller.OnSnapshot(func(prev, next *models.ProxmoxSnapshot) {
+			if err := metrics.Ingest(next); err != nil {
+				log.Printf("⚠️  Failed to record metrics: %v", err)
+			}
+		})
+		// Règles d'alerte évaluées sur chaque snapshot
+		engine := alerting.NewEngine(store)
+		engine.OnAlert(publishAlert)
+		poller.OnSnapshot(func(prev, next *models.ProxmoxSnapshot) {
+			engine.Evaluate(next)
+		})
+		// Sauvegardes vzdump terminées : un échec déclenche une alerte, la réussite suivante la résout
+		backupMonitor := services.NewBackupMonitor(store)
+		backupMonitor.OnAlert(publishAlert)
+		poller.OnSnapshot(backupMonitor.Observe)
+		// Rapport quotidien de conformité des sauvegardes (BACKUP_REPORT_EMAILS)
+		if len(cfg.Backup.ReportEmails) > 0 {
+			backupReporter := services.NewBackupReporter(backupPolicy, poller.Snapshot, emailWorker, cfg.Backup.ReportEmails, cfg.Backup.ReportHour)
+			backupReporter.SetPublicURL(cfg.Server.PublicURL)
+			backupReporter.Start()
+			defer backupReporter.Stop()
+		}
+		poller.Start()
+		defer poller.Stop()
+		handlers.SetPoller(poller)
+	} else {
+		log.Println("⚠️  Poller d'inventaire Proxmox désactivé: les endpoints interrogeront Proxmox à chaque requête")
+	}
 
 	// Configuration du routeur
-	r := routes.SetupRoutes(handlers, hub)
+	r := routes.SetupRoutes(handlers, authHandlers, authService, store, hub)
 
 	// Démarrer le serveur
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 	fmt.Printf("Server starting on %s\n", addr)
 	log.Fatal(http.ListenAndServe(addr, r))
 }
+
+// bootstrapPrometheusDataSource crée la source de données Prometheus par défaut si aucune n'existe
+func bootstrapPrometheusDataSource(store *store.Store, url string) error {
+	sources, err := store.GetPrometheusDataSources()
+	if err != nil || len(sources) > 0 {
+		return err
+	}
+
+	now := time.Now()
+	ds := &models.PrometheusDataSource{Name: "Prometheus", AuthType: models.PrometheusAuthNone, IsDefault: true, CreatedAt: now, UpdatedAt: now}
+	(&models.PrometheusDataSourceRequest{URL: &url}).Apply(ds)
+	if err := ds.Validate(); err != nil {
+		return err
+	}
+	if err := store.CreatePrometheusDataSource(ds); err != nil {
+		return err
+	}
+	log.Printf("📈 Source de données Prometheus %q créée (%s)", ds.Name, ds.URL)
+	return nil
+}
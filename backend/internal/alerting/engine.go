@@ -0,0 +1,418 @@
+package alerting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+)
+
+// AlertListener est appelé pour chaque alerte déclenchée ou résolue par le moteur (voir Alert.Status)
+type AlertListener func(alert *models.Alert)
+
+// Engine évalue les règles d'alerte sur chaque snapshot de l'inventaire.
+// Une condition vraie est d'abord en attente ; l'alerte est créée quand elle reste vraie pendant
+// la durée For de la règle, puis résolue dès qu'elle redevient fausse. Le fingerprint (règle + objet)
+// garantit une seule alerte ouverte par condition. Les conditions en attente sont conservées en mémoire.
+type Engine struct {
+	store     *store.Store
+	listeners []AlertListener
+
+	mu      sync.Mutex
+	pending map[string]time.Time // fingerprint -> début de la condition
+}
+
+// NewEngine crée un moteur de règles d'alerte
+func NewEngine(store *store.Store) *Engine {
+	return &Engine{store: store, pending: map[string]time.Time{}}
+}
+
+// OnAlert enregistre un listener appelé pour les alertes déclenchées et résolues (à appeler avant Evaluate)
+func (e *Engine) OnAlert(listener AlertListener) {
+	e.listeners = append(e.listeners, listener)
+}
+
+// Target est un objet de l'inventaire évalué par une règle
+type Target struct {
+	Kind    string   `json:"kind"` // node|qemu|lxc|storage|cluster
+	Cluster string   `json:"cluster,omitempty"`
+	ID      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	Node    string   `json:"node,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Value   float64  `json:"value"`
+}
+
+// key identifie l'objet indépendamment de ses mesures
+func (t Target) key() string {
+	return t.Kind + "/" + t.Cluster + "/" + t.ID
+}
+
+// label nomme l'objet dans les alertes (ex: "VM web-01 (100)")
+func (t Target) label() string {
+	prefix := map[string]string{"node": "Nœud", "qemu": "VM", "lxc": "Conteneur", "storage": "Storage", "cluster": "Cluster"}[t.Kind]
+	if t.Name != "" && t.Name != t.ID {
+		return fmt.Sprintf("%s %s (%s)", prefix, t.Name, t.ID)
+	}
+	return fmt.Sprintf("%s %s", prefix, t.ID)
+}
+
+// labels retourne les labels de l'alerte d'une règle sur l'objet, utilisables par les silences
+func (t Target) labels(rule *models.AlertRule) map[string]string {
+	labels := map[string]string{"alertname": rule.Name, "metric": rule.Metric, "kind": t.Kind, "id": t.ID}
+	for name, value := range map[string]string{"cluster": t.Cluster, "node": t.Node, "name": t.Name} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// rulePayload est le payload JSON des alertes créées par les règles
+type rulePayload struct {
+	RuleID     int     `json:"rule_id"`
+	Metric     string  `json:"metric"`
+	Comparator string  `json:"comparator"`
+	Threshold  float64 `json:"threshold"`
+	Target     Target  `json:"target"`
+	Since      string  `json:"since"`
+}
+
+// Fingerprint calcule l'empreinte stable d'une condition (règle + objet)
+func Fingerprint(ruleID int, target Target) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("rule:%d|%s", ruleID, target.key())))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Evaluate évalue les règles actives sur un snapshot, crée les alertes dont la condition dure
+// depuis For et résout celles dont la condition a cessé
+func (e *Engine) Evaluate(snapshot *models.ProxmoxSnapshot) {
+	rules, err := e.store.GetAlertRules(true)
+	if err != nil {
+		log.Printf("⚠️  Failed to load alert rules: %v", err)
+		return
+	}
+	open, err := e.store.GetOpenRuleAlerts()
+	if err != nil {
+		log.Printf("⚠️  Failed to load open alerts: %v", err)
+		return
+	}
+
+	now := snapshot.CollectedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	openByFingerprint := map[string]*models.Alert{}
+	for _, alert := range open {
+		openByFingerprint[alert.Fingerprint] = alert
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	evaluated := map[string]bool{} // conditions évaluées sur ce snapshot
+	active := map[string]bool{}    // conditions vraies
+	enabledRules := map[int]bool{}
+	for _, rule := range rules {
+		enabledRules[rule.ID] = true
+		for _, target := range Targets(rule, snapshot) {
+			fingerprint := Fingerprint(rule.ID, target)
+			evaluated[fingerprint] = true
+			if !rule.Matches(target.Value) {
+				continue
+			}
+			active[fingerprint] = true
+			if openByFingerprint[fingerprint] != nil {
+				continue
+			}
+
+			since, ok := e.pending[fingerprint]
+			if !ok {
+				since = now
+				e.pending[fingerprint] = now
+			}
+			if now.Sub(since) < time.Duration(rule.For) {
+				continue
+			}
+			e.fire(rule, target, fingerprint, since, now)
+		}
+	}
+
+	// Les conditions en attente qui ont cessé repartent de zéro
+	for fingerprint := range e.pending {
+		if !active[fingerprint] {
+			delete(e.pending, fingerprint)
+		}
+	}
+
+	for fingerprint, alert := range openByFingerprint {
+		if active[fingerprint] || !e.shouldResolve(alert, fingerprint, evaluated, enabledRules, snapshot) {
+			continue
+		}
+		if err := e.store.ResolveAlert(alert, "", now); err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		log.Printf("✅ Alerte %d résolue: %s", alert.ID, alert.Title)
+		e.notify(alert)
+	}
+}
+
+// shouldResolve indique si une alerte ouverte dont la condition n'est plus vraie peut être résolue :
+// la condition a été évaluée à faux, la règle a été désactivée, ou l'objet est connu comme supprimé.
+// Un objet absent faute de données (cluster injoignable, nœud illisible, nœud hors ligne) garde son alerte ouverte.
+func (e *Engine) shouldResolve(alert *models.Alert, fingerprint string, evaluated map[string]bool, enabledRules map[int]bool, snapshot *models.ProxmoxSnapshot) bool {
+	if evaluated[fingerprint] || alert.RuleID == nil || !enabledRules[*alert.RuleID] {
+		return true
+	}
+	var payload rulePayload
+	if alert.Payload == nil || json.Unmarshal([]byte(*alert.Payload), &payload) != nil {
+		return true
+	}
+	return knownDeleted(payload.Target, snapshot)
+}
+
+// knownDeleted indique si un objet non évalué a réellement disparu de l'inventaire, ou en est sorti
+// de la portée de la règle, plutôt que d'en être absent parce que sa collecte a échoué.
+// Un cluster retiré des connexions est considéré comme supprimé.
+func knownDeleted(target Target, snapshot *models.ProxmoxSnapshot) bool {
+	idx := slices.IndexFunc(snapshot.Clusters, func(c models.ProxmoxClusterStatus) bool { return c.Name == target.Cluster })
+	if idx < 0 {
+		return true
+	}
+	cluster := snapshot.Clusters[idx]
+	if !cluster.Success {
+		return false
+	}
+
+	switch target.Kind {
+	case "qemu", "lxc":
+		// Un invité dont les snapshots n'ont pas pu être lus n'est pas évalué par les règles guest.snapshot_age
+		if vmid, err := strconv.Atoi(target.ID); err == nil && cluster.SnapshotsFailed(target.Kind, vmid) {
+			return false
+		}
+		return !slices.Contains(cluster.FailedNodes(target.Kind), target.Node)
+	case "storage":
+		// Un storage partagé peut être rattaché à n'importe quel nœud : tous doivent avoir été lus
+		return len(cluster.FailedStorageNodes) == 0
+	case "node":
+		// Un nœud hors ligne ne remonte aucune mesure mais existe toujours
+		return !slices.ContainsFunc(snapshot.Nodes, func(n models.ProxmoxNode) bool {
+			return n.Cluster == target.Cluster && n.Name == target.ID && n.Status != "online"
+		})
+	}
+	return true
+}
+
+// fire crée l'alerte d'une condition vraie depuis since
+func (e *Engine) fire(rule *models.AlertRule, target Target, fingerprint string, since, now time.Time) {
+	data, _ := json.Marshal(rulePayload{
+		RuleID:     rule.ID,
+		Metric:     rule.Metric,
+		Comparator: rule.Comparator,
+		Threshold:  rule.Threshold,
+		Target:     target,
+		Since:      since.UTC().Format(time.RFC3339),
+	})
+	payload := string(data)
+	ruleID := rule.ID
+
+	message := fmt.Sprintf("%s = %s (%s %s)", rule.Metric, formatValue(target.Value), rule.Comparator, formatValue(rule.Threshold))
+	if rule.For > 0 {
+		message += fmt.Sprintf(" depuis %s", time.Duration(rule.For))
+	}
+	if target.Node != "" && target.Kind != "node" {
+		message += fmt.Sprintf(" sur le nœud %s", target.Node)
+	}
+	if target.Cluster != "" {
+		message += fmt.Sprintf(" (cluster %s)", target.Cluster)
+	}
+
+	alert := &models.Alert{
+		Source:      "rule:" + rule.Name,
+		Severity:    rule.Severity,
+		Title:       fmt.Sprintf("%s: %s", rule.Name, target.label()),
+		Message:     message,
+		Payload:     &payload,
+		Labels:      target.labels(rule),
+		CreatedAt:   now,
+		Status:      models.AlertStatusFiring,
+		Fingerprint: fingerprint,
+		RuleID:      &ruleID,
+	}
+	if err := e.store.CreateAlert(alert); err != nil {
+		log.Printf("⚠️  Failed to create alert for rule %q: %v", rule.Name, err)
+		return
+	}
+	delete(e.pending, fingerprint)
+	log.Printf("🚨 Alerte %d déclenchée: %s", alert.ID, alert.Title)
+	e.notify(alert)
+}
+
+// notify transmet une alerte déclenchée ou résolue aux listeners
+func (e *Engine) notify(alert *models.Alert) {
+	for _, listener := range e.listeners {
+		listener(alert)
+	}
+}
+
+// formatValue formate une mesure sans décimales inutiles
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Targets retourne les objets du snapshot évalués par une règle, avec la valeur de sa métrique
+func Targets(rule *models.AlertRule, snapshot *models.ProxmoxSnapshot) []Target {
+	var targets []Target
+	add := func(t Target) {
+		if inScope(rule.Scope, t) {
+			targets = append(targets, t)
+		}
+	}
+
+	switch models.RuleMetrics[rule.Metric] {
+	case "node":
+		for _, n := range snapshot.Nodes {
+			t := Target{Kind: "node", Cluster: n.Cluster, ID: n.Name, Name: n.Name, Node: n.Name}
+			switch rule.Metric {
+			case models.RuleMetricNodeCPU:
+				t.Value = n.CPUUsage
+			case models.RuleMetricNodeMemory:
+				t.Value = n.MemoryUsage
+			case models.RuleMetricNodeDisk:
+				t.Value = n.DiskUsage
+			case models.RuleMetricNodeOnline:
+				t.Value = boolValue(n.Status == "online")
+			}
+			// Un nœud hors ligne ne remonte aucune mesure : seules les règles node.online le concernent
+			if n.Status != "online" && rule.Metric != models.RuleMetricNodeOnline {
+				continue
+			}
+			add(t)
+		}
+	case "guest":
+		var oldestSnapshots map[string]time.Time
+		unreadable := map[string]bool{} // invités dont les snapshots n'ont pas pu être lus, non évalués
+		if rule.Metric == models.RuleMetricGuestSnapshots {
+			oldestSnapshots = oldestGuestSnapshots(snapshot.GuestSnapshots)
+			for _, c := range snapshot.Clusters {
+				for _, ref := range c.FailedSnapshotGuests {
+					unreadable[c.Name+"/"+ref] = true
+				}
+			}
+		}
+		for _, guests := range [][]models.ProxmoxGuest{snapshot.VMs, snapshot.LXC} {
+			for _, g := range guests {
+				if unreadable[guestKey(g.Cluster, g.Type, g.VMID)] {
+					continue
+				}
+				t := Target{Kind: g.Type, Cluster: g.Cluster, ID: strconv.Itoa(g.VMID), Name: g.Name, Node: g.Node,
+					Tags: splitTags(g.Tags)}
+				switch rule.Metric {
+				case models.RuleMetricGuestCPU:
+					t.Value = g.CPUUsage
+				case models.RuleMetricGuestMemory:
+					t.Value = g.MemoryUsage
+				case models.RuleMetricGuestDisk:
+					t.Value = g.DiskUsage
+				case models.RuleMetricGuestRunning:
+					t.Value = boolValue(g.Status == "running")
+				case models.RuleMetricGuestSnapshots:
+					if oldest, ok := oldestSnapshots[guestKey(g.Cluster, g.Type, g.VMID)]; ok {
+						t.Value = snapshotAgeDays(snapshot.CollectedAt, oldest)
+					}
+				}
+				add(t)
+			}
+		}
+	case "storage":
+		for _, st := range snapshot.Storages {
+			t := Target{Kind: "storage", Cluster: st.Cluster, ID: st.ID, Name: st.Name, Node: st.Node}
+			switch rule.Metric {
+			case models.RuleMetricStorageUsage:
+				t.Value = st.UsagePercent
+			case models.RuleMetricStorageActive:
+				t.Value = boolValue(st.Status == "online")
+			}
+			add(t)
+		}
+	case "cluster":
+		for _, c := range snapshot.Clusters {
+			add(Target{Kind: "cluster", Cluster: c.Name, ID: c.Name, Name: c.Name, Value: boolValue(c.Success)})
+		}
+	}
+	return targets
+}
+
+// guestKey identifie un invité dans l'inventaire multi-clusters
+func guestKey(cluster, guestType string, vmid int) string {
+	return cluster + "/" + guestType + "/" + strconv.Itoa(vmid)
+}
+
+// oldestGuestSnapshots retourne la date du plus ancien snapshot de chaque invité
+func oldestGuestSnapshots(snapshots []models.ProxmoxGuestSnapshot) map[string]time.Time {
+	oldest := map[string]time.Time{}
+	for _, s := range snapshots {
+		key := guestKey(s.Cluster, s.Type, s.VMID)
+		if current, ok := oldest[key]; !ok || s.CreatedAt.Before(current) {
+			oldest[key] = s.CreatedAt
+		}
+	}
+	return oldest
+}
+
+// snapshotAgeDays retourne l'âge d'un snapshot en jours (arrondi à 2 décimales) à la date de la collecte
+func snapshotAgeDays(collectedAt, created time.Time) float64 {
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+	days := collectedAt.Sub(created).Hours() / 24
+	return math.Round(max(days, 0)*100) / 100
+}
+
+// inScope indique si un objet entre dans la portée d'une règle
+func inScope(scope models.RuleScope, t Target) bool {
+	if scope.Cluster != "" && scope.Cluster != t.Cluster {
+		return false
+	}
+	if scope.Node != "" && scope.Node != t.Node {
+		return false
+	}
+	if scope.Type != "" && scope.Type != t.Kind {
+		return false
+	}
+	for _, tag := range scope.Tags {
+		if !slices.Contains(t.Tags, tag) {
+			return false
+		}
+	}
+	if len(scope.IDs) > 0 && !slices.Contains(scope.IDs, t.ID) && !slices.Contains(scope.IDs, t.Name) {
+		return false
+	}
+	return true
+}
+
+// splitTags découpe les tags Proxmox (séparés par ';', ',' ou des espaces)
+func splitTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// boolValue convertit un état en valeur de métrique (1 ou 0)
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
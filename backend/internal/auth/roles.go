@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// ErrUnknownRole est retourné quand un rôle n'existe pas dans la table roles
+var ErrUnknownRole = errors.New("rôle inconnu")
+
+// ErrRoleProtected est retourné lors d'une modification interdite d'un rôle intégré
+var ErrRoleProtected = errors.New("rôle intégré protégé")
+
+// ErrRoleInUse est retourné lors de la suppression d'un rôle encore attribué
+var ErrRoleInUse = errors.New("rôle attribué à des utilisateurs")
+
+// ListRoles liste les rôles intégrés puis personnalisés
+func (s *Service) ListRoles() ([]models.Role, error) {
+	rows, err := s.db.Query(`
+		SELECT name, description, permissions, built_in, created_at, updated_at
+		FROM roles ORDER BY built_in DESC, name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole récupère un rôle par nom
+func (s *Service) GetRole(name models.UserRole) (*models.Role, error) {
+	role, err := scanRole(s.db.QueryRow(`
+		SELECT name, description, permissions, built_in, created_at, updated_at
+		FROM roles WHERE name = ?
+	`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownRole
+	}
+	return role, err
+}
+
+// CreateRole crée un rôle personnalisé
+func (s *Service) CreateRole(role models.Role) (*models.Role, error) {
+	if err := role.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetRole(role.Name); err == nil {
+		return nil, fmt.Errorf("le rôle %s existe déjà", role.Name)
+	}
+
+	permissions, err := encodePermissions(role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO roles (name, description, permissions, built_in) VALUES (?, ?, ?, 0)
+	`, role.Name, role.Description, permissions)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la création du rôle: %w", err)
+	}
+
+	return s.GetRole(role.Name)
+}
+
+// UpdateRole met à jour la description et les permissions d'un rôle.
+// Les permissions du rôle admin ne sont pas modifiables.
+func (s *Service) UpdateRole(name models.UserRole, role models.Role) (*models.Role, error) {
+	existing, err := s.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	role.Name = existing.Name
+	if err := role.Validate(); err != nil {
+		return nil, err
+	}
+	if name == models.RoleAdmin {
+		return nil, ErrRoleProtected
+	}
+
+	permissions, err := encodePermissions(role.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(`
+		UPDATE roles SET description = ?, permissions = ?, updated_at = datetime('now') WHERE name = ?
+	`, role.Description, permissions, name)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la mise à jour du rôle: %w", err)
+	}
+
+	return s.GetRole(name)
+}
+
+// DeleteRole supprime un rôle personnalisé non attribué
+func (s *Service) DeleteRole(name models.UserRole) error {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrRoleProtected
+	}
+
+	var users int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = ?", name).Scan(&users); err != nil {
+		return err
+	}
+	if users > 0 {
+		return ErrRoleInUse
+	}
+
+	_, err = s.db.Exec("DELETE FROM roles WHERE name = ?", name)
+	return err
+}
+
+// loadPermissions renseigne les permissions de l'utilisateur depuis la table roles.
+// Un rôle supprimé ou inconnu ne donne aucune permission.
+func (s *Service) loadPermissions(user *models.User) error {
+	role, err := s.GetRole(user.Role)
+	if errors.Is(err, ErrUnknownRole) {
+		user.Permissions = []models.Permission{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user.Permissions = role.Permissions
+	return nil
+}
+
+// roleScanner est implémenté par *sql.Row et *sql.Rows
+type roleScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRole lit une ligne de la table roles
+func scanRole(row roleScanner) (*models.Role, error) {
+	var role models.Role
+	var permissions, createdAt, updatedAt string
+	if err := row.Scan(&role.Name, &role.Description, &permissions, &role.BuiltIn, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(permissions), &role.Permissions); err != nil {
+		return nil, fmt.Errorf("permissions invalides pour le rôle %s: %w", role.Name, err)
+	}
+	if role.Permissions == nil {
+		role.Permissions = []models.Permission{}
+	}
+
+	// Convertir les dates
+	role.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	role.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &role, nil
+}
+
+// encodePermissions sérialise des permissions en JSON
+func encodePermissions(permissions []models.Permission) (string, error) {
+	if permissions == nil {
+		permissions = []models.Permission{}
+	}
+	data, err := json.Marshal(permissions)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
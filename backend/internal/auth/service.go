@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"proxmox-dashboard/internal/models"
@@ -13,6 +15,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidPassword est retourné quand le mot de passe actuel ne correspond pas
+var ErrInvalidPassword = errors.New("mot de passe actuel incorrect")
+
+// ErrLastAdmin est retourné quand une opération retirerait le dernier administrateur actif
+var ErrLastAdmin = errors.New("impossible de retirer le dernier administrateur actif")
+
 // Service gère l'authentification
 type Service struct {
 	db *sql.DB
@@ -75,6 +83,9 @@ func (s *Service) Login(req models.LoginRequest, ipAddress, userAgent string) (*
 
 	// Masquer le mot de passe dans la réponse
 	user.Password = ""
+	if err := s.loadPermissions(user); err != nil {
+		return nil, fmt.Errorf("erreur lors du chargement des permissions")
+	}
 
 	return &models.LoginResponse{
 		Token:     token,
@@ -109,6 +120,11 @@ func (s *Service) ValidateToken(token string) (*models.User, error) {
 		return nil, fmt.Errorf("compte désactivé")
 	}
 
+	// Charger les permissions du rôle
+	if err := s.loadPermissions(user); err != nil {
+		return nil, fmt.Errorf("erreur lors du chargement des permissions")
+	}
+
 	// Masquer le mot de passe
 	user.Password = ""
 
@@ -127,9 +143,9 @@ func (s *Service) Logout(token string) error {
 
 // CreateUser crée un nouveau utilisateur
 func (s *Service) CreateUser(req models.CreateUserRequest) (*models.User, error) {
-	// Valider le rôle
-	if !req.Role.IsValid() {
-		return nil, fmt.Errorf("rôle invalide")
+	// Valider le rôle (intégré ou personnalisé)
+	if _, err := s.GetRole(req.Role); err != nil {
+		return nil, err
 	}
 
 	// Hasher le mot de passe
@@ -250,6 +266,169 @@ func (s *Service) ListUsers() ([]models.User, error) {
 	return users, rows.Err()
 }
 
+// UpdateUser met à jour les champs fournis d'un utilisateur.
+// Les sessions sont révoquées si le compte est désactivé ou si le mot de passe change.
+func (s *Service) UpdateUser(id int, req models.UpdateUserRequest) (*models.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	if req.Username != nil {
+		if *req.Username == "" {
+			return nil, fmt.Errorf("nom d'utilisateur requis")
+		}
+		sets = append(sets, "username = ?")
+		args = append(args, *req.Username)
+	}
+	if req.Email != nil {
+		if *req.Email == "" {
+			return nil, fmt.Errorf("email requis")
+		}
+		sets = append(sets, "email = ?")
+		args = append(args, *req.Email)
+	}
+	if req.Password != nil {
+		if *req.Password == "" {
+			return nil, fmt.Errorf("mot de passe requis")
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors du hashage du mot de passe")
+		}
+		sets = append(sets, "password_hash = ?")
+		args = append(args, string(hashedPassword))
+	}
+	if req.Role != nil {
+		if _, err := s.GetRole(*req.Role); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "role = ?")
+		args = append(args, *req.Role)
+	}
+	if req.Active != nil {
+		sets = append(sets, "active = ?")
+		args = append(args, *req.Active)
+	}
+	if len(sets) == 0 {
+		return user, nil
+	}
+
+	// Ne jamais retirer le dernier administrateur actif
+	demoted := (req.Role != nil && *req.Role != models.RoleAdmin) || (req.Active != nil && !*req.Active)
+	if user.Role == models.RoleAdmin && user.Active && demoted {
+		if err := s.ensureOtherAdmin(id); err != nil {
+			return nil, err
+		}
+	}
+
+	sets = append(sets, "updated_at = datetime('now')")
+	args = append(args, id)
+	if _, err := s.db.Exec("UPDATE users SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...); err != nil {
+		return nil, fmt.Errorf("erreur lors de la mise à jour de l'utilisateur: %w", err)
+	}
+
+	if req.Password != nil || (req.Active != nil && !*req.Active) {
+		if err := s.DeleteUserSessions(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUserByID(id)
+}
+
+// DeleteUser supprime un utilisateur et ses sessions
+func (s *Service) DeleteUser(id int) error {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if user.Role == models.RoleAdmin && user.Active {
+		if err := s.ensureOtherAdmin(id); err != nil {
+			return err
+		}
+	}
+
+	if err := s.DeleteUserSessions(id); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM users WHERE id = ?", id); err != nil {
+		return fmt.Errorf("erreur lors de la suppression de l'utilisateur: %w", err)
+	}
+	return nil
+}
+
+// CheckPassword vérifie le mot de passe actuel d'un utilisateur
+func (s *Service) CheckPassword(userID int, password string) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// ChangePassword change le mot de passe d'un utilisateur après vérification du mot de passe actuel.
+// Les autres sessions de l'utilisateur sont révoquées ; la session keepToken (celle de la requête) est conservée.
+func (s *Service) ChangePassword(userID int, req models.ChangePasswordRequest, keepToken string) error {
+	if err := s.CheckPassword(userID, req.CurrentPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("erreur lors du hashage du mot de passe")
+	}
+
+	if _, err := s.db.Exec(`
+		UPDATE users SET password_hash = ?, updated_at = datetime('now') WHERE id = ?
+	`, string(hashedPassword), userID); err != nil {
+		return err
+	}
+	return s.DeleteOtherUserSessions(userID, keepToken)
+}
+
+// BootstrapAdmin crée le compte administrateur initial si aucun administrateur n'existe.
+// Retourne nil sans erreur quand un administrateur est déjà présent.
+func (s *Service) BootstrapAdmin(username, email, password string) (*models.User, error) {
+	var admins int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = ?", models.RoleAdmin).Scan(&admins); err != nil {
+		return nil, err
+	}
+	if admins > 0 {
+		return nil, nil
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("aucun administrateur et ADMIN_USERNAME/ADMIN_PASSWORD non définis")
+	}
+
+	return s.CreateUser(models.CreateUserRequest{
+		Username: username,
+		Email:    email,
+		Password: password,
+		Role:     models.RoleAdmin,
+	})
+}
+
+// ensureOtherAdmin vérifie qu'il reste un autre administrateur actif que l'utilisateur id
+func (s *Service) ensureOtherAdmin(id int) error {
+	var admins int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM users WHERE role = ? AND active = 1 AND id != ?
+	`, models.RoleAdmin, id).Scan(&admins)
+	if err != nil {
+		return err
+	}
+	if admins == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
 // CreateSession crée une nouvelle session
 func (s *Service) CreateSession(session models.UserSession) error {
 	_, err := s.db.Exec(`
@@ -283,6 +462,18 @@ func (s *Service) GetSessionByToken(token string) (*models.UserSession, error) {
 	return &session, nil
 }
 
+// DeleteUserSessions supprime toutes les sessions d'un utilisateur
+func (s *Service) DeleteUserSessions(userID int) error {
+	_, err := s.db.Exec("DELETE FROM user_sessions WHERE user_id = ?", userID)
+	return err
+}
+
+// DeleteOtherUserSessions supprime les sessions d'un utilisateur sauf celle du token keepToken
+func (s *Service) DeleteOtherUserSessions(userID int, keepToken string) error {
+	_, err := s.db.Exec("DELETE FROM user_sessions WHERE user_id = ? AND token != ?", userID, keepToken)
+	return err
+}
+
 // DeleteSession supprime une session
 func (s *Service) DeleteSession(sessionID string) error {
 	_, err := s.db.Exec("DELETE FROM user_sessions WHERE id = ?", sessionID)
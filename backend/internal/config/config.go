@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config contient la configuration de l'application
@@ -11,7 +13,13 @@ type Config struct {
 	Database    DatabaseConfig
 	Server      ServerConfig
 	SMTP        SMTPConfig
+	Webhook     WebhookConfig
 	Security    SecurityConfig
+	Proxmox     ProxmoxConfig
+	Poller      PollerConfig
+	HealthCheck HealthCheckConfig
+	Prometheus  PrometheusConfig
+	Backup      BackupConfig
 }
 
 // DatabaseConfig contient la configuration de la base de données
@@ -21,24 +29,109 @@ type DatabaseConfig struct {
 
 // ServerConfig contient la configuration du serveur
 type ServerConfig struct {
-	Port string
-	Host string
+	Port      string
+	Host      string
+	PublicURL string // URL publique du dashboard, utilisée pour les liens des notifications
 }
 
 // SMTPConfig contient la configuration SMTP
 type SMTPConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
-	TLS      bool
+	Host         string
+	Port         int
+	Username     string
+	Password     string
+	From         string
+	TLS          bool          // STARTTLS quand Security n'est pas précisé
+	Security     string        // none|starttls|tls (TLS implicite, port 465), déduit de TLS et du port si vide
+	MaxAttempts  int           // nombre de tentatives avant abandon (dead-letter)
+	RetryBackoff time.Duration // délai avant la première nouvelle tentative, doublé à chaque échec
+}
+
+// Modes de sécurité de la connexion SMTP
+const (
+	SMTPSecurityNone     = "none"
+	SMTPSecuritySTARTTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+)
+
+// SecurityMode retourne le mode de sécurité effectif de la connexion SMTP
+func (c SMTPConfig) SecurityMode() string {
+	switch mode := strings.ToLower(c.Security); mode {
+	case SMTPSecurityNone, SMTPSecuritySTARTTLS, SMTPSecurityTLS:
+		return mode
+	}
+	switch {
+	case c.Port == 465:
+		return SMTPSecurityTLS
+	case c.TLS:
+		return SMTPSecuritySTARTTLS
+	default:
+		return SMTPSecurityNone
+	}
+}
+
+// WebhookConfig contient la configuration des livraisons webhook
+type WebhookConfig struct {
+	Timeout      time.Duration // délai maximal d'une requête
+	MaxAttempts  int           // tentatives par événement avant abandon
+	RetryBackoff time.Duration // délai avant la première nouvelle tentative, doublé à chaque échec
+	DisableAfter int           // échecs consécutifs avant désactivation de l'abonnement, 0 pour ne jamais désactiver
 }
 
 // SecurityConfig contient la configuration de sécurité
 type SecurityConfig struct {
-	JWTSecret string
-	CORS      CORSConfig
+	JWTSecret     string
+	EncryptionKey string // clé dédiée au chiffrement des secrets stockés en base (tokens Proxmox), distincte de JWTSecret
+	// AlertmanagerToken est le token Bearer accepté par POST /api/v1/alerts/alertmanager
+	// (authorization.credentials du webhook Alertmanager) ; vide pour exiger un token de session
+	AlertmanagerToken string
+	// MetricsToken est le token Bearer accepté par GET /metrics (authorization.credentials du scrape
+	// Prometheus) ; vide pour exiger un token de session
+	MetricsToken string
+	CORS         CORSConfig
+	Admin        AdminConfig
+}
+
+// AdminConfig contient le compte administrateur créé au premier démarrage
+type AdminConfig struct {
+	Username string
+	Email    string
+	Password string
+}
+
+// ProxmoxConfig contient la configuration des connexions Proxmox
+type ProxmoxConfig struct {
+	DevHosts []string // hôtes fictifs (et leurs sous-domaines) pour lesquels fetch-data ne contacte pas Proxmox
+}
+
+// PollerConfig contient la configuration du poller d'inventaire Proxmox
+type PollerConfig struct {
+	Enabled            bool
+	Interval           time.Duration
+	StorageThreshold   float64       // seuil d'occupation (%) des événements storage.threshold, 0 pour désactiver
+	ContentInterval    time.Duration // lecture des archives de sauvegarde et des snapshots des invités, plus espacée
+	ContentConcurrency int           // appels simultanés par cluster pour cette lecture
+}
+
+// HealthCheckConfig contient la configuration des health checks planifiés des applications
+type HealthCheckConfig struct {
+	Enabled          bool
+	Interval         time.Duration // intervalle des applications sans check_interval
+	FailureThreshold int           // échecs consécutifs avant l'alerte d'indisponibilité
+}
+
+// PrometheusConfig contient la configuration des requêtes Prometheus
+type PrometheusConfig struct {
+	URL  string // source de données créée au premier démarrage si aucune n'est configurée
+	Mock bool   // réponses simulées, pour le développement sans serveur Prometheus
+}
+
+// BackupConfig contient la politique de conformité des sauvegardes et son rapport quotidien
+type BackupConfig struct {
+	MaxAge       time.Duration // âge maximal de la dernière sauvegarde d'un invité sans règle
+	Policies     string        // règles par tag ou pool en heures ("tag:prod=24,pool:dev=168")
+	ReportEmails []string      // destinataires du rapport quotidien, vide pour le désactiver
+	ReportHour   int           // heure locale d'envoi du rapport (0-23)
 }
 
 // CORSConfig contient la configuration CORS
@@ -63,24 +156,67 @@ func Load() *Config {
 			Path: getEnv("DB_PATH", "data/app.db"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Host: getEnv("HOST", "0.0.0.0"), // 0.0.0.0 pour être accessible depuis Docker
+			Port:      getEnv("PORT", "8080"),
+			Host:      getEnv("HOST", "0.0.0.0"), // 0.0.0.0 pour être accessible depuis Docker
+			PublicURL: getEnv("PUBLIC_URL", ""),
 		},
 		SMTP: SMTPConfig{
-			Host:     getEnv("SMTP_HOST", ""),
-			Port:     getEnvAsInt("SMTP_PORT", 587),
-			Username: getEnv("SMTP_USERNAME", ""),
-			Password: getEnv("SMTP_PASSWORD", ""),
-			From:     getEnv("SMTP_FROM", ""),
-			TLS:      getEnvAsBool("SMTP_TLS", true),
+			Host:         getEnv("SMTP_HOST", ""),
+			Port:         getEnvAsInt("SMTP_PORT", 587),
+			Username:     getEnv("SMTP_USERNAME", ""),
+			Password:     getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("SMTP_FROM", "ProxmoxDash <noreply@localhost>"),
+			TLS:          getEnvAsBool("SMTP_TLS", true),
+			Security:     getEnv("SMTP_SECURITY", ""),
+			MaxAttempts:  getEnvAsInt("SMTP_MAX_ATTEMPTS", 5),
+			RetryBackoff: time.Duration(getEnvAsInt("SMTP_RETRY_BACKOFF", 30)) * time.Second,
+		},
+		Webhook: WebhookConfig{
+			Timeout:      time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT", 10)) * time.Second,
+			MaxAttempts:  getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+			RetryBackoff: time.Duration(getEnvAsInt("WEBHOOK_RETRY_BACKOFF", 30)) * time.Second,
+			DisableAfter: getEnvAsInt("WEBHOOK_DISABLE_AFTER", 10),
 		},
 		Security: SecurityConfig{
-			JWTSecret: getEnv("JWT_SECRET", ""),
+			JWTSecret:         getEnv("JWT_SECRET", ""),
+			EncryptionKey:     getEnv("ENCRYPTION_KEY", ""),
+			AlertmanagerToken: getEnv("ALERTMANAGER_TOKEN", ""),
+			MetricsToken:      getEnv("METRICS_TOKEN", ""),
 			CORS: CORSConfig{
 				AllowedOrigins: []string{getEnv("CORS_ORIGINS", "*")},
 				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 				AllowedHeaders: []string{"Content-Type", "Authorization"},
 			},
+			Admin: AdminConfig{
+				Username: getEnv("ADMIN_USERNAME", "admin"),
+				Email:    getEnv("ADMIN_EMAIL", "admin@localhost"),
+				Password: getEnv("ADMIN_PASSWORD", ""),
+			},
+		},
+		Proxmox: ProxmoxConfig{
+			DevHosts: getEnvAsList("PROXMOX_DEV_HOSTS", "example.com,proxmox-dev.local"),
+		},
+		Poller: PollerConfig{
+			Enabled:            getEnvAsBool("PROXMOX_POLL_ENABLED", true),
+			Interval:           time.Duration(getEnvAsInt("PROXMOX_POLL_INTERVAL", 30)) * time.Second,
+			StorageThreshold:   float64(getEnvAsInt("PROXMOX_STORAGE_THRESHOLD", 85)),
+			ContentInterval:    time.Duration(getEnvAsInt("PROXMOX_CONTENT_POLL_INTERVAL", 900)) * time.Second,
+			ContentConcurrency: getEnvAsInt("PROXMOX_CONTENT_CONCURRENCY", 4),
+		},
+		HealthCheck: HealthCheckConfig{
+			Enabled:          getEnvAsBool("HEALTH_CHECK_ENABLED", true),
+			Interval:         time.Duration(getEnvAsInt("HEALTH_CHECK_INTERVAL", 60)) * time.Second,
+			FailureThreshold: getEnvAsInt("HEALTH_CHECK_FAILURE_THRESHOLD", 3),
+		},
+		Prometheus: PrometheusConfig{
+			URL:  getEnv("PROMETHEUS_URL", ""),
+			Mock: getEnvAsBool("PROMETHEUS_MOCK", false),
+		},
+		Backup: BackupConfig{
+			MaxAge:       time.Duration(getEnvAsInt("BACKUP_MAX_AGE_HOURS", 24)) * time.Hour,
+			Policies:     getEnv("BACKUP_POLICIES", ""),
+			ReportEmails: getEnvAsList("BACKUP_REPORT_EMAILS", ""),
+			ReportHour:   getEnvAsInt("BACKUP_REPORT_HOUR", 7),
 		},
 	}
 }
@@ -112,3 +248,14 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsList récupère une variable d'environnement comme liste séparée par des virgules
+func getEnvAsList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
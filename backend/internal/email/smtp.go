@@ -1,56 +1,63 @@
 package email
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/mail"
 	"net/smtp"
-	"os"
+	"net/textproto"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"proxmox-dashboard/internal/config"
 	"proxmox-dashboard/internal/models"
 	"proxmox-dashboard/internal/store"
 )
 
-// Config contient la configuration SMTP
-type Config struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
-	TLS      bool
-}
+const (
+	// pollInterval est l'intervalle de lecture de la file d'attente
+	pollInterval = 2 * time.Second
+	// batchSize est le nombre maximal d'emails envoyés par passage
+	batchSize = 20
+	// sendTimeout borne la durée d'un envoi (connexion, TLS, dialogue SMTP)
+	sendTimeout = 30 * time.Second
+	// maxRetryDelay plafonne le délai entre deux tentatives
+	maxRetryDelay = time.Hour
+)
 
 // Worker gère l'envoi d'emails
 type Worker struct {
-	config *Config
-	store  *store.Store
-	quit   chan bool
+	config    config.SMTPConfig
+	store     *store.Store
+	tlsConfig *tls.Config
+	quit      chan bool
 }
 
-// NewWorker crée un nouveau worker email
-func NewWorker(store *store.Store) *Worker {
-	config := &Config{
-		Host:     getEnv("SMTP_HOST", "localhost"),
-		Port:     getEnvInt("SMTP_PORT", 587),
-		Username: getEnv("SMTP_USERNAME", ""),
-		Password: getEnv("SMTP_PASSWORD", ""),
-		From:     getEnv("SMTP_FROM", "noreply@example.com"),
-		TLS:      getEnvBool("SMTP_TLS", true),
+// NewWorker crée un nouveau worker email à partir de la configuration SMTP
+func NewWorker(store *store.Store, cfg config.SMTPConfig) *Worker {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = models.DefaultEmailMaxAttempts
 	}
-
 	return &Worker{
-		config: config,
+		config: cfg,
 		store:  store,
 		quit:   make(chan bool),
 	}
 }
 
+// SetTLSConfig remplace la configuration TLS (autorité de certification privée, tests)
+func (w *Worker) SetTLSConfig(tlsConfig *tls.Config) {
+	w.tlsConfig = tlsConfig
+}
+
 // Start démarre le worker email
 func (w *Worker) Start() {
-	log.Println("Starting email worker...")
+	log.Printf("📧 Starting email worker (%s:%d, %s)", w.config.Host, w.config.Port, w.config.SecurityMode())
 	go w.run()
 }
 
@@ -61,13 +68,13 @@ func (w *Worker) Stop() {
 
 // run est la boucle principale du worker
 func (w *Worker) run() {
-	ticker := time.NewTicker(2 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			w.processQueue()
+			w.ProcessQueue()
 		case <-w.quit:
 			log.Println("Email worker stopped")
 			return
@@ -75,142 +82,281 @@ func (w *Worker) run() {
 	}
 }
 
-// processQueue traite la file d'attente des emails
-func (w *Worker) processQueue() {
-	emails, err := w.store.GetQueuedEmails()
+// ProcessQueue envoie les emails en attente dont la prochaine tentative est échue.
+// Un échec replanifie l'email avec un délai exponentiel ; après max_attempts tentatives
+// ou une erreur permanente (code SMTP 5xx), l'email passe à l'état dead.
+func (w *Worker) ProcessQueue() {
+	emails, err := w.store.GetQueuedEmails(time.Now(), batchSize)
 	if err != nil {
 		log.Printf("Error getting queued emails: %v", err)
 		return
 	}
 
 	for _, email := range emails {
-		if err := w.sendEmail(*email); err != nil {
-			log.Printf("Error sending email %d: %v", email.ID, err)
-			w.store.MarkEmailError(email.ID, err.Error())
-		} else {
-			log.Printf("Email %d sent successfully to %s", email.ID, email.ToAddr)
-			w.store.MarkEmailSent(email.ID)
+		w.deliver(email)
+	}
+}
+
+// deliver envoie un email et enregistre le résultat de la tentative
+func (w *Worker) deliver(email *models.EmailQueue) {
+	err := w.sendEmail(*email)
+	if err == nil {
+		log.Printf("Email %d sent successfully to %s", email.ID, email.ToAddr)
+		if err := w.store.MarkEmailSent(email.ID); err != nil {
+			log.Printf("⚠️  %v", err)
 		}
+		return
+	}
+
+	attempts := email.Attempts + 1
+	if attempts >= email.MaxAttempts || isPermanent(err) {
+		log.Printf("☠️  Email %d to %s abandoned after %d attempt(s): %v", email.ID, email.ToAddr, attempts, err)
+		if err := w.store.MarkEmailDead(email.ID, err.Error()); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	next := time.Now().Add(retryDelay(w.config.RetryBackoff, attempts))
+	log.Printf("Error sending email %d (attempt %d/%d, retry at %s): %v",
+		email.ID, attempts, email.MaxAttempts, next.Format(time.RFC3339), err)
+	if err := w.store.MarkEmailRetry(email.ID, err.Error(), next); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// retryDelay calcule le délai avant la tentative suivant la n-ième tentative échouée (backoff exponentiel)
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
 	}
+	return delay
 }
 
-// sendEmail envoie un email
+// isPermanent indique si l'erreur SMTP est définitive (5xx) et ne doit pas être retentée
+func isPermanent(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
+
+// sendEmail envoie un email selon le mode de sécurité configuré :
+// tls (TLS implicite, port 465), starttls (obligatoire) ou none
 func (w *Worker) sendEmail(email models.EmailQueue) error {
-	// Construire l'adresse du serveur
-	addr := fmt.Sprintf("%s:%d", w.config.Host, w.config.Port)
+	from, err := mail.ParseAddress(w.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", w.config.From, err)
+	}
 
 	// Préparer le message
-	msg := w.buildMessage(email.ToAddr, email.Subject, email.BodyText)
+	msg, err := buildMessage(from, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(w.config.Host, strconv.Itoa(w.config.Port))
+	mode := w.config.SecurityMode()
+	dialer := &net.Dialer{Timeout: sendTimeout}
+
+	var conn net.Conn
+	if mode == config.SMTPSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, w.clientTLSConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	client, err := smtp.NewClient(conn, w.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if mode == config.SMTPSecuritySTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(w.clientTLSConfig()); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
 
 	// Authentification si nécessaire
-	var auth smtp.Auth
 	if w.config.Username != "" && w.config.Password != "" {
-		auth = smtp.PlainAuth("", w.config.Username, w.config.Password, w.config.Host)
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server %s does not support authentication", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", w.config.Username, w.config.Password, w.config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
 	}
 
-	// Envoyer l'email
-	err := smtp.SendMail(addr, auth, w.config.From, []string{email.ToAddr}, []byte(msg))
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("MAIL FROM rejected: %w", err)
+	}
+	if err := client.Rcpt(email.ToAddr); err != nil {
+		return fmt.Errorf("RCPT TO rejected: %w", err)
+	}
+	data, err := client.Data()
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		return fmt.Errorf("DATA rejected: %w", err)
+	}
+	if _, err := data.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
 	}
 
+	// Le message est accepté dès la réponse à DATA : un échec du QUIT ne doit pas provoquer de renvoi
+	if err := client.Quit(); err != nil {
+		log.Printf("⚠️  SMTP QUIT failed after email %d was accepted: %v", email.ID, err)
+	}
 	return nil
 }
 
-// buildMessage construit le message email
-func (w *Worker) buildMessage(to, subject, body string) string {
-	msg := fmt.Sprintf("From: %s\r\n", w.config.From)
-	msg += fmt.Sprintf("To: %s\r\n", to)
-	msg += fmt.Sprintf("Subject: %s\r\n", subject)
-	msg += "Content-Type: text/plain; charset=UTF-8\r\n"
-	msg += "\r\n"
-	msg += body
-	return msg
+// clientTLSConfig retourne la configuration TLS de la connexion au serveur SMTP
+func (w *Worker) clientTLSConfig() *tls.Config {
+	if w.tlsConfig != nil {
+		tlsConfig := w.tlsConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = w.config.Host
+		}
+		return tlsConfig
+	}
+	return &tls.Config{ServerName: w.config.Host, MinVersion: tls.VersionTLS12}
 }
 
-// SendTestEmail envoie un email de test
-func (w *Worker) SendTestEmail(to string) error {
-	subject := "Test de notification - ProxmoxDash"
-	body := fmt.Sprintf(`Bonjour,
-
-Ceci est un email de test envoyé depuis ProxmoxDash.
-
-Si vous recevez ce message, la configuration SMTP fonctionne correctement.
-
-Détails:
-- Serveur SMTP: %s:%d
-- Envoyé le: %s
-- Destinataire: %s
+// Enqueue génère un email à partir du gabarit name et l'ajoute à la file d'attente
+func (w *Worker) Enqueue(to, subject, name string, data interface{}) (*models.EmailQueue, error) {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
 
-Cordialement,
-L'équipe ProxmoxDash
-`, w.config.Host, w.config.Port, time.Now().Format("2006-01-02 15:04:05"), to)
+	text, html, err := Render(name, data)
+	if err != nil {
+		return nil, err
+	}
 
 	email := &models.EmailQueue{
-		ToAddr:    to,
-		Subject:   subject,
-		BodyText:  body,
-		State:     "pending",
-		CreatedAt: time.Now(),
+		ToAddr:      to,
+		Subject:     subject,
+		BodyText:    text,
+		BodyHTML:    html,
+		State:       models.EmailStatePending,
+		MaxAttempts: w.config.MaxAttempts,
+		CreatedAt:   time.Now(),
+	}
+	if err := w.store.EnqueueEmail(email); err != nil {
+		return nil, err
 	}
-	return w.store.EnqueueEmail(email)
+	return email, nil
 }
 
-// SendAlertEmail envoie un email d'alerte
-func (w *Worker) SendAlertEmail(to string, alert *models.Alert) error {
-	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), alert.Title)
+// TestEmailData est le contenu du gabarit "test"
+type TestEmailData struct {
+	To       string
+	Server   string
+	Security string
+	SentAt   time.Time
+}
 
-	body := fmt.Sprintf(`Nouvelle alerte ProxmoxDash
+// SendTestEmail envoie un email de test
+func (w *Worker) SendTestEmail(to string) (*models.EmailQueue, error) {
+	return w.Enqueue(to, "Test de notification - ProxmoxDash", "test", TestEmailData{
+		To:       to,
+		Server:   net.JoinHostPort(w.config.Host, strconv.Itoa(w.config.Port)),
+		Security: w.config.SecurityMode(),
+		SentAt:   time.Now(),
+	})
+}
 
-Titre: %s
-Sévérité: %s
-Source: %s
-Message: %s
+// AlertEmailData est le contenu du gabarit "alert"
+type AlertEmailData struct {
+	Alert  *models.Alert
+	AckURL string // lien vers l'alerte dans le dashboard, optionnel
+}
 
-Détails:
-- ID de l'alerte: %d
-- Créée le: %s
+// SendAlertEmail envoie un email d'alerte. ackURL (optionnel) est le lien d'accusé de réception.
+func (w *Worker) SendAlertEmail(to string, alert *models.Alert, ackURL string) (*models.EmailQueue, error) {
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), alert.Title)
+	if alert.Status == models.AlertStatusResolved {
+		subject = fmt.Sprintf("[RÉSOLU] %s", alert.Title)
+	}
+	return w.Enqueue(to, subject, "alert", AlertEmailData{Alert: alert, AckURL: ackURL})
+}
 
-Pour accuser réception de cette alerte, connectez-vous au dashboard ProxmoxDash.
+// AlertGroupEmailData est le contenu du gabarit "alert_group"
+type AlertGroupEmailData struct {
+	Alerts   []*models.Alert
+	Group    *models.AlertGroupInfo
+	Labels   []string // labels du groupe, "nom=valeur" triés
+	Firing   int      // nombre d'alertes ouvertes
+	Severity string   // plus haute sévérité des alertes ouvertes, "resolved" si toutes sont résolues
+	URL      string   // lien vers le dashboard, optionnel
+}
 
-Cordialement,
-Système de monitoring ProxmoxDash
-`, alert.Title, alert.Severity, alert.Source, alert.Message,
-		alert.ID, alert.CreatedAt.Format("2006-01-02 15:04:05"))
+// SendAlertGroupEmail envoie un email récapitulant les alertes d'un groupe de routage
+func (w *Worker) SendAlertGroupEmail(to string, alerts []*models.Alert, group *models.AlertGroupInfo, url string) (*models.EmailQueue, error) {
+	data := AlertGroupEmailData{Alerts: alerts, Group: group, Severity: "resolved", URL: url}
+	for _, alert := range alerts {
+		if alert.Status == models.AlertStatusResolved {
+			continue
+		}
+		data.Firing++
+		if data.Severity == "resolved" || models.SeverityRank(alert.Severity) > models.SeverityRank(data.Severity) {
+			data.Severity = alert.Severity
+		}
+	}
+	if group != nil {
+		for name, value := range group.Labels {
+			if value != "" {
+				data.Labels = append(data.Labels, name+"="+value)
+			}
+		}
+		sort.Strings(data.Labels)
+	}
 
-	email := &models.EmailQueue{
-		ToAddr:    to,
-		Subject:   subject,
-		BodyText:  body,
-		State:     "pending",
-		CreatedAt: time.Now(),
+	scope := ""
+	if len(data.Labels) > 0 {
+		scope = fmt.Sprintf(" (%s)", strings.Join(data.Labels, ", "))
+	}
+	subject := fmt.Sprintf("[%s] %d alertes ouvertes%s", strings.ToUpper(data.Severity), data.Firing, scope)
+	if data.Firing == 0 {
+		subject = fmt.Sprintf("[RÉSOLU] %d alertes%s", len(alerts), scope)
 	}
-	return w.store.EnqueueEmail(email)
+	return w.Enqueue(to, subject, "alert_group", data)
 }
 
-// Fonctions utilitaires pour les variables d'environnement
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// BackupReportEmailData est le contenu du gabarit "backup_report"
+type BackupReportEmailData struct {
+	Report     *models.BackupComplianceReport
+	Violations []models.BackupCompliance // invités non conformes
+	URL        string                    // lien vers le rapport, optionnel
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// SendBackupReportEmail envoie le rapport quotidien de conformité des sauvegardes
+func (w *Worker) SendBackupReportEmail(to string, report *models.BackupComplianceReport, url string) (*models.EmailQueue, error) {
+	data := BackupReportEmailData{Report: report, URL: url}
+	for _, guest := range report.Guests {
+		if !guest.Compliant {
+			data.Violations = append(data.Violations, guest)
 		}
 	}
-	return defaultValue
-}
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
-		}
+	subject := fmt.Sprintf("[CONFORME] Sauvegardes des %d invités", report.Total)
+	switch {
+	case report.Violations > report.Unknown:
+		subject = fmt.Sprintf("[NON CONFORME] %d invité(s) sur %d sans sauvegarde récente", report.Violations-report.Unknown, report.Total)
+	case !report.Complete:
+		subject = fmt.Sprintf("[INCOMPLET] Sauvegardes des %d invités, inventaire partiel", report.Total)
 	}
-	return defaultValue
+	return w.Enqueue(to, subject, "backup_report", data)
 }
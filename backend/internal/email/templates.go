@@ -0,0 +1,61 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFS contient les gabarits des emails : <nom>.txt.tmpl pour la partie texte,
+// <nom>.html.tmpl (bloc "content" inséré dans layout.html.tmpl) pour la partie HTML
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templateFuncs sont les fonctions disponibles dans les gabarits
+var templateFuncs = map[string]interface{}{
+	"severityColor": severityColor,
+	"hours":         func(seconds int64) int64 { return seconds / 3600 },
+}
+
+// Render génère les parties texte et HTML d'un email à partir du gabarit name (ex: "alert")
+func Render(name string, data interface{}) (text, html string, err error) {
+	textTmpl, err := texttemplate.New(name+".txt.tmpl").Funcs(templateFuncs).
+		ParseFS(templateFS, "templates/"+name+".txt.tmpl")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse text template %s: %w", name, err)
+	}
+	var textBuf bytes.Buffer
+	if err := textTmpl.Execute(&textBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render text template %s: %w", name, err)
+	}
+
+	htmlTmpl, err := htmltemplate.New("layout.html.tmpl").Funcs(templateFuncs).
+		ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML template %s: %w", name, err)
+	}
+	var htmlBuf bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&htmlBuf, "layout", data); err != nil {
+		return "", "", fmt.Errorf("failed to render HTML template %s: %w", name, err)
+	}
+
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+// severityColor retourne la couleur du badge d'une sévérité d'alerte
+func severityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "#c62828"
+	case "high", "warning":
+		return "#ef8f00"
+	case "resolved":
+		return "#2e7d32"
+	default:
+		return "#1e88e5"
+	}
+}
@@ -0,0 +1,261 @@
+package exporter
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+)
+
+// gb est le nombre d'octets d'un GB de l'inventaire (les tailles des storages et backups y sont en GB)
+const gb = 1024 * 1024 * 1024
+
+// taskKey identifie une série de proxmoxdash_task_failures_total
+type taskKey struct {
+	cluster, node, taskType string
+}
+
+// Exporter publie au format Prometheus l'inventaire mis en cache par le poller, la santé des applications
+// et les requêtes HTTP du dashboard. Un scrape ne lit que le dernier snapshot : il n'interroge jamais Proxmox.
+type Exporter struct {
+	store    *store.Store
+	snapshot func() *models.ProxmoxSnapshot
+
+	mu           sync.Mutex
+	taskFailures map[taskKey]float64
+	appHealth    map[int]models.HealthStatus
+	requests     map[requestKey]float64
+	durations    map[durationKey]*histogram
+}
+
+// NewExporter crée un exporter Prometheus
+func NewExporter(store *store.Store) *Exporter {
+	return &Exporter{
+		store:        store,
+		taskFailures: make(map[taskKey]float64),
+		appHealth:    make(map[int]models.HealthStatus),
+		requests:     make(map[requestKey]float64),
+		durations:    make(map[durationKey]*histogram),
+	}
+}
+
+// SetSnapshotSource configure la source du snapshot d'inventaire publié (typiquement Poller.Snapshot)
+func (e *Exporter) SetSnapshotSource(source func() *models.ProxmoxSnapshot) {
+	e.snapshot = source
+}
+
+// ObserveSnapshot compte les tâches en échec terminées depuis la collecte précédente (listener du poller).
+// Le premier snapshot sert de référence : les échecs antérieurs au démarrage ne sont pas comptés.
+func (e *Exporter) ObserveSnapshot(prev, next *models.ProxmoxSnapshot) {
+	if prev == nil {
+		return
+	}
+	before := make(map[string]string, len(prev.Tasks))
+	for _, t := range prev.Tasks {
+		before[t.Cluster+"/"+t.ID] = t.Status
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, t := range next.Tasks {
+		if t.Status != "failed" {
+			continue
+		}
+		status, known := before[t.Cluster+"/"+t.ID]
+		if status == "failed" || (!known && (t.CompletedAt == nil || t.CompletedAt.Before(prev.CollectedAt))) {
+			continue // déjà comptée, ou tâche ancienne revenue dans la liste
+		}
+		e.taskFailures[taskKey{t.Cluster, t.Node, t.Type}]++
+	}
+}
+
+// ObserveAppHealth enregistre le dernier résultat du health check d'une application
+func (e *Exporter) ObserveAppHealth(status *models.HealthStatus) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.appHealth[status.AppID] = *status
+}
+
+// ServeHTTP répond au scrape de Prometheus
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m := &metricSet{}
+	now := time.Now()
+	if e.snapshot != nil {
+		if snapshot := e.snapshot(); snapshot != nil {
+			collectInventory(m, snapshot, now)
+		}
+	}
+	e.collectTasks(m)
+	e.collectApps(m)
+	e.collectHTTP(m)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.write(w); err != nil {
+		log.Printf("⚠️  Failed to write Prometheus metrics: %v", err)
+	}
+}
+
+// collectInventory ajoute les métriques des clusters, nœuds, invités, storages, backups et snapshots du snapshot
+func collectInventory(m *metricSet, snapshot *models.ProxmoxSnapshot, now time.Time) {
+	m.gauge("proxmoxdash_inventory_collected_timestamp_seconds", "Unix time of the inventory snapshot served by the exporter.").
+		add(float64(snapshot.CollectedAt.Unix()))
+	m.gauge("proxmoxdash_inventory_age_seconds", "Age of the inventory snapshot served by the exporter.").
+		add(now.Sub(snapshot.CollectedAt).Seconds())
+
+	clusterUp := m.gauge("proxmoxdash_cluster_up", "Whether the last poll of the Proxmox cluster succeeded.")
+	for _, c := range snapshot.Clusters {
+		clusterUp.add(boolValue(c.Success), "cluster", c.Name)
+	}
+
+	nodeUp := m.gauge("proxmoxdash_node_up", "Whether the Proxmox node is online.")
+	nodeCPU := m.gauge("proxmoxdash_node_cpu_usage_ratio", "CPU usage of the node (0-1).")
+	nodeMem := m.gauge("proxmoxdash_node_memory_usage_ratio", "Memory usage of the node (0-1).")
+	nodeDisk := m.gauge("proxmoxdash_node_disk_usage_ratio", "Root disk usage of the node (0-1).")
+	nodeUptime := m.gauge("proxmoxdash_node_uptime_seconds", "Uptime of the node.")
+	for _, n := range snapshot.Nodes {
+		labels := []string{"cluster", n.Cluster, "node", n.Name}
+		nodeUp.add(boolValue(n.Status == "online"), labels...)
+		nodeCPU.add(n.CPUUsage/100, labels...)
+		nodeMem.add(n.MemoryUsage/100, labels...)
+		nodeDisk.add(n.DiskUsage/100, labels...)
+		nodeUptime.add(float64(n.Uptime), labels...)
+	}
+
+	guestUp := m.gauge("proxmoxdash_guest_up", "Whether the VM or container is running.")
+	guestCPU := m.gauge("proxmoxdash_guest_cpu_usage_ratio", "CPU usage of the guest (0-1).")
+	guestMem := m.gauge("proxmoxdash_guest_memory_usage_ratio", "Memory usage of the guest (0-1).")
+	guestDisk := m.gauge("proxmoxdash_guest_disk_usage_ratio", "Disk usage of the guest (0-1).")
+	guestMaxMem := m.gauge("proxmoxdash_guest_memory_max_bytes", "Memory allocated to the guest.")
+	guestCPUs := m.gauge("proxmoxdash_guest_cpus", "Virtual CPUs allocated to the guest.")
+	guestUptime := m.gauge("proxmoxdash_guest_uptime_seconds", "Uptime of the guest.")
+	for _, guests := range [][]models.ProxmoxGuest{snapshot.VMs, snapshot.LXC} {
+		for _, g := range guests {
+			labels := []string{"cluster", g.Cluster, "node", g.Node, "vmid", strconv.Itoa(g.VMID), "name", g.Name, "type", g.Type}
+			guestUp.add(boolValue(g.Status == "running"), labels...)
+			guestCPU.add(g.CPUUsage/100, labels...)
+			guestMem.add(g.MemoryUsage/100, labels...)
+			guestDisk.add(g.DiskUsage/100, labels...)
+			guestMaxMem.add(float64(g.MaxMem), labels...)
+			guestCPUs.add(g.MaxCPU, labels...)
+			guestUptime.add(float64(g.Uptime), labels...)
+		}
+	}
+
+	storageUp := m.gauge("proxmoxdash_storage_up", "Whether the storage is active.")
+	storageSize := m.gauge("proxmoxdash_storage_size_bytes", "Total size of the storage.")
+	storageUsed := m.gauge("proxmoxdash_storage_used_bytes", "Used space of the storage.")
+	storageUsage := m.gauge("proxmoxdash_storage_usage_ratio", "Usage of the storage (0-1).")
+	for _, s := range snapshot.Storages {
+		labels := []string{"cluster", s.Cluster, "node", s.Node, "storage", s.Name, "type", s.Type}
+		storageUp.add(boolValue(s.Status == "online"), labels...)
+		storageSize.add(s.TotalSpace*gb, labels...)
+		storageUsed.add(s.UsedSpace*gb, labels...)
+		storageUsage.add(s.UsagePercent/100, labels...)
+	}
+
+	// Dernier backup de chaque invité : un VMID est unique dans un cluster
+	type guestKey struct {
+		cluster, vmid, guestType string
+	}
+	latest := make(map[guestKey]models.ProxmoxBackup)
+	for _, b := range snapshot.Backups {
+		key := guestKey{b.Cluster, strconv.Itoa(b.VMID), b.Type}
+		if last, ok := latest[key]; !ok || b.CreatedAt.After(last.CreatedAt) {
+			latest[key] = b
+		}
+	}
+	backupTime := m.gauge("proxmoxdash_guest_last_backup_timestamp_seconds", "Unix time of the most recent vzdump backup of the guest.")
+	backupAge := m.gauge("proxmoxdash_guest_backup_age_seconds", "Age of the most recent vzdump backup of the guest.")
+	backupSize := m.gauge("proxmoxdash_guest_last_backup_size_bytes", "Size of the most recent vzdump backup of the guest.")
+	for key, b := range latest {
+		labels := []string{"cluster", key.cluster, "vmid", key.vmid, "type", key.guestType}
+		backupTime.add(float64(b.CreatedAt.Unix()), labels...)
+		backupAge.add(now.Sub(b.CreatedAt).Seconds(), labels...)
+		backupSize.add(b.Size*gb, labels...)
+	}
+
+	// Snapshots de chaque invité : nombre et âge du plus ancien
+	type guestSnapshots struct {
+		count  int
+		oldest time.Time
+	}
+	snapshots := make(map[guestKey]*guestSnapshots)
+	for _, s := range snapshot.GuestSnapshots {
+		key := guestKey{s.Cluster, strconv.Itoa(s.VMID), s.Type}
+		stats, ok := snapshots[key]
+		if !ok {
+			stats = &guestSnapshots{oldest: s.CreatedAt}
+			snapshots[key] = stats
+		}
+		stats.count++
+		if s.CreatedAt.Before(stats.oldest) {
+			stats.oldest = s.CreatedAt
+		}
+	}
+	snapshotCount := m.gauge("proxmoxdash_guest_snapshots", "Number of snapshots of the guest.")
+	snapshotAge := m.gauge("proxmoxdash_guest_oldest_snapshot_age_seconds", "Age of the oldest snapshot of the guest.")
+	for key, stats := range snapshots {
+		labels := []string{"cluster", key.cluster, "vmid", key.vmid, "type", key.guestType}
+		snapshotCount.add(float64(stats.count), labels...)
+		snapshotAge.add(now.Sub(stats.oldest).Seconds(), labels...)
+	}
+}
+
+// collectTasks ajoute les compteurs de tâches Proxmox en échec
+func (e *Exporter) collectTasks(m *metricSet) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	failures := m.counter("proxmoxdash_task_failures_total", "Proxmox tasks that finished with an error since the dashboard started.")
+	for key, count := range e.taskFailures {
+		failures.add(count, "cluster", key.cluster, "node", key.node, "type", key.taskType)
+	}
+}
+
+// collectApps ajoute l'état des health checks des applications enregistrées
+func (e *Exporter) collectApps(m *metricSet) {
+	apps, err := e.store.GetApps()
+	if err != nil {
+		log.Printf("⚠️  Failed to get apps for Prometheus metrics: %v", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	up := m.gauge("proxmoxdash_app_up", "Whether the last health check of the application succeeded.")
+	latency := m.gauge("proxmoxdash_app_health_latency_seconds", "Latency of the last health check of the application.")
+	lastCheck := m.gauge("proxmoxdash_app_health_last_check_timestamp_seconds", "Unix time of the last health check of the application.")
+	known := make(map[int]bool, len(apps))
+	for _, app := range apps {
+		known[app.ID] = true
+		status, ok := e.appHealth[app.ID]
+		if !ok {
+			continue // jamais vérifiée depuis le démarrage
+		}
+		labels := []string{"app_id", strconv.Itoa(app.ID), "app", app.Name}
+		up.add(boolValue(status.Status == "online"), labels...)
+		if status.Latency != nil {
+			latency.add(float64(*status.Latency)/1000, labels...)
+		}
+		lastCheck.add(float64(status.LastCheck.Unix()), labels...)
+	}
+	// Oublier les applications supprimées
+	for id := range e.appHealth {
+		if !known[id] {
+			delete(e.appHealth, id)
+		}
+	}
+}
+
+// boolValue convertit un booléen en valeur de gauge
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// durationBuckets sont les bornes (en secondes) de l'histogramme des durées de requêtes
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram compte les observations par borne supérieure de durationBuckets
+type histogram struct {
+	buckets []uint64 // cumulés : buckets[i] compte les observations <= durationBuckets[i]
+	sum     float64
+	count   uint64
+}
+
+// observe enregistre une observation
+func (h *histogram) observe(v float64) {
+	for i, bound := range durationBuckets {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// requestKey identifie une série de proxmoxdash_http_requests_total
+type requestKey struct {
+	method, route, code string
+}
+
+// durationKey identifie une série de proxmoxdash_http_request_duration_seconds
+type durationKey struct {
+	method, route string
+}
+
+// Middleware mesure les requêtes HTTP servies par le dashboard. Les séries sont étiquetées par le motif
+// de route chi (/api/v1/alerts/{id}) et non par le chemin, pour garder une cardinalité bornée.
+// La durée des flux SSE, ouverts pendant toute la session, n'est pas mesurée.
+func (e *Exporter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		stream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+		e.observeRequest(r.Method, route, status, time.Since(start), stream)
+	})
+}
+
+// observeRequest enregistre une requête terminée
+func (e *Exporter) observeRequest(method, route string, status int, elapsed time.Duration, stream bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests[requestKey{method, route, strconv.Itoa(status)}]++
+	if stream {
+		return
+	}
+	key := durationKey{method, route}
+	h := e.durations[key]
+	if h == nil {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		e.durations[key] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+// collectHTTP ajoute les métriques des requêtes HTTP
+func (e *Exporter) collectHTTP(m *metricSet) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	requests := m.counter("proxmoxdash_http_requests_total", "HTTP requests served by the dashboard, by method, route pattern and status code.")
+	for key, count := range e.requests {
+		requests.add(count, "method", key.method, "route", key.route, "code", key.code)
+	}
+
+	durations := m.family("proxmoxdash_http_request_duration_seconds", typeHistogram, "HTTP request latency by method and route pattern (SSE streams excluded).")
+	for _, key := range sortedDurationKeys(e.durations) {
+		durations.histogram(durationBuckets, e.durations[key], "method", key.method, "route", key.route)
+	}
+}
+
+// sortedDurationKeys trie les séries d'histogramme, dont les lignes ne doivent pas être mélangées
+func sortedDurationKeys(durations map[durationKey]*histogram) []durationKey {
+	keys := make([]durationKey, 0, len(durations))
+	for key := range durations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+	return keys
+}
@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types de métriques du format d'exposition texte de Prometheus
+const (
+	typeGauge     = "gauge"
+	typeCounter   = "counter"
+	typeHistogram = "histogram"
+)
+
+// sample est une valeur d'une famille de métriques ; suffix vaut _bucket, _sum ou _count pour les histogrammes
+type sample struct {
+	suffix string
+	labels []string // paires nom, valeur
+	value  float64
+}
+
+// family est une famille de métriques (même nom, même HELP et TYPE)
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// add ajoute une valeur ; labels est une liste de paires nom, valeur
+func (f *family) add(value float64, labels ...string) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// metricSet accumule les familles d'une collecte dans leur ordre de déclaration
+type metricSet struct {
+	families []*family
+}
+
+// family déclare une famille de métriques
+func (m *metricSet) family(name, typ, help string) *family {
+	f := &family{name: name, help: help, typ: typ}
+	m.families = append(m.families, f)
+	return f
+}
+
+func (m *metricSet) gauge(name, help string) *family   { return m.family(name, typeGauge, help) }
+func (m *metricSet) counter(name, help string) *family { return m.family(name, typeCounter, help) }
+
+// histogram ajoute les séries _bucket, _sum et _count d'un histogramme
+func (f *family) histogram(bounds []float64, h *histogram, labels ...string) {
+	for i, bound := range bounds {
+		bucketLabels := append(append([]string{}, labels...), "le", formatValue(bound))
+		f.samples = append(f.samples, sample{suffix: "_bucket", labels: bucketLabels, value: float64(h.buckets[i])})
+	}
+	infLabels := append(append([]string{}, labels...), "le", "+Inf")
+	f.samples = append(f.samples,
+		sample{suffix: "_bucket", labels: infLabels, value: float64(h.count)},
+		sample{suffix: "_sum", labels: labels, value: h.sum},
+		sample{suffix: "_count", labels: labels, value: float64(h.count)},
+	)
+}
+
+// write écrit les familles au format d'exposition texte (version 0.0.4).
+// Les familles sans valeur sont omises ; les séries sont triées pour une sortie stable.
+func (m *metricSet) write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	for _, f := range m.families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		out.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		out.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		if f.typ != typeHistogram {
+			sort.SliceStable(f.samples, func(i, j int) bool {
+				return labelKey(f.samples[i].labels) < labelKey(f.samples[j].labels)
+			})
+		}
+		for _, s := range f.samples {
+			out.WriteString(f.name + s.suffix)
+			if len(s.labels) > 0 {
+				out.WriteString("{")
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						out.WriteString(",")
+					}
+					out.WriteString(s.labels[i] + `="` + escapeLabel(s.labels[i+1]) + `"`)
+				}
+				out.WriteString("}")
+			}
+			out.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+	return out.Flush()
+}
+
+// labelKey retourne une clé de tri des labels d'une série
+func labelKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+// formatValue formate une valeur comme Prometheus (+Inf, -Inf, NaN)
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
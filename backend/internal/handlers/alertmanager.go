@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+)
+
+// ReceiveAlertmanager reçoit les notifications du receiver webhook de Prometheus Alertmanager.
+// Chaque alerte firing crée une alerte (dédupliquée par fingerprint tant qu'elle est ouverte) ;
+// une alerte resolved résout l'alerte ouverte correspondante.
+func (h *Handlers) ReceiveAlertmanager(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.alertmanager")
+
+	var webhook models.AlertmanagerWebhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("alertmanager/%s", webhook.Receiver))
+
+	result := models.AlertmanagerResult{Created: []int{}, Resolved: []int{}}
+	now := time.Now()
+	for i := range webhook.Alerts {
+		incoming := &webhook.Alerts[i]
+		open, err := h.store.GetOpenAlertByFingerprint(incoming.DashboardFingerprint())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if incoming.IsResolved() {
+			if open == nil {
+				result.Ignored++
+				continue
+			}
+			resolvedAt := incoming.EndsAt
+			if resolvedAt.IsZero() || resolvedAt.After(now) {
+				resolvedAt = now
+			}
+			if err := h.store.ResolveAlert(open, "", resolvedAt); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			result.Resolved = append(result.Resolved, open.ID)
+			h.broadcastAlertUpdate(open)
+			h.notifyAlert(open)
+			continue
+		}
+
+		if open != nil {
+			result.Deduplicated++
+			continue
+		}
+		alert := incoming.ToAlert(&webhook, now)
+		if err := alert.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.store.CreateAlert(alert); err != nil {
+			// Une livraison concurrente a pu créer l'alerte entre-temps (index unique sur le fingerprint ouvert)
+			if _, lookupErr := h.store.GetOpenAlertByFingerprint(alert.Fingerprint); lookupErr == nil {
+				result.Deduplicated++
+				continue
+			}
+			http.Error(w, fmt.Sprintf("Failed to create alert: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Created = append(result.Created, alert.ID)
+		if h.hub != nil {
+			h.hub.BroadcastAlert(alert)
+		}
+		h.notifyAlert(alert)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// notifyAlert planifie la notification d'une alerte aux abonnements
+func (h *Handlers) notifyAlert(alert *models.Alert) {
+	if h.notifier == nil {
+		return
+	}
+	if err := h.notifier.NotifyAlert(alert); err != nil {
+		log.Printf("⚠️  Failed to queue notifications for alert %d: %v", alert.ID, err)
+	}
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// currentUsername retourne le nom de l'utilisateur authentifié, enregistré comme auteur des changements d'état
+func currentUsername(r *http.Request) string {
+	if user, ok := middleware.GetCurrentUser(r); ok {
+		return user.Username
+	}
+	return ""
+}
+
+// alertStoreError écrit la réponse d'erreur d'une opération sur une alerte :
+// 404 si elle n'existe pas, 409 si son état ne permet pas l'action
+func alertStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Alert not found", http.StatusNotFound)
+	case errors.Is(err, models.ErrAlertTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// alertFromRequest lit l'alerte désignée par {id}. Écrit la réponse d'erreur et retourne false si absente.
+func (h *Handlers) alertFromRequest(w http.ResponseWriter, r *http.Request) (*models.Alert, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid alert ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("alert/%d", id))
+
+	alert, err := h.store.GetAlert(id)
+	if err != nil {
+		alertStoreError(w, err)
+		return nil, false
+	}
+	return alert, true
+}
+
+// markSilenced renseigne les silences actifs qui couvrent chaque alerte
+func (h *Handlers) markSilenced(alerts ...*models.Alert) error {
+	silences, err := h.store.GetSilences(true)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, alert := range alerts {
+		alert.SilencedBy = store.MatchingSilences(silences, alert, now)
+	}
+	return nil
+}
+
+// broadcastAlertUpdate diffuse le nouvel état d'une alerte sur le flux SSE
+func (h *Handlers) broadcastAlertUpdate(alert *models.Alert) {
+	if h.hub != nil {
+		h.hub.BroadcastAlertUpdate(alert)
+	}
+}
+
+// respondAlert relit une alerte après un changement d'état, le diffuse et la retourne
+func (h *Handlers) respondAlert(w http.ResponseWriter, id int) {
+	alert, err := h.store.GetAlert(id)
+	if err != nil {
+		alertStoreError(w, err)
+		return
+	}
+	if err := h.markSilenced(alert); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.broadcastAlertUpdate(alert)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alert)
+}
+
+// GetAlert retourne une alerte
+func (h *Handlers) GetAlert(w http.ResponseWriter, r *http.Request) {
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.markSilenced(alert); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alert)
+}
+
+// ResolveAlert résout manuellement une alerte ouverte (firing ou acknowledged → resolved).
+// Une alerte de règle dont la condition persiste est recréée à la collecte suivante.
+func (h *Handlers) ResolveAlert(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.resolve")
+
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.ResolveAlert(alert, currentUsername(r), time.Now()); err != nil {
+		alertStoreError(w, err)
+		return
+	}
+	h.respondAlert(w, alert.ID)
+}
+
+// SnoozeAlert suspend les notifications d'une alerte jusqu'à une date ({"until"}) ou pendant une durée ({"duration": "1h"})
+func (h *Handlers) SnoozeAlert(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.snooze")
+
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SnoozeAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	until := req.Until
+	if until == nil && req.Duration > 0 {
+		t := time.Now().Add(time.Duration(req.Duration))
+		until = &t
+	}
+	if until == nil || !until.After(time.Now()) {
+		http.Error(w, "Validation error: until must be in the future, or duration positive", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.SnoozeAlert(alert.ID, until); err != nil {
+		alertStoreError(w, err)
+		return
+	}
+	h.respondAlert(w, alert.ID)
+}
+
+// UnsnoozeAlert reprend les notifications d'une alerte suspendue
+func (h *Handlers) UnsnoozeAlert(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.unsnooze")
+
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.SnoozeAlert(alert.ID, nil); err != nil {
+		alertStoreError(w, err)
+		return
+	}
+	h.respondAlert(w, alert.ID)
+}
+
+// AssignAlert assigne une alerte à un utilisateur ({"user_id": null} pour la désassigner)
+func (h *Handlers) AssignAlert(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.assign")
+
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AssignAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.AssignAlert(alert.ID, req.UserID); err != nil {
+		if errors.Is(err, store.ErrAssigneeNotFound) {
+			http.Error(w, "Validation error: user not found or inactive", http.StatusBadRequest)
+			return
+		}
+		alertStoreError(w, err)
+		return
+	}
+	h.respondAlert(w, alert.ID)
+}
+
+// GetAlertComments retourne le fil de commentaires d'une alerte
+func (h *Handlers) GetAlertComments(w http.ResponseWriter, r *http.Request) {
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := h.store.GetAlertComments(alert.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// CreateAlertComment ajoute un commentaire au fil d'une alerte
+func (h *Handlers) CreateAlertComment(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert.comment")
+
+	alert, ok := h.alertFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		http.Error(w, "Validation error: body is required", http.StatusBadRequest)
+		return
+	}
+
+	comment := &models.AlertComment{AlertID: alert.ID, Body: body, CreatedAt: time.Now()}
+	if user, ok := middleware.GetCurrentUser(r); ok {
+		comment.UserID = &user.ID
+		comment.Username = user.Username
+	}
+	if err := h.store.CreateAlertComment(comment); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.hub != nil {
+		h.hub.BroadcastAlertComment(comment)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// GetSilences liste les silences (?active=true pour exclure les silences expirés)
+func (h *Handlers) GetSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := h.store.GetSilences(r.URL.Query().Get("active") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(silences)
+}
+
+// GetSilence retourne un silence
+func (h *Handlers) GetSilence(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid silence ID", http.StatusBadRequest)
+		return
+	}
+
+	silence, err := h.store.GetSilence(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Silence not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(silence)
+}
+
+// CreateSilence crée un silence : les alertes couvertes par ses matchers ne sont plus notifiées
+// jusqu'à ends_at (ou pendant duration)
+func (h *Handlers) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "silence.create")
+
+	var req models.CreateSilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	silence := &models.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  now,
+		Comment:   strings.TrimSpace(req.Comment),
+		CreatedBy: currentUsername(r),
+		CreatedAt: now,
+	}
+	if req.StartsAt != nil {
+		silence.StartsAt = *req.StartsAt
+	}
+	switch {
+	case req.EndsAt != nil:
+		silence.EndsAt = *req.EndsAt
+	case req.Duration > 0:
+		silence.EndsAt = silence.StartsAt.Add(time.Duration(req.Duration))
+	}
+	if err := silence.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !silence.EndsAt.After(now) {
+		http.Error(w, "Validation error: ends_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CreateSilence(silence); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("silence/%d", silence.ID))
+	if h.hub != nil {
+		h.hub.BroadcastSilence(silence)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(silence)
+}
+
+// ExpireSilence met fin à un silence immédiatement
+func (h *Handlers) ExpireSilence(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "silence.expire")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid silence ID", http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("silence/%d", id))
+
+	if err := h.store.ExpireSilence(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Silence not found or already expired", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if silence, err := h.store.GetSilence(id); err == nil && h.hub != nil {
+		h.hub.BroadcastSilence(silence)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// defaultAuditLimit est le nombre d'entrées retournées quand limit n'est pas précisé
+const defaultAuditLimit = 100
+
+// parseAuditQuery lit les filtres du journal d'audit depuis les paramètres de la requête
+func parseAuditQuery(r *http.Request) (models.AuditQuery, error) {
+	query := r.URL.Query()
+	q := models.AuditQuery{
+		Username: query.Get("user"),
+		Action:   query.Get("action"),
+		Target:   query.Get("target"),
+		Result:   query.Get("result"),
+		Limit:    defaultAuditLimit,
+	}
+
+	var err error
+	if q.From, err = parseMetricsTime(query.Get("from"), time.Time{}); err != nil {
+		return q, err
+	}
+	if q.To, err = parseMetricsTime(query.Get("to"), time.Time{}); err != nil {
+		return q, err
+	}
+	// Un export porte sur toute la période demandée
+	if query.Get("format") != "" {
+		q.Limit = models.MaxAuditQueryLimit
+	}
+	if value := query.Get("limit"); value != "" {
+		if q.Limit, err = strconv.Atoi(value); err != nil {
+			return q, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if q.Offset, err = strconv.Atoi(value); err != nil {
+			return q, fmt.Errorf("invalid offset %q", value)
+		}
+	}
+	return q, q.Validate()
+}
+
+// GetAuditLog retourne le journal d'audit filtré.
+// Paramètres: user, action (préfixe), target (sous-chaîne), result, from, to (RFC 3339 ou Unix),
+// limit, offset, format (csv ou json pour un export en pièce jointe).
+func (h *Handlers) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.store.QueryAuditLog(q)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get audit log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s", time.Now().Format("20060102-150405"))
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"entries": entries,
+			"total":   total,
+			"limit":   q.Limit,
+			"offset":  q.Offset,
+		})
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		json.NewEncoder(w).Encode(entries)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		writeAuditCSV(w, entries)
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported format %q: use csv or json", format), http.StatusBadRequest)
+	}
+}
+
+// writeAuditCSV écrit les entrées du journal d'audit au format CSV
+func writeAuditCSV(w http.ResponseWriter, entries []models.AuditEntry) {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"timestamp", "user_id", "username", "action", "target", "method", "path",
+		"ip_address", "status", "result", "upid", "duration_ms"})
+	for _, e := range entries {
+		userID := ""
+		if e.UserID != nil {
+			userID = strconv.Itoa(*e.UserID)
+		}
+		writer.Write([]string{
+			e.Timestamp.UTC().Format(time.RFC3339),
+			userID,
+			csvSafe(e.Username),
+			csvSafe(e.Action),
+			csvSafe(e.Target),
+			e.Method,
+			csvSafe(e.Path),
+			e.IPAddress,
+			strconv.Itoa(e.Status),
+			e.Result,
+			e.UPID,
+			strconv.FormatInt(e.DurationMs, 10),
+		})
+	}
+	writer.Flush()
+}
+
+// csvSafe neutralise les valeurs interprétées comme des formules par les tableurs
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
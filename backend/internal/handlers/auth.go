@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,6 +35,9 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	middleware.SetAuditAction(r, "auth.login")
+	middleware.SetAuditTarget(r, req.Username)
+
 	// Validation basique
 	if req.Username == "" || req.Password == "" {
 		h.writeError(w, http.StatusBadRequest, "Nom d'utilisateur et mot de passe requis", nil)
@@ -49,12 +54,15 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusUnauthorized, "Échec de l'authentification", err)
 		return
 	}
+	middleware.SetAuditUser(r, &response.User)
 
 	h.writeJSON(w, http.StatusOK, response)
 }
 
 // Logout déconnecte un utilisateur
 func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "auth.logout")
+
 	// Récupérer le token depuis le header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -104,11 +112,14 @@ func (h *AuthHandlers) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 // CreateUser crée un nouvel utilisateur (admin seulement)
 func (h *AuthHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "user.create")
+
 	var req models.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(w, http.StatusBadRequest, "Données utilisateur invalides", err)
 		return
 	}
+	middleware.SetAuditTarget(r, "user/"+req.Username)
 
 	// Validation basique
 	if req.Username == "" || req.Email == "" || req.Password == "" {
@@ -116,14 +127,19 @@ func (h *AuthHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !req.Role.IsValid() {
-		h.writeError(w, http.StatusBadRequest, "Rôle invalide", nil)
+	// Seul un administrateur crée un compte administrateur
+	if currentUser, ok := middleware.GetCurrentUser(r); !ok || (req.Role == models.RoleAdmin && currentUser.Role != models.RoleAdmin) {
+		h.writeError(w, http.StatusForbidden, "Seul un administrateur peut gérer un compte administrateur", nil)
 		return
 	}
 
 	// Créer l'utilisateur
 	user, err := h.authService.CreateUser(req)
 	if err != nil {
+		if errors.Is(err, auth.ErrUnknownRole) {
+			h.writeError(w, http.StatusBadRequest, "Rôle invalide", err)
+			return
+		}
 		h.writeError(w, http.StatusInternalServerError, "Erreur lors de la création de l'utilisateur", err)
 		return
 	}
@@ -152,6 +168,8 @@ func (h *AuthHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
 // UpdateUser met à jour un utilisateur
 func (h *AuthHandlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
+	middleware.SetAuditAction(r, "user.update")
+	middleware.SetAuditTarget(r, "user/"+idStr)
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		h.writeError(w, http.StatusBadRequest, "ID utilisateur invalide", err)
@@ -178,25 +196,99 @@ func (h *AuthHandlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Seul un admin peut modifier d'autres utilisateurs
+	// Seule la permission users:write permet de modifier d'autres utilisateurs
 	// Un utilisateur peut modifier son propre profil (sauf le rôle)
-	if currentUser.ID != targetUser.ID && currentUser.Role != models.RoleAdmin {
+	canManage := currentUser.HasPermission("users", "write")
+	self := currentUser.ID == targetUser.ID
+	if !self && !canManage {
 		h.writeError(w, http.StatusForbidden, "Permission insuffisante", nil)
 		return
 	}
 
-	// Un utilisateur non-admin ne peut pas changer son rôle
-	if currentUser.ID == targetUser.ID && currentUser.Role != models.RoleAdmin && req.Role != nil {
+	// Sans users:write, un utilisateur ne peut pas changer son rôle
+	if !canManage && req.Role != nil {
 		h.writeError(w, http.StatusForbidden, "Impossible de modifier son propre rôle", nil)
 		return
 	}
 
-	// TODO: Implémenter la mise à jour dans le service
-	h.writeJSON(w, http.StatusOK, map[string]string{"message": "Mise à jour non implémentée"})
+	// Seul un administrateur attribue le rôle admin ou modifie un compte administrateur :
+	// users:write ne doit pas permettre de s'élever au-dessus de son propre rôle
+	if currentUser.Role != models.RoleAdmin && (targetUser.Role == models.RoleAdmin || (req.Role != nil && *req.Role == models.RoleAdmin)) {
+		h.writeError(w, http.StatusForbidden, "Seul un administrateur peut gérer un compte administrateur", nil)
+		return
+	}
+
+	// Sans users:write, un utilisateur ne peut pas désactiver son propre compte
+	if !canManage && req.Active != nil {
+		h.writeError(w, http.StatusForbidden, "Impossible de modifier l'état de son propre compte", nil)
+		return
+	}
+
+	// Changer son propre mot de passe exige le mot de passe actuel, même avec users:write
+	if self && req.Password != nil {
+		if req.CurrentPassword == nil || *req.CurrentPassword == "" {
+			h.writeError(w, http.StatusBadRequest, "Mot de passe actuel requis", nil)
+			return
+		}
+		if err := h.authService.CheckPassword(currentUser.ID, *req.CurrentPassword); err != nil {
+			h.writeError(w, http.StatusBadRequest, "Mot de passe actuel incorrect", nil)
+			return
+		}
+	}
+
+	user, err := h.authService.UpdateUser(id, req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, auth.ErrLastAdmin) {
+			status = http.StatusConflict
+		}
+		h.writeError(w, status, "Erreur lors de la mise à jour de l'utilisateur", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser supprime un utilisateur (admin seulement)
+func (h *AuthHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	middleware.SetAuditAction(r, "user.delete")
+	middleware.SetAuditTarget(r, "user/"+idStr)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "ID utilisateur invalide", err)
+		return
+	}
+
+	currentUser, ok := middleware.GetCurrentUser(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Utilisateur non authentifié", nil)
+		return
+	}
+	if currentUser.ID == id {
+		h.writeError(w, http.StatusBadRequest, "Impossible de supprimer son propre compte", nil)
+		return
+	}
+
+	if err := h.authService.DeleteUser(id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "Utilisateur non trouvé", err)
+		case errors.Is(err, auth.ErrLastAdmin):
+			h.writeError(w, http.StatusConflict, "Erreur lors de la suppression de l'utilisateur", err)
+		default:
+			h.writeError(w, http.StatusInternalServerError, "Erreur lors de la suppression de l'utilisateur", err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // ChangePassword change le mot de passe d'un utilisateur
 func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "user.change-password")
+
 	var req models.ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeError(w, http.StatusBadRequest, "Données de changement de mot de passe invalides", err)
@@ -215,39 +307,111 @@ func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Implémenter le changement de mot de passe dans le service
-	_ = currentUser // Utiliser la variable pour éviter l'erreur de compilation
-	h.writeJSON(w, http.StatusOK, map[string]string{"message": "Changement de mot de passe non implémenté"})
+	// Les autres sessions sont révoquées, la session courante est conservée
+	if err := h.authService.ChangePassword(currentUser.ID, req, sessionToken(r)); err != nil {
+		if errors.Is(err, auth.ErrInvalidPassword) {
+			h.writeError(w, http.StatusBadRequest, "Mot de passe actuel incorrect", nil)
+			return
+		}
+		h.writeError(w, http.StatusInternalServerError, "Erreur lors du changement de mot de passe", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]string{"message": "Mot de passe modifié"})
 }
 
-// GetUserRoles retourne la liste des rôles disponibles
+// GetUserRoles retourne la liste des rôles disponibles (intégrés et personnalisés)
 func (h *AuthHandlers) GetUserRoles(w http.ResponseWriter, r *http.Request) {
-	roles := []map[string]interface{}{
-		{
-			"value":       models.RoleAdmin,
-			"label":       "Administrateur",
-			"description": "Accès complet à toutes les fonctionnalités",
-		},
-		{
-			"value":       models.RoleUser,
-			"label":       "Utilisateur",
-			"description": "Gestion des applications et alertes",
-		},
-		{
-			"value":       models.RoleViewer,
-			"label":       "Observateur",
-			"description": "Lecture seule des données",
-		},
-		{
-			"value":       models.RoleGuest,
-			"label":       "Invité",
-			"description": "Accès très limité",
-		},
+	roles, err := h.authService.ListRoles()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "Erreur lors de la récupération des rôles", err)
+		return
 	}
 
 	h.writeJSON(w, http.StatusOK, roles)
 }
 
+// GetPermissionCatalog retourne les permissions pouvant être attribuées aux rôles
+func (h *AuthHandlers) GetPermissionCatalog(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, models.PermissionCatalog)
+}
+
+// GetRole récupère un rôle par nom
+func (h *AuthHandlers) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := h.authService.GetRole(models.UserRole(chi.URLParam(r, "name")))
+	if err != nil {
+		h.writeRoleError(w, "Erreur lors de la récupération du rôle", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, role)
+}
+
+// CreateRole crée un rôle personnalisé (admin seulement)
+func (h *AuthHandlers) CreateRole(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "role.create")
+
+	var role models.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Données du rôle invalides", err)
+		return
+	}
+	middleware.SetAuditTarget(r, "role/"+string(role.Name))
+
+	created, err := h.authService.CreateRole(role)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Erreur lors de la création du rôle", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, created)
+}
+
+// UpdateRole met à jour la description et les permissions d'un rôle (admin seulement)
+func (h *AuthHandlers) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "role.update")
+	middleware.SetAuditTarget(r, "role/"+chi.URLParam(r, "name"))
+
+	var role models.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Données du rôle invalides", err)
+		return
+	}
+
+	updated, err := h.authService.UpdateRole(models.UserRole(chi.URLParam(r, "name")), role)
+	if err != nil {
+		h.writeRoleError(w, "Erreur lors de la mise à jour du rôle", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteRole supprime un rôle personnalisé (admin seulement)
+func (h *AuthHandlers) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "role.delete")
+	middleware.SetAuditTarget(r, "role/"+chi.URLParam(r, "name"))
+
+	if err := h.authService.DeleteRole(models.UserRole(chi.URLParam(r, "name"))); err != nil {
+		h.writeRoleError(w, "Erreur lors de la suppression du rôle", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRoleError écrit une erreur de gestion des rôles avec le code HTTP correspondant
+func (h *AuthHandlers) writeRoleError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUnknownRole):
+		h.writeError(w, http.StatusNotFound, "Rôle non trouvé", err)
+	case errors.Is(err, auth.ErrRoleProtected), errors.Is(err, auth.ErrRoleInUse):
+		h.writeError(w, http.StatusConflict, message, err)
+	default:
+		h.writeError(w, http.StatusBadRequest, message, err)
+	}
+}
+
 // GetUserPermissions retourne les permissions de l'utilisateur connecté
 func (h *AuthHandlers) GetUserPermissions(w http.ResponseWriter, r *http.Request) {
 	user, ok := middleware.GetCurrentUser(r)
@@ -256,10 +420,9 @@ func (h *AuthHandlers) GetUserPermissions(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	permissions := models.RolePermissions[user.Role]
 	h.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"role":        user.Role,
-		"permissions": permissions,
+		"permissions": user.Permissions,
 	})
 }
 
@@ -283,3 +446,12 @@ func (h *AuthHandlers) writeError(w http.ResponseWriter, status int, message str
 
 	h.writeJSON(w, status, response)
 }
+
+// sessionToken retourne le token de session de la requête (en-tête Bearer ou paramètre token),
+// comme l'accepte le middleware d'authentification
+func sessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultHealthChecksLimit est le nombre de health checks retournés par défaut par /apps/{id}/health
+const defaultHealthChecksLimit = 100
+
+// maxHealthChecksLimit borne le paramètre limit de /apps/{id}/health
+const maxHealthChecksLimit = 1000
+
+// appFromRequest lit l'application désignée par {id}. Écrit la réponse d'erreur et retourne false si absente.
+func (h *Handlers) appFromRequest(w http.ResponseWriter, r *http.Request) (*models.App, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid app ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("app/%d", id))
+
+	app, err := h.store.GetApp(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "App not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return app, true
+}
+
+// GetAppHealth retourne le dernier health check planifié d'une application et son historique récent
+// (?limit=, 100 par défaut)
+func (h *Handlers) GetAppHealth(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultHealthChecksLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxHealthChecksLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxHealthChecksLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	checks, err := h.store.GetHealthChecks(app.ID, time.Now().Add(-models.HealthCheckRetention), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get health checks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	health := &models.AppHealth{AppID: app.ID, IntervalSeconds: app.CheckInterval, Checks: checks}
+	if h.health != nil {
+		health.IntervalSeconds = int(h.health.Interval(app).Seconds())
+	}
+	if len(checks) > 0 {
+		health.Current = checks[0].HealthStatus()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// GetAppUptime retourne l'uptime et les percentiles de latence d'une application sur 24h, 7j et 30j
+func (h *Handlers) GetAppUptime(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	uptime, err := h.store.GetAppUptime(app.ID, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get app uptime: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uptime)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultMetricsRange est la période retournée quand from n'est pas précisé
+const defaultMetricsRange = time.Hour
+
+// parseMetricsTime lit un instant au format RFC 3339 ou en timestamp Unix (secondes)
+func parseMetricsTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a Unix timestamp", value)
+	}
+	return t, nil
+}
+
+// parseMetricsStep lit un pas en durée Go (5m, 1h) ou en secondes ; 0 si absent
+func parseMetricsStep(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	step, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid step %q: use a duration (5m, 1h) or seconds", value)
+	}
+	return step, nil
+}
+
+// GetMetrics retourne l'historique d'un nœud, d'un invité ou d'un storage.
+// Paramètres: from, to (RFC 3339 ou Unix), step (durée ou secondes), cluster (obligatoire
+// quand plusieurs connexions sont actives : les VMIDs et noms de nœuds peuvent se répéter).
+func (h *Handlers) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.metrics == nil {
+		http.Error(w, "Metrics history is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	to, err := parseMetricsTime(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseMetricsTime(query.Get("from"), to.Add(-defaultMetricsRange))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	step, err := parseMetricsStep(query.Get("step"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cluster := query.Get("cluster")
+	if cluster == "" {
+		conns, err := h.store.GetProxmoxConnections()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get proxmox connections: %v", err), storeErrorStatus(err))
+			return
+		}
+		if enabledConnections(conns) > 1 {
+			http.Error(w, "cluster is required when several Proxmox connections are enabled", http.StatusBadRequest)
+			return
+		}
+	}
+
+	series, err := h.metrics.Query(models.MetricsQuery{
+		Kind:     chi.URLParam(r, "kind"),
+		ObjectID: chi.URLParam(r, "id"),
+		Cluster:  cluster,
+		From:     from,
+		To:       to,
+		Step:     step,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidMetricsQuery) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("Failed to get metrics: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// BackfillMetrics importe l'historique rrddata de Proxmox pour les connexions actives.
+// Paramètres: timeframe (hour, day, week, month, year ; day par défaut), connection_id (optionnel).
+func (h *Handlers) BackfillMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.metrics == nil {
+		http.Error(w, "Metrics history is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	timeframe := r.URL.Query().Get("timeframe")
+	if timeframe == "" {
+		timeframe = "day"
+	}
+	if !services.IsRRDTimeframe(timeframe) {
+		http.Error(w, "timeframe must be one of hour, day, week, month, year", http.StatusBadRequest)
+		return
+	}
+	wanted, err := parseConnectionIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conns, err := h.store.GetProxmoxConnections()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get proxmox connections: %v", err), storeErrorStatus(err))
+		return
+	}
+
+	// L'import s'arrête avant le délai du routeur pour que les résultats partiels soient retournés
+	ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout-10*time.Second)
+	defer cancel()
+
+	results := []map[string]interface{}{}
+	total := 0
+	for _, cluster := range inventory.ClustersFromConnections(conns, wanted) {
+		result := map[string]interface{}{"connection_id": cluster.ConnectionID, "name": cluster.Name}
+		imported, err := h.metrics.Backfill(ctx, cluster, timeframe)
+		if err != nil {
+			result["success"] = false
+			result["error"] = proxmoxErrorMessage(err)
+		} else {
+			result["success"] = true
+			result["imported"] = imported
+			total += imported
+		}
+		results = append(results, result)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   fmt.Sprintf("%d point(s) d'historique importé(s)", total),
+		"timeframe": timeframe,
+		"clusters":  results,
+	})
+}
+
+// enabledConnections compte les connexions Proxmox actives
+func enabledConnections(conns []*models.ProxmoxConnection) int {
+	count := 0
+	for _, conn := range conns {
+		if conn.Enabled {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// notifyRouteFromRequest lit la route de notification désignée par {id}. Écrit la réponse d'erreur et retourne false si absente.
+func (h *Handlers) notifyRouteFromRequest(w http.ResponseWriter, r *http.Request) (*models.NotifyRoute, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("notify-route/%d", id))
+
+	route, err := h.store.GetNotifyRoute(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Notification route not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return route, true
+}
+
+// validateNotifyRoute valide une route et vérifie que ses abonnements existent
+func (h *Handlers) validateNotifyRoute(route *models.NotifyRoute) error {
+	if err := route.Validate(); err != nil {
+		return err
+	}
+	for _, id := range route.SubscriptionIDs {
+		if _, err := h.store.GetNotificationSubscription(id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("subscription %d not found", id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotifyRoutes liste les routes de notification dans leur ordre d'évaluation
+func (h *Handlers) GetNotifyRoutes(w http.ResponseWriter, r *http.Request) {
+	routes, err := h.store.GetNotifyRoutes(false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get notification routes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(routes)
+}
+
+// GetNotifyRoute retourne une route de notification
+func (h *Handlers) GetNotifyRoute(w http.ResponseWriter, r *http.Request) {
+	route, ok := h.notifyRouteFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// CreateNotifyRoute crée une route de notification (active par défaut)
+func (h *Handlers) CreateNotifyRoute(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "notify-route.create")
+
+	route := &models.NotifyRoute{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(route); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateNotifyRoute(route); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CreateNotifyRoute(route); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create notification route: %v", err), http.StatusInternalServerError)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("notify-route/%d", route.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(route)
+}
+
+// UpdateNotifyRoute met à jour une route de notification. Les champs absents du corps sont conservés.
+func (h *Handlers) UpdateNotifyRoute(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "notify-route.update")
+
+	route, ok := h.notifyRouteFromRequest(w, r)
+	if !ok {
+		return
+	}
+	id := route.ID
+	if err := json.NewDecoder(r.Body).Decode(route); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	route.ID = id
+	if err := h.validateNotifyRoute(route); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdateNotifyRoute(route); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Notification route not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to update notification route: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
+
+// DeleteNotifyRoute supprime une route de notification et ses groupes en attente
+func (h *Handlers) DeleteNotifyRoute(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "notify-route.delete")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid route ID", http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("notify-route/%d", id))
+
+	if err := h.store.DeleteNotifyRoute(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Notification route not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete notification route: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAlertGroups liste les groupes d'alertes en cours avec leur prochaine notification
+func (h *Handlers) GetAlertGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.store.GetAlertGroups()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get alert groups: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/prometheus"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// errNoDataSource est retourné quand aucune source de données Prometheus n'est configurée
+var errNoDataSource = errors.New("no Prometheus data source configured")
+
+// dataSourceFromRequest charge la source de données désignée par {id}. Écrit la réponse d'erreur et retourne false si absente.
+func (h *Handlers) dataSourceFromRequest(w http.ResponseWriter, r *http.Request) (*models.PrometheusDataSource, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid data source ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("prometheus-datasource/%d", id))
+
+	ds, err := h.store.GetPrometheusDataSource(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Prometheus data source not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return nil, false
+	}
+	return ds, true
+}
+
+// GetPrometheusDataSources liste les sources de données Prometheus (sans leurs secrets)
+func (h *Handlers) GetPrometheusDataSources(w http.ResponseWriter, r *http.Request) {
+	sources, err := h.store.GetPrometheusDataSources()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get prometheus data sources: %v", err), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sources)
+}
+
+// GetPrometheusDataSource retourne une source de données Prometheus
+func (h *Handlers) GetPrometheusDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.dataSourceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ds)
+}
+
+// CreatePrometheusDataSource enregistre une source de données Prometheus
+func (h *Handlers) CreatePrometheusDataSource(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "prometheus-datasource.create")
+
+	var req models.PrometheusDataSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	ds := &models.PrometheusDataSource{CreatedAt: now, UpdatedAt: now}
+	req.Apply(ds)
+	if err := ds.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CreatePrometheusDataSource(ds); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create prometheus data source: %v", err), storeErrorStatus(err))
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("prometheus-datasource/%d", ds.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ds)
+}
+
+// UpdatePrometheusDataSource met à jour une source de données Prometheus.
+// Le secret absent de la requête est conservé.
+func (h *Handlers) UpdatePrometheusDataSource(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "prometheus-datasource.update")
+
+	ds, ok := h.dataSourceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PrometheusDataSourceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Apply(ds)
+	ds.UpdatedAt = time.Now()
+	if err := ds.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdatePrometheusDataSource(ds); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update prometheus data source: %v", err), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ds)
+}
+
+// DeletePrometheusDataSource supprime une source de données Prometheus
+func (h *Handlers) DeletePrometheusDataSource(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "prometheus-datasource.delete")
+
+	ds, ok := h.dataSourceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeletePrometheusDataSource(ds.ID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete prometheus data source: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestPrometheusDataSource vérifie qu'une source de données répond à une requête PromQL
+func (h *Handlers) TestPrometheusDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.dataSourceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	path, _ := prometheus.EndpointQuery.Path("")
+	resp, err := prometheus.NewClient(ds).Get(r.Context(), path, url.Values{"query": {"vector(1)"}})
+	switch {
+	case err != nil:
+		respondJSON(w, http.StatusOK, map[string]interface{}{"success": false, "message": err.Error()})
+	case resp.StatusCode != http.StatusOK:
+		respondJSON(w, http.StatusOK, map[string]interface{}{"success": false, "message": fmt.Sprintf("Prometheus a répondu HTTP %d", resp.StatusCode)})
+	default:
+		respondJSON(w, http.StatusOK, map[string]interface{}{"success": true, "message": "Prometheus est joignable"})
+	}
+}
+
+// prometheusError écrit une erreur au format de l'API Prometheus, lisible par le frontend comme une réponse relayée
+func prometheusError(w http.ResponseWriter, status int, errorType string, err error) {
+	respondJSON(w, status, map[string]interface{}{
+		"status":    "error",
+		"errorType": errorType,
+		"error":     err.Error(),
+	})
+}
+
+// resolveDataSource retourne la source de données du paramètre datasource (ID), à défaut la source par défaut
+func (h *Handlers) resolveDataSource(value string) (*models.PrometheusDataSource, error) {
+	if value == "" {
+		ds, err := h.store.GetDefaultPrometheusDataSource()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNoDataSource
+		}
+		return ds, err
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, sql.ErrNoRows
+	}
+	return h.store.GetPrometheusDataSource(id)
+}
+
+// proxyPrometheus relaie une requête à l'API HTTP de Prometheus. Seules les sources de données configurées
+// côté serveur peuvent être interrogées ; les paramètres autorisés de l'endpoint sont réencodés.
+// La réponse de Prometheus (y compris ses erreurs PromQL) est renvoyée telle quelle.
+func (h *Handlers) proxyPrometheus(w http.ResponseWriter, r *http.Request, endpoint prometheus.Endpoint) {
+	query := r.URL.Query()
+	if query.Get("base_url") != "" {
+		prometheusError(w, http.StatusBadRequest, "bad_data",
+			errors.New("base_url is not supported: configure a data source in /api/v1/prometheus/datasources"))
+		return
+	}
+	path, err := endpoint.Path(chi.URLParam(r, "name"))
+	if err != nil {
+		prometheusError(w, http.StatusBadRequest, "bad_data", err)
+		return
+	}
+	params, err := endpoint.Params(query)
+	if err != nil {
+		prometheusError(w, http.StatusBadRequest, "bad_data", err)
+		return
+	}
+
+	var resp *prometheus.Response
+	if h.prometheusMock {
+		if resp, err = prometheus.Mock(endpoint, params, time.Now()); err != nil {
+			prometheusError(w, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+	} else {
+		ds, err := h.resolveDataSource(query.Get("datasource"))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			prometheusError(w, http.StatusNotFound, "not_found", fmt.Errorf("Prometheus data source %q not found", query.Get("datasource")))
+			return
+		case errors.Is(err, errNoDataSource):
+			prometheusError(w, http.StatusServiceUnavailable, "unavailable", err)
+			return
+		case err != nil:
+			prometheusError(w, storeErrorStatus(err), "internal", err)
+			return
+		}
+		if resp, err = prometheus.NewClient(ds).Get(r.Context(), path, params); err != nil {
+			log.Printf("❌ Erreur lors de la requête Prometheus vers %s (%s): %v", ds.Name, endpoint.Name, err)
+			prometheusError(w, http.StatusBadGateway, "unavailable", fmt.Errorf("failed to query Prometheus: %w", err))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// QueryPrometheus exécute une requête PromQL instantanée (query, time)
+func (h *Handlers) QueryPrometheus(w http.ResponseWriter, r *http.Request) {
+	h.proxyPrometheus(w, r, prometheus.EndpointQuery)
+}
+
+// QueryRangePrometheus exécute une requête PromQL sur une période (query, start, end, step)
+func (h *Handlers) QueryRangePrometheus(w http.ResponseWriter, r *http.Request) {
+	h.proxyPrometheus(w, r, prometheus.EndpointQueryRange)
+}
+
+// GetPrometheusSeries liste les séries correspondant aux sélecteurs match[]
+func (h *Handlers) GetPrometheusSeries(w http.ResponseWriter, r *http.Request) {
+	h.proxyPrometheus(w, r, prometheus.EndpointSeries)
+}
+
+// GetPrometheusLabels liste les noms de labels
+func (h *Handlers) GetPrometheusLabels(w http.ResponseWriter, r *http.Request) {
+	h.proxyPrometheus(w, r, prometheus.EndpointLabels)
+}
+
+// GetPrometheusLabelValues liste les valeurs du label {name}
+func (h *Handlers) GetPrometheusLabelValues(w http.ResponseWriter, r *http.Request) {
+	h.proxyPrometheus(w, r, prometheus.EndpointLabelValues)
+}
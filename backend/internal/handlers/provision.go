@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limites de la liste des jobs de provisionnement
+const (
+	defaultProvisionJobsLimit = 50
+	maxProvisionJobsLimit     = 500
+)
+
+// ProvisionRequest représente une demande de provisionnement : identifiants Proxmox et description du clone
+type ProvisionRequest struct {
+	proxmoxCredentials
+	models.ProvisionSpec
+}
+
+// SetProvisioner configure le service qui exécute les jobs de provisionnement
+func (h *Handlers) SetProvisioner(provisioner *services.Provisioner) {
+	h.provisioner = provisioner
+}
+
+// ListProvisionTemplates liste les templates de VMs et de conteneurs de chaque nœud
+// que l'utilisateur peut cloner (permission clone et portée nœud, pool, tag)
+func (h *Handlers) ListProvisionTemplates(w http.ResponseWriter, r *http.Request) {
+	creds, ok := h.decodeProxmoxCredentials(w, r)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetCurrentUser(r)
+
+	resources, err := creds.client().ClusterResources(r.Context(), "vm")
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	templates := []models.ProvisionTemplate{}
+	for _, res := range resources {
+		if !bool(res.Template) || !res.IsGuest() {
+			continue
+		}
+		guestType := proxmox.GuestQemu
+		if res.Type == "lxc" {
+			guestType = proxmox.GuestLXC
+		}
+		tags := splitTags(res.Tags)
+		target := models.PermissionTarget{Node: res.Node, Pool: res.Pool, Tags: tags}
+		if user == nil || !user.Can(guestResource(guestType), "clone", target) {
+			continue
+		}
+		templates = append(templates, models.ProvisionTemplate{
+			Node:    res.Node,
+			Type:    string(guestType),
+			VMID:    int(res.VMID),
+			Name:    res.Name,
+			Pool:    res.Pool,
+			Tags:    tags,
+			MaxMem:  res.MaxMem,
+			MaxDisk: res.MaxDisk,
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Node != templates[j].Node {
+			return templates[i].Node < templates[j].Node
+		}
+		return templates[i].VMID < templates[j].VMID
+	})
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    templates,
+	})
+}
+
+// CreateProvisionJob lance le clonage d'un template en arrière-plan et retourne le job (202).
+// L'avancement est consultable sur /provision/jobs/{id} et diffusé sur /provision/jobs/{id}/events.
+func (h *Handlers) CreateProvisionJob(w http.ResponseWriter, r *http.Request) {
+	var req ProvisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+	guestType := proxmox.GuestType(req.GuestType)
+	auditPrefix := "vm."
+	if guestType == proxmox.GuestLXC {
+		auditPrefix = "lxc."
+	}
+	middleware.SetAuditAction(r, auditPrefix+"clone")
+	middleware.SetAuditTarget(r, guestAuditTarget(req.Node, guestType, req.TemplateVMID))
+
+	if h.provisioner == nil {
+		respondJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"success": false,
+			"error":   "Provisioning is not available",
+		})
+		return
+	}
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+	if !req.valid() {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id est requis",
+		})
+		return
+	}
+	if err := req.ProvisionSpec.Validate(); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+
+	client := req.client()
+	resource := guestResource(guestType)
+	if !authorizeGuest(w, r, client, resource, "clone", req.Node, req.TemplateVMID) {
+		return
+	}
+
+	// La portée doit aussi couvrir l'invité créé (nœud cible, pool, tags hérités du template),
+	// et son démarrage exige la permission power
+	destination, ok := provisionDestination(w, r, client, resource, req.ProvisionSpec)
+	if !ok {
+		return
+	}
+	targets := map[int]models.PermissionTarget{req.VMID: destination}
+	if !authorizeTargets(w, r, resource, "clone", targets) {
+		return
+	}
+	if req.Start && !authorizeTargets(w, r, resource, "power", targets) {
+		return
+	}
+
+	var connectionID, createdBy *int
+	if req.ConnectionID != 0 {
+		connectionID = &req.ConnectionID
+	}
+	if user, ok := middleware.GetCurrentUser(r); ok {
+		createdBy = &user.ID
+	}
+
+	job, err := h.provisioner.Submit(client, req.ProvisionSpec, connectionID, createdBy)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("🧬 Provisionnement %d: clone de %s/%d vers %s (%s)\n", job.ID, req.Node, req.TemplateVMID, req.Target(), req.Name)
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// provisionDestination retourne la portée de l'invité créé par un clone : nœud cible, pool demandé et tags
+// du template (copiés par le clone). Les tags ne sont lus dans Proxmox que pour une permission à portée.
+func provisionDestination(w http.ResponseWriter, r *http.Request, client *proxmox.Client, resource string, spec models.ProvisionSpec) (models.PermissionTarget, bool) {
+	destination := models.PermissionTarget{Node: spec.Target(), Pool: spec.Pool}
+	if user, ok := middleware.GetCurrentUser(r); ok && user.Can(resource, "clone", models.PermissionTarget{}) {
+		return destination, true
+	}
+	template, err := guestTarget(r, client, spec.Node, spec.TemplateVMID)
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return destination, false
+	}
+	destination.Tags = template.Tags
+	return destination, true
+}
+
+// provisionJobVisible indique si l'utilisateur peut consulter un job : il l'a créé,
+// ou la permission clone de son type d'invité couvre la destination du job
+func provisionJobVisible(user *models.User, job *models.ProvisionJob) bool {
+	if user == nil {
+		return false
+	}
+	if job.CreatedBy != nil && *job.CreatedBy == user.ID {
+		return true
+	}
+	destination := models.PermissionTarget{Node: job.Spec.Target(), Pool: job.Spec.Pool}
+	return user.Can(guestResource(proxmox.GuestType(job.Spec.GuestType)), "clone", destination)
+}
+
+// GetProvisionJobs liste les derniers jobs de provisionnement visibles par l'utilisateur (?limit=, 50 par défaut)
+func (h *Handlers) GetProvisionJobs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultProvisionJobsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxProvisionJobsLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxProvisionJobsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	all, err := h.store.GetProvisionJobs(limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get provision jobs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	user, _ := middleware.GetCurrentUser(r)
+	jobs := make([]*models.ProvisionJob, 0, len(all))
+	for _, job := range all {
+		if provisionJobVisible(user, job) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// provisionJobFromRequest récupère le job désigné par {id} et écrit l'erreur HTTP le cas échéant.
+// Un job que l'utilisateur ne peut pas consulter est traité comme inexistant.
+func (h *Handlers) provisionJobFromRequest(w http.ResponseWriter, r *http.Request) (*models.ProvisionJob, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := h.store.GetProvisionJob(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Provision job not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if user, _ := middleware.GetCurrentUser(r); !provisionJobVisible(user, job) {
+		http.Error(w, "Provision job not found", http.StatusNotFound)
+		return nil, false
+	}
+	return job, true
+}
+
+// GetProvisionJob retourne l'état d'un job de provisionnement
+func (h *Handlers) GetProvisionJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.provisionJobFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// StreamProvisionJob diffuse l'avancement d'un job en Server-Sent Events (événement "job" à chaque étape)
+// jusqu'à sa fin. L'authentification accepte le token en paramètre ?token= (EventSource).
+func (h *Handlers) StreamProvisionJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.provisionJobFromRequest(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(job *models.ProvisionJob) bool {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: job\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	// S'abonner avant de relire l'état : aucune mise à jour ne peut être manquée entre les deux
+	var updates <-chan models.ProvisionJob
+	if h.provisioner != nil && !job.Finished() {
+		ch, unsubscribe := h.provisioner.Subscribe(job.ID)
+		defer unsubscribe()
+		updates = ch
+		if current, err := h.store.GetProvisionJob(job.ID); err == nil {
+			job = current
+		}
+	}
+	if !send(job) || job.Finished() || updates == nil {
+		return
+	}
+
+	for {
+		select {
+		case update, open := <-updates:
+			if !open {
+				// Fin du job : l'état final enregistré fait foi (une mise à jour a pu être ignorée)
+				if final, err := h.store.GetProvisionJob(job.ID); err == nil {
+					send(final)
+				}
+				return
+			}
+			if !send(&update) {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,697 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// proxmoxCredentials représente la connexion Proxmox référencée par le frontend (connection_id).
+// L'URL et les secrets viennent uniquement de la connexion enregistrée côté serveur : des identifiants
+// bruts dans le corps de la requête sont ignorés.
+type proxmoxCredentials struct {
+	ConnectionID int    `json:"connection_id"`
+	URL          string `json:"-"`
+	Username     string `json:"-"`
+	Secret       string `json:"-"`
+	password     string // mot de passe enregistré avec la connexion (tickets de session)
+}
+
+var (
+	// errConnectionRequired est retourné quand la requête ne référence aucune connexion enregistrée
+	errConnectionRequired = errors.New("connection_id is required: register the Proxmox connection in the settings")
+	// errConnectionDisabled est retourné quand la connexion référencée est désactivée
+	errConnectionDisabled = errors.New("proxmox connection is disabled")
+)
+
+// resolveProxmoxCredentials charge les identifiants de la connexion enregistrée désignée par connection_id
+func (h *Handlers) resolveProxmoxCredentials(creds *proxmoxCredentials) error {
+	if creds.ConnectionID == 0 {
+		return errConnectionRequired
+	}
+
+	conn, err := h.store.GetProxmoxConnection(creds.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if !conn.Enabled {
+		return errConnectionDisabled
+	}
+
+	creds.URL = conn.URL
+	creds.Username = conn.Username
+	creds.Secret = conn.Secret
+	creds.password = conn.Password
+	return nil
+}
+
+// connectionErrorStatus retourne le code HTTP associé à une erreur de résolution de connexion
+func connectionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errConnectionRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, errConnectionDisabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// connectionErrorMessage retourne le message associé à une erreur de résolution de connexion
+func connectionErrorMessage(err error) string {
+	if errors.Is(err, sql.ErrNoRows) {
+		return "Proxmox connection not found"
+	}
+	return err.Error()
+}
+
+// valid indique si les champs requis sont présents
+func (c proxmoxCredentials) valid() bool {
+	return c.URL != "" && c.Username != "" && c.Secret != ""
+}
+
+// client crée un client Proxmox à partir des identifiants
+func (c proxmoxCredentials) client() *proxmox.Client {
+	return proxmox.NewClient(c.URL, c.Username, c.Secret)
+}
+
+// respondJSON écrit une réponse JSON avec le code HTTP donné
+func respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// proxmoxErrorMessage traduit une erreur du client Proxmox en message lisible pour l'utilisateur
+func proxmoxErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, proxmox.ErrAuthFailed):
+		return "Erreur d'authentification Proxmox: Vérifiez vos credentials (utilisateur et secret)"
+	case errors.Is(err, proxmox.ErrPermissionDenied):
+		return "Permissions Proxmox insuffisantes: l'utilisateur doit disposer du rôle PVEAuditor (VM.Audit, Datastore.Audit)"
+	case errors.Is(err, proxmox.ErrUnreachable):
+		return "Impossible de se connecter au serveur Proxmox. Vérifiez l'URL et que le serveur est accessible."
+	default:
+		return err.Error()
+	}
+}
+
+// defaultDevProxmoxHosts sont les hôtes fictifs reconnus sans configuration (voir SetDevProxmoxHosts)
+var defaultDevProxmoxHosts = []string{"example.com", "proxmox-dev.local"}
+
+// isDevProxmoxURL détecte les URLs de développement fictives : l'hôte de l'URL est l'un des
+// hôtes configurés ou l'un de leurs sous-domaines. Un Proxmox local (localhost, tunnel SSH)
+// n'est écarté que si son hôte est configuré.
+func (h *Handlers) isDevProxmoxURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, dev := range h.devProxmoxHosts {
+		dev = strings.ToLower(dev)
+		if host == dev || strings.HasSuffix(host, "."+dev) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchProxmoxData récupère les données depuis Proxmox
+func (h *Handlers) FetchProxmoxData(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("🔍 FetchProxmoxData called")
+
+	// Vérifier que la méthode est POST
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		proxmoxCredentials
+		Node string `json:"node"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Printf("❌ JSON decode error: %v\n", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		http.Error(w, connectionErrorMessage(err), connectionErrorStatus(err))
+		return
+	}
+
+	fmt.Printf("📊 Config received: URL=%s, Username=%s, Node=%s\n", req.URL, req.Username, req.Node)
+
+	if !req.valid() {
+		fmt.Println("❌ Missing required fields")
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	if h.isDevProxmoxURL(req.URL) {
+		fmt.Println("⚠️ URL de développement détectée, retour de données fictives")
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  false,
+			"message":  "URL de développement détectée. Veuillez configurer une URL Proxmox réelle dans les Paramètres.",
+			"nodes":    []models.ProxmoxNode{},
+			"vms":      []models.ProxmoxGuest{},
+			"lxc":      []models.ProxmoxGuest{},
+			"storages": []models.ProxmoxStorage{},
+			"networks": []models.ProxmoxNetwork{},
+		})
+		return
+	}
+
+	ctx := r.Context()
+	client := req.client()
+
+	// Les nœuds sont indispensables : sans eux, on renvoie une erreur
+	inv, _, err := inventory.Collect(ctx, client, req.Node)
+	if err != nil {
+		fmt.Printf("❌ Failed to fetch nodes: %v\n", err)
+		message := fmt.Sprintf("Erreur lors de la récupération des nœuds: %v", err)
+		if errors.Is(err, proxmox.ErrAuthFailed) || errors.Is(err, proxmox.ErrUnreachable) || errors.Is(err, proxmox.ErrPermissionDenied) {
+			message = proxmoxErrorMessage(err)
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"message": message,
+			"nodes":   []models.ProxmoxNode{},
+			"vms":     []models.ProxmoxGuest{},
+			"lxc":     []models.ProxmoxGuest{},
+		})
+		return
+	}
+	fmt.Printf("✅ Nodes fetched: %d nodes\n", len(inv.Nodes))
+
+	// Vérifier si des données sont manquantes et ajouter un message d'avertissement
+	message := "Proxmox data fetched successfully"
+	if len(inv.VMs) == 0 && len(inv.LXC) == 0 && len(inv.Storages) == 0 {
+		message = "Proxmox data fetched, but no VMs, LXC, or storages found. This is likely a PERMISSIONS issue. Please check that the Proxmox user has the necessary permissions (VM.Audit, Datastore.Audit, or PVEAuditor role)."
+		fmt.Printf("⚠️ WARNING: %s\n", message)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"nodes":    inv.Nodes,
+		"vms":      inv.VMs,
+		"lxc":      inv.LXC,
+		"storages": inv.Storages,
+		"networks": inv.Networks,
+		"message":  message,
+	})
+}
+
+// decodeProxmoxCredentials décode les identifiants d'une requête de liste et écrit l'erreur HTTP le cas échéant
+func (h *Handlers) decodeProxmoxCredentials(w http.ResponseWriter, r *http.Request) (proxmoxCredentials, bool) {
+	var creds proxmoxCredentials
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return creds, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return creds, false
+	}
+	if err := h.resolveProxmoxCredentials(&creds); err != nil {
+		http.Error(w, connectionErrorMessage(err), connectionErrorStatus(err))
+		return creds, false
+	}
+	if !creds.valid() {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return creds, false
+	}
+	return creds, true
+}
+
+// FetchProxmoxBackups récupère les backups depuis Proxmox
+func (h *Handlers) FetchProxmoxBackups(w http.ResponseWriter, r *http.Request) {
+	creds, ok := h.decodeProxmoxCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	backups, failed, err := inventory.FetchBackups(r.Context(), creds.client(), inventory.DefaultContentConcurrency)
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("Erreur lors de la récupération des backups: %s", proxmoxErrorMessage(err)),
+			"backups": []models.ProxmoxBackup{},
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"backups": backups,
+	}
+	// Les archives des storages illisibles manquent à la liste
+	if len(failed) > 0 {
+		response["failed_storages"] = failed
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// FetchProxmoxTasks récupère les tâches depuis Proxmox
+func (h *Handlers) FetchProxmoxTasks(w http.ResponseWriter, r *http.Request) {
+	creds, ok := h.decodeProxmoxCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := inventory.FetchTasks(r.Context(), creds.client())
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("Erreur lors de la récupération des tâches: %s", proxmoxErrorMessage(err)),
+			"tasks":   []models.ProxmoxTask{},
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"tasks":   tasks,
+	})
+}
+
+// FetchProxmoxDocker récupère les conteneurs Docker depuis Proxmox
+func (h *Handlers) FetchProxmoxDocker(w http.ResponseWriter, r *http.Request) {
+	creds, ok := h.decodeProxmoxCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	containers, err := inventory.FetchDocker(r.Context(), creds.client())
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success":    false,
+			"message":    fmt.Sprintf("Erreur lors de la récupération des conteneurs Docker: %s", proxmoxErrorMessage(err)),
+			"containers": []map[string]interface{}{},
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"containers": containers,
+	})
+}
+
+// FetchProxmoxDatabases récupère les bases de données depuis Proxmox
+func (h *Handlers) FetchProxmoxDatabases(w http.ResponseWriter, r *http.Request) {
+	creds, ok := h.decodeProxmoxCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	databases, err := inventory.FetchDatabases(r.Context(), creds.client())
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success":   false,
+			"message":   fmt.Sprintf("Erreur lors de la récupération des bases de données: %s", proxmoxErrorMessage(err)),
+			"databases": []map[string]interface{}{},
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"databases": databases,
+	})
+}
+
+// FetchProxmoxNetworks récupère les interfaces réseau depuis Proxmox
+func (h *Handlers) FetchProxmoxNetworks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		proxmoxCredentials
+		Node string `json:"node"` // Optionnel, si vide récupère de tous les nœuds
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		http.Error(w, connectionErrorMessage(err), connectionErrorStatus(err))
+		return
+	}
+	if !req.valid() {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	networks, err := inventory.FetchNetworks(r.Context(), req.client(), req.Node)
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  false,
+			"message":  fmt.Sprintf("Erreur lors de la récupération des interfaces réseau: %s", proxmoxErrorMessage(err)),
+			"networks": []models.ProxmoxNetwork{},
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"networks": networks,
+	})
+}
+
+// VMActionRequest représente une requête pour une action sur une VM ou un conteneur
+type VMActionRequest struct {
+	proxmoxCredentials
+	Node      string `json:"node"`
+	VMID      int    `json:"vmid"`
+	Timeout   int    `json:"timeout,omitempty"`    // shutdown : délai en secondes avant abandon
+	ForceStop bool   `json:"force_stop,omitempty"` // shutdown : arrêt forcé à l'expiration du délai
+}
+
+// guestPowerAction décrit l'appel Proxmox correspondant à une action du dashboard
+type guestPowerAction struct {
+	status string     // action de nodes/{node}/{type}/{vmid}/status/{status}
+	params url.Values // paramètres fixes de l'action
+	lxc    bool       // disponible pour les conteneurs
+}
+
+// vmActions associe les actions du dashboard aux actions de l'API Proxmox
+var vmActions = map[string]guestPowerAction{
+	"start":     {status: "start", lxc: true},
+	"stop":      {status: "stop", lxc: true},
+	"shutdown":  {status: "shutdown", lxc: true},
+	"restart":   {status: "reboot", lxc: true},
+	"reboot":    {status: "reboot", lxc: true},
+	"pause":     {status: "suspend", lxc: true},
+	"suspend":   {status: "suspend", lxc: true},
+	"resume":    {status: "resume", lxc: true},
+	"reset":     {status: "reset"},
+	"hibernate": {status: "suspend", params: url.Values{"todisk": {"1"}}},
+}
+
+// maxShutdownTimeout borne le délai d'un arrêt propre (secondes)
+const maxShutdownTimeout = 3600
+
+// VMAction gère les actions sur les VMs (start, stop, shutdown, restart, pause, resume, reset, hibernate)
+func (h *Handlers) VMAction(w http.ResponseWriter, r *http.Request) {
+	h.guestAction(w, r, proxmox.GuestQemu)
+}
+
+// LXCAction gère les actions sur les conteneurs (start, stop, shutdown, restart, pause, resume)
+func (h *Handlers) LXCAction(w http.ResponseWriter, r *http.Request) {
+	h.guestAction(w, r, proxmox.GuestLXC)
+}
+
+// guestAction exécute une action d'alimentation sur un invité et retourne l'UPID de la tâche Proxmox
+func (h *Handlers) guestAction(w http.ResponseWriter, r *http.Request, guestType proxmox.GuestType) {
+	action := chi.URLParam(r, "action")
+	if action == "" {
+		fmt.Printf("❌ GuestAction: Action manquante\n")
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Action manquante",
+		})
+		return
+	}
+
+	var req VMActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Printf("❌ GuestAction: Erreur de décodage JSON: %v\n", err)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+
+	if !req.valid() || req.Node == "" || req.VMID == 0 {
+		fmt.Printf("❌ GuestAction: Champs manquants - URL: %s, Username: %s, Node: %s, VMID: %d\n",
+			req.URL, req.Username, req.Node, req.VMID)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id, node et vmid sont requis",
+		})
+		return
+	}
+
+	powerAction, ok := vmActions[action]
+	if !ok || (guestType == proxmox.GuestLXC && !powerAction.lxc) {
+		fmt.Printf("❌ GuestAction: Action non supportée pour %s: %s\n", guestType, action)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Action non supportée pour %s: %s", guestType, action),
+		})
+		return
+	}
+	if req.Timeout < 0 || req.Timeout > maxShutdownTimeout {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("timeout doit être compris entre 0 et %d secondes", maxShutdownTimeout),
+		})
+		return
+	}
+
+	auditPrefix := "vm."
+	if guestType == proxmox.GuestLXC {
+		auditPrefix = "lxc."
+	}
+	middleware.SetAuditAction(r, auditPrefix+action)
+	middleware.SetAuditTarget(r, guestAuditTarget(req.Node, guestType, req.VMID))
+
+	client := req.client()
+	if !authorizeGuest(w, r, client, guestResource(guestType), "power", req.Node, req.VMID) {
+		return
+	}
+
+	params := url.Values{}
+	for key, values := range powerAction.params {
+		params[key] = values
+	}
+	if powerAction.status == "shutdown" {
+		if req.Timeout > 0 {
+			params.Set("timeout", strconv.Itoa(req.Timeout))
+		}
+		if req.ForceStop {
+			params.Set("forceStop", "1")
+		}
+	}
+
+	fmt.Printf("🔧 %s Action: %s on %d (node: %s)\n", guestType, action, req.VMID, req.Node)
+
+	upid, err := client.GuestStatusAction(r.Context(), req.Node, guestType, req.VMID, powerAction.status, params)
+	if err != nil {
+		fmt.Printf("❌ %s Action failed: %v\n", guestType, err)
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	middleware.SetAuditUPID(r, upid)
+	fmt.Printf("✅ %s Action %s successful for %d (UPID: %s)\n", guestType, action, req.VMID, upid)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%s action %s executed successfully", guestType, action),
+		"data":    upid,
+		"upid":    upid,
+	})
+}
+
+// TaskWaitRequest représente une requête d'attente de fin de tâche Proxmox
+type TaskWaitRequest struct {
+	proxmoxCredentials
+	UPID    string `json:"upid"`
+	Timeout int    `json:"timeout,omitempty"` // secondes, defaultTaskWaitTimeout par défaut
+}
+
+// RequestTimeout est le délai au-delà duquel le routeur interrompt une requête (hors flux SSE)
+const RequestTimeout = 60 * time.Second
+
+// Délais de l'attente de fin de tâche. L'attente maximale reste sous RequestTimeout pour que la réponse
+// (finished à false) parte avant que le routeur n'interrompe la requête ; le client relance alors l'attente.
+const (
+	defaultTaskWaitTimeout = 30
+	maxTaskWaitTimeout     = int(RequestTimeout/time.Second) - 10
+	taskPollInterval       = time.Second
+)
+
+// WaitProxmoxTask attend la fin d'une tâche Proxmox (UPID retourné par une action) et retourne son statut.
+// Si la tâche n'est pas terminée à l'expiration du délai, finished vaut false et le client peut relancer l'attente.
+func (h *Handlers) WaitProxmoxTask(w http.ResponseWriter, r *http.Request) {
+	var req TaskWaitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+
+	upid, err := proxmox.ParseUPID(req.UPID)
+	if !req.valid() || err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id et un upid valide sont requis",
+		})
+		return
+	}
+	if req.Timeout == 0 {
+		req.Timeout = defaultTaskWaitTimeout
+	}
+	if req.Timeout < 0 || req.Timeout > maxTaskWaitTimeout {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("timeout doit être compris entre 1 et %d secondes", maxTaskWaitTimeout),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	status, err := req.client().WaitTask(ctx, upid.Node, req.UPID, taskPollInterval)
+	if err != nil && (status == nil || !errors.Is(err, context.DeadlineExceeded)) {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"finished":   !status.Running(),
+		"ok":         status.Succeeded(),
+		"exitstatus": status.ExitStatus,
+		"data":       status,
+	})
+}
+
+// TestProxmoxPasswordRequest représente une requête pour tester le mot de passe Proxmox
+type TestProxmoxPasswordRequest struct {
+	proxmoxCredentials
+	Password string `json:"password"` // Mot de passe à tester
+}
+
+// usernameWithRealm normalise un identifiant Proxmox au format user@realm.
+// Les identifiants de token (user@realm!tokenid) sont ramenés à l'utilisateur, le realm par défaut est pam.
+func usernameWithRealm(username string) string {
+	if i := strings.Index(username, "!"); i >= 0 {
+		username = username[:i]
+	}
+	if !strings.Contains(username, "@") {
+		username += "@pam"
+	}
+	return username
+}
+
+// TestProxmoxPassword teste si le mot de passe fonctionne avec Proxmox
+func (h *Handlers) TestProxmoxPassword(w http.ResponseWriter, r *http.Request) {
+	var req TestProxmoxPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Printf("❌ TestProxmoxPassword: Erreur de décodage JSON: %v\n", err)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+
+	// Sans mot de passe dans la requête, on teste celui enregistré avec la connexion
+	if req.Password == "" {
+		req.Password = req.password
+	}
+	if req.Password == "" {
+		fmt.Printf("❌ TestProxmoxPassword: Champs manquants\n")
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: password est requis (aucun mot de passe enregistré avec la connexion)",
+		})
+		return
+	}
+
+	user := usernameWithRealm(req.Username)
+	fmt.Printf("🔍 TestProxmoxPassword: Test du mot de passe pour %s\n", user)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	_, err := proxmox.NewClient(req.URL, "", "").Ticket(ctx, user, req.Password)
+	if err == nil {
+		fmt.Printf("✅ TestProxmoxPassword: Mot de passe valide pour %s\n", user)
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Mot de passe valide",
+		})
+		return
+	}
+
+	if errors.Is(err, proxmox.ErrUnreachable) {
+		fmt.Printf("❌ TestProxmoxPassword: Erreur requête: %v\n", err)
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to test password: %v", err),
+		})
+		return
+	}
+
+	fmt.Printf("❌ TestProxmoxPassword: Mot de passe invalide: %v\n", err)
+
+	details := err.Error()
+	var apiErr *proxmox.APIError
+	if errors.As(err, &apiErr) {
+		details = apiErr.Message
+	}
+
+	respondJSON(w, http.StatusUnauthorized, map[string]interface{}{
+		"success": false,
+		"error":   fmt.Sprintf("Mot de passe invalide pour %s. L'utilisateur n'a probablement pas de mot de passe défini dans Proxmox (seulement un token API).", user),
+		"details": details,
+		"hint":    fmt.Sprintf("Pour résoudre: Connectez-vous à Proxmox (%s), allez dans Datacenter → Permissions → Users, sélectionnez %s, et définissez un mot de passe.", req.URL, user),
+	})
+}
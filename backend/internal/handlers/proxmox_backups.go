@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limites de lecture du journal d'une tâche
+const (
+	defaultTaskLogLimit = 500
+	maxTaskLogLimit     = 5000
+)
+
+// backupRun est une exécution de vzdump, reliée à son journal par son UPID
+type backupRun struct {
+	models.ProxmoxTask
+	VMID   int  `json:"vmid,omitempty"` // 0 pour une tâche couvrant plusieurs invités
+	Failed bool `json:"failed"`
+}
+
+// GetProxmoxBackups sert les archives de sauvegarde du snapshot d'inventaire, de la plus récente à la plus ancienne.
+// vmid et storage filtrent les archives.
+func (h *Handlers) GetProxmoxBackups(w http.ResponseWriter, r *http.Request) {
+	vmid, err := optionalInt(r.URL.Query().Get("vmid"))
+	if err != nil {
+		http.Error(w, "Invalid vmid", http.StatusBadRequest)
+		return
+	}
+	storage := r.URL.Query().Get("storage")
+
+	h.serveCachedList(w, r, "backups", func(inv *models.ProxmoxInventory) interface{} {
+		backups := []models.ProxmoxBackup{}
+		for _, b := range inv.Backups {
+			if (vmid == 0 || b.VMID == vmid) && (storage == "" || b.Storage == storage) {
+				backups = append(backups, b)
+			}
+		}
+		sort.SliceStable(backups, func(i, j int) bool {
+			return backups[i].CreatedAt.After(backups[j].CreatedAt)
+		})
+		return backups
+	})
+}
+
+// GetProxmoxBackupRuns sert les tâches vzdump récentes du snapshot d'inventaire, de la plus récente à la plus ancienne.
+// failed=true ne retient que les échecs ; le journal d'une exécution se lit avec POST /tasks/log et son UPID.
+func (h *Handlers) GetProxmoxBackupRuns(w http.ResponseWriter, r *http.Request) {
+	onlyFailed := r.URL.Query().Get("failed") == "true"
+
+	h.serveCachedList(w, r, "runs", func(inv *models.ProxmoxInventory) interface{} {
+		runs := []backupRun{}
+		for _, t := range inv.Tasks {
+			if t.Type != services.BackupTaskType {
+				continue
+			}
+			failed := services.BackupRunFailed(t)
+			if onlyFailed && !failed {
+				continue
+			}
+			vmid, _ := strconv.Atoi(t.Name)
+			runs = append(runs, backupRun{ProxmoxTask: t, VMID: vmid, Failed: failed})
+		}
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		})
+		return runs
+	})
+}
+
+// optionalInt lit un entier positif optionnel d'un paramètre de requête (0 si absent)
+func optionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return n, nil
+}
+
+// TaskLogRequest représente une requête de lecture du journal d'une tâche
+type TaskLogRequest struct {
+	proxmoxCredentials
+	UPID  string `json:"upid"`
+	Start int    `json:"start,omitempty"` // première ligne lue
+	Limit int    `json:"limit,omitempty"` // défaut : 500 lignes
+}
+
+// GetProxmoxTaskLog retourne les lignes du journal d'une tâche Proxmox (UPID)
+func (h *Handlers) GetProxmoxTaskLog(w http.ResponseWriter, r *http.Request) {
+	var req TaskLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+
+	upid, err := proxmox.ParseUPID(req.UPID)
+	if !req.valid() || err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id et un upid valide sont requis",
+		})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultTaskLogLimit
+	}
+	if req.Start < 0 || req.Limit < 0 || req.Limit > maxTaskLogLimit {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("start doit être positif et limit compris entre 1 et %d", maxTaskLogLimit),
+		})
+		return
+	}
+
+	lines, err := req.client().TaskLog(r.Context(), upid.Node, req.UPID, req.Start, req.Limit)
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    lines,
+	})
+}
+
+// BackupRunRequest représente une sauvegarde à la demande d'un ou plusieurs invités
+type BackupRunRequest struct {
+	proxmoxCredentials
+	proxmox.VzdumpOptions
+	VMIDs []int `json:"vmids"`
+}
+
+// startedBackup est une tâche vzdump lancée sur un nœud
+type startedBackup struct {
+	Node  string `json:"node"`
+	VMIDs []int  `json:"vmids"`
+	UPID  string `json:"upid"`
+}
+
+// RunBackup lance la sauvegarde vzdump des invités demandés : une tâche par nœud, dont l'UPID est retourné.
+// La permission backups:run est vérifiée sur chaque invité (nœud, pool, tags).
+func (h *Handlers) RunBackup(w http.ResponseWriter, r *http.Request) {
+	var req BackupRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return
+	}
+
+	slices.Sort(req.VMIDs)
+	req.VMIDs = slices.Compact(req.VMIDs)
+	middleware.SetAuditAction(r, "backup.run")
+	if !req.valid() || len(req.VMIDs) == 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id et vmids sont requis",
+		})
+		return
+	}
+	if _, err := req.Params(req.VMIDs); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+
+	targets := make([]string, len(req.VMIDs))
+	for i, vmid := range req.VMIDs {
+		targets[i] = strconv.Itoa(vmid)
+	}
+	middleware.SetAuditTarget(r, "vmid/"+strings.Join(targets, ","))
+
+	// Nœud, pool et tags de chaque invité : vzdump ne sauvegarde que les invités du nœud appelé
+	client := req.client()
+	resources, err := client.ClusterResources(r.Context(), "vm")
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+	guests := make(map[int]models.PermissionTarget)
+	for _, res := range resources {
+		if slices.Contains(req.VMIDs, int(res.VMID)) {
+			guests[int(res.VMID)] = models.PermissionTarget{Node: res.Node, Pool: res.Pool, Tags: splitTags(res.Tags)}
+		}
+	}
+	byNode := make(map[string][]int)
+	var nodes []string
+	for _, vmid := range req.VMIDs {
+		target, ok := guests[vmid]
+		if !ok {
+			respondJSON(w, http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"error":   fmt.Sprintf("Invité %d introuvable", vmid),
+			})
+			return
+		}
+		if _, seen := byNode[target.Node]; !seen {
+			nodes = append(nodes, target.Node)
+		}
+		byNode[target.Node] = append(byNode[target.Node], vmid)
+	}
+	if !authorizeTargets(w, r, "backups", "run", guests) {
+		return
+	}
+
+	started := []startedBackup{}
+	var upids []string
+	for _, node := range nodes {
+		params, _ := req.Params(byNode[node])
+		fmt.Printf("💾 vzdump %v on node %s\n", byNode[node], node)
+		upid, err := client.Vzdump(r.Context(), node, params)
+		if err != nil {
+			fmt.Printf("❌ vzdump on node %s failed: %v\n", node, err)
+			middleware.SetAuditUPID(r, strings.Join(upids, ","))
+			respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+				"success": false,
+				"error":   fmt.Sprintf("Sauvegarde sur le nœud %s: %s", node, proxmoxErrorMessage(err)),
+				"data":    started,
+			})
+			return
+		}
+		started = append(started, startedBackup{Node: node, VMIDs: byNode[node], UPID: upid})
+		upids = append(upids, upid)
+	}
+	middleware.SetAuditUPID(r, strings.Join(upids, ","))
+
+	fmt.Printf("✅ vzdump started for %d guest(s) on %d node(s)\n", len(req.VMIDs), len(started))
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Sauvegarde de %d invité(s) lancée", len(req.VMIDs)),
+		"data":    started,
+	})
+}
+
+// backupJob est une tâche de sauvegarde planifiée telle que retournée par l'API
+type backupJob struct {
+	proxmox.BackupJob
+	Enabled bool  `json:"enabled"`
+	VMIDs   []int `json:"vmids,omitempty"`
+}
+
+// newBackupJob complète une tâche planifiée de Proxmox (état actif, liste des invités)
+func newBackupJob(job proxmox.BackupJob) backupJob {
+	vmids, _ := proxmox.ParseVMIDList(job.VMID)
+	return backupJob{BackupJob: job, Enabled: job.IsEnabled(), VMIDs: vmids}
+}
+
+// BackupJobRequest représente une requête sur les tâches de sauvegarde planifiées
+type BackupJobRequest struct {
+	proxmoxCredentials
+	Job proxmox.BackupJobUpdate `json:"job"` // création et modification
+}
+
+// decodeBackupJobRequest décode une requête de tâche planifiée et vérifie les identifiants
+func (h *Handlers) decodeBackupJobRequest(w http.ResponseWriter, r *http.Request) (BackupJobRequest, bool) {
+	var req BackupJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return req, false
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return req, false
+	}
+
+	if !req.valid() {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id est requis",
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// respondProxmoxError écrit l'erreur d'un appel à Proxmox
+func respondProxmoxError(w http.ResponseWriter, err error) {
+	respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+		"success": false,
+		"error":   proxmoxErrorMessage(err),
+	})
+}
+
+// ListBackupJobs retourne les tâches de sauvegarde planifiées du cluster (cluster/backup)
+func (h *Handlers) ListBackupJobs(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeBackupJobRequest(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := req.client().BackupJobs(r.Context())
+	if err != nil {
+		respondProxmoxError(w, err)
+		return
+	}
+
+	data := make([]backupJob, len(jobs))
+	for i, job := range jobs {
+		data[i] = newBackupJob(job)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// CreateBackupJob crée une tâche de sauvegarde planifiée et retourne la liste des tâches
+func (h *Handlers) CreateBackupJob(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "backup.job.create")
+	req, ok := h.decodeBackupJobRequest(w, r)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(r, "backup-job/"+req.Job.ID)
+
+	params, err := req.Job.Params(true)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+
+	client := req.client()
+	if err := client.CreateBackupJob(r.Context(), params); err != nil {
+		fmt.Printf("❌ Backup job creation failed: %v\n", err)
+		respondProxmoxError(w, err)
+		return
+	}
+	jobs, err := client.BackupJobs(r.Context())
+	if err != nil {
+		respondProxmoxError(w, err)
+		return
+	}
+
+	data := make([]backupJob, len(jobs))
+	for i, job := range jobs {
+		data[i] = newBackupJob(job)
+	}
+	fmt.Printf("✅ Backup job created (schedule %s)\n", params.Get("schedule"))
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Tâche de sauvegarde créée",
+		"data":    data,
+	})
+}
+
+// UpdateBackupJob modifie une tâche de sauvegarde planifiée et retourne la tâche relue
+func (h *Handlers) UpdateBackupJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	middleware.SetAuditAction(r, "backup.job.update")
+	middleware.SetAuditTarget(r, "backup-job/"+id)
+
+	req, ok := h.decodeBackupJobRequest(w, r)
+	if !ok {
+		return
+	}
+	params, err := req.Job.Params(false)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+
+	client := req.client()
+	if err := client.UpdateBackupJob(r.Context(), id, params); err != nil {
+		fmt.Printf("❌ Backup job %s update failed: %v\n", id, err)
+		respondProxmoxError(w, err)
+		return
+	}
+	job, err := client.BackupJob(r.Context(), id)
+	if err != nil {
+		respondProxmoxError(w, err)
+		return
+	}
+
+	fmt.Printf("✅ Backup job %s updated\n", id)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Tâche de sauvegarde %s modifiée", id),
+		"data":    newBackupJob(*job),
+	})
+}
+
+// DeleteBackupJob supprime une tâche de sauvegarde planifiée
+func (h *Handlers) DeleteBackupJob(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	middleware.SetAuditAction(r, "backup.job.delete")
+	middleware.SetAuditTarget(r, "backup-job/"+id)
+
+	req, ok := h.decodeBackupJobRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := req.client().DeleteBackupJob(r.Context(), id); err != nil {
+		fmt.Printf("❌ Backup job %s deletion failed: %v\n", id, err)
+		respondProxmoxError(w, err)
+		return
+	}
+
+	fmt.Printf("🗑️ Backup job %s deleted\n", id)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Tâche de sauvegarde %s supprimée", id),
+	})
+}
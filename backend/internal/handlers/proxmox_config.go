@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// GuestConfigRequest représente une requête de lecture ou de modification de la configuration d'un invité
+type GuestConfigRequest struct {
+	proxmoxCredentials
+	Node    string                     `json:"node"`
+	VMID    int                        `json:"vmid"`
+	Digest  string                     `json:"digest,omitempty"`  // modification : digest de la configuration lue
+	Changes *proxmox.GuestConfigUpdate `json:"changes,omitempty"` // modification : champs à modifier
+}
+
+// GetVMConfig retourne la configuration typée d'une VM et ses modifications en attente
+func (h *Handlers) GetVMConfig(w http.ResponseWriter, r *http.Request) {
+	h.getGuestConfig(w, r, proxmox.GuestQemu)
+}
+
+// GetLXCConfig retourne la configuration typée d'un conteneur et ses modifications en attente
+func (h *Handlers) GetLXCConfig(w http.ResponseWriter, r *http.Request) {
+	h.getGuestConfig(w, r, proxmox.GuestLXC)
+}
+
+// UpdateVMConfig applique une modification partielle de la configuration d'une VM
+func (h *Handlers) UpdateVMConfig(w http.ResponseWriter, r *http.Request) {
+	h.updateGuestConfig(w, r, proxmox.GuestQemu)
+}
+
+// UpdateLXCConfig applique une modification partielle de la configuration d'un conteneur
+func (h *Handlers) UpdateLXCConfig(w http.ResponseWriter, r *http.Request) {
+	h.updateGuestConfig(w, r, proxmox.GuestLXC)
+}
+
+// decodeGuestConfigRequest décode une requête de configuration et vérifie les champs requis
+func (h *Handlers) decodeGuestConfigRequest(w http.ResponseWriter, r *http.Request) (GuestConfigRequest, bool) {
+	var req GuestConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return req, false
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return req, false
+	}
+
+	if !req.valid() || req.Node == "" || req.VMID == 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id, node et vmid sont requis",
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// readGuestConfig lit la configuration typée d'un invité et ses modifications en attente
+func readGuestConfig(r *http.Request, client *proxmox.Client, guestType proxmox.GuestType, node string, vmid int) (*proxmox.GuestConfig, error) {
+	raw, err := client.GuestConfig(r.Context(), node, guestType, vmid)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := client.GuestPending(r.Context(), node, guestType, vmid)
+	if err != nil {
+		return nil, err
+	}
+	cfg := proxmox.ParseGuestConfig(guestType, raw)
+	cfg.SetPending(pending)
+	return cfg, nil
+}
+
+// getGuestConfig retourne la configuration typée d'un invité
+func (h *Handlers) getGuestConfig(w http.ResponseWriter, r *http.Request, guestType proxmox.GuestType) {
+	req, ok := h.decodeGuestConfigRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// La lecture relève de proxmox:read (comme l'inventaire) ; la modification exige l'action config
+	client := req.client()
+	if !authorizeGuest(w, r, client, guestResource(guestType), "read", req.Node, req.VMID) {
+		return
+	}
+
+	cfg, err := readGuestConfig(r, client, guestType, req.Node, req.VMID)
+	if err != nil {
+		fmt.Printf("❌ %s config %d: %v\n", guestType, req.VMID, err)
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    cfg,
+	})
+}
+
+// updateGuestConfig applique une modification partielle avec le digest lu (verrouillage optimiste)
+// et retourne la configuration relue, avec les modifications qui attendent un redémarrage
+func (h *Handlers) updateGuestConfig(w http.ResponseWriter, r *http.Request, guestType proxmox.GuestType) {
+	auditPrefix := "vm."
+	if guestType == proxmox.GuestLXC {
+		auditPrefix = "lxc."
+	}
+	middleware.SetAuditAction(r, auditPrefix+"config.update")
+
+	req, ok := h.decodeGuestConfigRequest(w, r)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(r, guestAuditTarget(req.Node, guestType, req.VMID))
+
+	if req.Digest == "" || req.Changes == nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: digest (de la configuration lue) et changes sont requis",
+		})
+		return
+	}
+
+	client := req.client()
+	if !authorizeGuest(w, r, client, guestResource(guestType), "config", req.Node, req.VMID) {
+		return
+	}
+
+	current, err := readGuestConfig(r, client, guestType, req.Node, req.VMID)
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+	params, err := req.Changes.Params(current)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+	changed := make([]string, 0, len(params))
+	for key := range params {
+		changed = append(changed, key)
+	}
+	sort.Strings(changed)
+
+	// Proxmox vérifie le digest : une modification concurrente depuis la lecture donne ErrConfigChanged (409)
+	params.Set("digest", req.Digest)
+	fmt.Printf("🔧 %s config update on %d (node: %s): %v\n", guestType, req.VMID, req.Node, changed)
+	if err := client.UpdateGuestConfig(r.Context(), req.Node, guestType, req.VMID, params); err != nil {
+		fmt.Printf("❌ %s config update failed: %v\n", guestType, err)
+		message := proxmoxErrorMessage(err)
+		if proxmox.HTTPStatus(err) == http.StatusConflict {
+			message = "La configuration a été modifiée depuis sa lecture : rechargez-la avant de réessayer"
+		}
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   message,
+		})
+		return
+	}
+
+	cfg, err := readGuestConfig(r, client, guestType, req.Node, req.VMID)
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	fmt.Printf("✅ %s config updated for %d (reboot required: %v)\n", guestType, req.VMID, cfg.RebootRequired)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%s %d configuration updated", guestType, req.VMID),
+		"changed": changed,
+		"data":    cfg,
+	})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// connectionFromRequest charge la connexion désignée par le paramètre {id} et écrit l'erreur HTTP le cas échéant
+func (h *Handlers) connectionFromRequest(w http.ResponseWriter, r *http.Request) (*models.ProxmoxConnection, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid connection ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	conn, err := h.store.GetProxmoxConnection(id)
+	if err != nil {
+		http.Error(w, connectionErrorMessage(err), connectionErrorStatus(err))
+		return nil, false
+	}
+	return conn, true
+}
+
+// storeErrorStatus retourne le code HTTP associé à une erreur d'écriture d'une connexion
+func storeErrorStatus(err error) int {
+	if errors.Is(err, store.ErrNoCipher) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// GetProxmoxConnections liste les connexions Proxmox enregistrées (sans leurs secrets)
+func (h *Handlers) GetProxmoxConnections(w http.ResponseWriter, r *http.Request) {
+	conns, err := h.store.GetProxmoxConnections()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get proxmox connections: %v", err), storeErrorStatus(err))
+		return
+	}
+	if conns == nil {
+		conns = []*models.ProxmoxConnection{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conns)
+}
+
+// GetProxmoxConnection récupère une connexion Proxmox
+func (h *Handlers) GetProxmoxConnection(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.connectionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conn)
+}
+
+// CreateProxmoxConnection enregistre une nouvelle connexion Proxmox
+func (h *Handlers) CreateProxmoxConnection(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "connection.create")
+
+	var req models.ProxmoxConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	conn := &models.ProxmoxConnection{Enabled: true, CreatedAt: now, UpdatedAt: now}
+	req.Apply(conn)
+
+	if err := conn.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CreateProxmoxConnection(conn); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create proxmox connection: %v", err), storeErrorStatus(err))
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("connection/%d", conn.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conn)
+}
+
+// UpdateProxmoxConnection met à jour une connexion Proxmox.
+// Les secrets absents de la requête sont conservés.
+func (h *Handlers) UpdateProxmoxConnection(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "connection.update")
+	middleware.SetAuditTarget(r, "connection/"+chi.URLParam(r, "id"))
+
+	conn, ok := h.connectionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ProxmoxConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	req.Apply(conn)
+	conn.UpdatedAt = time.Now()
+
+	if err := conn.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdateProxmoxConnection(conn); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update proxmox connection: %v", err), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conn)
+}
+
+// DeleteProxmoxConnection supprime une connexion Proxmox
+func (h *Handlers) DeleteProxmoxConnection(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "connection.delete")
+	middleware.SetAuditTarget(r, "connection/"+chi.URLParam(r, "id"))
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid connection ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.store.GetProxmoxConnection(id); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, connectionErrorMessage(err), http.StatusNotFound)
+		return
+	}
+
+	if err := h.store.DeleteProxmoxConnection(id); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete proxmox connection: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestProxmoxConnection vérifie qu'une connexion enregistrée permet d'interroger l'API Proxmox
+func (h *Handlers) TestProxmoxConnection(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.connectionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	client := proxmox.NewClient(conn.URL, conn.Username, conn.Secret)
+	nodes, err := client.Nodes(r.Context())
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": false,
+			"message": proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Connexion réussie: %d nœud(s) accessible(s)", len(nodes)),
+	})
+}
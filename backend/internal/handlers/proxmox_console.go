@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// consoleTokenTTL est la durée de validité d'un token de console inutilisé ; chaque requête de la
+// console (page, ressources, WebSocket) la prolonge
+const consoleTokenTTL = 2 * time.Minute
+
+// consoleSessionMaxAge borne une session de console à la durée de vie d'un ticket Proxmox
+const consoleSessionMaxAge = 2 * time.Hour
+
+// consoleTokenCookie transmet le token aux ressources /novnc/ relayées sans paramètres (proxy Nginx)
+const consoleTokenCookie = "proxmox_console_token"
+
+// errConsoleToken est retourné quand le token de console est absent, inconnu ou expiré
+var errConsoleToken = errors.New("invalid or expired console token")
+
+// consoleSession associe un token de console à l'invité et au ticket Proxmox obtenus par /vm/console.
+// L'URL de Proxmox n'est pas conservée : elle est relue dans la connexion enregistrée à chaque requête.
+type consoleSession struct {
+	connectionID int
+	node         string
+	vmid         int
+	ticket       string
+	created      time.Time
+	expires      time.Time
+}
+
+// consoleSessions conserve en mémoire les sessions de console ouvertes.
+// La fenêtre de console ne transmet pas le token de session : le token de console, aléatoire et
+// de courte durée, est la seule preuve d'authentification des routes console-proxy et console-redirect.
+type consoleSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*consoleSession
+}
+
+func newConsoleSessions() *consoleSessions {
+	return &consoleSessions{sessions: make(map[string]*consoleSession)}
+}
+
+// issue enregistre une session et retourne son token
+func (c *consoleSessions) issue(session consoleSession, now time.Time) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate console token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, s := range c.sessions {
+		if now.After(s.expires) {
+			delete(c.sessions, t)
+		}
+	}
+	session.created = now
+	session.expires = now.Add(consoleTokenTTL)
+	c.sessions[token] = &session
+	return token, nil
+}
+
+// lookup retourne la session d'un token valide et prolonge sa validité
+func (c *consoleSessions) lookup(token string, now time.Time) (consoleSession, bool) {
+	if token == "" {
+		return consoleSession{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.sessions[token]
+	if !ok {
+		return consoleSession{}, false
+	}
+	if now.After(s.expires) || now.Sub(s.created) > consoleSessionMaxAge {
+		delete(c.sessions, token)
+		return consoleSession{}, false
+	}
+	s.expires = now.Add(consoleTokenTTL)
+	return *s, true
+}
+
+// consoleTokenFromRequest lit le token de console dans la requête, à défaut dans le Referer
+// (ressources chargées par la page de console) ou dans le cookie posé par la page
+func consoleTokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("console_token"); token != "" {
+		return token
+	}
+	if referer, err := url.Parse(r.Header.Get("Referer")); err == nil && strings.Contains(referer.Path, "console-proxy") {
+		if token := referer.Query().Get("console_token"); token != "" {
+			return token
+		}
+	}
+	if cookie, err := r.Cookie(consoleTokenCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// resolveConsoleSession valide le token de console de la requête et retourne la session,
+// son token et l'URL de la connexion enregistrée (jamais une URL fournie par le client)
+func (h *Handlers) resolveConsoleSession(r *http.Request) (consoleSession, string, string, error) {
+	token := consoleTokenFromRequest(r)
+	session, ok := h.consoles.lookup(token, time.Now())
+	if !ok {
+		return consoleSession{}, "", "", errConsoleToken
+	}
+
+	creds := proxmoxCredentials{ConnectionID: session.connectionID}
+	if err := h.resolveProxmoxCredentials(&creds); err != nil {
+		return consoleSession{}, "", "", err
+	}
+	return session, token, strings.TrimSuffix(creds.URL, "/"), nil
+}
+
+// writeConsoleSessionError écrit l'erreur de validation d'un token de console
+func writeConsoleSessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errConsoleToken) {
+		http.Error(w, "Token de console invalide ou expiré", http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, connectionErrorMessage(err), connectionErrorStatus(err))
+}
+
+// consoleResourcePrefixes sont les ressources statiques de la page de console noVNC (hors API)
+var consoleResourcePrefixes = []string{"/novnc/", "/pve2/"}
+
+// consoleTarget valide un chemin que le proxy de console relaie vers Proxmox et le retourne normalisé.
+// Sont acceptés en lecture les ressources statiques de noVNC, et pour l'invité autorisé à l'ouverture
+// de la session ses seuls endpoints de console : vncproxy (POST) et vncwebsocket (GET, upgrade WebSocket).
+func consoleTarget(method, target string, session consoleSession) (string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	clean := path.Clean(u.Path)
+	if u.RawQuery != "" {
+		clean += "?" + u.RawQuery
+	}
+
+	guest := fmt.Sprintf("/nodes/%s/qemu/%d/", session.node, session.vmid)
+	for _, api := range []string{"/api2/json", "/api2/extjs"} {
+		switch path.Clean(u.Path) {
+		case api + guest + "vncproxy":
+			return clean, method == http.MethodPost
+		case api + guest + "vncwebsocket":
+			return clean, method == http.MethodGet
+		}
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return "", false
+	}
+	for _, prefix := range append(consoleResourcePrefixes, "/"+session.node+"/novnc/") {
+		if strings.HasPrefix(clean, prefix) {
+			return clean, true
+		}
+	}
+	return "", false
+}
+
+// VMConsoleRequest représente une requête pour obtenir l'URL de la console VNC
+type VMConsoleRequest struct {
+	proxmoxCredentials
+	Password string `json:"password"` // Mot de passe optionnel pour obtenir un ticket (si différent du secret)
+	Node     string `json:"node"`
+	VMID     int    `json:"vmid"`
+}
+
+// decodeTicketRequest décode une requête de console et vérifie les champs requis
+func (h *Handlers) decodeTicketRequest(w http.ResponseWriter, r *http.Request, caller string) (VMConsoleRequest, bool) {
+	var req VMConsoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Printf("❌ %s: Erreur de décodage JSON: %v\n", caller, err)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return req, false
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return req, false
+	}
+
+	if !req.valid() || req.Node == "" || req.VMID == 0 {
+		fmt.Printf("❌ %s: Champs manquants\n", caller)
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id, node et vmid sont requis",
+		})
+		return req, false
+	}
+
+	return req, true
+}
+
+// sessionTicket obtient un ticket de session Proxmox (PVEAuthCookie) pour la requête.
+// Un token API ne permet pas d'ouvrir une session : on utilise le mot de passe de l'utilisateur
+// (fourni dans la requête ou enregistré avec la connexion), à défaut le secret.
+func (h *Handlers) sessionTicket(r *http.Request, req VMConsoleRequest) (*proxmox.Ticket, string, error) {
+	user := usernameWithRealm(req.Username)
+	password := req.Password
+	if password == "" {
+		password = req.password
+	}
+	if password == "" {
+		password = req.Secret
+	}
+
+	fmt.Printf("🔑 Tentative d'obtention de ticket pour %s (mot de passe fourni: %v)\n", user, req.Password != "" || req.password != "")
+	ticket, err := req.client().Ticket(r.Context(), user, password)
+	if err == nil && ticket.Ticket == "" {
+		err = fmt.Errorf("ticket vide reçu de Proxmox")
+	}
+	return ticket, user, err
+}
+
+// writeTicketError écrit l'erreur d'obtention de ticket avec des instructions pour l'utilisateur
+func writeTicketError(w http.ResponseWriter, req VMConsoleRequest, user, feature string, err error) {
+	if errors.Is(err, proxmox.ErrUnreachable) {
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to get ticket: %v", err),
+		})
+		return
+	}
+
+	errorMsg := fmt.Sprintf("Impossible d'obtenir un ticket de session pour %s. ", user)
+	if req.Password == "" && req.password == "" {
+		errorMsg += fmt.Sprintf("Pour utiliser %s avec un token API, vous devez fournir le mot de passe de l'utilisateur dans le champ 'Mot de passe (optionnel - pour console VNC)' des Paramètres Proxmox.", feature)
+	} else {
+		displayURL := strings.TrimPrefix(strings.TrimPrefix(req.URL, "https://"), "http://")
+		errorMsg += fmt.Sprintf("Erreur d'authentification (401). Le mot de passe fourni n'est pas accepté par Proxmox.\n\nVérifications à faire :\n1. Testez le mot de passe en vous connectant directement à Proxmox : https://%s avec l'utilisateur %s\n2. Assurez-vous que l'utilisateur %s a bien un mot de passe défini (pas seulement un token API)\n3. Si l'utilisateur n'a pas de mot de passe :\n   - Allez dans Datacenter → Permissions → Users\n   - Sélectionnez %s → Edit → Change Password\n   - Définissez un mot de passe\n   - Utilisez ce mot de passe dans les Paramètres", displayURL, user, user, user)
+	}
+
+	respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"success": false,
+		"error":   errorMsg,
+	})
+}
+
+// VMConsole génère un ticket d'authentification Proxmox et retourne l'URL de la console VNC
+func (h *Handlers) VMConsole(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "vm.console")
+	req, ok := h.decodeTicketRequest(w, r, "VMConsole")
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(r, guestAuditTarget(req.Node, proxmox.GuestQemu, req.VMID))
+	if !authorizeGuest(w, r, req.client(), "proxmox.vm", "console", req.Node, req.VMID) {
+		return
+	}
+
+	fmt.Printf("🖥️ Console request for VM %d on node %s\n", req.VMID, req.Node)
+
+	ticket, user, err := h.sessionTicket(r, req)
+	if err != nil {
+		fmt.Printf("❌ VMConsole: Impossible d'obtenir un ticket: %v\n", err)
+		writeTicketError(w, req, user, "la console VNC", err)
+		return
+	}
+
+	// IMPORTANT: Proxmox attend le ticket dans un cookie HTTP, pas dans l'URL
+	// Utiliser un proxy backend qui fait la requête vers Proxmox avec le cookie dans les en-têtes
+	// Le proxy gérera les WebSockets pour la console VNC ; le ticket reste côté serveur,
+	// la fenêtre de console ne reçoit qu'un token de console de courte durée
+	token, err := h.consoles.issue(consoleSession{
+		connectionID: req.ConnectionID,
+		node:         req.Node,
+		vmid:         req.VMID,
+		ticket:       ticket.Ticket,
+	}, time.Now())
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	proxyURL := "/api/v1/proxmox/vm/console-proxy?console_token=" + url.QueryEscape(token)
+
+	fmt.Printf("✅ Console URL generated for VM %d\n", req.VMID)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"consoleUrl": proxyURL,
+	})
+}
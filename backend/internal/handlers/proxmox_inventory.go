@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/models"
+)
+
+// inventoryResponse est la réponse agrégée multi-clusters
+type inventoryResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*models.ProxmoxInventory
+	Cache *models.ProxmoxCacheStatus `json:"cache,omitempty"`
+}
+
+// emptyInventory retourne un inventaire sans objet (listes vides plutôt que null)
+func emptyInventory() *models.ProxmoxInventory {
+	return &models.ProxmoxInventory{
+		Nodes:          []models.ProxmoxNode{},
+		VMs:            []models.ProxmoxGuest{},
+		LXC:            []models.ProxmoxGuest{},
+		Storages:       []models.ProxmoxStorage{},
+		Networks:       []models.ProxmoxNetwork{},
+		Tasks:          []models.ProxmoxTask{},
+		Backups:        []models.ProxmoxBackup{},
+		GuestSnapshots: []models.ProxmoxGuestSnapshot{},
+		Clusters:       []models.ProxmoxClusterStatus{},
+	}
+}
+
+// parseConnectionIDs lit le filtre connection_id (paramètre répétable ou séparé par des virgules)
+func parseConnectionIDs(r *http.Request) (map[int]bool, error) {
+	wanted := make(map[int]bool)
+	for _, value := range r.URL.Query()["connection_id"] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid connection_id %q", part)
+			}
+			wanted[id] = true
+		}
+	}
+	return wanted, nil
+}
+
+// filterByCluster conserve les objets dont le cluster fait partie de names
+func filterByCluster[T any](items []T, names map[string]bool, cluster func(T) string) []T {
+	filtered := []T{}
+	for _, item := range items {
+		if names[cluster(item)] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// filterInventory restreint un inventaire aux connexions de wanted
+func filterInventory(inv *models.ProxmoxInventory, wanted map[int]bool) *models.ProxmoxInventory {
+	if len(wanted) == 0 {
+		return inv
+	}
+
+	filtered := emptyInventory()
+	names := make(map[string]bool)
+	for _, c := range inv.Clusters {
+		if wanted[c.ConnectionID] {
+			names[c.Name] = true
+			filtered.Clusters = append(filtered.Clusters, c)
+		}
+	}
+	filtered.Nodes = filterByCluster(inv.Nodes, names, func(n models.ProxmoxNode) string { return n.Cluster })
+	filtered.VMs = filterByCluster(inv.VMs, names, func(g models.ProxmoxGuest) string { return g.Cluster })
+	filtered.LXC = filterByCluster(inv.LXC, names, func(g models.ProxmoxGuest) string { return g.Cluster })
+	filtered.Storages = filterByCluster(inv.Storages, names, func(s models.ProxmoxStorage) string { return s.Cluster })
+	filtered.Networks = filterByCluster(inv.Networks, names, func(n models.ProxmoxNetwork) string { return n.Cluster })
+	filtered.Tasks = filterByCluster(inv.Tasks, names, func(t models.ProxmoxTask) string { return t.Cluster })
+	filtered.Backups = filterByCluster(inv.Backups, names, func(b models.ProxmoxBackup) string { return b.Cluster })
+	filtered.GuestSnapshots = filterByCluster(inv.GuestSnapshots, names, func(s models.ProxmoxGuestSnapshot) string { return s.Cluster })
+	return filtered
+}
+
+// inventoryMessage résume le résultat de la collecte des clusters
+func inventoryMessage(inv *models.ProxmoxInventory) (bool, string) {
+	if len(inv.Clusters) == 0 {
+		return false, "Aucune connexion Proxmox active. Ajoutez une connexion dans les Paramètres."
+	}
+
+	succeeded := 0
+	for _, c := range inv.Clusters {
+		if c.Success {
+			succeeded++
+		}
+	}
+
+	switch {
+	case succeeded == 0:
+		return false, "Aucun cluster Proxmox n'a pu être interrogé"
+	case succeeded < len(inv.Clusters):
+		return true, fmt.Sprintf("Données partielles: %d/%d cluster(s) interrogé(s) avec succès", succeeded, len(inv.Clusters))
+	default:
+		return true, "Proxmox data fetched successfully"
+	}
+}
+
+// cachedInventory retourne le snapshot du poller filtré par connection_id, avec sa fraîcheur.
+// Écrit l'erreur HTTP et retourne false si le poller est désactivé ou le filtre invalide.
+func (h *Handlers) cachedInventory(w http.ResponseWriter, r *http.Request) (*models.ProxmoxInventory, *models.ProxmoxCacheStatus, bool) {
+	if h.poller == nil {
+		http.Error(w, "Proxmox inventory poller is disabled (PROXMOX_POLL_ENABLED=false)", http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+
+	wanted, err := parseConnectionIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	status := h.poller.Status()
+	w.Header().Set("Age", strconv.FormatInt(status.AgeSeconds, 10))
+
+	snapshot := h.poller.Snapshot()
+	if snapshot == nil {
+		return nil, &status, true
+	}
+	return filterInventory(&snapshot.ProxmoxInventory, wanted), &status, true
+}
+
+// GetProxmoxInventory agrège les nœuds, VMs, LXC et storages de toutes les connexions Proxmox actives.
+// Le dernier snapshot du poller est servi avec son âge ; sans poller, les clusters sont interrogés directement.
+// Les clusters injoignables sont signalés dans "clusters" sans bloquer les autres.
+func (h *Handlers) GetProxmoxInventory(w http.ResponseWriter, r *http.Request) {
+	if h.poller == nil {
+		h.collectProxmoxInventory(w, r)
+		return
+	}
+
+	inv, status, ok := h.cachedInventory(w, r)
+	if !ok {
+		return
+	}
+	if inv == nil {
+		respondJSON(w, http.StatusOK, inventoryResponse{
+			Success:          false,
+			Message:          "Inventaire Proxmox en cours de collecte",
+			ProxmoxInventory: emptyInventory(),
+			Cache:            status,
+		})
+		return
+	}
+
+	success, message := inventoryMessage(inv)
+	respondJSON(w, http.StatusOK, inventoryResponse{
+		Success:          success,
+		Message:          message,
+		ProxmoxInventory: inv,
+		Cache:            status,
+	})
+}
+
+// collectProxmoxInventory interroge directement les clusters (poller désactivé)
+func (h *Handlers) collectProxmoxInventory(w http.ResponseWriter, r *http.Request) {
+	wanted, err := parseConnectionIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conns, err := h.store.GetProxmoxConnections()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get proxmox connections: %v", err), storeErrorStatus(err))
+		return
+	}
+
+	inv := emptyInventory()
+	if clusters := inventory.ClustersFromConnections(conns, wanted); len(clusters) > 0 {
+		inv = inventory.CollectAll(r.Context(), clusters, inventory.Content{Concurrency: inventory.DefaultContentConcurrency})
+	}
+
+	success, message := inventoryMessage(inv)
+	respondJSON(w, http.StatusOK, inventoryResponse{
+		Success:          success,
+		Message:          message,
+		ProxmoxInventory: inv,
+	})
+}
+
+// RefreshProxmoxInventory force une collecte immédiate puis sert le nouveau snapshot
+func (h *Handlers) RefreshProxmoxInventory(w http.ResponseWriter, r *http.Request) {
+	if h.poller == nil {
+		http.Error(w, "Proxmox inventory poller is disabled (PROXMOX_POLL_ENABLED=false)", http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, err := h.poller.Refresh(r.Context()); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to refresh proxmox inventory: %v", err), storeErrorStatus(err))
+		return
+	}
+	h.GetProxmoxInventory(w, r)
+}
+
+// GetProxmoxCacheStatus retourne la fraîcheur du snapshot d'inventaire
+func (h *Handlers) GetProxmoxCacheStatus(w http.ResponseWriter, r *http.Request) {
+	if h.poller == nil {
+		http.Error(w, "Proxmox inventory poller is disabled (PROXMOX_POLL_ENABLED=false)", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.poller.Status())
+}
+
+// serveCachedList sert une partie du snapshot d'inventaire sous la clé donnée
+func (h *Handlers) serveCachedList(w http.ResponseWriter, r *http.Request, key string, list func(*models.ProxmoxInventory) interface{}) {
+	inv, status, ok := h.cachedInventory(w, r)
+	if !ok {
+		return
+	}
+
+	success, message := false, "Inventaire Proxmox en cours de collecte"
+	if inv == nil {
+		inv = emptyInventory()
+	} else {
+		success, message = inventoryMessage(inv)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": success,
+		"message": message,
+		key:       list(inv),
+		"cache":   status,
+	})
+}
+
+// GetProxmoxNodes sert les nœuds du snapshot d'inventaire
+func (h *Handlers) GetProxmoxNodes(w http.ResponseWriter, r *http.Request) {
+	h.serveCachedList(w, r, "nodes", func(inv *models.ProxmoxInventory) interface{} { return inv.Nodes })
+}
+
+// GetProxmoxVMs sert les VMs du snapshot d'inventaire
+func (h *Handlers) GetProxmoxVMs(w http.ResponseWriter, r *http.Request) {
+	h.serveCachedList(w, r, "vms", func(inv *models.ProxmoxInventory) interface{} { return inv.VMs })
+}
+
+// GetProxmoxLXC sert les conteneurs LXC du snapshot d'inventaire
+func (h *Handlers) GetProxmoxLXC(w http.ResponseWriter, r *http.Request) {
+	h.serveCachedList(w, r, "lxc", func(inv *models.ProxmoxInventory) interface{} { return inv.LXC })
+}
+
+// GetProxmoxStorages sert les storages du snapshot d'inventaire
+func (h *Handlers) GetProxmoxStorages(w http.ResponseWriter, r *http.Request) {
+	h.serveCachedList(w, r, "storages", func(inv *models.ProxmoxInventory) interface{} { return inv.Storages })
+}
+
+// GetProxmoxNetworks sert les interfaces réseau du snapshot d'inventaire
+func (h *Handlers) GetProxmoxNetworks(w http.ResponseWriter, r *http.Request) {
+	h.serveCachedList(w, r, "networks", func(inv *models.ProxmoxInventory) interface{} { return inv.Networks })
+}
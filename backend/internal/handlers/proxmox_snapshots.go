@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SnapshotRequest représente une requête sur les snapshots d'un invité
+type SnapshotRequest struct {
+	proxmoxCredentials
+	Node        string `json:"node"`
+	VMID        int    `json:"vmid"`
+	Name        string `json:"name,omitempty"`        // create, rollback, delete
+	Description string `json:"description,omitempty"` // create
+	VMState     bool   `json:"vmstate,omitempty"`     // create : inclure l'état de la RAM (VMs uniquement)
+	Start       bool   `json:"start,omitempty"`       // rollback : démarrer l'invité après la restauration
+	Force       bool   `json:"force,omitempty"`       // delete : supprimer de la configuration même si la suppression des disques échoue
+	Wait        int    `json:"wait,omitempty"`        // secondes d'attente de fin de tâche, maxTaskWaitTimeout au plus (0 : retour immédiat avec l'UPID)
+}
+
+// snapshotOperations liste les opérations sur les snapshots (/vm/snapshots/{operation})
+var snapshotOperations = map[string]bool{"create": true, "rollback": true, "delete": true}
+
+// ListVMSnapshots retourne l'arbre des snapshots d'une VM
+func (h *Handlers) ListVMSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.listGuestSnapshots(w, r, proxmox.GuestQemu)
+}
+
+// ListLXCSnapshots retourne l'arbre des snapshots d'un conteneur
+func (h *Handlers) ListLXCSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.listGuestSnapshots(w, r, proxmox.GuestLXC)
+}
+
+// VMSnapshotAction crée, restaure ou supprime un snapshot de VM
+func (h *Handlers) VMSnapshotAction(w http.ResponseWriter, r *http.Request) {
+	h.guestSnapshotAction(w, r, proxmox.GuestQemu)
+}
+
+// LXCSnapshotAction crée, restaure ou supprime un snapshot de conteneur
+func (h *Handlers) LXCSnapshotAction(w http.ResponseWriter, r *http.Request) {
+	h.guestSnapshotAction(w, r, proxmox.GuestLXC)
+}
+
+// decodeSnapshotRequest décode une requête de snapshot et vérifie les champs requis
+func (h *Handlers) decodeSnapshotRequest(w http.ResponseWriter, r *http.Request) (SnapshotRequest, bool) {
+	var req SnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Invalid JSON: %v", err),
+		})
+		return req, false
+	}
+
+	if err := h.resolveProxmoxCredentials(&req.proxmoxCredentials); err != nil {
+		respondJSON(w, connectionErrorStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   connectionErrorMessage(err),
+		})
+		return req, false
+	}
+
+	if !req.valid() || req.Node == "" || req.VMID == 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Champs manquants: connection_id, node et vmid sont requis",
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// listGuestSnapshots retourne l'arbre des snapshots d'un invité, l'état courant compris
+func (h *Handlers) listGuestSnapshots(w http.ResponseWriter, r *http.Request, guestType proxmox.GuestType) {
+	req, ok := h.decodeSnapshotRequest(w, r)
+	if !ok {
+		return
+	}
+
+	client := req.client()
+	if !authorizeGuest(w, r, client, guestResource(guestType), "snapshot", req.Node, req.VMID) {
+		return
+	}
+
+	snapshots, err := client.GuestSnapshots(r.Context(), req.Node, guestType, req.VMID)
+	if err != nil {
+		fmt.Printf("❌ %s snapshots %d: %v\n", guestType, req.VMID, err)
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    proxmox.BuildSnapshotTree(snapshots),
+	})
+}
+
+// validate vérifie les paramètres d'une opération sur un snapshot
+func (req *SnapshotRequest) validate(operation string, guestType proxmox.GuestType) error {
+	if err := proxmox.ValidateSnapshotName(req.Name); err != nil {
+		return err
+	}
+	if req.Wait < 0 || req.Wait > maxTaskWaitTimeout {
+		return fmt.Errorf("wait doit être compris entre 0 et %d secondes", maxTaskWaitTimeout)
+	}
+	if operation != "create" && (req.Description != "" || req.VMState) {
+		return fmt.Errorf("description et vmstate ne s'appliquent qu'à la création")
+	}
+	if req.VMState && guestType != proxmox.GuestQemu {
+		return fmt.Errorf("vmstate n'est disponible que pour les VMs")
+	}
+	if len(req.Description) > proxmox.MaxSnapshotDescriptionLen {
+		return fmt.Errorf("description ne doit pas dépasser %d octets", proxmox.MaxSnapshotDescriptionLen)
+	}
+	if req.Start && operation != "rollback" {
+		return fmt.Errorf("start ne s'applique qu'à la restauration")
+	}
+	if req.Force && operation != "delete" {
+		return fmt.Errorf("force ne s'applique qu'à la suppression")
+	}
+	return nil
+}
+
+// guestSnapshotAction lance une opération sur un snapshot et retourne l'UPID de la tâche Proxmox.
+// Avec wait, la tâche est suivie jusqu'à sa fin (ou l'expiration du délai) et son statut est retourné.
+func (h *Handlers) guestSnapshotAction(w http.ResponseWriter, r *http.Request, guestType proxmox.GuestType) {
+	operation := chi.URLParam(r, "operation")
+	if !snapshotOperations[operation] {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Opération non supportée: %s (create, rollback ou delete)", operation),
+		})
+		return
+	}
+	auditPrefix := "vm."
+	if guestType == proxmox.GuestLXC {
+		auditPrefix = "lxc."
+	}
+	middleware.SetAuditAction(r, auditPrefix+"snapshot."+operation)
+
+	req, ok := h.decodeSnapshotRequest(w, r)
+	if !ok {
+		return
+	}
+	middleware.SetAuditTarget(r, guestAuditTarget(req.Node, guestType, req.VMID)+"@"+req.Name)
+
+	if err := req.validate(operation, guestType); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Validation error: %v", err),
+		})
+		return
+	}
+
+	client := req.client()
+	if !authorizeGuest(w, r, client, guestResource(guestType), "snapshot", req.Node, req.VMID) {
+		return
+	}
+
+	fmt.Printf("📸 %s snapshot %s %q on %d (node: %s)\n", guestType, operation, req.Name, req.VMID, req.Node)
+
+	params := url.Values{}
+	var upid string
+	var err error
+	switch operation {
+	case "create":
+		params.Set("snapname", req.Name)
+		if req.Description != "" {
+			params.Set("description", req.Description)
+		}
+		if req.VMState {
+			params.Set("vmstate", "1")
+		}
+		upid, err = client.CreateSnapshot(r.Context(), req.Node, guestType, req.VMID, params)
+	case "rollback":
+		if req.Start {
+			params.Set("start", "1")
+		}
+		upid, err = client.RollbackSnapshot(r.Context(), req.Node, guestType, req.VMID, req.Name, params)
+	case "delete":
+		if req.Force {
+			params.Set("force", "1")
+		}
+		upid, err = client.DeleteSnapshot(r.Context(), req.Node, guestType, req.VMID, req.Name, params)
+	}
+	if err != nil {
+		fmt.Printf("❌ %s snapshot %s failed: %v\n", guestType, operation, err)
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return
+	}
+	middleware.SetAuditUPID(r, upid)
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%s %d snapshot %s %s started", guestType, req.VMID, req.Name, operation),
+		"data":    upid,
+		"upid":    upid,
+	}
+	if req.Wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.Wait)*time.Second)
+		defer cancel()
+
+		status, err := client.WaitTask(ctx, req.Node, upid, taskPollInterval)
+		if err != nil && (status == nil || !errors.Is(err, context.DeadlineExceeded)) {
+			respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+				"success": false,
+				"error":   proxmoxErrorMessage(err),
+				"upid":    upid,
+			})
+			return
+		}
+		response["finished"] = !status.Running()
+		response["ok"] = status.Succeeded()
+		response["exitstatus"] = status.ExitStatus
+		response["task"] = status
+	}
+
+	fmt.Printf("✅ %s snapshot %s %q on %d (UPID: %s)\n", guestType, operation, req.Name, req.VMID, upid)
+	respondJSON(w, http.StatusOK, response)
+}
+
+// snapshotAge est un snapshot du rapport d'âge
+type snapshotAge struct {
+	models.ProxmoxGuestSnapshot
+	AgeSeconds int64   `json:"age_seconds"`
+	AgeDays    float64 `json:"age_days"`
+}
+
+// parseSnapshotAge lit un âge minimal en jours ("7d"), en durée Go ("36h") ou en secondes ; 0 si absent
+func parseSnapshotAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid older_than %q: use days (7d), a duration (36h) or seconds", value)
+	}
+	return age, nil
+}
+
+// GetProxmoxSnapshotAges sert les snapshots des invités du snapshot d'inventaire, du plus ancien au plus récent.
+// older_than (7d, 36h ou secondes) ne retient que les snapshots plus anciens.
+func (h *Handlers) GetProxmoxSnapshotAges(w http.ResponseWriter, r *http.Request) {
+	olderThan, err := parseSnapshotAge(r.URL.Query().Get("older_than"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	h.serveCachedList(w, r, "snapshots", func(inv *models.ProxmoxInventory) interface{} {
+		ages := []snapshotAge{}
+		for _, s := range inv.GuestSnapshots {
+			age := now.Sub(s.CreatedAt)
+			if age < olderThan {
+				continue
+			}
+			ages = append(ages, snapshotAge{
+				ProxmoxGuestSnapshot: s,
+				AgeSeconds:           int64(age.Seconds()),
+				AgeDays:              float64(int64(age.Hours()/24*100)) / 100,
+			})
+		}
+		sort.Slice(ages, func(i, j int) bool {
+			return ages[i].CreatedAt.Before(ages[j].CreatedAt)
+		})
+		return ages
+	})
+}
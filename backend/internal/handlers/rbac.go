@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// guestResource retourne la ressource de permission d'un type d'invité (proxmox.vm, proxmox.lxc)
+func guestResource(guestType proxmox.GuestType) string {
+	if guestType == proxmox.GuestLXC {
+		return "proxmox.lxc"
+	}
+	return "proxmox.vm"
+}
+
+// guestAuditTarget formate la cible d'audit d'un invité (ex: pve1/qemu/100)
+func guestAuditTarget(node string, guestType proxmox.GuestType, vmid int) string {
+	return fmt.Sprintf("%s/%s/%d", node, guestType, vmid)
+}
+
+// splitTags découpe les tags Proxmox (séparés par ';', ',' ou des espaces)
+func splitTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// guestTarget retrouve le nœud, le pool et les tags d'un invité via cluster/resources
+func guestTarget(r *http.Request, client *proxmox.Client, node string, vmid int) (models.PermissionTarget, error) {
+	target := models.PermissionTarget{Node: node}
+
+	resources, err := client.ClusterResources(r.Context(), "vm")
+	if err != nil {
+		return target, err
+	}
+	for _, res := range resources {
+		if int(res.VMID) == vmid {
+			target.Node = res.Node
+			target.Pool = res.Pool
+			target.Tags = splitTags(res.Tags)
+			break
+		}
+	}
+	return target, nil
+}
+
+// authorizeGuest vérifie que l'utilisateur courant peut effectuer resource:action sur l'invité.
+// Les permissions sans portée suffisent ; sinon le nœud, le pool et les tags de l'invité
+// sont lus dans Proxmox pour vérifier la portée. Écrit la réponse d'erreur et retourne false si refusé.
+func authorizeGuest(w http.ResponseWriter, r *http.Request, client *proxmox.Client, resource, action, node string, vmid int) bool {
+	user, ok := middleware.GetCurrentUser(r)
+	if !ok {
+		respondJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"success": false,
+			"error":   "Utilisateur non authentifié",
+		})
+		return false
+	}
+
+	if user.Can(resource, action, models.PermissionTarget{}) {
+		return true
+	}
+	if !user.HasPermission(resource, action) {
+		respondJSON(w, http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"error":   "Permission insuffisante",
+		})
+		return false
+	}
+
+	target, err := guestTarget(r, client, node, vmid)
+	if err != nil {
+		respondJSON(w, proxmox.HTTPStatus(err), map[string]interface{}{
+			"success": false,
+			"error":   proxmoxErrorMessage(err),
+		})
+		return false
+	}
+	if !user.Can(resource, action, target) {
+		fmt.Printf("⛔ %s: %s:%s refusé sur l'invité %d (nœud %s)\n", user.Username, resource, action, vmid, target.Node)
+		respondJSON(w, http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Permission %s:%s insuffisante pour l'invité %d", resource, action, vmid),
+		})
+		return false
+	}
+	return true
+}
+
+// authorizeTargets vérifie que l'utilisateur courant peut effectuer resource:action sur chacun des invités,
+// dont le nœud, le pool et les tags sont déjà connus. Écrit la réponse d'erreur et retourne false si refusé.
+func authorizeTargets(w http.ResponseWriter, r *http.Request, resource, action string, targets map[int]models.PermissionTarget) bool {
+	user, ok := middleware.GetCurrentUser(r)
+	if !ok {
+		respondJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"success": false,
+			"error":   "Utilisateur non authentifié",
+		})
+		return false
+	}
+
+	for _, vmid := range slices.Sorted(maps.Keys(targets)) {
+		target := targets[vmid]
+		if user.Can(resource, action, target) {
+			continue
+		}
+		fmt.Printf("⛔ %s: %s:%s refusé sur l'invité %d (nœud %s)\n", user.Username, resource, action, vmid, target.Node)
+		respondJSON(w, http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("Permission %s:%s insuffisante pour l'invité %d", resource, action, vmid),
+		})
+		return false
+	}
+	return true
+}
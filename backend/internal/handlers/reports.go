@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// defaultBackupMaxAge est l'âge maximal des sauvegardes sans politique configurée
+const defaultBackupMaxAge = 24 * time.Hour
+
+// GetBackupComplianceReport sert la conformité des sauvegardes de chaque invité du snapshot d'inventaire :
+// dernière archive, tendance des tailles, nombre d'archives conservées et respect de l'âge maximal de sa
+// politique. violations=true, tag et pool filtrent les invités ; format=csv ou json télécharge le rapport.
+// Calculé sur un inventaire partiel, le rapport a complete à false et ses avertissements.
+func (h *Handlers) GetBackupComplianceReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		http.Error(w, fmt.Sprintf("Unsupported format %q: use csv or json", format), http.StatusBadRequest)
+		return
+	}
+
+	inv, status, ok := h.cachedInventory(w, r)
+	if !ok {
+		return
+	}
+	success, message := false, "Inventaire Proxmox en cours de collecte"
+	if inv == nil {
+		inv = emptyInventory()
+	} else {
+		success, message = inventoryMessage(inv)
+	}
+
+	policy := h.backupPolicy
+	if policy == nil {
+		policy, _ = models.ParseBackupPolicy(defaultBackupMaxAge, "")
+	}
+	report := filterComplianceReport(models.BuildBackupComplianceReport(inv, policy, time.Now()),
+		query.Get("violations") == "true", query.Get("tag"), query.Get("pool"))
+
+	filename := fmt.Sprintf("backup-compliance-%s", report.GeneratedAt.Format("20060102-150405"))
+	switch format {
+	case "":
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"success": success,
+			"message": message,
+			"data":    report,
+			"cache":   status,
+		})
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		json.NewEncoder(w).Encode(report)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		writeComplianceCSV(w, report)
+	}
+}
+
+// filterComplianceReport ne garde que les invités demandés et recalcule les totaux du rapport
+func filterComplianceReport(report *models.BackupComplianceReport, onlyViolations bool, tag, pool string) *models.BackupComplianceReport {
+	filtered := &models.BackupComplianceReport{
+		GeneratedAt: report.GeneratedAt,
+		Complete:    report.Complete,
+		Warnings:    report.Warnings,
+		Guests:      []models.BackupCompliance{},
+	}
+	for _, g := range report.Guests {
+		if onlyViolations && g.Compliant {
+			continue
+		}
+		if tag != "" && !slices.ContainsFunc(g.Tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			continue
+		}
+		if pool != "" && g.Pool != pool {
+			continue
+		}
+		filtered.Count(g)
+		filtered.Guests = append(filtered.Guests, g)
+	}
+	return filtered
+}
+
+// writeComplianceCSV écrit la conformité des sauvegardes des invités au format CSV.
+// Le corps ne contient que l'en-tête et les lignes des invités ; la complétude du rapport et
+// ses avertissements sont portés par les en-têtes X-Report-Complete et X-Report-Warning.
+func writeComplianceCSV(w http.ResponseWriter, report *models.BackupComplianceReport) {
+	w.Header().Set("X-Report-Complete", strconv.FormatBool(report.Complete))
+	for _, warning := range report.Warnings {
+		w.Header().Add("X-Report-Warning", strings.Join(strings.Fields(warning), " "))
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"cluster", "vmid", "name", "type", "node", "pool", "tags", "last_backup", "age_hours",
+		"last_backup_size_gb", "retention_count", "size_change_percent", "max_age_hours", "policy", "compliant", "violation"})
+	for _, g := range report.Guests {
+		lastBackup, ageHours, sizeChange := "", "", ""
+		if g.LastBackup != nil {
+			lastBackup = g.LastBackup.UTC().Format(time.RFC3339)
+		}
+		if g.AgeSeconds != nil {
+			ageHours = strconv.FormatFloat(float64(*g.AgeSeconds)/3600, 'f', 1, 64)
+		}
+		if g.SizeChangePercent != nil {
+			sizeChange = strconv.FormatFloat(*g.SizeChangePercent, 'f', 2, 64)
+		}
+		writer.Write([]string{
+			csvSafe(g.Cluster),
+			strconv.Itoa(g.VMID),
+			csvSafe(g.Name),
+			g.Type,
+			csvSafe(g.Node),
+			csvSafe(g.Pool),
+			csvSafe(strings.Join(g.Tags, ";")),
+			lastBackup,
+			ageHours,
+			strconv.FormatFloat(g.LastBackupSize, 'f', 2, 64),
+			strconv.Itoa(g.RetentionCount),
+			sizeChange,
+			strconv.FormatInt(g.MaxAgeSeconds/3600, 10),
+			csvSafe(g.Policy),
+			strconv.FormatBool(g.Compliant),
+			g.Violation,
+		})
+	}
+	writer.Flush()
+}
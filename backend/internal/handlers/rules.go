@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ruleFromRequest lit la règle d'alerte désignée par {id}. Écrit la réponse d'erreur et retourne false si absente.
+func (h *Handlers) ruleFromRequest(w http.ResponseWriter, r *http.Request) (*models.AlertRule, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("alert-rule/%d", id))
+
+	rule, err := h.store.GetAlertRule(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Alert rule not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetAlertRules liste les règles d'alerte
+func (h *Handlers) GetAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.store.GetAlertRules(false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get alert rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetAlertRule retourne une règle d'alerte
+func (h *Handlers) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.ruleFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateAlertRule crée une règle d'alerte (active par défaut)
+func (h *Handlers) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert-rule.create")
+
+	rule := &models.AlertRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CreateAlertRule(rule); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create alert rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("alert-rule/%d", rule.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateAlertRule met à jour une règle d'alerte. Les champs absents du corps sont conservés ;
+// les alertes ouvertes d'une règle désactivée sont résolues à la collecte suivante.
+func (h *Handlers) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert-rule.update")
+
+	rule, ok := h.ruleFromRequest(w, r)
+	if !ok {
+		return
+	}
+	id := rule.ID
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	if err := rule.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.UpdateAlertRule(rule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Alert rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to update alert rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteAlertRule supprime une règle d'alerte et résout ses alertes ouvertes
+func (h *Handlers) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "alert-rule.delete")
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("alert-rule/%d", id))
+
+	if err := h.store.DeleteAlertRule(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Alert rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete alert rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// subscriptionFromRequest lit l'abonnement désigné par {id}. Écrit la réponse d'erreur et retourne false si absent.
+func (h *Handlers) subscriptionFromRequest(w http.ResponseWriter, r *http.Request) (*models.NotifySubscription, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid subscription ID", http.StatusBadRequest)
+		return nil, false
+	}
+	middleware.SetAuditTarget(r, fmt.Sprintf("subscription/%d", id))
+
+	sub, err := h.store.GetNotificationSubscription(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Subscription not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return sub, true
+}
+
+// GetNotificationSubscriptions liste les abonnements aux notifications (sans leurs secrets)
+func (h *Handlers) GetNotificationSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.store.GetNotificationSubscriptions()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get subscriptions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if subs == nil {
+		subs = []*models.NotifySubscription{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// UpdateSubscription active ou désactive un abonnement. La réactivation d'un webhook désactivé
+// automatiquement remet son compteur d'échecs à zéro.
+func (h *Handlers) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "notification.update")
+
+	sub, ok := h.subscriptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		http.Error(w, "Validation error: enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.SetSubscriptionEnabled(sub.ID, *req.Enabled); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subscription: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := h.store.GetNotificationSubscription(sub.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// GetSubscriptionDeliveries liste les tentatives de livraison d'un abonnement
+// (?state, limit, offset), les plus récentes d'abord
+func (h *Handlers) GetSubscriptionDeliveries(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.subscriptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	switch state {
+	case "", models.DeliveryStatePending, models.DeliveryStateDelivered, models.DeliveryStateFailed,
+		models.DeliveryStateDead, models.DeliveryStateCancelled:
+	default:
+		http.Error(w, fmt.Sprintf("Invalid state %q", state), http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	deliveries, err := h.store.GetWebhookDeliveries(sub.ID, state, limit, offset)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// PingSubscription envoie un événement de test ("ping") à un abonnement pour vérifier sa configuration
+func (h *Handlers) PingSubscription(w http.ResponseWriter, r *http.Request) {
+	middleware.SetAuditAction(r, "notification.ping")
+
+	sub, ok := h.subscriptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if h.notifier == nil || !h.notifier.Supports(sub.Channel) {
+		http.Error(w, fmt.Sprintf("No notifier configured for channel %s", sub.Channel), http.StatusServiceUnavailable)
+		return
+	}
+
+	delivery, err := h.notifier.Ping(sub.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue ping: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -0,0 +1,249 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// Cluster identifie un cluster Proxmox à interroger
+type Cluster struct {
+	ConnectionID int
+	Name         string
+	Client       *proxmox.Client
+}
+
+// Collect récupère l'inventaire complet d'un cluster.
+// Seuls les nœuds sont indispensables : les autres échecs sont retournés comme avertissements
+// et la collecte continue avec des listes vides. Le statut retourné ne porte que les avertissements
+// et les nœuds en échec ; l'identification du cluster est laissée à l'appelant.
+func Collect(ctx context.Context, client *proxmox.Client, nodeName string) (*models.ProxmoxInventory, models.ProxmoxClusterStatus, error) {
+	var status models.ProxmoxClusterStatus
+	nodes, err := FetchNodes(ctx, client)
+	if err != nil {
+		return nil, status, err
+	}
+
+	inv := &models.ProxmoxInventory{Nodes: nodes}
+	warn := func(what string, err error) {
+		fmt.Printf("⚠️ Failed to fetch %s: %v (continuing without %s)\n", what, err, what)
+		status.Warnings = append(status.Warnings, fmt.Sprintf("%s: %v", what, err))
+	}
+	warnNodes := func(what string, failed []string) {
+		for _, node := range failed {
+			status.Warnings = append(status.Warnings, fmt.Sprintf("%s: node %s unreachable", what, node))
+		}
+	}
+
+	// Sans liste d'invités, aucun nœud n'a pu être lu
+	if inv.VMs, status.FailedVMNodes, err = FetchGuests(ctx, client, proxmox.GuestQemu); err != nil {
+		warn("VMs", err)
+		inv.VMs = []models.ProxmoxGuest{}
+		status.FailedVMNodes = nodeNames(nodes)
+	} else {
+		warnNodes("VMs", status.FailedVMNodes)
+	}
+	if inv.LXC, status.FailedLXCNodes, err = FetchGuests(ctx, client, proxmox.GuestLXC); err != nil {
+		warn("LXC", err)
+		inv.LXC = []models.ProxmoxGuest{}
+		status.FailedLXCNodes = nodeNames(nodes)
+	} else {
+		warnNodes("LXC", status.FailedLXCNodes)
+	}
+	if inv.Storages, status.FailedStorageNodes, err = FetchStorages(ctx, client); err != nil {
+		warn("storages", err)
+		inv.Storages = []models.ProxmoxStorage{}
+		status.FailedStorageNodes = nodeNames(nodes)
+	} else {
+		warnNodes("storages", status.FailedStorageNodes)
+	}
+	if inv.Networks, err = FetchNetworks(ctx, client, nodeName); err != nil {
+		warn("networks", err)
+		inv.Networks = []models.ProxmoxNetwork{}
+	}
+
+	return inv, status, nil
+}
+
+// DefaultContentConcurrency est le nombre d'appels simultanés par cluster pour lire le contenu
+const DefaultContentConcurrency = 4
+
+// Content règle la collecte du contenu des clusters (archives de sauvegarde et snapshots des invités),
+// plus coûteuse que l'inventaire : un appel par storage de sauvegarde et par invité.
+// Tant que Interval n'est pas écoulé depuis la dernière lecture d'un cluster, son contenu est repris
+// du snapshot Previous. Un Interval nul relit le contenu à chaque collecte.
+type Content struct {
+	Interval    time.Duration
+	Concurrency int // appels simultanés par cluster, 1 au minimum
+	Previous    *models.ProxmoxSnapshot
+}
+
+// previousStatus retourne le statut précédent d'un cluster dont le contenu est encore frais
+func (c Content) previousStatus(cluster string, now time.Time) (models.ProxmoxClusterStatus, bool) {
+	if c.Interval <= 0 || c.Previous == nil {
+		return models.ProxmoxClusterStatus{}, false
+	}
+	for _, status := range c.Previous.Clusters {
+		if status.Name == cluster && status.ContentCollectedAt != nil && now.Sub(*status.ContentCollectedAt) < c.Interval {
+			return status, true
+		}
+	}
+	return models.ProxmoxClusterStatus{}, false
+}
+
+// CollectAll interroge les clusters en parallèle et fusionne leurs inventaires.
+// Chaque objet est étiqueté avec le nom de son cluster ; un cluster en échec n'empêche pas
+// les autres d'être servis et son erreur est reportée dans Clusters. Le contenu des clusters
+// est lu ou repris de la collecte précédente selon content.
+func CollectAll(ctx context.Context, clusters []Cluster, content Content) *models.ProxmoxInventory {
+	results := make([]*models.ProxmoxInventory, len(clusters))
+	statuses := make([]models.ProxmoxClusterStatus, len(clusters))
+
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func(i int, c Cluster) {
+			defer wg.Done()
+
+			start := time.Now()
+			inv, status, err := Collect(ctx, c.Client, "")
+			status.ConnectionID = c.ConnectionID
+			status.Name = c.Name
+			status.Success = err == nil
+			status.CollectedAt = start
+			status.DurationMs = time.Since(start).Milliseconds()
+			statuses[i] = status
+			if err != nil {
+				fmt.Printf("❌ Cluster %s: %v\n", c.Name, err)
+				statuses[i].Error = err.Error()
+				return
+			}
+			// Les tâches récentes permettent de détecter leur fin entre deux collectes
+			if inv.Tasks, err = FetchTasks(ctx, c.Client); err != nil {
+				statuses[i].Warnings = append(statuses[i].Warnings, fmt.Sprintf("tasks: %v", err))
+			}
+			if prev, ok := content.previousStatus(c.Name, start); ok {
+				carryContent(content.Previous, prev, inv, &statuses[i])
+			} else {
+				collectContent(ctx, c.Client, content.Concurrency, inv, &statuses[i])
+				statuses[i].ContentCollectedAt = &start
+			}
+			statuses[i].DurationMs = time.Since(start).Milliseconds()
+			tagCluster(inv, c.Name)
+			results[i] = inv
+		}(i, c)
+	}
+	wg.Wait()
+
+	merged := &models.ProxmoxInventory{
+		Nodes:          []models.ProxmoxNode{},
+		VMs:            []models.ProxmoxGuest{},
+		LXC:            []models.ProxmoxGuest{},
+		Storages:       []models.ProxmoxStorage{},
+		Networks:       []models.ProxmoxNetwork{},
+		Tasks:          []models.ProxmoxTask{},
+		Backups:        []models.ProxmoxBackup{},
+		GuestSnapshots: []models.ProxmoxGuestSnapshot{},
+		Clusters:       statuses,
+	}
+	// Fusion dans l'ordre des clusters pour une réponse stable
+	for _, inv := range results {
+		if inv == nil {
+			continue
+		}
+		merged.Nodes = append(merged.Nodes, inv.Nodes...)
+		merged.VMs = append(merged.VMs, inv.VMs...)
+		merged.LXC = append(merged.LXC, inv.LXC...)
+		merged.Storages = append(merged.Storages, inv.Storages...)
+		merged.Networks = append(merged.Networks, inv.Networks...)
+		merged.Tasks = append(merged.Tasks, inv.Tasks...)
+		merged.Backups = append(merged.Backups, inv.Backups...)
+		merged.GuestSnapshots = append(merged.GuestSnapshots, inv.GuestSnapshots...)
+	}
+	return merged
+}
+
+// collectContent lit les archives de sauvegarde et les snapshots des invités d'un cluster
+func collectContent(ctx context.Context, client *proxmox.Client, concurrency int, inv *models.ProxmoxInventory, status *models.ProxmoxClusterStatus) {
+	var err error
+	if inv.Backups, status.FailedBackupStorages, err = FetchBackups(ctx, client, concurrency); err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("backups: %v", err))
+		inv.Backups = []models.ProxmoxBackup{}
+		status.FailedBackupStorages = nodeNames(inv.Nodes)
+	} else {
+		for _, storage := range status.FailedBackupStorages {
+			status.Warnings = append(status.Warnings, fmt.Sprintf("backups: %s unreachable", storage))
+		}
+	}
+	inv.GuestSnapshots, status.FailedSnapshotGuests = FetchGuestSnapshots(ctx, client, slices.Concat(inv.VMs, inv.LXC), concurrency)
+	if len(status.FailedSnapshotGuests) > 0 {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("snapshots: %s unreadable", strings.Join(status.FailedSnapshotGuests, ", ")))
+	}
+}
+
+// carryContent reprend le contenu d'un cluster lu lors d'une collecte précédente
+func carryContent(prev *models.ProxmoxSnapshot, prevStatus models.ProxmoxClusterStatus, inv *models.ProxmoxInventory, status *models.ProxmoxClusterStatus) {
+	inv.Backups = []models.ProxmoxBackup{}
+	for _, b := range prev.Backups {
+		if b.Cluster == prevStatus.Name {
+			inv.Backups = append(inv.Backups, b)
+		}
+	}
+	inv.GuestSnapshots = []models.ProxmoxGuestSnapshot{}
+	for _, s := range prev.GuestSnapshots {
+		if s.Cluster == prevStatus.Name {
+			inv.GuestSnapshots = append(inv.GuestSnapshots, s)
+		}
+	}
+	for _, warning := range prevStatus.Warnings {
+		if strings.HasPrefix(warning, "backups: ") || strings.HasPrefix(warning, "snapshots: ") {
+			status.Warnings = append(status.Warnings, warning)
+		}
+	}
+	status.FailedBackupStorages = prevStatus.FailedBackupStorages
+	status.FailedSnapshotGuests = prevStatus.FailedSnapshotGuests
+	status.ContentCollectedAt = prevStatus.ContentCollectedAt
+}
+
+// nodeNames retourne le nom des nœuds
+func nodeNames(nodes []models.ProxmoxNode) []string {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+// tagCluster renseigne le cluster d'origine de chaque objet de l'inventaire
+func tagCluster(inv *models.ProxmoxInventory, name string) {
+	for i := range inv.Nodes {
+		inv.Nodes[i].Cluster = name
+	}
+	for i := range inv.VMs {
+		inv.VMs[i].Cluster = name
+	}
+	for i := range inv.LXC {
+		inv.LXC[i].Cluster = name
+	}
+	for i := range inv.Storages {
+		inv.Storages[i].Cluster = name
+	}
+	for i := range inv.Networks {
+		inv.Networks[i].Cluster = name
+	}
+	for i := range inv.Tasks {
+		inv.Tasks[i].Cluster = name
+	}
+	for i := range inv.Backups {
+		inv.Backups[i].Cluster = name
+	}
+	for i := range inv.GuestSnapshots {
+		inv.GuestSnapshots[i].Cluster = name
+	}
+}
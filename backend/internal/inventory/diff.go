@@ -0,0 +1,265 @@
+package inventory
+
+import (
+	"strconv"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// Diff compare deux snapshots successifs et retourne les événements SSE correspondants.
+// Seuls les clusters collectés avec succès dans les deux snapshots sont comparés : les objets
+// d'un cluster devenu injoignable ne sont pas signalés comme supprimés, ni les invités d'un nœud
+// dont la liste n'a pas pu être lue (ils réapparaissent ensuite sans être signalés comme créés).
+// Un storageThreshold nul ou négatif désactive les événements storage.threshold.
+func Diff(prev, next *models.ProxmoxSnapshot, storageThreshold float64) []models.SSEEvent {
+	if prev == nil || next == nil {
+		return nil
+	}
+
+	d := &differ{
+		prev:       prev,
+		next:       next,
+		comparable: comparableClusters(prev, next),
+	}
+	d.diffNodes()
+	d.diffGuests(prev.VMs, next.VMs, "qemu", models.EventVMStatusChanged, models.EventVMCreated, models.EventVMDeleted)
+	d.diffGuests(prev.LXC, next.LXC, "lxc", models.EventLXCStatusChanged, models.EventLXCCreated, models.EventLXCDeleted)
+	if storageThreshold > 0 {
+		d.diffStorages(storageThreshold)
+	}
+	d.diffTasks()
+	return d.events
+}
+
+// comparableClusters retourne les clusters collectés avec succès dans les deux snapshots
+func comparableClusters(prev, next *models.ProxmoxSnapshot) map[string]bool {
+	ok := make(map[string]bool)
+	for _, c := range prev.Clusters {
+		if c.Success {
+			ok[c.Name] = true
+		}
+	}
+
+	comparable := make(map[string]bool)
+	for _, c := range next.Clusters {
+		if c.Success && ok[c.Name] {
+			comparable[c.Name] = true
+		}
+	}
+	return comparable
+}
+
+// FailedGuestNodes retourne les nœuds du snapshot dont les invités du type donné (qemu ou lxc)
+// n'ont pas pu être listés, indexés par cluster et nom de nœud (voir NodeKey)
+func FailedGuestNodes(snapshot *models.ProxmoxSnapshot, guestType string) map[string]bool {
+	failed := make(map[string]bool)
+	if snapshot == nil {
+		return failed
+	}
+	for _, c := range snapshot.Clusters {
+		for _, node := range c.FailedNodes(guestType) {
+			failed[NodeKey(c.Name, node)] = true
+		}
+	}
+	return failed
+}
+
+// NodeKey identifie un nœud de manière unique entre clusters
+func NodeKey(cluster, node string) string {
+	return key(cluster, node)
+}
+
+// differ accumule les événements produits par la comparaison de deux snapshots
+type differ struct {
+	prev, next *models.ProxmoxSnapshot
+	comparable map[string]bool
+	events     []models.SSEEvent
+}
+
+// emit ajoute un événement
+func (d *differ) emit(eventType string, data interface{}) {
+	d.events = append(d.events, models.SSEEvent{Type: eventType, Data: data})
+}
+
+// source construit l'identification commune des événements
+func (d *differ) source(cluster, kind, id, name, node string) models.InventoryEventSource {
+	return models.InventoryEventSource{
+		Cluster:   cluster,
+		Kind:      kind,
+		ID:        id,
+		Name:      name,
+		Node:      node,
+		Timestamp: d.next.CollectedAt,
+	}
+}
+
+// guestSource construit l'identification d'un invité, avec le pool et les tags
+// qui servent à filtrer les événements selon la portée des permissions
+func (d *differ) guestSource(g models.ProxmoxGuest, kind string) models.InventoryEventSource {
+	src := d.source(g.Cluster, kind, strconv.Itoa(g.VMID), g.Name, g.Node)
+	src.Pool = g.Pool
+	src.Tags = g.Tags
+	return src
+}
+
+// key identifie un objet de manière unique entre clusters
+func key(cluster, id string) string {
+	return cluster + "/" + id
+}
+
+// diffNodes signale les nœuds passant hors ligne ou revenant en ligne
+func (d *differ) diffNodes() {
+	before := make(map[string]models.ProxmoxNode)
+	for _, n := range d.prev.Nodes {
+		before[key(n.Cluster, n.ID)] = n
+	}
+
+	for _, n := range d.next.Nodes {
+		if !d.comparable[n.Cluster] {
+			continue
+		}
+		old, ok := before[key(n.Cluster, n.ID)]
+		if !ok || old.Status == n.Status {
+			continue
+		}
+
+		event := models.StatusChangeEvent{
+			InventoryEventSource: d.source(n.Cluster, "node", n.ID, n.Name, n.Name),
+			Before:               old.Status,
+			After:                n.Status,
+		}
+		switch {
+		case old.Status == "online":
+			d.emit(models.EventNodeOffline, event)
+		case n.Status == "online":
+			d.emit(models.EventNodeOnline, event)
+		}
+	}
+}
+
+// diffGuests signale les changements de statut, créations et suppressions d'invités
+func (d *differ) diffGuests(prev, next []models.ProxmoxGuest, kind, statusEvent, createdEvent, deletedEvent string) {
+	before := make(map[string]models.ProxmoxGuest)
+	for _, g := range prev {
+		before[key(g.Cluster, strconv.Itoa(g.VMID))] = g
+	}
+	after := make(map[string]bool)
+	failedBefore := FailedGuestNodes(d.prev, kind)
+	failedAfter := FailedGuestNodes(d.next, kind)
+
+	for i := range next {
+		g := next[i]
+		if !d.comparable[g.Cluster] {
+			continue
+		}
+		k := key(g.Cluster, strconv.Itoa(g.VMID))
+		after[k] = true
+		src := d.guestSource(g, kind)
+
+		old, ok := before[k]
+		switch {
+		case !ok && failedBefore[NodeKey(g.Cluster, g.Node)]:
+			continue // nœud illisible lors de la collecte précédente : l'invité existait peut-être déjà
+		case !ok:
+			d.emit(createdEvent, models.GuestLifecycleEvent{InventoryEventSource: src, After: &g})
+		case old.Status != g.Status:
+			d.emit(statusEvent, models.StatusChangeEvent{InventoryEventSource: src, Before: old.Status, After: g.Status})
+		}
+	}
+
+	for i := range prev {
+		g := prev[i]
+		if !d.comparable[g.Cluster] || after[key(g.Cluster, strconv.Itoa(g.VMID))] || failedAfter[NodeKey(g.Cluster, g.Node)] {
+			continue
+		}
+		src := d.guestSource(g, kind)
+		d.emit(deletedEvent, models.GuestLifecycleEvent{InventoryEventSource: src, Before: &g})
+	}
+}
+
+// diffStorages signale les storages franchissant le seuil d'occupation
+func (d *differ) diffStorages(threshold float64) {
+	before := make(map[string]models.ProxmoxStorage)
+	for _, s := range d.prev.Storages {
+		before[key(s.Cluster, s.ID)] = s
+	}
+
+	for _, s := range d.next.Storages {
+		if !d.comparable[s.Cluster] {
+			continue
+		}
+		old, ok := before[key(s.Cluster, s.ID)]
+		if !ok {
+			continue
+		}
+
+		wasAbove := old.UsagePercent >= threshold
+		isAbove := s.UsagePercent >= threshold
+		if wasAbove == isAbove {
+			continue
+		}
+		d.emit(models.EventStorageThreshold, models.StorageThresholdEvent{
+			InventoryEventSource: d.source(s.Cluster, "storage", s.ID, s.Name, s.Node),
+			Threshold:            threshold,
+			Exceeded:             isAbove,
+			Before:               old.UsagePercent,
+			After:                s.UsagePercent,
+		})
+	}
+}
+
+// diffTasks signale les tâches terminées depuis la collecte précédente
+func (d *differ) diffTasks() {
+	for _, event := range finishedTasks(d.prev, d.next, d.comparable) {
+		d.emit(models.EventTaskFinished, event)
+	}
+}
+
+// FinishedTasks retourne les tâches terminées depuis la collecte précédente, pour les clusters
+// collectés avec succès dans les deux snapshots (voir Diff)
+func FinishedTasks(prev, next *models.ProxmoxSnapshot) []models.TaskFinishedEvent {
+	if prev == nil || next == nil {
+		return nil
+	}
+	return finishedTasks(prev, next, comparableClusters(prev, next))
+}
+
+// finishedTasks compare les tâches de deux snapshots : une tâche est signalée une seule fois,
+// quand elle est terminée et qu'elle était en cours ou inconnue lors de la collecte précédente
+func finishedTasks(prev, next *models.ProxmoxSnapshot, comparable map[string]bool) []models.TaskFinishedEvent {
+	before := make(map[string]models.ProxmoxTask)
+	for _, t := range prev.Tasks {
+		before[key(t.Cluster, t.ID)] = t
+	}
+
+	var events []models.TaskFinishedEvent
+	for i := range next.Tasks {
+		t := next.Tasks[i]
+		if !comparable[t.Cluster] || (t.Status != "completed" && t.Status != "failed") {
+			continue
+		}
+
+		event := models.TaskFinishedEvent{
+			InventoryEventSource: models.InventoryEventSource{
+				Cluster:   t.Cluster,
+				Kind:      "task",
+				ID:        t.ID,
+				Name:      t.Type,
+				Node:      t.Node,
+				Timestamp: next.CollectedAt,
+			},
+			After: &t,
+		}
+		old, ok := before[key(t.Cluster, t.ID)]
+		switch {
+		case ok && old.Status == "running":
+			event.Before = &old
+		case ok:
+			continue // déjà terminée lors de la collecte précédente
+		case t.CompletedAt == nil || t.CompletedAt.Before(prev.CollectedAt):
+			continue // tâche ancienne sortie puis revenue dans la liste
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,701 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// percent calcule un pourcentage arrondi à deux décimales
+func percent(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round2(float64(used) / float64(total) * 100)
+}
+
+// round2 arrondit une valeur à deux décimales
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// bytesToGB convertit des octets en GB
+func bytesToGB(b int64) float64 {
+	return float64(b) / (1024 * 1024 * 1024)
+}
+
+// FetchNodes récupère les nœuds avec leurs métriques et la liste de leurs invités
+func FetchNodes(ctx context.Context, client *proxmox.Client) ([]models.ProxmoxNode, error) {
+	resources, err := client.ClusterResources(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Collecter les VMs et LXC par nœud
+	nodeVMs := make(map[string][]models.ProxmoxGuestSummary)
+	nodeLXC := make(map[string][]models.ProxmoxGuestSummary)
+	for _, res := range resources {
+		if !res.IsGuest() {
+			continue
+		}
+		summary := models.ProxmoxGuestSummary{
+			ID:     int(res.VMID),
+			Name:   res.Name,
+			Status: res.Status,
+			Type:   res.Type,
+		}
+		if res.Type == "lxc" {
+			nodeLXC[res.Node] = append(nodeLXC[res.Node], summary)
+		} else {
+			nodeVMs[res.Node] = append(nodeVMs[res.Node], summary)
+		}
+	}
+
+	var nodes []models.ProxmoxNode
+	for _, res := range resources {
+		if res.Type != "node" {
+			continue
+		}
+
+		node := models.ProxmoxNode{
+			ID:         res.Node,
+			Name:       res.Node,
+			Status:     res.Status,
+			LastUpdate: time.Now(),
+			VMsCount:   len(nodeVMs[res.Node]),
+			LXCCount:   len(nodeLXC[res.Node]),
+			VMs:        nodeVMs[res.Node],
+			LXC:        nodeLXC[res.Node],
+		}
+
+		if err := fetchNodeMetrics(ctx, client, &node); err != nil {
+			// Repli sur les valeurs instantanées de cluster/resources (aucune valeur inventée)
+			fmt.Printf("⚠️ Failed to fetch metrics for node %s: %v (using cluster resources)\n", res.Node, err)
+			applyResourceMetrics(&node, res)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// fetchNodeMetrics complète un nœud avec ses métriques réelles (status et interfaces réseau)
+func fetchNodeMetrics(ctx context.Context, client *proxmox.Client, node *models.ProxmoxNode) error {
+	status, err := client.NodeStatus(ctx, node.Name)
+	if err != nil {
+		return err
+	}
+
+	node.CPUUsage = round2(status.CPU * 100)
+	node.MemoryUsage = percent(status.Memory.Used, status.Memory.Total)
+	node.DiskUsage = percent(status.RootFS.Used, status.RootFS.Total)
+	node.Uptime = status.Uptime
+	node.Version = status.PVEVersion
+	node.KVersion = status.KVersion
+	node.LoadAvg = strings.Join(status.LoadAvg, ", ")
+	node.CPUInfo = fmt.Sprintf("%d x %s (%d Support de processeur)", status.CPUInfo.CPUs, status.CPUInfo.Model, status.CPUInfo.Sockets)
+	node.MemInfo = fmt.Sprintf("%.2f GiB sur %.2f GiB", bytesToGB(status.Memory.Used), bytesToGB(status.Memory.Total))
+	node.SwapInfo = fmt.Sprintf("%.2f%% (%.2f MiB sur %.2f GiB)",
+		percent(status.Swap.Used, status.Swap.Total), float64(status.Swap.Used)/1024/1024, bytesToGB(status.Swap.Total))
+	node.DiskInfo = fmt.Sprintf("%.2f GiB sur %.2f GiB", bytesToGB(status.RootFS.Used), bytesToGB(status.RootFS.Total))
+
+	// Chercher l'IP de l'interface principale (vmbr0 ou eth0)
+	node.IPAddress = "N/A"
+	if ifaces, err := client.NodeNetwork(ctx, node.Name); err == nil {
+		for _, iface := range ifaces {
+			if (iface.Iface == "vmbr0" || iface.Iface == "eth0") && iface.Address != "" {
+				node.IPAddress = strings.Split(iface.Address, "/")[0]
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyResourceMetrics renseigne les métriques d'un nœud depuis son entrée cluster/resources
+func applyResourceMetrics(node *models.ProxmoxNode, res proxmox.Resource) {
+	node.CPUUsage = round2(res.CPU * 100)
+	node.MemoryUsage = percent(res.Mem, res.MaxMem)
+	node.DiskUsage = percent(res.Disk, res.MaxDisk)
+	node.Uptime = res.Uptime
+	node.MemInfo = fmt.Sprintf("%.2f GiB sur %.2f GiB", bytesToGB(res.Mem), bytesToGB(res.MaxMem))
+	node.DiskInfo = fmt.Sprintf("%.2f GiB sur %.2f GiB", bytesToGB(res.Disk), bytesToGB(res.MaxDisk))
+	node.IPAddress = "N/A"
+}
+
+// FetchGuests récupère les VMs ou les conteneurs LXC de tous les nœuds.
+// Les nœuds dont les invités n'ont pas pu être listés sont retournés à part : leurs invités
+// manquent à la liste mais ne doivent pas être considérés comme supprimés.
+func FetchGuests(ctx context.Context, client *proxmox.Client, guestType proxmox.GuestType) ([]models.ProxmoxGuest, []string, error) {
+	nodes, err := client.Nodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Les pools ne sont connus que de cluster/resources ; sans eux, les invités restent listés
+	pools := map[int]string{}
+	if resources, err := client.ClusterResources(ctx, "vm"); err != nil {
+		fmt.Printf("⚠️ Failed to fetch guest pools: %v (continuing)\n", err)
+	} else {
+		for _, res := range resources {
+			if res.Pool != "" {
+				pools[int(res.VMID)] = res.Pool
+			}
+		}
+	}
+
+	guests := []models.ProxmoxGuest{}
+	var failed []string
+	for _, node := range nodes {
+		list, err := client.Guests(ctx, node.Node, guestType)
+		if err != nil {
+			// Un nœud hors ligne ne doit pas empêcher la récupération des autres
+			fmt.Printf("⚠️ Failed to fetch %s from node %s: %v (continuing)\n", guestType, node.Node, err)
+			failed = append(failed, node.Node)
+			continue
+		}
+
+		for _, g := range list {
+			guest := models.ProxmoxGuest{
+				ID:          int(g.VMID),
+				VMID:        int(g.VMID),
+				Name:        g.Name,
+				Type:        string(guestType),
+				Status:      g.Status,
+				Node:        node.Node,
+				Tags:        g.Tags,
+				Pool:        pools[int(g.VMID)],
+				Template:    bool(g.Template),
+				CPUUsage:    round2(g.CPU * 100),
+				MemoryUsage: percent(g.Mem, g.MaxMem),
+				DiskUsage:   percent(g.Disk, g.MaxDisk),
+				Uptime:      g.Uptime,
+				MaxCPU:      g.CPUs,
+				MaxMem:      g.MaxMem,
+				Disk:        g.Disk,
+				LastUpdate:  time.Now(),
+			}
+
+			if g.Status == "running" {
+				guest.IPAddress = fetchGuestIP(ctx, client, node.Node, guestType, int(g.VMID))
+			}
+
+			guests = append(guests, guest)
+		}
+	}
+
+	fmt.Printf("✅ Total %s found across all nodes: %d\n", guestType, len(guests))
+	return guests, failed, nil
+}
+
+// fetchGuestIP récupère la première adresse IPv4 d'un invité en cours d'exécution.
+// Les VMs passent par le guest agent QEMU, les conteneurs par leurs interfaces puis leur configuration.
+func fetchGuestIP(ctx context.Context, client *proxmox.Client, node string, guestType proxmox.GuestType, vmid int) string {
+	if guestType == proxmox.GuestQemu {
+		// L'agent n'est pas disponible sur toutes les VMs, c'est normal
+		ifaces, err := client.QemuAgentInterfaces(ctx, node, vmid)
+		if err != nil {
+			return ""
+		}
+		for _, iface := range ifaces {
+			if iface.Name == "lo" {
+				continue
+			}
+			if ip := iface.IPv4(); ip != "" {
+				return ip
+			}
+		}
+		return ""
+	}
+
+	// Méthode 1: interfaces du conteneur (fonctionne aussi en DHCP)
+	if ifaces, err := client.ContainerInterfaces(ctx, node, vmid); err == nil {
+		for _, iface := range ifaces {
+			if iface.Name == "lo" || iface.Inet == "" {
+				continue
+			}
+			return strings.Split(iface.Inet, "/")[0]
+		}
+	}
+
+	// Méthode 2: adresse statique déclarée dans la configuration (net0, net1...)
+	config, err := client.GuestConfig(ctx, node, proxmox.GuestLXC, vmid)
+	if err != nil {
+		return ""
+	}
+	for key, value := range config {
+		netStr, ok := value.(string)
+		if !ok || !strings.HasPrefix(key, "net") {
+			continue
+		}
+		// Format: name=eth0,bridge=vmbr0,ip=dhcp ou ip=192.168.1.100/24
+		for _, part := range strings.Split(netStr, ",") {
+			ip := strings.Split(strings.TrimPrefix(part, "ip="), "/")[0]
+			if strings.HasPrefix(part, "ip=") && ip != "dhcp" && ip != "" {
+				return ip
+			}
+		}
+	}
+	return ""
+}
+
+// FetchStorages récupère les storages de tous les nœuds (sans doublons).
+// Les nœuds dont les storages n'ont pas pu être listés sont retournés à part (voir FetchGuests).
+func FetchStorages(ctx context.Context, client *proxmox.Client) ([]models.ProxmoxStorage, []string, error) {
+	nodes, err := client.Nodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storages := []models.ProxmoxStorage{}
+	var failed []string
+	seen := make(map[string]bool) // Même storage partagé sur plusieurs nœuds
+	for _, node := range nodes {
+		list, err := client.NodeStorages(ctx, node.Node)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to fetch storages for node %s: %v\n", node.Node, err)
+			failed = append(failed, node.Node)
+			continue
+		}
+
+		for _, s := range list {
+			if s.Storage == "" || seen[s.Storage] {
+				continue
+			}
+			seen[s.Storage] = true
+
+			status := "online"
+			if !s.Enabled {
+				status = "offline"
+			}
+
+			storages = append(storages, models.ProxmoxStorage{
+				ID:           s.Storage,
+				Name:         s.Storage,
+				Type:         s.Type,
+				Status:       status,
+				Node:         node.Node,
+				TotalSpace:   round2(bytesToGB(s.Total)),
+				UsedSpace:    round2(bytesToGB(s.Used)),
+				FreeSpace:    round2(bytesToGB(s.Avail)),
+				UsagePercent: percent(s.Used, s.Total),
+				Content:      s.Content,
+				LastUpdate:   time.Now(),
+			})
+		}
+	}
+
+	return storages, failed, nil
+}
+
+// FetchNetworks récupère les interfaces réseau.
+// Récupère les interfaces de tous les nœuds si nodeName est vide.
+func FetchNetworks(ctx context.Context, client *proxmox.Client, nodeName string) ([]models.ProxmoxNetwork, error) {
+	nodes, err := client.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if nodeName == "" || node.Node == nodeName {
+			names = append(names, node.Node)
+		}
+	}
+	if nodeName != "" && len(names) == 0 {
+		return nil, fmt.Errorf("node %s: %w", nodeName, proxmox.ErrNotFound)
+	}
+
+	networks := []models.ProxmoxNetwork{}
+	for _, name := range names {
+		ifaces, err := client.NodeNetwork(ctx, name)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to fetch networks from node %s: %v\n", name, err)
+			continue
+		}
+
+		for _, iface := range ifaces {
+			status := "inactive"
+			if iface.Active {
+				status = "active"
+			}
+			networks = append(networks, models.ProxmoxNetwork{
+				ID:         fmt.Sprintf("%s-%s", name, iface.Iface),
+				Name:       iface.Iface,
+				Type:       iface.Type,
+				Status:     status,
+				Node:       name,
+				IPAddress:  iface.Address,
+				Netmask:    iface.Netmask,
+				Gateway:    iface.Gateway,
+				Active:     bool(iface.Active),
+				LastUpdate: time.Now(),
+			})
+		}
+	}
+
+	return networks, nil
+}
+
+// FetchBackups récupère les archives de sauvegarde des storages de contenu backup de tous les nœuds.
+// Un storage partagé n'est lu qu'une fois, depuis le nœud suivant tant que la lecture échoue ; le type et
+// le VMID sont lus dans le contenu du storage ou, à défaut, dans le volid (vzdump-qemu-100-..., backup/ct/101/...
+// pour PBS). Les storages sont lus avec au plus concurrency appels simultanés.
+// Les storages qui n'ont pu être lus sur aucun nœud sont retournés à part ("nœud/storage", ou le nœud
+// seul quand ses storages n'ont pas pu être listés) : leurs archives manquent à la liste.
+func FetchBackups(ctx context.Context, client *proxmox.Client, concurrency int) ([]models.ProxmoxBackup, []string, error) {
+	nodes, err := client.Nodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Storages à lire : un storage local par nœud, un storage partagé avec la liste des nœuds qui y accèdent
+	type backupStorage struct {
+		name  string
+		nodes []string
+	}
+	var storages []*backupStorage
+	var failed []string
+	shared := make(map[string]*backupStorage)
+	for _, node := range nodes {
+		list, err := client.NodeStorages(ctx, node.Node)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to fetch storages for node %s: %v\n", node.Node, err)
+			failed = append(failed, node.Node)
+			continue
+		}
+
+		for _, s := range list {
+			if !bool(s.Active) || !slices.Contains(strings.Split(s.Content, ","), proxmox.BackupContent) {
+				continue
+			}
+			if st := shared[s.Storage]; bool(s.Shared) && st != nil {
+				st.nodes = append(st.nodes, node.Node)
+				continue
+			}
+			st := &backupStorage{name: s.Storage, nodes: []string{node.Node}}
+			if bool(s.Shared) {
+				shared[s.Storage] = st
+			}
+			storages = append(storages, st)
+		}
+	}
+
+	results := make([][]models.ProxmoxBackup, len(storages))
+	unread := make([]string, len(storages)) // premier nœud en échec d'un storage resté illisible
+	ForEach(len(storages), concurrency, func(i int) {
+		st := storages[i]
+		for _, node := range st.nodes {
+			list, err := client.StorageBackups(ctx, node, st.name)
+			if err != nil {
+				fmt.Printf("⚠️ Failed to fetch backups from storage %s on node %s: %v\n", st.name, node, err)
+				if unread[i] == "" {
+					unread[i] = node + "/" + st.name
+				}
+				continue
+			}
+			results[i], unread[i] = convertBackups(list, node, st.name), ""
+			return
+		}
+	})
+
+	backups := []models.ProxmoxBackup{}
+	for i := range storages {
+		backups = append(backups, results[i]...)
+		if unread[i] != "" {
+			failed = append(failed, unread[i])
+		}
+	}
+
+	fmt.Printf("✅ Backups fetched: %d backups\n", len(backups))
+	return backups, failed, nil
+}
+
+// convertBackups convertit le contenu backup d'un storage lu depuis un nœud
+func convertBackups(list []proxmox.Backup, node, storage string) []models.ProxmoxBackup {
+	backups := []models.ProxmoxBackup{}
+	for _, b := range list {
+		volume, ok := b.Volume()
+		if !ok {
+			continue // archive qui n'est pas celle d'un invité
+		}
+		backupType := "vm"
+		if volume.GuestType == proxmox.GuestLXC {
+			backupType = "lxc"
+		}
+		created := volume.Time
+		backups = append(backups, models.ProxmoxBackup{
+			ID:          b.VolID,
+			Name:        b.VolID,
+			Type:        backupType,
+			Status:      "completed",
+			Size:        bytesToGB(b.Size),
+			StartedAt:   created,
+			CompletedAt: created,
+			Node:        node,
+			Storage:     storage,
+			Format:      b.Format,
+			Notes:       b.Notes,
+			Protected:   bool(b.Protected),
+			VMID:        volume.VMID,
+			CreatedAt:   created,
+		})
+	}
+	return backups
+}
+
+// FetchGuestSnapshots récupère les snapshots des invités déjà collectés (l'état courant est ignoré),
+// avec au plus concurrency appels simultanés. Les invités dont les snapshots ne peuvent pas être lus sont
+// retournés à part ("type/vmid") : l'absence de leurs snapshots ne signifie pas qu'ils n'en ont pas.
+func FetchGuestSnapshots(ctx context.Context, client *proxmox.Client, guests []models.ProxmoxGuest, concurrency int) ([]models.ProxmoxGuestSnapshot, []string) {
+	results := make([][]models.ProxmoxGuestSnapshot, len(guests))
+	failures := make([]bool, len(guests))
+	ForEach(len(guests), concurrency, func(i int) {
+		g := guests[i]
+		list, err := client.GuestSnapshots(ctx, g.Node, proxmox.GuestType(g.Type), g.VMID)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to fetch snapshots of %s %d: %v\n", g.Type, g.VMID, err)
+			failures[i] = true
+			return
+		}
+		for _, s := range list {
+			if s.IsCurrent() {
+				continue
+			}
+			results[i] = append(results[i], models.ProxmoxGuestSnapshot{
+				Node:        g.Node,
+				Type:        g.Type,
+				VMID:        g.VMID,
+				GuestName:   g.Name,
+				Name:        s.Name,
+				Description: s.Description,
+				Parent:      s.Parent,
+				VMState:     bool(s.VMState),
+				CreatedAt:   s.CreatedAt(),
+			})
+		}
+	})
+
+	snapshots := slices.Concat(results...)
+	if snapshots == nil {
+		snapshots = []models.ProxmoxGuestSnapshot{}
+	}
+	var failed []string
+	for i, g := range guests {
+		if failures[i] {
+			failed = append(failed, models.GuestRef(g.Type, g.VMID))
+		}
+	}
+	fmt.Printf("✅ Snapshots fetched: %d snapshots\n", len(snapshots))
+	return snapshots, failed
+}
+
+// ForEach appelle fn pour chaque indice de 0 à n-1, avec au plus concurrency appels simultanés
+func ForEach(n, concurrency int, fn func(i int)) {
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// FetchTasks récupère les tâches récentes du cluster
+func FetchTasks(ctx context.Context, client *proxmox.Client) ([]models.ProxmoxTask, error) {
+	list, err := client.ClusterTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]models.ProxmoxTask, 0, len(list))
+	for _, t := range list {
+		tasks = append(tasks, ConvertTask(t))
+	}
+
+	fmt.Printf("✅ Tasks fetched: %d tasks\n", len(tasks))
+	return tasks, nil
+}
+
+// ConvertTask convertit une tâche Proxmox au format du dashboard
+func ConvertTask(t proxmox.Task) models.ProxmoxTask {
+	// Déterminer le statut : une tâche sans endtime est toujours en cours
+	status := "pending"
+	switch {
+	case t.Status == "running" || (t.Status == "" && t.EndTime == 0 && t.StartTime > 0):
+		status = "running"
+	case t.Status == "OK":
+		status = "completed"
+	case t.Status != "":
+		status = "failed"
+	}
+
+	started := time.Unix(t.StartTime, 0)
+	task := models.ProxmoxTask{
+		ID:        t.UPID,
+		Name:      t.ID,
+		Type:      t.Type,
+		Status:    status,
+		StartedAt: started,
+		User:      t.User,
+		Node:      t.Node,
+		CreatedAt: started,
+	}
+	if status == "completed" || status == "failed" {
+		task.ExitStatus = t.Status
+	}
+	if t.EndTime > 0 {
+		completed := time.Unix(t.EndTime, 0)
+		task.CompletedAt = &completed
+		if t.StartTime > 0 {
+			duration := int(t.EndTime - t.StartTime)
+			task.Duration = &duration
+		}
+	}
+	return task
+}
+
+// FetchDocker récupère les conteneurs Docker depuis Proxmox (via LXC)
+func FetchDocker(ctx context.Context, client *proxmox.Client) ([]map[string]interface{}, error) {
+	// Les conteneurs Docker dans Proxmox sont généralement des LXC
+	// On récupère les LXC et on les convertit en format Docker
+	containers, _, err := FetchGuests(ctx, client, proxmox.GuestLXC)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerContainers := []map[string]interface{}{}
+	for _, lxc := range containers {
+		dockerContainers = append(dockerContainers, map[string]interface{}{
+			"id":           fmt.Sprintf("%d", lxc.VMID),
+			"name":         lxc.Name,
+			"status":       lxc.Status,
+			"image":        "proxmox-lxc",
+			"tag":          "latest",
+			"cpu_usage":    lxc.CPUUsage,
+			"memory_usage": lxc.MemoryUsage,
+			"memory_limit": lxc.MaxMem / (1024 * 1024), // en MB
+			"uptime":       lxc.Uptime,
+			"ports":        []string{},
+			"created_at":   lxc.LastUpdate.Format(time.RFC3339),
+			"node":         lxc.Node,
+		})
+	}
+
+	fmt.Printf("✅ Docker containers fetched: %d containers\n", len(dockerContainers))
+	return dockerContainers, nil
+}
+
+// dbKeywords associe les mots-clés présents dans les noms ou tags à un type de base de données.
+// L'ordre compte : les mots-clés génériques (db, database) sont testés en dernier.
+var dbKeywords = []struct {
+	keyword string
+	dbType  string
+}{
+	{"mysql", "mysql"},
+	{"mariadb", "mysql"},
+	{"postgres", "postgresql"},
+	{"mongo", "mongodb"},
+	{"redis", "redis"},
+	{"elastic", "elasticsearch"},
+	{"database", "mysql"},
+	{"db", "mysql"},
+}
+
+// dbPorts contient le port par défaut de chaque type de base de données
+var dbPorts = map[string]int{
+	"mysql":         3306,
+	"postgresql":    5432,
+	"mongodb":       27017,
+	"redis":         6379,
+	"elasticsearch": 9200,
+}
+
+// detectDatabaseType retourne le type de base de données correspondant au texte, ou une chaîne vide
+func detectDatabaseType(text string) string {
+	text = strings.ToLower(text)
+	for _, kw := range dbKeywords {
+		if strings.Contains(text, kw.keyword) {
+			return kw.dbType
+		}
+	}
+	return ""
+}
+
+// FetchDatabases récupère les bases de données depuis Proxmox
+// Détecte automatiquement les bases de données dans les VMs et LXC en analysant les noms et tags
+func FetchDatabases(ctx context.Context, client *proxmox.Client) ([]map[string]interface{}, error) {
+	resources, err := client.ClusterResources(ctx, "vm")
+	if err != nil {
+		return nil, err
+	}
+
+	databases := []map[string]interface{}{}
+	for _, res := range resources {
+		if !res.IsGuest() {
+			continue
+		}
+
+		detectedType := detectDatabaseType(res.Name)
+		if detectedType == "" {
+			detectedType = detectDatabaseType(res.Tags)
+		}
+		if detectedType == "" {
+			continue
+		}
+
+		dbStatus := "maintenance"
+		if res.Status == "running" || res.Status == "stopped" {
+			dbStatus = res.Status
+		}
+
+		resourceType := res.Type
+		if resourceType == "qemu" {
+			resourceType = "vm"
+		}
+
+		diskSize := bytesToGB(res.MaxDisk)
+		databases = append(databases, map[string]interface{}{
+			"id":              fmt.Sprintf("%s-%d", res.Type, res.VMID),
+			"name":            res.Name,
+			"type":            detectedType,
+			"status":          dbStatus,
+			"host":            "localhost", // Par défaut, peut être amélioré avec l'IP
+			"port":            dbPorts[detectedType],
+			"version":         "N/A",
+			"cpu_usage":       round2(res.CPU * 100),
+			"memory_usage":    percent(res.Mem, res.MaxMem),
+			"disk_usage":      diskSize,
+			"connections":     0,
+			"max_connections": 100,
+			"uptime":          res.Uptime,
+			"size":            diskSize,
+			"created_at":      time.Now().Format(time.RFC3339),
+			"ssl_enabled":     false,
+			"authentication":  "none",
+			// Informations pour ouvrir la VM/LXC
+			"resource_type": resourceType,
+			"resource_id":   int(res.VMID),
+			"node":          res.Node,
+		})
+	}
+
+	fmt.Printf("✅ Databases fetched: %d databases detected\n", len(databases))
+	return databases, nil
+}
@@ -0,0 +1,205 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/store"
+)
+
+// minPollTimeout est la durée minimale accordée à une collecte
+const minPollTimeout = 30 * time.Second
+
+// ClustersFromConnections construit les clusters à interroger à partir des connexions actives,
+// limités aux identifiants de wanted s'il n'est pas vide
+func ClustersFromConnections(conns []*models.ProxmoxConnection, wanted map[int]bool) []Cluster {
+	var clusters []Cluster
+	for _, conn := range conns {
+		if !conn.Enabled || (len(wanted) > 0 && !wanted[conn.ID]) {
+			continue
+		}
+		clusters = append(clusters, Cluster{
+			ConnectionID: conn.ID,
+			Name:         conn.Name,
+			Client:       proxmox.NewClient(conn.URL, conn.Username, conn.Secret),
+		})
+	}
+	return clusters
+}
+
+// SnapshotListener est appelé après chaque collecte réussie avec le snapshot précédent (nil au premier) et le nouveau
+type SnapshotListener func(prev, next *models.ProxmoxSnapshot)
+
+// Poller collecte périodiquement l'inventaire de toutes les connexions Proxmox actives.
+// Le dernier snapshot est conservé en mémoire et en base pour être servi par l'API
+// sans solliciter pveproxy à chaque chargement de page.
+type Poller struct {
+	store    *store.Store
+	interval time.Duration
+	content  Content // planification de la lecture des sauvegardes et snapshots (Previous ignoré)
+	quit     chan bool
+
+	pollMu    sync.Mutex // une seule collecte à la fois (ticker ou rafraîchissement manuel)
+	listeners []SnapshotListener
+
+	mu        sync.RWMutex
+	snapshot  *models.ProxmoxSnapshot
+	polling   bool
+	lastError string
+}
+
+// NewPoller crée un nouveau poller d'inventaire
+func NewPoller(store *store.Store, interval time.Duration) *Poller {
+	return &Poller{
+		store:    store,
+		interval: interval,
+		content:  Content{Concurrency: DefaultContentConcurrency},
+		quit:     make(chan bool),
+	}
+}
+
+// SetContentSchedule configure la lecture des archives de sauvegarde et des snapshots des invités :
+// toutes les interval au plus (à chaque collecte si nul), avec concurrency appels simultanés par cluster
+func (p *Poller) SetContentSchedule(interval time.Duration, concurrency int) {
+	p.content = Content{Interval: interval, Concurrency: concurrency}
+}
+
+// OnSnapshot enregistre un listener appelé après chaque collecte (à appeler avant Start)
+func (p *Poller) OnSnapshot(listener SnapshotListener) {
+	p.listeners = append(p.listeners, listener)
+}
+
+// Start restaure le dernier snapshot enregistré puis démarre la collecte périodique
+func (p *Poller) Start() {
+	log.Printf("Starting Proxmox inventory poller (every %s)...", p.interval)
+
+	snapshot, err := p.store.GetProxmoxSnapshot()
+	switch {
+	case err == nil:
+		p.mu.Lock()
+		p.snapshot = snapshot
+		p.mu.Unlock()
+	case !errors.Is(err, sql.ErrNoRows):
+		log.Printf("⚠️  Failed to restore proxmox snapshot: %v", err)
+	}
+
+	go p.run()
+}
+
+// Stop arrête le poller
+func (p *Poller) Stop() {
+	p.quit <- true
+}
+
+// run est la boucle principale du poller
+func (p *Poller) run() {
+	p.Refresh(context.Background())
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.Refresh(context.Background())
+		case <-p.quit:
+			log.Println("Proxmox inventory poller stopped")
+			return
+		}
+	}
+}
+
+// Refresh collecte immédiatement l'inventaire et remplace le snapshot courant
+func (p *Poller) Refresh(ctx context.Context) (*models.ProxmoxSnapshot, error) {
+	p.pollMu.Lock()
+	defer p.pollMu.Unlock()
+
+	p.setPolling(true)
+	defer p.setPolling(false)
+
+	snapshot, err := p.poll(ctx)
+	p.mu.Lock()
+	prev := p.snapshot
+	if err != nil {
+		p.lastError = err.Error()
+	} else {
+		p.snapshot = snapshot
+		p.lastError = ""
+	}
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("❌ Proxmox inventory poll failed: %v", err)
+		return nil, err
+	}
+
+	if err := p.store.SaveProxmoxSnapshot(snapshot); err != nil {
+		log.Printf("⚠️  Failed to save proxmox snapshot: %v", err)
+	}
+	for _, listener := range p.listeners {
+		listener(prev, snapshot)
+	}
+	return snapshot, nil
+}
+
+// poll interroge toutes les connexions actives
+func (p *Poller) poll(ctx context.Context) (*models.ProxmoxSnapshot, error) {
+	conns, err := p.store.GetProxmoxConnections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxmox connections: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, max(p.interval, minPollTimeout))
+	defer cancel()
+
+	start := time.Now()
+	content := p.content
+	content.Previous = p.Snapshot()
+	inv := CollectAll(ctx, ClustersFromConnections(conns, nil), content)
+	return &models.ProxmoxSnapshot{
+		ProxmoxInventory: *inv,
+		CollectedAt:      start,
+		DurationMs:       time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// setPolling indique si une collecte est en cours
+func (p *Poller) setPolling(polling bool) {
+	p.mu.Lock()
+	p.polling = polling
+	p.mu.Unlock()
+}
+
+// Snapshot retourne le dernier snapshot collecté (nil si aucune collecte n'a encore abouti)
+func (p *Poller) Snapshot() *models.ProxmoxSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.snapshot
+}
+
+// Status retourne la fraîcheur du snapshot courant
+func (p *Poller) Status() models.ProxmoxCacheStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := models.ProxmoxCacheStatus{
+		IntervalSeconds: int64(p.interval.Seconds()),
+		Stale:           true,
+		Polling:         p.polling,
+		LastError:       p.lastError,
+	}
+	if p.snapshot != nil {
+		collectedAt := p.snapshot.CollectedAt
+		age := time.Since(collectedAt)
+		status.CollectedAt = &collectedAt
+		status.AgeSeconds = int64(age.Seconds())
+		status.Stale = age > 2*p.interval
+	}
+	return status
+}
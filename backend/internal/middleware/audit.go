@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/auth"
+	"proxmox-dashboard/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// AuditRecorder enregistre les entrées du journal d'audit (implémenté par store.Store)
+type AuditRecorder interface {
+	CreateAuditEntry(entry *models.AuditEntry) error
+}
+
+// auditContextKey est la clé de contexte de l'entrée d'audit en cours
+type auditContextKey struct{}
+
+// auditRecord est l'entrée d'audit d'une requête, complétée par les middlewares et handlers
+type auditRecord struct {
+	entry models.AuditEntry
+	skip  bool
+}
+
+// AuditMiddleware enregistre dans le journal d'audit chaque requête modifiant l'état
+// (POST, PUT, PATCH, DELETE) : utilisateur, action, cible, IP, résultat et UPID Proxmox.
+// Les handlers précisent l'action et la cible avec SetAuditAction, SetAuditTarget et SetAuditUPID.
+func AuditMiddleware(recorder AuditRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			record := &auditRecord{entry: models.AuditEntry{
+				Timestamp: start,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				IPAddress: auth.GetClientIP(r),
+				UserAgent: r.UserAgent(),
+			}}
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, record)))
+
+			if record.skip {
+				return
+			}
+			entry := record.entry
+			entry.Status = ww.Status()
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			entry.Result = models.AuditResultForStatus(entry.Status)
+			entry.DurationMs = time.Since(start).Milliseconds()
+			if entry.Action == "" {
+				entry.Action = defaultAuditAction(r)
+			}
+
+			if err := recorder.CreateAuditEntry(&entry); err != nil {
+				log.Printf("⚠️  Failed to record audit entry %s %s: %v", entry.Method, entry.Path, err)
+			}
+		})
+	}
+}
+
+// defaultAuditAction nomme l'action d'après la méthode et le motif de la route (ex: "POST /api/v1/apps")
+func defaultAuditAction(r *http.Request) string {
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		pattern = strings.ReplaceAll(rctx.RoutePattern(), "/*/", "/")
+	}
+	return r.Method + " " + pattern
+}
+
+// auditFromContext retourne l'entrée d'audit de la requête, nil si la requête n'est pas auditée
+func auditFromContext(r *http.Request) *auditRecord {
+	record, _ := r.Context().Value(auditContextKey{}).(*auditRecord)
+	return record
+}
+
+// SetAuditAction nomme l'action auditée (ex: "vm.restart")
+func SetAuditAction(r *http.Request, action string) {
+	if record := auditFromContext(r); record != nil {
+		record.entry.Action = action
+	}
+}
+
+// SetAuditTarget précise l'objet visé par l'action (ex: "pve1/qemu/100")
+func SetAuditTarget(r *http.Request, target string) {
+	if record := auditFromContext(r); record != nil {
+		record.entry.Target = target
+	}
+}
+
+// SetAuditUPID associe la tâche Proxmox lancée par l'action
+func SetAuditUPID(r *http.Request, upid string) {
+	if record := auditFromContext(r); record != nil {
+		record.entry.UPID = upid
+	}
+}
+
+// SetAuditUser renseigne l'utilisateur à l'origine de l'action
+func SetAuditUser(r *http.Request, user *models.User) {
+	if record := auditFromContext(r); record != nil && user != nil {
+		id := user.ID
+		record.entry.UserID = &id
+		record.entry.Username = user.Username
+	}
+}
+
+// SkipAudit exclut du journal les routes POST en lecture seule (collecte, tests de connexion)
+func SkipAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if record := auditFromContext(r); record != nil {
+			record.skip = true
+		}
+		next.ServeHTTP(w, r)
+	})
+}
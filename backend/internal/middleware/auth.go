@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"os"
 	"strings"
@@ -44,13 +45,35 @@ func JWTAuthMiddleware(authService *auth.Service) func(http.Handler) http.Handle
 				return
 			}
 
-			// Ajouter l'utilisateur au contexte
+			// Ajouter l'utilisateur au contexte et au journal d'audit
+			SetAuditUser(r, user)
 			ctx := context.WithValue(r.Context(), "user", user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ServiceTokenAuth authentifie un service externe (ex: Alertmanager) par un token Bearer partagé.
+// La requête est attribuée au compte de service name, limité aux permissions données ; sans token
+// configuré ou si le token ne correspond pas, la requête passe par fallback (authentification de session).
+func ServiceTokenAuth(token, name string, permissions []models.Permission, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				authenticated.ServeHTTP(w, r)
+				return
+			}
+
+			service := &models.User{Username: name, Role: models.RoleGuest, Active: true, Permissions: permissions}
+			SetAuditUser(r, service)
+			ctx := context.WithValue(r.Context(), "user", service)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // LegacyAuthMiddleware vérifie le token d'authentification legacy (pour compatibilité)
 func LegacyAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -159,8 +182,8 @@ func RequirePermission(resource, action string) func(http.Handler) http.Handler
 				return
 			}
 
-			// Vérifier la permission
-			if !user.Role.HasPermission(resource, action) {
+			// Vérifier la permission (les portées nœud/pool/tag sont vérifiées par les handlers)
+			if !user.HasPermission(resource, action) {
 				http.Error(w, "Permission insuffisante", http.StatusForbidden)
 				return
 			}
@@ -170,6 +193,28 @@ func RequirePermission(resource, action string) func(http.Handler) http.Handler
 	}
 }
 
+// RequireAnyPermission vérifie qu'un utilisateur a au moins une des permissions (ex: clone de VMs ou de conteneurs)
+func RequireAnyPermission(permissions ...models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value("user").(*models.User)
+			if !ok {
+				http.Error(w, "Utilisateur non authentifié", http.StatusUnauthorized)
+				return
+			}
+
+			// Les portées nœud/pool/tag sont vérifiées par les handlers
+			for _, perm := range permissions {
+				if user.HasPermission(perm.Resource, perm.Action) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Permission insuffisante", http.StatusForbidden)
+		})
+	}
+}
+
 // RequireRole vérifie qu'un utilisateur a un rôle spécifique ou supérieur
 func RequireRole(minRole string) func(http.Handler) http.Handler {
 	roleHierarchy := map[models.UserRole]int{
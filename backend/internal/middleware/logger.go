@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// redactedQueryParams sont les paramètres de requête portant un token d'authentification :
+// ?token= (flux SSE, token de session) et ?console_token= (fenêtre de console)
+var redactedQueryParams = []string{"token", "console_token"}
+
+// redactedLogFormatter formate les requêtes comme le logger de chi, tokens masqués
+type redactedLogFormatter struct {
+	chimw.LogFormatter
+}
+
+// NewLogEntry journalise une copie de la requête dont l'URI ne contient plus de token
+func (f redactedLogFormatter) NewLogEntry(r *http.Request) chimw.LogEntry {
+	logged := *r
+	logged.RequestURI = RedactURI(r.RequestURI)
+	return f.LogFormatter.NewLogEntry(&logged)
+}
+
+// Logger journalise les requêtes (méthode, URI, statut, taille, durée) sans les tokens passés en paramètre
+var Logger = chimw.RequestLogger(redactedLogFormatter{&chimw.DefaultLogFormatter{
+	Logger:  log.New(os.Stdout, "", log.LstdFlags),
+	NoColor: false,
+}})
+
+// RedactURI masque la valeur des paramètres de requête portant un token ("?token=REDACTED")
+func RedactURI(uri string) string {
+	base, rawQuery, ok := strings.Cut(uri, "?")
+	if !ok {
+		return uri
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, param := range redactedQueryParams {
+		if _, found := query[param]; found {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return uri
+	}
+	return base + "?" + query.Encode()
+}
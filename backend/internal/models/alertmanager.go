@@ -0,0 +1,134 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertmanagerWebhook est le corps envoyé par le receiver webhook_configs de Prometheus Alertmanager (version 4)
+type AlertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"` // firing|resolved
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert est une alerte d'un webhook Alertmanager
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"` // firing|resolved
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AlertmanagerSourcePrefix préfixe la source des alertes reçues d'Alertmanager ("alertmanager:HighCPUUsage")
+const AlertmanagerSourcePrefix = "alertmanager:"
+
+// AlertmanagerSeverity convertit le label severity d'Alertmanager en sévérité du dashboard
+func AlertmanagerSeverity(label string) string {
+	switch strings.ToLower(label) {
+	case "critical", "page", "emergency", "fatal":
+		return "critical"
+	case "high", "error", "warning", "warn":
+		return "high"
+	case "info", "informational", "none", "low":
+		return "low"
+	default:
+		return "medium"
+	}
+}
+
+// IsResolved indique si Alertmanager signale la fin de l'alerte
+func (a *AlertmanagerAlert) IsResolved() bool {
+	return a.Status == AlertStatusResolved
+}
+
+// DashboardFingerprint retourne l'empreinte de déduplication de l'alerte : celle calculée par Alertmanager,
+// ou à défaut un hash de ses labels, préfixée pour ne pas entrer en collision avec les règles du dashboard
+func (a *AlertmanagerAlert) DashboardFingerprint() string {
+	fingerprint := a.Fingerprint
+	if fingerprint == "" {
+		names := make([]string, 0, len(a.Labels))
+		for name := range a.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		hash := sha256.New()
+		for _, name := range names {
+			hash.Write([]byte(name + "\xff" + a.Labels[name] + "\xff"))
+		}
+		fingerprint = hex.EncodeToString(hash.Sum(nil)[:8])
+	}
+	return "alertmanager:" + fingerprint
+}
+
+// ToAlert convertit une alerte Alertmanager en alerte du dashboard : le titre vient de l'annotation summary
+// (à défaut alertname), le message de description (à défaut message ou summary), la sévérité du label severity
+func (a *AlertmanagerAlert) ToAlert(webhook *AlertmanagerWebhook, now time.Time) *Alert {
+	alertname := a.Labels["alertname"]
+	title := firstNonEmpty(a.Annotations["summary"], a.Annotations["title"], alertname, "Alertmanager alert")
+	message := firstNonEmpty(a.Annotations["description"], a.Annotations["message"], a.Annotations["summary"], title)
+
+	labels := make(map[string]string, len(a.Labels))
+	for name, value := range a.Labels {
+		labels[name] = value
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"receiver":      webhook.Receiver,
+		"external_url":  webhook.ExternalURL,
+		"group_key":     webhook.GroupKey,
+		"labels":        a.Labels,
+		"annotations":   a.Annotations,
+		"starts_at":     a.StartsAt,
+		"generator_url": a.GeneratorURL,
+		"fingerprint":   a.Fingerprint,
+	})
+	payload := string(data)
+
+	createdAt := now
+	if !a.StartsAt.IsZero() && a.StartsAt.Before(now) {
+		createdAt = a.StartsAt
+	}
+	return &Alert{
+		Source:      AlertmanagerSourcePrefix + firstNonEmpty(alertname, "unknown"),
+		Severity:    AlertmanagerSeverity(a.Labels["severity"]),
+		Title:       title,
+		Message:     message,
+		Payload:     &payload,
+		Labels:      labels,
+		Fingerprint: a.DashboardFingerprint(),
+		CreatedAt:   createdAt,
+	}
+}
+
+// AlertmanagerResult résume le traitement d'un webhook Alertmanager
+type AlertmanagerResult struct {
+	Created      []int `json:"created"`      // alertes créées
+	Resolved     []int `json:"resolved"`     // alertes résolues
+	Deduplicated int   `json:"deduplicated"` // alertes déjà ouvertes
+	Ignored      int   `json:"ignored"`      // résolutions sans alerte ouverte correspondante
+}
+
+// firstNonEmpty retourne la première valeur non vide
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAlertTransition est retournée quand l'état d'une alerte ne permet pas l'action demandée
+// (ex: acquitter une alerte résolue)
+var ErrAlertTransition = errors.New("invalid alert state transition")
+
+// MatchLabels retourne les labels d'une alerte utilisés par les silences et les routes : ses labels propres
+// complétés par source, source_prefix (avant ':', ex: "rule"), severity, title, fingerprint et rule_id
+func (a *Alert) MatchLabels() map[string]string {
+	labels := make(map[string]string, len(a.Labels)+6)
+	for name, value := range a.Labels {
+		labels[name] = value
+	}
+	labels["source"] = a.Source
+	labels["source_prefix"], _, _ = strings.Cut(a.Source, ":")
+	labels["severity"] = a.Severity
+	labels["title"] = a.Title
+	if a.Fingerprint != "" {
+		labels["fingerprint"] = a.Fingerprint
+	}
+	if a.RuleID != nil {
+		labels["rule_id"] = strconv.Itoa(*a.RuleID)
+	}
+	return labels
+}
+
+// IsSnoozed indique si les notifications de l'alerte sont suspendues à l'instant now
+func (a *Alert) IsSnoozed(now time.Time) bool {
+	return a.SnoozedUntil != nil && a.SnoozedUntil.After(now)
+}
+
+// Opérateurs des matchers de labels des silences et des routes (syntaxe Alertmanager)
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+// LabelMatcher compare un label d'alerte à une valeur ou une expression régulière (ancrée)
+type LabelMatcher struct {
+	Name     string `json:"name"`
+	Operator string `json:"operator"` // = (défaut), !=, =~, !~
+	Value    string `json:"value"`
+}
+
+// Validate valide un matcher
+func (m *LabelMatcher) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("matcher name is required")
+	}
+	switch m.Operator {
+	case "":
+		m.Operator = MatchEqual
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		if _, err := regexp.Compile("^(?:" + m.Value + ")$"); err != nil {
+			return fmt.Errorf("invalid regexp for matcher %s: %v", m.Name, err)
+		}
+	default:
+		return fmt.Errorf("matcher operator must be =, !=, =~ or !~")
+	}
+	return nil
+}
+
+// Matches indique si les labels satisfont le matcher (un label absent vaut "")
+func (m *LabelMatcher) Matches(labels map[string]string) bool {
+	value := labels[m.Name]
+	switch m.Operator {
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value) == (m.Operator == MatchRegexp)
+	default:
+		return value == m.Value
+	}
+}
+
+// États d'un silence, calculés à partir de StartsAt et EndsAt
+const (
+	SilenceStatePending = "pending"
+	SilenceStateActive  = "active"
+	SilenceStateExpired = "expired"
+)
+
+// Silence suspend les notifications des alertes dont les labels satisfont tous les matchers,
+// entre StartsAt et EndsAt. Les alertes restent créées et visibles.
+type Silence struct {
+	ID        int            `json:"id"`
+	Matchers  []LabelMatcher `json:"matchers"`
+	StartsAt  time.Time      `json:"starts_at"`
+	EndsAt    time.Time      `json:"ends_at"`
+	Comment   string         `json:"comment"`
+	CreatedBy string         `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	State     string         `json:"state"` // pending|active|expired
+}
+
+// Validate valide un silence
+func (s *Silence) Validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("at least one matcher is required")
+	}
+	for i := range s.Matchers {
+		if err := s.Matchers[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// StateAt retourne l'état du silence à l'instant now
+func (s *Silence) StateAt(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilenceStatePending
+	case now.Before(s.EndsAt):
+		return SilenceStateActive
+	default:
+		return SilenceStateExpired
+	}
+}
+
+// Matches indique si le silence couvre une alerte (tous les matchers doivent correspondre)
+func (s *Silence) Matches(alert *Alert) bool {
+	labels := alert.MatchLabels()
+	for i := range s.Matchers {
+		if !s.Matchers[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateSilenceRequest représente une requête de création de silence.
+// La fin est donnée par ends_at ou par une durée ("2h") à partir du début.
+type CreateSilenceRequest struct {
+	Matchers []LabelMatcher `json:"matchers"`
+	StartsAt *time.Time     `json:"starts_at,omitempty"` // défaut: maintenant
+	EndsAt   *time.Time     `json:"ends_at,omitempty"`
+	Duration Duration       `json:"duration,omitempty"`
+	Comment  string         `json:"comment"`
+}
+
+// SnoozeAlertRequest suspend les notifications d'une alerte jusqu'à until, ou pendant duration
+type SnoozeAlertRequest struct {
+	Until    *time.Time `json:"until,omitempty"`
+	Duration Duration   `json:"duration,omitempty"`
+}
+
+// AssignAlertRequest assigne une alerte à un utilisateur (null pour la désassigner)
+type AssignAlertRequest struct {
+	UserID *int `json:"user_id"`
+}
+
+// AlertComment est un commentaire du fil de discussion d'une alerte
+type AlertComment struct {
+	ID        int       `json:"id"`
+	AlertID   int       `json:"alert_id"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Username  string    `json:"username"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateCommentRequest représente une requête d'ajout de commentaire
+type CreateCommentRequest struct {
+	Body string `json:"body"`
+}
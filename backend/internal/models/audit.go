@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Résultats d'une action auditée
+const (
+	AuditResultSuccess = "success"
+	AuditResultDenied  = "denied" // 401 ou 403
+	AuditResultFailure = "failure"
+)
+
+// AuditEntry représente une action enregistrée dans le journal d'audit
+type AuditEntry struct {
+	ID         int       `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	UserID     *int      `json:"user_id,omitempty"`
+	Username   string    `json:"username"`
+	Action     string    `json:"action"` // ex: vm.restart, app.delete, admin.clear-db
+	Target     string    `json:"target,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Status     int       `json:"status"` // code HTTP de la réponse
+	Result     string    `json:"result"` // success|denied|failure
+	UPID       string    `json:"upid,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// AuditResultForStatus déduit le résultat d'une action du code HTTP de la réponse
+func AuditResultForStatus(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return AuditResultDenied
+	case status >= 400:
+		return AuditResultFailure
+	default:
+		return AuditResultSuccess
+	}
+}
+
+// AuditQuery décrit les filtres de /api/v1/audit
+type AuditQuery struct {
+	Username string // correspondance exacte
+	Action   string // préfixe (vm. couvre vm.start, vm.restart...)
+	Target   string // sous-chaîne
+	Result   string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// MaxAuditQueryLimit est le nombre maximal d'entrées retournées par requête
+const MaxAuditQueryLimit = 10000
+
+// Validate valide une requête du journal d'audit
+func (q *AuditQuery) Validate() error {
+	switch q.Result {
+	case "", AuditResultSuccess, AuditResultDenied, AuditResultFailure:
+	default:
+		return fmt.Errorf("result must be one of success, denied, failure")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return fmt.Errorf("from must be before to")
+	}
+	if q.Limit < 0 || q.Limit > MaxAuditQueryLimit {
+		return fmt.Errorf("limit must be between 0 and %d", MaxAuditQueryLimit)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("offset must be positive")
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,6 +27,9 @@ type User struct {
 	LastLogin *time.Time `json:"last_login" db:"last_login"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Permissions du rôle, chargées depuis la table roles à la validation du token
+	Permissions []Permission `json:"permissions,omitempty" db:"-"`
 }
 
 // UserSession représente une session utilisateur
@@ -61,11 +66,12 @@ type CreateUserRequest struct {
 
 // UpdateUserRequest représente une requête de mise à jour d'utilisateur
 type UpdateUserRequest struct {
-	Username *string   `json:"username,omitempty"`
-	Email    *string   `json:"email,omitempty"`
-	Password *string   `json:"password,omitempty"`
-	Role     *UserRole `json:"role,omitempty"`
-	Active   *bool     `json:"active,omitempty"`
+	Username        *string   `json:"username,omitempty"`
+	Email           *string   `json:"email,omitempty"`
+	Password        *string   `json:"password,omitempty"`
+	CurrentPassword *string   `json:"current_password,omitempty"` // requis pour changer son propre mot de passe
+	Role            *UserRole `json:"role,omitempty"`
+	Active          *bool     `json:"active,omitempty"`
 }
 
 // ChangePasswordRequest représente une requête de changement de mot de passe
@@ -74,13 +80,76 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password"`
 }
 
-// Permission représente une permission système
+// Permission représente une permission système, éventuellement limitée à une portée
 type Permission struct {
-	Resource string `json:"resource"` // ex: "apps", "alerts", "users"
-	Action   string `json:"action"`   // ex: "read", "write", "delete"
+	Resource string           `json:"resource"`        // ex: "apps", "proxmox.vm", "backups"
+	Action   string           `json:"action"`          // ex: "read", "write", "power"
+	Scope    *PermissionScope `json:"scope,omitempty"` // nil: tous les objets
+}
+
+// PermissionScope limite une permission aux invités de certains nœuds, pools ou tags.
+// Chaque critère renseigné doit correspondre ; un critère vide est ignoré.
+type PermissionScope struct {
+	Nodes []string `json:"nodes,omitempty"`
+	Pools []string `json:"pools,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// PermissionTarget décrit l'objet Proxmox visé par une action
+type PermissionTarget struct {
+	Node string
+	Pool string
+	Tags []string
+}
+
+// PermissionInfo décrit une permission connue du catalogue
+type PermissionInfo struct {
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	Scopable    bool   `json:"scopable"` // accepte une portée nœud/pool/tag
+}
+
+// PermissionCatalog liste les permissions vérifiées par l'API
+var PermissionCatalog = []PermissionInfo{
+	{Resource: "apps", Action: "read", Description: "Consulter les applications"},
+	{Resource: "apps", Action: "write", Description: "Gérer les applications"},
+	{Resource: "alerts", Action: "read", Description: "Consulter les alertes"},
+	{Resource: "alerts", Action: "write", Description: "Créer et acquitter les alertes"},
+	{Resource: "alerts", Action: "rules", Description: "Gérer les règles d'alerte"},
+	{Resource: "health", Action: "read", Description: "Tester la santé des services"},
+	{Resource: "notifications", Action: "read", Description: "Consulter les abonnements"},
+	{Resource: "notifications", Action: "write", Description: "Gérer les abonnements et envoyer des tests"},
+	{Resource: "profile", Action: "read", Description: "Consulter son profil"},
+	{Resource: "profile", Action: "write", Description: "Modifier son profil"},
+	{Resource: "users", Action: "read", Description: "Consulter les utilisateurs"},
+	{Resource: "users", Action: "write", Description: "Gérer les utilisateurs"},
+	{Resource: "roles", Action: "write", Description: "Gérer les rôles personnalisés"},
+	{Resource: "connections", Action: "write", Description: "Gérer les connexions Proxmox"},
+	{Resource: "proxmox", Action: "read", Description: "Consulter l'inventaire Proxmox"},
+	{Resource: "proxmox.vm", Action: "power", Description: "Démarrer, arrêter et redémarrer les VMs", Scopable: true},
+	{Resource: "proxmox.vm", Action: "console", Description: "Ouvrir la console des VMs", Scopable: true},
+	{Resource: "proxmox.vm", Action: "config", Description: "Modifier la configuration des VMs", Scopable: true},
+	{Resource: "proxmox.vm", Action: "clone", Description: "Créer des VMs depuis les templates", Scopable: true},
+	{Resource: "proxmox.vm", Action: "snapshot", Description: "Gérer les snapshots des VMs", Scopable: true},
+	{Resource: "proxmox.lxc", Action: "power", Description: "Démarrer, arrêter et redémarrer les conteneurs", Scopable: true},
+	{Resource: "proxmox.lxc", Action: "console", Description: "Ouvrir la console des conteneurs", Scopable: true},
+	{Resource: "proxmox.lxc", Action: "config", Description: "Modifier la configuration des conteneurs", Scopable: true},
+	{Resource: "proxmox.lxc", Action: "clone", Description: "Créer des conteneurs depuis les templates", Scopable: true},
+	{Resource: "proxmox.lxc", Action: "snapshot", Description: "Gérer les snapshots des conteneurs", Scopable: true},
+	{Resource: "backups", Action: "read", Description: "Consulter les sauvegardes"},
+	{Resource: "backups", Action: "run", Description: "Lancer des sauvegardes", Scopable: true},
+	{Resource: "backups", Action: "jobs", Description: "Gérer les sauvegardes planifiées"},
+	{Resource: "metrics", Action: "read", Description: "Consulter l'historique des métriques"},
+	{Resource: "metrics", Action: "write", Description: "Importer l'historique rrddata"},
+	{Resource: "prometheus", Action: "read", Description: "Interroger Prometheus"},
+	{Resource: "prometheus", Action: "write", Description: "Gérer les sources de données Prometheus"},
+	{Resource: "audit", Action: "read", Description: "Consulter et exporter le journal d'audit"},
+	{Resource: "admin", Action: "clear-db", Description: "Vider la base de données"},
 }
 
-// RolePermissions définit les permissions par rôle
+// RolePermissions définit les permissions des rôles intégrés.
+// Elles initialisent la table roles ; les rôles sont ensuite gérés en base.
 var RolePermissions = map[UserRole][]Permission{
 	RoleAdmin: {
 		// Accès complet à tout
@@ -96,6 +165,21 @@ var RolePermissions = map[UserRole][]Permission{
 		{Resource: "notifications", Action: "read"},
 		{Resource: "profile", Action: "read"},
 		{Resource: "profile", Action: "write"},
+		// Inventaire et actions Proxmox
+		{Resource: "proxmox", Action: "read"},
+		{Resource: "proxmox.vm", Action: "power"},
+		{Resource: "proxmox.vm", Action: "console"},
+		{Resource: "proxmox.vm", Action: "config"},
+		{Resource: "proxmox.vm", Action: "clone"},
+		{Resource: "proxmox.vm", Action: "snapshot"},
+		{Resource: "proxmox.lxc", Action: "power"},
+		{Resource: "proxmox.lxc", Action: "console"},
+		{Resource: "proxmox.lxc", Action: "config"},
+		{Resource: "proxmox.lxc", Action: "clone"},
+		{Resource: "proxmox.lxc", Action: "snapshot"},
+		{Resource: "backups", Action: "read"},
+		{Resource: "metrics", Action: "read"},
+		{Resource: "prometheus", Action: "read"},
 	},
 	RoleViewer: {
 		// Lecture seule
@@ -103,6 +187,10 @@ var RolePermissions = map[UserRole][]Permission{
 		{Resource: "alerts", Action: "read"},
 		{Resource: "health", Action: "read"},
 		{Resource: "profile", Action: "read"},
+		{Resource: "proxmox", Action: "read"},
+		{Resource: "backups", Action: "read"},
+		{Resource: "metrics", Action: "read"},
+		{Resource: "prometheus", Action: "read"},
 	},
 	RoleGuest: {
 		// Accès très limité
@@ -110,31 +198,145 @@ var RolePermissions = map[UserRole][]Permission{
 	},
 }
 
-// HasPermission vérifie si un rôle a une permission spécifique
-func (r UserRole) HasPermission(resource, action string) bool {
-	permissions, exists := RolePermissions[r]
-	if !exists {
+// Role représente un rôle et ses permissions, stocké en base
+type Role struct {
+	Name        UserRole     `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	BuiltIn     bool         `json:"built_in"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Validate valide un rôle et ses permissions
+func (r *Role) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, c := range r.Name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("name must only contain lowercase letters, digits, '-' and '_'")
+		}
+	}
+	for _, p := range r.Permissions {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate vérifie qu'une permission correspond au catalogue (jokers acceptés)
+func (p Permission) Validate() error {
+	if p.Resource == "" || p.Action == "" {
+		return fmt.Errorf("permission resource and action are required")
+	}
+
+	known, scopable := false, false
+	for _, info := range PermissionCatalog {
+		if p.covers(info.Resource, info.Action) {
+			known = true
+			scopable = scopable || info.Scopable
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown permission %s:%s", p.Resource, p.Action)
+	}
+	if p.Scope != nil && !scopable {
+		return fmt.Errorf("permission %s:%s cannot be scoped", p.Resource, p.Action)
+	}
+	return nil
+}
+
+// covers indique si la permission couvre resource:action, sans tenir compte de la portée.
+// "*" couvre tout ; "proxmox" couvre aussi les sous-ressources "proxmox.vm", "proxmox.lxc".
+func (p Permission) covers(resource, action string) bool {
+	resourceMatch := p.Resource == "*" || p.Resource == resource || strings.HasPrefix(resource, p.Resource+".")
+	return resourceMatch && (p.Action == "*" || p.Action == action)
+}
+
+// Matches indique si la cible entre dans la portée
+func (s *PermissionScope) Matches(target PermissionTarget) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.Nodes) > 0 && !containsFold(s.Nodes, target.Node) {
+		return false
+	}
+	if len(s.Pools) > 0 && !containsFold(s.Pools, target.Pool) {
 		return false
 	}
+	if len(s.Tags) > 0 {
+		tagged := false
+		for _, tag := range target.Tags {
+			if containsFold(s.Tags, tag) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	return true
+}
 
-	for _, perm := range permissions {
-		// Accès complet (admin)
-		if perm.Resource == "*" && perm.Action == "*" {
+// containsFold indique si values contient value (insensible à la casse)
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
 			return true
 		}
-		// Ressource spécifique avec action wildcard
-		if perm.Resource == resource && perm.Action == "*" {
-			return true
+	}
+	return false
+}
+
+// grants indique si l'une des permissions couvre resource:action pour la cible.
+// Une cible nil ignore les portées (l'action est permise sur au moins une partie des objets).
+func grants(permissions []Permission, resource, action string, target *PermissionTarget) bool {
+	for _, perm := range permissions {
+		if !perm.covers(resource, action) {
+			continue
 		}
-		// Correspondance exacte
-		if perm.Resource == resource && perm.Action == action {
+		if target == nil || perm.Scope.Matches(*target) {
 			return true
 		}
 	}
 	return false
 }
 
-// IsValid vérifie si le rôle est valide
+// HasPermission vérifie si un rôle intégré a une permission spécifique
+func (r UserRole) HasPermission(resource, action string) bool {
+	permissions, exists := RolePermissions[r]
+	if !exists {
+		return false
+	}
+	return grants(permissions, resource, action, nil)
+}
+
+// effectivePermissions retourne les permissions chargées depuis la table roles,
+// à défaut celles du rôle intégré
+func (u *User) effectivePermissions() []Permission {
+	if u.Permissions != nil {
+		return u.Permissions
+	}
+	return RolePermissions[u.Role]
+}
+
+// HasPermission vérifie si l'utilisateur a la permission sur au moins une partie des objets
+func (u *User) HasPermission(resource, action string) bool {
+	return grants(u.effectivePermissions(), resource, action, nil)
+}
+
+// Can vérifie si l'utilisateur a la permission sur la cible donnée (portées comprises)
+func (u *User) Can(resource, action string, target PermissionTarget) bool {
+	return grants(u.effectivePermissions(), resource, action, &target)
+}
+
+// IsValid vérifie si le rôle est un rôle intégré
 func (r UserRole) IsValid() bool {
 	switch r {
 	case RoleAdmin, RoleUser, RoleViewer, RoleGuest:
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Motifs de non-conformité d'un invité
+const (
+	BackupViolationNoBackup = "no_backup" // aucune archive
+	BackupViolationTooOld   = "too_old"   // dernière archive plus ancienne que l'âge maximal
+	BackupViolationUnknown  = "unknown"   // invité ou archives non lus : la conformité ne peut être établie
+)
+
+// BackupPolicyRuleDefault est la règle des invités sans tag ni pool couverts par la politique
+const BackupPolicyRuleDefault = "default"
+
+// BackupPolicy est la politique de sauvegarde : âge maximal de la dernière archive d'un invité,
+// défini par tag ou par pool, à défaut l'âge par défaut. La règle la plus stricte s'applique.
+type BackupPolicy struct {
+	MaxAge time.Duration
+	Tags   map[string]time.Duration // tags en minuscules
+	Pools  map[string]time.Duration
+}
+
+// ParseBackupPolicy lit les règles d'une politique de sauvegarde ("tag:prod=24,pool:dev=168", en heures)
+func ParseBackupPolicy(maxAge time.Duration, rules string) (*BackupPolicy, error) {
+	if maxAge <= 0 {
+		return nil, fmt.Errorf("backup max age must be positive")
+	}
+	policy := &BackupPolicy{MaxAge: maxAge, Tags: map[string]time.Duration{}, Pools: map[string]time.Duration{}}
+	for _, rule := range strings.FieldsFunc(rules, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		scope, hours, ok := strings.Cut(rule, "=")
+		kind, name, scoped := strings.Cut(scope, ":")
+		n, err := strconv.Atoi(hours)
+		if !ok || !scoped || name == "" || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid backup policy rule %q: use tag:<tag>=<hours> or pool:<pool>=<hours>", rule)
+		}
+		switch kind {
+		case "tag":
+			policy.Tags[strings.ToLower(name)] = time.Duration(n) * time.Hour
+		case "pool":
+			policy.Pools[name] = time.Duration(n) * time.Hour
+		default:
+			return nil, fmt.Errorf("invalid backup policy rule %q: scope must be tag or pool", rule)
+		}
+	}
+	return policy, nil
+}
+
+// For retourne l'âge maximal de la dernière archive d'un invité et la règle appliquée ("tag:prod", "pool:dev", "default")
+func (p *BackupPolicy) For(guest ProxmoxGuest) (time.Duration, string) {
+	maxAge, rule := p.MaxAge, BackupPolicyRuleDefault
+	matched := false
+	apply := func(age time.Duration, name string) {
+		if !matched || age < maxAge {
+			maxAge, rule, matched = age, name, true
+		}
+	}
+	for _, tag := range splitGuestTags(guest.Tags) {
+		if age, ok := p.Tags[strings.ToLower(tag)]; ok {
+			apply(age, "tag:"+strings.ToLower(tag))
+		}
+	}
+	if age, ok := p.Pools[guest.Pool]; ok && guest.Pool != "" {
+		apply(age, "pool:"+guest.Pool)
+	}
+	return maxAge, rule
+}
+
+// splitGuestTags découpe les tags Proxmox d'un invité (séparés par ';', ',' ou des espaces)
+func splitGuestTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// BackupSizePoint est la taille d'une archive conservée, pour la tendance des tailles
+type BackupSizePoint struct {
+	CreatedAt time.Time `json:"created_at"`
+	Size      float64   `json:"size"` // en GB
+}
+
+// BackupCompliance est la conformité des sauvegardes d'un invité
+type BackupCompliance struct {
+	Cluster           string            `json:"cluster,omitempty"`
+	VMID              int               `json:"vmid"`
+	Name              string            `json:"name"`
+	Type              string            `json:"type"` // qemu|lxc
+	Node              string            `json:"node"`
+	Pool              string            `json:"pool,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	LastBackup        *time.Time        `json:"last_backup,omitempty"`
+	LastBackupID      string            `json:"last_backup_id,omitempty"` // volid de la dernière archive
+	LastBackupSize    float64           `json:"last_backup_size"`         // en GB
+	AgeSeconds        *int64            `json:"age_seconds,omitempty"`
+	RetentionCount    int               `json:"retention_count"` // archives conservées, tous storages confondus
+	SizeTrend         []BackupSizePoint `json:"size_trend"`      // de la plus ancienne à la plus récente
+	SizeChangePercent *float64          `json:"size_change_percent,omitempty"`
+	MaxAgeSeconds     int64             `json:"max_age_seconds"`
+	Policy            string            `json:"policy"` // règle appliquée
+	Compliant         bool              `json:"compliant"`
+	Violation         string            `json:"violation,omitempty"`
+}
+
+// BackupComplianceReport est le rapport de conformité des sauvegardes des invités de l'inventaire.
+// Un rapport calculé sur un inventaire partiel a Complete à false et la raison dans Warnings ;
+// Violations inclut les invités de conformité inconnue, comptés à part dans Unknown.
+type BackupComplianceReport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Complete    bool               `json:"complete"`
+	Warnings    []string           `json:"warnings,omitempty"`
+	Total       int                `json:"total"`
+	Compliant   int                `json:"compliant"`
+	Violations  int                `json:"violations"`
+	Unknown     int                `json:"unknown"`
+	Guests      []BackupCompliance `json:"guests"`
+}
+
+// Count ajoute un invité aux totaux du rapport
+func (r *BackupComplianceReport) Count(entry BackupCompliance) {
+	r.Total++
+	switch {
+	case entry.Compliant:
+		r.Compliant++
+	case entry.Violation == BackupViolationUnknown:
+		r.Violations++
+		r.Unknown++
+	default:
+		r.Violations++
+	}
+}
+
+// BuildBackupComplianceReport évalue les archives de chaque invité de l'inventaire (hors templates) selon la
+// politique. Les archives sont rattachées aux invités par cluster et VMID ; les invités non conformes sont
+// listés en premier. Les invités des nœuds illisibles, connus par le résumé des nœuds, et les invités non
+// conformes d'un cluster dont des storages de sauvegarde sont illisibles ont une conformité inconnue.
+func BuildBackupComplianceReport(inv *ProxmoxInventory, policy *BackupPolicy, now time.Time) *BackupComplianceReport {
+	type guestKey struct {
+		cluster string
+		vmid    int
+	}
+	archives := map[guestKey][]ProxmoxBackup{}
+	for _, b := range inv.Backups {
+		key := guestKey{b.Cluster, b.VMID}
+		archives[key] = append(archives[key], b)
+	}
+
+	report := &BackupComplianceReport{GeneratedAt: now, Guests: []BackupCompliance{}}
+	partialBackups := map[string]bool{}
+	for _, c := range inv.Clusters {
+		var warnings []string
+		switch {
+		case !c.Success:
+			warnings = append(warnings, fmt.Sprintf("cluster %s unreachable: %s", c.Name, c.Error))
+		default:
+			for _, node := range c.FailedVMNodes {
+				warnings = append(warnings, fmt.Sprintf("cluster %s: VMs of node %s could not be listed", c.Name, node))
+			}
+			for _, node := range c.FailedLXCNodes {
+				warnings = append(warnings, fmt.Sprintf("cluster %s: containers of node %s could not be listed", c.Name, node))
+			}
+			for _, storage := range c.FailedBackupStorages {
+				warnings = append(warnings, fmt.Sprintf("cluster %s: backups of %s could not be listed", c.Name, storage))
+			}
+		}
+		partialBackups[c.Name] = len(c.FailedBackupStorages) > 0
+		report.Warnings = append(report.Warnings, warnings...)
+	}
+	report.Complete = len(report.Warnings) == 0
+
+	evaluate := func(g ProxmoxGuest, unknown bool) {
+		maxAge, rule := policy.For(g)
+		entry := BackupCompliance{
+			Cluster:       g.Cluster,
+			VMID:          g.VMID,
+			Name:          g.Name,
+			Type:          g.Type,
+			Node:          g.Node,
+			Pool:          g.Pool,
+			Tags:          splitGuestTags(g.Tags),
+			SizeTrend:     []BackupSizePoint{},
+			MaxAgeSeconds: int64(maxAge.Seconds()),
+			Policy:        rule,
+			Violation:     BackupViolationNoBackup,
+		}
+
+		list := archives[guestKey{g.Cluster, g.VMID}]
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+		entry.RetentionCount = len(list)
+		for _, b := range list {
+			entry.SizeTrend = append(entry.SizeTrend, BackupSizePoint{CreatedAt: b.CreatedAt, Size: b.Size})
+		}
+		if n := len(list); n > 0 {
+			last := list[n-1]
+			age := int64(now.Sub(last.CreatedAt).Seconds())
+			entry.LastBackup, entry.LastBackupID, entry.LastBackupSize, entry.AgeSeconds = &last.CreatedAt, last.ID, last.Size, &age
+			if n > 1 && list[n-2].Size > 0 {
+				change := math.Round((last.Size-list[n-2].Size)/list[n-2].Size*10000) / 100
+				entry.SizeChangePercent = &change
+			}
+			entry.Violation = ""
+			if now.Sub(last.CreatedAt) > maxAge {
+				entry.Violation = BackupViolationTooOld
+			}
+		}
+
+		// Une archive récente peut se trouver sur un storage illisible
+		if unknown || (entry.Violation != "" && partialBackups[g.Cluster]) {
+			entry.Violation = BackupViolationUnknown
+		}
+		entry.Compliant = entry.Violation == ""
+		report.Count(entry)
+		report.Guests = append(report.Guests, entry)
+	}
+
+	for _, guests := range [][]ProxmoxGuest{inv.VMs, inv.LXC} {
+		for _, g := range guests {
+			if !g.Template {
+				evaluate(g, false)
+			}
+		}
+	}
+
+	// Invités des nœuds illisibles : seuls leur nom et leur type sont connus (cluster/resources)
+	failed := map[string]bool{}
+	for _, c := range inv.Clusters {
+		for _, guestType := range []string{"qemu", "lxc"} {
+			for _, node := range c.FailedNodes(guestType) {
+				failed[c.Name+"/"+node+"/"+guestType] = true
+			}
+		}
+	}
+	for _, n := range inv.Nodes {
+		for _, summaries := range [][]ProxmoxGuestSummary{n.VMs, n.LXC} {
+			for _, summary := range summaries {
+				if failed[n.Cluster+"/"+n.Name+"/"+summary.Type] {
+					evaluate(ProxmoxGuest{Cluster: n.Cluster, VMID: summary.ID, Name: summary.Name, Type: summary.Type, Node: n.Name}, true)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(report.Guests, func(i, j int) bool {
+		a, b := report.Guests[i], report.Guests[j]
+		if a.Compliant != b.Compliant {
+			return !a.Compliant
+		}
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return a.VMID < b.VMID
+	})
+	return report
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// Types d'événements SSE émis à partir des différences entre deux snapshots d'inventaire
+const (
+	EventVMStatusChanged  = "vm.status_changed"
+	EventVMCreated        = "vm.created"
+	EventVMDeleted        = "vm.deleted"
+	EventLXCStatusChanged = "lxc.status_changed"
+	EventLXCCreated       = "lxc.created"
+	EventLXCDeleted       = "lxc.deleted"
+	EventNodeOffline      = "node.offline"
+	EventNodeOnline       = "node.online"
+	EventStorageThreshold = "storage.threshold"
+	EventTaskFinished     = "task.finished"
+)
+
+// InventoryEventSource identifie l'objet concerné par un événement d'inventaire
+type InventoryEventSource struct {
+	Cluster   string    `json:"cluster,omitempty"`
+	Kind      string    `json:"kind"` // node|qemu|lxc|storage|task
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Node      string    `json:"node,omitempty"`
+	Pool      string    `json:"pool,omitempty"` // invités uniquement
+	Tags      string    `json:"tags,omitempty"` // invités uniquement
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// InventoryEvent est implémenté par tous les événements d'inventaire (via InventoryEventSource)
+type InventoryEvent interface {
+	EventSource() InventoryEventSource
+}
+
+// EventSource retourne l'identification de l'objet concerné
+func (s InventoryEventSource) EventSource() InventoryEventSource {
+	return s
+}
+
+// Target retourne la cible de permission de l'objet (nœud, pool et tags)
+func (s InventoryEventSource) Target() PermissionTarget {
+	return PermissionTarget{Node: s.Node, Pool: s.Pool, Tags: splitGuestTags(s.Tags)}
+}
+
+// StatusChangeEvent signale le changement de statut d'un nœud ou d'un invité
+type StatusChangeEvent struct {
+	InventoryEventSource
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// GuestLifecycleEvent signale l'apparition (Before nil) ou la disparition (After nil) d'un invité
+type GuestLifecycleEvent struct {
+	InventoryEventSource
+	Before *ProxmoxGuest `json:"before"`
+	After  *ProxmoxGuest `json:"after"`
+}
+
+// StorageThresholdEvent signale le franchissement du seuil d'occupation d'un storage, dans un sens ou dans l'autre
+type StorageThresholdEvent struct {
+	InventoryEventSource
+	Threshold float64 `json:"threshold"` // en pourcentage
+	Exceeded  bool    `json:"exceeded"`  // true si le seuil est dépassé après le changement
+	Before    float64 `json:"before"`
+	After     float64 `json:"after"`
+}
+
+// TaskFinishedEvent signale la fin d'une tâche Proxmox.
+// Before est nil quand la tâche a démarré et s'est terminée entre deux collectes.
+type TaskFinishedEvent struct {
+	InventoryEventSource
+	Before *ProxmoxTask `json:"before"`
+	After  *ProxmoxTask `json:"after"`
+}
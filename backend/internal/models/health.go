@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limites de l'intervalle de vérification d'une application (secondes, 0 = intervalle par défaut)
+const (
+	MinAppCheckInterval = 10
+	MaxAppCheckInterval = 86400
+)
+
+// HealthCheckRetention est la durée de conservation de l'historique des health checks (fenêtre d'uptime la plus longue)
+const HealthCheckRetention = 30 * 24 * time.Hour
+
+// UptimeWindow est une période de calcul de l'uptime
+type UptimeWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// UptimeWindows liste les périodes d'uptime exposées par /api/v1/apps/{id}/uptime
+var UptimeWindows = []UptimeWindow{
+	{Name: "24h", Duration: 24 * time.Hour},
+	{Name: "7d", Duration: 7 * 24 * time.Hour},
+	{Name: "30d", Duration: 30 * 24 * time.Hour},
+}
+
+// HealthCheck est le résultat enregistré d'un health check planifié
+type HealthCheck struct {
+	ID         int       `json:"id" db:"id"`
+	AppID      int       `json:"app_id" db:"app_id"`
+	Status     string    `json:"status" db:"status"`         // online|offline
+	LatencyMs  int64     `json:"latency_ms" db:"latency_ms"` // durée de la vérification
+	StatusCode *int      `json:"status_code,omitempty" db:"status_code"`
+	Error      *string   `json:"error,omitempty" db:"error"`
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at"`
+}
+
+// NewHealthCheck convertit le statut retourné par une vérification en résultat enregistrable
+func NewHealthCheck(status *HealthStatus) *HealthCheck {
+	check := &HealthCheck{
+		AppID:      status.AppID,
+		Status:     status.Status,
+		StatusCode: status.StatusCode,
+		Error:      status.Error,
+		CheckedAt:  status.LastCheck,
+	}
+	if status.Latency != nil {
+		check.LatencyMs = *status.Latency
+	}
+	return check
+}
+
+// HealthStatus retourne le résultat sous la forme du statut de santé de l'application
+func (c *HealthCheck) HealthStatus() *HealthStatus {
+	latency := c.LatencyMs
+	return &HealthStatus{
+		AppID:      c.AppID,
+		Status:     c.Status,
+		Latency:    &latency,
+		LastCheck:  c.CheckedAt,
+		StatusCode: c.StatusCode,
+		Error:      c.Error,
+	}
+}
+
+// AppHealth est l'état de santé d'une application : dernier résultat et historique récent
+type AppHealth struct {
+	AppID           int            `json:"app_id"`
+	IntervalSeconds int            `json:"interval_seconds"` // intervalle effectif des vérifications planifiées
+	Current         *HealthStatus  `json:"current"`          // nil si l'application n'a jamais été vérifiée
+	Checks          []*HealthCheck `json:"checks"`           // du plus récent au plus ancien
+}
+
+// UptimeStats résume les health checks d'une application sur une période.
+// Les percentiles de latence portent sur les vérifications réussies ; ils sont absents sans vérification réussie.
+type UptimeStats struct {
+	Window        string   `json:"window"`
+	Checks        int      `json:"checks"`
+	Failures      int      `json:"failures"`
+	UptimePercent *float64 `json:"uptime_percent"` // nil sans vérification sur la période
+	LatencyP50    *int64   `json:"latency_p50_ms,omitempty"`
+	LatencyP95    *int64   `json:"latency_p95_ms,omitempty"`
+	LatencyP99    *int64   `json:"latency_p99_ms,omitempty"`
+}
+
+// AppUptime est l'uptime d'une application sur chaque période de UptimeWindows
+type AppUptime struct {
+	AppID   int           `json:"app_id"`
+	Windows []UptimeStats `json:"windows"`
+}
+
+// NewUptimeStats construit les statistiques d'une période à partir du nombre de vérifications et de
+// vérifications réussies ; latency retourne la latence de rang rank (à partir de 1) des vérifications
+// réussies triées par latence croissante, et sert au calcul des percentiles.
+func NewUptimeStats(window string, checks, online int, latency func(rank int) (int64, error)) (UptimeStats, error) {
+	stats := UptimeStats{Window: window, Checks: checks, Failures: checks - online}
+	if checks > 0 {
+		percent := math.Round(float64(online)/float64(checks)*100000) / 1000
+		stats.UptimePercent = &percent
+	}
+	if online == 0 {
+		return stats, nil
+	}
+	for _, target := range []struct {
+		p     float64
+		value **int64
+	}{{50, &stats.LatencyP50}, {95, &stats.LatencyP95}, {99, &stats.LatencyP99}} {
+		value, err := latency(percentileRank(target.p, online))
+		if err != nil {
+			return stats, err
+		}
+		*target.value = &value
+	}
+	return stats, nil
+}
+
+// percentileRank retourne le rang (à partir de 1) du percentile p parmi n valeurs triées (méthode du rang le plus proche)
+func percentileRank(p float64, n int) int {
+	return max(int(math.Ceil(p/100*float64(n))), 1)
+}
+
+// validateCheckInterval valide l'intervalle de vérification d'une application
+func validateCheckInterval(seconds int) error {
+	if seconds != 0 && (seconds < MinAppCheckInterval || seconds > MaxAppCheckInterval) {
+		return fmt.Errorf("check_interval must be 0 (default) or between %d and %d seconds", MinAppCheckInterval, MaxAppCheckInterval)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Résolutions de l'historique des métriques (en secondes, 0 pour les points bruts)
+const (
+	MetricsResolutionRaw    = 0
+	MetricsResolution5m     = 300
+	MetricsResolutionHourly = 3600
+)
+
+// MetricsRetention associe chaque résolution à sa durée de conservation
+var MetricsRetention = map[int]time.Duration{
+	MetricsResolutionRaw:    24 * time.Hour,
+	MetricsResolution5m:     30 * 24 * time.Hour,
+	MetricsResolutionHourly: 365 * 24 * time.Hour,
+}
+
+// MetricKinds liste les types d'objets dont l'historique est conservé
+var MetricKinds = []string{"node", "qemu", "lxc", "storage"}
+
+// MetricSample est une mesure instantanée d'un objet de l'inventaire
+type MetricSample struct {
+	Kind      string    `json:"kind"` // node|qemu|lxc|storage
+	Cluster   string    `json:"cluster,omitempty"`
+	ObjectID  string    `json:"id"` // nom du nœud, VMID ou storage
+	Timestamp time.Time `json:"timestamp"`
+	CPU       float64   `json:"cpu"`    // en pourcentage
+	Memory    float64   `json:"memory"` // en pourcentage
+	Disk      float64   `json:"disk"`   // en pourcentage
+}
+
+// MetricPoint est un point agrégé d'une série
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	CPU       float64   `json:"cpu"`
+	Memory    float64   `json:"memory"`
+	Disk      float64   `json:"disk"`
+}
+
+// MetricsQuery décrit une requête d'historique
+type MetricsQuery struct {
+	Kind       string
+	Cluster    string // vide pour tous les clusters
+	ObjectID   string
+	From       time.Time
+	To         time.Time
+	Step       time.Duration
+	Resolution int
+}
+
+// MetricSeries est la réponse de /api/v1/metrics/{kind}/{id}
+type MetricSeries struct {
+	Kind       string        `json:"kind"`
+	ID         string        `json:"id"`
+	Cluster    string        `json:"cluster,omitempty"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Step       int64         `json:"step"`       // en secondes
+	Resolution int           `json:"resolution"` // résolution stockée utilisée, en secondes
+	Points     []MetricPoint `json:"points"`
+}
+
+// Validate valide une requête d'historique
+func (q *MetricsQuery) Validate() error {
+	valid := false
+	for _, kind := range MetricKinds {
+		if q.Kind == kind {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("kind must be one of %v", MetricKinds)
+	}
+	if q.ObjectID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	if q.Step < time.Second {
+		return fmt.Errorf("step must be at least 1s")
+	}
+	return nil
+}
@@ -2,56 +2,97 @@ package models
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
 // App représente une application monitorée
 type App struct {
-	ID         int       `json:"id" db:"id"`
-	Name       string    `json:"name" db:"name"`
-	Protocol   string    `json:"protocol" db:"protocol"`
-	Host       string    `json:"host" db:"host"`
-	Port       int       `json:"port" db:"port"`
-	Path       string    `json:"path" db:"path"`
-	Tag        *string   `json:"tag" db:"tag"`
-	Icon       *string   `json:"icon" db:"icon"`
-	HealthPath string    `json:"health_path" db:"health_path"`
-	HealthType string    `json:"health_type" db:"health_type"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-}
-
-// Alert représente une alerte système
+	ID            int       `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	Protocol      string    `json:"protocol" db:"protocol"`
+	Host          string    `json:"host" db:"host"`
+	Port          int       `json:"port" db:"port"`
+	Path          string    `json:"path" db:"path"`
+	Tag           *string   `json:"tag" db:"tag"`
+	Icon          *string   `json:"icon" db:"icon"`
+	HealthPath    string    `json:"health_path" db:"health_path"`
+	HealthType    string    `json:"health_type" db:"health_type"`
+	CheckInterval int       `json:"check_interval" db:"check_interval"` // secondes entre deux health checks planifiés, 0 = intervalle par défaut
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Alert représente une alerte système.
+// Cycle de vie : firing → acknowledged → resolved (une alerte peut être résolue sans avoir été acquittée).
 type Alert struct {
-	ID           int       `json:"id" db:"id"`
-	Source       string    `json:"source" db:"source"`
-	Severity     string    `json:"severity" db:"severity"`
-	Title        string    `json:"title" db:"title"`
-	Message      string    `json:"message" db:"message"`
-	Payload      *string   `json:"payload" db:"payload"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	Acknowledged bool      `json:"acknowledged" db:"acknowledged"`
-}
+	ID             int               `json:"id" db:"id"`
+	Source         string            `json:"source" db:"source"`
+	Severity       string            `json:"severity" db:"severity"`
+	Title          string            `json:"title" db:"title"`
+	Message        string            `json:"message" db:"message"`
+	Payload        *string           `json:"payload" db:"payload"`
+	Labels         map[string]string `json:"labels,omitempty" db:"labels"` // labels de l'objet concerné (cluster, node...), utilisés par les silences
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	Acknowledged   bool              `json:"acknowledged" db:"acknowledged"`
+	Status         string            `json:"status" db:"status"`                     // firing|acknowledged|resolved
+	Fingerprint    string            `json:"fingerprint,omitempty" db:"fingerprint"` // identifie la condition (règle + objet) pour la déduplication
+	RuleID         *int              `json:"rule_id,omitempty" db:"rule_id"`         // règle à l'origine de l'alerte
+	AcknowledgedAt *time.Time        `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty" db:"resolved_at"`
+	ResolvedBy     string            `json:"resolved_by,omitempty" db:"resolved_by"` // vide si résolue automatiquement
+	SnoozedUntil   *time.Time        `json:"snoozed_until,omitempty" db:"snoozed_until"`
+	AssigneeID     *int              `json:"assignee_id,omitempty" db:"assignee_id"`
+	Assignee       string            `json:"assignee,omitempty" db:"assignee"`
+	SilencedBy     []int             `json:"silenced_by,omitempty" db:"-"` // silences actifs couvrant l'alerte, calculés à la lecture
+}
+
+// États d'une alerte
+const (
+	AlertStatusFiring       = "firing"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
 
 // NotifySubscription représente un abonnement aux notifications
 type NotifySubscription struct {
-	ID        int       `json:"id" db:"id"`
-	Channel   string    `json:"channel" db:"channel"`
-	Endpoint  string    `json:"endpoint" db:"endpoint"`
-	Enabled   bool      `json:"enabled" db:"enabled"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID           int        `json:"id" db:"id"`
+	Channel      string     `json:"channel" db:"channel"`
+	Endpoint     string     `json:"endpoint" db:"endpoint"`
+	Secret       string     `json:"secret,omitempty" db:"secret"` // clé HMAC des webhooks (retournée uniquement à la création) ou token Gotify/ntfy
+	Enabled      bool       `json:"enabled" db:"enabled"`
+	FailureCount int        `json:"failure_count" db:"failure_count"`       // échecs de livraison consécutifs
+	DisabledAt   *time.Time `json:"disabled_at,omitempty" db:"disabled_at"` // désactivation automatique après trop d'échecs
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 }
 
 // EmailQueue représente un email en file d'attente
 type EmailQueue struct {
-	ID        int        `json:"id" db:"id"`
-	ToAddr    string     `json:"to_addr" db:"to_addr"`
-	Subject   string     `json:"subject" db:"subject"`
-	BodyText  string     `json:"body_text" db:"body_text"`
-	State     string     `json:"state" db:"state"`
-	LastError *string    `json:"last_error" db:"last_error"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	SentAt    *time.Time `json:"sent_at" db:"sent_at"`
-}
+	ID            int        `json:"id" db:"id"`
+	ToAddr        string     `json:"to_addr" db:"to_addr"`
+	Subject       string     `json:"subject" db:"subject"`
+	BodyText      string     `json:"body_text" db:"body_text"`
+	BodyHTML      string     `json:"body_html,omitempty" db:"body_html"` // partie HTML optionnelle (multipart/alternative)
+	State         string     `json:"state" db:"state"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	MaxAttempts   int        `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	SentAt        *time.Time `json:"sent_at" db:"sent_at"`
+}
+
+// États d'un email de la file d'attente
+const (
+	EmailStatePending = "pending" // en attente du premier envoi
+	EmailStateFailed  = "failed"  // échec temporaire, nouvelle tentative planifiée (next_attempt_at)
+	EmailStateSent    = "sent"
+	EmailStateDead    = "dead" // abandonné après max_attempts échecs ou une erreur permanente
+)
+
+// DefaultEmailMaxAttempts est le nombre de tentatives d'un email quand max_attempts n'est pas précisé
+const DefaultEmailMaxAttempts = 5
 
 // HealthStatus représente le statut de santé d'une app
 type HealthStatus struct {
@@ -65,15 +106,16 @@ type HealthStatus struct {
 
 // CreateAppRequest représente une requête de création d'app
 type CreateAppRequest struct {
-	Name       string  `json:"name"`
-	Protocol   string  `json:"protocol"`
-	Host       string  `json:"host"`
-	Port       int     `json:"port"`
-	Path       string  `json:"path"`
-	Tag        *string `json:"tag"`
-	Icon       *string `json:"icon"`
-	HealthPath string  `json:"health_path"`
-	HealthType string  `json:"health_type"`
+	Name          string  `json:"name"`
+	Protocol      string  `json:"protocol"`
+	Host          string  `json:"host"`
+	Port          int     `json:"port"`
+	Path          string  `json:"path"`
+	Tag           *string `json:"tag"`
+	Icon          *string `json:"icon"`
+	HealthPath    string  `json:"health_path"`
+	HealthType    string  `json:"health_type"`
+	CheckInterval int     `json:"check_interval"` // secondes, 0 = intervalle par défaut
 }
 
 // CreateAlertRequest représente une requête de création d'alerte
@@ -94,6 +136,12 @@ type NotifyTestRequest struct {
 type SubscribeRequest struct {
 	Channel  string `json:"channel"`
 	Endpoint string `json:"endpoint"`
+	Secret   string `json:"secret,omitempty"` // webhook : clé HMAC, générée si absente ; gotify, ntfy : token
+}
+
+// UpdateSubscriptionRequest représente une requête de modification d'abonnement
+type UpdateSubscriptionRequest struct {
+	Enabled *bool `json:"enabled"`
 }
 
 // SSEEvent représente un événement Server-Sent Events
@@ -116,7 +164,7 @@ func (a *App) Validate() error {
 	if a.Port <= 0 || a.Port > 65535 {
 		return fmt.Errorf("port must be between 1 and 65535")
 	}
-	return nil
+	return validateCheckInterval(a.CheckInterval)
 }
 
 // Validate valide les données d'une Alert
@@ -127,7 +175,7 @@ func (a *Alert) Validate() error {
 	if a.Message == "" {
 		return fmt.Errorf("message is required")
 	}
-	if a.Severity != "low" && a.Severity != "medium" && a.Severity != "high" && a.Severity != "critical" {
+	if !slices.Contains(AlertSeverities, a.Severity) {
 		return fmt.Errorf("severity must be low, medium, high, or critical")
 	}
 	return nil
@@ -135,13 +183,16 @@ func (a *Alert) Validate() error {
 
 // Validate valide les données d'une NotifySubscription
 func (n *NotifySubscription) Validate() error {
-	if n.Channel != "email" && n.Channel != "webhook" {
-		return fmt.Errorf("channel must be email or webhook")
+	if !slices.Contains(NotifyChannels, n.Channel) {
+		return fmt.Errorf("channel must be one of %s", strings.Join(NotifyChannels, ", "))
 	}
 	if n.Endpoint == "" {
 		return fmt.Errorf("endpoint is required")
 	}
-	return nil
+	if n.Secret != "" && !ChannelUsesSecret(n.Channel) {
+		return fmt.Errorf("secret is not supported by the %s channel", n.Channel)
+	}
+	return n.validateEndpoint()
 }
 
 // Validate valide les données d'un EmailQueue
@@ -152,8 +203,10 @@ func (e *EmailQueue) Validate() error {
 	if e.Subject == "" {
 		return fmt.Errorf("subject is required")
 	}
-	if e.State != "pending" && e.State != "sent" && e.State != "failed" {
-		return fmt.Errorf("state must be pending, sent, or failed")
+	switch e.State {
+	case EmailStatePending, EmailStateFailed, EmailStateSent, EmailStateDead:
+	default:
+		return fmt.Errorf("state must be pending, failed, sent, or dead")
 	}
 
 	// Validation basique de l'email
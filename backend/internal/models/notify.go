@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Canaux de notification (NotifySubscription.Channel)
+const (
+	ChannelEmail      = "email"
+	ChannelWebhook    = "webhook"    // JSON signé HMAC-SHA256
+	ChannelSlack      = "slack"      // incoming webhook Slack
+	ChannelMattermost = "mattermost" // incoming webhook Mattermost (format compatible Slack)
+	ChannelDiscord    = "discord"
+	ChannelTeams      = "teams" // Microsoft Teams (Workflows / connecteur entrant, carte adaptative)
+	ChannelGotify     = "gotify"
+	ChannelNtfy       = "ntfy"
+)
+
+// NotifyChannels liste les canaux de notification supportés
+var NotifyChannels = []string{ChannelEmail, ChannelWebhook, ChannelSlack, ChannelMattermost, ChannelDiscord,
+	ChannelTeams, ChannelGotify, ChannelNtfy}
+
+// ntfyTopicPattern est le format d'un topic ntfy
+var ntfyTopicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ChannelUsesSecret indique si le canal utilise le secret de l'abonnement
+// (clé HMAC des webhooks, token d'application Gotify, token d'accès ntfy)
+func ChannelUsesSecret(channel string) bool {
+	return channel == ChannelWebhook || channel == ChannelGotify || channel == ChannelNtfy
+}
+
+// validateEndpoint vérifie l'endpoint d'un abonnement selon son canal
+func (n *NotifySubscription) validateEndpoint() error {
+	if n.Channel == ChannelEmail {
+		if !isValidEmail(n.Endpoint) {
+			return fmt.Errorf("invalid email address: %s", n.Endpoint)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(n.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s endpoint must be an absolute http(s) URL", n.Channel)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	switch n.Channel {
+	case ChannelSlack:
+		if u.Scheme != "https" || host != "hooks.slack.com" || !strings.HasPrefix(u.Path, "/services/") {
+			return fmt.Errorf("slack endpoint must be an incoming webhook URL (https://hooks.slack.com/services/...)")
+		}
+	case ChannelMattermost:
+		if !strings.Contains(u.Path, "/hooks/") {
+			return fmt.Errorf("mattermost endpoint must be an incoming webhook URL (https://mattermost.example.com/hooks/...)")
+		}
+	case ChannelDiscord:
+		switch host {
+		case "discord.com", "discordapp.com", "ptb.discord.com", "canary.discord.com":
+		default:
+			return fmt.Errorf("discord endpoint must be a discord.com webhook URL")
+		}
+		if u.Scheme != "https" || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return fmt.Errorf("discord endpoint must be a webhook URL (https://discord.com/api/webhooks/...)")
+		}
+	case ChannelTeams:
+		if u.Scheme != "https" {
+			return fmt.Errorf("teams endpoint must be an https URL")
+		}
+	case ChannelGotify:
+		if n.Secret == "" && u.Query().Get("token") == "" {
+			return fmt.Errorf("gotify requires an application token (secret)")
+		}
+	case ChannelNtfy:
+		topic := u.Path[strings.LastIndex(u.Path, "/")+1:]
+		if !ntfyTopicPattern.MatchString(topic) {
+			return fmt.Errorf("ntfy endpoint must include the topic (https://ntfy.sh/<topic>)")
+		}
+	}
+	return nil
+}
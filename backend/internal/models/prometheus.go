@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Authentification des sources de données Prometheus
+const (
+	PrometheusAuthNone   = "none"
+	PrometheusAuthBasic  = "basic"
+	PrometheusAuthBearer = "bearer"
+)
+
+// PrometheusDataSource est une source de données Prometheus configurée côté serveur.
+// Les requêtes PromQL du dashboard ne peuvent viser que ces sources ; le secret n'est jamais renvoyé au frontend.
+type PrometheusDataSource struct {
+	ID            int       `json:"id" db:"id"`
+	Name          string    `json:"name" db:"name"`
+	URL           string    `json:"url" db:"url"`             // ex: http://prometheus:9090
+	AuthType      string    `json:"auth_type" db:"auth_type"` // none|basic|bearer
+	Username      string    `json:"username,omitempty" db:"username"`
+	Secret        string    `json:"-" db:"secret"` // mot de passe (basic) ou token (bearer), chiffré en base
+	HasSecret     bool      `json:"has_secret"`
+	TLSSkipVerify bool      `json:"tls_skip_verify" db:"tls_skip_verify"`
+	IsDefault     bool      `json:"is_default" db:"is_default"` // source interrogée quand la requête n'en précise pas
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PrometheusDataSourceRequest représente une requête de création ou de mise à jour d'une source de données.
+// Les champs absents d'une mise à jour conservent leur valeur actuelle.
+type PrometheusDataSourceRequest struct {
+	Name          *string `json:"name"`
+	URL           *string `json:"url"`
+	AuthType      *string `json:"auth_type"`
+	Username      *string `json:"username"`
+	Secret        *string `json:"secret"`
+	TLSSkipVerify *bool   `json:"tls_skip_verify"`
+	IsDefault     *bool   `json:"is_default"`
+}
+
+// Apply applique les champs renseignés de la requête à la source de données
+func (r *PrometheusDataSourceRequest) Apply(ds *PrometheusDataSource) {
+	if r.Name != nil {
+		ds.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.URL != nil {
+		ds.URL = strings.TrimSuffix(strings.TrimSpace(*r.URL), "/")
+	}
+	if r.AuthType != nil {
+		ds.AuthType = strings.ToLower(strings.TrimSpace(*r.AuthType))
+	}
+	if r.Username != nil {
+		ds.Username = strings.TrimSpace(*r.Username)
+	}
+	if r.Secret != nil {
+		ds.Secret = *r.Secret
+	}
+	if r.TLSSkipVerify != nil {
+		ds.TLSSkipVerify = *r.TLSSkipVerify
+	}
+	if r.IsDefault != nil {
+		ds.IsDefault = *r.IsDefault
+	}
+	if ds.AuthType == "" {
+		ds.AuthType = PrometheusAuthNone
+	}
+	if ds.AuthType == PrometheusAuthNone {
+		ds.Username, ds.Secret = "", ""
+	}
+	ds.HasSecret = ds.Secret != ""
+}
+
+// Validate valide une source de données Prometheus
+func (ds *PrometheusDataSource) Validate() error {
+	if ds.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(ds.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("url must not contain credentials, a query or a fragment")
+	}
+	switch ds.AuthType {
+	case PrometheusAuthNone:
+	case PrometheusAuthBasic:
+		if ds.Username == "" || ds.Secret == "" {
+			return fmt.Errorf("basic auth requires username and secret")
+		}
+	case PrometheusAuthBearer:
+		if ds.Secret == "" {
+			return fmt.Errorf("bearer auth requires secret")
+		}
+	default:
+		return fmt.Errorf("auth_type must be one of: none, basic, bearer")
+	}
+	return nil
+}
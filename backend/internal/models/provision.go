@@ -0,0 +1,191 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Statuts d'un job de provisionnement
+const (
+	ProvisionJobPending   = "pending"
+	ProvisionJobRunning   = "running"
+	ProvisionJobSucceeded = "succeeded"
+	ProvisionJobFailed    = "failed"
+)
+
+// Étapes d'un job de provisionnement, dans l'ordre d'exécution
+const (
+	ProvisionStepAllocate  = "allocate"  // réservation du VMID (cluster/nextid)
+	ProvisionStepClone     = "clone"     // tâche de clonage du template
+	ProvisionStepConfigure = "configure" // cloud-init
+	ProvisionStepStart     = "start"     // démarrage optionnel
+	ProvisionStepDone      = "done"
+)
+
+// Limites des VMID acceptés par Proxmox
+const (
+	MinGuestVMID = 100
+	MaxGuestVMID = 999999999
+)
+
+// MaxCloudInitInterfaces est le nombre maximal d'ipconfigN acceptés
+const MaxCloudInitInterfaces = 32
+
+var (
+	guestNamePattern  = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9\-.]{0,61}[a-zA-Z0-9])?$`)
+	proxmoxIDPattern  = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+	sshKeyPattern     = regexp.MustCompile(`^(ssh-|ecdsa-|sk-)[a-zA-Z0-9@.\-]+ [A-Za-z0-9+/=]+( .*)?$`)
+	ipConfigPattern   = regexp.MustCompile(`^[a-z0-9]+=[^,=\s]+(,[a-z0-9]+=[^,=\s]+)*$`)
+	cloudInitUserExpr = regexp.MustCompile(`^[a-z_][a-z0-9_\-]{0,31}$`)
+)
+
+// CloudInitConfig est la configuration cloud-init appliquée à une VM clonée
+type CloudInitConfig struct {
+	User         string   `json:"user,omitempty"`
+	Password     string   `json:"password,omitempty"` // jamais enregistré avec le job
+	SSHKeys      []string `json:"ssh_keys,omitempty"`
+	IPConfig     []string `json:"ip_config,omitempty"` // ipconfig0, ipconfig1... : "ip=dhcp" ou "ip=10.0.0.5/24,gw=10.0.0.1"
+	Nameserver   string   `json:"nameserver,omitempty"`
+	SearchDomain string   `json:"search_domain,omitempty"`
+}
+
+// ProvisionSpec décrit l'invité à créer par clonage d'un template
+type ProvisionSpec struct {
+	Node         string           `json:"node"` // nœud du template
+	GuestType    string           `json:"type"` // qemu|lxc
+	TemplateVMID int              `json:"template_vmid"`
+	TargetNode   string           `json:"target_node,omitempty"` // nœud cible, à défaut celui du template
+	Storage      string           `json:"storage,omitempty"`     // stockage cible d'un clone complet
+	Full         bool             `json:"full"`                  // clone complet, sinon clone lié
+	VMID         int              `json:"vmid,omitempty"`        // 0 = alloué via cluster/nextid
+	Name         string           `json:"name"`                  // name (qemu) ou hostname (lxc)
+	Pool         string           `json:"pool,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	CloudInit    *CloudInitConfig `json:"cloud_init,omitempty"` // VMs uniquement
+	Start        bool             `json:"start"`
+}
+
+// Target retourne le nœud sur lequel l'invité est créé
+func (s *ProvisionSpec) Target() string {
+	if s.TargetNode != "" {
+		return s.TargetNode
+	}
+	return s.Node
+}
+
+// Validate valide la description d'un clone
+func (s *ProvisionSpec) Validate() error {
+	if s.GuestType != "qemu" && s.GuestType != "lxc" {
+		return fmt.Errorf("type must be qemu or lxc")
+	}
+	if !proxmoxIDPattern.MatchString(s.Node) {
+		return fmt.Errorf("node is required")
+	}
+	if s.TargetNode != "" && !proxmoxIDPattern.MatchString(s.TargetNode) {
+		return fmt.Errorf("invalid target_node %q", s.TargetNode)
+	}
+	if s.TemplateVMID < MinGuestVMID || s.TemplateVMID > MaxGuestVMID {
+		return fmt.Errorf("template_vmid is required")
+	}
+	if s.VMID != 0 && (s.VMID < MinGuestVMID || s.VMID > MaxGuestVMID) {
+		return fmt.Errorf("vmid must be 0 (next free id) or between %d and %d", MinGuestVMID, MaxGuestVMID)
+	}
+	if !guestNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("name must be a valid DNS name")
+	}
+	if s.Storage != "" {
+		if !s.Full {
+			return fmt.Errorf("storage can only be set for a full clone")
+		}
+		if !proxmoxIDPattern.MatchString(s.Storage) {
+			return fmt.Errorf("invalid storage %q", s.Storage)
+		}
+	}
+	if s.Pool != "" && !proxmoxIDPattern.MatchString(s.Pool) {
+		return fmt.Errorf("invalid pool %q", s.Pool)
+	}
+	if len(s.Description) > 8192 {
+		return fmt.Errorf("description must not exceed 8192 bytes")
+	}
+	if s.CloudInit != nil {
+		if s.GuestType != "qemu" {
+			return fmt.Errorf("cloud_init is only supported for VMs")
+		}
+		if err := s.CloudInit.Validate(); err != nil {
+			return fmt.Errorf("cloud_init: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate valide une configuration cloud-init
+func (c *CloudInitConfig) Validate() error {
+	if c.User != "" && !cloudInitUserExpr.MatchString(c.User) {
+		return fmt.Errorf("invalid user %q", c.User)
+	}
+	for _, key := range c.SSHKeys {
+		if !sshKeyPattern.MatchString(strings.TrimSpace(key)) {
+			return fmt.Errorf("invalid SSH public key %q", key)
+		}
+	}
+	if len(c.IPConfig) > MaxCloudInitInterfaces {
+		return fmt.Errorf("at most %d ip_config entries are supported", MaxCloudInitInterfaces)
+	}
+	for i, ip := range c.IPConfig {
+		if !ipConfigPattern.MatchString(ip) {
+			return fmt.Errorf("invalid ip_config[%d] %q (expected ip=dhcp or ip=<cidr>,gw=<ip>)", i, ip)
+		}
+	}
+	for _, field := range []string{c.Nameserver, c.SearchDomain} {
+		if strings.ContainsAny(field, ",=\n") {
+			return fmt.Errorf("invalid nameserver or search_domain %q", field)
+		}
+	}
+	return nil
+}
+
+// Sanitized retourne une copie de la description sans le mot de passe cloud-init, pour l'enregistrement
+func (s ProvisionSpec) Sanitized() ProvisionSpec {
+	if s.CloudInit != nil {
+		ci := *s.CloudInit
+		ci.Password = ""
+		s.CloudInit = &ci
+	}
+	return s
+}
+
+// ProvisionJob est un job de provisionnement : clonage d'un template, cloud-init et démarrage optionnel
+type ProvisionJob struct {
+	ID           int           `json:"id"`
+	ConnectionID *int          `json:"connection_id,omitempty"`
+	Spec         ProvisionSpec `json:"spec"` // sans mot de passe cloud-init
+	Status       string        `json:"status"`
+	Step         string        `json:"step"`
+	Progress     int           `json:"progress"`       // 0 à 100
+	VMID         int           `json:"vmid,omitempty"` // VMID de l'invité créé, connu après l'allocation
+	UPID         string        `json:"upid,omitempty"` // dernière tâche Proxmox suivie
+	Error        string        `json:"error,omitempty"`
+	CreatedBy    *int          `json:"created_by,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	FinishedAt   *time.Time    `json:"finished_at,omitempty"`
+}
+
+// Finished indique si le job est terminé (succès ou échec)
+func (j *ProvisionJob) Finished() bool {
+	return j.Status == ProvisionJobSucceeded || j.Status == ProvisionJobFailed
+}
+
+// ProvisionTemplate est un template de VM ou de conteneur disponible pour le provisionnement
+type ProvisionTemplate struct {
+	Node    string   `json:"node"`
+	Type    string   `json:"type"` // qemu|lxc
+	VMID    int      `json:"vmid"`
+	Name    string   `json:"name"`
+	Pool    string   `json:"pool,omitempty"`
+	Tags    []string `json:"tags"`
+	MaxMem  int64    `json:"maxmem"`
+	MaxDisk int64    `json:"maxdisk"`
+}
@@ -0,0 +1,284 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxmoxGuestSummary représente une VM ou un conteneur listé sous un nœud
+type ProxmoxGuestSummary struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Type   string `json:"type"` // qemu|lxc
+}
+
+// ProxmoxNode représente un nœud Proxmox tel qu'affiché par le dashboard
+type ProxmoxNode struct {
+	ID          string                `json:"id"`
+	Cluster     string                `json:"cluster,omitempty"`
+	Name        string                `json:"name"`
+	Status      string                `json:"status"`
+	CPUUsage    float64               `json:"cpu_usage"`    // en pourcentage
+	MemoryUsage float64               `json:"memory_usage"` // en pourcentage
+	DiskUsage   float64               `json:"disk_usage"`   // en pourcentage
+	Uptime      int64                 `json:"uptime"`       // en secondes
+	Temperature float64               `json:"temperature"`
+	LastUpdate  time.Time             `json:"last_update"`
+	Version     string                `json:"version"`
+	IPAddress   string                `json:"ip_address"`
+	LoadAvg     string                `json:"loadavg"`
+	KVersion    string                `json:"kversion"`
+	CPUInfo     string                `json:"cpuinfo"`
+	MemInfo     string                `json:"meminfo"`
+	SwapInfo    string                `json:"swapinfo"`
+	DiskInfo    string                `json:"diskinfo"`
+	VMsCount    int                   `json:"vms_count"`
+	LXCCount    int                   `json:"lxc_count"`
+	VMs         []ProxmoxGuestSummary `json:"vms"`
+	LXC         []ProxmoxGuestSummary `json:"lxc"`
+}
+
+// ProxmoxGuest représente une VM (qemu) ou un conteneur (lxc)
+type ProxmoxGuest struct {
+	ID          int       `json:"id"`
+	Cluster     string    `json:"cluster,omitempty"`
+	VMID        int       `json:"vmid"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"` // qemu|lxc
+	Status      string    `json:"status"`
+	Node        string    `json:"node"`
+	Tags        string    `json:"tags,omitempty"`
+	Pool        string    `json:"pool,omitempty"`
+	Template    bool      `json:"template,omitempty"`
+	CPUUsage    float64   `json:"cpu_usage"`    // en pourcentage
+	MemoryUsage float64   `json:"memory_usage"` // en pourcentage
+	DiskUsage   float64   `json:"disk_usage"`   // en pourcentage
+	Uptime      int64     `json:"uptime"`       // en secondes
+	MaxCPU      float64   `json:"maxcpu,omitempty"`
+	MaxMem      int64     `json:"maxmem,omitempty"` // en octets
+	Disk        int64     `json:"disk,omitempty"`   // en octets
+	IPAddress   string    `json:"ip_address,omitempty"`
+	LastUpdate  time.Time `json:"last_update"`
+}
+
+// ProxmoxStorage représente un storage Proxmox
+type ProxmoxStorage struct {
+	ID           string    `json:"id"`
+	Cluster      string    `json:"cluster,omitempty"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Status       string    `json:"status"` // online|offline
+	Node         string    `json:"node"`
+	TotalSpace   float64   `json:"total_space"` // en GB
+	UsedSpace    float64   `json:"used_space"`  // en GB
+	FreeSpace    float64   `json:"free_space"`  // en GB
+	UsagePercent float64   `json:"usage_percent"`
+	Content      string    `json:"content"`
+	LastUpdate   time.Time `json:"last_update"`
+}
+
+// ProxmoxNetwork représente une interface réseau d'un nœud
+type ProxmoxNetwork struct {
+	ID         string    `json:"id"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Status     string    `json:"status"` // active|inactive
+	Node       string    `json:"node"`
+	IPAddress  string    `json:"ip_address"`
+	Netmask    string    `json:"netmask"`
+	Gateway    string    `json:"gateway"`
+	Active     bool      `json:"active"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// ProxmoxBackup représente une archive de sauvegarde vzdump présente dans un storage (ID : volid)
+type ProxmoxBackup struct {
+	ID          string    `json:"id"`
+	Cluster     string    `json:"cluster,omitempty"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"` // vm|lxc
+	Status      string    `json:"status"`
+	Size        float64   `json:"size"` // en GB
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Node        string    `json:"node"`
+	Storage     string    `json:"storage"`
+	Format      string    `json:"format,omitempty"` // vma.zst, tar.gz, pbs-vm...
+	Notes       string    `json:"notes,omitempty"`
+	Protected   bool      `json:"protected"`
+	VMID        int       `json:"vmid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ProxmoxGuestSnapshot représente un snapshot d'une VM ou d'un conteneur (hors état courant)
+type ProxmoxGuestSnapshot struct {
+	Cluster     string    `json:"cluster,omitempty"`
+	Node        string    `json:"node"`
+	Type        string    `json:"type"` // qemu|lxc
+	VMID        int       `json:"vmid"`
+	GuestName   string    `json:"guest_name"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Parent      string    `json:"parent,omitempty"`
+	VMState     bool      `json:"vmstate"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ProxmoxTask représente une tâche Proxmox
+type ProxmoxTask struct {
+	ID          string     `json:"id"` // UPID
+	Cluster     string     `json:"cluster,omitempty"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`               // pending|running|completed|failed
+	ExitStatus  string     `json:"exitstatus,omitempty"` // statut de fin de Proxmox (OK, WARNINGS: n, message d'erreur)
+	Progress    int        `json:"progress"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Duration    *int       `json:"duration,omitempty"` // en secondes
+	User        string     `json:"user"`
+	Node        string     `json:"node"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ProxmoxInventory regroupe l'inventaire d'un ou plusieurs clusters Proxmox
+type ProxmoxInventory struct {
+	Nodes          []ProxmoxNode          `json:"nodes"`
+	VMs            []ProxmoxGuest         `json:"vms"`
+	LXC            []ProxmoxGuest         `json:"lxc"`
+	Storages       []ProxmoxStorage       `json:"storages"`
+	Networks       []ProxmoxNetwork       `json:"networks"`
+	Tasks          []ProxmoxTask          `json:"tasks,omitempty"`           // tâches récentes, pour détecter leur fin
+	Backups        []ProxmoxBackup        `json:"backups,omitempty"`         // sauvegardes vzdump, pour l'âge du dernier backup
+	GuestSnapshots []ProxmoxGuestSnapshot `json:"guest_snapshots,omitempty"` // snapshots des invités, pour leur âge
+	Clusters       []ProxmoxClusterStatus `json:"clusters,omitempty"`
+}
+
+// ProxmoxClusterStatus décrit le résultat de la collecte d'un cluster.
+// Un cluster injoignable a Success à false et son erreur dans Error ; les autres clusters restent servis.
+// Les nœuds dont les invités ou les storages n'ont pas pu être listés, les storages de sauvegarde
+// illisibles et les invités dont les snapshots n'ont pas pu être lus sont reportés à part : leurs objets
+// sont absents de l'inventaire sans avoir été supprimés.
+type ProxmoxClusterStatus struct {
+	ConnectionID         int        `json:"connection_id"`
+	Name                 string     `json:"name"`
+	Success              bool       `json:"success"`
+	Error                string     `json:"error,omitempty"`
+	Warnings             []string   `json:"warnings,omitempty"` // collectes partielles (VMs, storages...)
+	FailedVMNodes        []string   `json:"failed_vm_nodes,omitempty"`
+	FailedLXCNodes       []string   `json:"failed_lxc_nodes,omitempty"`
+	FailedStorageNodes   []string   `json:"failed_storage_nodes,omitempty"`
+	FailedBackupStorages []string   `json:"failed_backup_storages,omitempty"` // "nœud/storage", ou le nœud seul
+	FailedSnapshotGuests []string   `json:"failed_snapshot_guests,omitempty"` // "type/vmid" (ex: qemu/100)
+	CollectedAt          time.Time  `json:"collected_at"`
+	ContentCollectedAt   *time.Time `json:"content_collected_at,omitempty"` // dernière lecture des sauvegardes et snapshots
+	DurationMs           int64      `json:"duration_ms"`
+}
+
+// FailedNodes retourne les nœuds dont les invités du type donné (qemu ou lxc) n'ont pas pu être listés
+func (s ProxmoxClusterStatus) FailedNodes(guestType string) []string {
+	if guestType == "lxc" {
+		return s.FailedLXCNodes
+	}
+	return s.FailedVMNodes
+}
+
+// SnapshotsFailed indique si les snapshots d'un invité (qemu ou lxc) n'ont pas pu être lus
+func (s ProxmoxClusterStatus) SnapshotsFailed(guestType string, vmid int) bool {
+	return slices.Contains(s.FailedSnapshotGuests, GuestRef(guestType, vmid))
+}
+
+// GuestRef identifie un invité d'un cluster ("qemu/100")
+func GuestRef(guestType string, vmid int) string {
+	return guestType + "/" + strconv.Itoa(vmid)
+}
+
+// ProxmoxSnapshot est un inventaire multi-clusters horodaté produit par le poller
+type ProxmoxSnapshot struct {
+	ProxmoxInventory
+	CollectedAt time.Time `json:"collected_at"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+// ProxmoxCacheStatus décrit la fraîcheur du snapshot servi par l'API
+type ProxmoxCacheStatus struct {
+	CollectedAt     *time.Time `json:"collected_at,omitempty"`
+	AgeSeconds      int64      `json:"age_seconds"`
+	IntervalSeconds int64      `json:"interval_seconds"`
+	Stale           bool       `json:"stale"`   // aucun snapshot, ou snapshot plus vieux que deux intervalles
+	Polling         bool       `json:"polling"` // une collecte est en cours
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// ProxmoxConnection représente une connexion à un cluster Proxmox enregistrée côté serveur.
+// Les secrets ne sont jamais renvoyés au frontend.
+type ProxmoxConnection struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	URL         string    `json:"url" db:"url"`
+	Username    string    `json:"username" db:"username"`
+	Secret      string    `json:"-" db:"secret"`   // secret du token API (chiffré en base)
+	Password    string    `json:"-" db:"password"` // mot de passe optionnel pour les tickets (console VNC)
+	HasPassword bool      `json:"has_password"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProxmoxConnectionRequest représente une requête de création ou de mise à jour d'une connexion.
+// Les champs absents d'une mise à jour conservent leur valeur actuelle.
+type ProxmoxConnectionRequest struct {
+	Name     *string `json:"name"`
+	URL      *string `json:"url"`
+	Username *string `json:"username"`
+	Secret   *string `json:"secret"`
+	Password *string `json:"password"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// Apply applique les champs renseignés de la requête à la connexion
+func (r *ProxmoxConnectionRequest) Apply(c *ProxmoxConnection) {
+	if r.Name != nil {
+		c.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.URL != nil {
+		c.URL = strings.TrimSuffix(strings.TrimSpace(*r.URL), "/")
+	}
+	if r.Username != nil {
+		c.Username = strings.TrimSpace(*r.Username)
+	}
+	if r.Secret != nil {
+		c.Secret = *r.Secret
+	}
+	if r.Password != nil {
+		c.Password = *r.Password
+	}
+	if r.Enabled != nil {
+		c.Enabled = *r.Enabled
+	}
+	c.HasPassword = c.Password != ""
+}
+
+// Validate valide les données d'une ProxmoxConnection
+func (c *ProxmoxConnection) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if c.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if c.Secret == "" {
+		return fmt.Errorf("secret is required")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// QuietHours est une plage horaire quotidienne pendant laquelle les notifications d'une route
+// sont retenues jusqu'à la fin de la plage (ex: 22:00 → 07:00)
+type QuietHours struct {
+	Start    string `json:"start"`              // HH:MM
+	End      string `json:"end"`                // HH:MM, le lendemain si antérieure au début
+	Days     []int  `json:"days,omitempty"`     // jours de début de la plage (0 = dimanche), tous si vide
+	Timezone string `json:"timezone,omitempty"` // ex: Europe/Paris, fuseau du serveur si vide
+}
+
+// parseClock convertit "HH:MM" en minutes depuis minuit
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// location retourne le fuseau de la plage
+func (q *QuietHours) location() (*time.Location, error) {
+	if q.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(q.Timezone)
+}
+
+// Validate valide une plage de silence horaire
+func (q *QuietHours) Validate() error {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return fmt.Errorf("quiet_hours.start: %w", err)
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return fmt.Errorf("quiet_hours.end: %w", err)
+	}
+	if start == end {
+		return fmt.Errorf("quiet_hours.start and quiet_hours.end must differ")
+	}
+	for _, day := range q.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("quiet_hours.days must be between 0 (sunday) and 6")
+		}
+	}
+	if _, err := q.location(); err != nil {
+		return fmt.Errorf("invalid quiet_hours.timezone %q", q.Timezone)
+	}
+	return nil
+}
+
+// ActiveUntil indique si t est dans la plage et retourne alors sa fin
+func (q *QuietHours) ActiveUntil(t time.Time) (time.Time, bool) {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := q.location()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	// Une plage qui passe minuit a pu commencer la veille
+	for _, offset := range []int{0, -1} {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		if len(q.Days) > 0 && !slices.Contains(q.Days, int(day.Weekday())) {
+			continue
+		}
+		from := day.Add(time.Duration(start) * time.Minute)
+		to := day.Add(time.Duration(end) * time.Minute)
+		if end < start {
+			to = to.Add(24 * time.Hour)
+		}
+		if !local.Before(from) && local.Before(to) {
+			return to, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NotifyRoute est une route de notification. Les routes sont évaluées par position croissante :
+// la première route qui correspond à une alerte la reçoit (les suivantes aussi si Continue).
+// Les alertes d'une route sont regroupées par les labels GroupBy ; chaque groupe est notifié
+// après GroupWait, puis rappelé tous les RepeatInterval tant qu'il reste des alertes ouvertes.
+type NotifyRoute struct {
+	ID              int            `json:"id"`
+	Name            string         `json:"name"`
+	Position        int            `json:"position"`
+	Severities      []string       `json:"severities,omitempty"`       // sévérités acceptées, toutes si vide
+	Sources         []string       `json:"sources,omitempty"`          // préfixes de source (ex: "rule:", "prometheus"), toutes si vide
+	Matchers        []LabelMatcher `json:"matchers,omitempty"`         // labels supplémentaires
+	SubscriptionIDs []int          `json:"subscription_ids,omitempty"` // abonnements notifiés ; vide = flux SSE uniquement
+	GroupBy         []string       `json:"group_by,omitempty"`         // labels de regroupement (ex: cluster, node, source_prefix)
+	GroupWait       Duration       `json:"group_wait"`
+	RepeatInterval  Duration       `json:"repeat_interval"` // 0 = pas de rappel
+	QuietHours      *QuietHours    `json:"quiet_hours,omitempty"`
+	Continue        bool           `json:"continue"`
+	Enabled         bool           `json:"enabled"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// Validate valide une route de notification
+func (r *NotifyRoute) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, severity := range r.Severities {
+		if !slices.Contains(AlertSeverities, severity) {
+			return fmt.Errorf("severities must be low, medium, high, or critical")
+		}
+	}
+	for i := range r.Matchers {
+		if err := r.Matchers[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for _, label := range r.GroupBy {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("group_by labels must not be empty")
+		}
+	}
+	if r.GroupWait < 0 || r.RepeatInterval < 0 {
+		return fmt.Errorf("group_wait and repeat_interval must be positive")
+	}
+	if r.RepeatInterval > 0 && r.RepeatInterval < Duration(time.Minute) {
+		return fmt.Errorf("repeat_interval must be at least 1m")
+	}
+	if r.QuietHours != nil {
+		if err := r.QuietHours.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches indique si une alerte est concernée par la route
+func (r *NotifyRoute) Matches(alert *Alert) bool {
+	if len(r.Severities) > 0 && !slices.Contains(r.Severities, alert.Severity) {
+		return false
+	}
+	if len(r.Sources) > 0 && !slices.ContainsFunc(r.Sources, func(prefix string) bool {
+		return strings.HasPrefix(alert.Source, prefix)
+	}) {
+		return false
+	}
+	labels := alert.MatchLabels()
+	for i := range r.Matchers {
+		if !r.Matchers[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// GroupLabels retourne les labels GroupBy d'une alerte et la clé de son groupe ("cluster=lab,node=pve1")
+func (r *NotifyRoute) GroupLabels(alert *Alert) (map[string]string, string) {
+	labels := alert.MatchLabels()
+	group := make(map[string]string, len(r.GroupBy))
+	keys := make([]string, 0, len(r.GroupBy))
+	for _, name := range r.GroupBy {
+		group[name] = labels[name]
+	}
+	for name, value := range group {
+		keys = append(keys, name+"="+value)
+	}
+	sort.Strings(keys)
+	return group, strings.Join(keys, ",")
+}
+
+// AlertGroup est un groupe d'alertes d'une route, notifiées ensemble
+type AlertGroup struct {
+	ID          int               `json:"id"`
+	RouteID     int               `json:"route_id"`
+	Key         string            `json:"key"`
+	Labels      map[string]string `json:"labels"`
+	FlushAt     *time.Time        `json:"flush_at,omitempty"` // prochaine notification planifiée
+	LastFlushAt *time.Time        `json:"last_flush_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Alerts      []*Alert          `json:"alerts,omitempty"`
+}
+
+// AlertGroupMember est une alerte d'un groupe avec l'état sous lequel elle a été notifiée en dernier
+type AlertGroupMember struct {
+	Alert          *Alert
+	NotifiedStatus string // "" si jamais notifiée, firing ou resolved
+}
+
+// AlertGroupInfo décrit le groupe d'une notification dans le payload des webhooks
+type AlertGroupInfo struct {
+	Key       string            `json:"key"`
+	Labels    map[string]string `json:"labels"`
+	RouteID   int               `json:"route_id"`
+	RouteName string            `json:"route_name"`
+}
+
+// NotifiedStatus ramène l'état d'une alerte à celui qui est notifié : une alerte acquittée reste firing
+func NotifiedStatus(alert *Alert) string {
+	if alert.Status == AlertStatusResolved {
+		return AlertStatusResolved
+	}
+	return AlertStatusFiring
+}
+
+// SeverityRank retourne le rang d'une sévérité (0 pour low, -1 si inconnue)
+func SeverityRank(severity string) int {
+	return slices.Index(AlertSeverities, severity)
+}
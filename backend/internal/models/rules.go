@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Métriques évaluées par les règles d'alerte. Les pourcentages vont de 0 à 100,
+// les états valent 1 (vrai) ou 0 (faux), l'âge des snapshots est en jours.
+const (
+	RuleMetricNodeCPU        = "node.cpu"
+	RuleMetricNodeMemory     = "node.memory"
+	RuleMetricNodeDisk       = "node.disk"
+	RuleMetricNodeOnline     = "node.online"
+	RuleMetricGuestCPU       = "guest.cpu"
+	RuleMetricGuestMemory    = "guest.memory"
+	RuleMetricGuestDisk      = "guest.disk"
+	RuleMetricGuestRunning   = "guest.running"
+	RuleMetricGuestSnapshots = "guest.snapshot_age" // âge du plus ancien snapshot de l'invité (0 sans snapshot)
+	RuleMetricStorageUsage   = "storage.usage"
+	RuleMetricStorageActive  = "storage.active"
+	RuleMetricClusterReached = "cluster.reachable"
+)
+
+// RuleMetrics associe chaque métrique au type d'objet évalué (node, guest, storage, cluster)
+var RuleMetrics = map[string]string{
+	RuleMetricNodeCPU:        "node",
+	RuleMetricNodeMemory:     "node",
+	RuleMetricNodeDisk:       "node",
+	RuleMetricNodeOnline:     "node",
+	RuleMetricGuestCPU:       "guest",
+	RuleMetricGuestMemory:    "guest",
+	RuleMetricGuestDisk:      "guest",
+	RuleMetricGuestRunning:   "guest",
+	RuleMetricGuestSnapshots: "guest",
+	RuleMetricStorageUsage:   "storage",
+	RuleMetricStorageActive:  "storage",
+	RuleMetricClusterReached: "cluster",
+}
+
+// RuleComparators liste les comparateurs acceptés par les règles
+var RuleComparators = []string{">", ">=", "<", "<=", "==", "!="}
+
+// AlertSeverities liste les sévérités des alertes
+var AlertSeverities = []string{"low", "medium", "high", "critical"}
+
+// RuleScope restreint les objets évalués par une règle. Un champ vide ne filtre pas.
+type RuleScope struct {
+	Cluster string   `json:"cluster,omitempty"`
+	Node    string   `json:"node,omitempty"`
+	Type    string   `json:"type,omitempty"` // qemu|lxc, pour les métriques guest.*
+	Tags    []string `json:"tags,omitempty"` // invités portant tous ces tags
+	IDs     []string `json:"ids,omitempty"`  // noms des nœuds, VMIDs, storages ou clusters
+}
+
+// Duration est une durée sérialisée en JSON sous la forme "5m" (un nombre est lu en secondes)
+type Duration time.Duration
+
+// MarshalJSON encode la durée au format de time.Duration
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON lit une durée ("90s", "5m") ou un nombre de secondes
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(time.Duration(seconds * float64(time.Second)))
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\" or a number of seconds")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// AlertRule est une règle d'alerte évaluée après chaque collecte de l'inventaire :
+// une alerte est créée quand "metric comparator threshold" est vrai pour un objet
+// pendant au moins For, et résolue quand la condition redevient fausse.
+type AlertRule struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Metric      string    `json:"metric"`
+	Scope       RuleScope `json:"scope"`
+	Comparator  string    `json:"comparator"`
+	Threshold   float64   `json:"threshold"`
+	For         Duration  `json:"for"`
+	Severity    string    `json:"severity"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate valide une règle d'alerte
+func (r *AlertRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	kind, ok := RuleMetrics[r.Metric]
+	if !ok {
+		metrics := make([]string, 0, len(RuleMetrics))
+		for metric := range RuleMetrics {
+			metrics = append(metrics, metric)
+		}
+		slices.Sort(metrics)
+		return fmt.Errorf("metric must be one of %s", strings.Join(metrics, ", "))
+	}
+	if !slices.Contains(RuleComparators, r.Comparator) {
+		return fmt.Errorf("comparator must be one of %s", strings.Join(RuleComparators, " "))
+	}
+	if !slices.Contains(AlertSeverities, r.Severity) {
+		return fmt.Errorf("severity must be low, medium, high, or critical")
+	}
+	if r.For < 0 {
+		return fmt.Errorf("for must be positive")
+	}
+	if r.Scope.Type != "" && r.Scope.Type != "qemu" && r.Scope.Type != "lxc" {
+		return fmt.Errorf("scope.type must be qemu or lxc")
+	}
+	if (r.Scope.Type != "" || len(r.Scope.Tags) > 0) && kind != "guest" {
+		return fmt.Errorf("scope.type and scope.tags only apply to guest metrics")
+	}
+	if r.Scope.Node != "" && kind == "cluster" {
+		return fmt.Errorf("scope.node does not apply to %s", r.Metric)
+	}
+	return nil
+}
+
+// Matches indique si la valeur mesurée remplit la condition de la règle
+func (r *AlertRule) Matches(value float64) bool {
+	switch r.Comparator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+	return false
+}
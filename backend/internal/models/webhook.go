@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Événements notifiés aux webhooks
+const (
+	WebhookEventAlertCreated  = "alert.created"
+	WebhookEventAlertResolved = "alert.resolved"
+	WebhookEventAlertGroup    = "alert.group" // plusieurs alertes d'un même groupe de routage
+	WebhookEventPing          = "ping"
+)
+
+// États d'une tentative de livraison webhook
+const (
+	DeliveryStatePending   = "pending"   // tentative planifiée (next_attempt_at)
+	DeliveryStateDelivered = "delivered" // réponse 2xx
+	DeliveryStateFailed    = "failed"    // échec, une nouvelle tentative est planifiée
+	DeliveryStateDead      = "dead"      // échec de la dernière tentative
+	DeliveryStateCancelled = "cancelled" // abonnement désactivé avant la tentative
+)
+
+// WebhookDelivery est une tentative de livraison d'un événement à un abonnement webhook.
+// Les tentatives successives d'un même événement partagent le même DeliveryID.
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscription_id"`
+	DeliveryID     string     `json:"delivery_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Attempt        int        `json:"attempt"`
+	State          string     `json:"state"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	StatusCode     int        `json:"status_code,omitempty"`
+	Response       string     `json:"response,omitempty"` // début du corps de la réponse
+	Error          string     `json:"error,omitempty"`
+	DurationMs     int64      `json:"duration_ms"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// WebhookPayload est le corps JSON envoyé aux webhooks
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	DeliveryID string    `json:"delivery_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Alert      *Alert    `json:"alert,omitempty"`
+	// Notification d'un groupe de routage : Alerts contient les alertes ouvertes et celles résolues
+	// depuis la notification précédente (événement alert.group)
+	Alerts []*Alert        `json:"alerts,omitempty"`
+	Group  *AlertGroupInfo `json:"group,omitempty"`
+}
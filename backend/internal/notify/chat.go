@@ -0,0 +1,342 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// summary regroupe les éléments d'un événement affichés par les formateurs de messages
+type summary struct {
+	title    string // "[CRITICAL] Nœud hors ligne"
+	severity string
+	source   string
+	text     string
+	ackURL   string
+	time     time.Time
+}
+
+// summarize extrait le titre, la sévérité, la source, le message et le lien d'un événement
+func summarize(event *Event) summary {
+	if event.Alert == nil && len(event.Alerts) == 0 {
+		return summary{
+			title:    "Test de notification - ProxmoxDash",
+			severity: "info",
+			source:   "proxmoxdash",
+			text:     "Si vous recevez ce message, l'abonnement fonctionne correctement.",
+			time:     event.Timestamp,
+		}
+	}
+	if len(event.Alerts) > 0 {
+		return summarizeGroup(event)
+	}
+	alert := event.Alert
+	if alert.Status == models.AlertStatusResolved {
+		return summary{
+			title:    fmt.Sprintf("[RÉSOLU] %s", alert.Title),
+			severity: "resolved",
+			source:   alert.Source,
+			text:     alert.Message,
+			time:     event.Timestamp,
+		}
+	}
+	return summary{
+		title:    fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), alert.Title),
+		severity: alert.Severity,
+		source:   alert.Source,
+		text:     alert.Message,
+		ackURL:   event.AckURL,
+		time:     alert.CreatedAt,
+	}
+}
+
+// summarizeGroup résume une notification groupée : la sévérité est la plus haute des alertes ouvertes
+// ("resolved" si toutes sont résolues) et le message liste les alertes
+func summarizeGroup(event *Event) summary {
+	s := summary{severity: "resolved", ackURL: event.AckURL, time: event.Timestamp}
+	var lines, sources []string
+	firing := 0
+	for _, alert := range event.Alerts {
+		state := "RÉSOLU"
+		if alert.Status != models.AlertStatusResolved {
+			firing++
+			state = strings.ToUpper(alert.Severity)
+			if s.severity == "resolved" || models.SeverityRank(alert.Severity) > models.SeverityRank(s.severity) {
+				s.severity = alert.Severity
+			}
+		}
+		lines = append(lines, fmt.Sprintf("• [%s] %s", state, alert.Title))
+		if !slices.Contains(sources, alert.Source) {
+			sources = append(sources, alert.Source)
+		}
+	}
+
+	var labels []string
+	if event.Group != nil {
+		for name, value := range event.Group.Labels {
+			if value != "" {
+				labels = append(labels, name+"="+value)
+			}
+		}
+	}
+	sort.Strings(labels)
+	scope := ""
+	if len(labels) > 0 {
+		scope = fmt.Sprintf(" (%s)", strings.Join(labels, ", "))
+	}
+
+	if firing == 0 {
+		s.title = fmt.Sprintf("[RÉSOLU] %d alertes%s", len(event.Alerts), scope)
+	} else {
+		s.title = fmt.Sprintf("[%s] %d alertes ouvertes%s", strings.ToUpper(s.severity), firing, scope)
+	}
+	s.source = strings.Join(sources, ", ")
+	s.text = strings.Join(lines, "\n")
+	return s
+}
+
+// severityColor retourne la couleur associée à une sévérité (palette des emails)
+func severityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "#c62828"
+	case "high", "warning":
+		return "#ef8f00"
+	case "resolved":
+		return "#2e7d32"
+	default:
+		return "#1e88e5"
+	}
+}
+
+// jsonMessage encode le corps JSON d'un message
+func jsonMessage(endpoint string, body interface{}) (*message, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return &message{url: endpoint, contentType: "application/json", body: data}, nil
+}
+
+// formatSlack met en forme un événement pour un incoming webhook Slack (pièce jointe colorée)
+func formatSlack(sub *models.NotifySubscription, event *Event) (*message, error) {
+	// Slack interprète <, > et & (liens, mentions) : le contenu de l'alerte est échappé
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	s := summarize(event)
+	s.title, s.source, s.text = escape.Replace(s.title), escape.Replace(s.source), escape.Replace(s.text)
+	return slackMessage(sub.Endpoint, s, func(label, link string) string {
+		return fmt.Sprintf("<%s|%s>", link, label)
+	})
+}
+
+// formatMattermost met en forme un événement pour un incoming webhook Mattermost (format Slack, liens Markdown)
+func formatMattermost(sub *models.NotifySubscription, event *Event) (*message, error) {
+	return slackMessage(sub.Endpoint, summarize(event), func(label, link string) string {
+		return fmt.Sprintf("[%s](%s)", label, link)
+	})
+}
+
+// slackMessage construit le message commun à Slack et Mattermost ; link formate un lien
+func slackMessage(endpoint string, s summary, link func(label, url string) string) (*message, error) {
+	text := s.text
+	if s.ackURL != "" {
+		text = strings.TrimSpace(text + "\n" + link("Accuser réception", s.ackURL))
+	}
+
+	attachment := map[string]interface{}{
+		"fallback": s.title,
+		"color":    severityColor(s.severity),
+		"title":    s.title,
+		"text":     text,
+		"fields": []map[string]interface{}{
+			{"title": "Sévérité", "value": s.severity, "short": true},
+			{"title": "Source", "value": s.source, "short": true},
+		},
+		"footer": "ProxmoxDash",
+		"ts":     s.time.Unix(),
+	}
+	if s.ackURL != "" {
+		attachment["title_link"] = s.ackURL
+	}
+
+	return jsonMessage(endpoint, map[string]interface{}{
+		"username":    "ProxmoxDash",
+		"text":        s.title,
+		"attachments": []interface{}{attachment},
+	})
+}
+
+// formatDiscord met en forme un événement pour un webhook Discord (embed coloré)
+func formatDiscord(sub *models.NotifySubscription, event *Event) (*message, error) {
+	s := summarize(event)
+	description := s.text
+	if s.ackURL != "" {
+		description = strings.TrimSpace(fmt.Sprintf("%s\n[Accuser réception](%s)", description, s.ackURL))
+	}
+	color, _ := strconv.ParseInt(strings.TrimPrefix(severityColor(s.severity), "#"), 16, 32)
+
+	embed := map[string]interface{}{
+		"title":       truncate(s.title, 256),
+		"description": truncate(description, 4096),
+		"color":       color,
+		"fields": []map[string]interface{}{
+			{"name": "Sévérité", "value": s.severity, "inline": true},
+			{"name": "Source", "value": s.source, "inline": true},
+		},
+		"footer":    map[string]string{"text": "ProxmoxDash"},
+		"timestamp": s.time.UTC().Format(time.RFC3339),
+	}
+	if s.ackURL != "" {
+		embed["url"] = s.ackURL
+	}
+
+	return jsonMessage(sub.Endpoint, map[string]interface{}{
+		"username":         "ProxmoxDash",
+		"embeds":           []interface{}{embed},
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	})
+}
+
+// formatTeams met en forme un événement pour Microsoft Teams (carte adaptative)
+func formatTeams(sub *models.NotifySubscription, event *Event) (*message, error) {
+	s := summarize(event)
+	color := "Accent"
+	switch strings.ToLower(s.severity) {
+	case "critical":
+		color = "Attention"
+	case "high", "warning":
+		color = "Warning"
+	case "resolved":
+		color = "Good"
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "text": s.title, "size": "Large", "weight": "Bolder", "color": color, "wrap": true},
+			{"type": "FactSet", "facts": []map[string]string{
+				{"title": "Sévérité", "value": s.severity},
+				{"title": "Source", "value": s.source},
+				{"title": "Date", "value": s.time.Format("2006-01-02 15:04:05")},
+			}},
+			{"type": "TextBlock", "text": s.text, "wrap": true},
+		},
+	}
+	if s.ackURL != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "Accuser réception", "url": s.ackURL}}
+	}
+
+	return jsonMessage(sub.Endpoint, map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	})
+}
+
+// formatGotify met en forme un événement pour l'API message de Gotify.
+// Le token d'application est le secret de l'abonnement (ou le paramètre token de l'endpoint).
+func formatGotify(sub *models.NotifySubscription, event *Event) (*message, error) {
+	s := summarize(event)
+	priority := 2
+	switch strings.ToLower(s.severity) {
+	case "critical":
+		priority = 8
+	case "high", "warning":
+		priority = 5
+	}
+
+	endpoint := strings.TrimRight(sub.Endpoint, "/")
+	if u, err := url.Parse(endpoint); err == nil && !strings.HasSuffix(u.Path, "/message") {
+		u.Path = strings.TrimRight(u.Path, "/") + "/message"
+		endpoint = u.String()
+	}
+
+	text := fmt.Sprintf("**Source:** %s\n\n%s", s.source, s.text)
+	extras := map[string]interface{}{
+		"client::display": map[string]string{"contentType": "text/markdown"},
+	}
+	if s.ackURL != "" {
+		text += fmt.Sprintf("\n\n[Accuser réception](%s)", s.ackURL)
+		extras["client::notification"] = map[string]interface{}{"click": map[string]string{"url": s.ackURL}}
+	}
+
+	msg, err := jsonMessage(endpoint, map[string]interface{}{
+		"title":    s.title,
+		"message":  text,
+		"priority": priority,
+		"extras":   extras,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sub.Secret != "" {
+		msg.headers = map[string]string{"X-Gotify-Key": sub.Secret}
+	}
+	return msg, nil
+}
+
+// formatNtfy met en forme un événement pour ntfy (publication JSON à la racine du serveur).
+// L'endpoint est l'URL du topic ; le secret optionnel est un token d'accès.
+func formatNtfy(sub *models.NotifySubscription, event *Event) (*message, error) {
+	s := summarize(event)
+	priority, tag := 3, "information_source"
+	switch strings.ToLower(s.severity) {
+	case "critical":
+		priority, tag = 5, "rotating_light"
+	case "high", "warning":
+		priority, tag = 4, "warning"
+	case "resolved":
+		tag = "white_check_mark"
+	}
+
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ntfy endpoint: %w", err)
+	}
+	path := strings.TrimRight(u.Path, "/")
+	topic := path[strings.LastIndex(path, "/")+1:]
+	u.Path = path[:len(path)-len(topic)]
+	u.RawQuery = ""
+
+	body := map[string]interface{}{
+		"topic":    topic,
+		"title":    s.title,
+		"message":  fmt.Sprintf("Source: %s\n%s", s.source, s.text),
+		"priority": priority,
+		"tags":     []string{tag},
+		"markdown": true,
+	}
+	if s.ackURL != "" {
+		body["click"] = s.ackURL
+		body["actions"] = []map[string]string{{"action": "view", "label": "Accuser réception", "url": s.ackURL}}
+	}
+
+	msg, err := jsonMessage(u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Secret != "" {
+		msg.headers = map[string]string{"Authorization": "Bearer " + sub.Secret}
+	}
+	return msg, nil
+}
+
+// truncate tronque une chaîne à max caractères (limites de taille des embeds Discord)
+func truncate(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,335 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/config"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+)
+
+const (
+	// pollInterval est l'intervalle de lecture de la file des livraisons
+	pollInterval = 2 * time.Second
+	// batchSize est le nombre maximal de livraisons traitées par passage
+	batchSize = 20
+	// maxRetryDelay plafonne le délai entre deux tentatives
+	maxRetryDelay = time.Hour
+)
+
+// Dispatcher livre les événements aux abonnements via le Notifier de leur canal. Chaque tentative
+// est enregistrée dans webhook_deliveries ; un échec planifie la tentative suivante avec un délai
+// exponentiel et l'abonnement est désactivé après WebhookConfig.DisableAfter échecs consécutifs.
+type Dispatcher struct {
+	store     *store.Store
+	config    config.WebhookConfig
+	client    *http.Client
+	notifiers map[string]Notifier
+	publicURL string
+	wake      chan struct{}
+	quit      chan bool
+}
+
+// NewDispatcher crée un dispatcher avec les notifiers des canaux HTTP (webhook, Slack, Mattermost,
+// Discord, Teams, Gotify, ntfy). Le canal email est branché avec Register et NewEmailNotifier.
+func NewDispatcher(store *store.Store, cfg config.WebhookConfig) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// Une redirection est traitée comme un échec : la signature vise l'endpoint enregistré
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	d := &Dispatcher{
+		store:     store,
+		config:    cfg,
+		client:    client,
+		notifiers: map[string]Notifier{},
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan bool),
+	}
+	formatters := map[string]formatter{
+		models.ChannelWebhook:    formatWebhook,
+		models.ChannelSlack:      formatSlack,
+		models.ChannelMattermost: formatMattermost,
+		models.ChannelDiscord:    formatDiscord,
+		models.ChannelTeams:      formatTeams,
+		models.ChannelGotify:     formatGotify,
+		models.ChannelNtfy:       formatNtfy,
+	}
+	for channel, format := range formatters {
+		d.Register(channel, &httpNotifier{client: client, format: format})
+	}
+	return d
+}
+
+// Register associe un notifier à un canal, en remplaçant le notifier existant
+func (d *Dispatcher) Register(channel string, notifier Notifier) {
+	d.notifiers[channel] = notifier
+}
+
+// Supports indique si un notifier est enregistré pour le canal
+func (d *Dispatcher) Supports(channel string) bool {
+	_, ok := d.notifiers[channel]
+	return ok
+}
+
+// SetPublicURL configure l'URL publique du dashboard, utilisée pour les liens d'accusé de réception
+func (d *Dispatcher) SetPublicURL(publicURL string) {
+	d.publicURL = strings.TrimRight(publicURL, "/")
+}
+
+// ackURL retourne le lien vers une alerte dans le dashboard, vide si l'URL publique n'est pas définie
+// ou si l'alerte est résolue
+func (d *Dispatcher) ackURL(alert *models.Alert) string {
+	if d.publicURL == "" || alert == nil || alert.Status == models.AlertStatusResolved {
+		return ""
+	}
+	return fmt.Sprintf("%s/?alert=%d", d.publicURL, alert.ID)
+}
+
+// Start démarre la livraison des notifications en arrière-plan
+func (d *Dispatcher) Start() {
+	log.Println("🔔 Starting notification dispatcher...")
+	go d.run()
+}
+
+// Stop arrête la livraison des notifications
+func (d *Dispatcher) Stop() {
+	d.quit <- true
+}
+
+// run est la boucle principale du dispatcher
+func (d *Dispatcher) run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.ProcessQueue()
+		case <-d.wake:
+			d.ProcessQueue()
+		case <-d.quit:
+			log.Println("Notification dispatcher stopped")
+			return
+		}
+	}
+}
+
+// signal réveille la boucle de livraison sans attendre le prochain passage
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NotifyAlert planifie la notification d'une alerte. Si des routes actives correspondent à l'alerte,
+// elle rejoint leurs groupes et sera notifiée aux abonnements des routes après leur group_wait
+// (voir routeAlert) ; sinon elle est livrée immédiatement à tous les abonnements actifs des canaux
+// supportés. Une alerte résolue est notifiée avec l'événement alert.resolved. Les alertes en snooze
+// ou couvertes par un silence actif ne sont pas notifiées.
+func (d *Dispatcher) NotifyAlert(alert *models.Alert) error {
+	muted, err := d.store.IsAlertMuted(alert)
+	if err != nil {
+		return err
+	}
+	if muted {
+		log.Printf("🔕 Alerte %d non notifiée (silence ou snooze)", alert.ID)
+		return nil
+	}
+
+	routed, err := d.routeAlert(alert)
+	if err != nil || routed {
+		return err
+	}
+
+	payload := models.WebhookPayload{Event: alertEvent(alert), Alert: alert}
+	var errs []error
+	for _, channel := range models.NotifyChannels {
+		if !d.Supports(channel) {
+			continue
+		}
+		subs, err := d.store.GetEnabledSubscriptions(channel)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, sub := range subs {
+			if _, err := d.enqueue(sub.ID, payload); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	d.signal()
+	return errors.Join(errs...)
+}
+
+// alertEvent retourne l'événement notifié pour une alerte selon son état
+func alertEvent(alert *models.Alert) string {
+	if alert.Status == models.AlertStatusResolved {
+		return models.WebhookEventAlertResolved
+	}
+	return models.WebhookEventAlertCreated
+}
+
+// Ping planifie un événement de test pour un abonnement
+func (d *Dispatcher) Ping(subscriptionID int) (*models.WebhookDelivery, error) {
+	delivery, err := d.enqueue(subscriptionID, models.WebhookPayload{Event: models.WebhookEventPing})
+	if err != nil {
+		return nil, err
+	}
+	d.signal()
+	return delivery, nil
+}
+
+// enqueue crée la première tentative de livraison d'un événement pour un abonnement
+func (d *Dispatcher) enqueue(subscriptionID int, payload models.WebhookPayload) (*models.WebhookDelivery, error) {
+	payload.DeliveryID = newDeliveryID()
+	payload.Timestamp = time.Now().UTC()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		DeliveryID:     payload.DeliveryID,
+		Event:          payload.Event,
+		Payload:        string(body),
+		Attempt:        1,
+		State:          models.DeliveryStatePending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := d.store.CreateWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ProcessQueue notifie les groupes d'alertes échus puis effectue les tentatives de livraison échues
+func (d *Dispatcher) ProcessQueue() {
+	d.flushGroups(time.Now())
+
+	deliveries, err := d.store.GetDueWebhookDeliveries(time.Now(), batchSize)
+	if err != nil {
+		log.Printf("Error getting webhook deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		d.deliver(&deliveries[i])
+	}
+}
+
+// deliver effectue une tentative de livraison et planifie la suivante en cas d'échec
+func (d *Dispatcher) deliver(delivery *models.WebhookDelivery) {
+	sub, err := d.store.GetSubscriptionWithSecret(delivery.SubscriptionID)
+	var notifier Notifier
+	if err == nil {
+		notifier = d.notifiers[sub.Channel]
+		if notifier == nil {
+			err = fmt.Errorf("no notifier for channel %q", sub.Channel)
+		}
+	}
+	if err != nil || !sub.Enabled {
+		delivery.State = models.DeliveryStateCancelled
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if err := d.store.CompleteWebhookDelivery(delivery); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+
+	event := &Event{Body: []byte(delivery.Payload), Attempt: delivery.Attempt}
+	if err := json.Unmarshal(event.Body, &event.WebhookPayload); err != nil {
+		log.Printf("⚠️  Invalid payload for webhook delivery %d: %v", delivery.ID, err)
+	}
+	event.AckURL = d.ackURL(event.Alert)
+	if len(event.Alerts) > 0 {
+		event.AckURL = d.groupURL(event.Alerts)
+	}
+
+	start := time.Now()
+	result, err := notifier.Notify(ctx, sub, event)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.StatusCode = result.StatusCode
+	delivery.Response = result.Response
+
+	if err == nil {
+		delivery.State = models.DeliveryStateDelivered
+		if err := d.store.CompleteWebhookDelivery(delivery); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		if err := d.store.RecordSubscriptionSuccess(sub.ID); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	delivery.Error = err.Error()
+	delivery.State = models.DeliveryStateDead
+	if delivery.Attempt < d.config.MaxAttempts {
+		delivery.State = models.DeliveryStateFailed
+	}
+	if err := d.store.CompleteWebhookDelivery(delivery); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	if delivery.State == models.DeliveryStateFailed {
+		next := &models.WebhookDelivery{
+			SubscriptionID: delivery.SubscriptionID,
+			DeliveryID:     delivery.DeliveryID,
+			Event:          delivery.Event,
+			Payload:        delivery.Payload,
+			Attempt:        delivery.Attempt + 1,
+			NextAttemptAt:  time.Now().Add(retryDelay(d.config.RetryBackoff, delivery.Attempt)),
+		}
+		if err := d.store.CreateWebhookDelivery(next); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		log.Printf("Notification %s to %s subscription %d failed (attempt %d/%d, retry at %s): %v", delivery.Event,
+			sub.Channel, sub.ID, delivery.Attempt, d.config.MaxAttempts, next.NextAttemptAt.Format(time.RFC3339), delivery.Error)
+	} else {
+		log.Printf("☠️  Notification %s to %s subscription %d abandoned after %d attempts: %v", delivery.Event,
+			sub.Channel, sub.ID, delivery.Attempt, delivery.Error)
+	}
+
+	disabled, err := d.store.RecordSubscriptionFailure(sub.ID, d.config.DisableAfter)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	} else if disabled {
+		log.Printf("⛔ %s subscription %d disabled after %d consecutive failures", sub.Channel, sub.ID, d.config.DisableAfter)
+	}
+}
+
+// retryDelay calcule le délai avant la tentative suivant la n-ième tentative échouée (backoff exponentiel)
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"proxmox-dashboard/internal/email"
+	"proxmox-dashboard/internal/models"
+)
+
+// emailNotifier confie les événements des abonnements email au worker SMTP, qui gère ses propres relances
+type emailNotifier struct {
+	worker *email.Worker
+}
+
+// NewEmailNotifier crée le notifier du canal email
+func NewEmailNotifier(worker *email.Worker) Notifier {
+	return &emailNotifier{worker: worker}
+}
+
+// Notify met l'email d'alerte (groupée ou non, ou de test pour un ping) en file d'attente
+func (n *emailNotifier) Notify(ctx context.Context, sub *models.NotifySubscription, event *Event) (Result, error) {
+	var queued *models.EmailQueue
+	var err error
+	switch {
+	case len(event.Alerts) > 0:
+		queued, err = n.worker.SendAlertGroupEmail(sub.Endpoint, event.Alerts, event.Group, event.AckURL)
+	case event.Alert == nil:
+		queued, err = n.worker.SendTestEmail(sub.Endpoint)
+	default:
+		queued, err = n.worker.SendAlertEmail(sub.Endpoint, event.Alert, event.AckURL)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Response: fmt.Sprintf("queued as email %d", queued.ID)}, nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// Event est un événement à livrer à un abonnement, relu depuis la file webhook_deliveries
+type Event struct {
+	models.WebhookPayload
+	Body    []byte // corps JSON canonique, envoyé tel quel (et signé) au canal webhook
+	Attempt int
+	AckURL  string // lien vers l'alerte dans le dashboard, vide si PUBLIC_URL n'est pas défini
+}
+
+// Result est la réponse du service de notification, conservée dans l'historique des livraisons
+type Result struct {
+	StatusCode int
+	Response   string // début du corps de la réponse
+}
+
+// Notifier livre les événements aux abonnements d'un canal. Une erreur planifie une nouvelle tentative.
+type Notifier interface {
+	Notify(ctx context.Context, sub *models.NotifySubscription, event *Event) (Result, error)
+}
+
+// message est la requête HTTP produite par le formateur d'un canal
+type message struct {
+	url         string
+	contentType string
+	headers     map[string]string
+	body        []byte
+}
+
+// formatter met en forme un événement pour un canal HTTP
+type formatter func(sub *models.NotifySubscription, event *Event) (*message, error)
+
+// httpNotifier livre les événements par un POST dont le corps est produit par le formateur du canal
+type httpNotifier struct {
+	client *http.Client
+	format formatter
+}
+
+// Notify envoie l'événement mis en forme à l'endpoint de l'abonnement
+func (n *httpNotifier) Notify(ctx context.Context, sub *models.NotifySubscription, event *Event) (Result, error) {
+	var result Result
+
+	msg, err := n.format(sub, event)
+	if err != nil {
+		return result, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.url, bytes.NewReader(msg.body))
+	if err != nil {
+		return result, fmt.Errorf("invalid %s request: %w", sub.Channel, err)
+	}
+	req.Header.Set("Content-Type", msg.contentType)
+	req.Header.Set("User-Agent", "ProxmoxDash-Webhook/1.0")
+	req.Header.Set(HeaderEvent, event.Event)
+	req.Header.Set(HeaderDelivery, event.DeliveryID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(event.Attempt))
+	for key, value := range msg.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// L'URL des webhooks de chat contient leur token : elle n'apparaît pas dans l'erreur
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return result, fmt.Errorf("%s request failed: %w", sub.Channel, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	result.StatusCode = resp.StatusCode
+	result.Response = string(data)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("%s endpoint returned HTTP %d", sub.Channel, resp.StatusCode)
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// routeAlert ajoute une alerte aux groupes des routes actives qui la concernent, évaluées par position :
+// la première route correspondante la reçoit, les suivantes seulement si la précédente a Continue.
+// Retourne false si aucune route ne correspond (l'alerte est alors notifiée à tous les abonnements).
+// Une route sans abonnement réserve l'alerte au flux SSE du dashboard.
+func (d *Dispatcher) routeAlert(alert *models.Alert) (bool, error) {
+	routes, err := d.store.GetNotifyRoutes(true)
+	if err != nil {
+		return false, err
+	}
+
+	routed := false
+	now := time.Now()
+	for _, route := range routes {
+		if !route.Matches(alert) {
+			continue
+		}
+		routed = true
+		if len(route.SubscriptionIDs) > 0 {
+			if err := d.store.AddAlertToGroup(route, alert, now); err != nil {
+				return true, err
+			}
+		}
+		if !route.Continue {
+			break
+		}
+	}
+	if routed {
+		d.signal()
+	}
+	return routed, nil
+}
+
+// groupURL retourne le lien vers le dashboard d'une notification groupée, vide si toutes ses alertes sont résolues
+func (d *Dispatcher) groupURL(alerts []*models.Alert) string {
+	for _, alert := range alerts {
+		if alert.Status != models.AlertStatusResolved {
+			return d.publicURL
+		}
+	}
+	return ""
+}
+
+// flushGroups notifie les groupes d'alertes échus. Un groupe est notifié s'il contient des alertes
+// nouvelles ou dont l'état a changé depuis la notification précédente, ou à chaque repeat_interval de
+// sa route tant qu'il reste des alertes ouvertes. Pendant la plage de silence horaire de la route,
+// la notification est reportée à la fin de la plage.
+func (d *Dispatcher) flushGroups(now time.Time) {
+	groups, err := d.store.GetDueAlertGroups(now)
+	if err != nil {
+		log.Printf("Error getting alert groups: %v", err)
+		return
+	}
+	if len(groups) == 0 {
+		return
+	}
+	routes, err := d.store.GetNotifyRoutes(false)
+	if err != nil {
+		log.Printf("Error getting notification routes: %v", err)
+		return
+	}
+	byID := make(map[int]*models.NotifyRoute, len(routes))
+	for _, route := range routes {
+		byID[route.ID] = route
+	}
+
+	for _, group := range groups {
+		route := byID[group.RouteID]
+		if route == nil {
+			continue
+		}
+		if route.QuietHours != nil {
+			if until, active := route.QuietHours.ActiveUntil(now); active {
+				if err := d.store.PostponeAlertGroup(group.ID, until); err != nil {
+					log.Printf("⚠️  %v", err)
+				}
+				continue
+			}
+		}
+		if err := d.flushGroup(route, group, now); err != nil {
+			log.Printf("⚠️  Notification du groupe %q (route %s) : %v", group.Key, route.Name, err)
+		}
+	}
+}
+
+// flushGroup notifie un groupe échu aux abonnements de sa route et enregistre l'état notifié de ses alertes
+func (d *Dispatcher) flushGroup(route *models.NotifyRoute, group *models.AlertGroup, now time.Time) error {
+	members, err := d.store.GetAlertGroupMembers(group.ID)
+	if err != nil {
+		return err
+	}
+
+	var alerts []*models.Alert
+	notified := map[int]string{}
+	changed := false
+	for _, member := range members {
+		status := models.NotifiedStatus(member.Alert)
+		if status == models.AlertStatusResolved && member.NotifiedStatus == "" {
+			// Résolue avant d'avoir été notifiée : elle quitte le groupe sans notification
+			notified[member.Alert.ID] = status
+			continue
+		}
+		muted, err := d.store.IsAlertMuted(member.Alert)
+		if err != nil {
+			return err
+		}
+		if muted {
+			if status == models.AlertStatusResolved {
+				notified[member.Alert.ID] = status
+			}
+			continue
+		}
+		if status != member.NotifiedStatus {
+			changed = true
+		} else if status == models.AlertStatusResolved {
+			continue
+		}
+		alerts = append(alerts, member.Alert)
+		notified[member.Alert.ID] = status
+	}
+
+	// Sans changement, seul un rappel renvoie les alertes ouvertes
+	repeat := route.RepeatInterval > 0 && group.LastFlushAt != nil &&
+		!now.Before(group.LastFlushAt.Add(time.Duration(route.RepeatInterval)))
+	if len(alerts) > 0 && (changed || repeat) {
+		if err := d.sendGroup(route, group, alerts); err != nil {
+			return err
+		}
+	}
+	return d.store.CompleteAlertGroupFlush(group.ID, notified, now)
+}
+
+// sendGroup planifie la livraison d'une notification groupée aux abonnements actifs de la route.
+// Un groupe d'une seule alerte est notifié avec l'événement de l'alerte (alert.created ou alert.resolved).
+func (d *Dispatcher) sendGroup(route *models.NotifyRoute, group *models.AlertGroup, alerts []*models.Alert) error {
+	payload := models.WebhookPayload{
+		Event:  models.WebhookEventAlertGroup,
+		Alerts: alerts,
+		Group: &models.AlertGroupInfo{
+			Key:       group.Key,
+			Labels:    group.Labels,
+			RouteID:   route.ID,
+			RouteName: route.Name,
+		},
+	}
+	if len(alerts) == 1 {
+		payload.Event = alertEvent(alerts[0])
+		payload.Alert = alerts[0]
+		payload.Alerts = nil
+	}
+
+	var errs []error
+	for _, id := range route.SubscriptionIDs {
+		sub, err := d.store.GetSubscriptionWithSecret(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", route.Name, err))
+			continue
+		}
+		if !sub.Enabled || !d.Supports(sub.Channel) {
+			continue
+		}
+		if _, err := d.enqueue(sub.ID, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	log.Printf("📨 Groupe %q de la route %s notifié (%d alertes)", group.Key, route.Name, len(alerts))
+	return errors.Join(errs...)
+}
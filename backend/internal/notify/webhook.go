@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// En-têtes des requêtes de notification
+const (
+	HeaderEvent     = "X-ProxmoxDash-Event"
+	HeaderDelivery  = "X-ProxmoxDash-Delivery"
+	HeaderAttempt   = "X-ProxmoxDash-Attempt"
+	HeaderSignature = "X-ProxmoxDash-Signature-256"
+)
+
+// maxResponseSize est la taille du début de réponse conservé pour le débogage
+const maxResponseSize = 1024
+
+// Sign calcule la signature HMAC-SHA256 d'un corps de requête ("sha256=" suivi de l'empreinte hexadécimale).
+// Le destinataire la recalcule avec le secret de l'abonnement et la compare avec hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret génère un secret de signature aléatoire (256 bits, hexadécimal)
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// newDeliveryID génère l'identifiant partagé par les tentatives d'une même livraison
+func newDeliveryID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// formatWebhook envoie le payload JSON canonique, signé avec le secret de l'abonnement
+func formatWebhook(sub *models.NotifySubscription, event *Event) (*message, error) {
+	msg := &message{url: sub.Endpoint, contentType: "application/json", body: event.Body}
+	if sub.Secret != "" {
+		msg.headers = map[string]string{HeaderSignature: Sign(sub.Secret, event.Body)}
+	}
+	return msg, nil
+}
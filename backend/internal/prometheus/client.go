@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// maxResponseSize limite la taille d'une réponse relayée au frontend
+const maxResponseSize = 32 << 20
+
+// Endpoint décrit un endpoint de l'API HTTP de Prometheus relayé par le dashboard.
+// Seuls les paramètres autorisés sont transmis, encodés par url.Values.
+type Endpoint struct {
+	Name     string
+	Allowed  []string // paramètres transmis
+	Required []string // paramètres obligatoires
+}
+
+// Endpoints relayés (api/v1/query, api/v1/query_range, api/v1/series, api/v1/labels, api/v1/label/{name}/values)
+var (
+	EndpointQuery       = Endpoint{Name: "query", Allowed: []string{"query", "time", "timeout", "limit"}, Required: []string{"query"}}
+	EndpointQueryRange  = Endpoint{Name: "query_range", Allowed: []string{"query", "start", "end", "step", "timeout", "limit"}, Required: []string{"query", "start", "end", "step"}}
+	EndpointSeries      = Endpoint{Name: "series", Allowed: []string{"match[]", "start", "end", "limit"}, Required: []string{"match[]"}}
+	EndpointLabels      = Endpoint{Name: "labels", Allowed: []string{"match[]", "start", "end", "limit"}}
+	EndpointLabelValues = Endpoint{Name: "label_values", Allowed: []string{"match[]", "start", "end", "limit"}}
+)
+
+// Params extrait de values les paramètres transmis à l'endpoint et vérifie les paramètres obligatoires
+func (e Endpoint) Params(values url.Values) (url.Values, error) {
+	params := url.Values{}
+	for _, name := range e.Allowed {
+		for _, value := range values[name] {
+			if strings.TrimSpace(value) != "" {
+				params.Add(name, value)
+			}
+		}
+	}
+	for _, name := range e.Required {
+		if len(params[name]) == 0 {
+			return nil, fmt.Errorf("%s parameter is required", name)
+		}
+	}
+	return params, nil
+}
+
+// Path retourne le chemin de l'endpoint ; label est le nom du label des label_values
+func (e Endpoint) Path(label string) (string, error) {
+	if e.Name != EndpointLabelValues.Name {
+		return "api/v1/" + e.Name, nil
+	}
+	if !ValidLabelName(label) {
+		return "", fmt.Errorf("invalid label name %q", label)
+	}
+	return "api/v1/label/" + label + "/values", nil
+}
+
+// labelNameRe valide un nom de label Prometheus (y compris __name__)
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidLabelName indique si name est un nom de label Prometheus valide
+func ValidLabelName(name string) bool {
+	return labelNameRe.MatchString(name)
+}
+
+// newTransport crée un pool de connexions vers Prometheus
+func newTransport(skipVerify bool) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: skipVerify},
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// Clients HTTP partagés ; les redirections ne sont pas suivies pour que les requêtes restent sur l'hôte configuré
+var (
+	verifiedHTTPClient = &http.Client{
+		Timeout:       30 * time.Second,
+		Transport:     newTransport(false),
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	insecureHTTPClient = &http.Client{
+		Timeout:       30 * time.Second,
+		Transport:     newTransport(true),
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+)
+
+// Client interroge l'API HTTP d'une source de données Prometheus
+type Client struct {
+	baseURL    string
+	authHeader string
+	httpClient *http.Client
+}
+
+// NewClient crée un client pour une source de données, authentifié selon son auth_type
+func NewClient(ds *models.PrometheusDataSource) *Client {
+	c := &Client{baseURL: strings.TrimSuffix(ds.URL, "/"), httpClient: verifiedHTTPClient}
+	if ds.TLSSkipVerify {
+		c.httpClient = insecureHTTPClient
+	}
+	switch ds.AuthType {
+	case models.PrometheusAuthBasic:
+		c.authHeader = "Basic " + base64.StdEncoding.EncodeToString([]byte(ds.Username+":"+ds.Secret))
+	case models.PrometheusAuthBearer:
+		c.authHeader = "Bearer " + ds.Secret
+	}
+	return c
+}
+
+// WithHTTPClient retourne une copie du client utilisant un autre client HTTP (tests)
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	clone := *c
+	clone.httpClient = httpClient
+	return &clone
+}
+
+// Response est la réponse JSON brute de Prometheus et son code HTTP.
+// Les erreurs PromQL (400, 422) et d'exécution (503) sont des réponses valides à relayer telles quelles.
+type Response struct {
+	StatusCode int
+	Body       json.RawMessage
+}
+
+// ErrInvalidResponse est retourné quand le serveur ne répond pas au format de l'API Prometheus
+var ErrInvalidResponse = errors.New("invalid response from Prometheus")
+
+// Get interroge un chemin de l'API (api/v1/...) avec des paramètres déjà filtrés
+func (c *Client) Get(ctx context.Context, path string, params url.Values) (*Response, error) {
+	endpoint := c.baseURL + "/" + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "ProxmoxDash/1.0")
+	if c.authHeader != "" {
+		req.Header.Set("Authorization", c.authHeader)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxResponseSize {
+		return nil, fmt.Errorf("%w: response larger than %d bytes", ErrInvalidResponse, maxResponseSize)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrInvalidResponse, resp.StatusCode)
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: body}, nil
+}
@@ -0,0 +1,117 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMockPoints limite le nombre de points d'une série simulée (comme la limite de 11 000 points de Prometheus)
+const maxMockPoints = 11000
+
+// Mock simule une réponse de Prometheus pour le développement sans serveur (PROMETHEUS_MOCK=true).
+// Les valeurs dépendent des mots-clés de la requête (cpu, memory, disk) et varient dans le temps.
+func Mock(endpoint Endpoint, params url.Values, now time.Time) (*Response, error) {
+	var data interface{}
+	switch endpoint.Name {
+	case EndpointQuery.Name:
+		at, err := parseTime(params.Get("time"), now)
+		if err != nil {
+			return nil, err
+		}
+		data = map[string]interface{}{
+			"resultType": "vector",
+			"result": []map[string]interface{}{{
+				"metric": map[string]string{},
+				"value":  mockPoint(params.Get("query"), at),
+			}},
+		}
+	case EndpointQueryRange.Name:
+		start, err := parseTime(params.Get("start"), now)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTime(params.Get("end"), now)
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseStep(params.Get("step"))
+		if err != nil {
+			return nil, err
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("end timestamp must not be before start time")
+		}
+		if end.Sub(start)/step > maxMockPoints {
+			return nil, fmt.Errorf("exceeded maximum resolution of %d points per timeseries", maxMockPoints)
+		}
+		values := [][]interface{}{}
+		for t := start; !t.After(end); t = t.Add(step) {
+			values = append(values, mockPoint(params.Get("query"), t))
+		}
+		data = map[string]interface{}{
+			"resultType": "matrix",
+			"result":     []map[string]interface{}{{"metric": map[string]string{}, "values": values}},
+		}
+	case EndpointLabels.Name:
+		data = []string{"__name__", "instance", "job"}
+	default:
+		data = []interface{}{}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"status": "success", "data": data})
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: 200, Body: body}, nil
+}
+
+// mockPoint retourne un point [timestamp, "valeur"] simulé
+func mockPoint(query string, at time.Time) []interface{} {
+	base, spread := 50.0, 30.0 // valeur par défaut
+	switch {
+	case strings.Contains(query, "cpu"):
+		base, spread = 45.5, 20
+	case strings.Contains(query, "memory") || strings.Contains(query, "mem"):
+		base, spread = 68.2, 15
+	case strings.Contains(query, "disk"):
+		base, spread = 72.8, 10
+	}
+	// Variation lente et continue pour des courbes lisibles
+	value := base + spread*(1+math.Sin(float64(at.Unix())/600))/2
+	return []interface{}{float64(at.Unix()), strconv.FormatFloat(value, 'f', 2, 64)}
+}
+
+// parseTime lit un instant au format de l'API Prometheus : timestamp Unix (décimal) ou RFC 3339
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		sec, frac := math.Modf(seconds)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", value)
+	}
+	return t, nil
+}
+
+// parseStep lit un pas en secondes (décimal) ou en durée (30s, 5m)
+func parseStep(value string) (time.Duration, error) {
+	var step time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		step = time.Duration(seconds * float64(time.Second))
+	} else if step, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", value)
+	}
+	if step <= 0 {
+		return 0, fmt.Errorf("zero or negative query resolution step widths are not accepted")
+	}
+	return step, nil
+}
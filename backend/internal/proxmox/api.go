@@ -0,0 +1,339 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ClusterResources récupère cluster/resources, filtré par type si resourceType n'est pas vide (vm, storage, node)
+func (c *Client) ClusterResources(ctx context.Context, resourceType string) ([]Resource, error) {
+	var query url.Values
+	if resourceType != "" {
+		query = url.Values{"type": {resourceType}}
+	}
+	var resources []Resource
+	if err := c.get(ctx, "cluster/resources", query, &resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// ClusterTasks récupère les tâches récentes du cluster
+func (c *Client) ClusterTasks(ctx context.Context) ([]Task, error) {
+	var tasks []Task
+	if err := c.get(ctx, "cluster/tasks", nil, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Nodes récupère la liste des nœuds
+func (c *Client) Nodes(ctx context.Context) ([]Node, error) {
+	var nodes []Node
+	if err := c.get(ctx, "nodes", nil, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// NodeStatus récupère le statut détaillé d'un nœud
+func (c *Client) NodeStatus(ctx context.Context, node string) (*NodeStatus, error) {
+	var status NodeStatus
+	if err := c.get(ctx, fmt.Sprintf("nodes/%s/status", url.PathEscape(node)), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// NodeNetwork récupère les interfaces réseau d'un nœud
+func (c *Client) NodeNetwork(ctx context.Context, node string) ([]NetworkInterface, error) {
+	var ifaces []NetworkInterface
+	if err := c.get(ctx, fmt.Sprintf("nodes/%s/network", url.PathEscape(node)), nil, &ifaces); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// NodeStorages récupère les storages visibles depuis un nœud
+func (c *Client) NodeStorages(ctx context.Context, node string) ([]Storage, error) {
+	var storages []Storage
+	if err := c.get(ctx, fmt.Sprintf("nodes/%s/storage", url.PathEscape(node)), nil, &storages); err != nil {
+		return nil, err
+	}
+	return storages, nil
+}
+
+// StorageBackups récupère les archives de sauvegarde d'un storage vu depuis un nœud
+func (c *Client) StorageBackups(ctx context.Context, node, storage string) ([]Backup, error) {
+	var backups []Backup
+	path := fmt.Sprintf("nodes/%s/storage/%s/content", url.PathEscape(node), url.PathEscape(storage))
+	if err := c.get(ctx, path, url.Values{"content": {BackupContent}}, &backups); err != nil {
+		return nil, err
+	}
+	return backups, nil
+}
+
+// Vzdump lance la sauvegarde d'invités d'un nœud et retourne l'UPID de la tâche
+func (c *Client) Vzdump(ctx context.Context, node string, params url.Values) (string, error) {
+	var upid string
+	if err := c.post(ctx, fmt.Sprintf("nodes/%s/vzdump", url.PathEscape(node)), params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// BackupJobs récupère les tâches de sauvegarde planifiées du cluster
+func (c *Client) BackupJobs(ctx context.Context) ([]BackupJob, error) {
+	var jobs []BackupJob
+	if err := c.get(ctx, "cluster/backup", nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// BackupJob récupère une tâche de sauvegarde planifiée
+func (c *Client) BackupJob(ctx context.Context, id string) (*BackupJob, error) {
+	var job BackupJob
+	if err := c.get(ctx, "cluster/backup/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CreateBackupJob crée une tâche de sauvegarde planifiée
+func (c *Client) CreateBackupJob(ctx context.Context, params url.Values) error {
+	return c.post(ctx, "cluster/backup", params, nil)
+}
+
+// UpdateBackupJob modifie une tâche de sauvegarde planifiée (delete liste les options à retirer)
+func (c *Client) UpdateBackupJob(ctx context.Context, id string, params url.Values) error {
+	return c.put(ctx, "cluster/backup/"+url.PathEscape(id), params, nil)
+}
+
+// DeleteBackupJob supprime une tâche de sauvegarde planifiée
+func (c *Client) DeleteBackupJob(ctx context.Context, id string) error {
+	return c.delete(ctx, "cluster/backup/"+url.PathEscape(id), nil, nil)
+}
+
+// Guests récupère les VMs (qemu) ou conteneurs (lxc) d'un nœud
+func (c *Client) Guests(ctx context.Context, node string, guestType GuestType) ([]Guest, error) {
+	var guests []Guest
+	if err := c.get(ctx, fmt.Sprintf("nodes/%s/%s", url.PathEscape(node), guestType), nil, &guests); err != nil {
+		return nil, err
+	}
+	return guests, nil
+}
+
+// GuestStatus récupère le statut courant d'un invité
+func (c *Client) GuestStatus(ctx context.Context, node string, guestType GuestType, vmid int) (*GuestStatus, error) {
+	var status GuestStatus
+	path := fmt.Sprintf("nodes/%s/%s/%d/status/current", url.PathEscape(node), guestType, vmid)
+	if err := c.get(ctx, path, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GuestConfig récupère la configuration brute d'un invité
+func (c *Client) GuestConfig(ctx context.Context, node string, guestType GuestType, vmid int) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	path := fmt.Sprintf("nodes/%s/%s/%d/config", url.PathEscape(node), guestType, vmid)
+	if err := c.get(ctx, path, nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// UpdateGuestConfig applique des modifications de configuration à un invité (PUT, synchrone).
+// Le paramètre digest, s'il est fourni, fait échouer la mise à jour avec ErrConfigChanged si la
+// configuration a été modifiée depuis sa lecture.
+func (c *Client) UpdateGuestConfig(ctx context.Context, node string, guestType GuestType, vmid int, params url.Values) error {
+	path := fmt.Sprintf("nodes/%s/%s/%d/config", url.PathEscape(node), guestType, vmid)
+	return c.put(ctx, path, params, nil)
+}
+
+// GuestPending récupère la configuration d'un invité avec ses modifications en attente
+func (c *Client) GuestPending(ctx context.Context, node string, guestType GuestType, vmid int) ([]PendingItem, error) {
+	var items []PendingItem
+	path := fmt.Sprintf("nodes/%s/%s/%d/pending", url.PathEscape(node), guestType, vmid)
+	if err := c.get(ctx, path, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// NodeRRDData récupère l'historique RRD d'un nœud (timeframe: hour, day, week, month ou year)
+func (c *Client) NodeRRDData(ctx context.Context, node, timeframe string) ([]RRDPoint, error) {
+	var points []RRDPoint
+	path := fmt.Sprintf("nodes/%s/rrddata", url.PathEscape(node))
+	if err := c.get(ctx, path, url.Values{"timeframe": {timeframe}, "cf": {"AVERAGE"}}, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GuestRRDData récupère l'historique RRD d'un invité (timeframe: hour, day, week, month ou year)
+func (c *Client) GuestRRDData(ctx context.Context, node string, guestType GuestType, vmid int, timeframe string) ([]RRDPoint, error) {
+	var points []RRDPoint
+	path := fmt.Sprintf("nodes/%s/%s/%d/rrddata", url.PathEscape(node), guestType, vmid)
+	if err := c.get(ctx, path, url.Values{"timeframe": {timeframe}, "cf": {"AVERAGE"}}, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// GuestStatusAction exécute une action d'alimentation (start, stop, reboot...) et retourne l'UPID de la tâche
+func (c *Client) GuestStatusAction(ctx context.Context, node string, guestType GuestType, vmid int, action string, params url.Values) (string, error) {
+	var upid string
+	path := fmt.Sprintf("nodes/%s/%s/%d/status/%s", url.PathEscape(node), guestType, vmid, action)
+	if err := c.post(ctx, path, params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// TaskStatus récupère le statut d'une tâche à partir de son UPID
+func (c *Client) TaskStatus(ctx context.Context, node, upid string) (*TaskStatus, error) {
+	var status TaskStatus
+	path := fmt.Sprintf("nodes/%s/tasks/%s/status", url.PathEscape(node), url.PathEscape(upid))
+	if err := c.get(ctx, path, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// WaitTask interroge le statut d'une tâche toutes les interval jusqu'à sa fin ou l'annulation du contexte.
+// En cas d'expiration du contexte, le dernier statut connu (running) est retourné avec l'erreur du contexte.
+func (c *Client) WaitTask(ctx context.Context, node, upid string, interval time.Duration) (*TaskStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *TaskStatus
+	for {
+		status, err := c.TaskStatus(ctx, node, upid)
+		if err != nil {
+			if ctx.Err() != nil && last != nil {
+				return last, ctx.Err()
+			}
+			return nil, err
+		}
+		if !status.Running() {
+			return status, nil
+		}
+		last = status
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// TaskLog récupère les lignes du journal d'une tâche à partir de la ligne start (au plus limit lignes)
+func (c *Client) TaskLog(ctx context.Context, node, upid string, start, limit int) ([]TaskLogLine, error) {
+	var lines []TaskLogLine
+	path := fmt.Sprintf("nodes/%s/tasks/%s/log", url.PathEscape(node), url.PathEscape(upid))
+	query := url.Values{"start": {strconv.Itoa(start)}, "limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, path, query, &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// NextID retourne le prochain VMID libre du cluster
+func (c *Client) NextID(ctx context.Context) (int, error) {
+	var id VMID
+	if err := c.get(ctx, "cluster/nextid", nil, &id); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// CloneGuest clone une VM ou un conteneur (newid, name/hostname, target, full, storage...) et retourne l'UPID de la tâche
+func (c *Client) CloneGuest(ctx context.Context, node string, guestType GuestType, vmid int, params url.Values) (string, error) {
+	var upid string
+	path := fmt.Sprintf("nodes/%s/%s/%d/clone", url.PathEscape(node), guestType, vmid)
+	if err := c.post(ctx, path, params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// GuestSnapshots liste les snapshots d'une VM ou d'un conteneur, y compris l'entrée "current" (état courant)
+func (c *Client) GuestSnapshots(ctx context.Context, node string, guestType GuestType, vmid int) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	path := fmt.Sprintf("nodes/%s/%s/%d/snapshot", url.PathEscape(node), guestType, vmid)
+	if err := c.get(ctx, path, nil, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// CreateSnapshot crée un snapshot (snapname, description, vmstate) et retourne l'UPID de la tâche
+func (c *Client) CreateSnapshot(ctx context.Context, node string, guestType GuestType, vmid int, params url.Values) (string, error) {
+	var upid string
+	path := fmt.Sprintf("nodes/%s/%s/%d/snapshot", url.PathEscape(node), guestType, vmid)
+	if err := c.post(ctx, path, params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// RollbackSnapshot restaure un snapshot et retourne l'UPID de la tâche
+func (c *Client) RollbackSnapshot(ctx context.Context, node string, guestType GuestType, vmid int, name string, params url.Values) (string, error) {
+	var upid string
+	path := fmt.Sprintf("nodes/%s/%s/%d/snapshot/%s/rollback", url.PathEscape(node), guestType, vmid, url.PathEscape(name))
+	if err := c.post(ctx, path, params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// DeleteSnapshot supprime un snapshot et retourne l'UPID de la tâche
+func (c *Client) DeleteSnapshot(ctx context.Context, node string, guestType GuestType, vmid int, name string, params url.Values) (string, error) {
+	var upid string
+	path := fmt.Sprintf("nodes/%s/%s/%d/snapshot/%s", url.PathEscape(node), guestType, vmid, url.PathEscape(name))
+	if err := c.delete(ctx, path, params, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// QemuAgentInterfaces récupère les interfaces réseau d'une VM via le guest agent QEMU
+func (c *Client) QemuAgentInterfaces(ctx context.Context, node string, vmid int) ([]AgentInterface, error) {
+	var result struct {
+		Result []AgentInterface `json:"result"`
+	}
+	path := fmt.Sprintf("nodes/%s/qemu/%d/agent/network-get-interfaces", url.PathEscape(node), vmid)
+	if err := c.get(ctx, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Result, nil
+}
+
+// ContainerInterfaces récupère les interfaces réseau d'un conteneur en cours d'exécution
+func (c *Client) ContainerInterfaces(ctx context.Context, node string, vmid int) ([]ContainerInterface, error) {
+	var ifaces []ContainerInterface
+	path := fmt.Sprintf("nodes/%s/lxc/%d/interfaces", url.PathEscape(node), vmid)
+	if err := c.get(ctx, path, nil, &ifaces); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// Ticket obtient un ticket de session (PVEAuthCookie) avec un couple utilisateur/mot de passe
+func (c *Client) Ticket(ctx context.Context, username, password string) (*Ticket, error) {
+	var ticket Ticket
+	params := url.Values{"username": {username}, "password": {password}}
+	// access/ticket ne doit pas recevoir l'en-tête du token API
+	anonymous := *c
+	anonymous.authHeader = ""
+	if err := anonymous.post(ctx, "access/ticket", params, &ticket); err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
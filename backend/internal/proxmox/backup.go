@@ -0,0 +1,380 @@
+package proxmox
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupContent est le type de contenu des archives de sauvegarde dans un storage
+const BackupContent = "backup"
+
+// Limites des options de sauvegarde acceptées par le dashboard
+const (
+	MaxBackupNotesLen    = 1024
+	MaxBackupScheduleLen = 128
+)
+
+// backupModes et backupCompressions listent les valeurs acceptées par vzdump
+var (
+	backupModes        = map[string]bool{"snapshot": true, "suspend": true, "stop": true}
+	backupCompressions = map[string]bool{"0": true, "1": true, "gzip": true, "lzo": true, "zstd": true}
+)
+
+var (
+	storageIDPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.\-]*$`)
+	backupJobPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_\-]{0,63}$`)
+	// événement calendrier systemd simplifié de Proxmox : "daily", "sat 02:00", "*/6:00", "mon..fri 22:30"
+	schedulePattern = regexp.MustCompile(`^[a-zA-Z0-9 :*/,.\-~]+$`)
+	mailToPattern   = regexp.MustCompile(`^[^\s,;@]+@[^\s,;@]+$`)
+	// vzdump-qemu-100-2024_01_31-02_00_03.vma.zst (storages fichiers) ; openvz est l'ancien nom des conteneurs
+	vzdumpVolIDPattern = regexp.MustCompile(`vzdump-(qemu|lxc|openvz)-(\d+)-(\d{4}_\d{2}_\d{2}-\d{2}_\d{2}_\d{2})`)
+	// backup/vm/100/2024-01-31T02:00:03Z (Proxmox Backup Server)
+	pbsVolIDPattern = regexp.MustCompile(`backup/(vm|ct)/(\d+)/(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z)`)
+)
+
+// BackupVolume décrit une archive de sauvegarde identifiée par son volid
+type BackupVolume struct {
+	GuestType GuestType
+	VMID      int
+	Time      time.Time // date de l'archive lue dans son nom (zéro si absente)
+}
+
+// ParseBackupVolID extrait le type d'invité, le VMID et la date d'une archive vzdump ou PBS.
+// Retourne false si le volid n'est pas celui d'une sauvegarde d'invité.
+func ParseBackupVolID(volid string) (BackupVolume, bool) {
+	if m := vzdumpVolIDPattern.FindStringSubmatch(volid); m != nil {
+		vmid, _ := strconv.Atoi(m[2])
+		volume := BackupVolume{GuestType: GuestQemu, VMID: vmid}
+		if m[1] != "qemu" {
+			volume.GuestType = GuestLXC
+		}
+		// L'horodatage du nom est celui du nœud : l'heure locale du dashboard est la meilleure approximation
+		volume.Time, _ = time.ParseInLocation("2006_01_02-15_04_05", m[3], time.Local)
+		return volume, true
+	}
+	if m := pbsVolIDPattern.FindStringSubmatch(volid); m != nil {
+		vmid, _ := strconv.Atoi(m[2])
+		volume := BackupVolume{GuestType: GuestQemu, VMID: vmid}
+		if m[1] == "ct" {
+			volume.GuestType = GuestLXC
+		}
+		volume.Time, _ = time.Parse(time.RFC3339, m[3])
+		return volume, true
+	}
+	return BackupVolume{}, false
+}
+
+// Volume retourne le type d'invité et le VMID d'une archive : subtype et vmid de Proxmox
+// s'ils sont présents, à défaut ceux lus dans le volid
+func (b Backup) Volume() (BackupVolume, bool) {
+	volume, ok := ParseBackupVolID(b.VolID)
+	switch b.Subtype {
+	case "qemu":
+		volume.GuestType, ok = GuestQemu, true
+	case "lxc", "openvz":
+		volume.GuestType, ok = GuestLXC, true
+	}
+	if b.VMID != 0 {
+		volume.VMID = int(b.VMID)
+	}
+	if b.CTime > 0 {
+		volume.Time = time.Unix(b.CTime, 0)
+	}
+	return volume, ok && volume.VMID != 0
+}
+
+// BackupJob est une tâche de sauvegarde planifiée (cluster/backup)
+type BackupJob struct {
+	ID            string   `json:"id"`
+	Type          string   `json:"type,omitempty"` // vzdump
+	Enabled       *IntBool `json:"enabled,omitempty"`
+	Schedule      string   `json:"schedule,omitempty"`
+	Storage       string   `json:"storage,omitempty"`
+	Node          string   `json:"node,omitempty"`
+	Mode          string   `json:"mode,omitempty"`
+	Compress      string   `json:"compress,omitempty"`
+	VMID          string   `json:"vmid,omitempty"` // liste séparée par des virgules
+	All           IntBool  `json:"all,omitempty"`
+	Exclude       string   `json:"exclude,omitempty"`
+	Pool          string   `json:"pool,omitempty"`
+	MailTo        string   `json:"mailto,omitempty"`
+	MailNotify    string   `json:"mailnotification,omitempty"`
+	NotesTemplate string   `json:"notes-template,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+	PruneBackups  string   `json:"prune-backups,omitempty"`
+	NextRun       int64    `json:"next-run,omitempty"`
+}
+
+// IsEnabled indique si la tâche est active (Proxmox omet enabled pour une tâche active)
+func (j BackupJob) IsEnabled() bool {
+	return j.Enabled == nil || bool(*j.Enabled)
+}
+
+// ParseVMIDList découpe une liste de VMID Proxmox ("100,101")
+func ParseVMIDList(list string) ([]int, error) {
+	var vmids []int
+	for _, part := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		vmid, err := strconv.Atoi(part)
+		if err != nil || vmid <= 0 {
+			return nil, fmt.Errorf("invalid vmid %q", part)
+		}
+		vmids = append(vmids, vmid)
+	}
+	return vmids, nil
+}
+
+// VzdumpOptions sont les options d'une sauvegarde lancée à la demande
+type VzdumpOptions struct {
+	Mode          string `json:"mode,omitempty"`     // snapshot (défaut), suspend ou stop
+	Compress      string `json:"compress,omitempty"` // 0, gzip, lzo ou zstd (défaut : celui du storage)
+	Storage       string `json:"storage,omitempty"`  // défaut : storage de sauvegarde du nœud
+	NotesTemplate string `json:"notes_template,omitempty"`
+	Protected     bool   `json:"protected,omitempty"` // archive protégée contre l'élagage et la suppression
+}
+
+// Params valide les options et construit les paramètres de POST nodes/{node}/vzdump pour les invités donnés
+func (o *VzdumpOptions) Params(vmids []int) (url.Values, error) {
+	list, err := vmidList(vmids)
+	if err != nil {
+		return nil, err
+	}
+	if list == "" {
+		return nil, fmt.Errorf("at least one vmid is required")
+	}
+
+	params := url.Values{"vmid": {list}}
+	if o.Mode != "" {
+		if !backupModes[o.Mode] {
+			return nil, fmt.Errorf("mode must be snapshot, suspend or stop")
+		}
+		params.Set("mode", o.Mode)
+	}
+	if o.Compress != "" {
+		if !backupCompressions[o.Compress] {
+			return nil, fmt.Errorf("compress must be 0, gzip, lzo or zstd")
+		}
+		params.Set("compress", o.Compress)
+	}
+	if o.Storage != "" {
+		if !storageIDPattern.MatchString(o.Storage) {
+			return nil, fmt.Errorf("invalid storage %q", o.Storage)
+		}
+		params.Set("storage", o.Storage)
+	}
+	if o.NotesTemplate != "" {
+		if err := validateNotesTemplate(o.NotesTemplate); err != nil {
+			return nil, err
+		}
+		params.Set("notes-template", o.NotesTemplate)
+	}
+	if o.Protected {
+		params.Set("protected", "1")
+	}
+	return params, nil
+}
+
+// validateNotesTemplate vérifie un modèle de notes ({{guestname}}, {{vmid}}...) : une seule ligne
+func validateNotesTemplate(notes string) error {
+	if len(notes) > MaxBackupNotesLen {
+		return fmt.Errorf("notes_template must not exceed %d bytes", MaxBackupNotesLen)
+	}
+	if strings.ContainsAny(notes, "\r\n") {
+		return fmt.Errorf("notes_template must be a single line")
+	}
+	return nil
+}
+
+// BackupJobUpdate crée ou modifie une tâche de sauvegarde planifiée : en modification, seuls les champs
+// renseignés sont changés et une chaîne vide supprime l'option. La sélection des invités (vmids, all ou
+// pool) est exclusive : en choisir une retire les autres.
+type BackupJobUpdate struct {
+	ID            string    `json:"id,omitempty"` // création uniquement, généré par Proxmox si absent
+	Enabled       *bool     `json:"enabled,omitempty"`
+	Schedule      *string   `json:"schedule,omitempty"`
+	Storage       *string   `json:"storage,omitempty"`
+	Node          *string   `json:"node,omitempty"` // limite la tâche aux invités d'un nœud
+	Mode          *string   `json:"mode,omitempty"`
+	Compress      *string   `json:"compress,omitempty"`
+	VMIDs         *[]int    `json:"vmids,omitempty"`
+	All           *bool     `json:"all,omitempty"`
+	Pool          *string   `json:"pool,omitempty"`
+	Exclude       *[]int    `json:"exclude,omitempty"` // avec all uniquement
+	MailTo        *[]string `json:"mailto,omitempty"`
+	NotesTemplate *string   `json:"notes_template,omitempty"`
+	Comment       *string   `json:"comment,omitempty"`
+}
+
+// Params valide la tâche et construit les paramètres de POST cluster/backup (create) ou
+// PUT cluster/backup/{id}
+func (u *BackupJobUpdate) Params(create bool) (url.Values, error) {
+	params := url.Values{}
+	var deletes []string
+	setOrDelete := func(key string, value *string, valid func(string) error) error {
+		if value == nil {
+			return nil
+		}
+		if *value == "" {
+			deletes = append(deletes, key)
+			return nil
+		}
+		if valid != nil {
+			if err := valid(*value); err != nil {
+				return err
+			}
+		}
+		params.Set(key, *value)
+		return nil
+	}
+
+	if create {
+		if u.ID != "" {
+			if !backupJobPattern.MatchString(u.ID) {
+				return nil, fmt.Errorf("invalid id %q", u.ID)
+			}
+			params.Set("id", u.ID)
+		}
+		if u.Schedule == nil || *u.Schedule == "" {
+			return nil, fmt.Errorf("schedule is required")
+		}
+		if u.VMIDs == nil && (u.All == nil || !*u.All) && (u.Pool == nil || *u.Pool == "") {
+			return nil, fmt.Errorf("one of vmids, all or pool is required")
+		}
+	} else if u.ID != "" {
+		return nil, fmt.Errorf("id cannot be changed")
+	}
+
+	selections := 0
+	for _, set := range []bool{u.VMIDs != nil, u.All != nil && *u.All, u.Pool != nil && *u.Pool != ""} {
+		if set {
+			selections++
+		}
+	}
+	if selections > 1 {
+		return nil, fmt.Errorf("vmids, all and pool are mutually exclusive")
+	}
+
+	if u.Enabled != nil {
+		params.Set("enabled", boolParam(*u.Enabled))
+	}
+	if u.Schedule != nil {
+		if *u.Schedule == "" {
+			return nil, fmt.Errorf("schedule cannot be removed")
+		}
+		if len(*u.Schedule) > MaxBackupScheduleLen || !schedulePattern.MatchString(*u.Schedule) {
+			return nil, fmt.Errorf("invalid schedule %q", *u.Schedule)
+		}
+		params.Set("schedule", *u.Schedule)
+	}
+	validID := func(name string) func(string) error {
+		return func(value string) error {
+			if !storageIDPattern.MatchString(value) {
+				return fmt.Errorf("invalid %s %q", name, value)
+			}
+			return nil
+		}
+	}
+	if err := setOrDelete("storage", u.Storage, validID("storage")); err != nil {
+		return nil, err
+	}
+	if err := setOrDelete("node", u.Node, validID("node")); err != nil {
+		return nil, err
+	}
+	if err := setOrDelete("mode", u.Mode, func(mode string) error {
+		if !backupModes[mode] {
+			return fmt.Errorf("mode must be snapshot, suspend or stop")
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := setOrDelete("compress", u.Compress, func(compress string) error {
+		if !backupCompressions[compress] {
+			return fmt.Errorf("compress must be 0, gzip, lzo or zstd")
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := setOrDelete("notes-template", u.NotesTemplate, validateNotesTemplate); err != nil {
+		return nil, err
+	}
+	if err := setOrDelete("comment", u.Comment, func(comment string) error {
+		if strings.ContainsAny(comment, "\r\n") || len(comment) > MaxBackupNotesLen {
+			return fmt.Errorf("comment must be a single line of at most %d bytes", MaxBackupNotesLen)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if u.MailTo != nil {
+		for _, address := range *u.MailTo {
+			if !mailToPattern.MatchString(address) {
+				return nil, fmt.Errorf("invalid mailto address %q", address)
+			}
+		}
+		if len(*u.MailTo) == 0 {
+			deletes = append(deletes, "mailto")
+		} else {
+			params.Set("mailto", strings.Join(*u.MailTo, ","))
+		}
+	}
+
+	// Sélection des invités
+	switch {
+	case u.VMIDs != nil:
+		list, err := vmidList(*u.VMIDs)
+		if err != nil || list == "" {
+			return nil, fmt.Errorf("vmids must list at least one valid vmid")
+		}
+		params.Set("vmid", list)
+		deletes = append(deletes, "all", "pool", "exclude")
+	case u.All != nil && *u.All:
+		params.Set("all", "1")
+		deletes = append(deletes, "vmid", "pool")
+	case u.Pool != nil && *u.Pool != "":
+		if !storageIDPattern.MatchString(*u.Pool) {
+			return nil, fmt.Errorf("invalid pool %q", *u.Pool)
+		}
+		params.Set("pool", *u.Pool)
+		deletes = append(deletes, "vmid", "all", "exclude")
+	}
+	if u.Exclude != nil {
+		if u.VMIDs != nil || (u.Pool != nil && *u.Pool != "") {
+			return nil, fmt.Errorf("exclude only applies to all")
+		}
+		list, err := vmidList(*u.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if list == "" {
+			deletes = append(deletes, "exclude")
+		} else {
+			params.Set("exclude", list)
+		}
+	}
+
+	if len(params) == 0 && len(deletes) == 0 {
+		return nil, fmt.Errorf("no changes")
+	}
+	// Une création n'a rien à supprimer
+	if len(deletes) > 0 && !create {
+		params.Set("delete", strings.Join(deletes, ","))
+	}
+	return params, nil
+}
+
+// vmidList formate une liste de VMID pour Proxmox ("100,101")
+func vmidList(vmids []int) (string, error) {
+	ids := make([]string, len(vmids))
+	for i, vmid := range vmids {
+		if vmid <= 0 {
+			return "", fmt.Errorf("invalid vmid %d", vmid)
+		}
+		ids[i] = strconv.Itoa(vmid)
+	}
+	return strings.Join(ids, ","), nil
+}
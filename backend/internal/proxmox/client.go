@@ -0,0 +1,170 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sharedTransport est le pool de connexions partagé par tous les clients Proxmox.
+// La vérification TLS est désactivée car la plupart des installations PVE utilisent un certificat auto-signé.
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+	TLSHandshakeTimeout: 10 * time.Second,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// sharedHTTPClient est le client HTTP utilisé par défaut
+var sharedHTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: sharedTransport,
+}
+
+// Client est un client pour l'API REST de Proxmox VE (api2/json)
+type Client struct {
+	baseURL    string
+	authHeader string
+	httpClient *http.Client
+}
+
+// NewClient crée un client authentifié par token API
+func NewClient(baseURL, username, secret string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		authHeader: APITokenHeader(username, secret),
+		httpClient: sharedHTTPClient,
+	}
+}
+
+// WithHTTPClient retourne une copie du client utilisant un autre client HTTP (tests, timeouts spécifiques)
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	clone := *c
+	clone.httpClient = httpClient
+	return &clone
+}
+
+// BaseURL retourne l'URL de base du serveur Proxmox
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// APITokenHeader construit l'en-tête Authorization d'un token API Proxmox.
+// Le format attendu est PVEAPIToken=user@realm!tokenid=secret ; le secret peut
+// aussi contenir directement l'identifiant complet du token.
+func APITokenHeader(username, secret string) string {
+	if !strings.Contains(username, "!") && strings.Contains(secret, "!") {
+		return fmt.Sprintf("PVEAPIToken=%s", secret)
+	}
+	return fmt.Sprintf("PVEAPIToken=%s=%s", username, secret)
+}
+
+// envelope est l'enveloppe standard des réponses Proxmox
+type envelope struct {
+	Data    json.RawMessage   `json:"data"`
+	Message string            `json:"message"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// get exécute une requête GET et décode le champ data dans out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, out)
+}
+
+// post exécute une requête POST avec des paramètres de formulaire
+func (c *Client) post(ctx context.Context, path string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodPost, path, params, out)
+}
+
+// put exécute une requête PUT avec des paramètres de formulaire
+func (c *Client) put(ctx context.Context, path string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodPut, path, params, out)
+}
+
+// delete exécute une requête DELETE (paramètres en query string)
+func (c *Client) delete(ctx context.Context, path string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodDelete, path, params, out)
+}
+
+// do exécute une requête vers l'API et convertit les erreurs en *APIError
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, out interface{}) error {
+	endpoint := c.baseURL + "/api2/json/" + strings.TrimPrefix(path, "/")
+
+	var body io.Reader
+	if len(params) > 0 {
+		if method == http.MethodGet || method == http.MethodDelete {
+			endpoint += "?" + params.Encode()
+		} else {
+			body = strings.NewReader(params.Encode())
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return &APIError{Method: method, Path: path, Message: err.Error(), cause: err}
+	}
+	if c.authHeader != "" {
+		req.Header.Set("Authorization", c.authHeader)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &APIError{Method: method, Path: path, Message: err.Error(), kind: ErrUnreachable, cause: err}
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: err.Error(), kind: ErrUnreachable, cause: err}
+	}
+
+	var env envelope
+	decodeErr := json.Unmarshal(raw, &env)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Proxmox place le message d'erreur dans la ligne de statut HTTP
+		message := strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprintf("%d", resp.StatusCode)))
+		if decodeErr == nil && env.Message != "" {
+			message = strings.TrimSpace(env.Message)
+		}
+		return &APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Message:    message,
+			Errors:     env.Errors,
+			kind:       kindForResponse(resp.StatusCode, message),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if decodeErr != nil {
+		return &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: "invalid JSON response: " + decodeErr.Error(), cause: decodeErr}
+	}
+	if len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return &APIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: "failed to decode data: " + err.Error(), cause: err}
+	}
+	return nil
+}
@@ -0,0 +1,497 @@
+package proxmox
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Valeurs par défaut de Proxmox pour les options absentes de la configuration
+const (
+	defaultGuestMemory = 512 // MiB, VMs et conteneurs
+	defaultLXCSwap     = 512 // MiB
+)
+
+// Limites des modifications de configuration acceptées par le dashboard
+const (
+	MaxGuestCores          = 1024
+	MaxGuestSockets        = 16
+	MinGuestMemory         = 16 // MiB
+	MaxGuestDescriptionLen = 8192
+)
+
+// qemuNetModels liste les modèles de carte réseau QEMU ; dans netN, le modèle est la clé de l'adresse MAC (virtio=BC:24:...)
+var qemuNetModels = map[string]bool{
+	"virtio": true, "e1000": true, "e1000e": true, "rtl8139": true, "vmxnet3": true,
+	"i82551": true, "i82557b": true, "i82559er": true, "ne2k_isa": true, "ne2k_pci": true, "pcnet": true,
+}
+
+var (
+	netKeyPattern     = regexp.MustCompile(`^net\d+$`)
+	qemuDiskPattern   = regexp.MustCompile(`^((ide|sata|scsi|virtio)\d+|efidisk0|tpmstate0)$`)
+	lxcDiskPattern    = regexp.MustCompile(`^(rootfs|mp\d+)$`)
+	tagPattern        = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_\-+.]*$`)
+	bridgePattern     = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+	keyNumberSuffixRe = regexp.MustCompile(`^(\D*)(\d*)$`)
+)
+
+// GuestNet est une interface réseau de la configuration d'un invité (netN)
+type GuestNet struct {
+	ID       string `json:"id"`              // net0, net1...
+	Model    string `json:"model,omitempty"` // qemu : virtio, e1000...
+	Name     string `json:"name,omitempty"`  // lxc : nom de l'interface dans le conteneur (eth0)
+	MAC      string `json:"mac,omitempty"`
+	Bridge   string `json:"bridge,omitempty"`
+	Tag      *int   `json:"tag,omitempty"` // VLAN
+	Firewall bool   `json:"firewall"`
+	IP       string `json:"ip,omitempty"` // lxc : ip=dhcp ou CIDR
+	raw      string
+}
+
+// GuestDisk est un disque ou un point de montage de la configuration d'un invité (lecture seule)
+type GuestDisk struct {
+	ID         string `json:"id"` // scsi0, virtio1, ide2, rootfs, mp0...
+	Volume     string `json:"volume"`
+	Storage    string `json:"storage,omitempty"`
+	Size       string `json:"size,omitempty"`
+	Media      string `json:"media,omitempty"`      // qemu : cdrom
+	Mountpoint string `json:"mountpoint,omitempty"` // lxc : chemin de montage des mpN
+}
+
+// PendingItem est une entrée de nodes/{node}/{type}/{vmid}/pending : valeur courante, valeur en attente
+// (pending) et suppression en attente (delete = 1, ou 2 pour une suppression forcée)
+type PendingItem struct {
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value,omitempty"`
+	Pending interface{} `json:"pending,omitempty"`
+	Delete  int         `json:"delete,omitempty"`
+}
+
+// IsPending indique si l'entrée a une modification qui ne sera appliquée qu'au prochain redémarrage
+func (p PendingItem) IsPending() bool {
+	return p.Pending != nil || p.Delete > 0
+}
+
+// GuestConfig est la configuration typée d'une VM ou d'un conteneur
+type GuestConfig struct {
+	Type        GuestType   `json:"type"`
+	Name        string      `json:"name"` // name (qemu) ou hostname (lxc)
+	OSType      string      `json:"ostype,omitempty"`
+	Cores       int         `json:"cores"`             // lxc : 0 = tous les cœurs de l'hôte
+	Sockets     int         `json:"sockets,omitempty"` // qemu uniquement
+	Memory      int         `json:"memory"`            // MiB
+	Balloon     *int        `json:"balloon,omitempty"` // qemu : mémoire minimale (MiB), 0 = ballooning désactivé
+	Swap        *int        `json:"swap,omitempty"`    // lxc : MiB
+	OnBoot      bool        `json:"onboot"`
+	Description string      `json:"description"`
+	Tags        []string    `json:"tags"`
+	Nets        []GuestNet  `json:"nets"`
+	Disks       []GuestDisk `json:"disks"`
+	Digest      string      `json:"digest"` // à renvoyer avec les modifications (verrouillage optimiste)
+
+	// Modifications en attente (appliquées au prochain redémarrage), renseignées par SetPending
+	Pending        []PendingItem `json:"pending"`
+	RebootRequired bool          `json:"reboot_required"`
+}
+
+// ParseGuestConfig convertit la configuration brute retournée par nodes/{node}/{type}/{vmid}/config
+func ParseGuestConfig(guestType GuestType, raw map[string]interface{}) *GuestConfig {
+	cfg := &GuestConfig{
+		Type:        guestType,
+		OSType:      configString(raw["ostype"]),
+		Memory:      defaultGuestMemory,
+		OnBoot:      configString(raw["onboot"]) == "1",
+		Description: configString(raw["description"]),
+		Tags:        splitConfigTags(configString(raw["tags"])),
+		Nets:        []GuestNet{},
+		Disks:       []GuestDisk{},
+		Digest:      configString(raw["digest"]),
+		Pending:     []PendingItem{},
+	}
+	if memory, ok := configMemory(raw["memory"]); ok {
+		cfg.Memory = memory
+	}
+	cfg.Cores, _ = configInt(raw["cores"])
+
+	if guestType == GuestLXC {
+		cfg.Name = configString(raw["hostname"])
+		swap := defaultLXCSwap
+		if value, ok := configInt(raw["swap"]); ok {
+			swap = value
+		}
+		cfg.Swap = &swap
+	} else {
+		cfg.Name = configString(raw["name"])
+		if cfg.Cores == 0 {
+			cfg.Cores = 1
+		}
+		cfg.Sockets = 1
+		if sockets, ok := configInt(raw["sockets"]); ok {
+			cfg.Sockets = sockets
+		}
+		if balloon, ok := configInt(raw["balloon"]); ok {
+			cfg.Balloon = &balloon
+		}
+	}
+
+	for _, key := range sortedConfigKeys(raw) {
+		value := configString(raw[key])
+		switch {
+		case netKeyPattern.MatchString(key):
+			cfg.Nets = append(cfg.Nets, parseGuestNet(guestType, key, value))
+		case guestType == GuestLXC && lxcDiskPattern.MatchString(key),
+			guestType != GuestLXC && qemuDiskPattern.MatchString(key):
+			cfg.Disks = append(cfg.Disks, parseGuestDisk(key, value))
+		}
+	}
+	return cfg
+}
+
+// SetPending renseigne les modifications en attente à partir de nodes/{node}/{type}/{vmid}/pending
+func (c *GuestConfig) SetPending(items []PendingItem) {
+	c.Pending = []PendingItem{}
+	for _, item := range items {
+		if item.Key != "digest" && item.IsPending() {
+			c.Pending = append(c.Pending, item)
+		}
+	}
+	c.RebootRequired = len(c.Pending) > 0
+}
+
+// net retourne l'interface réseau id de la configuration
+func (c *GuestConfig) net(id string) (GuestNet, bool) {
+	for _, n := range c.Nets {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	return GuestNet{}, false
+}
+
+// NetUpdate décrit la modification d'une interface réseau existante
+type NetUpdate struct {
+	Model  *string `json:"model,omitempty"` // qemu uniquement
+	Bridge *string `json:"bridge,omitempty"`
+}
+
+// GuestConfigUpdate est une modification partielle de la configuration d'un invité : seuls les champs
+// renseignés sont modifiés. Une description ou une liste de tags vide supprime l'option.
+type GuestConfigUpdate struct {
+	Cores       *int                 `json:"cores,omitempty"`
+	Sockets     *int                 `json:"sockets,omitempty"` // qemu
+	Memory      *int                 `json:"memory,omitempty"`  // MiB
+	Balloon     *int                 `json:"balloon,omitempty"` // qemu, MiB
+	Swap        *int                 `json:"swap,omitempty"`    // lxc, MiB
+	OnBoot      *bool                `json:"onboot,omitempty"`
+	Description *string              `json:"description,omitempty"`
+	Tags        *[]string            `json:"tags,omitempty"`
+	Nets        map[string]NetUpdate `json:"nets,omitempty"` // clé : net0, net1...
+}
+
+// Params valide la modification par rapport à la configuration courante et construit les paramètres
+// de PUT nodes/{node}/{type}/{vmid}/config (sans le digest)
+func (u *GuestConfigUpdate) Params(current *GuestConfig) (url.Values, error) {
+	params := url.Values{}
+	var deletes []string
+	qemu := current.Type != GuestLXC
+
+	if u.Sockets != nil && !qemu {
+		return nil, fmt.Errorf("sockets is not supported for containers")
+	}
+	if u.Balloon != nil && !qemu {
+		return nil, fmt.Errorf("balloon is not supported for containers")
+	}
+	if u.Swap != nil && qemu {
+		return nil, fmt.Errorf("swap is only supported for containers")
+	}
+
+	if u.Cores != nil {
+		if *u.Cores < 1 || *u.Cores > MaxGuestCores {
+			return nil, fmt.Errorf("cores must be between 1 and %d", MaxGuestCores)
+		}
+		params.Set("cores", strconv.Itoa(*u.Cores))
+	}
+	if u.Sockets != nil {
+		if *u.Sockets < 1 || *u.Sockets > MaxGuestSockets {
+			return nil, fmt.Errorf("sockets must be between 1 and %d", MaxGuestSockets)
+		}
+		params.Set("sockets", strconv.Itoa(*u.Sockets))
+	}
+	memory := current.Memory
+	if u.Memory != nil {
+		if *u.Memory < MinGuestMemory {
+			return nil, fmt.Errorf("memory must be at least %d MiB", MinGuestMemory)
+		}
+		memory = *u.Memory
+		params.Set("memory", strconv.Itoa(memory))
+	}
+	balloon := current.Balloon
+	if u.Balloon != nil {
+		balloon = u.Balloon
+		if *u.Balloon < 0 {
+			return nil, fmt.Errorf("balloon must be positive (0 disables ballooning)")
+		}
+		params.Set("balloon", strconv.Itoa(*u.Balloon))
+	}
+	if balloon != nil && *balloon > memory && (u.Balloon != nil || u.Memory != nil) {
+		return nil, fmt.Errorf("balloon (%d MiB) must not exceed memory (%d MiB)", *balloon, memory)
+	}
+	if u.Swap != nil {
+		if *u.Swap < 0 {
+			return nil, fmt.Errorf("swap must be positive")
+		}
+		params.Set("swap", strconv.Itoa(*u.Swap))
+	}
+	if u.OnBoot != nil {
+		params.Set("onboot", boolParam(*u.OnBoot))
+	}
+	if u.Description != nil {
+		switch {
+		case len(*u.Description) > MaxGuestDescriptionLen:
+			return nil, fmt.Errorf("description must not exceed %d bytes", MaxGuestDescriptionLen)
+		case *u.Description == "":
+			deletes = append(deletes, "description")
+		default:
+			params.Set("description", *u.Description)
+		}
+	}
+	if u.Tags != nil {
+		var tags []string
+		seen := map[string]bool{}
+		for _, tag := range *u.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || seen[tag] {
+				continue
+			}
+			if !tagPattern.MatchString(tag) {
+				return nil, fmt.Errorf("invalid tag %q", tag)
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		if len(tags) == 0 {
+			deletes = append(deletes, "tags")
+		} else {
+			params.Set("tags", strings.Join(tags, ";"))
+		}
+	}
+
+	for _, id := range sortedKeys(u.Nets) {
+		change := u.Nets[id]
+		n, ok := current.net(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown network interface %s", id)
+		}
+		if change.Model != nil && !qemu {
+			return nil, fmt.Errorf("%s: model is not supported for containers", id)
+		}
+		if change.Model != nil && !qemuNetModels[*change.Model] {
+			return nil, fmt.Errorf("%s: unsupported model %q", id, *change.Model)
+		}
+		if change.Bridge != nil && !bridgePattern.MatchString(*change.Bridge) {
+			return nil, fmt.Errorf("%s: invalid bridge %q", id, *change.Bridge)
+		}
+		if change.Model == nil && change.Bridge == nil {
+			continue
+		}
+		params.Set(id, n.withChanges(change))
+	}
+
+	if len(deletes) > 0 {
+		params.Set("delete", strings.Join(deletes, ","))
+	}
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no configuration change")
+	}
+	return params, nil
+}
+
+// withChanges retourne la valeur netN modifiée, en conservant les autres propriétés (MAC, VLAN, pare-feu...)
+func (n GuestNet) withChanges(change NetUpdate) string {
+	props := parseProperties(n.raw)
+	bridgeSet := false
+	for i, prop := range props {
+		switch {
+		case change.Model != nil && qemuNetModels[prop.key]:
+			props[i].key = *change.Model
+		case change.Model != nil && prop.key == "model":
+			props[i].value = *change.Model
+		case change.Bridge != nil && prop.key == "bridge":
+			props[i].value = *change.Bridge
+			bridgeSet = true
+		}
+	}
+	if change.Bridge != nil && !bridgeSet {
+		props = append(props, property{key: "bridge", value: *change.Bridge})
+	}
+	return formatProperties(props)
+}
+
+// parseGuestNet décode une valeur netN
+func parseGuestNet(guestType GuestType, id, value string) GuestNet {
+	n := GuestNet{ID: id, raw: value}
+	for _, prop := range parseProperties(value) {
+		switch {
+		case guestType != GuestLXC && qemuNetModels[prop.key]:
+			n.Model, n.MAC = prop.key, prop.value
+		case prop.key == "model":
+			n.Model = prop.value
+		case prop.key == "macaddr", prop.key == "hwaddr":
+			n.MAC = prop.value
+		case prop.key == "name":
+			n.Name = prop.value
+		case prop.key == "bridge":
+			n.Bridge = prop.value
+		case prop.key == "ip":
+			n.IP = prop.value
+		case prop.key == "firewall":
+			n.Firewall = prop.value == "1"
+		case prop.key == "tag":
+			if tag, err := strconv.Atoi(prop.value); err == nil {
+				n.Tag = &tag
+			}
+		}
+	}
+	return n
+}
+
+// parseGuestDisk décode une valeur de disque (scsi0, virtio0...) ou de point de montage (rootfs, mpN)
+func parseGuestDisk(id, value string) GuestDisk {
+	d := GuestDisk{ID: id}
+	for _, prop := range parseProperties(value) {
+		switch prop.key {
+		case "", "file", "volume":
+			d.Volume = prop.value
+		case "size":
+			d.Size = prop.value
+		case "media":
+			d.Media = prop.value
+		case "mp":
+			d.Mountpoint = prop.value
+		}
+	}
+	if storage, _, ok := strings.Cut(d.Volume, ":"); ok {
+		d.Storage = storage
+	}
+	return d
+}
+
+// property est une propriété d'une chaîne de propriétés Proxmox (clé vide pour la valeur positionnelle)
+type property struct {
+	key, value string
+}
+
+// parseProperties découpe une chaîne de propriétés Proxmox (virtio=BC:24:11:00:00:01,bridge=vmbr0,firewall=1)
+func parseProperties(s string) []property {
+	var props []property
+	for _, part := range strings.Split(s, ",") {
+		if part == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(part, "="); ok {
+			props = append(props, property{key: key, value: value})
+		} else {
+			props = append(props, property{value: part})
+		}
+	}
+	return props
+}
+
+// formatProperties reconstruit une chaîne de propriétés Proxmox
+func formatProperties(props []property) string {
+	parts := make([]string, 0, len(props))
+	for _, prop := range props {
+		if prop.key == "" {
+			parts = append(parts, prop.value)
+		} else {
+			parts = append(parts, prop.key+"="+prop.value)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// configString convertit une valeur de configuration (chaîne ou nombre JSON) en chaîne
+func configString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return boolParam(value)
+	default:
+		return ""
+	}
+}
+
+// configInt convertit une valeur de configuration en entier
+func configInt(v interface{}) (int, bool) {
+	n, err := strconv.Atoi(configString(v))
+	return n, err == nil
+}
+
+// configMemory lit l'option memory, entière ou chaîne de propriétés ([current=]<MiB>) depuis PVE 8.1
+func configMemory(v interface{}) (int, bool) {
+	if n, ok := configInt(v); ok {
+		return n, true
+	}
+	for _, prop := range parseProperties(configString(v)) {
+		if prop.key == "" || prop.key == "current" {
+			n, err := strconv.Atoi(prop.value)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// splitConfigTags découpe l'option tags (séparée par ';', ',' ou des espaces)
+func splitConfigTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' '
+	})
+}
+
+// boolParam convertit un booléen au format Proxmox (0/1)
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// sortedConfigKeys trie les clés d'une configuration en ordre naturel (net2 avant net10)
+func sortedConfigKeys(raw map[string]interface{}) []string {
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sortNatural(keys)
+	return keys
+}
+
+// sortedKeys trie les clés des modifications d'interfaces réseau en ordre naturel
+func sortedKeys(nets map[string]NetUpdate) []string {
+	keys := make([]string, 0, len(nets))
+	for key := range nets {
+		keys = append(keys, key)
+	}
+	sortNatural(keys)
+	return keys
+}
+
+// sortNatural trie des clés de la forme <préfixe><numéro> par préfixe puis par numéro
+func sortNatural(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		a := keyNumberSuffixRe.FindStringSubmatch(keys[i])
+		b := keyNumberSuffixRe.FindStringSubmatch(keys[j])
+		if a == nil || b == nil || a[1] != b[1] {
+			return keys[i] < keys[j]
+		}
+		na, _ := strconv.Atoi(a[2])
+		nb, _ := strconv.Atoi(b[2])
+		return na < nb
+	})
+}
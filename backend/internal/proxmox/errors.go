@@ -0,0 +1,97 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Erreurs typées retournées par le client. Elles peuvent être testées avec errors.Is.
+var (
+	ErrAuthFailed       = errors.New("proxmox: authentication failed")
+	ErrPermissionDenied = errors.New("proxmox: permission denied")
+	ErrNotFound         = errors.New("proxmox: resource not found")
+	ErrUnreachable      = errors.New("proxmox: server unreachable")
+	ErrConfigChanged    = errors.New("proxmox: configuration modified concurrently")
+)
+
+// statusUnreachable est le code retourné par pveproxy quand il ne peut pas joindre le nœud cible
+const statusUnreachable = 596
+
+// APIError représente une erreur retournée par l'API Proxmox (ou par le transport HTTP)
+type APIError struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	StatusCode int               `json:"status_code"`
+	Message    string            `json:"message"`
+	Errors     map[string]string `json:"errors,omitempty"` // erreurs de validation par paramètre
+	kind       error
+	cause      error
+}
+
+// Error implémente l'interface error
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("proxmox: %s %s: %s", e.Method, e.Path, e.Message)
+	}
+	return fmt.Sprintf("proxmox: %s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Unwrap permet d'utiliser errors.Is avec les erreurs typées et la cause d'origine
+func (e *APIError) Unwrap() []error {
+	var errs []error
+	if e.kind != nil {
+		errs = append(errs, e.kind)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// digestMismatchMessage est le message de Proxmox quand le digest fourni ne correspond plus à la configuration
+const digestMismatchMessage = "detected modified configuration"
+
+// kindForResponse associe une réponse en erreur à une erreur typée.
+// Proxmox signale un digest périmé par une erreur 500 : on la reconnaît à son message.
+func kindForResponse(status int, message string) error {
+	if strings.Contains(message, digestMismatchMessage) {
+		return ErrConfigChanged
+	}
+	return kindForStatus(status)
+}
+
+// kindForStatus associe un code HTTP à une erreur typée
+func kindForStatus(status int) error {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrAuthFailed
+	case http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, statusUnreachable:
+		return ErrUnreachable
+	default:
+		return nil
+	}
+}
+
+// HTTPStatus retourne le code HTTP à renvoyer au client du dashboard pour une erreur Proxmox
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnreachable):
+		return http.StatusBadGateway
+	case errors.Is(err, ErrConfigChanged):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,110 @@
+package proxmox
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// CurrentSnapshot est le nom de l'entrée représentant l'état courant dans la liste des snapshots
+const CurrentSnapshot = "current"
+
+// MaxSnapshotDescriptionLen est la longueur maximale de la description d'un snapshot
+const MaxSnapshotDescriptionLen = 8192
+
+// snapshotNamePattern est le format des noms de snapshot accepté par Proxmox (pve-configid)
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_\-]{1,39}$`)
+
+// Snapshot est une entrée de nodes/{node}/{type}/{vmid}/snapshot
+type Snapshot struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	SnapTime    int64   `json:"snaptime"` // absent pour "current"
+	VMState     IntBool `json:"vmstate"`  // état de la RAM inclus (VMs)
+	Parent      string  `json:"parent"`
+	Running     IntBool `json:"running"` // "current" uniquement
+}
+
+// IsCurrent indique si l'entrée représente l'état courant de l'invité
+func (s *Snapshot) IsCurrent() bool {
+	return s.Name == CurrentSnapshot
+}
+
+// CreatedAt retourne la date de création du snapshot (zéro pour "current")
+func (s *Snapshot) CreatedAt() time.Time {
+	if s.SnapTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.SnapTime, 0)
+}
+
+// SnapshotNode est un snapshot dans l'arbre des snapshots d'un invité.
+// L'état courant ("current") est la feuille placée sous le snapshot dont il dérive.
+type SnapshotNode struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+	VMState     bool            `json:"vmstate"`
+	Current     bool            `json:"current"`
+	Children    []*SnapshotNode `json:"children"`
+}
+
+// BuildSnapshotTree construit l'arbre des snapshots à partir de leur parent.
+// Un snapshot dont le parent est inconnu est une racine ; les enfants sont triés par date.
+func BuildSnapshotTree(snapshots []Snapshot) []*SnapshotNode {
+	nodes := make(map[string]*SnapshotNode, len(snapshots))
+	for _, s := range snapshots {
+		node := &SnapshotNode{
+			Name:        s.Name,
+			Description: s.Description,
+			VMState:     bool(s.VMState),
+			Current:     s.IsCurrent(),
+			Children:    []*SnapshotNode{},
+		}
+		if created := s.CreatedAt(); !created.IsZero() {
+			node.CreatedAt = &created
+		}
+		nodes[s.Name] = node
+	}
+
+	roots := []*SnapshotNode{}
+	for _, s := range snapshots {
+		node := nodes[s.Name]
+		if parent, ok := nodes[s.Parent]; ok && s.Parent != s.Name {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortSnapshotNodes(roots)
+	return roots
+}
+
+// sortSnapshotNodes trie récursivement les snapshots par date, l'état courant en dernier
+func sortSnapshotNodes(nodes []*SnapshotNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Current != nodes[j].Current {
+			return nodes[j].Current
+		}
+		if nodes[i].CreatedAt == nil || nodes[j].CreatedAt == nil {
+			return nodes[i].CreatedAt != nil
+		}
+		return nodes[i].CreatedAt.Before(*nodes[j].CreatedAt)
+	})
+	for _, node := range nodes {
+		sortSnapshotNodes(node.Children)
+	}
+}
+
+// ValidateSnapshotName vérifie qu'un nom de snapshot est accepté par Proxmox
+func ValidateSnapshotName(name string) error {
+	if name == CurrentSnapshot {
+		return fmt.Errorf("snapshot name %q is reserved", name)
+	}
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("snapshot name must start with a letter and contain 2 to 40 letters, digits, '-' or '_'")
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package proxmox
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GuestType identifie le type d'invité Proxmox
+type GuestType string
+
+const (
+	GuestQemu GuestType = "qemu"
+	GuestLXC  GuestType = "lxc"
+)
+
+// IntBool décode les booléens Proxmox, qui sont envoyés sous forme 0/1, "0"/"1" ou true/false
+type IntBool bool
+
+// UnmarshalJSON implémente json.Unmarshaler
+func (b *IntBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	switch s {
+	case "1", "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// VMID décode un identifiant d'invité envoyé indifféremment comme nombre ou chaîne
+type VMID int
+
+// UnmarshalJSON implémente json.Unmarshaler
+func (v *VMID) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	if s == "" || s == "null" {
+		*v = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = VMID(n)
+	return nil
+}
+
+// Resource est un élément de cluster/resources (node, qemu, lxc, storage, pool)
+type Resource struct {
+	ID       string  `json:"id"`
+	Type     string  `json:"type"`
+	Node     string  `json:"node"`
+	Status   string  `json:"status"`
+	Name     string  `json:"name"`
+	VMID     VMID    `json:"vmid"`
+	Pool     string  `json:"pool"`
+	Tags     string  `json:"tags"`
+	Template IntBool `json:"template"`
+	Storage  string  `json:"storage"`
+	Content  string  `json:"content"`
+	CPU      float64 `json:"cpu"`
+	MaxCPU   float64 `json:"maxcpu"`
+	Mem      int64   `json:"mem"`
+	MaxMem   int64   `json:"maxmem"`
+	Disk     int64   `json:"disk"`
+	MaxDisk  int64   `json:"maxdisk"`
+	Uptime   int64   `json:"uptime"`
+	NetIn    int64   `json:"netin"`
+	NetOut   int64   `json:"netout"`
+}
+
+// IsGuest indique si la ressource est une VM ou un conteneur
+func (r Resource) IsGuest() bool {
+	return r.Type == "qemu" || r.Type == "vm" || r.Type == "lxc"
+}
+
+// Node est un élément de la liste /nodes
+type Node struct {
+	Node    string  `json:"node"`
+	Status  string  `json:"status"`
+	CPU     float64 `json:"cpu"`
+	MaxCPU  float64 `json:"maxcpu"`
+	Mem     int64   `json:"mem"`
+	MaxMem  int64   `json:"maxmem"`
+	Disk    int64   `json:"disk"`
+	MaxDisk int64   `json:"maxdisk"`
+	Uptime  int64   `json:"uptime"`
+}
+
+// MemoryUsage décrit une consommation used/total
+type MemoryUsage struct {
+	Used  int64 `json:"used"`
+	Total int64 `json:"total"`
+	Free  int64 `json:"free"`
+	Avail int64 `json:"avail"`
+}
+
+// CPUInfo décrit le processeur d'un nœud
+type CPUInfo struct {
+	Model   string `json:"model"`
+	CPUs    int    `json:"cpus"`
+	Sockets int    `json:"sockets"`
+	Cores   int    `json:"cores"`
+}
+
+// NodeStatus est la réponse de nodes/{node}/status
+type NodeStatus struct {
+	CPU        float64     `json:"cpu"`
+	Wait       float64     `json:"wait"`
+	Uptime     int64       `json:"uptime"`
+	PVEVersion string      `json:"pveversion"`
+	KVersion   string      `json:"kversion"`
+	LoadAvg    []string    `json:"loadavg"`
+	Memory     MemoryUsage `json:"memory"`
+	Swap       MemoryUsage `json:"swap"`
+	RootFS     MemoryUsage `json:"rootfs"`
+	CPUInfo    CPUInfo     `json:"cpuinfo"`
+}
+
+// NetworkInterface est un élément de nodes/{node}/network
+type NetworkInterface struct {
+	Iface       string  `json:"iface"`
+	Type        string  `json:"type"`
+	Active      IntBool `json:"active"`
+	Autostart   IntBool `json:"autostart"`
+	Method      string  `json:"method"`
+	Address     string  `json:"address"`
+	Netmask     string  `json:"netmask"`
+	Gateway     string  `json:"gateway"`
+	CIDR        string  `json:"cidr"`
+	BridgePorts string  `json:"bridge_ports"`
+	Comments    string  `json:"comments"`
+}
+
+// Guest est un élément de nodes/{node}/qemu ou nodes/{node}/lxc
+type Guest struct {
+	VMID     VMID    `json:"vmid"`
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Tags     string  `json:"tags"`
+	Template IntBool `json:"template"`
+	Lock     string  `json:"lock"`
+	CPU      float64 `json:"cpu"`
+	CPUs     float64 `json:"cpus"`
+	Mem      int64   `json:"mem"`
+	MaxMem   int64   `json:"maxmem"`
+	Disk     int64   `json:"disk"`
+	MaxDisk  int64   `json:"maxdisk"`
+	Uptime   int64   `json:"uptime"`
+	NetIn    int64   `json:"netin"`
+	NetOut   int64   `json:"netout"`
+}
+
+// GuestStatus est la réponse de nodes/{node}/{qemu|lxc}/{vmid}/status/current
+type GuestStatus struct {
+	Guest
+	QMPStatus string  `json:"qmpstatus"`
+	Agent     IntBool `json:"agent"`
+	HA        struct {
+		Managed IntBool `json:"managed"`
+	} `json:"ha"`
+}
+
+// AgentIPAddress est une adresse retournée par le guest agent QEMU
+type AgentIPAddress struct {
+	Address string `json:"ip-address"`
+	Type    string `json:"ip-address-type"`
+	Prefix  int    `json:"prefix"`
+}
+
+// AgentInterface est une interface retournée par le guest agent QEMU
+type AgentInterface struct {
+	Name        string           `json:"name"`
+	HWAddress   string           `json:"hardware-address"`
+	IPAddresses []AgentIPAddress `json:"ip-addresses"`
+}
+
+// IPv4 retourne la première adresse IPv4 de l'interface, ou une chaîne vide
+func (i AgentInterface) IPv4() string {
+	for _, addr := range i.IPAddresses {
+		if addr.Type == "ipv4" && addr.Address != "" {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+// ContainerInterface est un élément de nodes/{node}/lxc/{vmid}/interfaces
+type ContainerInterface struct {
+	Name   string `json:"name"`
+	HWAddr string `json:"hwaddr"`
+	Inet   string `json:"inet"`
+	Inet6  string `json:"inet6"`
+}
+
+// Storage est un élément de nodes/{node}/storage
+type Storage struct {
+	Storage      string  `json:"storage"`
+	Type         string  `json:"type"`
+	Content      string  `json:"content"`
+	Enabled      IntBool `json:"enabled"`
+	Active       IntBool `json:"active"`
+	Shared       IntBool `json:"shared"`
+	Total        int64   `json:"total"`
+	Used         int64   `json:"used"`
+	Avail        int64   `json:"avail"`
+	UsedFraction float64 `json:"used_fraction"`
+}
+
+// Backup est une archive de sauvegarde vzdump
+type Backup struct {
+	VolID     string  `json:"volid"`
+	Format    string  `json:"format"`
+	Size      int64   `json:"size"`
+	CTime     int64   `json:"ctime"`
+	VMID      VMID    `json:"vmid"`
+	Notes     string  `json:"notes"`
+	Protected IntBool `json:"protected"`
+	Subtype   string  `json:"subtype"`
+}
+
+// Task est un élément de cluster/tasks ou nodes/{node}/tasks
+type Task struct {
+	UPID      string `json:"upid"`
+	Node      string `json:"node"`
+	PID       int64  `json:"pid"`
+	PStart    int64  `json:"pstart"`
+	StartTime int64  `json:"starttime"`
+	EndTime   int64  `json:"endtime"`
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Status    string `json:"status"`
+}
+
+// TaskStatus est le statut d'une tâche (nodes/{node}/tasks/{upid}/status).
+// Status vaut "running" puis "stopped" ; ExitStatus vaut alors "OK", "WARNINGS: n" ou le message d'erreur.
+type TaskStatus struct {
+	UPID       string `json:"upid"`
+	Node       string `json:"node"`
+	PID        int64  `json:"pid"`
+	StartTime  int64  `json:"starttime"`
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	User       string `json:"user"`
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus,omitempty"`
+}
+
+// Running indique si la tâche est encore en cours
+func (t *TaskStatus) Running() bool {
+	return t.Status == "running"
+}
+
+// Succeeded indique si la tâche s'est terminée sans erreur (avertissements compris)
+func (t *TaskStatus) Succeeded() bool {
+	return t.Status == "stopped" && (t.ExitStatus == "OK" || strings.HasPrefix(t.ExitStatus, "WARNINGS"))
+}
+
+// TaskLogLine est une ligne du journal d'une tâche (nodes/{node}/tasks/{upid}/log)
+type TaskLogLine struct {
+	N int    `json:"n"`
+	T string `json:"t"`
+}
+
+// UPID est l'identifiant décodé d'une tâche Proxmox
+// (UPID:node:pid:pstart:starttime:type:id:user:)
+type UPID struct {
+	Node string
+	Type string
+	ID   string
+	User string
+}
+
+// ParseUPID décode un UPID Proxmox
+func ParseUPID(upid string) (UPID, error) {
+	parts := strings.Split(upid, ":")
+	if len(parts) < 8 || parts[0] != "UPID" || parts[1] == "" {
+		return UPID{}, fmt.Errorf("invalid UPID %q", upid)
+	}
+	return UPID{Node: parts[1], Type: parts[5], ID: parts[6], User: parts[7]}, nil
+}
+
+// RRDPoint est un point de nodes/{node}/rrddata ou nodes/{node}/{qemu|lxc}/{vmid}/rrddata.
+// Les nœuds exposent memused/memtotal et rootused/roottotal, les invités mem/maxmem et disk/maxdisk.
+// CPU est nil pour les intervalles sans donnée.
+type RRDPoint struct {
+	Time      int64    `json:"time"`
+	CPU       *float64 `json:"cpu"` // fraction (0-1)
+	MaxCPU    float64  `json:"maxcpu"`
+	Mem       float64  `json:"mem"`
+	MaxMem    float64  `json:"maxmem"`
+	MemUsed   float64  `json:"memused"`
+	MemTotal  float64  `json:"memtotal"`
+	Disk      float64  `json:"disk"`
+	MaxDisk   float64  `json:"maxdisk"`
+	RootUsed  float64  `json:"rootused"`
+	RootTotal float64  `json:"roottotal"`
+	NetIn     float64  `json:"netin"`
+	NetOut    float64  `json:"netout"`
+}
+
+// Ticket est la réponse de access/ticket
+type Ticket struct {
+	Ticket              string `json:"ticket"`
+	CSRFPreventionToken string `json:"CSRFPreventionToken"`
+	Username            string `json:"username"`
+}
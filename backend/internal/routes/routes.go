@@ -2,8 +2,13 @@ package routes
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	"proxmox-dashboard/internal/auth"
 	"proxmox-dashboard/internal/handlers"
+	appmw "proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
 	"proxmox-dashboard/internal/sse"
 
 	"github.com/go-chi/chi/v5"
@@ -11,15 +16,38 @@ import (
 	"github.com/go-chi/cors"
 )
 
-// SetupRoutes configure toutes les routes de l'application
-func SetupRoutes(h *handlers.Handlers, hub *sse.Hub) *chi.Mux {
+// timeoutExceptStreams applique middleware.Timeout à toutes les requêtes sauf aux flux SSE,
+// qui doivent rester ouverts au-delà du délai
+func timeoutExceptStreams(timeout time.Duration) func(http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		timed := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetupRoutes configure toutes les routes de l'application.
+// Toutes les routes API, sauf /health et la connexion, exigent un token de session ;
+// chaque route vérifie ensuite la permission correspondante de models.RolePermissions.
+// Les requêtes modifiant l'état sont enregistrées dans le journal d'audit.
+func SetupRoutes(h *handlers.Handlers, authHandlers *handlers.AuthHandlers, authService *auth.Service, auditLog appmw.AuditRecorder, hub *sse.Hub) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware global
-	r.Use(middleware.Logger)
+	r.Use(appmw.Logger) // tokens de session et de console masqués dans les journaux
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60))
+	if exp := h.Exporter(); exp != nil {
+		r.Use(exp.Middleware) // métriques HTTP publiées sur /metrics
+	}
+	r.Use(timeoutExceptStreams(handlers.RequestTimeout))
+	r.Use(appmw.AuditMiddleware(auditLog))
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
@@ -29,77 +57,272 @@ func SetupRoutes(h *handlers.Handlers, hub *sse.Hub) *chi.Mux {
 		AllowCredentials: true,
 	}))
 
+	requireAuth := appmw.JWTAuthMiddleware(authService)
+	can := appmw.RequirePermission
+
 	// Routes de santé
 	r.Get("/health", h.GetHealth)
 
+	// Exporter Prometheus : token de session, ou token partagé METRICS_TOKEN s'il est configuré
+	if exp := h.Exporter(); exp != nil {
+		metricsAuth := appmw.ServiceTokenAuth(h.MetricsToken(), "prometheus",
+			[]models.Permission{{Resource: "metrics", Action: "read"}}, requireAuth)
+		r.With(metricsAuth, can("metrics", "read")).Method(http.MethodGet, "/metrics", exp)
+	}
+
+	// Authentification et gestion des utilisateurs
+	r.Route("/api/auth", func(r chi.Router) {
+		r.Post("/login", authHandlers.Login)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAuth)
+			r.Post("/logout", authHandlers.Logout)
+			r.Get("/me", authHandlers.Me)
+			r.Get("/permissions", authHandlers.GetUserPermissions)
+			r.Post("/change-password", authHandlers.ChangePassword)
+
+			// Rôles (intégrés et personnalisés) et catalogue des permissions
+			r.Route("/roles", func(r chi.Router) {
+				r.Get("/", authHandlers.GetUserRoles)
+				r.Get("/catalog", authHandlers.GetPermissionCatalog)
+				r.Get("/{name}", authHandlers.GetRole)
+				r.With(can("roles", "write")).Post("/", authHandlers.CreateRole)
+				r.With(can("roles", "write")).Put("/{name}", authHandlers.UpdateRole)
+				r.With(can("roles", "write")).Delete("/{name}", authHandlers.DeleteRole)
+			})
+
+			r.Route("/users", func(r chi.Router) {
+				r.With(can("users", "read")).Get("/", authHandlers.ListUsers)
+				r.With(can("users", "write")).Post("/", authHandlers.CreateUser)
+				r.With(can("users", "read")).Get("/{id}", authHandlers.GetUser)
+				// Le handler autorise aussi un utilisateur à modifier son propre profil
+				r.With(can("profile", "write")).Put("/{id}", authHandlers.UpdateUser)
+				r.With(can("users", "write")).Delete("/{id}", authHandlers.DeleteUser)
+			})
+		})
+	})
+
 	// Route de compatibilité pour /api/apps (redirige vers /api/v1/apps)
 	r.Route("/api/apps", func(r chi.Router) {
-		r.Get("/", h.GetApps)
-		r.Post("/", h.CreateApp)
-		r.Put("/{id}", h.UpdateApp)
-		r.Delete("/{id}", h.DeleteApp)
+		r.Use(requireAuth)
+		r.With(can("apps", "read")).Get("/", h.GetApps)
+		r.With(can("apps", "write")).Post("/", h.CreateApp)
+		r.With(can("apps", "write")).Put("/{id}", h.UpdateApp)
+		r.With(can("apps", "write")).Delete("/{id}", h.DeleteApp)
 	})
 
 	// API v1
 	r.Route("/api/v1", func(r chi.Router) {
-		// Applications
-		r.Route("/apps", func(r chi.Router) {
-			r.Get("/", h.GetApps)
-			r.Post("/", h.CreateApp)
-			r.Put("/{id}", h.UpdateApp)
-			r.Delete("/{id}", h.DeleteApp)
-		})
+		// La console VNC est ouverte dans une fenêtre séparée qui ne transmet pas le token de session :
+		// ces routes exigent le token de console de courte durée émis par /proxmox/vm/console
+		// (authentification et permission vérifiées) et ne joignent que la connexion enregistrée
+		r.Get("/proxmox/vm/console-redirect", h.VMConsoleRedirect)  // redirection console VNC avec cookie
+		r.HandleFunc("/proxmox/vm/console-proxy", h.VMConsoleProxy) // proxy console VNC avec cookie HTTP
 
-		// Alertes
-		r.Route("/alerts", func(r chi.Router) {
-			r.Get("/", h.GetAlerts)
-			r.Post("/", h.CreateAlert)
-			r.Put("/:id/acknowledge", h.AcknowledgeAlert)
-			r.Put("/acknowledge-all", h.AcknowledgeAllAlerts)
-			r.Get("/stream", h.StreamAlerts) // SSE endpoint
-		})
+		// Récepteur Alertmanager : token de session, ou token partagé ALERTMANAGER_TOKEN s'il est configuré
+		alertmanagerAuth := appmw.ServiceTokenAuth(h.AlertmanagerToken(), "alertmanager",
+			[]models.Permission{{Resource: "alerts", Action: "write"}}, requireAuth)
+		r.With(alertmanagerAuth, can("alerts", "write")).Post("/alerts/alertmanager", h.ReceiveAlertmanager)
 
-		// Notifications
-		r.Route("/notifications", func(r chi.Router) {
-			r.Post("/subscribe", h.SubscribeNotification)
-			r.Post("/test", h.TestEmail)
-		})
+		r.Group(func(r chi.Router) {
+			r.Use(requireAuth)
 
-		// Santé des services
-		r.Route("/health", func(r chi.Router) {
-			r.Get("/http", h.GetHealthHTTP)
-			r.Get("/tcp", h.GetHealthTCP)
-		})
+			// Applications
+			r.Route("/apps", func(r chi.Router) {
+				r.With(can("apps", "read")).Get("/", h.GetApps)
+				r.With(can("apps", "write")).Post("/", h.CreateApp)
+				r.With(can("apps", "write")).Put("/{id}", h.UpdateApp)
+				r.With(can("apps", "write")).Delete("/{id}", h.DeleteApp)
+				r.With(can("apps", "read")).Get("/{id}/health", h.GetAppHealth)
+				r.With(can("apps", "read")).Get("/{id}/uptime", h.GetAppUptime)
+			})
 
-		// Administration
-		r.Route("/admin", func(r chi.Router) {
-			r.Post("/clear-db", h.ClearDatabase)
-		})
+			// Alertes
+			r.Route("/alerts", func(r chi.Router) {
+				r.With(can("alerts", "read")).Get("/", h.GetAlerts)
+				r.With(can("alerts", "write")).Post("/", h.CreateAlert)
+				r.With(can("alerts", "write")).Put("/acknowledge-all", h.AcknowledgeAllAlerts)
+				r.With(can("alerts", "read")).Get("/stream", h.StreamAlerts)   // SSE endpoint
+				r.With(can("alerts", "read")).Get("/groups", h.GetAlertGroups) // groupes en attente de notification
+				r.With(can("alerts", "read")).Get("/{id}", h.GetAlert)
+				// Cycle de vie : firing → acknowledged → resolved
+				r.With(can("alerts", "write")).Put("/{id}/acknowledge", h.AcknowledgeAlert)
+				r.With(can("alerts", "write")).Put("/{id}/resolve", h.ResolveAlert)
+				r.With(can("alerts", "write")).Put("/{id}/snooze", h.SnoozeAlert)
+				r.With(can("alerts", "write")).Delete("/{id}/snooze", h.UnsnoozeAlert)
+				r.With(can("alerts", "write")).Put("/{id}/assign", h.AssignAlert)
+				r.With(can("alerts", "read")).Get("/{id}/comments", h.GetAlertComments)
+				r.With(can("alerts", "write")).Post("/{id}/comments", h.CreateAlertComment)
 
-		// Proxmox
-		r.Route("/proxmox", func(r chi.Router) {
-			r.Post("/fetch-data", h.FetchProxmoxData)
-			r.Post("/fetch-backups", h.FetchProxmoxBackups)
-			r.Post("/fetch-tasks", h.FetchProxmoxTasks)
-			r.Post("/fetch-docker", h.FetchProxmoxDocker)
-			r.Post("/fetch-databases", h.FetchProxmoxDatabases)
-			r.Post("/fetch-networks", h.FetchProxmoxNetworks)
-			r.Post("/test-password", h.TestProxmoxPassword)     // test du mot de passe
-			r.Post("/vm/{action}", h.VMAction)                  // start, stop, restart, pause
-			r.Post("/vm/console", h.VMConsole)                  // console VNC
-			r.Get("/vm/console-redirect", h.VMConsoleRedirect)  // redirection console VNC avec cookie
-			r.HandleFunc("/vm/console-proxy", h.VMConsoleProxy) // proxy console VNC avec cookie HTTP
-			r.Post("/vm/config", h.VMConfig)                    // configuration VM
-		})
+				// Silences : suspendent les notifications des alertes couvertes par leurs matchers
+				r.Route("/silences", func(r chi.Router) {
+					r.With(can("alerts", "read")).Get("/", h.GetSilences)
+					r.With(can("alerts", "write")).Post("/", h.CreateSilence)
+					r.With(can("alerts", "read")).Get("/{id}", h.GetSilence)
+					r.With(can("alerts", "write")).Delete("/{id}", h.ExpireSilence)
+				})
+
+				// Règles évaluées après chaque collecte de l'inventaire
+				r.Route("/rules", func(r chi.Router) {
+					r.With(can("alerts", "read")).Get("/", h.GetAlertRules)
+					r.With(can("alerts", "rules")).Post("/", h.CreateAlertRule)
+					r.With(can("alerts", "read")).Get("/{id}", h.GetAlertRule)
+					r.With(can("alerts", "rules")).Put("/{id}", h.UpdateAlertRule)
+					r.With(can("alerts", "rules")).Delete("/{id}", h.DeleteAlertRule)
+				})
+			})
+
+			// Notifications
+			r.Route("/notifications", func(r chi.Router) {
+				r.With(can("notifications", "write")).Post("/subscribe", h.SubscribeNotification)
+				r.With(can("notifications", "write")).Post("/test", h.TestEmail)
+				r.With(can("notifications", "read")).Get("/emails", h.GetEmails)               // file d'attente (?state=dead)
+				r.With(can("notifications", "write")).Post("/emails/{id}/retry", h.RetryEmail) // relance d'un email abandonné
+				r.With(can("notifications", "read")).Get("/", h.GetNotificationSubscriptions)
+				r.With(can("notifications", "write")).Put("/{id}", h.UpdateSubscription)                  // activation / réactivation
+				r.With(can("notifications", "read")).Get("/{id}/deliveries", h.GetSubscriptionDeliveries) // historique des livraisons webhook
+				r.With(can("notifications", "write")).Post("/{id}/ping", h.PingSubscription)
+
+				// Routage : abonnements notifiés selon la sévérité et la source, regroupement et plages de silence
+				r.Route("/routes", func(r chi.Router) {
+					r.With(can("notifications", "read")).Get("/", h.GetNotifyRoutes)
+					r.With(can("notifications", "write")).Post("/", h.CreateNotifyRoute)
+					r.With(can("notifications", "read")).Get("/{id}", h.GetNotifyRoute)
+					r.With(can("notifications", "write")).Put("/{id}", h.UpdateNotifyRoute)
+					r.With(can("notifications", "write")).Delete("/{id}", h.DeleteNotifyRoute)
+				})
+			})
+
+			// Santé des services
+			r.Route("/health", func(r chi.Router) {
+				r.Use(can("health", "read"))
+				r.Get("/http", h.GetHealthHTTP)
+				r.Get("/tcp", h.GetHealthTCP)
+			})
+
+			// Administration
+			r.Route("/admin", func(r chi.Router) {
+				r.With(can("admin", "clear-db")).Post("/clear-db", h.ClearDatabase)
+			})
+
+			// Proxmox
+			r.Route("/proxmox", func(r chi.Router) {
+				// Connexions enregistrées côté serveur (les secrets ne quittent pas le backend)
+				r.Route("/connections", func(r chi.Router) {
+					r.With(can("proxmox", "read")).Get("/", h.GetProxmoxConnections)
+					r.With(can("connections", "write")).Post("/", h.CreateProxmoxConnection)
+					r.With(can("proxmox", "read")).Get("/{id}", h.GetProxmoxConnection)
+					r.With(can("connections", "write")).Put("/{id}", h.UpdateProxmoxConnection)
+					r.With(can("connections", "write")).Delete("/{id}", h.DeleteProxmoxConnection)
+					r.With(can("proxmox", "read"), appmw.SkipAudit).Post("/{id}/test", h.TestProxmoxConnection)
+				})
+
+				// Lecture de l'inventaire
+				r.Group(func(r chi.Router) {
+					r.Use(can("proxmox", "read"))
+					r.Use(appmw.SkipAudit) // collecte et tests de connexion, sans effet sur Proxmox
+
+					// Inventaire agrégé de tous les clusters, servi depuis le snapshot du poller
+					r.Get("/inventory", h.GetProxmoxInventory)
+					r.Post("/inventory/refresh", h.RefreshProxmoxInventory)
+					r.Get("/cache", h.GetProxmoxCacheStatus)
+					r.Get("/nodes", h.GetProxmoxNodes)
+					r.Get("/vms", h.GetProxmoxVMs)
+					r.Get("/lxc", h.GetProxmoxLXC)
+					r.Get("/storages", h.GetProxmoxStorages)
+					r.Get("/networks", h.GetProxmoxNetworks)
+					r.Get("/snapshots", h.GetProxmoxSnapshotAges)                               // âge des snapshots des invités (?older_than=7d)
+					r.With(can("backups", "read")).Get("/backups", h.GetProxmoxBackups)         // archives des storages de sauvegarde
+					r.With(can("backups", "read")).Get("/backups/runs", h.GetProxmoxBackupRuns) // tâches vzdump récentes
+
+					r.Post("/fetch-data", h.FetchProxmoxData)
+					r.With(can("backups", "read")).Post("/fetch-backups", h.FetchProxmoxBackups)
+					r.Post("/fetch-tasks", h.FetchProxmoxTasks)
+					r.Post("/fetch-docker", h.FetchProxmoxDocker)
+					r.Post("/fetch-databases", h.FetchProxmoxDatabases)
+					r.Post("/fetch-networks", h.FetchProxmoxNetworks)
+					r.Post("/test-password", h.TestProxmoxPassword) // test du mot de passe
+					r.Post("/tasks/wait", h.WaitProxmoxTask)        // attente de fin de tâche (UPID)
+					r.Post("/tasks/log", h.GetProxmoxTaskLog)       // journal d'une tâche (UPID)
+				})
+
+				// Actions sur les invités : la portée (nœud, pool, tag) est vérifiée par les handlers
+				r.With(can("proxmox.vm", "console")).Post("/vm/console", h.VMConsole) // console VNC
+				// Configuration typée : lecture (POST, identifiants dans le corps) et modification partielle avec digest
+				r.With(can("proxmox", "read"), appmw.SkipAudit).Post("/vm/config", h.GetVMConfig)
+				r.With(can("proxmox.vm", "config")).Put("/vm/config", h.UpdateVMConfig)
+				r.With(can("proxmox", "read"), appmw.SkipAudit).Post("/lxc/config", h.GetLXCConfig)
+				r.With(can("proxmox.lxc", "config")).Put("/lxc/config", h.UpdateLXCConfig)
+				// Snapshots : arbre (POST, identifiants dans le corps), création, restauration et suppression (UPID retourné)
+				r.With(can("proxmox.vm", "snapshot"), appmw.SkipAudit).Post("/vm/snapshots", h.ListVMSnapshots)
+				r.With(can("proxmox.vm", "snapshot")).Post("/vm/snapshots/{operation}", h.VMSnapshotAction)
+				r.With(can("proxmox.lxc", "snapshot"), appmw.SkipAudit).Post("/lxc/snapshots", h.ListLXCSnapshots)
+				r.With(can("proxmox.lxc", "snapshot")).Post("/lxc/snapshots/{operation}", h.LXCSnapshotAction)
+				r.With(can("proxmox.vm", "power")).Post("/vm/{action}", h.VMAction)    // start, stop, shutdown, restart, pause, resume, reset, hibernate
+				r.With(can("proxmox.lxc", "power")).Post("/lxc/{action}", h.LXCAction) // start, stop, shutdown, restart, pause, resume
+
+				// Sauvegardes : vzdump à la demande (portée vérifiée par le handler sur chaque invité)
+				// et tâches planifiées du cluster (cluster/backup, liste en POST avec les identifiants)
+				r.With(can("backups", "run")).Post("/backups/run", h.RunBackup)
+				r.With(can("backups", "read"), appmw.SkipAudit).Post("/backups/jobs/list", h.ListBackupJobs)
+				r.With(can("backups", "jobs")).Post("/backups/jobs", h.CreateBackupJob)
+				r.With(can("backups", "jobs")).Put("/backups/jobs/{id}", h.UpdateBackupJob)
+				r.With(can("backups", "jobs")).Delete("/backups/jobs/{id}", h.DeleteBackupJob)
+
+				// Provisionnement depuis les templates : la permission clone (qemu ou lxc) est exigée, sa portée
+				// est vérifiée par les handlers sur le template et la destination ; les jobs listés sont filtrés
+				// selon cette portée et l'avancement des jobs est diffusé en SSE
+				r.Route("/provision", func(r chi.Router) {
+					canClone := appmw.RequireAnyPermission(
+						models.Permission{Resource: "proxmox.vm", Action: "clone"},
+						models.Permission{Resource: "proxmox.lxc", Action: "clone"})
+					r.With(canClone, appmw.SkipAudit).Post("/templates", h.ListProvisionTemplates)
+					r.With(canClone).Post("/jobs", h.CreateProvisionJob)
+					r.With(can("proxmox", "read")).Get("/jobs", h.GetProvisionJobs)
+					r.With(can("proxmox", "read")).Get("/jobs/{id}", h.GetProvisionJob)
+					r.With(can("proxmox", "read")).Get("/jobs/{id}/events", h.StreamProvisionJob)
+				})
+			})
+
+			// Historique des métriques (nœuds, VMs, LXC, storages)
+			r.Route("/metrics", func(r chi.Router) {
+				r.With(can("metrics", "write")).Post("/backfill", h.BackfillMetrics) // import depuis rrddata
+				r.With(can("metrics", "read")).Get("/{kind}/{id}", h.GetMetrics)
+			})
+
+			// Journal d'audit (filtres, export CSV/JSON)
+			r.With(can("audit", "read")).Get("/audit", h.GetAuditLog)
+
+			// Rapports calculés sur l'inventaire (export CSV/JSON)
+			r.Route("/reports", func(r chi.Router) {
+				r.With(can("backups", "read")).Get("/backup-compliance", h.GetBackupComplianceReport) // conformité des sauvegardes (?violations=true, ?tag=, ?pool=)
+			})
+
+			// Prometheus : requêtes relayées vers les sources de données configurées côté serveur (?datasource=id)
+			r.Route("/prometheus", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(can("prometheus", "read"))
+					r.Get("/query", h.QueryPrometheus)
+					r.Get("/query_range", h.QueryRangePrometheus)
+					r.Get("/series", h.GetPrometheusSeries)
+					r.Get("/labels", h.GetPrometheusLabels)
+					r.Get("/label/{name}/values", h.GetPrometheusLabelValues)
+				})
 
-		// Prometheus
-		r.Route("/prometheus", func(r chi.Router) {
-			r.Get("/query", h.QueryPrometheus)
+				r.Route("/datasources", func(r chi.Router) {
+					r.With(can("prometheus", "read")).Get("/", h.GetPrometheusDataSources)
+					r.With(can("prometheus", "write")).Post("/", h.CreatePrometheusDataSource)
+					r.With(can("prometheus", "read")).Get("/{id}", h.GetPrometheusDataSource)
+					r.With(can("prometheus", "write")).Put("/{id}", h.UpdatePrometheusDataSource)
+					r.With(can("prometheus", "write")).Delete("/{id}", h.DeletePrometheusDataSource)
+					r.With(can("prometheus", "read"), appmw.SkipAudit).Post("/{id}/test", h.TestPrometheusDataSource)
+				})
+			})
 		})
 	})
 
-	// Server-Sent Events
-	r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+	// Server-Sent Events (le token est passé en paramètre ?token=, EventSource n'envoyant pas de header)
+	r.With(requireAuth).Get("/events", func(w http.ResponseWriter, r *http.Request) {
 		hub.ServeHTTP(w, r)
 	})
 
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prefix identifie la version du format chiffré stocké en base
+const prefix = "v1:"
+
+// ErrEmptyKey est retourné quand aucune clé de chiffrement n'est configurée
+var ErrEmptyKey = errors.New("secrets: encryption key is empty")
+
+// Cipher chiffre les secrets stockés en base avec AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher crée un Cipher à partir d'une clé arbitraire (dérivée en 256 bits par SHA-256)
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create block cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt chiffre une valeur. Une valeur vide reste vide.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt déchiffre une valeur produite par Encrypt
+func (c *Cipher) Decrypt(encoded string) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(encoded, prefix) {
+		return "", fmt.Errorf("secrets: unknown format")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, prefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", fmt.Errorf("secrets: ciphertext too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -46,14 +46,14 @@ func (s *AlertService) GetAlerts() ([]*models.Alert, error) {
 	return s.store.GetAlerts()
 }
 
-// AcknowledgeAlert marque une alerte comme acquittée
-func (s *AlertService) AcknowledgeAlert(id int) error {
-	return s.store.AcknowledgeAlert(id)
+// AcknowledgeAlert marque une alerte comme acquittée par l'utilisateur by
+func (s *AlertService) AcknowledgeAlert(id int, by string) error {
+	return s.store.AcknowledgeAlert(id, by)
 }
 
-// AcknowledgeAllAlerts marque toutes les alertes comme acquittées
-func (s *AlertService) AcknowledgeAllAlerts() error {
-	return s.store.AcknowledgeAllAlerts()
+// AcknowledgeAllAlerts marque toutes les alertes déclenchées comme acquittées par l'utilisateur by
+func (s *AlertService) AcknowledgeAllAlerts(by string) ([]int, error) {
+	return s.store.AcknowledgeAllAlerts(by)
 }
 
 // GetAlertsBySeverity récupère les alertes par niveau de sévérité
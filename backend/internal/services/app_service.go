@@ -4,12 +4,19 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"proxmox-dashboard/internal/models"
 	"proxmox-dashboard/internal/store"
 )
 
+// healthCheckTimeout borne la durée d'une vérification de santé
+const healthCheckTimeout = 10 * time.Second
+
+// healthHTTPClient effectue les vérifications HTTP des applications
+var healthHTTPClient = &http.Client{Timeout: healthCheckTimeout}
+
 // AppService gère la logique métier des applications
 type AppService struct {
 	store *store.Store
@@ -23,16 +30,17 @@ func NewAppService(store *store.Store) *AppService {
 // CreateApp crée une nouvelle application
 func (s *AppService) CreateApp(req models.CreateAppRequest) (*models.App, error) {
 	app := &models.App{
-		Name:       req.Name,
-		Protocol:   req.Protocol,
-		Host:       req.Host,
-		Port:       req.Port,
-		Path:       req.Path,
-		Tag:        req.Tag,
-		Icon:       req.Icon,
-		HealthPath: req.HealthPath,
-		HealthType: req.HealthType,
-		CreatedAt:  time.Now(),
+		Name:          req.Name,
+		Protocol:      req.Protocol,
+		Host:          req.Host,
+		Port:          req.Port,
+		Path:          req.Path,
+		Tag:           req.Tag,
+		Icon:          req.Icon,
+		HealthPath:    req.HealthPath,
+		HealthType:    req.HealthType,
+		CheckInterval: req.CheckInterval,
+		CreatedAt:     time.Now(),
 	}
 
 	if err := app.Validate(); err != nil {
@@ -83,7 +91,7 @@ func (s *AppService) CheckHealth(app *models.App) (*models.HealthStatus, error)
 
 	if app.HealthType == "tcp" {
 		// Vérification TCP
-		conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", app.Host, app.Port), 5*time.Second)
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(app.Host, strconv.Itoa(app.Port)), healthCheckTimeout)
 		latency = time.Since(startTime).Milliseconds()
 
 		if err != nil {
@@ -97,7 +105,7 @@ func (s *AppService) CheckHealth(app *models.App) (*models.HealthStatus, error)
 	} else {
 		// Vérification HTTP
 		url := fmt.Sprintf("%s://%s:%d%s", app.Protocol, app.Host, app.Port, app.HealthPath)
-		resp, err := http.Get(url)
+		resp, err := healthHTTPClient.Get(url)
 		latency = time.Since(startTime).Milliseconds()
 
 		if err != nil {
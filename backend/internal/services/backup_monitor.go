@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+)
+
+// BackupAlertSourcePrefix préfixe la source des alertes de sauvegarde ("backup:prod")
+const BackupAlertSourcePrefix = "backup:"
+
+// BackupTaskType est le type des tâches Proxmox de sauvegarde
+const BackupTaskType = "vzdump"
+
+// Lecture du journal d'une sauvegarde en échec
+const (
+	backupLogTimeout  = 10 * time.Second
+	backupLogMaxLines = 5000
+	backupLogExcerpt  = 5 // lignes d'erreur reprises dans l'alerte
+)
+
+// backupErrorPattern reconnaît l'échec d'un invité dans le journal vzdump ("ERROR: Backup of VM 100 failed - ...")
+var backupErrorPattern = regexp.MustCompile(`^ERROR: Backup of VM (\d+) failed - (.*)$`)
+
+// backupFinishedPattern reconnaît la sauvegarde réussie d'un invité ("INFO: Finished Backup of VM 100 (00:01:02)")
+var backupFinishedPattern = regexp.MustCompile(`^INFO: Finished Backup of VM (\d+)\b`)
+
+// BackupAlertFingerprint retourne l'empreinte de l'alerte d'échec des sauvegardes d'un invité (vmid), indépendante
+// de son nœud pour survivre à une migration. vmid 0 désigne une tâche planifiée du nœud dont le journal n'a pas
+// permis d'identifier les invités en échec.
+func BackupAlertFingerprint(cluster, node string, vmid int) string {
+	if vmid > 0 {
+		return BackupAlertSourcePrefix + cluster + "/" + strconv.Itoa(vmid)
+	}
+	return BackupAlertSourcePrefix + cluster + "/" + node + "/job"
+}
+
+// BackupRunFailed indique si une tâche vzdump terminée a échoué (les avertissements ne sont pas des échecs)
+func BackupRunFailed(task models.ProxmoxTask) bool {
+	return task.Status == "failed" && !strings.HasPrefix(task.ExitStatus, "WARNINGS")
+}
+
+// BackupMonitor suit les tâches vzdump terminées entre deux collectes du poller : un échec déclenche une
+// alerte illustrée par les erreurs de son journal, la sauvegarde réussie suivante du même périmètre la résout.
+// L'état est déduit de l'alerte ouverte (fingerprint), ce qui le conserve d'un redémarrage à l'autre.
+type BackupMonitor struct {
+	store  *store.Store
+	alerts []func(alert *models.Alert)
+}
+
+// NewBackupMonitor crée un moniteur des sauvegardes
+func NewBackupMonitor(store *store.Store) *BackupMonitor {
+	return &BackupMonitor{store: store}
+}
+
+// OnAlert enregistre un listener appelé pour les alertes déclenchées et résolues
+func (m *BackupMonitor) OnAlert(listener func(alert *models.Alert)) {
+	m.alerts = append(m.alerts, listener)
+}
+
+// Observe traite les sauvegardes terminées depuis le snapshot précédent, dans l'ordre de fin
+func (m *BackupMonitor) Observe(prev, next *models.ProxmoxSnapshot) {
+	var runs []models.ProxmoxTask
+	for _, event := range inventory.FinishedTasks(prev, next) {
+		if event.After.Type == BackupTaskType {
+			runs = append(runs, *event.After)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CompletedAt.Before(*runs[j].CompletedAt)
+	})
+
+	for _, run := range runs {
+		if BackupRunFailed(run) {
+			m.fire(next, run)
+		} else {
+			m.resolve(run)
+		}
+	}
+}
+
+// runVMID retourne l'invité sauvegardé par une tâche vzdump (0 pour une tâche couvrant plusieurs invités)
+func runVMID(run models.ProxmoxTask) int {
+	vmid, _ := strconv.Atoi(run.Name)
+	return vmid
+}
+
+// backupLog résume le journal d'une tâche vzdump
+type backupLog struct {
+	errors      []string         // premières lignes ERROR: de la tâche
+	failed      []int            // invités en échec, dans l'ordre du journal
+	guestErrors map[int][]string // lignes ERROR: propres à chaque invité en échec
+	finished    []int            // invités sauvegardés avec succès
+}
+
+// fire crée les alertes d'échec d'une sauvegarde : une par invité en échec d'une tâche couvrant plusieurs invités,
+// pour que l'échec d'un invité ne masque pas celui d'un autre, et résout celles des invités sauvegardés par la même
+// tâche. Sans invité identifiable dans le journal, l'alerte porte sur la tâche du nœud.
+func (m *BackupMonitor) fire(snapshot *models.ProxmoxSnapshot, run models.ProxmoxTask) {
+	runLog := m.taskLog(run)
+	if vmid := runVMID(run); vmid > 0 {
+		m.create(snapshot, run, vmid, runLog.errors, runLog.failed)
+		return
+	}
+	if len(runLog.failed) == 0 {
+		m.create(snapshot, run, 0, runLog.errors, runLog.failed)
+		return
+	}
+	for _, vmid := range runLog.failed {
+		m.create(snapshot, run, vmid, runLog.guestErrors[vmid], runLog.failed)
+	}
+	m.resolveGuests(run, runLog.finished)
+}
+
+// create enregistre l'alerte d'échec d'un périmètre (invité ou tâche du nœud), sauf si elle est déjà ouverte
+func (m *BackupMonitor) create(snapshot *models.ProxmoxSnapshot, run models.ProxmoxTask, vmid int, errorLines []string, failedVMIDs []int) {
+	fingerprint := BackupAlertFingerprint(run.Cluster, run.Node, vmid)
+	if _, err := m.store.GetOpenAlertByFingerprint(fingerprint); !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	subject := fmt.Sprintf("des invités du nœud %s", run.Node)
+	labels := map[string]string{"alertname": "BackupFailed", "kind": "backup", "cluster": run.Cluster, "node": run.Node, "id": "job", "upid": run.ID}
+	if vmid > 0 {
+		subject = guestLabel(snapshot, run.Cluster, vmid)
+		labels["id"] = strconv.Itoa(vmid)
+	}
+	message := fmt.Sprintf("La tâche %s s'est terminée en erreur : %s", run.ID, run.ExitStatus)
+	if len(errorLines) > 0 {
+		message += "\n" + strings.Join(errorLines, "\n")
+	}
+	if failedVMIDs == nil {
+		failedVMIDs = []int{}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"upid":         run.ID,
+		"cluster":      run.Cluster,
+		"node":         run.Node,
+		"vmid":         vmid,
+		"exitstatus":   run.ExitStatus,
+		"errors":       errorLines,
+		"failed_vmids": failedVMIDs,
+	})
+	payload := string(data)
+
+	createdAt := time.Now()
+	if run.CompletedAt != nil {
+		createdAt = *run.CompletedAt
+	}
+	alert := &models.Alert{
+		Source:      BackupAlertSourcePrefix + run.Cluster,
+		Severity:    "high",
+		Title:       fmt.Sprintf("Sauvegarde %s échouée", subject),
+		Message:     message,
+		Payload:     &payload,
+		Labels:      labels,
+		CreatedAt:   createdAt,
+		Status:      models.AlertStatusFiring,
+		Fingerprint: fingerprint,
+	}
+	if err := m.store.CreateAlert(alert); err != nil {
+		log.Printf("⚠️  Failed to create backup alert for task %s: %v", run.ID, err)
+		return
+	}
+	log.Printf("🚨 Alerte %d déclenchée: %s", alert.ID, alert.Title)
+	m.notify(alert)
+}
+
+// resolve résout les alertes qu'une sauvegarde réussie couvre : celle de son invité, ou pour une tâche couvrant
+// plusieurs invités celle de la tâche du nœud et celles des invités que son journal donne sauvegardés.
+// Le journal n'est lu que si des alertes d'invités sont ouvertes sur le cluster.
+func (m *BackupMonitor) resolve(run models.ProxmoxTask) {
+	vmid := runVMID(run)
+	m.resolveFingerprint(run, BackupAlertFingerprint(run.Cluster, run.Node, vmid))
+	if vmid > 0 {
+		return
+	}
+
+	open, err := m.store.GetOpenAlertsBySource(BackupAlertSourcePrefix + run.Cluster)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	for _, alert := range open {
+		if alert.Labels["id"] != "job" {
+			m.resolveGuests(run, m.taskLog(run).finished)
+			return
+		}
+	}
+}
+
+// resolveGuests résout les alertes ouvertes des invités sauvegardés par une tâche
+func (m *BackupMonitor) resolveGuests(run models.ProxmoxTask, vmids []int) {
+	for _, vmid := range vmids {
+		m.resolveFingerprint(run, BackupAlertFingerprint(run.Cluster, run.Node, vmid))
+	}
+}
+
+// resolveFingerprint résout l'alerte ouverte d'un périmètre à la fin de la tâche
+func (m *BackupMonitor) resolveFingerprint(run models.ProxmoxTask, fingerprint string) {
+	open, err := m.store.GetOpenAlertByFingerprint(fingerprint)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	at := time.Now()
+	if run.CompletedAt != nil {
+		at = *run.CompletedAt
+	}
+	if err := m.store.ResolveAlert(open, "", at); err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	log.Printf("✅ Alerte %d résolue: %s", open.ID, open.Title)
+	m.notify(open)
+}
+
+// taskLog lit le journal d'une tâche vzdump et en extrait les erreurs et les invités en échec ou sauvegardés.
+// Le journal est lu avec la connexion du cluster ; une erreur de lecture laisse l'alerte sans extrait.
+func (m *BackupMonitor) taskLog(run models.ProxmoxTask) backupLog {
+	var result backupLog
+	conns, err := m.store.GetProxmoxConnections()
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return result
+	}
+	for _, cluster := range inventory.ClustersFromConnections(conns, nil) {
+		if cluster.Name != run.Cluster {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), backupLogTimeout)
+		defer cancel()
+		lines, err := cluster.Client.TaskLog(ctx, run.Node, run.ID, 0, backupLogMaxLines)
+		if err != nil {
+			log.Printf("⚠️  Failed to read log of backup task %s: %v", run.ID, err)
+			return result
+		}
+
+		result.guestErrors = map[int][]string{}
+		for _, line := range lines {
+			if match := backupFinishedPattern.FindStringSubmatch(line.T); match != nil {
+				vmid, _ := strconv.Atoi(match[1])
+				result.finished = append(result.finished, vmid)
+				continue
+			}
+			if !strings.HasPrefix(line.T, "ERROR:") {
+				continue
+			}
+			if len(result.errors) < backupLogExcerpt {
+				result.errors = append(result.errors, line.T)
+			}
+			if match := backupErrorPattern.FindStringSubmatch(line.T); match != nil {
+				vmid, _ := strconv.Atoi(match[1])
+				if _, seen := result.guestErrors[vmid]; !seen {
+					result.failed = append(result.failed, vmid)
+				}
+				if len(result.guestErrors[vmid]) < backupLogExcerpt {
+					result.guestErrors[vmid] = append(result.guestErrors[vmid], line.T)
+				}
+			}
+		}
+		return result
+	}
+	return result
+}
+
+// guestLabel nomme un invité de l'inventaire dans les alertes (ex: "de la VM web-01 (100)")
+func guestLabel(snapshot *models.ProxmoxSnapshot, cluster string, vmid int) string {
+	for _, guests := range [][]models.ProxmoxGuest{snapshot.VMs, snapshot.LXC} {
+		for _, g := range guests {
+			if g.Cluster != cluster || g.VMID != vmid {
+				continue
+			}
+			if g.Type == "lxc" {
+				return fmt.Sprintf("du conteneur %s (%d)", g.Name, vmid)
+			}
+			return fmt.Sprintf("de la VM %s (%d)", g.Name, vmid)
+		}
+	}
+	return fmt.Sprintf("de l'invité %d", vmid)
+}
+
+// notify transmet une alerte déclenchée ou résolue aux listeners
+func (m *BackupMonitor) notify(alert *models.Alert) {
+	for _, listener := range m.alerts {
+		listener(alert)
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/email"
+	"proxmox-dashboard/internal/models"
+)
+
+// backupReportTick est la granularité du planificateur du rapport quotidien
+const backupReportTick = time.Minute
+
+// ErrNoInventory indique qu'aucun inventaire n'a encore été collecté par le poller
+var ErrNoInventory = errors.New("no inventory collected yet")
+
+// BackupReporter envoie chaque jour à heure fixe le rapport de conformité des sauvegardes, calculé sur le
+// dernier snapshot du poller, aux destinataires configurés via la file d'emails. Tant qu'aucun inventaire
+// n'est disponible, l'envoi est retenté au tick suivant.
+type BackupReporter struct {
+	policy     *models.BackupPolicy
+	snapshot   func() *models.ProxmoxSnapshot
+	email      *email.Worker
+	recipients []string
+	hour       int
+	url        string
+	quit       chan bool
+
+	mu   sync.Mutex
+	next time.Time // prochain envoi
+}
+
+// NewBackupReporter crée le planificateur du rapport quotidien envoyé à hour (heure locale) aux destinataires
+func NewBackupReporter(policy *models.BackupPolicy, snapshot func() *models.ProxmoxSnapshot, worker *email.Worker, recipients []string, hour int) *BackupReporter {
+	r := &BackupReporter{
+		policy:     policy,
+		snapshot:   snapshot,
+		email:      worker,
+		recipients: recipients,
+		hour:       hour,
+		quit:       make(chan bool),
+	}
+	r.next = r.NextRun(time.Now())
+	return r
+}
+
+// SetPublicURL configure le lien vers le dashboard inclus dans le rapport
+func (r *BackupReporter) SetPublicURL(url string) {
+	r.url = url
+}
+
+// NextRun retourne le premier envoi planifié après now
+func (r *BackupReporter) NextRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), r.hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Start démarre l'envoi quotidien
+func (r *BackupReporter) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log.Printf("Starting backup compliance report (next at %s)...", r.next.Format("2006-01-02 15:04"))
+	go r.run()
+}
+
+// Stop arrête l'envoi quotidien
+func (r *BackupReporter) Stop() {
+	r.quit <- true
+}
+
+// run est la boucle principale du planificateur
+func (r *BackupReporter) run() {
+	ticker := time.NewTicker(backupReportTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.RunDue(time.Now())
+		case <-r.quit:
+			log.Println("Backup compliance report stopped")
+			return
+		}
+	}
+}
+
+// RunDue envoie le rapport si son heure est passée, puis planifie l'envoi du lendemain
+func (r *BackupReporter) RunDue(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.next) {
+		return
+	}
+
+	if _, err := r.Send(now); err != nil {
+		log.Printf("⚠️  Failed to send backup compliance report: %v", err)
+		if errors.Is(err, ErrNoInventory) {
+			return
+		}
+	}
+	r.next = r.NextRun(now)
+}
+
+// Send calcule le rapport sur le dernier snapshot et le met en file d'attente pour chaque destinataire
+func (r *BackupReporter) Send(now time.Time) (*models.BackupComplianceReport, error) {
+	snapshot := r.snapshot()
+	if snapshot == nil {
+		return nil, ErrNoInventory
+	}
+
+	report := models.BuildBackupComplianceReport(&snapshot.ProxmoxInventory, r.policy, now)
+	var errs []error
+	for _, to := range r.recipients {
+		if _, err := r.email.SendBackupReportEmail(to, report, r.url); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	log.Printf("📧 Rapport de conformité des sauvegardes envoyé à %d destinataire(s): %d/%d invités conformes",
+		len(r.recipients)-len(errs), report.Compliant, report.Total)
+	return report, errors.Join(errs...)
+}
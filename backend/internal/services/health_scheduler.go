@@ -0,0 +1,332 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+)
+
+// healthSchedulerTick est la granularité du planificateur : chaque tick vérifie les applications échues
+const healthSchedulerTick = 5 * time.Second
+
+// maxConcurrentHealthChecks limite le nombre de vérifications simultanées
+const maxConcurrentHealthChecks = 8
+
+// healthPruneInterval est l'intervalle minimal entre deux purges de l'historique des health checks
+const healthPruneInterval = time.Hour
+
+// HealthAlertSourcePrefix préfixe la source des alertes des health checks ("health:Grafana")
+const HealthAlertSourcePrefix = "health:"
+
+// DefaultHealthFailureThreshold est le nombre d'échecs consécutifs avant l'alerte d'indisponibilité
+const DefaultHealthFailureThreshold = 3
+
+// healthAlertFingerprintPrefix préfixe l'empreinte des alertes d'indisponibilité des applications
+const healthAlertFingerprintPrefix = "health:app/"
+
+// HealthAlertFingerprint retourne l'empreinte de l'alerte d'indisponibilité d'une application
+func HealthAlertFingerprint(appID int) string {
+	return healthAlertFingerprintPrefix + strconv.Itoa(appID)
+}
+
+// HealthListener est appelé après chaque health check planifié
+type HealthListener func(app *models.App, status *models.HealthStatus)
+
+// HealthScheduler vérifie périodiquement la santé de chaque application selon son intervalle
+// (CheckInterval, à défaut l'intervalle du planificateur) et enregistre les résultats dans health_checks.
+// Plusieurs échecs consécutifs déclenchent une alerte, le retour à online la résout ; l'état est déduit de
+// l'alerte ouverte (fingerprint), ce qui le conserve d'un redémarrage à l'autre. Le compte des échecs reste
+// en mémoire : après un redémarrage, une application toujours hors ligne sans alerte repart de zéro.
+type HealthScheduler struct {
+	store     *store.Store
+	apps      *AppService
+	interval  time.Duration
+	quit      chan bool
+	wg        sync.WaitGroup
+	slots     chan struct{}
+	listeners []HealthListener
+	alerts    []func(alert *models.Alert)
+
+	threshold int // échecs consécutifs avant l'alerte
+
+	mu         sync.Mutex
+	next       map[int]time.Time // prochaine vérification de chaque application
+	running    map[int]bool
+	failures   map[int]int // échecs consécutifs de chaque application
+	reconciled bool        // alertes des applications supprimées avant le démarrage résolues
+	lastPrune  time.Time
+}
+
+// NewHealthScheduler crée un planificateur de health checks avec l'intervalle par défaut des applications
+func NewHealthScheduler(store *store.Store, interval time.Duration) *HealthScheduler {
+	return &HealthScheduler{
+		store:     store,
+		apps:      NewAppService(store),
+		interval:  interval,
+		threshold: DefaultHealthFailureThreshold,
+		quit:      make(chan bool),
+		slots:     make(chan struct{}, maxConcurrentHealthChecks),
+		next:      map[int]time.Time{},
+		running:   map[int]bool{},
+		failures:  map[int]int{},
+	}
+}
+
+// SetFailureThreshold configure le nombre d'échecs consécutifs avant l'alerte d'indisponibilité (1 au minimum, à appeler avant Start)
+func (s *HealthScheduler) SetFailureThreshold(threshold int) {
+	if threshold < 1 {
+		threshold = 1
+	}
+	s.threshold = threshold
+}
+
+// OnResult enregistre un listener appelé après chaque vérification (à appeler avant Start)
+func (s *HealthScheduler) OnResult(listener HealthListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// OnAlert enregistre un listener appelé pour les alertes déclenchées et résolues (à appeler avant Start)
+func (s *HealthScheduler) OnAlert(listener func(alert *models.Alert)) {
+	s.alerts = append(s.alerts, listener)
+}
+
+// Interval retourne l'intervalle effectif des vérifications d'une application
+func (s *HealthScheduler) Interval(app *models.App) time.Duration {
+	if app.CheckInterval > 0 {
+		return time.Duration(app.CheckInterval) * time.Second
+	}
+	return s.interval
+}
+
+// Start démarre les vérifications périodiques
+func (s *HealthScheduler) Start() {
+	log.Printf("Starting health check scheduler (every %s by default)...", s.interval)
+	go s.run()
+}
+
+// Stop arrête le planificateur et attend la fin des vérifications en cours
+func (s *HealthScheduler) Stop() {
+	s.quit <- true
+	s.wg.Wait()
+}
+
+// run est la boucle principale du planificateur
+func (s *HealthScheduler) run() {
+	s.RunDue(time.Now())
+
+	ticker := time.NewTicker(healthSchedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.RunDue(time.Now())
+		case <-s.quit:
+			log.Println("Health check scheduler stopped")
+			return
+		}
+	}
+}
+
+// RunDue lance en arrière-plan la vérification des applications échues à now et résout les alertes des
+// applications supprimées, y compris au premier passage celles supprimées pendant l'arrêt du planificateur
+func (s *HealthScheduler) RunDue(now time.Time) {
+	apps, err := s.store.GetApps()
+	if err != nil {
+		log.Printf("⚠️  Failed to get apps for health checks: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	known := make(map[int]bool, len(apps))
+	var due []*models.App
+	for _, app := range apps {
+		known[app.ID] = true
+		if s.running[app.ID] || now.Before(s.next[app.ID]) {
+			continue
+		}
+		s.running[app.ID] = true
+		s.next[app.ID] = now.Add(s.Interval(app))
+		due = append(due, app)
+	}
+	var removed []int
+	for id := range s.next {
+		if !known[id] {
+			delete(s.next, id)
+			delete(s.failures, id)
+			removed = append(removed, id)
+		}
+	}
+	reconcile := !s.reconciled
+	s.reconciled = true
+	prune := now.Sub(s.lastPrune) >= healthPruneInterval
+	if prune {
+		s.lastPrune = now
+	}
+	s.mu.Unlock()
+
+	for _, app := range due {
+		s.wg.Add(1)
+		go func(app *models.App) {
+			defer s.wg.Done()
+			s.slots <- struct{}{}
+			defer func() { <-s.slots }()
+
+			s.Check(app)
+
+			s.mu.Lock()
+			delete(s.running, app.ID)
+			s.mu.Unlock()
+		}(app)
+	}
+
+	for _, id := range removed {
+		s.resolve(id, now)
+	}
+	if reconcile {
+		s.resolveOrphans(known, now)
+	}
+	if prune {
+		if n, err := s.store.PruneHealthChecks(now.Add(-models.HealthCheckRetention)); err != nil {
+			log.Printf("⚠️  %v", err)
+		} else if n > 0 {
+			log.Printf("🧹 %d health checks purgés", n)
+		}
+	}
+}
+
+// Check vérifie immédiatement une application, enregistre le résultat et déclenche ou résout son alerte
+func (s *HealthScheduler) Check(app *models.App) *models.HealthStatus {
+	status, err := s.apps.CheckHealth(app)
+	if err != nil {
+		log.Printf("⚠️  Health check of app %d failed: %v", app.ID, err)
+		return nil
+	}
+	if err := s.store.RecordHealthCheck(models.NewHealthCheck(status)); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	for _, listener := range s.listeners {
+		listener(app, status)
+	}
+
+	s.mu.Lock()
+	if status.Status == "online" {
+		delete(s.failures, app.ID)
+	} else {
+		s.failures[app.ID]++
+	}
+	failures := s.failures[app.ID]
+	s.mu.Unlock()
+
+	if status.Status == "online" {
+		s.resolve(app.ID, status.LastCheck)
+	} else if failures >= s.threshold {
+		s.fire(app, status, failures)
+	}
+	return status
+}
+
+// fire crée l'alerte d'indisponibilité d'une application, sauf si elle est déjà ouverte
+func (s *HealthScheduler) fire(app *models.App, status *models.HealthStatus, failures int) {
+	fingerprint := HealthAlertFingerprint(app.ID)
+	if _, err := s.store.GetOpenAlertByFingerprint(fingerprint); !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	reason := "aucune réponse"
+	switch {
+	case status.Error != nil:
+		reason = *status.Error
+	case status.StatusCode != nil:
+		reason = fmt.Sprintf("HTTP %d", *status.StatusCode)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"app_id":      app.ID,
+		"health_type": app.HealthType,
+		"host":        app.Host,
+		"port":        app.Port,
+		"status_code": status.StatusCode,
+		"error":       status.Error,
+		"failures":    failures,
+	})
+	payload := string(data)
+
+	labels := map[string]string{"alertname": "AppDown", "kind": "app", "id": strconv.Itoa(app.ID), "name": app.Name}
+	if app.Tag != nil && *app.Tag != "" {
+		labels["tag"] = *app.Tag
+	}
+	alert := &models.Alert{
+		Source:      HealthAlertSourcePrefix + app.Name,
+		Severity:    "high",
+		Title:       fmt.Sprintf("Application %s injoignable", app.Name),
+		Message:     fmt.Sprintf("Le health check %s de %s:%d a échoué %d fois de suite : %s", app.HealthType, app.Host, app.Port, failures, reason),
+		Payload:     &payload,
+		Labels:      labels,
+		CreatedAt:   status.LastCheck,
+		Status:      models.AlertStatusFiring,
+		Fingerprint: fingerprint,
+	}
+	if err := s.store.CreateAlert(alert); err != nil {
+		log.Printf("⚠️  Failed to create health alert for app %d: %v", app.ID, err)
+		return
+	}
+	log.Printf("🚨 Alerte %d déclenchée: %s", alert.ID, alert.Title)
+	s.notify(alert)
+}
+
+// resolve résout l'alerte d'indisponibilité ouverte d'une application
+func (s *HealthScheduler) resolve(appID int, at time.Time) {
+	open, err := s.store.GetOpenAlertByFingerprint(HealthAlertFingerprint(appID))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	s.resolveAlert(open, at)
+}
+
+// resolveOrphans résout les alertes d'indisponibilité ouvertes des applications qui n'existent plus
+func (s *HealthScheduler) resolveOrphans(known map[int]bool, at time.Time) {
+	open, err := s.store.GetOpenAlertsByFingerprintPrefix(healthAlertFingerprintPrefix)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	for _, alert := range open {
+		id, err := strconv.Atoi(strings.TrimPrefix(alert.Fingerprint, healthAlertFingerprintPrefix))
+		if err != nil || known[id] {
+			continue
+		}
+		s.resolveAlert(alert, at)
+	}
+}
+
+// resolveAlert résout une alerte d'indisponibilité et la transmet aux listeners
+func (s *HealthScheduler) resolveAlert(alert *models.Alert, at time.Time) {
+	if err := s.store.ResolveAlert(alert, "", at); err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	log.Printf("✅ Alerte %d résolue: %s", alert.ID, alert.Title)
+	s.notify(alert)
+}
+
+// notify transmet une alerte déclenchée ou résolue aux listeners
+func (s *HealthScheduler) notify(alert *models.Alert) {
+	for _, listener := range s.alerts {
+		listener(alert)
+	}
+}
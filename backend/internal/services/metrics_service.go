@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/inventory"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/store"
+)
+
+// metricsPruneInterval est l'intervalle minimal entre deux purges de l'historique
+const metricsPruneInterval = 10 * time.Minute
+
+// maxMetricPoints est le nombre de points visé quand aucun pas n'est demandé
+const maxMetricPoints = 500
+
+// Limites de l'import rrddata : appels simultanés par cluster et délai de chaque appel,
+// pour que l'import d'un cluster de plusieurs centaines d'invités reste sous le délai des requêtes
+const (
+	backfillConcurrency = 4
+	backfillCallTimeout = 10 * time.Second
+)
+
+// rrdTimeframes liste les périodes acceptées par les endpoints rrddata de Proxmox
+var rrdTimeframes = map[string]bool{"hour": true, "day": true, "week": true, "month": true, "year": true}
+
+// IsRRDTimeframe indique si timeframe est une période rrddata valide
+func IsRRDTimeframe(timeframe string) bool {
+	return rrdTimeframes[timeframe]
+}
+
+// ErrInvalidMetricsQuery est retourné quand les paramètres d'une requête d'historique sont invalides
+var ErrInvalidMetricsQuery = errors.New("invalid metrics query")
+
+// MetricsService gère l'historique des métriques des nœuds, invités et storages
+type MetricsService struct {
+	store     *store.Store
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewMetricsService crée une nouvelle instance de MetricsService
+func NewMetricsService(store *store.Store) *MetricsService {
+	return &MetricsService{store: store}
+}
+
+// Ingest enregistre les métriques d'un snapshot d'inventaire puis purge périodiquement l'historique expiré
+func (s *MetricsService) Ingest(snapshot *models.ProxmoxSnapshot) error {
+	if err := s.store.RecordMetricSamples(SamplesFromSnapshot(snapshot)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastPrune) < metricsPruneInterval {
+		return nil
+	}
+	s.lastPrune = time.Now()
+
+	deleted, err := s.store.PruneMetrics(time.Now())
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("🧹 %d point(s) de métriques expirés supprimés", deleted)
+	}
+	return nil
+}
+
+// SamplesFromSnapshot extrait les mesures d'un snapshot.
+// Les nœuds hors ligne sont ignorés : ils ne remontent aucune mesure.
+func SamplesFromSnapshot(snapshot *models.ProxmoxSnapshot) []models.MetricSample {
+	var samples []models.MetricSample
+	at := snapshot.CollectedAt
+
+	for _, n := range snapshot.Nodes {
+		if n.Status != "online" {
+			continue
+		}
+		samples = append(samples, models.MetricSample{
+			Kind: "node", Cluster: n.Cluster, ObjectID: n.Name, Timestamp: at,
+			CPU: n.CPUUsage, Memory: n.MemoryUsage, Disk: n.DiskUsage,
+		})
+	}
+	for _, guests := range [][]models.ProxmoxGuest{snapshot.VMs, snapshot.LXC} {
+		for _, g := range guests {
+			samples = append(samples, models.MetricSample{
+				Kind: g.Type, Cluster: g.Cluster, ObjectID: strconv.Itoa(g.VMID), Timestamp: at,
+				CPU: g.CPUUsage, Memory: g.MemoryUsage, Disk: g.DiskUsage,
+			})
+		}
+	}
+	for _, st := range snapshot.Storages {
+		samples = append(samples, models.MetricSample{
+			Kind: "storage", Cluster: st.Cluster, ObjectID: st.ID, Timestamp: at,
+			Disk: st.UsagePercent,
+		})
+	}
+	return samples
+}
+
+// resolutionFor choisit la résolution stockée la plus fine couvrant le début de la période et le pas demandé
+func resolutionFor(from time.Time, step time.Duration, now time.Time) int {
+	age := now.Sub(from)
+	switch {
+	case age > models.MetricsRetention[models.MetricsResolution5m] || step >= time.Hour:
+		return models.MetricsResolutionHourly
+	case age > models.MetricsRetention[models.MetricsResolutionRaw] || step >= 5*time.Minute:
+		return models.MetricsResolution5m
+	default:
+		return models.MetricsResolutionRaw
+	}
+}
+
+// Query retourne l'historique d'un objet. Sans pas explicite, le pas est choisi pour
+// obtenir au plus maxMetricPoints points, sans descendre sous la minute.
+func (s *MetricsService) Query(q models.MetricsQuery) (*models.MetricSeries, error) {
+	now := time.Now()
+	if q.Step == 0 {
+		q.Step = max(q.To.Sub(q.From)/maxMetricPoints, time.Minute)
+	}
+	if err := q.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetricsQuery, err)
+	}
+
+	q.Resolution = resolutionFor(q.From, q.Step, now)
+	// Un pas plus fin que la résolution stockée n'apporterait que des trous
+	if minStep := time.Duration(max(q.Resolution, 60)) * time.Second; q.Step < minStep {
+		q.Step = minStep
+	}
+	q.Step = q.Step.Truncate(time.Second)
+
+	points, err := s.store.QueryMetrics(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MetricSeries{
+		Kind:       q.Kind,
+		ID:         q.ObjectID,
+		Cluster:    q.Cluster,
+		From:       q.From,
+		To:         q.To,
+		Step:       int64(q.Step / time.Second),
+		Resolution: q.Resolution,
+		Points:     points,
+	}, nil
+}
+
+// Backfill importe l'historique rrddata des nœuds et invités d'un cluster, avec au plus
+// backfillConcurrency appels simultanés. Un objet dont l'historique ne peut pas être lu est ignoré.
+// Les intervalles déjà renseignés sont conservés ; retourne le nombre de points importés.
+func (s *MetricsService) Backfill(ctx context.Context, cluster inventory.Cluster, timeframe string) (int, error) {
+	if !IsRRDTimeframe(timeframe) {
+		return 0, fmt.Errorf("%w: timeframe must be one of hour, day, week, month, year", ErrInvalidMetricsQuery)
+	}
+
+	resources, err := cluster.Client.ClusterResources(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu      sync.Mutex
+		samples []models.MetricSample
+	)
+	inventory.ForEach(len(resources), backfillConcurrency, func(i int) {
+		res := resources[i]
+		var kind, id string
+		var fetch func(ctx context.Context) ([]proxmox.RRDPoint, error)
+		switch {
+		case res.Type == "node" && res.Status == "online":
+			kind, id = "node", res.Node
+			fetch = func(ctx context.Context) ([]proxmox.RRDPoint, error) {
+				return cluster.Client.NodeRRDData(ctx, res.Node, timeframe)
+			}
+		case (res.Type == "qemu" || res.Type == "lxc") && !bool(res.Template):
+			kind, id = res.Type, strconv.Itoa(int(res.VMID))
+			fetch = func(ctx context.Context) ([]proxmox.RRDPoint, error) {
+				return cluster.Client.GuestRRDData(ctx, res.Node, proxmox.GuestType(res.Type), int(res.VMID), timeframe)
+			}
+		default:
+			return
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, backfillCallTimeout)
+		defer cancel()
+		points, err := fetch(callCtx)
+		if err != nil {
+			log.Printf("⚠️  rrddata indisponible pour %s %s: %v", kind, id, err)
+			return
+		}
+		mu.Lock()
+		samples = append(samples, rrdSamples(kind, cluster.Name, id, points)...)
+		mu.Unlock()
+	})
+
+	return s.store.BackfillMetricSamples(samples)
+}
+
+// rrdSamples convertit des points rrddata en mesures (les intervalles sans donnée sont ignorés)
+func rrdSamples(kind, cluster, id string, points []proxmox.RRDPoint) []models.MetricSample {
+	samples := make([]models.MetricSample, 0, len(points))
+	for _, p := range points {
+		if p.CPU == nil {
+			continue
+		}
+		sample := models.MetricSample{
+			Kind:      kind,
+			Cluster:   cluster,
+			ObjectID:  id,
+			Timestamp: time.Unix(p.Time, 0),
+			CPU:       round2(*p.CPU * 100),
+		}
+		if kind == "node" {
+			sample.Memory = ratio(p.MemUsed, p.MemTotal)
+			sample.Disk = ratio(p.RootUsed, p.RootTotal)
+		} else {
+			sample.Memory = ratio(p.Mem, p.MaxMem)
+			sample.Disk = ratio(p.Disk, p.MaxDisk)
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// ratio calcule un pourcentage arrondi à deux décimales
+func ratio(used, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round2(used / total * 100)
+}
+
+// round2 arrondit une valeur à deux décimales
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
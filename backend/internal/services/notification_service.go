@@ -49,7 +49,7 @@ func (s *NotificationService) SendTestEmail(req models.NotifyTestRequest) error
 		ToAddr:    req.To,
 		Subject:   "Test Email from ProxmoxDash",
 		BodyText:  "This is a test email to verify your notification settings.",
-		State:     "pending",
+		State:     models.EmailStatePending,
 		CreatedAt: time.Now(),
 	}
 
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+	"proxmox-dashboard/internal/store"
+)
+
+// provisionTaskPollInterval est l'intervalle de suivi des tâches Proxmox d'un job
+const provisionTaskPollInterval = 2 * time.Second
+
+// provisionTaskTimeout borne la durée d'une tâche de clonage ou de démarrage
+const provisionTaskTimeout = 2 * time.Hour
+
+// provisionSubscriberBuffer est la taille du tampon d'un abonné aux mises à jour d'un job
+const provisionSubscriberBuffer = 16
+
+// Avancement (%) associé à chaque étape ; la tâche de clonage occupe l'intervalle cloneProgressStart-cloneProgressEnd
+const (
+	allocateProgress   = 5
+	cloneProgressStart = 10
+	cloneProgressEnd   = 80
+	configureProgress  = 85
+	startProgress      = 90
+)
+
+// taskPercentPattern extrait le pourcentage des lignes de journal de copie ("transferred 2.0 GiB of 32.0 GiB (6.25%)")
+var taskPercentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(?:/100)?%`)
+
+// Provisioner exécute les jobs de provisionnement en arrière-plan : allocation du VMID, clonage du template,
+// cloud-init et démarrage optionnel. Chaque étape est enregistrée dans provision_jobs et diffusée aux abonnés du job.
+type Provisioner struct {
+	store        *store.Store
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+
+	mu          sync.Mutex
+	subscribers map[int][]chan models.ProvisionJob
+}
+
+// NewProvisioner crée le service de provisionnement
+func NewProvisioner(store *store.Store) *Provisioner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Provisioner{
+		store:        store,
+		pollInterval: provisionTaskPollInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+		subscribers:  map[int][]chan models.ProvisionJob{},
+	}
+}
+
+// SetPollInterval modifie l'intervalle de suivi des tâches Proxmox
+func (p *Provisioner) SetPollInterval(interval time.Duration) {
+	p.pollInterval = interval
+}
+
+// RecoverInterrupted marque en échec les jobs interrompus par un arrêt du serveur
+// (les identifiants Proxmox ne sont pas conservés : un job ne peut pas reprendre)
+func (p *Provisioner) RecoverInterrupted() {
+	if n, err := p.store.FailInterruptedProvisionJobs(time.Now()); err != nil {
+		log.Printf("⚠️  %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️  %d jobs de provisionnement interrompus marqués en échec", n)
+	}
+}
+
+// Stop annule les jobs en cours et attend leur fin
+func (p *Provisioner) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Submit enregistre un job de provisionnement et l'exécute en arrière-plan avec le client Proxmox fourni
+func (p *Provisioner) Submit(client *proxmox.Client, spec models.ProvisionSpec, connectionID, createdBy *int) (*models.ProvisionJob, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.ProvisionJob{
+		ConnectionID: connectionID,
+		Spec:         spec.Sanitized(),
+		Status:       models.ProvisionJobPending,
+		Step:         models.ProvisionStepAllocate,
+		VMID:         spec.VMID,
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := p.store.CreateProvisionJob(job); err != nil {
+		return nil, err
+	}
+
+	p.wg.Add(1)
+	go func(job models.ProvisionJob) {
+		defer p.wg.Done()
+		p.run(client, &job, spec)
+	}(*job)
+	return job, nil
+}
+
+// Subscribe retourne un canal recevant chaque mise à jour du job, fermé à la fin du job,
+// et la fonction de désabonnement
+func (p *Provisioner) Subscribe(jobID int) (<-chan models.ProvisionJob, func()) {
+	ch := make(chan models.ProvisionJob, provisionSubscriberBuffer)
+	p.mu.Lock()
+	p.subscribers[jobID] = append(p.subscribers[jobID], ch)
+	p.mu.Unlock()
+
+	return ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		subs := p.subscribers[jobID]
+		for i, sub := range subs {
+			if sub == ch {
+				p.subscribers[jobID] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(p.subscribers[jobID]) == 0 {
+			delete(p.subscribers, jobID)
+		}
+	}
+}
+
+// publish enregistre l'état du job et le diffuse à ses abonnés ; les abonnés sont détachés à la fin du job
+func (p *Provisioner) publish(job *models.ProvisionJob) {
+	job.UpdatedAt = time.Now()
+	if err := p.store.UpdateProvisionJob(job); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ch := range p.subscribers[job.ID] {
+		select {
+		case ch <- *job:
+		default:
+			// abonné trop lent : il relira l'état final à la fermeture du canal
+		}
+		if job.Finished() {
+			close(ch)
+		}
+	}
+	if job.Finished() {
+		delete(p.subscribers, job.ID)
+	}
+}
+
+// run exécute les étapes d'un job ; spec contient le mot de passe cloud-init, absent de job.Spec
+func (p *Provisioner) run(client *proxmox.Client, job *models.ProvisionJob, spec models.ProvisionSpec) {
+	ctx := p.ctx
+	guestType := proxmox.GuestType(spec.GuestType)
+	target := spec.Target()
+
+	job.Status = models.ProvisionJobRunning
+	p.publish(job)
+
+	fail := func(step string, err error) {
+		now := time.Now()
+		job.Status = models.ProvisionJobFailed
+		job.Error = fmt.Sprintf("%s: %v", step, err)
+		job.FinishedAt = &now
+		log.Printf("❌ Provisionnement %d (%s) en échec: %s", job.ID, spec.Name, job.Error)
+		p.publish(job)
+	}
+
+	if job.VMID == 0 {
+		vmid, err := client.NextID(ctx)
+		if err != nil {
+			fail(models.ProvisionStepAllocate, err)
+			return
+		}
+		job.VMID = vmid
+	}
+	job.Progress = allocateProgress
+	p.publish(job)
+
+	job.Step = models.ProvisionStepClone
+	job.Progress = cloneProgressStart
+	upid, err := client.CloneGuest(ctx, spec.Node, guestType, spec.TemplateVMID, cloneParams(spec, job.VMID))
+	if err != nil {
+		fail(models.ProvisionStepClone, err)
+		return
+	}
+	job.UPID = upid
+	p.publish(job)
+	if err := p.waitTask(ctx, client, job, spec.Node, cloneProgressStart, cloneProgressEnd); err != nil {
+		fail(models.ProvisionStepClone, err)
+		return
+	}
+
+	if params := cloudInitParams(spec.CloudInit); len(params) > 0 {
+		job.Step = models.ProvisionStepConfigure
+		if err := client.UpdateGuestConfig(ctx, target, guestType, job.VMID, params); err != nil {
+			fail(models.ProvisionStepConfigure, err)
+			return
+		}
+		job.Progress = configureProgress
+		p.publish(job)
+	}
+
+	if spec.Start {
+		job.Step = models.ProvisionStepStart
+		job.Progress = startProgress
+		upid, err := client.GuestStatusAction(ctx, target, guestType, job.VMID, "start", nil)
+		if err != nil {
+			fail(models.ProvisionStepStart, err)
+			return
+		}
+		job.UPID = upid
+		p.publish(job)
+		if err := p.waitTask(ctx, client, job, target, startProgress, startProgress); err != nil {
+			fail(models.ProvisionStepStart, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.ProvisionJobSucceeded
+	job.Step = models.ProvisionStepDone
+	job.Progress = 100
+	job.FinishedAt = &now
+	log.Printf("✅ Provisionnement %d terminé: %s (%s/%d) sur %s", job.ID, spec.Name, guestType, job.VMID, target)
+	p.publish(job)
+}
+
+// waitTask suit la tâche job.UPID jusqu'à sa fin et convertit le pourcentage de son journal
+// en avancement du job entre from et to
+func (p *Provisioner) waitTask(ctx context.Context, client *proxmox.Client, job *models.ProvisionJob, node string, from, to int) error {
+	ctx, cancel := context.WithTimeout(ctx, provisionTaskTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	logStart := 0
+	for {
+		status, err := client.TaskStatus(ctx, node, job.UPID)
+		if err != nil {
+			return err
+		}
+
+		if to > from {
+			if lines, err := client.TaskLog(ctx, node, job.UPID, logStart, 500); err == nil && len(lines) > 0 {
+				logStart = lines[len(lines)-1].N
+				if percent, ok := lastTaskPercent(lines); ok {
+					if progress := from + int(percent*float64(to-from)/100); progress > job.Progress {
+						job.Progress = progress
+						p.publish(job)
+					}
+				}
+			}
+		}
+
+		if !status.Running() {
+			if !status.Succeeded() {
+				return fmt.Errorf("task %s failed: %s", job.UPID, status.ExitStatus)
+			}
+			job.Progress = max(job.Progress, to)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastTaskPercent retourne le dernier pourcentage d'avancement trouvé dans des lignes de journal
+func lastTaskPercent(lines []proxmox.TaskLogLine) (float64, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		matches := taskPercentPattern.FindAllStringSubmatch(lines[i].T, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		percent, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+		if err == nil && percent <= 100 {
+			return percent, true
+		}
+	}
+	return 0, false
+}
+
+// cloneParams construit les paramètres de nodes/{node}/{type}/{vmid}/clone
+func cloneParams(spec models.ProvisionSpec, vmid int) url.Values {
+	params := url.Values{"newid": {strconv.Itoa(vmid)}}
+	if spec.GuestType == string(proxmox.GuestLXC) {
+		params.Set("hostname", spec.Name)
+	} else {
+		params.Set("name", spec.Name)
+	}
+	if spec.Full {
+		params.Set("full", "1")
+	} else {
+		params.Set("full", "0")
+	}
+	if spec.TargetNode != "" && spec.TargetNode != spec.Node {
+		params.Set("target", spec.TargetNode)
+	}
+	if spec.Storage != "" {
+		params.Set("storage", spec.Storage)
+	}
+	if spec.Pool != "" {
+		params.Set("pool", spec.Pool)
+	}
+	if spec.Description != "" {
+		params.Set("description", spec.Description)
+	}
+	return params
+}
+
+// cloudInitParams construit les paramètres cloud-init de la configuration d'une VM (vides sans cloud-init)
+func cloudInitParams(ci *models.CloudInitConfig) url.Values {
+	params := url.Values{}
+	if ci == nil {
+		return params
+	}
+	if ci.User != "" {
+		params.Set("ciuser", ci.User)
+	}
+	if ci.Password != "" {
+		params.Set("cipassword", ci.Password)
+	}
+	if len(ci.SSHKeys) > 0 {
+		keys := make([]string, 0, len(ci.SSHKeys))
+		for _, key := range ci.SSHKeys {
+			keys = append(keys, strings.TrimSpace(key))
+		}
+		// Proxmox attend les clés encodées en pourcentage (espaces compris), en plus de l'encodage du formulaire
+		params.Set("sshkeys", strings.ReplaceAll(url.QueryEscape(strings.Join(keys, "\n")), "+", "%20"))
+	}
+	for i, ip := range ci.IPConfig {
+		params.Set(fmt.Sprintf("ipconfig%d", i), ip)
+	}
+	if ci.Nameserver != "" {
+		params.Set("nameserver", ci.Nameserver)
+	}
+	if ci.SearchDomain != "" {
+		params.Set("searchdomain", ci.SearchDomain)
+	}
+	return params
+}
@@ -8,16 +8,23 @@ import (
 	"sync"
 	"time"
 
+	"proxmox-dashboard/internal/middleware"
 	"proxmox-dashboard/internal/models"
 )
 
+// clientBufferSize est la capacité du canal de chaque client : une collecte d'inventaire
+// peut produire plusieurs dizaines d'événements d'un coup (démarrage d'un nœud, création en masse)
+const clientBufferSize = 256
+
 // Client représente une connexion SSE
 type Client struct {
 	ID      string
 	Channel chan models.SSEEvent
+	User    *models.User // utilisateur authentifié : filtre les événements reçus
 	Request *http.Request
 	Writer  http.ResponseWriter
 	Done    chan bool
+	dropped int // événements perdus car le canal était plein (accès sous verrou du hub)
 }
 
 // Hub gère toutes les connexions SSE
@@ -65,19 +72,68 @@ func (h *Hub) run() {
 			log.Printf("SSE client disconnected: %s", client.ID)
 
 		case event := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock() // verrou exclusif : le compteur d'événements perdus est mis à jour
 			for _, client := range h.clients {
+				if !canReceive(client.User, event) {
+					continue
+				}
 				select {
 				case client.Channel <- event:
 				default:
-					// Client ne peut pas recevoir, le déconnecter
-					close(client.Channel)
-					delete(h.clients, client.ID)
+					// Client saturé : l'événement est perdu pour ce client, la connexion est conservée
+					client.dropped++
+					if client.dropped == 1 || client.dropped%100 == 0 {
+						log.Printf("⚠️  SSE client %s is too slow, %d event(s) dropped", client.ID, client.dropped)
+					}
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+		}
+	}
+}
+
+// canReceive indique si l'utilisateur peut recevoir l'événement, selon les permissions
+// vérifiées par l'API REST équivalente : alerts:read pour les alertes, proxmox:read pour
+// l'inventaire. Les événements d'un invité sont aussi transmis aux utilisateurs dont une
+// permission sur les invités (ex: proxmox.vm:power limitée au pool "dev") couvre cet invité.
+func canReceive(user *models.User, event models.SSEEvent) bool {
+	if event.Type == "ping" {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+
+	switch event.Type {
+	case "alert", "alert.updated", "alert.comment", "silence", "ack":
+		return user.HasPermission("alerts", "read")
+	}
+
+	inventoryEvent, ok := event.Data.(models.InventoryEvent)
+	if !ok {
+		return false
+	}
+	if user.HasPermission("proxmox", "read") {
+		return true
+	}
+
+	source := inventoryEvent.EventSource()
+	resource := ""
+	switch source.Kind {
+	case "qemu":
+		resource = "proxmox.vm"
+	case "lxc":
+		resource = "proxmox.lxc"
+	default:
+		return false
+	}
+	target := source.Target()
+	for _, info := range models.PermissionCatalog {
+		if info.Resource == resource && user.Can(resource, info.Action, target) {
+			return true
 		}
 	}
+	return false
 }
 
 // pingClients envoie un ping périodique pour maintenir les connexions
@@ -108,7 +164,7 @@ func (h *Hub) UnregisterClient(client *Client) {
 	h.unregister <- client
 }
 
-// Broadcast diffuse un événement à tous les clients
+// Broadcast diffuse un événement aux clients autorisés à le recevoir (voir canReceive)
 func (h *Hub) Broadcast(event models.SSEEvent) {
 	select {
 	case h.broadcast <- event:
@@ -125,6 +181,30 @@ func (h *Hub) BroadcastAlert(alert *models.Alert) {
 	})
 }
 
+// BroadcastAlertUpdate diffuse le nouvel état d'une alerte (acquittement, résolution, snooze, assignation)
+func (h *Hub) BroadcastAlertUpdate(alert *models.Alert) {
+	h.Broadcast(models.SSEEvent{
+		Type: "alert.updated",
+		Data: alert,
+	})
+}
+
+// BroadcastAlertComment diffuse un nouveau commentaire d'alerte
+func (h *Hub) BroadcastAlertComment(comment *models.AlertComment) {
+	h.Broadcast(models.SSEEvent{
+		Type: "alert.comment",
+		Data: comment,
+	})
+}
+
+// BroadcastSilence diffuse la création ou l'expiration d'un silence
+func (h *Hub) BroadcastSilence(silence *models.Silence) {
+	h.Broadcast(models.SSEEvent{
+		Type: "silence",
+		Data: silence,
+	})
+}
+
 // BroadcastAck diffuse un accusé de réception d'alerte
 func (h *Hub) BroadcastAck(alertID int) {
 	h.Broadcast(models.SSEEvent{
@@ -146,9 +226,11 @@ func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
 
 	// Créer un nouveau client
 	clientID := fmt.Sprintf("client_%d", time.Now().UnixNano())
+	user, _ := middleware.GetCurrentUser(r)
 	client := &Client{
 		ID:      clientID,
-		Channel: make(chan models.SSEEvent, 10),
+		Channel: make(chan models.SSEEvent, clientBufferSize),
+		User:    user,
 		Request: r,
 		Writer:  w,
 		Done:    make(chan bool),
@@ -177,7 +259,11 @@ func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
 
 		for {
 			select {
-			case event := <-client.Channel:
+			case event, ok := <-client.Channel:
+				if !ok {
+					// Canal fermé par le hub (client désenregistré)
+					return
+				}
 				if err := h.sendEvent(w, event); err != nil {
 					log.Printf("Error sending SSE event: %v", err)
 					return
@@ -0,0 +1,303 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// GetAlert récupère une alerte par ID
+func (s *Store) GetAlert(id int) (*models.Alert, error) {
+	alert, err := scanAlert(s.db.QueryRow(`SELECT `+alertColumns+` FROM alerts WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+	return alert, nil
+}
+
+// GetAlertsByStatus récupère les alertes dans un état donné (firing, acknowledged, resolved)
+func (s *Store) GetAlertsByStatus(status string) ([]*models.Alert, error) {
+	return s.queryAlerts(`SELECT `+alertColumns+` FROM alerts WHERE status = ? ORDER BY created_at DESC`, status)
+}
+
+// GetOpenAlertByFingerprint récupère l'alerte non résolue d'une condition (sql.ErrNoRows si aucune)
+func (s *Store) GetOpenAlertByFingerprint(fingerprint string) (*models.Alert, error) {
+	alert, err := scanAlert(s.db.QueryRow(`SELECT `+alertColumns+` FROM alerts WHERE fingerprint = ? AND status != ?`,
+		fingerprint, models.AlertStatusResolved))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+	return alert, nil
+}
+
+// GetOpenAlertsBySource récupère les alertes non résolues d'une source (ex: "backup:prod")
+func (s *Store) GetOpenAlertsBySource(source string) ([]*models.Alert, error) {
+	return s.queryAlerts(`SELECT `+alertColumns+` FROM alerts WHERE source = ? AND status != ? ORDER BY id ASC`,
+		source, models.AlertStatusResolved)
+}
+
+// GetOpenAlertsByFingerprintPrefix récupère les alertes non résolues dont l'empreinte commence par prefix
+func (s *Store) GetOpenAlertsByFingerprintPrefix(prefix string) ([]*models.Alert, error) {
+	return s.queryAlerts(`SELECT `+alertColumns+` FROM alerts WHERE substr(fingerprint, 1, ?) = ? AND status != ? ORDER BY id ASC`,
+		len(prefix), prefix, models.AlertStatusResolved)
+}
+
+// transitionAlert applique une mise à jour conditionnée à l'état de l'alerte.
+// Retourne sql.ErrNoRows si l'alerte n'existe pas, ErrAlertTransition si son état ne le permet pas.
+func (s *Store) transitionAlert(id int, query string, args ...interface{}) error {
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	var status string
+	if err := s.db.QueryRow(`SELECT status FROM alerts WHERE id = ?`, id).Scan(&status); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: alert %d is %s", models.ErrAlertTransition, id, status)
+}
+
+// AcknowledgeAlert acquitte une alerte déclenchée (firing → acknowledged)
+func (s *Store) AcknowledgeAlert(id int, by string) error {
+	err := s.transitionAlert(id, `UPDATE alerts SET status = ?, acknowledged = 1, acknowledged_at = ?, acknowledged_by = ?
+			  WHERE id = ? AND status = ?`,
+		models.AlertStatusAcknowledged, time.Now(), by, id, models.AlertStatusFiring)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge alert: %w", err)
+	}
+	return nil
+}
+
+// AcknowledgeAllAlerts acquitte toutes les alertes déclenchées et retourne leurs IDs
+func (s *Store) AcknowledgeAllAlerts(by string) ([]int, error) {
+	rows, err := s.db.Query(`UPDATE alerts SET status = ?, acknowledged = 1, acknowledged_at = ?, acknowledged_by = ?
+			  WHERE status = ? RETURNING id`,
+		models.AlertStatusAcknowledged, time.Now(), by, models.AlertStatusFiring)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge all alerts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to acknowledge all alerts: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ResolveAlert résout une alerte ouverte (firing ou acknowledged → resolved).
+// by est l'utilisateur à l'origine de la résolution, vide pour une résolution automatique.
+func (s *Store) ResolveAlert(alert *models.Alert, by string, at time.Time) error {
+	err := s.transitionAlert(alert.ID, `UPDATE alerts SET status = ?, resolved_at = ?, resolved_by = ?
+			  WHERE id = ? AND status != ?`,
+		models.AlertStatusResolved, at, by, alert.ID, models.AlertStatusResolved)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert %d: %w", alert.ID, err)
+	}
+	alert.Status = models.AlertStatusResolved
+	alert.ResolvedAt = &at
+	alert.ResolvedBy = by
+	return nil
+}
+
+// SnoozeAlert suspend les notifications d'une alerte ouverte jusqu'à until (nil pour reprendre)
+func (s *Store) SnoozeAlert(id int, until *time.Time) error {
+	err := s.transitionAlert(id, `UPDATE alerts SET snoozed_until = ? WHERE id = ? AND status != ?`,
+		until, id, models.AlertStatusResolved)
+	if err != nil {
+		return fmt.Errorf("failed to snooze alert: %w", err)
+	}
+	return nil
+}
+
+// ErrAssigneeNotFound est retournée quand l'utilisateur assigné n'existe pas ou est désactivé
+var ErrAssigneeNotFound = errors.New("assignee not found")
+
+// AssignAlert assigne une alerte à un utilisateur actif (nil pour la désassigner)
+func (s *Store) AssignAlert(id int, userID *int) error {
+	var username string
+	if userID != nil {
+		err := s.db.QueryRow(`SELECT username FROM users WHERE id = ? AND active = 1`, *userID).Scan(&username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAssigneeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get assignee: %w", err)
+		}
+	}
+	result, err := s.db.Exec(`UPDATE alerts SET assignee_id = ?, assignee = ? WHERE id = ?`, userID, username, id)
+	if err != nil {
+		return fmt.Errorf("failed to assign alert: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateAlertComment ajoute un commentaire au fil d'une alerte
+func (s *Store) CreateAlertComment(comment *models.AlertComment) error {
+	result, err := s.db.Exec(`INSERT INTO alert_comments (alert_id, user_id, username, body, created_at)
+			  VALUES (?, ?, ?, ?, ?)`,
+		comment.AlertID, comment.UserID, comment.Username, comment.Body, comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	comment.ID = int(id)
+	return nil
+}
+
+// GetAlertComments récupère le fil de commentaires d'une alerte, du plus ancien au plus récent
+func (s *Store) GetAlertComments(alertID int) ([]models.AlertComment, error) {
+	rows, err := s.db.Query(`SELECT id, alert_id, user_id, username, body, created_at
+			  FROM alert_comments WHERE alert_id = ? ORDER BY id ASC`, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.AlertComment{}
+	for rows.Next() {
+		var comment models.AlertComment
+		var userID sql.NullInt64
+		if err := rows.Scan(&comment.ID, &comment.AlertID, &userID, &comment.Username, &comment.Body,
+			&comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comment.UserID = nullIntPtr(userID)
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// silenceColumns liste les colonnes lues par scanSilence
+const silenceColumns = `id, matchers, starts_at, ends_at, comment, created_by, created_at`
+
+// scanSilence lit un silence et calcule son état à l'instant présent.
+// Les dates sont stockées en texte (timestampLayout) pour être comparées en SQL.
+func scanSilence(scanner rowScanner) (*models.Silence, error) {
+	silence := &models.Silence{}
+	var matchers, startsAt, endsAt, createdAt string
+	if err := scanner.Scan(&silence.ID, &matchers, &startsAt, &endsAt, &silence.Comment,
+		&silence.CreatedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	silence.StartsAt, _ = time.Parse(timestampLayout, startsAt)
+	silence.EndsAt, _ = time.Parse(timestampLayout, endsAt)
+	silence.CreatedAt, _ = time.Parse(timestampLayout, createdAt)
+	if err := json.Unmarshal([]byte(matchers), &silence.Matchers); err != nil {
+		return nil, fmt.Errorf("invalid matchers of silence %d: %w", silence.ID, err)
+	}
+	silence.State = silence.StateAt(time.Now())
+	return silence, nil
+}
+
+// CreateSilence enregistre un silence
+func (s *Store) CreateSilence(silence *models.Silence) error {
+	matchers, err := json.Marshal(silence.Matchers)
+	if err != nil {
+		return fmt.Errorf("failed to encode matchers: %w", err)
+	}
+	result, err := s.db.Exec(`INSERT INTO silences (matchers, starts_at, ends_at, comment, created_by, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`,
+		string(matchers), silence.StartsAt.UTC().Format(timestampLayout), silence.EndsAt.UTC().Format(timestampLayout),
+		silence.Comment, silence.CreatedBy, silence.CreatedAt.UTC().Format(timestampLayout))
+	if err != nil {
+		return fmt.Errorf("failed to create silence: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	silence.ID = int(id)
+	silence.State = silence.StateAt(time.Now())
+	return nil
+}
+
+// GetSilence récupère un silence par ID
+func (s *Store) GetSilence(id int) (*models.Silence, error) {
+	silence, err := scanSilence(s.db.QueryRow(`SELECT `+silenceColumns+` FROM silences WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get silence: %w", err)
+	}
+	return silence, nil
+}
+
+// GetSilences récupère les silences, uniquement ceux qui ne sont pas expirés si activeOnly
+func (s *Store) GetSilences(activeOnly bool) ([]*models.Silence, error) {
+	query := `SELECT ` + silenceColumns + ` FROM silences`
+	var args []interface{}
+	if activeOnly {
+		query += ` WHERE ends_at > ?`
+		args = append(args, time.Now().UTC().Format(timestampLayout))
+	}
+	rows, err := s.db.Query(query+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get silences: %w", err)
+	}
+	defer rows.Close()
+
+	silences := []*models.Silence{}
+	for rows.Next() {
+		silence, err := scanSilence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// ExpireSilence met fin à un silence immédiatement (sql.ErrNoRows s'il n'existe pas ou est déjà expiré)
+func (s *Store) ExpireSilence(id int) error {
+	now := time.Now().UTC().Format(timestampLayout)
+	result, err := s.db.Exec(`UPDATE silences SET ends_at = ?, starts_at = MIN(starts_at, ?) WHERE id = ? AND ends_at > ?`,
+		now, now, id, now)
+	if err != nil {
+		return fmt.Errorf("failed to expire silence: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MatchingSilences retourne les IDs des silences actifs qui couvrent une alerte
+func MatchingSilences(silences []*models.Silence, alert *models.Alert, now time.Time) []int {
+	var ids []int
+	for _, silence := range silences {
+		if silence.StateAt(now) == models.SilenceStateActive && silence.Matches(alert) {
+			ids = append(ids, silence.ID)
+		}
+	}
+	return ids
+}
+
+// IsAlertMuted indique si les notifications d'une alerte sont suspendues (snooze ou silence actif)
+func (s *Store) IsAlertMuted(alert *models.Alert) (bool, error) {
+	now := time.Now()
+	if alert.IsSnoozed(now) {
+		return true, nil
+	}
+	silences, err := s.GetSilences(true)
+	if err != nil {
+		return false, err
+	}
+	return len(MatchingSilences(silences, alert, now)) > 0, nil
+}
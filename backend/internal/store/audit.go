@@ -0,0 +1,109 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// timestampLayout est le format des horodatages stockés en texte (journal d'audit, livraisons webhook) :
+// UTC, largeur fixe pour un tri lexical
+const timestampLayout = "2006-01-02T15:04:05.000Z"
+
+// CreateAuditEntry enregistre une action dans le journal d'audit
+func (s *Store) CreateAuditEntry(entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (timestamp, user_id, username, action, target, method, path,
+			  ip_address, user_agent, status, result, upid, duration_ms)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.Exec(query, entry.Timestamp.UTC().Format(timestampLayout), entry.UserID,
+		entry.Username, entry.Action, entry.Target, entry.Method, entry.Path, entry.IPAddress,
+		entry.UserAgent, entry.Status, entry.Result, entry.UPID, entry.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	entry.ID = int(id)
+	return nil
+}
+
+// QueryAuditLog retourne les entrées du journal d'audit correspondant aux filtres
+// (les plus récentes d'abord) et le nombre total d'entrées correspondantes
+func (s *Store) QueryAuditLog(q models.AuditQuery) ([]models.AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+	if q.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, q.Username)
+	}
+	if q.Action != "" {
+		conditions = append(conditions, "action LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLike(q.Action)+"%")
+	}
+	if q.Target != "" {
+		conditions = append(conditions, "target LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(q.Target)+"%")
+	}
+	if q.Result != "" {
+		conditions = append(conditions, "result = ?")
+		args = append(args, q.Result)
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, q.From.UTC().Format(timestampLayout))
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, q.To.UTC().Format(timestampLayout))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := `SELECT id, timestamp, user_id, username, action, target, method, path, ip_address,
+			  user_agent, status, result, upid, duration_ms
+			  FROM audit_log` + where + ` ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var userID sql.NullInt64
+		var timestamp string
+		if err := rows.Scan(&entry.ID, &timestamp, &userID, &entry.Username, &entry.Action, &entry.Target,
+			&entry.Method, &entry.Path, &entry.IPAddress, &entry.UserAgent, &entry.Status, &entry.Result,
+			&entry.UPID, &entry.DurationMs); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Timestamp, _ = time.Parse(timestampLayout, timestamp)
+		if userID.Valid {
+			id := int(userID.Int64)
+			entry.UserID = &id
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// escapeLike échappe les caractères spéciaux d'un motif LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// healthCheckColumns sont les colonnes lues par scanHealthCheck
+const healthCheckColumns = `id, app_id, status, latency_ms, status_code, error, checked_at`
+
+// scanHealthCheck lit un health check
+func scanHealthCheck(row rowScanner) (*models.HealthCheck, error) {
+	check := &models.HealthCheck{}
+	var statusCode sql.NullInt64
+	var errMsg sql.NullString
+	var checkedAt int64
+	if err := row.Scan(&check.ID, &check.AppID, &check.Status, &check.LatencyMs, &statusCode, &errMsg, &checkedAt); err != nil {
+		return nil, err
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		check.StatusCode = &code
+	}
+	if errMsg.Valid {
+		check.Error = &errMsg.String
+	}
+	check.CheckedAt = time.Unix(checkedAt, 0)
+	return check, nil
+}
+
+// RecordHealthCheck enregistre le résultat d'un health check
+func (s *Store) RecordHealthCheck(check *models.HealthCheck) error {
+	result, err := s.db.Exec(`INSERT INTO health_checks (app_id, status, latency_ms, status_code, error, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		check.AppID, check.Status, check.LatencyMs, check.StatusCode, check.Error, check.CheckedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record health check: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	check.ID = int(id)
+	return nil
+}
+
+// GetHealthChecks retourne les health checks d'une application depuis since, du plus récent au plus ancien
+// (au plus limit résultats, sans limite si limit <= 0)
+func (s *Store) GetHealthChecks(appID int, since time.Time, limit int) ([]*models.HealthCheck, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`SELECT `+healthCheckColumns+` FROM health_checks
+		WHERE app_id = ? AND checked_at >= ? ORDER BY checked_at DESC, id DESC LIMIT ?`, appID, since.Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health checks: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []*models.HealthCheck{}
+	for rows.Next() {
+		check, err := scanHealthCheck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health check: %w", err)
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
+// GetAppUptime calcule l'uptime et les percentiles de latence d'une application sur chaque période
+// de UptimeWindows se terminant à now. Les agrégats sont calculés par SQLite : l'historique n'est
+// pas chargé en mémoire.
+func (s *Store) GetAppUptime(appID int, now time.Time) (*models.AppUptime, error) {
+	uptime := &models.AppUptime{AppID: appID, Windows: make([]models.UptimeStats, 0, len(models.UptimeWindows))}
+	until := now.Unix()
+	for _, window := range models.UptimeWindows {
+		since := now.Add(-window.Duration).Unix()
+
+		var checks, online int
+		err := s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = 'online' THEN 1 ELSE 0 END), 0)
+			FROM health_checks WHERE app_id = ? AND checked_at >= ? AND checked_at <= ?`,
+			appID, since, until).Scan(&checks, &online)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute uptime: %w", err)
+		}
+
+		stats, err := models.NewUptimeStats(window.Name, checks, online, func(rank int) (int64, error) {
+			var latency int64
+			err := s.db.QueryRow(`SELECT latency_ms FROM health_checks
+				WHERE app_id = ? AND status = 'online' AND checked_at >= ? AND checked_at <= ?
+				ORDER BY latency_ms LIMIT 1 OFFSET ?`, appID, since, until, rank-1).Scan(&latency)
+			return latency, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute latency percentiles: %w", err)
+		}
+		uptime.Windows = append(uptime.Windows, stats)
+	}
+	return uptime, nil
+}
+
+// GetLatestHealthCheck retourne le dernier health check d'une application (sql.ErrNoRows si aucun)
+func (s *Store) GetLatestHealthCheck(appID int) (*models.HealthCheck, error) {
+	row := s.db.QueryRow(`SELECT `+healthCheckColumns+` FROM health_checks
+		WHERE app_id = ? ORDER BY checked_at DESC, id DESC LIMIT 1`, appID)
+	check, err := scanHealthCheck(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest health check: %w", err)
+	}
+	return check, nil
+}
+
+// PruneHealthChecks supprime les health checks antérieurs à before
+func (s *Store) PruneHealthChecks(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM health_checks WHERE checked_at < ?`, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune health checks: %w", err)
+	}
+	return result.RowsAffected()
+}
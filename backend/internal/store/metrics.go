@@ -0,0 +1,142 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// metricsRollups liste les résolutions agrégées alimentées à chaque mesure
+var metricsRollups = []int{models.MetricsResolution5m, models.MetricsResolutionHourly}
+
+// bucket retourne le début de l'intervalle de la résolution contenant ts
+func bucket(ts time.Time, resolution int) int64 {
+	unix := ts.Unix()
+	if resolution <= 0 {
+		return unix
+	}
+	return unix - unix%int64(resolution)
+}
+
+// RecordMetricSamples enregistre des mesures brutes et met à jour les moyennes des agrégats 5 min et horaires
+func (s *Store) RecordMetricSamples(samples []models.MetricSample) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rawQuery := `INSERT OR REPLACE INTO metrics (resolution, kind, cluster, object_id, ts, cpu, memory, disk, samples)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`
+	rollupQuery := `INSERT INTO metrics (resolution, kind, cluster, object_id, ts, cpu, memory, disk, samples)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)
+					ON CONFLICT (resolution, kind, cluster, object_id, ts) DO UPDATE SET
+						cpu = (cpu * samples + excluded.cpu) / (samples + 1),
+						memory = (memory * samples + excluded.memory) / (samples + 1),
+						disk = (disk * samples + excluded.disk) / (samples + 1),
+						samples = samples + 1`
+
+	for _, m := range samples {
+		if _, err := tx.Exec(rawQuery, models.MetricsResolutionRaw, m.Kind, m.Cluster, m.ObjectID,
+			bucket(m.Timestamp, models.MetricsResolutionRaw), m.CPU, m.Memory, m.Disk); err != nil {
+			return fmt.Errorf("failed to record metric sample: %w", err)
+		}
+		for _, resolution := range metricsRollups {
+			if _, err := tx.Exec(rollupQuery, resolution, m.Kind, m.Cluster, m.ObjectID,
+				bucket(m.Timestamp, resolution), m.CPU, m.Memory, m.Disk); err != nil {
+				return fmt.Errorf("failed to update metric rollup: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit metric samples: %w", err)
+	}
+	return nil
+}
+
+// BackfillMetricSamples importe des mesures historiques dans toutes les résolutions
+// sans écraser les intervalles déjà renseignés (import idempotent)
+func (s *Store) BackfillMetricSamples(samples []models.MetricSample) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT OR IGNORE INTO metrics (resolution, kind, cluster, object_id, ts, cpu, memory, disk, samples)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)`
+
+	inserted := 0
+	now := time.Now()
+	for _, m := range samples {
+		for _, resolution := range append([]int{models.MetricsResolutionRaw}, metricsRollups...) {
+			if now.Sub(m.Timestamp) > models.MetricsRetention[resolution] {
+				continue
+			}
+			result, err := tx.Exec(query, resolution, m.Kind, m.Cluster, m.ObjectID,
+				bucket(m.Timestamp, resolution), m.CPU, m.Memory, m.Disk)
+			if err != nil {
+				return 0, fmt.Errorf("failed to backfill metric sample: %w", err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				inserted++
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit metric samples: %w", err)
+	}
+	return inserted, nil
+}
+
+// PruneMetrics supprime les points dépassant la durée de conservation de leur résolution
+func (s *Store) PruneMetrics(now time.Time) (int64, error) {
+	var deleted int64
+	for resolution, retention := range models.MetricsRetention {
+		result, err := s.db.Exec(`DELETE FROM metrics WHERE resolution = ? AND ts < ?`,
+			resolution, now.Add(-retention).Unix())
+		if err != nil {
+			return deleted, fmt.Errorf("failed to prune metrics: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// QueryMetrics retourne la série moyennée par pas de q.Step dans la résolution q.Resolution
+func (s *Store) QueryMetrics(q models.MetricsQuery) ([]models.MetricPoint, error) {
+	step := int64(q.Step / time.Second)
+	query := `SELECT (ts / ?) * ? AS bucket, AVG(cpu), AVG(memory), AVG(disk)
+			  FROM metrics
+			  WHERE resolution = ? AND kind = ? AND object_id = ? AND (? = '' OR cluster = ?)
+			  AND ts >= ? AND ts <= ?
+			  GROUP BY bucket ORDER BY bucket`
+
+	rows, err := s.db.Query(query, step, step, q.Resolution, q.Kind, q.ObjectID, q.Cluster, q.Cluster,
+		q.From.Unix(), q.To.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.MetricPoint{}
+	for rows.Next() {
+		var ts int64
+		var cpu, memory, disk sql.NullFloat64
+		if err := rows.Scan(&ts, &cpu, &memory, &disk); err != nil {
+			return nil, fmt.Errorf("failed to scan metric point: %w", err)
+		}
+		points = append(points, models.MetricPoint{
+			Timestamp: time.Unix(ts, 0).UTC(),
+			CPU:       cpu.Float64,
+			Memory:    memory.Float64,
+			Disk:      disk.Float64,
+		})
+	}
+	return points, rows.Err()
+}
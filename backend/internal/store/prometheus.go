@@ -0,0 +1,161 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// prometheusDataSourceColumns sont les colonnes lues par scanPrometheusDataSource
+const prometheusDataSourceColumns = `id, name, url, auth_type, username, secret, tls_skip_verify, is_default, created_at, updated_at`
+
+// encryptDataSourceSecret chiffre le secret d'une source de données ; une source sans secret n'exige pas de clé
+func (s *Store) encryptDataSourceSecret(ds *models.PrometheusDataSource) (string, error) {
+	if ds.Secret == "" {
+		return "", nil
+	}
+	if s.cipher == nil {
+		return "", ErrNoCipher
+	}
+	return s.cipher.Encrypt(ds.Secret)
+}
+
+// scanPrometheusDataSource lit une source de données et déchiffre son secret
+func (s *Store) scanPrometheusDataSource(row rowScanner) (*models.PrometheusDataSource, error) {
+	ds := &models.PrometheusDataSource{}
+	var secret string
+	if err := row.Scan(&ds.ID, &ds.Name, &ds.URL, &ds.AuthType, &ds.Username, &secret,
+		&ds.TLSSkipVerify, &ds.IsDefault, &ds.CreatedAt, &ds.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		if s.cipher == nil {
+			return nil, ErrNoCipher
+		}
+		var err error
+		if ds.Secret, err = s.cipher.Decrypt(secret); err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret of prometheus data source %d: %w", ds.ID, err)
+		}
+	}
+	ds.HasSecret = ds.Secret != ""
+	return ds, nil
+}
+
+// GetPrometheusDataSources récupère toutes les sources de données Prometheus, secrets déchiffrés
+func (s *Store) GetPrometheusDataSources() ([]*models.PrometheusDataSource, error) {
+	rows, err := s.db.Query(`SELECT ` + prometheusDataSourceColumns + ` FROM prometheus_datasources ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prometheus data sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []*models.PrometheusDataSource{}
+	for rows.Next() {
+		ds, err := s.scanPrometheusDataSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prometheus data source: %w", err)
+		}
+		sources = append(sources, ds)
+	}
+	return sources, rows.Err()
+}
+
+// GetPrometheusDataSource récupère une source de données Prometheus par ID
+func (s *Store) GetPrometheusDataSource(id int) (*models.PrometheusDataSource, error) {
+	row := s.db.QueryRow(`SELECT `+prometheusDataSourceColumns+` FROM prometheus_datasources WHERE id = ?`, id)
+	ds, err := s.scanPrometheusDataSource(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prometheus data source: %w", err)
+	}
+	return ds, nil
+}
+
+// GetDefaultPrometheusDataSource récupère la source par défaut, à défaut la plus ancienne
+func (s *Store) GetDefaultPrometheusDataSource() (*models.PrometheusDataSource, error) {
+	row := s.db.QueryRow(`SELECT ` + prometheusDataSourceColumns + ` FROM prometheus_datasources
+			  ORDER BY is_default DESC, id ASC LIMIT 1`)
+	ds, err := s.scanPrometheusDataSource(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default prometheus data source: %w", err)
+	}
+	return ds, nil
+}
+
+// CreatePrometheusDataSource enregistre une source de données Prometheus
+func (s *Store) CreatePrometheusDataSource(ds *models.PrometheusDataSource) error {
+	secret, err := s.encryptDataSourceSecret(ds)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if ds.IsDefault {
+		if _, err := tx.Exec(`UPDATE prometheus_datasources SET is_default = 0`); err != nil {
+			return fmt.Errorf("failed to reset default prometheus data source: %w", err)
+		}
+	}
+	result, err := tx.Exec(`INSERT INTO prometheus_datasources (name, url, auth_type, username, secret, tls_skip_verify,
+			  is_default, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ds.Name, ds.URL, ds.AuthType, ds.Username, secret, ds.TLSSkipVerify, ds.IsDefault, ds.CreatedAt, ds.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create prometheus data source: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit prometheus data source: %w", err)
+	}
+
+	ds.ID = int(id)
+	return nil
+}
+
+// UpdatePrometheusDataSource met à jour une source de données Prometheus
+func (s *Store) UpdatePrometheusDataSource(ds *models.PrometheusDataSource) error {
+	secret, err := s.encryptDataSourceSecret(ds)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if ds.IsDefault {
+		if _, err := tx.Exec(`UPDATE prometheus_datasources SET is_default = 0 WHERE id != ?`, ds.ID); err != nil {
+			return fmt.Errorf("failed to reset default prometheus data source: %w", err)
+		}
+	}
+	_, err = tx.Exec(`UPDATE prometheus_datasources SET name = ?, url = ?, auth_type = ?, username = ?, secret = ?,
+			  tls_skip_verify = ?, is_default = ?, updated_at = ? WHERE id = ?`,
+		ds.Name, ds.URL, ds.AuthType, ds.Username, secret, ds.TLSSkipVerify, ds.IsDefault, ds.UpdatedAt, ds.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update prometheus data source: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit prometheus data source: %w", err)
+	}
+	return nil
+}
+
+// DeletePrometheusDataSource supprime une source de données Prometheus
+func (s *Store) DeletePrometheusDataSource(id int) error {
+	result, err := s.db.Exec(`DELETE FROM prometheus_datasources WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete prometheus data source: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
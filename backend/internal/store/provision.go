@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// provisionJobColumns sont les colonnes lues par scanProvisionJob
+const provisionJobColumns = `id, connection_id, spec, status, step, progress, vmid, upid, error, created_by, created_at, updated_at, finished_at`
+
+// scanProvisionJob lit un job de provisionnement
+func scanProvisionJob(row rowScanner) (*models.ProvisionJob, error) {
+	job := &models.ProvisionJob{}
+	var connectionID, createdBy sql.NullInt64
+	var spec string
+	var finishedAt sql.NullTime
+	if err := row.Scan(&job.ID, &connectionID, &spec, &job.Status, &job.Step, &job.Progress, &job.VMID, &job.UPID,
+		&job.Error, &createdBy, &job.CreatedAt, &job.UpdatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(spec), &job.Spec); err != nil {
+		return nil, fmt.Errorf("failed to decode spec of provision job %d: %w", job.ID, err)
+	}
+	if connectionID.Valid {
+		id := int(connectionID.Int64)
+		job.ConnectionID = &id
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		job.CreatedBy = &id
+	}
+	job.FinishedAt = nullTimePtr(finishedAt)
+	return job, nil
+}
+
+// CreateProvisionJob enregistre un job de provisionnement (la description est enregistrée sans mot de passe)
+func (s *Store) CreateProvisionJob(job *models.ProvisionJob) error {
+	spec, err := json.Marshal(job.Spec.Sanitized())
+	if err != nil {
+		return fmt.Errorf("failed to encode provision spec: %w", err)
+	}
+	result, err := s.db.Exec(`INSERT INTO provision_jobs (connection_id, spec, status, step, progress, vmid, upid, error, created_by, created_at, updated_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ConnectionID, string(spec), job.Status, job.Step, job.Progress, job.VMID, job.UPID, job.Error,
+		job.CreatedBy, job.CreatedAt, job.UpdatedAt, job.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create provision job: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	job.ID = int(id)
+	return nil
+}
+
+// UpdateProvisionJob enregistre l'avancement d'un job de provisionnement
+func (s *Store) UpdateProvisionJob(job *models.ProvisionJob) error {
+	_, err := s.db.Exec(`UPDATE provision_jobs SET status = ?, step = ?, progress = ?, vmid = ?, upid = ?, error = ?,
+		updated_at = ?, finished_at = ? WHERE id = ?`,
+		job.Status, job.Step, job.Progress, job.VMID, job.UPID, job.Error, job.UpdatedAt, job.FinishedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update provision job: %w", err)
+	}
+	return nil
+}
+
+// GetProvisionJob récupère un job de provisionnement par ID
+func (s *Store) GetProvisionJob(id int) (*models.ProvisionJob, error) {
+	row := s.db.QueryRow(`SELECT `+provisionJobColumns+` FROM provision_jobs WHERE id = ?`, id)
+	job, err := scanProvisionJob(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provision job: %w", err)
+	}
+	return job, nil
+}
+
+// GetProvisionJobs récupère les derniers jobs de provisionnement, du plus récent au plus ancien
+func (s *Store) GetProvisionJobs(limit int) ([]*models.ProvisionJob, error) {
+	rows, err := s.db.Query(`SELECT `+provisionJobColumns+` FROM provision_jobs ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provision jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.ProvisionJob{}
+	for rows.Next() {
+		job, err := scanProvisionJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan provision job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// FailInterruptedProvisionJobs marque en échec les jobs restés en cours lors d'un arrêt du serveur
+func (s *Store) FailInterruptedProvisionJobs(at time.Time) (int64, error) {
+	result, err := s.db.Exec(`UPDATE provision_jobs SET status = ?, error = ?, updated_at = ?, finished_at = ?
+		WHERE status IN (?, ?)`,
+		models.ProvisionJobFailed, "interrupted by a server restart", at, at,
+		models.ProvisionJobPending, models.ProvisionJobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted provision jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// ErrNoCipher est retourné quand aucune clé de chiffrement n'est configurée (ENCRYPTION_KEY)
+var ErrNoCipher = errors.New("encryption key not configured: set ENCRYPTION_KEY to store Proxmox and Prometheus credentials")
+
+// encryptConnection chiffre les secrets d'une connexion
+func (s *Store) encryptConnection(conn *models.ProxmoxConnection) (secret, password string, err error) {
+	if s.cipher == nil {
+		return "", "", ErrNoCipher
+	}
+	if secret, err = s.cipher.Encrypt(conn.Secret); err != nil {
+		return "", "", err
+	}
+	if password, err = s.cipher.Encrypt(conn.Password); err != nil {
+		return "", "", err
+	}
+	return secret, password, nil
+}
+
+// decryptConnection déchiffre les secrets d'une connexion lue en base
+func (s *Store) decryptConnection(conn *models.ProxmoxConnection, secret string, password sql.NullString) error {
+	if s.cipher == nil {
+		return ErrNoCipher
+	}
+	var err error
+	if conn.Secret, err = s.cipher.Decrypt(secret); err != nil {
+		return fmt.Errorf("failed to decrypt secret of connection %d: %w", conn.ID, err)
+	}
+	if conn.Password, err = s.cipher.Decrypt(password.String); err != nil {
+		return fmt.Errorf("failed to decrypt password of connection %d: %w", conn.ID, err)
+	}
+	conn.HasPassword = conn.Password != ""
+	return nil
+}
+
+// CreateProxmoxConnection enregistre une nouvelle connexion Proxmox
+func (s *Store) CreateProxmoxConnection(conn *models.ProxmoxConnection) error {
+	secret, password, err := s.encryptConnection(conn)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO proxmox_connections (name, url, username, secret, password, enabled, created_at, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.Exec(query, conn.Name, conn.URL, conn.Username, secret, password,
+		conn.Enabled, conn.CreatedAt, conn.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create proxmox connection: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	conn.ID = int(id)
+	return nil
+}
+
+// GetProxmoxConnection récupère une connexion Proxmox par ID, secrets déchiffrés
+func (s *Store) GetProxmoxConnection(id int) (*models.ProxmoxConnection, error) {
+	query := `SELECT id, name, url, username, secret, password, enabled, created_at, updated_at
+			  FROM proxmox_connections WHERE id = ?`
+
+	conn := &models.ProxmoxConnection{}
+	var secret string
+	var password sql.NullString
+	err := s.db.QueryRow(query, id).Scan(&conn.ID, &conn.Name, &conn.URL, &conn.Username,
+		&secret, &password, &conn.Enabled, &conn.CreatedAt, &conn.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxmox connection: %w", err)
+	}
+
+	if err := s.decryptConnection(conn, secret, password); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// GetProxmoxConnections récupère toutes les connexions Proxmox, secrets déchiffrés
+func (s *Store) GetProxmoxConnections() ([]*models.ProxmoxConnection, error) {
+	query := `SELECT id, name, url, username, secret, password, enabled, created_at, updated_at
+			  FROM proxmox_connections ORDER BY name ASC`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxmox connections: %w", err)
+	}
+	defer rows.Close()
+
+	var conns []*models.ProxmoxConnection
+	for rows.Next() {
+		conn := &models.ProxmoxConnection{}
+		var secret string
+		var password sql.NullString
+		err := rows.Scan(&conn.ID, &conn.Name, &conn.URL, &conn.Username,
+			&secret, &password, &conn.Enabled, &conn.CreatedAt, &conn.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan proxmox connection: %w", err)
+		}
+		if err := s.decryptConnection(conn, secret, password); err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+
+	return conns, rows.Err()
+}
+
+// UpdateProxmoxConnection met à jour une connexion Proxmox
+func (s *Store) UpdateProxmoxConnection(conn *models.ProxmoxConnection) error {
+	secret, password, err := s.encryptConnection(conn)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE proxmox_connections SET name = ?, url = ?, username = ?, secret = ?, password = ?,
+			  enabled = ?, updated_at = ? WHERE id = ?`
+
+	_, err = s.db.Exec(query, conn.Name, conn.URL, conn.Username, secret, password,
+		conn.Enabled, conn.UpdatedAt, conn.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update proxmox connection: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteProxmoxConnection supprime une connexion Proxmox
+func (s *Store) DeleteProxmoxConnection(id int) error {
+	query := `DELETE FROM proxmox_connections WHERE id = ?`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete proxmox connection: %w", err)
+	}
+
+	return nil
+}
+
+// SaveProxmoxSnapshot remplace le dernier snapshot d'inventaire enregistré
+func (s *Store) SaveProxmoxSnapshot(snapshot *models.ProxmoxSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proxmox snapshot: %w", err)
+	}
+
+	query := `INSERT OR REPLACE INTO proxmox_snapshots (id, collected_at, data) VALUES (1, ?, ?)`
+	if _, err := s.db.Exec(query, snapshot.CollectedAt, string(data)); err != nil {
+		return fmt.Errorf("failed to save proxmox snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetProxmoxSnapshot récupère le dernier snapshot d'inventaire enregistré
+func (s *Store) GetProxmoxSnapshot() (*models.ProxmoxSnapshot, error) {
+	var data string
+	if err := s.db.QueryRow(`SELECT data FROM proxmox_snapshots WHERE id = 1`).Scan(&data); err != nil {
+		return nil, fmt.Errorf("failed to get proxmox snapshot: %w", err)
+	}
+
+	snapshot := &models.ProxmoxSnapshot{}
+	if err := json.Unmarshal([]byte(data), snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proxmox snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,341 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// routeColumns liste les colonnes lues par scanRoute
+const routeColumns = `id, name, position, severities, sources, matchers, subscription_ids, group_by,
+	group_wait_seconds, repeat_interval_seconds, quiet_hours, continue, enabled, created_at, updated_at`
+
+// scanRoute lit une route de notification. Les listes et la plage horaire sont stockées en JSON.
+func scanRoute(scanner rowScanner) (*models.NotifyRoute, error) {
+	route := &models.NotifyRoute{}
+	var severities, sources, matchers, subscriptionIDs, groupBy, quietHours string
+	var groupWait, repeatInterval int64
+	if err := scanner.Scan(&route.ID, &route.Name, &route.Position, &severities, &sources, &matchers,
+		&subscriptionIDs, &groupBy, &groupWait, &repeatInterval, &quietHours, &route.Continue, &route.Enabled,
+		&route.CreatedAt, &route.UpdatedAt); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		value  string
+		target interface{}
+	}{
+		{severities, &route.Severities},
+		{sources, &route.Sources},
+		{matchers, &route.Matchers},
+		{subscriptionIDs, &route.SubscriptionIDs},
+		{groupBy, &route.GroupBy},
+	} {
+		if err := json.Unmarshal([]byte(field.value), field.target); err != nil {
+			return nil, fmt.Errorf("invalid notification route %d: %w", route.ID, err)
+		}
+	}
+	if quietHours != "" {
+		route.QuietHours = &models.QuietHours{}
+		if err := json.Unmarshal([]byte(quietHours), route.QuietHours); err != nil {
+			return nil, fmt.Errorf("invalid quiet hours of notification route %d: %w", route.ID, err)
+		}
+	}
+	route.GroupWait = models.Duration(time.Duration(groupWait) * time.Second)
+	route.RepeatInterval = models.Duration(time.Duration(repeatInterval) * time.Second)
+	return route, nil
+}
+
+// routeValues encode les colonnes d'une route dans l'ordre de routeColumns (sans id ni dates)
+func routeValues(route *models.NotifyRoute) ([]interface{}, error) {
+	values := []interface{}{route.Name, route.Position}
+	for _, list := range []interface{}{route.Severities, route.Sources, route.Matchers, route.SubscriptionIDs, route.GroupBy} {
+		data, err := json.Marshal(list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode notification route: %w", err)
+		}
+		if string(data) == "null" {
+			data = []byte("[]")
+		}
+		values = append(values, string(data))
+	}
+	quietHours := ""
+	if route.QuietHours != nil {
+		data, err := json.Marshal(route.QuietHours)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode quiet hours: %w", err)
+		}
+		quietHours = string(data)
+	}
+	return append(values,
+		int64(time.Duration(route.GroupWait).Seconds()),
+		int64(time.Duration(route.RepeatInterval).Seconds()),
+		quietHours, route.Continue, route.Enabled), nil
+}
+
+// GetNotifyRoutes récupère les routes de notification par position croissante,
+// uniquement les routes actives si enabledOnly
+func (s *Store) GetNotifyRoutes(enabledOnly bool) ([]*models.NotifyRoute, error) {
+	query := `SELECT ` + routeColumns + ` FROM notify_routes`
+	if enabledOnly {
+		query += ` WHERE enabled = 1`
+	}
+	rows, err := s.db.Query(query + ` ORDER BY position ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []*models.NotifyRoute{}
+	for rows.Next() {
+		route, err := scanRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification route: %w", err)
+		}
+		routes = append(routes, route)
+	}
+	return routes, rows.Err()
+}
+
+// GetNotifyRoute récupère une route de notification par ID
+func (s *Store) GetNotifyRoute(id int) (*models.NotifyRoute, error) {
+	route, err := scanRoute(s.db.QueryRow(`SELECT `+routeColumns+` FROM notify_routes WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification route: %w", err)
+	}
+	return route, nil
+}
+
+// CreateNotifyRoute enregistre une nouvelle route de notification
+func (s *Store) CreateNotifyRoute(route *models.NotifyRoute) error {
+	values, err := routeValues(route)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	result, err := s.db.Exec(`INSERT INTO notify_routes (name, position, severities, sources, matchers,
+			  subscription_ids, group_by, group_wait_seconds, repeat_interval_seconds, quiet_hours, continue, enabled,
+			  created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		append(values, now, now)...)
+	if err != nil {
+		return fmt.Errorf("failed to create notification route: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	route.ID = int(id)
+	route.CreatedAt = now
+	route.UpdatedAt = now
+	return nil
+}
+
+// UpdateNotifyRoute met à jour une route de notification (sql.ErrNoRows si elle n'existe pas).
+// Les groupes en cours sont conservés et suivent la nouvelle configuration.
+func (s *Store) UpdateNotifyRoute(route *models.NotifyRoute) error {
+	values, err := routeValues(route)
+	if err != nil {
+		return err
+	}
+	route.UpdatedAt = time.Now()
+	result, err := s.db.Exec(`UPDATE notify_routes SET name = ?, position = ?, severities = ?, sources = ?,
+			  matchers = ?, subscription_ids = ?, group_by = ?, group_wait_seconds = ?, repeat_interval_seconds = ?,
+			  quiet_hours = ?, continue = ?, enabled = ?, updated_at = ? WHERE id = ?`,
+		append(values, route.UpdatedAt, route.ID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update notification route: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteNotifyRoute supprime une route de notification et ses groupes (sql.ErrNoRows si elle n'existe pas)
+func (s *Store) DeleteNotifyRoute(id int) error {
+	result, err := s.db.Exec(`DELETE FROM notify_routes WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification route: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = s.db.Exec(`DELETE FROM alert_group_members WHERE group_id IN (SELECT id FROM alert_groups WHERE route_id = ?)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert groups of route %d: %w", id, err)
+	}
+	if _, err := s.db.Exec(`DELETE FROM alert_groups WHERE route_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete alert groups of route %d: %w", id, err)
+	}
+	return nil
+}
+
+// AddAlertToGroup ajoute une alerte au groupe de la route désigné par sa clé (créé au besoin) et planifie
+// la notification du groupe si elle ne l'est pas déjà : après group_wait pour un nouveau groupe,
+// au plus tôt group_wait après la notification précédente sinon.
+func (s *Store) AddAlertToGroup(route *models.NotifyRoute, alert *models.Alert, now time.Time) error {
+	labels, key := route.GroupLabels(alert)
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("failed to encode group labels: %w", err)
+	}
+	wait := int64(time.Duration(route.GroupWait).Seconds())
+
+	var groupID int
+	err = s.db.QueryRow(`INSERT INTO alert_groups (route_id, group_key, labels, flush_at, created_at) VALUES (?, ?, ?, ?, ?)
+			  ON CONFLICT(route_id, group_key) DO UPDATE SET flush_at = COALESCE(flush_at, MAX(?, COALESCE(last_flush_at, 0) + ?))
+			  RETURNING id`,
+		route.ID, key, string(data), now.Unix()+wait, now, now.Unix(), wait).Scan(&groupID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert alert group: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO alert_group_members (group_id, alert_id) VALUES (?, ?)
+			  ON CONFLICT(group_id, alert_id) DO NOTHING`, groupID, alert.ID)
+	if err != nil {
+		return fmt.Errorf("failed to add alert %d to group %d: %w", alert.ID, groupID, err)
+	}
+	return nil
+}
+
+// groupColumns liste les colonnes lues par scanGroup
+const groupColumns = `g.id, g.route_id, g.group_key, g.labels, g.flush_at, g.last_flush_at, g.created_at`
+
+// scanGroup lit un groupe d'alertes. flush_at et last_flush_at sont des timestamps Unix.
+func scanGroup(scanner rowScanner) (*models.AlertGroup, error) {
+	group := &models.AlertGroup{}
+	var labels string
+	var flushAt, lastFlushAt sql.NullInt64
+	if err := scanner.Scan(&group.ID, &group.RouteID, &group.Key, &labels, &flushAt, &lastFlushAt,
+		&group.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(labels), &group.Labels); err != nil {
+		return nil, fmt.Errorf("invalid labels of alert group %d: %w", group.ID, err)
+	}
+	if flushAt.Valid {
+		t := time.Unix(flushAt.Int64, 0)
+		group.FlushAt = &t
+	}
+	if lastFlushAt.Valid {
+		t := time.Unix(lastFlushAt.Int64, 0)
+		group.LastFlushAt = &t
+	}
+	return group, nil
+}
+
+// queryGroups exécute une requête de groupes d'alertes
+func (s *Store) queryGroups(query string, args ...interface{}) ([]*models.AlertGroup, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*models.AlertGroup{}
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// GetAlertGroups récupère les groupes d'alertes en cours avec leurs alertes
+func (s *Store) GetAlertGroups() ([]*models.AlertGroup, error) {
+	groups, err := s.queryGroups(`SELECT ` + groupColumns + ` FROM alert_groups g ORDER BY g.route_id ASC, g.group_key ASC`)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		members, err := s.GetAlertGroupMembers(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		group.Alerts = make([]*models.Alert, 0, len(members))
+		for _, member := range members {
+			group.Alerts = append(group.Alerts, member.Alert)
+		}
+	}
+	return groups, nil
+}
+
+// GetDueAlertGroups récupère les groupes dont la notification est échue, ou dont le rappel
+// (repeat_interval de la route) est échu
+func (s *Store) GetDueAlertGroups(now time.Time) ([]*models.AlertGroup, error) {
+	return s.queryGroups(`SELECT `+groupColumns+` FROM alert_groups g JOIN notify_routes r ON r.id = g.route_id
+			  WHERE g.flush_at <= ?
+			     OR (g.flush_at IS NULL AND r.repeat_interval_seconds > 0 AND g.last_flush_at + r.repeat_interval_seconds <= ?)
+			  ORDER BY g.id ASC`, now.Unix(), now.Unix())
+}
+
+// GetAlertGroupMembers récupère les alertes d'un groupe avec l'état sous lequel chacune a été notifiée
+func (s *Store) GetAlertGroupMembers(groupID int) ([]models.AlertGroupMember, error) {
+	rows, err := s.db.Query(`SELECT alert_id, notified_status FROM alert_group_members WHERE group_id = ?`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert group members: %w", err)
+	}
+	notified := map[int]string{}
+	for rows.Next() {
+		var alertID int
+		var status string
+		if err := rows.Scan(&alertID, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan alert group member: %w", err)
+		}
+		notified[alertID] = status
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get alert group members: %w", err)
+	}
+
+	alerts, err := s.queryAlerts(`SELECT `+alertColumns+` FROM alerts
+			  WHERE id IN (SELECT alert_id FROM alert_group_members WHERE group_id = ?) ORDER BY id ASC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]models.AlertGroupMember, 0, len(alerts))
+	for _, alert := range alerts {
+		members = append(members, models.AlertGroupMember{Alert: alert, NotifiedStatus: notified[alert.ID]})
+	}
+	return members, nil
+}
+
+// PostponeAlertGroup reporte la notification d'un groupe (plage de silence horaire de la route)
+func (s *Store) PostponeAlertGroup(groupID int, until time.Time) error {
+	if _, err := s.db.Exec(`UPDATE alert_groups SET flush_at = ? WHERE id = ?`, until.Unix(), groupID); err != nil {
+		return fmt.Errorf("failed to postpone alert group: %w", err)
+	}
+	return nil
+}
+
+// CompleteAlertGroupFlush enregistre la notification d'un groupe : notified associe à chaque alerte
+// l'état sous lequel elle a été notifiée. Les alertes notifiées résolues quittent le groupe,
+// qui est supprimé lorsqu'il est vide.
+func (s *Store) CompleteAlertGroupFlush(groupID int, notified map[int]string, now time.Time) error {
+	for alertID, status := range notified {
+		query := `UPDATE alert_group_members SET notified_status = ? WHERE group_id = ? AND alert_id = ?`
+		args := []interface{}{status, groupID, alertID}
+		if status == models.AlertStatusResolved {
+			query = `DELETE FROM alert_group_members WHERE group_id = ? AND alert_id = ?`
+			args = args[1:]
+		}
+		if _, err := s.db.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to update alert group member: %w", err)
+		}
+	}
+	if _, err := s.db.Exec(`UPDATE alert_groups SET flush_at = NULL, last_flush_at = ? WHERE id = ?`, now.Unix(), groupID); err != nil {
+		return fmt.Errorf("failed to update alert group: %w", err)
+	}
+	_, err := s.db.Exec(`DELETE FROM alert_groups WHERE id = ?
+			  AND NOT EXISTS (SELECT 1 FROM alert_group_members WHERE group_id = ?)`, groupID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete empty alert group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// ruleColumns liste les colonnes lues par scanRule
+const ruleColumns = `id, name, description, metric, scope, comparator, threshold, for_seconds, severity,
+	enabled, created_at, updated_at`
+
+// scanRule lit une règle d'alerte
+func scanRule(scanner rowScanner) (*models.AlertRule, error) {
+	rule := &models.AlertRule{}
+	var scope string
+	var forSeconds int64
+	if err := scanner.Scan(&rule.ID, &rule.Name, &rule.Description, &rule.Metric, &scope, &rule.Comparator,
+		&rule.Threshold, &forSeconds, &rule.Severity, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scope), &rule.Scope); err != nil {
+		return nil, fmt.Errorf("invalid scope of alert rule %d: %w", rule.ID, err)
+	}
+	rule.For = models.Duration(time.Duration(forSeconds) * time.Second)
+	return rule, nil
+}
+
+// GetAlertRules récupère les règles d'alerte, uniquement les règles actives si enabledOnly
+func (s *Store) GetAlertRules(enabledOnly bool) ([]*models.AlertRule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM alert_rules`
+	if enabledOnly {
+		query += ` WHERE enabled = 1`
+	}
+	rows, err := s.db.Query(query + ` ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []*models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetAlertRule récupère une règle d'alerte par ID
+func (s *Store) GetAlertRule(id int) (*models.AlertRule, error) {
+	rule, err := scanRule(s.db.QueryRow(`SELECT `+ruleColumns+` FROM alert_rules WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateAlertRule enregistre une nouvelle règle d'alerte
+func (s *Store) CreateAlertRule(rule *models.AlertRule) error {
+	scope, err := json.Marshal(rule.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to encode scope: %w", err)
+	}
+	now := time.Now()
+	result, err := s.db.Exec(`INSERT INTO alert_rules (name, description, metric, scope, comparator, threshold,
+			  for_seconds, severity, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.Name, rule.Description, rule.Metric, string(scope), rule.Comparator, rule.Threshold,
+		int64(time.Duration(rule.For).Seconds()), rule.Severity, rule.Enabled, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	rule.ID = int(id)
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	return nil
+}
+
+// UpdateAlertRule met à jour une règle d'alerte (sql.ErrNoRows si elle n'existe pas)
+func (s *Store) UpdateAlertRule(rule *models.AlertRule) error {
+	scope, err := json.Marshal(rule.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to encode scope: %w", err)
+	}
+	rule.UpdatedAt = time.Now()
+	result, err := s.db.Exec(`UPDATE alert_rules SET name = ?, description = ?, metric = ?, scope = ?, comparator = ?,
+			  threshold = ?, for_seconds = ?, severity = ?, enabled = ?, updated_at = ? WHERE id = ?`,
+		rule.Name, rule.Description, rule.Metric, string(scope), rule.Comparator, rule.Threshold,
+		int64(time.Duration(rule.For).Seconds()), rule.Severity, rule.Enabled, rule.UpdatedAt, rule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteAlertRule supprime une règle d'alerte et résout ses alertes ouvertes (sql.ErrNoRows si elle n'existe pas)
+func (s *Store) DeleteAlertRule(id int) error {
+	result, err := s.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = s.db.Exec(`UPDATE alerts SET status = ?, resolved_at = ? WHERE rule_id = ? AND status != ?`,
+		models.AlertStatusResolved, time.Now(), id, models.AlertStatusResolved)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alerts of rule %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetOpenRuleAlerts récupère les alertes non résolues créées par les règles
+func (s *Store) GetOpenRuleAlerts() ([]*models.Alert, error) {
+	return s.queryAlerts(`SELECT `+alertColumns+` FROM alerts
+			  WHERE rule_id IS NOT NULL AND fingerprint != '' AND status != ? ORDER BY id ASC`,
+		models.AlertStatusResolved)
+}
@@ -2,16 +2,20 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/secrets"
 
 	_ "modernc.org/sqlite"
 )
 
 // Store représente la couche d'accès aux données
 type Store struct {
-	db *sql.DB
+	db     *sql.DB
+	cipher *secrets.Cipher // chiffrement des secrets (connexions Proxmox)
 }
 
 // NewStore crée une nouvelle instance de Store
@@ -19,6 +23,11 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+// SetCipher configure le chiffrement utilisé pour les secrets stockés en base
+func (s *Store) SetCipher(cipher *secrets.Cipher) {
+	s.cipher = cipher
+}
+
 // Close ferme la connexion à la base de données
 func (s *Store) Close() error {
 	return s.db.Close()
@@ -45,6 +54,25 @@ func (s *Store) Migrate() error {
 	if _, err := s.db.Exec(appsSQL); err != nil {
 		return fmt.Errorf("failed to create apps table: %w", err)
 	}
+	if err := s.addColumnIfMissing("apps", "check_interval", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Créer la table health_checks (historique des health checks planifiés, horodatés en secondes Unix)
+	healthChecksSQL := `
+	CREATE TABLE IF NOT EXISTS health_checks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		app_id INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		status_code INTEGER,
+		error TEXT,
+		checked_at INTEGER NOT NULL
+	);`
+
+	if _, err := s.db.Exec(healthChecksSQL); err != nil {
+		return fmt.Errorf("failed to create health_checks table: %w", err)
+	}
 
 	// Créer la table alerts
 	alertsSQL := `
@@ -55,13 +83,115 @@ func (s *Store) Migrate() error {
 		title TEXT NOT NULL,
 		message TEXT NOT NULL,
 		payload TEXT,
+		labels TEXT NOT NULL DEFAULT '{}',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		acknowledged BOOLEAN NOT NULL DEFAULT FALSE
+		acknowledged BOOLEAN NOT NULL DEFAULT FALSE,
+		status TEXT NOT NULL DEFAULT 'firing',
+		fingerprint TEXT NOT NULL DEFAULT '',
+		rule_id INTEGER,
+		acknowledged_at DATETIME,
+		acknowledged_by TEXT NOT NULL DEFAULT '',
+		resolved_at DATETIME,
+		resolved_by TEXT NOT NULL DEFAULT '',
+		snoozed_until DATETIME,
+		assignee_id INTEGER,
+		assignee TEXT NOT NULL DEFAULT ''
 	);`
 
 	if _, err := s.db.Exec(alertsSQL); err != nil {
 		return fmt.Errorf("failed to create alerts table: %w", err)
 	}
+	if err := s.migrateAlerts(); err != nil {
+		return err
+	}
+
+	// Créer la table alert_rules (règles évaluées après chaque collecte de l'inventaire)
+	alertRulesSQL := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		metric TEXT NOT NULL,
+		scope TEXT NOT NULL DEFAULT '{}',
+		comparator TEXT NOT NULL,
+		threshold REAL NOT NULL,
+		for_seconds INTEGER NOT NULL DEFAULT 0,
+		severity TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := s.db.Exec(alertRulesSQL); err != nil {
+		return fmt.Errorf("failed to create alert_rules table: %w", err)
+	}
+
+	// Créer les tables silences et alert_comments (cycle de vie des alertes)
+	alertLifecycleSQL := `
+	CREATE TABLE IF NOT EXISTS silences (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		matchers TEXT NOT NULL,
+		starts_at TEXT NOT NULL,
+		ends_at TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+		user_id INTEGER,
+		username TEXT NOT NULL DEFAULT '',
+		body TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := s.db.Exec(alertLifecycleSQL); err != nil {
+		return fmt.Errorf("failed to create alert lifecycle tables: %w", err)
+	}
+
+	// Créer les tables de routage et de regroupement des notifications
+	notifyRoutesSQL := `
+	CREATE TABLE IF NOT EXISTS notify_routes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		severities TEXT NOT NULL DEFAULT '[]',
+		sources TEXT NOT NULL DEFAULT '[]',
+		matchers TEXT NOT NULL DEFAULT '[]',
+		subscription_ids TEXT NOT NULL DEFAULT '[]',
+		group_by TEXT NOT NULL DEFAULT '[]',
+		group_wait_seconds INTEGER NOT NULL DEFAULT 0,
+		repeat_interval_seconds INTEGER NOT NULL DEFAULT 0,
+		quiet_hours TEXT NOT NULL DEFAULT '',
+		continue BOOLEAN NOT NULL DEFAULT FALSE,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_groups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		route_id INTEGER NOT NULL REFERENCES notify_routes(id) ON DELETE CASCADE,
+		group_key TEXT NOT NULL,
+		labels TEXT NOT NULL DEFAULT '{}',
+		flush_at INTEGER,
+		last_flush_at INTEGER,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(route_id, group_key)
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_group_members (
+		group_id INTEGER NOT NULL REFERENCES alert_groups(id) ON DELETE CASCADE,
+		alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+		notified_status TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (group_id, alert_id)
+	);`
+
+	if _, err := s.db.Exec(notifyRoutesSQL); err != nil {
+		return fmt.Errorf("failed to create notification routing tables: %w", err)
+	}
 
 	// Créer la table notify_subscriptions
 	subscriptionsSQL := `
@@ -69,13 +199,42 @@ func (s *Store) Migrate() error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		channel TEXT NOT NULL,
 		endpoint TEXT NOT NULL,
+		secret TEXT NOT NULL DEFAULT '',
 		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		failure_count INTEGER NOT NULL DEFAULT 0,
+		disabled_at DATETIME,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 	);`
 
 	if _, err := s.db.Exec(subscriptionsSQL); err != nil {
 		return fmt.Errorf("failed to create notify_subscriptions table: %w", err)
 	}
+	if err := s.migrateNotifySubscriptions(); err != nil {
+		return err
+	}
+
+	// Créer la table webhook_deliveries (une ligne par tentative de livraison)
+	webhookDeliveriesSQL := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		subscription_id INTEGER NOT NULL REFERENCES notify_subscriptions(id) ON DELETE CASCADE,
+		delivery_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempt INTEGER NOT NULL DEFAULT 1,
+		state TEXT NOT NULL DEFAULT 'pending',
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		response TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		created_at TEXT NOT NULL,
+		completed_at TEXT
+	);`
+
+	if _, err := s.db.Exec(webhookDeliveriesSQL); err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries table: %w", err)
+	}
 
 	// Créer la table email_queue
 	emailQueueSQL := `
@@ -84,7 +243,11 @@ func (s *Store) Migrate() error {
 		to_addr TEXT NOT NULL,
 		subject TEXT NOT NULL,
 		body_text TEXT NOT NULL,
+		body_html TEXT NOT NULL DEFAULT '',
 		state TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		max_attempts INTEGER NOT NULL DEFAULT 5,
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
 		last_error TEXT,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		sent_at DATETIME
@@ -93,6 +256,9 @@ func (s *Store) Migrate() error {
 	if _, err := s.db.Exec(emailQueueSQL); err != nil {
 		return fmt.Errorf("failed to create email_queue table: %w", err)
 	}
+	if err := s.migrateEmailQueue(); err != nil {
+		return err
+	}
 
 	// Créer la table users (authentification)
 	usersSQL := `
@@ -129,6 +295,137 @@ func (s *Store) Migrate() error {
 		return fmt.Errorf("failed to create user_sessions table: %w", err)
 	}
 
+	// Créer la table roles (permissions des rôles intégrés et personnalisés)
+	rolesSQL := `
+	CREATE TABLE IF NOT EXISTS roles (
+		name        TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		permissions TEXT NOT NULL DEFAULT '[]',
+		built_in    BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at  TEXT DEFAULT (datetime('now')),
+		updated_at  TEXT DEFAULT (datetime('now'))
+	);`
+
+	if _, err := s.db.Exec(rolesSQL); err != nil {
+		return fmt.Errorf("failed to create roles table: %w", err)
+	}
+	if err := s.seedBuiltInRoles(); err != nil {
+		return err
+	}
+
+	// Créer la table proxmox_connections (secrets chiffrés)
+	proxmoxConnectionsSQL := `
+	CREATE TABLE IF NOT EXISTS proxmox_connections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL,
+		username TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		password TEXT,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := s.db.Exec(proxmoxConnectionsSQL); err != nil {
+		return fmt.Errorf("failed to create proxmox_connections table: %w", err)
+	}
+
+	// Créer la table prometheus_datasources (sources PromQL interrogées par le dashboard, secrets chiffrés)
+	prometheusDataSourcesSQL := `
+	CREATE TABLE IF NOT EXISTS prometheus_datasources (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL,
+		auth_type TEXT NOT NULL DEFAULT 'none',
+		username TEXT NOT NULL DEFAULT '',
+		secret TEXT NOT NULL DEFAULT '',
+		tls_skip_verify BOOLEAN NOT NULL DEFAULT FALSE,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := s.db.Exec(prometheusDataSourcesSQL); err != nil {
+		return fmt.Errorf("failed to create prometheus_datasources table: %w", err)
+	}
+
+	// Créer la table proxmox_snapshots (dernier inventaire collecté par le poller)
+	proxmoxSnapshotsSQL := `
+	CREATE TABLE IF NOT EXISTS proxmox_snapshots (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		collected_at DATETIME NOT NULL,
+		data TEXT NOT NULL
+	);`
+
+	if _, err := s.db.Exec(proxmoxSnapshotsSQL); err != nil {
+		return fmt.Errorf("failed to create proxmox_snapshots table: %w", err)
+	}
+
+	// Créer la table provision_jobs (clonage de templates suivi comme un job)
+	provisionJobsSQL := `
+	CREATE TABLE IF NOT EXISTS provision_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		connection_id INTEGER,
+		spec TEXT NOT NULL,
+		status TEXT NOT NULL,
+		step TEXT NOT NULL,
+		progress INTEGER NOT NULL DEFAULT 0,
+		vmid INTEGER NOT NULL DEFAULT 0,
+		upid TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		created_by INTEGER,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		finished_at DATETIME
+	);`
+
+	if _, err := s.db.Exec(provisionJobsSQL); err != nil {
+		return fmt.Errorf("failed to create provision_jobs table: %w", err)
+	}
+
+	// Créer la table metrics (historique: brut, agrégats 5 min et horaires)
+	metricsSQL := `
+	CREATE TABLE IF NOT EXISTS metrics (
+		resolution INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		cluster TEXT NOT NULL DEFAULT '',
+		object_id TEXT NOT NULL,
+		ts INTEGER NOT NULL,
+		cpu REAL,
+		memory REAL,
+		disk REAL,
+		samples INTEGER NOT NULL DEFAULT 1,
+		PRIMARY KEY (resolution, kind, cluster, object_id, ts)
+	);`
+
+	if _, err := s.db.Exec(metricsSQL); err != nil {
+		return fmt.Errorf("failed to create metrics table: %w", err)
+	}
+
+	// Créer la table audit_log (journal des actions, conservé par ClearDatabase)
+	auditLogSQL := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp TEXT NOT NULL,
+		user_id INTEGER,
+		username TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		target TEXT NOT NULL DEFAULT '',
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		ip_address TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		status INTEGER NOT NULL,
+		result TEXT NOT NULL,
+		upid TEXT NOT NULL DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0
+	);`
+
+	if _, err := s.db.Exec(auditLogSQL); err != nil {
+		return fmt.Errorf("failed to create audit_log table: %w", err)
+	}
+
 	// Créer les index pour les utilisateurs
 	indexesSQL := []string{
 		"CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);",
@@ -137,6 +434,22 @@ func (s *Store) Migrate() error {
 		"CREATE INDEX IF NOT EXISTS idx_sessions_token ON user_sessions(token);",
 		"CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON user_sessions(user_id);",
 		"CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON user_sessions(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_metrics_retention ON metrics(resolution, ts);",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_username ON audit_log(username, timestamp);",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, timestamp);",
+		"CREATE INDEX IF NOT EXISTS idx_email_queue_state ON email_queue(state, next_attempt_at);",
+		"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(state, next_attempt_at);",
+		"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);",
+		// Une seule alerte ouverte par condition (fingerprint)
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open_fingerprint ON alerts(fingerprint) WHERE fingerprint != '' AND status != 'resolved';",
+		"CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status, created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_silences_ends_at ON silences(ends_at);",
+		"CREATE INDEX IF NOT EXISTS idx_alert_comments_alert ON alert_comments(alert_id, id);",
+		"CREATE INDEX IF NOT EXISTS idx_alert_groups_flush ON alert_groups(flush_at);",
+		"CREATE INDEX IF NOT EXISTS idx_health_checks_app ON health_checks(app_id, checked_at);",
+		"CREATE INDEX IF NOT EXISTS idx_health_checks_checked_at ON health_checks(checked_at);",
+		"CREATE INDEX IF NOT EXISTS idx_provision_jobs_status ON provision_jobs(status);",
 	}
 
 	for _, indexSQL := range indexesSQL {
@@ -148,16 +461,135 @@ func (s *Store) Migrate() error {
 	return nil
 }
 
-// ClearDatabase vide complètement toutes les tables de la base de données sans recréer de données
+// migrateEmailQueue ajoute les colonnes de relance aux tables email_queue créées par les versions précédentes
+// et convertit les anciens états (queued, error)
+func (s *Store) migrateEmailQueue() error {
+	columns := []struct{ name, definition string }{
+		{"body_html", "TEXT NOT NULL DEFAULT ''"},
+		{"attempts", "INTEGER NOT NULL DEFAULT 0"},
+		{"max_attempts", "INTEGER NOT NULL DEFAULT 5"},
+		{"next_attempt_at", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, column := range columns {
+		if err := s.addColumnIfMissing("email_queue", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.Exec(`UPDATE email_queue SET state = 'pending' WHERE state = 'queued'`); err != nil {
+		return fmt.Errorf("failed to migrate queued emails: %w", err)
+	}
+	if _, err := s.db.Exec(`UPDATE email_queue SET state = 'dead' WHERE state = 'error'`); err != nil {
+		return fmt.Errorf("failed to migrate failed emails: %w", err)
+	}
+	return nil
+}
+
+// migrateAlerts ajoute les colonnes des règles et du cycle de vie des alertes
+// aux tables alerts créées par les versions précédentes
+func (s *Store) migrateAlerts() error {
+	columns := []struct{ name, definition string }{
+		{"status", "TEXT NOT NULL DEFAULT 'firing'"},
+		{"fingerprint", "TEXT NOT NULL DEFAULT ''"},
+		{"rule_id", "INTEGER"},
+		{"resolved_at", "DATETIME"},
+		{"labels", "TEXT NOT NULL DEFAULT '{}'"},
+		{"acknowledged_at", "DATETIME"},
+		{"acknowledged_by", "TEXT NOT NULL DEFAULT ''"},
+		{"resolved_by", "TEXT NOT NULL DEFAULT ''"},
+		{"snoozed_until", "DATETIME"},
+		{"assignee_id", "INTEGER"},
+		{"assignee", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, column := range columns {
+		if err := s.addColumnIfMissing("alerts", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	// Les alertes acquittées avant le cycle de vie passent à l'état acknowledged
+	if _, err := s.db.Exec(`UPDATE alerts SET status = ? WHERE acknowledged = 1 AND status = ?`,
+		models.AlertStatusAcknowledged, models.AlertStatusFiring); err != nil {
+		return fmt.Errorf("failed to migrate acknowledged alerts: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing ajoute une colonne à une table existante si elle n'existe pas encore
+func (s *Store) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// builtInRoleDescriptions décrit les rôles intégrés
+var builtInRoleDescriptions = map[models.UserRole]string{
+	models.RoleAdmin:  "Accès complet à toutes les fonctionnalités",
+	models.RoleUser:   "Gestion des applications, alertes et invités Proxmox",
+	models.RoleViewer: "Lecture seule des données",
+	models.RoleGuest:  "Accès très limité",
+}
+
+// seedBuiltInRoles insère les rôles intégrés absents de la table roles
+func (s *Store) seedBuiltInRoles() error {
+	for role, permissions := range models.RolePermissions {
+		data, err := json.Marshal(permissions)
+		if err != nil {
+			return fmt.Errorf("failed to encode permissions of role %s: %w", role, err)
+		}
+		_, err = s.db.Exec(`INSERT OR IGNORE INTO roles (name, description, permissions, built_in) VALUES (?, ?, ?, 1)`,
+			role, builtInRoleDescriptions[role], string(data))
+		if err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// ClearDatabase vide les données du dashboard sans recréer de données.
+// Les tables d'identité (utilisateurs, sessions, rôles) et le journal d'audit sont conservés.
 func (s *Store) ClearDatabase() error {
 	// Liste de toutes les tables à vider
 	tables := []string{
 		"email_queue",
+		"webhook_deliveries",
+		"alert_group_members",
+		"alert_groups",
+		"notify_routes",
 		"notify_subscriptions",
+		"alert_comments",
+		"silences",
 		"alerts",
+		"alert_rules",
+		"health_checks",
 		"apps",
-		"user_sessions",
-		"users",
+		"proxmox_connections",
+		"proxmox_snapshots",
+		"provision_jobs",
+		"prometheus_datasources",
+		"metrics",
 	}
 
 	// Vider chaque table
@@ -168,6 +600,10 @@ func (s *Store) ClearDatabase() error {
 		}
 	}
 
+	// Le journal d'audit (audit_log) n'est jamais vidé : la remise à zéro y est elle-même tracée.
+	// Les utilisateurs, leurs sessions et les rôles ne sont pas touchés : vider la base ne doit
+	// ni déconnecter l'administrateur ni retirer les permissions accordées.
+
 	// Réinitialiser les séquences AUTOINCREMENT
 	for _, table := range tables {
 		query := fmt.Sprintf("DELETE FROM sqlite_sequence WHERE name='%s'", table)
@@ -183,11 +619,11 @@ func (s *Store) ClearDatabase() error {
 
 // CreateApp crée une nouvelle application
 func (s *Store) CreateApp(app *models.App) error {
-	query := `INSERT INTO apps (name, protocol, host, port, path, tag, icon, health_path, health_type, created_at)
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO apps (name, protocol, host, port, path, tag, icon, health_path, health_type, check_interval, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	result, err := s.db.Exec(query, app.Name, app.Protocol, app.Host, app.Port, app.Path,
-		app.Tag, app.Icon, app.HealthPath, app.HealthType, app.CreatedAt)
+		app.Tag, app.Icon, app.HealthPath, app.HealthType, app.CheckInterval, app.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create app: %w", err)
 	}
@@ -203,14 +639,14 @@ func (s *Store) CreateApp(app *models.App) error {
 
 // GetApp récupère une application par ID
 func (s *Store) GetApp(id int) (*models.App, error) {
-	query := `SELECT id, name, protocol, host, port, path, tag, icon, health_path, health_type, created_at
+	query := `SELECT id, name, protocol, host, port, path, tag, icon, health_path, health_type, check_interval, created_at
 			  FROM apps WHERE id = ?`
 
 	row := s.db.QueryRow(query, id)
 
 	app := &models.App{}
 	err := row.Scan(&app.ID, &app.Name, &app.Protocol, &app.Host, &app.Port, &app.Path,
-		&app.Tag, &app.Icon, &app.HealthPath, &app.HealthType, &app.CreatedAt)
+		&app.Tag, &app.Icon, &app.HealthPath, &app.HealthType, &app.CheckInterval, &app.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get app: %w", err)
 	}
@@ -220,7 +656,7 @@ func (s *Store) GetApp(id int) (*models.App, error) {
 
 // GetApps récupère toutes les applications
 func (s *Store) GetApps() ([]*models.App, error) {
-	query := `SELECT id, name, protocol, host, port, path, tag, icon, health_path, health_type, created_at
+	query := `SELECT id, name, protocol, host, port, path, tag, icon, health_path, health_type, check_interval, created_at
 			  FROM apps ORDER BY created_at DESC`
 
 	rows, err := s.db.Query(query)
@@ -233,7 +669,7 @@ func (s *Store) GetApps() ([]*models.App, error) {
 	for rows.Next() {
 		app := &models.App{}
 		err := rows.Scan(&app.ID, &app.Name, &app.Protocol, &app.Host, &app.Port, &app.Path,
-			&app.Tag, &app.Icon, &app.HealthPath, &app.HealthType, &app.CreatedAt)
+			&app.Tag, &app.Icon, &app.HealthPath, &app.HealthType, &app.CheckInterval, &app.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan app: %w", err)
 		}
@@ -246,10 +682,10 @@ func (s *Store) GetApps() ([]*models.App, error) {
 // UpdateApp met à jour une application
 func (s *Store) UpdateApp(id int, req models.CreateAppRequest) error {
 	query := `UPDATE apps SET name = ?, protocol = ?, host = ?, port = ?, path = ?,
-			  tag = ?, icon = ?, health_path = ?, health_type = ? WHERE id = ?`
+			  tag = ?, icon = ?, health_path = ?, health_type = ?, check_interval = ? WHERE id = ?`
 
 	_, err := s.db.Exec(query, req.Name, req.Protocol, req.Host, req.Port, req.Path,
-		req.Tag, req.Icon, req.HealthPath, req.HealthType, id)
+		req.Tag, req.Icon, req.HealthPath, req.HealthType, req.CheckInterval, id)
 	if err != nil {
 		return fmt.Errorf("failed to update app: %w", err)
 	}
@@ -257,7 +693,7 @@ func (s *Store) UpdateApp(id int, req models.CreateAppRequest) error {
 	return nil
 }
 
-// DeleteApp supprime une application
+// DeleteApp supprime une application et l'historique de ses health checks
 func (s *Store) DeleteApp(id int) error {
 	query := `DELETE FROM apps WHERE id = ?`
 
@@ -265,17 +701,34 @@ func (s *Store) DeleteApp(id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete app: %w", err)
 	}
+	if _, err := s.db.Exec(`DELETE FROM health_checks WHERE app_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete health checks of app %d: %w", id, err)
+	}
 
 	return nil
 }
 
 // CreateAlert crée une nouvelle alerte
 func (s *Store) CreateAlert(alert *models.Alert) error {
-	query := `INSERT INTO alerts (source, severity, title, message, payload, created_at, acknowledged)
-			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if alert.Status == "" {
+		alert.Status = models.AlertStatusFiring
+		if alert.Acknowledged {
+			alert.Status = models.AlertStatusAcknowledged
+		}
+	}
+
+	labels, err := json.Marshal(alert.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to encode labels: %w", err)
+	}
+
+	query := `INSERT INTO alerts (source, severity, title, message, payload, labels, created_at, acknowledged,
+			  status, fingerprint, rule_id, resolved_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	result, err := s.db.Exec(query, alert.Source, alert.Severity, alert.Title, alert.Message,
-		alert.Payload, alert.CreatedAt, alert.Acknowledged)
+		alert.Payload, string(labels), alert.CreatedAt, alert.Acknowledged, alert.Status, alert.Fingerprint,
+		alert.RuleID, alert.ResolvedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create alert: %w", err)
 	}
@@ -291,10 +744,59 @@ func (s *Store) CreateAlert(alert *models.Alert) error {
 
 // GetAlerts récupère toutes les alertes
 func (s *Store) GetAlerts() ([]*models.Alert, error) {
-	query := `SELECT id, source, severity, title, message, payload, created_at, acknowledged
-			  FROM alerts ORDER BY created_at DESC`
+	return s.queryAlerts(`SELECT ` + alertColumns + ` FROM alerts ORDER BY created_at DESC`)
+}
 
-	rows, err := s.db.Query(query)
+// alertColumns liste les colonnes lues par scanAlert
+const alertColumns = `id, source, severity, title, message, payload, labels, created_at, acknowledged,
+	status, fingerprint, rule_id, acknowledged_at, acknowledged_by, resolved_at, resolved_by,
+	snoozed_until, assignee_id, assignee`
+
+// scanAlert lit une alerte
+func scanAlert(scanner rowScanner) (*models.Alert, error) {
+	alert := &models.Alert{}
+	var labels string
+	var ruleID, assigneeID sql.NullInt64
+	var acknowledgedAt, resolvedAt, snoozedUntil sql.NullTime
+	if err := scanner.Scan(&alert.ID, &alert.Source, &alert.Severity, &alert.Title, &alert.Message,
+		&alert.Payload, &labels, &alert.CreatedAt, &alert.Acknowledged, &alert.Status, &alert.Fingerprint,
+		&ruleID, &acknowledgedAt, &alert.AcknowledgedBy, &resolvedAt, &alert.ResolvedBy,
+		&snoozedUntil, &assigneeID, &alert.Assignee); err != nil {
+		return nil, err
+	}
+	if labels != "" && labels != "{}" && labels != "null" {
+		if err := json.Unmarshal([]byte(labels), &alert.Labels); err != nil {
+			return nil, fmt.Errorf("invalid labels of alert %d: %w", alert.ID, err)
+		}
+	}
+	alert.RuleID = nullIntPtr(ruleID)
+	alert.AssigneeID = nullIntPtr(assigneeID)
+	alert.AcknowledgedAt = nullTimePtr(acknowledgedAt)
+	alert.ResolvedAt = nullTimePtr(resolvedAt)
+	alert.SnoozedUntil = nullTimePtr(snoozedUntil)
+	return alert, nil
+}
+
+// nullIntPtr convertit un entier nullable en pointeur
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	n := int(value.Int64)
+	return &n
+}
+
+// nullTimePtr convertit une date nullable en pointeur
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// queryAlerts exécute une requête retournant des alertes
+func (s *Store) queryAlerts(query string, args ...interface{}) ([]*models.Alert, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get alerts: %w", err)
 	}
@@ -302,44 +804,34 @@ func (s *Store) GetAlerts() ([]*models.Alert, error) {
 
 	var alerts []*models.Alert
 	for rows.Next() {
-		alert := &models.Alert{}
-		err := rows.Scan(&alert.ID, &alert.Source, &alert.Severity, &alert.Title, &alert.Message,
-			&alert.Payload, &alert.CreatedAt, &alert.Acknowledged)
+		alert, err := scanAlert(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
 		alerts = append(alerts, alert)
 	}
 
-	return alerts, nil
+	return alerts, rows.Err()
 }
 
-// AcknowledgeAlert marque une alerte comme acquittée
-func (s *Store) AcknowledgeAlert(id int) error {
-	query := `UPDATE alerts SET acknowledged = 1 WHERE id = ?`
-	_, err := s.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to acknowledge alert: %w", err)
-	}
-	return nil
-}
-
-// AcknowledgeAllAlerts marque toutes les alertes comme acquittées
-func (s *Store) AcknowledgeAllAlerts() error {
-	query := `UPDATE alerts SET acknowledged = 1 WHERE acknowledged = 0`
-	_, err := s.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to acknowledge all alerts: %w", err)
+// CreateNotificationSubscription crée un nouvel abonnement.
+// Le secret des webhooks est chiffré avec la clé ENCRYPTION_KEY.
+func (s *Store) CreateNotificationSubscription(sub *models.NotifySubscription) error {
+	var secret string
+	if sub.Secret != "" {
+		if s.cipher == nil {
+			return ErrNoCipher
+		}
+		var err error
+		if secret, err = s.cipher.Encrypt(sub.Secret); err != nil {
+			return err
+		}
 	}
-	return nil
-}
 
-// CreateNotificationSubscription crée un nouvel abonnement
-func (s *Store) CreateNotificationSubscription(sub *models.NotifySubscription) error {
-	query := `INSERT INTO notify_subscriptions (channel, endpoint, enabled, created_at)
-			  VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO notify_subscriptions (channel, endpoint, secret, enabled, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
 
-	result, err := s.db.Exec(query, sub.Channel, sub.Endpoint, sub.Enabled, sub.CreatedAt)
+	result, err := s.db.Exec(query, sub.Channel, sub.Endpoint, secret, sub.Enabled, sub.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create notification subscription: %w", err)
 	}
@@ -353,9 +845,23 @@ func (s *Store) CreateNotificationSubscription(sub *models.NotifySubscription) e
 	return nil
 }
 
-// GetNotificationSubscriptions récupère tous les abonnements
+// subscriptionColumns liste les colonnes lues par scanSubscription (sans le secret)
+const subscriptionColumns = `id, channel, endpoint, enabled, failure_count, disabled_at, created_at`
+
+// scanSubscription lit un abonnement
+func scanSubscription(scanner rowScanner, extra ...interface{}) (*models.NotifySubscription, error) {
+	sub := &models.NotifySubscription{}
+	dest := append([]interface{}{&sub.ID, &sub.Channel, &sub.Endpoint, &sub.Enabled, &sub.FailureCount,
+		&sub.DisabledAt, &sub.CreatedAt}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetNotificationSubscriptions récupère tous les abonnements (sans leurs secrets)
 func (s *Store) GetNotificationSubscriptions() ([]*models.NotifySubscription, error) {
-	query := `SELECT id, channel, endpoint, enabled, created_at
+	query := `SELECT ` + subscriptionColumns + `
 			  FROM notify_subscriptions ORDER BY created_at DESC`
 
 	rows, err := s.db.Query(query)
@@ -366,23 +872,42 @@ func (s *Store) GetNotificationSubscriptions() ([]*models.NotifySubscription, er
 
 	var subs []*models.NotifySubscription
 	for rows.Next() {
-		sub := &models.NotifySubscription{}
-		err := rows.Scan(&sub.ID, &sub.Channel, &sub.Endpoint, &sub.Enabled, &sub.CreatedAt)
+		sub, err := scanSubscription(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscription: %w", err)
 		}
 		subs = append(subs, sub)
 	}
 
-	return subs, nil
+	return subs, rows.Err()
+}
+
+// GetNotificationSubscription récupère un abonnement par ID (sans son secret)
+func (s *Store) GetNotificationSubscription(id int) (*models.NotifySubscription, error) {
+	sub, err := scanSubscription(s.db.QueryRow(`SELECT `+subscriptionColumns+` FROM notify_subscriptions WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification subscription: %w", err)
+	}
+	return sub, nil
 }
 
 // CreateEmailQueue crée un nouvel email en queue
 func (s *Store) CreateEmailQueue(email *models.EmailQueue) error {
-	query := `INSERT INTO email_queue (to_addr, subject, body_text, state, created_at)
-			  VALUES (?, ?, ?, ?, ?)`
+	if email.State == "" {
+		email.State = models.EmailStatePending
+	}
+	if email.MaxAttempts <= 0 {
+		email.MaxAttempts = models.DefaultEmailMaxAttempts
+	}
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO email_queue (to_addr, subject, body_text, body_html, state, max_attempts, next_attempt_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := s.db.Exec(query, email.ToAddr, email.Subject, email.BodyText, email.State, email.CreatedAt)
+	result, err := s.db.Exec(query, email.ToAddr, email.Subject, email.BodyText, email.BodyHTML, email.State,
+		email.MaxAttempts, email.NextAttemptAt.Unix(), email.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create email queue: %w", err)
 	}
@@ -396,29 +921,52 @@ func (s *Store) CreateEmailQueue(email *models.EmailQueue) error {
 	return nil
 }
 
-// GetPendingEmails récupère les emails en attente
-func (s *Store) GetPendingEmails() ([]*models.EmailQueue, error) {
-	query := `SELECT id, to_addr, subject, body_text, state, last_error, created_at, sent_at
-			  FROM email_queue WHERE state = 'pending' ORDER BY created_at ASC`
+// emailColumns liste les colonnes lues par scanEmail
+const emailColumns = `id, to_addr, subject, body_text, body_html, state, attempts, max_attempts, next_attempt_at,
+			  last_error, created_at, sent_at`
 
-	rows, err := s.db.Query(query)
+// rowScanner est implémenté par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEmail lit un email de la file d'attente
+func scanEmail(scanner rowScanner) (*models.EmailQueue, error) {
+	email := &models.EmailQueue{}
+	var nextAttempt int64
+	err := scanner.Scan(&email.ID, &email.ToAddr, &email.Subject, &email.BodyText, &email.BodyHTML,
+		&email.State, &email.Attempts, &email.MaxAttempts, &nextAttempt, &email.LastError, &email.CreatedAt, &email.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	email.NextAttemptAt = time.Unix(nextAttempt, 0)
+	return email, nil
+}
+
+// queryEmails exécute une requête sur email_queue et retourne les emails lus
+func (s *Store) queryEmails(query string, args ...interface{}) ([]*models.EmailQueue, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pending emails: %w", err)
+		return nil, fmt.Errorf("failed to get emails: %w", err)
 	}
 	defer rows.Close()
 
 	var emails []*models.EmailQueue
 	for rows.Next() {
-		email := &models.EmailQueue{}
-		err := rows.Scan(&email.ID, &email.ToAddr, &email.Subject, &email.BodyText,
-			&email.State, &email.LastError, &email.CreatedAt, &email.SentAt)
+		email, err := scanEmail(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan email: %w", err)
 		}
 		emails = append(emails, email)
 	}
 
-	return emails, nil
+	return emails, rows.Err()
+}
+
+// GetPendingEmails récupère les emails en attente
+func (s *Store) GetPendingEmails() ([]*models.EmailQueue, error) {
+	return s.queryEmails(`SELECT `+emailColumns+` FROM email_queue WHERE state = ? ORDER BY created_at ASC`,
+		models.EmailStatePending)
 }
 
 // UpdateEmailStatus met à jour le statut d'un email
@@ -435,14 +983,7 @@ func (s *Store) UpdateEmailStatus(id int, status string) error {
 
 // GetEmailQueue récupère un email par ID
 func (s *Store) GetEmailQueue(id int) (*models.EmailQueue, error) {
-	query := `SELECT id, to_addr, subject, body_text, state, last_error, created_at, sent_at
-			  FROM email_queue WHERE id = ?`
-
-	row := s.db.QueryRow(query, id)
-
-	email := &models.EmailQueue{}
-	err := row.Scan(&email.ID, &email.ToAddr, &email.Subject, &email.BodyText,
-		&email.State, &email.LastError, &email.CreatedAt, &email.SentAt)
+	email, err := scanEmail(s.db.QueryRow(`SELECT `+emailColumns+` FROM email_queue WHERE id = ?`, id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get email: %w", err)
 	}
@@ -450,23 +991,59 @@ func (s *Store) GetEmailQueue(id int) (*models.EmailQueue, error) {
 	return email, nil
 }
 
-// GetQueuedEmails récupère les emails en queue (alias pour GetPendingEmails)
-func (s *Store) GetQueuedEmails() ([]*models.EmailQueue, error) {
-	return s.GetPendingEmails()
+// GetQueuedEmails récupère les emails à envoyer : en attente ou en échec temporaire dont la
+// prochaine tentative est échue, dans l'ordre d'arrivée
+func (s *Store) GetQueuedEmails(now time.Time, limit int) ([]*models.EmailQueue, error) {
+	return s.queryEmails(`SELECT `+emailColumns+` FROM email_queue
+			  WHERE state IN (?, ?) AND next_attempt_at <= ? ORDER BY id ASC LIMIT ?`,
+		models.EmailStatePending, models.EmailStateFailed, now.Unix(), limit)
+}
+
+// GetEmailsByState récupère les emails d'un état donné, les plus récents d'abord (ex: dead-letter)
+func (s *Store) GetEmailsByState(state string, limit int) ([]*models.EmailQueue, error) {
+	return s.queryEmails(`SELECT `+emailColumns+` FROM email_queue WHERE state = ? ORDER BY id DESC LIMIT ?`,
+		state, limit)
 }
 
-// MarkEmailError marque un email comme ayant une erreur
-func (s *Store) MarkEmailError(id int, errorMsg string) error {
-	query := `UPDATE email_queue SET state = 'error', last_error = ? WHERE id = ?`
-	_, err := s.db.Exec(query, errorMsg, id)
-	return err
+// MarkEmailRetry enregistre l'échec d'une tentative et planifie la suivante
+func (s *Store) MarkEmailRetry(id int, errorMsg string, nextAttempt time.Time) error {
+	query := `UPDATE email_queue SET state = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+			  WHERE id = ?`
+	if _, err := s.db.Exec(query, models.EmailStateFailed, errorMsg, nextAttempt.Unix(), id); err != nil {
+		return fmt.Errorf("failed to schedule email retry: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailDead enregistre l'échec définitif d'un email (dead-letter)
+func (s *Store) MarkEmailDead(id int, errorMsg string) error {
+	query := `UPDATE email_queue SET state = ?, attempts = attempts + 1, last_error = ? WHERE id = ?`
+	if _, err := s.db.Exec(query, models.EmailStateDead, errorMsg, id); err != nil {
+		return fmt.Errorf("failed to mark email as dead: %w", err)
+	}
+	return nil
 }
 
 // MarkEmailSent marque un email comme envoyé
 func (s *Store) MarkEmailSent(id int) error {
-	query := `UPDATE email_queue SET state = 'sent', sent_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := s.db.Exec(query, id)
-	return err
+	query := `UPDATE email_queue SET state = ?, attempts = attempts + 1, sent_at = CURRENT_TIMESTAMP WHERE id = ?`
+	if _, err := s.db.Exec(query, models.EmailStateSent, id); err != nil {
+		return fmt.Errorf("failed to mark email as sent: %w", err)
+	}
+	return nil
+}
+
+// RequeueEmail remet un email abandonné dans la file d'attente avec un nouveau jeu de tentatives
+func (s *Store) RequeueEmail(id int) error {
+	query := `UPDATE email_queue SET state = ?, attempts = 0, next_attempt_at = 0 WHERE id = ?`
+	result, err := s.db.Exec(query, models.EmailStatePending, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue email: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
 // EnqueueEmail ajoute un email à la queue (alias pour CreateEmailQueue)
@@ -0,0 +1,234 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// migrateNotifySubscriptions ajoute les colonnes des webhooks aux tables notify_subscriptions
+// créées par les versions précédentes
+func (s *Store) migrateNotifySubscriptions() error {
+	columns := []struct{ name, definition string }{
+		{"secret", "TEXT NOT NULL DEFAULT ''"},
+		{"failure_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"disabled_at", "DATETIME"},
+	}
+	for _, column := range columns {
+		if err := s.addColumnIfMissing("notify_subscriptions", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEnabledSubscriptions récupère les abonnements actifs d'un canal avec leur secret déchiffré
+func (s *Store) GetEnabledSubscriptions(channel string) ([]*models.NotifySubscription, error) {
+	query := `SELECT ` + subscriptionColumns + `, secret
+			  FROM notify_subscriptions WHERE channel = ? AND enabled = 1 ORDER BY id ASC`
+
+	rows, err := s.db.Query(query, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s subscriptions: %w", channel, err)
+	}
+	defer rows.Close()
+
+	var subs []*models.NotifySubscription
+	for rows.Next() {
+		var secret string
+		sub, err := scanSubscription(rows, &secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		if err := s.decryptSubscriptionSecret(sub, secret); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// GetSubscriptionWithSecret récupère un abonnement par ID avec son secret déchiffré
+func (s *Store) GetSubscriptionWithSecret(id int) (*models.NotifySubscription, error) {
+	var secret string
+	sub, err := scanSubscription(s.db.QueryRow(`SELECT `+subscriptionColumns+`, secret FROM notify_subscriptions WHERE id = ?`, id), &secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification subscription: %w", err)
+	}
+	if err := s.decryptSubscriptionSecret(sub, secret); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// decryptSubscriptionSecret déchiffre le secret d'un abonnement lu en base
+func (s *Store) decryptSubscriptionSecret(sub *models.NotifySubscription, secret string) error {
+	if secret == "" {
+		return nil
+	}
+	if s.cipher == nil {
+		return ErrNoCipher
+	}
+	var err error
+	if sub.Secret, err = s.cipher.Decrypt(secret); err != nil {
+		return fmt.Errorf("failed to decrypt secret of subscription %d: %w", sub.ID, err)
+	}
+	return nil
+}
+
+// SetSubscriptionEnabled active ou désactive un abonnement. La réactivation remet à zéro le compteur
+// d'échecs ; la désactivation annule les livraisons en attente.
+func (s *Store) SetSubscriptionEnabled(id int, enabled bool) error {
+	query := `UPDATE notify_subscriptions SET enabled = ?, failure_count = 0, disabled_at = NULL WHERE id = ?`
+	if !enabled {
+		query = `UPDATE notify_subscriptions SET enabled = ? WHERE id = ?`
+	}
+	result, err := s.db.Exec(query, enabled, id)
+	if err != nil {
+		return fmt.Errorf("failed to update notification subscription: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if !enabled {
+		return s.CancelPendingDeliveries(id)
+	}
+	return nil
+}
+
+// RecordSubscriptionSuccess remet à zéro le compteur d'échecs consécutifs d'un abonnement
+func (s *Store) RecordSubscriptionSuccess(id int) error {
+	if _, err := s.db.Exec(`UPDATE notify_subscriptions SET failure_count = 0 WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to reset subscription failures: %w", err)
+	}
+	return nil
+}
+
+// RecordSubscriptionFailure incrémente le compteur d'échecs consécutifs d'un abonnement et le désactive
+// quand il atteint disableAfter (0 pour ne jamais désactiver). Retourne true si l'abonnement a été désactivé.
+func (s *Store) RecordSubscriptionFailure(id, disableAfter int) (bool, error) {
+	if _, err := s.db.Exec(`UPDATE notify_subscriptions SET failure_count = failure_count + 1 WHERE id = ?`, id); err != nil {
+		return false, fmt.Errorf("failed to record subscription failure: %w", err)
+	}
+	if disableAfter <= 0 {
+		return false, nil
+	}
+
+	result, err := s.db.Exec(`UPDATE notify_subscriptions SET enabled = 0, disabled_at = ?
+			  WHERE id = ? AND enabled = 1 AND failure_count >= ?`, time.Now(), id, disableAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable subscription: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	return true, s.CancelPendingDeliveries(id)
+}
+
+// CreateWebhookDelivery planifie une tentative de livraison
+func (s *Store) CreateWebhookDelivery(d *models.WebhookDelivery) error {
+	if d.State == "" {
+		d.State = models.DeliveryStatePending
+	}
+	if d.Attempt == 0 {
+		d.Attempt = 1
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO webhook_deliveries (subscription_id, delivery_id, event, payload, attempt, state,
+			  next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(query, d.SubscriptionID, d.DeliveryID, d.Event, d.Payload, d.Attempt, d.State,
+		d.NextAttemptAt.Unix(), d.CreatedAt.UTC().Format(timestampLayout))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	d.ID = int(id)
+	return nil
+}
+
+// CompleteWebhookDelivery enregistre le résultat d'une tentative de livraison
+func (s *Store) CompleteWebhookDelivery(d *models.WebhookDelivery) error {
+	completedAt := time.Now()
+	if d.CompletedAt != nil {
+		completedAt = *d.CompletedAt
+	}
+	query := `UPDATE webhook_deliveries SET state = ?, status_code = ?, response = ?, error = ?, duration_ms = ?,
+			  completed_at = ? WHERE id = ?`
+	if _, err := s.db.Exec(query, d.State, d.StatusCode, d.Response, d.Error, d.DurationMs,
+		completedAt.UTC().Format(timestampLayout), d.ID); err != nil {
+		return fmt.Errorf("failed to complete webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// CancelPendingDeliveries annule les livraisons en attente d'un abonnement
+func (s *Store) CancelPendingDeliveries(subscriptionID int) error {
+	query := `UPDATE webhook_deliveries SET state = ?, completed_at = ? WHERE subscription_id = ? AND state = ?`
+	if _, err := s.db.Exec(query, models.DeliveryStateCancelled, time.Now().UTC().Format(timestampLayout),
+		subscriptionID, models.DeliveryStatePending); err != nil {
+		return fmt.Errorf("failed to cancel pending deliveries: %w", err)
+	}
+	return nil
+}
+
+// deliveryColumns liste les colonnes lues par queryDeliveries
+const deliveryColumns = `id, subscription_id, delivery_id, event, payload, attempt, state, next_attempt_at,
+			  status_code, response, error, duration_ms, created_at, completed_at`
+
+// queryDeliveries exécute une requête sur webhook_deliveries et retourne les tentatives lues
+func (s *Store) queryDeliveries(query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var nextAttempt int64
+		var createdAt string
+		var completedAt sql.NullString
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.DeliveryID, &d.Event, &d.Payload, &d.Attempt, &d.State,
+			&nextAttempt, &d.StatusCode, &d.Response, &d.Error, &d.DurationMs, &createdAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.NextAttemptAt = time.Unix(nextAttempt, 0)
+		d.CreatedAt, _ = time.Parse(timestampLayout, createdAt)
+		if completedAt.Valid {
+			t, _ := time.Parse(timestampLayout, completedAt.String)
+			d.CompletedAt = &t
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDueWebhookDeliveries récupère les tentatives en attente dont l'heure est échue
+func (s *Store) GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return s.queryDeliveries(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+			  WHERE state = ? AND next_attempt_at <= ? ORDER BY id ASC LIMIT ?`,
+		models.DeliveryStatePending, now.Unix(), limit)
+}
+
+// GetWebhookDeliveries récupère les tentatives de livraison d'un abonnement, les plus récentes d'abord,
+// filtrées par état si state n'est pas vide
+func (s *Store) GetWebhookDeliveries(subscriptionID int, state string, limit, offset int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = ?`
+	args := []interface{}{subscriptionID}
+	if state != "" {
+		query += ` AND state = ?`
+		args = append(args, state)
+	}
+	query += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	return s.queryDeliveries(query, append(args, limit, offset)...)
+}
@@ -22,7 +22,7 @@ CREATE TABLE IF NOT EXISTS email_queue (
   to_addr      TEXT NOT NULL,
   subject      TEXT NOT NULL,
   body_text    TEXT NOT NULL,
-  state        TEXT DEFAULT 'queued',  -- queued|sent|error
+  state        TEXT DEFAULT 'pending', -- pending|failed|sent|dead (voir 009_email_retries.sql)
   last_error   TEXT NULL,
   created_at   TEXT DEFAULT (datetime('now')),
   sent_at      TEXT NULL
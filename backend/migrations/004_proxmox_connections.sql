@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS proxmox_connections (
+  id           INTEGER PRIMARY KEY AUTOINCREMENT,
+  name         TEXT NOT NULL UNIQUE,
+  url          TEXT NOT NULL,          -- ex: https://pve.example.com:8006
+  username     TEXT NOT NULL,          -- ex: root@pam!dashboard
+  secret       TEXT NOT NULL,          -- secret du token API, chiffré (AES-256-GCM)
+  password     TEXT,                   -- mot de passe optionnel (console VNC), chiffré
+  enabled      BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
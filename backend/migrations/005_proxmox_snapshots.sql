@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS proxmox_snapshots (
+  id           INTEGER PRIMARY KEY CHECK (id = 1), -- une seule ligne: le dernier snapshot
+  collected_at TEXT NOT NULL,
+  data         TEXT NOT NULL                       -- inventaire multi-clusters sérialisé en JSON
+);
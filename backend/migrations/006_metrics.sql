@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS metrics (
+  resolution   INTEGER NOT NULL,          -- 0 (brut, 24h), 300 (5 min, 30j) ou 3600 (horaire, 1 an)
+  kind         TEXT NOT NULL,             -- node|qemu|lxc|storage
+  cluster      TEXT NOT NULL DEFAULT '',  -- nom de la connexion Proxmox
+  object_id    TEXT NOT NULL,             -- nom du nœud, VMID ou storage
+  ts           INTEGER NOT NULL,          -- début de l'intervalle (timestamp Unix)
+  cpu          REAL,                      -- en pourcentage
+  memory       REAL,
+  disk         REAL,
+  samples      INTEGER NOT NULL DEFAULT 1, -- nombre de mesures moyennées dans l'intervalle
+  PRIMARY KEY (resolution, kind, cluster, object_id, ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_metrics_retention ON metrics(resolution, ts);
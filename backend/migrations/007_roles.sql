@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS roles (
+  name         TEXT PRIMARY KEY,          -- valeur de users.role
+  description  TEXT NOT NULL DEFAULT '',
+  permissions  TEXT NOT NULL DEFAULT '[]', -- JSON: [{"resource":"proxmox.vm","action":"power","scope":{"tags":["dev"]}}]
+  built_in     BOOLEAN NOT NULL DEFAULT FALSE, -- rôles admin, user, viewer, guest (non supprimables)
+  created_at   TEXT DEFAULT (datetime('now')),
+  updated_at   TEXT DEFAULT (datetime('now'))
+);
+
+-- Les rôles intégrés sont insérés au démarrage depuis models.RolePermissions (INSERT OR IGNORE)
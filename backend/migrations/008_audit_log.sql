@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id           INTEGER PRIMARY KEY AUTOINCREMENT,
+  timestamp    TEXT NOT NULL,              -- UTC, format 2006-01-02T15:04:05.000Z
+  user_id      INTEGER,                    -- NULL pour les requêtes non authentifiées
+  username     TEXT NOT NULL DEFAULT '',
+  action       TEXT NOT NULL,              -- ex: vm.restart, app.delete, admin.clear-db
+  target       TEXT NOT NULL DEFAULT '',   -- ex: pve1/qemu/100, app/3
+  method       TEXT NOT NULL,
+  path         TEXT NOT NULL,
+  ip_address   TEXT NOT NULL DEFAULT '',
+  user_agent   TEXT NOT NULL DEFAULT '',
+  status       INTEGER NOT NULL,           -- code HTTP de la réponse
+  result       TEXT NOT NULL,              -- success|denied|failure
+  upid         TEXT NOT NULL DEFAULT '',   -- tâche Proxmox lancée
+  duration_ms  INTEGER NOT NULL DEFAULT 0
+);
+
+-- Le journal n'est pas vidé par /api/v1/admin/clear-db
+CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_log_username ON audit_log(username, timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, timestamp);
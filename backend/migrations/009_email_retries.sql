@@ -0,0 +1,14 @@
+-- Relances des emails : backoff exponentiel et dead-letter
+-- pending : en attente du premier envoi
+-- failed  : échec temporaire, nouvelle tentative à next_attempt_at (timestamp Unix)
+-- sent    : envoyé
+-- dead    : abandonné après max_attempts tentatives ou une erreur SMTP permanente (5xx)
+ALTER TABLE email_queue ADD COLUMN body_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE email_queue ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 5;
+ALTER TABLE email_queue ADD COLUMN next_attempt_at INTEGER NOT NULL DEFAULT 0;
+
+UPDATE email_queue SET state = 'pending' WHERE state = 'queued';
+UPDATE email_queue SET state = 'dead' WHERE state = 'error';
+
+CREATE INDEX IF NOT EXISTS idx_email_queue_state ON email_queue(state, next_attempt_at);
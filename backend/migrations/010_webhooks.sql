@@ -0,0 +1,30 @@
+-- Webhooks signés : secret HMAC-SHA256 chiffré, désactivation automatique après des échecs consécutifs
+ALTER TABLE notify_subscriptions ADD COLUMN secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE notify_subscriptions ADD COLUMN failure_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notify_subscriptions ADD COLUMN disabled_at DATETIME;
+
+-- Une ligne par tentative de livraison ; les tentatives d'un même événement partagent delivery_id
+-- pending   : tentative planifiée à next_attempt_at (timestamp Unix)
+-- delivered : réponse 2xx
+-- failed    : échec, la tentative suivante est planifiée
+-- dead      : échec de la dernière tentative
+-- cancelled : abonnement désactivé avant la tentative
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscription_id INTEGER NOT NULL REFERENCES notify_subscriptions(id) ON DELETE CASCADE,
+    delivery_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempt INTEGER NOT NULL DEFAULT 1,
+    state TEXT NOT NULL DEFAULT 'pending',
+    next_attempt_at INTEGER NOT NULL DEFAULT 0,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL,
+    completed_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(state, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);
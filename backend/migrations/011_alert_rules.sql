@@ -0,0 +1,26 @@
+-- Règles d'alerte : "metric comparator threshold" vrai pendant for_seconds sur un objet de l'inventaire
+-- scope : JSON {"cluster", "node", "type", "tags", "ids"}, un champ absent ne filtre pas
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    metric TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT '{}',
+    comparator TEXT NOT NULL,
+    threshold REAL NOT NULL,
+    for_seconds INTEGER NOT NULL DEFAULT 0,
+    severity TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Cycle de vie des alertes : firing puis resolved quand la condition disparaît
+-- fingerprint : empreinte stable règle + objet, une seule alerte ouverte par empreinte
+ALTER TABLE alerts ADD COLUMN status TEXT NOT NULL DEFAULT 'firing';
+ALTER TABLE alerts ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE alerts ADD COLUMN rule_id INTEGER;
+ALTER TABLE alerts ADD COLUMN resolved_at DATETIME;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open_fingerprint ON alerts(fingerprint)
+    WHERE fingerprint != '' AND status != 'resolved';
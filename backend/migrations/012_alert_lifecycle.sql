@@ -0,0 +1,38 @@
+-- Cycle de vie des alertes : firing → acknowledged → resolved, avec l'auteur et la date de chaque transition
+-- labels : JSON des labels de l'objet concerné (cluster, node...), comparés aux matchers des silences
+ALTER TABLE alerts ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE alerts ADD COLUMN acknowledged_at DATETIME;
+ALTER TABLE alerts ADD COLUMN acknowledged_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE alerts ADD COLUMN resolved_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE alerts ADD COLUMN snoozed_until DATETIME;
+ALTER TABLE alerts ADD COLUMN assignee_id INTEGER;
+ALTER TABLE alerts ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+
+-- Silences : suspendent les notifications des alertes couvertes par tous les matchers entre starts_at et ends_at
+-- matchers : JSON [{"name", "operator" (=, !=, =~, !~), "value"}] ; dates au format 2006-01-02T15:04:05.000Z
+CREATE TABLE IF NOT EXISTS silences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    matchers TEXT NOT NULL,
+    starts_at TEXT NOT NULL,
+    ends_at TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+
+-- Fil de commentaires des alertes
+CREATE TABLE IF NOT EXISTS alert_comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    user_id INTEGER,
+    username TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Les alertes acquittées avant cette version passent à l'état acknowledged
+UPDATE alerts SET status = 'acknowledged' WHERE acknowledged = 1 AND status = 'firing';
+
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_silences_ends_at ON silences(ends_at);
+CREATE INDEX IF NOT EXISTS idx_alert_comments_alert ON alert_comments(alert_id, id);
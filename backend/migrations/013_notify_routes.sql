@@ -0,0 +1,44 @@
+-- Routage des notifications : chaque route sélectionne des alertes (sévérités, préfixes de source, matchers)
+-- et les notifie à ses abonnements, regroupées par les labels group_by.
+-- Les listes sont stockées en JSON ; subscription_ids vide = flux SSE uniquement.
+-- quiet_hours : JSON {"start": "22:00", "end": "07:00", "days": [1,2,3,4,5], "timezone": "Europe/Paris"}, '' si aucune
+CREATE TABLE IF NOT EXISTS notify_routes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    severities TEXT NOT NULL DEFAULT '[]',
+    sources TEXT NOT NULL DEFAULT '[]',
+    matchers TEXT NOT NULL DEFAULT '[]',
+    subscription_ids TEXT NOT NULL DEFAULT '[]',
+    group_by TEXT NOT NULL DEFAULT '[]',
+    group_wait_seconds INTEGER NOT NULL DEFAULT 0,
+    repeat_interval_seconds INTEGER NOT NULL DEFAULT 0,
+    quiet_hours TEXT NOT NULL DEFAULT '',
+    continue BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Groupes d'alertes d'une route (group_key : "cluster=lab,node=pve1")
+-- flush_at : prochaine notification planifiée, last_flush_at : dernière notification (timestamps Unix)
+CREATE TABLE IF NOT EXISTS alert_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    route_id INTEGER NOT NULL REFERENCES notify_routes(id) ON DELETE CASCADE,
+    group_key TEXT NOT NULL,
+    labels TEXT NOT NULL DEFAULT '{}',
+    flush_at INTEGER,
+    last_flush_at INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(route_id, group_key)
+);
+
+-- Alertes d'un groupe et état sous lequel chacune a été notifiée en dernier ('' si jamais, firing, resolved)
+CREATE TABLE IF NOT EXISTS alert_group_members (
+    group_id INTEGER NOT NULL REFERENCES alert_groups(id) ON DELETE CASCADE,
+    alert_id INTEGER NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    notified_status TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (group_id, alert_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_groups_flush ON alert_groups(flush_at);
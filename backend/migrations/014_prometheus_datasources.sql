@@ -0,0 +1,14 @@
+-- Sources de données Prometheus interrogées par /api/v1/prometheus/* : le frontend ne fournit jamais d'URL
+-- (protection SSRF), seulement l'identifiant d'une source (paramètre datasource, à défaut la source is_default).
+CREATE TABLE IF NOT EXISTS prometheus_datasources (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+  name            TEXT NOT NULL UNIQUE,
+  url             TEXT NOT NULL,                -- ex: http://prometheus:9090
+  auth_type       TEXT NOT NULL DEFAULT 'none', -- none|basic|bearer
+  username        TEXT NOT NULL DEFAULT '',     -- utilisateur (basic)
+  secret          TEXT NOT NULL DEFAULT '',     -- mot de passe (basic) ou token (bearer), chiffré (AES-256-GCM)
+  tls_skip_verify INTEGER NOT NULL DEFAULT 0,
+  is_default      INTEGER NOT NULL DEFAULT 0,   -- source interrogée sans paramètre datasource
+  created_at      TEXT DEFAULT (datetime('now')),
+  updated_at      TEXT DEFAULT (datetime('now'))
+);
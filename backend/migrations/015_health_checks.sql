@@ -0,0 +1,17 @@
+-- Health checks planifiés : intervalle propre à chaque application (secondes, 0 = HEALTH_CHECK_INTERVAL)
+ALTER TABLE apps ADD COLUMN check_interval INTEGER NOT NULL DEFAULT 0;
+
+-- Historique des health checks, conservé 30 jours pour le calcul de l'uptime (24h, 7j, 30j)
+-- checked_at : secondes Unix ; latency_ms : durée de la vérification
+CREATE TABLE IF NOT EXISTS health_checks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    status_code INTEGER,
+    error TEXT,
+    checked_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_health_checks_app ON health_checks(app_id, checked_at);
+CREATE INDEX IF NOT EXISTS idx_health_checks_checked_at ON health_checks(checked_at);
@@ -0,0 +1,19 @@
+-- Jobs de provisionnement : clonage d'un template (clone lié ou complet), cloud-init et démarrage optionnel
+-- spec : description JSON du clone, sans le mot de passe cloud-init
+CREATE TABLE IF NOT EXISTS provision_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    connection_id INTEGER,
+    spec TEXT NOT NULL,
+    status TEXT NOT NULL,
+    step TEXT NOT NULL,
+    progress INTEGER NOT NULL DEFAULT 0,
+    vmid INTEGER NOT NULL DEFAULT 0,
+    upid TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_by INTEGER,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_provision_jobs_status ON provision_jobs(status);
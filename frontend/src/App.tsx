@@ -80,6 +80,20 @@ function App() {
         )
       );
     },
+    onAlertUpdate: (updated: Alert) => {
+      setAlerts(prev => (prev || []).map(alert => (alert.id === updated.id ? updated : alert)));
+    },
+    onInventoryEvent: (type, data) => {
+      // Relayer l'événement aux pages pour qu'elles se mettent à jour sans re-interroger Proxmox
+      window.dispatchEvent(new CustomEvent('proxmoxInventoryEvent', { detail: { type, data } }));
+
+      const label = data.name || data.id;
+      if (type === 'node.offline') {
+        error('Nœud hors ligne', `${data.cluster ? `${data.cluster}/` : ''}${label}: ${data.before} → ${data.after}`);
+      } else if (type === 'storage.threshold' && data.exceeded) {
+        warning('Storage presque plein', `${label}: ${data.after}% (seuil ${data.threshold}%)`);
+      }
+    },
     onConnected: () => {
       // Connexion SSE établie - pas de notification
     },
@@ -0,0 +1,133 @@
+import { useEffect, useState } from 'react';
+import { Modal } from '@/components/ui/Modal';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Select } from '@/components/ui/Select';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPut, BackupJob, BackupJobUpdate, BackupMode } from '@/utils/api';
+import { storage } from '@/utils/storage';
+
+interface BackupJobModalProps {
+  job: BackupJob | null;
+  onClose: () => void;
+  onSaved?: (job: BackupJob) => void;
+}
+
+type Selection = 'vmids' | 'all' | 'pool';
+
+export function BackupJobModal({ job, onClose, onSaved }: BackupJobModalProps) {
+  const { t } = useTranslation();
+  const { success, error, warning } = useToast();
+  const [schedule, setSchedule] = useState('');
+  const [target, setTarget] = useState('');
+  const [mode, setMode] = useState<BackupMode | ''>('');
+  const [selection, setSelection] = useState<Selection>('vmids');
+  const [vmids, setVmids] = useState('');
+  const [pool, setPool] = useState('');
+  const [comment, setComment] = useState('');
+  const [busy, setBusy] = useState(false);
+
+  useEffect(() => {
+    if (!job) return;
+    setSchedule(job.schedule);
+    setTarget(job.storage || '');
+    setMode(job.mode || '');
+    setSelection(job.all ? 'all' : job.pool ? 'pool' : 'vmids');
+    setVmids((job.vmids || []).join(', '));
+    setPool(job.pool || '');
+    setComment(job.comment || '');
+  }, [job]);
+
+  // changes ne retient que les champs modifiés : une chaîne vide supprime l'option côté Proxmox
+  const changes = (current: BackupJob): BackupJobUpdate => {
+    const update: BackupJobUpdate = {};
+    if (schedule !== current.schedule) update.schedule = schedule.trim();
+    if (target !== (current.storage || '')) update.storage = target.trim();
+    if (mode !== (current.mode || '')) update.mode = mode;
+    if (comment !== (current.comment || '')) update.comment = comment;
+    if (selection === 'all' && !current.all) update.all = true;
+    if (selection === 'pool' && pool !== (current.pool || '')) update.pool = pool.trim();
+    if (selection === 'vmids') {
+      const list = vmids.split(/[\s,;]+/).filter(Boolean).map(Number);
+      if (list.join(',') !== (current.vmids || []).join(',')) update.vmids = list;
+    }
+    return update;
+  };
+
+  const save = async () => {
+    if (!job) return;
+    const proxmox = storage.getProxmoxConnection();
+    if (!proxmox) {
+      warning('Information', 'Configurez Proxmox dans les Paramètres avant de modifier une tâche');
+      return;
+    }
+    const update = changes(job);
+    if (Object.keys(update).length === 0) {
+      onClose();
+      return;
+    }
+
+    setBusy(true);
+    try {
+      const response = await apiPut<{ success: boolean; message: string; data: BackupJob }>(
+        `/api/v1/proxmox/backups/jobs/${encodeURIComponent(job.id)}`,
+        { connection_id: proxmox.connection_id, job: update }
+      );
+      success('Succès', response.message);
+      onSaved?.(response.data);
+      onClose();
+    } catch (err: any) {
+      error('Erreur', err.message);
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  return (
+    <Modal isOpen={job !== null} onClose={onClose} title={`${t('backups.job') || 'Tâche planifiée'} ${job?.id || ''}`}>
+      <div className="space-y-4">
+        <div className="grid grid-cols-2 gap-4">
+          <Input label={t('backups.schedule') || 'Planification'} value={schedule} onChange={e => setSchedule(e.target.value)} />
+          <Input label={t('backups.storage') || 'Storage'} value={target} onChange={e => setTarget(e.target.value)} />
+        </div>
+        <div className="grid grid-cols-2 gap-4">
+          <Select
+            label={t('backups.mode') || 'Mode'}
+            value={mode}
+            onChange={e => setMode(e.target.value as BackupMode | '')}
+            options={[
+              { value: '', label: t('backups.default') || 'Défaut' },
+              { value: 'snapshot', label: 'Snapshot' },
+              { value: 'suspend', label: 'Suspend' },
+              { value: 'stop', label: 'Stop' },
+            ]}
+          />
+          <Select
+            label={t('backups.selection') || 'Invités'}
+            value={selection}
+            onChange={e => setSelection(e.target.value as Selection)}
+            options={[
+              { value: 'vmids', label: t('backups.selectionVmids') || 'Liste de VMID' },
+              { value: 'all', label: t('backups.selectionAll') || 'Tous' },
+              { value: 'pool', label: t('backups.selectionPool') || 'Pool' },
+            ]}
+          />
+        </div>
+        {selection === 'vmids' && (
+          <Input label={t('backups.vmids') || 'Invités (VMID)'} value={vmids} onChange={e => setVmids(e.target.value)} />
+        )}
+        {selection === 'pool' && <Input label="Pool" value={pool} onChange={e => setPool(e.target.value)} />}
+        <Input label={t('backups.comment') || 'Commentaire'} value={comment} onChange={e => setComment(e.target.value)} />
+        <div className="flex justify-end gap-2 pt-2">
+          <Button variant="outline" onClick={onClose} disabled={busy}>
+            {t('common.cancel') || 'Annuler'}
+          </Button>
+          <Button onClick={save} disabled={busy}>
+            {t('common.save') || 'Enregistrer'}
+          </Button>
+        </div>
+      </div>
+    </Modal>
+  );
+}
@@ -0,0 +1,132 @@
+import { useEffect, useState } from 'react';
+import { Modal } from '@/components/ui/Modal';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Select } from '@/components/ui/Select';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPost, BackupMode, BackupRunRequest, StartedBackup } from '@/utils/api';
+import { storage } from '@/utils/storage';
+
+interface BackupRunModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+  vmids?: number[]; // invités présélectionnés
+  onStarted?: (started: StartedBackup[]) => void;
+}
+
+// parseVMIDs lit une liste de VMID saisie ("100, 101 102")
+const parseVMIDs = (value: string) =>
+  value
+    .split(/[\s,;]+/)
+    .filter(Boolean)
+    .map(Number);
+
+export function BackupRunModal({ isOpen, onClose, vmids = [], onStarted }: BackupRunModalProps) {
+  const { t } = useTranslation();
+  const { success, error, warning } = useToast();
+  const [guests, setGuests] = useState('');
+  const [mode, setMode] = useState<BackupMode>('snapshot');
+  const [compress, setCompress] = useState('zstd');
+  const [target, setTarget] = useState('');
+  const [notes, setNotes] = useState('{{guestname}}');
+  const [isProtected, setIsProtected] = useState(false);
+  const [busy, setBusy] = useState(false);
+
+  useEffect(() => {
+    if (isOpen) {
+      setGuests(vmids.join(', '));
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [isOpen]);
+
+  const submit = async () => {
+    const proxmox = storage.getProxmoxConnection();
+    if (!proxmox) {
+      warning('Information', 'Configurez Proxmox dans les Paramètres avant de lancer une sauvegarde');
+      return;
+    }
+    const selected = parseVMIDs(guests);
+    if (selected.length === 0 || selected.some(vmid => !Number.isInteger(vmid) || vmid <= 0)) {
+      warning('Information', t('backups.vmidsRequired') || 'Saisissez au moins un VMID valide');
+      return;
+    }
+
+    const request: BackupRunRequest = {
+      vmids: selected,
+      mode,
+      compress: compress || undefined,
+      storage: target.trim() || undefined,
+      notes_template: notes.trim() || undefined,
+      protected: isProtected || undefined,
+    };
+    setBusy(true);
+    try {
+      const response = await apiPost<{ success: boolean; message: string; data: StartedBackup[] }>('/api/v1/proxmox/backups/run', {
+        connection_id: proxmox.connection_id,
+        ...request,
+      });
+      success('Succès', response.message);
+      onStarted?.(response.data);
+      onClose();
+    } catch (err: any) {
+      error('Erreur', err.message);
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  return (
+    <Modal isOpen={isOpen} onClose={onClose} title={t('backups.run') || 'Sauvegarder maintenant'}>
+      <div className="space-y-4">
+        <Input
+          label={t('backups.vmids') || 'Invités (VMID)'}
+          placeholder="100, 101"
+          value={guests}
+          onChange={e => setGuests(e.target.value)}
+        />
+        <div className="grid grid-cols-2 gap-4">
+          <Select
+            label={t('backups.mode') || 'Mode'}
+            value={mode}
+            onChange={e => setMode(e.target.value as BackupMode)}
+            options={[
+              { value: 'snapshot', label: 'Snapshot' },
+              { value: 'suspend', label: 'Suspend' },
+              { value: 'stop', label: 'Stop' },
+            ]}
+          />
+          <Select
+            label={t('backups.compress') || 'Compression'}
+            value={compress}
+            onChange={e => setCompress(e.target.value)}
+            options={[
+              { value: 'zstd', label: 'ZSTD' },
+              { value: 'lzo', label: 'LZO' },
+              { value: 'gzip', label: 'GZIP' },
+              { value: '0', label: t('backups.none') || 'Aucune' },
+            ]}
+          />
+        </div>
+        <Input
+          label={t('backups.storage') || 'Storage (défaut du nœud si vide)'}
+          value={target}
+          onChange={e => setTarget(e.target.value)}
+        />
+        <Input label={t('backups.notes') || 'Notes'} value={notes} onChange={e => setNotes(e.target.value)} />
+        <label className="flex items-center gap-2 text-sm text-slate-700 dark:text-slate-300">
+          <input type="checkbox" checked={isProtected} onChange={e => setIsProtected(e.target.checked)} />
+          {t('backups.protected') || 'Protéger l\'archive contre la suppression'}
+        </label>
+        <div className="flex justify-end gap-2 pt-2">
+          <Button variant="outline" onClick={onClose} disabled={busy}>
+            {t('common.cancel') || 'Annuler'}
+          </Button>
+          <Button onClick={submit} disabled={busy}>
+            {busy ? t('backups.starting') || 'Démarrage...' : t('backups.start') || 'Démarrer'}
+          </Button>
+        </div>
+      </div>
+    </Modal>
+  );
+}
@@ -2,7 +2,17 @@ import { useState, useEffect } from 'react';
 import { Clock, RefreshCw, CheckCircle, AlertTriangle } from 'lucide-react';
 import { Badge } from '@/components/ui/Badge';
 import { Button } from '@/components/ui/Button';
-import { storage } from '@/utils/storage';
+import { apiGet, apiPost } from '@/utils/api';
+
+// Fraîcheur du snapshot d'inventaire collecté par le poller du backend
+interface CacheStatus {
+  collected_at?: string;
+  age_seconds: number;
+  interval_seconds: number;
+  stale: boolean;
+  polling: boolean;
+  last_error?: string;
+}
 
 export function CacheIndicator() {
   const [cacheStatus, setCacheStatus] = useState<{
@@ -18,17 +28,25 @@ export function CacheIndicator() {
   const [refreshing, setRefreshing] = useState(false);
 
   useEffect(() => {
-    const updateCacheStatus = () => {
-      const data = storage.getProxmoxData();
-      setCacheStatus({
-        isValid: storage.isProxmoxDataValid(),
-        timestamp: data?.timestamp || null,
-        expires: data?.expires || null
-      });
+    const updateCacheStatus = async () => {
+      try {
+        const status = await apiGet<CacheStatus>('/api/v1/proxmox/cache');
+        const timestamp = status.collected_at ? new Date(status.collected_at).getTime() : null;
+        setCacheStatus({
+          isValid: !status.stale,
+          timestamp,
+          // La prochaine collecte est attendue un intervalle après la dernière
+          expires: timestamp ? timestamp + status.interval_seconds * 1000 : null
+        });
+      } catch (error) {
+        console.error('Erreur lors de la récupération de l\'état du cache:', error);
+        setCacheStatus({ isValid: false, timestamp: null, expires: null });
+      }
     };
 
-    // Mise à jour initiale
+    // Mise à jour initiale puis périodique
     updateCacheStatus();
+    const interval = setInterval(updateCacheStatus, 15000);
 
     // Écouter les mises à jour des données
     const handleDataUpdate = () => {
@@ -38,6 +56,7 @@ export function CacheIndicator() {
     window.addEventListener('proxmoxDataUpdated', handleDataUpdate);
 
     return () => {
+      clearInterval(interval);
       window.removeEventListener('proxmoxDataUpdated', handleDataUpdate);
     };
   }, []);
@@ -45,15 +64,13 @@ export function CacheIndicator() {
   const handleRefresh = async () => {
     setRefreshing(true);
     try {
-      // Déclencher un rafraîchissement
+      // Forcer une collecte côté serveur puis rafraîchir les pages
+      await apiPost('/api/v1/proxmox/inventory/refresh');
       window.dispatchEvent(new CustomEvent('proxmoxDataRefreshNeeded'));
-
-      // Attendre un peu pour que les données se mettent à jour
-      setTimeout(() => {
-        setRefreshing(false);
-      }, 2000);
+      window.dispatchEvent(new CustomEvent('proxmoxDataUpdated'));
     } catch (error) {
       console.error('Erreur lors du rafraîchissement:', error);
+    } finally {
       setRefreshing(false);
     }
   };
@@ -79,14 +96,14 @@ export function CacheIndicator() {
     const now = Date.now();
     const diff = cacheStatus.expires - now;
 
-    if (diff <= 0) return 'Expiré';
+    if (diff <= 0) return 'Collecte en attente';
 
     const minutes = Math.floor(diff / (1000 * 60));
-    if (minutes < 1) return 'Expire bientôt';
-    if (minutes < 60) return `Expire dans ${minutes} min`;
+    if (minutes < 1) return 'Prochaine collecte imminente';
+    if (minutes < 60) return `Prochaine collecte dans ${minutes} min`;
 
     const hours = Math.floor(minutes / 60);
-    return `Expire dans ${hours}h`;
+    return `Prochaine collecte dans ${hours}h`;
   };
 
   return (
@@ -0,0 +1,278 @@
+import { useEffect, useState } from 'react';
+import { AlertTriangle, RefreshCw } from 'lucide-react';
+import { Modal } from '@/components/ui/Modal';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Select } from '@/components/ui/Select';
+import { Loader } from '@/components/ui/Loader';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPost, apiPut, GuestConfig, GuestConfigUpdate, GuestType } from '@/utils/api';
+import { storage } from '@/utils/storage';
+
+const NET_MODELS = ['virtio', 'e1000', 'e1000e', 'rtl8139', 'vmxnet3'];
+
+interface GuestConfigModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+  guestType: GuestType;
+  node: string;
+  vmid: number;
+  name: string;
+  onSaved?: () => void;
+}
+
+interface FormState {
+  cores: string;
+  sockets: string;
+  memory: string;
+  balloon: string;
+  swap: string;
+  onboot: boolean;
+  description: string;
+  tags: string;
+  nets: Record<string, { model: string; bridge: string }>;
+}
+
+function toForm(config: GuestConfig): FormState {
+  return {
+    cores: String(config.cores),
+    sockets: String(config.sockets ?? ''),
+    memory: String(config.memory),
+    balloon: config.balloon !== undefined ? String(config.balloon) : '',
+    swap: config.swap !== undefined ? String(config.swap) : '',
+    onboot: config.onboot,
+    description: config.description,
+    tags: config.tags.join(', '),
+    nets: Object.fromEntries(config.nets.map(n => [n.id, { model: n.model || '', bridge: n.bridge || '' }])),
+  };
+}
+
+// diff ne retourne que les champs modifiés : l'API applique une modification partielle
+function diff(config: GuestConfig, form: FormState): GuestConfigUpdate {
+  const changes: GuestConfigUpdate = {};
+  const number = (value: string) => (value.trim() === '' ? undefined : Number(value));
+
+  if (number(form.cores) !== undefined && number(form.cores) !== config.cores) changes.cores = number(form.cores);
+  if (config.type === 'qemu') {
+    if (number(form.sockets) !== undefined && number(form.sockets) !== config.sockets) changes.sockets = number(form.sockets);
+    if (number(form.balloon) !== undefined && number(form.balloon) !== config.balloon) changes.balloon = number(form.balloon);
+  } else if (number(form.swap) !== undefined && number(form.swap) !== config.swap) {
+    changes.swap = number(form.swap);
+  }
+  if (number(form.memory) !== undefined && number(form.memory) !== config.memory) changes.memory = number(form.memory);
+  if (form.onboot !== config.onboot) changes.onboot = form.onboot;
+  if (form.description !== config.description) changes.description = form.description;
+
+  const tags = form.tags.split(/[,;\s]+/).filter(Boolean);
+  if (tags.join(';') !== config.tags.join(';')) changes.tags = tags;
+
+  for (const net of config.nets) {
+    const edited = form.nets[net.id];
+    if (!edited) continue;
+    const change: { model?: string; bridge?: string } = {};
+    if (config.type === 'qemu' && edited.model && edited.model !== net.model) change.model = edited.model;
+    if (edited.bridge && edited.bridge !== net.bridge) change.bridge = edited.bridge;
+    if (Object.keys(change).length > 0) {
+      changes.nets = { ...changes.nets, [net.id]: change };
+    }
+  }
+  return changes;
+}
+
+export function GuestConfigModal({ isOpen, onClose, guestType, node, vmid, name, onSaved }: GuestConfigModalProps) {
+  const { t } = useTranslation();
+  const { success, error, warning } = useToast();
+  const [config, setConfig] = useState<GuestConfig | null>(null);
+  const [form, setForm] = useState<FormState | null>(null);
+  const [loading, setLoading] = useState(false);
+  const [saving, setSaving] = useState(false);
+
+  const endpoint = `/api/v1/proxmox/${guestType === 'lxc' ? 'lxc' : 'vm'}/config`;
+
+  const credentials = () => {
+    return storage.proxmoxCredentials();
+  };
+
+  const load = async () => {
+    const creds = credentials();
+    if (!creds) {
+      warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la configuration');
+      onClose();
+      return;
+    }
+    setLoading(true);
+    try {
+      const response = await apiPost<{ success: boolean; data: GuestConfig; error?: string }>(endpoint, { ...creds, node, vmid });
+      setConfig(response.data);
+      setForm(toForm(response.data));
+    } catch (err: any) {
+      error('Erreur', `Impossible de lire la configuration de ${name}: ${err.message}`);
+      onClose();
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    if (isOpen) {
+      load();
+    } else {
+      setConfig(null);
+      setForm(null);
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [isOpen, node, vmid, guestType]);
+
+  const save = async () => {
+    const creds = credentials();
+    if (!config || !form || !creds) return;
+    const changes = diff(config, form);
+    if (Object.keys(changes).length === 0) {
+      warning('Information', t('guestConfig.noChanges') || 'Aucune modification à appliquer');
+      return;
+    }
+
+    setSaving(true);
+    try {
+      const response = await apiPut<{ success: boolean; data: GuestConfig; changed: string[] }>(endpoint, {
+        ...creds,
+        node,
+        vmid,
+        digest: config.digest,
+        changes,
+      });
+      setConfig(response.data);
+      setForm(toForm(response.data));
+      if (response.data.reboot_required) {
+        warning(
+          t('guestConfig.rebootRequired') || 'Redémarrage requis',
+          `${name}: ${response.data.pending.map(p => p.key).join(', ')}`
+        );
+      } else {
+        success('Succès', `Configuration de ${name} mise à jour`);
+      }
+      onSaved?.();
+    } catch (err: any) {
+      error('Erreur', err.message);
+      if (err.status === 409) {
+        // Configuration modifiée ailleurs : recharger avant une nouvelle tentative
+        load();
+      }
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const update = (patch: Partial<FormState>) => setForm(prev => (prev ? { ...prev, ...patch } : prev));
+
+  return (
+    <Modal isOpen={isOpen} onClose={onClose} title={`${t('guestConfig.title') || 'Configuration'} — ${name} (${vmid})`} size="lg">
+      {loading || !config || !form ? (
+        <div className="flex justify-center py-8">
+          <Loader />
+        </div>
+      ) : (
+        <div className="space-y-4 max-h-[70vh] overflow-y-auto pr-1">
+          {config.reboot_required && (
+            <div className="flex items-start gap-2 rounded-2xl bg-amber-50 p-3 text-sm text-amber-800 dark:bg-amber-900/20 dark:text-amber-300">
+              <AlertTriangle className="h-4 w-4 mt-0.5 flex-shrink-0" />
+              <span>
+                {t('guestConfig.pendingChanges') || 'Modifications en attente de redémarrage'}:{' '}
+                {config.pending.map(p => p.key).join(', ')}
+              </span>
+            </div>
+          )}
+
+          <div className="grid grid-cols-2 gap-4">
+            <Input label={t('guestConfig.cores') || 'Cœurs'} type="number" min={1} value={form.cores} onChange={e => update({ cores: e.target.value })} />
+            {guestType === 'qemu' && (
+              <Input label={t('guestConfig.sockets') || 'Sockets'} type="number" min={1} value={form.sockets} onChange={e => update({ sockets: e.target.value })} />
+            )}
+            <Input label={t('guestConfig.memory') || 'Mémoire (MiB)'} type="number" min={16} value={form.memory} onChange={e => update({ memory: e.target.value })} />
+            {guestType === 'qemu' ? (
+              <Input label={t('guestConfig.balloon') || 'Mémoire minimale / balloon (MiB)'} type="number" min={0} value={form.balloon} onChange={e => update({ balloon: e.target.value })} />
+            ) : (
+              <Input label={t('guestConfig.swap') || 'Swap (MiB)'} type="number" min={0} value={form.swap} onChange={e => update({ swap: e.target.value })} />
+            )}
+          </div>
+
+          <Input label={t('guestConfig.tags') || 'Tags'} value={form.tags} onChange={e => update({ tags: e.target.value })} />
+
+          <div className="space-y-2">
+            <label className="block text-sm font-medium text-slate-700 dark:text-slate-300">
+              {t('guestConfig.description') || 'Description'}
+            </label>
+            <textarea
+              className="w-full rounded-2xl border border-slate-300 bg-white px-3 py-2 text-sm dark:border-slate-600 dark:bg-slate-800 dark:text-slate-100"
+              rows={3}
+              value={form.description}
+              onChange={e => update({ description: e.target.value })}
+            />
+          </div>
+
+          <label className="flex items-center gap-2 text-sm text-slate-700 dark:text-slate-300">
+            <input type="checkbox" checked={form.onboot} onChange={e => update({ onboot: e.target.checked })} />
+            {t('guestConfig.onboot') || 'Démarrer au boot du nœud'}
+          </label>
+
+          {config.nets.length > 0 && (
+            <div className="space-y-2">
+              <h3 className="text-sm font-semibold text-slate-900 dark:text-slate-100">{t('guestConfig.networks') || 'Interfaces réseau'}</h3>
+              {config.nets.map(net => (
+                <div key={net.id} className="grid grid-cols-3 gap-3 items-end">
+                  <div className="text-sm text-slate-600 dark:text-slate-400 pb-2">
+                    {net.id} {net.name && `(${net.name})`}
+                    <div className="text-xs text-slate-400">{net.mac}</div>
+                  </div>
+                  {guestType === 'qemu' ? (
+                    <Select
+                      label={t('guestConfig.model') || 'Modèle'}
+                      value={form.nets[net.id]?.model || ''}
+                      options={NET_MODELS.includes(net.model || '') || !net.model
+                        ? NET_MODELS.map(m => ({ value: m, label: m }))
+                        : [net.model, ...NET_MODELS].map(m => ({ value: m, label: m }))}
+                      onChange={e => update({ nets: { ...form.nets, [net.id]: { ...form.nets[net.id], model: e.target.value } } })}
+                    />
+                  ) : (
+                    <div />
+                  )}
+                  <Input
+                    label={t('guestConfig.bridge') || 'Bridge'}
+                    value={form.nets[net.id]?.bridge || ''}
+                    onChange={e => update({ nets: { ...form.nets, [net.id]: { ...form.nets[net.id], bridge: e.target.value } } })}
+                  />
+                </div>
+              ))}
+            </div>
+          )}
+
+          {config.disks.length > 0 && (
+            <div className="space-y-1">
+              <h3 className="text-sm font-semibold text-slate-900 dark:text-slate-100">{t('guestConfig.disks') || 'Disques'}</h3>
+              {config.disks.map(disk => (
+                <div key={disk.id} className="flex justify-between text-sm text-slate-600 dark:text-slate-400">
+                  <span>{disk.id}{disk.mountpoint && ` → ${disk.mountpoint}`}</span>
+                  <span className="truncate ml-4">{disk.volume}{disk.size && ` (${disk.size})`}</span>
+                </div>
+              ))}
+            </div>
+          )}
+
+          <div className="flex justify-end gap-2 pt-2">
+            <Button variant="ghost" onClick={() => load()} disabled={saving}>
+              <RefreshCw className="h-4 w-4 mr-1" />
+              {t('common.refresh') || 'Recharger'}
+            </Button>
+            <Button variant="outline" onClick={onClose} disabled={saving}>
+              {t('common.cancel') || 'Annuler'}
+            </Button>
+            <Button onClick={save} disabled={saving}>
+              {saving ? (t('common.saving') || 'Enregistrement...') : (t('common.save') || 'Enregistrer')}
+            </Button>
+          </div>
+        </div>
+      )}
+    </Modal>
+  );
+}
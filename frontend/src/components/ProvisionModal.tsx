@@ -0,0 +1,307 @@
+import { useEffect, useRef, useState } from 'react';
+import { CheckCircle, XCircle } from 'lucide-react';
+import { Modal } from '@/components/ui/Modal';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Select } from '@/components/ui/Select';
+import { Loader } from '@/components/ui/Loader';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPost, ProvisionJob, ProvisionSpec, ProvisionTemplate } from '@/utils/api';
+import { authManager } from '@/utils/auth';
+import { storage } from '@/utils/storage';
+
+const API_BASE_URL = import.meta.env.VITE_API_URL || '';
+
+interface ProvisionModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+  onProvisioned?: () => void;
+}
+
+interface FormState {
+  template: string; // "node/vmid"
+  name: string;
+  vmid: string;
+  targetNode: string;
+  full: boolean;
+  storage: string;
+  start: boolean;
+  ciUser: string;
+  ciPassword: string;
+  sshKeys: string;
+  ipConfig: string;
+}
+
+const EMPTY_FORM: FormState = {
+  template: '',
+  name: '',
+  vmid: '',
+  targetNode: '',
+  full: false,
+  storage: '',
+  start: false,
+  ciUser: '',
+  ciPassword: '',
+  sshKeys: '',
+  ipConfig: '',
+};
+
+const STEP_LABELS: Record<ProvisionJob['step'], string> = {
+  allocate: 'Réservation du VMID',
+  clone: 'Clonage du template',
+  configure: 'Configuration cloud-init',
+  start: 'Démarrage',
+  done: 'Terminé',
+};
+
+function toSpec(template: ProvisionTemplate, form: FormState): ProvisionSpec {
+  const spec: ProvisionSpec = {
+    node: template.node,
+    type: template.type,
+    template_vmid: template.vmid,
+    name: form.name.trim(),
+    full: form.full,
+    start: form.start,
+  };
+  if (form.vmid.trim()) spec.vmid = Number(form.vmid);
+  if (form.targetNode.trim()) spec.target_node = form.targetNode.trim();
+  if (form.full && form.storage.trim()) spec.storage = form.storage.trim();
+
+  if (template.type === 'qemu') {
+    const sshKeys = form.sshKeys.split('\n').map(k => k.trim()).filter(Boolean);
+    const ipConfig = form.ipConfig.split('\n').map(c => c.trim()).filter(Boolean);
+    if (form.ciUser || form.ciPassword || sshKeys.length > 0 || ipConfig.length > 0) {
+      spec.cloud_init = {
+        user: form.ciUser || undefined,
+        password: form.ciPassword || undefined,
+        ssh_keys: sshKeys.length > 0 ? sshKeys : undefined,
+        ip_config: ipConfig.length > 0 ? ipConfig : undefined,
+      };
+    }
+  }
+  return spec;
+}
+
+export function ProvisionModal({ isOpen, onClose, onProvisioned }: ProvisionModalProps) {
+  const { t } = useTranslation();
+  const { success, error, warning } = useToast();
+  const [templates, setTemplates] = useState<ProvisionTemplate[]>([]);
+  const [form, setForm] = useState<FormState>(EMPTY_FORM);
+  const [loading, setLoading] = useState(false);
+  const [submitting, setSubmitting] = useState(false);
+  const [job, setJob] = useState<ProvisionJob | null>(null);
+  const eventSourceRef = useRef<EventSource | null>(null);
+
+  const credentials = () => {
+    return storage.proxmoxCredentials();
+  };
+
+  const closeStream = () => {
+    eventSourceRef.current?.close();
+    eventSourceRef.current = null;
+  };
+
+  useEffect(() => {
+    if (!isOpen) {
+      closeStream();
+      setJob(null);
+      setForm(EMPTY_FORM);
+      return;
+    }
+    const creds = credentials();
+    if (!creds) {
+      warning('Information', 'Configurez Proxmox dans les Paramètres avant de provisionner');
+      onClose();
+      return;
+    }
+    setLoading(true);
+    apiPost<{ success: boolean; data: ProvisionTemplate[] }>('/api/v1/proxmox/provision/templates', creds)
+      .then(response => setTemplates(response.data))
+      .catch((err: any) => error('Erreur', `Impossible de lister les templates: ${err.message}`))
+      .finally(() => setLoading(false));
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [isOpen]);
+
+  useEffect(() => closeStream, []);
+
+  // follow suit l'avancement du job via le flux SSE /events jusqu'à sa fin
+  const follow = (jobId: number) => {
+    closeStream();
+    const token = authManager.getToken();
+    const url = `${API_BASE_URL}/api/v1/proxmox/provision/jobs/${jobId}/events`;
+    const eventSource = new EventSource(token ? `${url}?token=${encodeURIComponent(token)}` : url);
+    eventSourceRef.current = eventSource;
+
+    eventSource.addEventListener('job', (event: MessageEvent) => {
+      const update: ProvisionJob = JSON.parse(event.data);
+      setJob(update);
+      if (update.status === 'succeeded') {
+        closeStream();
+        success('Succès', `${update.spec.name} (${update.vmid}) provisionné`);
+        onProvisioned?.();
+      } else if (update.status === 'failed') {
+        closeStream();
+        error('Erreur', update.error || 'Le provisionnement a échoué');
+      }
+    });
+    eventSource.onerror = () => {
+      // Flux fermé par le serveur à la fin du job
+      closeStream();
+    };
+  };
+
+  const selected = templates.find(tpl => `${tpl.node}/${tpl.vmid}` === form.template);
+
+  const submit = async () => {
+    const creds = credentials();
+    if (!creds || !selected) return;
+    if (!form.name.trim()) {
+      warning('Information', t('provision.nameRequired') || 'Le nom est requis');
+      return;
+    }
+
+    setSubmitting(true);
+    try {
+      const response = await apiPost<{ success: boolean; data: ProvisionJob }>('/api/v1/proxmox/provision/jobs', {
+        ...creds,
+        ...toSpec(selected, form),
+      });
+      setJob(response.data);
+      follow(response.data.id);
+    } catch (err: any) {
+      error('Erreur', err.message);
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  const update = (patch: Partial<FormState>) => setForm(prev => ({ ...prev, ...patch }));
+
+  const renderJob = (current: ProvisionJob) => (
+    <div className="space-y-4">
+      <div className="flex items-center justify-between text-sm text-slate-700 dark:text-slate-300">
+        <span>
+          {current.spec.name}
+          {current.vmid ? ` (${current.vmid})` : ''} — {t(`provision.steps.${current.step}`) || STEP_LABELS[current.step]}
+        </span>
+        {current.status === 'succeeded' && <CheckCircle className="h-5 w-5 text-green-500" />}
+        {current.status === 'failed' && <XCircle className="h-5 w-5 text-red-500" />}
+      </div>
+      <div className="h-2 w-full overflow-hidden rounded-full bg-slate-200 dark:bg-slate-700">
+        <div
+          className={`h-full transition-all ${current.status === 'failed' ? 'bg-red-500' : 'bg-blue-500'}`}
+          style={{ width: `${current.progress}%` }}
+        />
+      </div>
+      {current.error && <p className="text-sm text-red-600 dark:text-red-400">{current.error}</p>}
+      <div className="flex justify-end">
+        <Button variant="outline" onClick={onClose}>
+          {current.status === 'succeeded' || current.status === 'failed'
+            ? (t('common.close') || 'Fermer')
+            : (t('provision.background') || 'Continuer en arrière-plan')}
+        </Button>
+      </div>
+    </div>
+  );
+
+  return (
+    <Modal isOpen={isOpen} onClose={onClose} title={t('provision.title') || 'Créer depuis un template'} size="lg">
+      {job ? (
+        renderJob(job)
+      ) : loading ? (
+        <div className="flex justify-center py-8">
+          <Loader />
+        </div>
+      ) : (
+        <div className="space-y-4 max-h-[70vh] overflow-y-auto pr-1">
+          <Select
+            label={t('provision.template') || 'Template'}
+            value={form.template}
+            options={[
+              { value: '', label: t('provision.selectTemplate') || 'Sélectionner un template' },
+              ...templates.map(tpl => ({
+                value: `${tpl.node}/${tpl.vmid}`,
+                label: `${tpl.name || tpl.vmid} (${tpl.type === 'lxc' ? 'LXC' : 'VM'} ${tpl.vmid} — ${tpl.node})`,
+              })),
+            ]}
+            onChange={e => update({ template: e.target.value })}
+          />
+
+          <div className="grid grid-cols-2 gap-4">
+            <Input label={t('provision.name') || 'Nom'} value={form.name} onChange={e => update({ name: e.target.value })} />
+            <Input
+              label={t('provision.vmid') || 'VMID (vide = automatique)'}
+              type="number"
+              min={100}
+              value={form.vmid}
+              onChange={e => update({ vmid: e.target.value })}
+            />
+            <Input
+              label={t('provision.targetNode') || 'Nœud cible'}
+              placeholder={selected?.node}
+              value={form.targetNode}
+              onChange={e => update({ targetNode: e.target.value })}
+            />
+            <Input
+              label={t('provision.storage') || 'Stockage (clone complet)'}
+              value={form.storage}
+              disabled={!form.full}
+              onChange={e => update({ storage: e.target.value })}
+            />
+          </div>
+
+          <div className="flex gap-6">
+            <label className="flex items-center gap-2 text-sm text-slate-700 dark:text-slate-300">
+              <input type="checkbox" checked={form.full} onChange={e => update({ full: e.target.checked })} />
+              {t('provision.full') || 'Clone complet'}
+            </label>
+            <label className="flex items-center gap-2 text-sm text-slate-700 dark:text-slate-300">
+              <input type="checkbox" checked={form.start} onChange={e => update({ start: e.target.checked })} />
+              {t('provision.start') || 'Démarrer après création'}
+            </label>
+          </div>
+
+          {selected?.type === 'qemu' && (
+            <div className="space-y-3">
+              <h3 className="text-sm font-semibold text-slate-900 dark:text-slate-100">Cloud-init</h3>
+              <div className="grid grid-cols-2 gap-4">
+                <Input label={t('provision.ciUser') || 'Utilisateur'} value={form.ciUser} onChange={e => update({ ciUser: e.target.value })} />
+                <Input
+                  label={t('provision.ciPassword') || 'Mot de passe'}
+                  type="password"
+                  value={form.ciPassword}
+                  onChange={e => update({ ciPassword: e.target.value })}
+                />
+              </div>
+              {(['sshKeys', 'ipConfig'] as const).map(field => (
+                <div key={field} className="space-y-2">
+                  <label className="block text-sm font-medium text-slate-700 dark:text-slate-300">
+                    {field === 'sshKeys'
+                      ? (t('provision.sshKeys') || 'Clés SSH (une par ligne)')
+                      : (t('provision.ipConfig') || 'Configuration IP (ip=dhcp ou ip=10.0.0.5/24,gw=10.0.0.1, une par interface)')}
+                  </label>
+                  <textarea
+                    className="w-full rounded-2xl border border-slate-300 bg-white px-3 py-2 text-sm font-mono dark:border-slate-600 dark:bg-slate-800 dark:text-slate-100"
+                    rows={2}
+                    value={form[field]}
+                    onChange={e => update({ [field]: e.target.value })}
+                  />
+                </div>
+              ))}
+            </div>
+          )}
+
+          <div className="flex justify-end gap-2 pt-2">
+            <Button variant="outline" onClick={onClose} disabled={submitting}>
+              {t('common.cancel') || 'Annuler'}
+            </Button>
+            <Button onClick={submit} disabled={submitting || !selected}>
+              {submitting ? (t('provision.submitting') || 'Lancement...') : (t('provision.submit') || 'Créer')}
+            </Button>
+          </div>
+        </div>
+      )}
+    </Modal>
+  );
+}
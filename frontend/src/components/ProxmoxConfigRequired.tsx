@@ -14,7 +14,7 @@ export function ProxmoxConfigRequired({
   message 
 }: ProxmoxConfigRequiredProps) {
   const { t } = useTranslation();
-  const proxmoxConfig = storage.getProxmoxConfig();
+  const proxmoxConfig = storage.getProxmoxConnection();
 
   // Vérifier si on est en production
   const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
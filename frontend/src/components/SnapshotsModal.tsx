@@ -0,0 +1,244 @@
+import { useEffect, useState } from 'react';
+import { Camera, RotateCcw, Trash2, MapPin } from 'lucide-react';
+import { Modal } from '@/components/ui/Modal';
+import { Button } from '@/components/ui/Button';
+import { Input } from '@/components/ui/Input';
+import { Loader } from '@/components/ui/Loader';
+import { ConfirmModal } from '@/components/ui/ConfirmModal';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPost, GuestSnapshotNode, GuestType, SnapshotTaskResponse } from '@/utils/api';
+import { storage } from '@/utils/storage';
+
+// Attente de fin de tâche demandée à l'API (secondes, 50 au plus), relancée tant que la tâche tourne
+const TASK_WAIT_SECONDS = 45;
+const TASK_WAIT_ATTEMPTS = 4;
+
+interface SnapshotsModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+  guestType: GuestType;
+  node: string;
+  vmid: number;
+  name: string;
+  onChanged?: () => void;
+}
+
+type Operation = 'rollback' | 'delete';
+
+export function SnapshotsModal({ isOpen, onClose, guestType, node, vmid, name, onChanged }: SnapshotsModalProps) {
+  const { t } = useTranslation();
+  const { success, error, warning } = useToast();
+  const [tree, setTree] = useState<GuestSnapshotNode[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [busy, setBusy] = useState(false);
+  const [snapName, setSnapName] = useState('');
+  const [description, setDescription] = useState('');
+  const [vmstate, setVmstate] = useState(false);
+  const [pending, setPending] = useState<{ operation: Operation; snapshot: string } | null>(null);
+
+  const endpoint = `/api/v1/proxmox/${guestType === 'lxc' ? 'lxc' : 'vm'}/snapshots`;
+
+  const credentials = () => {
+    return storage.proxmoxCredentials();
+  };
+
+  const load = async () => {
+    const creds = credentials();
+    if (!creds) {
+      warning('Information', 'Configurez Proxmox dans les Paramètres avant de gérer les snapshots');
+      onClose();
+      return;
+    }
+    setLoading(true);
+    try {
+      const response = await apiPost<{ success: boolean; data: GuestSnapshotNode[] }>(endpoint, { ...creds, node, vmid });
+      setTree(response.data);
+    } catch (err: any) {
+      error('Erreur', `Impossible de lister les snapshots de ${name}: ${err.message}`);
+      onClose();
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    if (isOpen) {
+      load();
+    } else {
+      setTree([]);
+      setSnapName('');
+      setDescription('');
+      setVmstate(false);
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [isOpen, node, vmid, guestType]);
+
+  // run lance une opération et attend la fin de sa tâche Proxmox avant de recharger l'arbre
+  const run = async (operation: 'create' | Operation, body: Record<string, unknown>, label: string) => {
+    const creds = credentials();
+    if (!creds) return;
+    setBusy(true);
+    try {
+      let response = await apiPost<SnapshotTaskResponse>(`${endpoint}/${operation}`, {
+        ...creds,
+        node,
+        vmid,
+        wait: TASK_WAIT_SECONDS,
+        ...body,
+      });
+      for (let attempt = 1; response.finished === false && attempt < TASK_WAIT_ATTEMPTS; attempt++) {
+        const status = await apiPost<SnapshotTaskResponse>('/api/v1/proxmox/tasks/wait', {
+          ...creds,
+          upid: response.upid,
+          timeout: TASK_WAIT_SECONDS,
+        });
+        response = { ...response, finished: status.finished, ok: status.ok, exitstatus: status.exitstatus };
+      }
+      if (response.finished === false) {
+        warning('Information', `${label}: tâche toujours en cours (${response.upid})`);
+      } else if (response.ok === false) {
+        error('Erreur', `${label}: ${response.exitstatus}`);
+      } else {
+        success('Succès', label);
+      }
+      onChanged?.();
+      await load();
+    } catch (err: any) {
+      error('Erreur', err.message);
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  const create = () => {
+    if (!snapName.trim()) {
+      warning('Information', t('snapshots.nameRequired') || 'Le nom du snapshot est requis');
+      return;
+    }
+    run(
+      'create',
+      { name: snapName.trim(), description: description || undefined, vmstate: guestType === 'qemu' ? vmstate : undefined },
+      `Snapshot ${snapName.trim()} créé`
+    ).then(() => {
+      setSnapName('');
+      setDescription('');
+    });
+  };
+
+  const confirm = () => {
+    if (!pending) return;
+    const { operation, snapshot } = pending;
+    setPending(null);
+    run(operation, { name: snapshot }, operation === 'rollback' ? `${name} restauré sur ${snapshot}` : `Snapshot ${snapshot} supprimé`);
+  };
+
+  const renderNode = (snapshot: GuestSnapshotNode, depth: number): JSX.Element => (
+    <div key={snapshot.name}>
+      <div
+        className="flex items-center justify-between rounded-xl px-2 py-1.5 hover:bg-slate-50 dark:hover:bg-slate-800"
+        style={{ paddingLeft: `${depth * 1.25 + 0.5}rem` }}
+      >
+        {snapshot.current ? (
+          <span className="flex items-center gap-1.5 text-sm font-medium text-blue-600 dark:text-blue-400">
+            <MapPin className="h-4 w-4" />
+            {t('snapshots.current') || 'État actuel'}
+          </span>
+        ) : (
+          <>
+            <div className="min-w-0">
+              <div className="text-sm font-medium text-slate-900 dark:text-slate-100">
+                {snapshot.name}
+                {snapshot.vmstate && <span className="ml-2 text-xs text-slate-500">RAM</span>}
+              </div>
+              <div className="truncate text-xs text-slate-500 dark:text-slate-400">
+                {snapshot.created_at && new Date(snapshot.created_at).toLocaleString()}
+                {snapshot.description && ` — ${snapshot.description}`}
+              </div>
+            </div>
+            <div className="flex flex-shrink-0 gap-1">
+              <Button
+                variant="ghost"
+                size="sm"
+                disabled={busy}
+                title={t('snapshots.rollback') || 'Restaurer'}
+                onClick={() => setPending({ operation: 'rollback', snapshot: snapshot.name })}
+              >
+                <RotateCcw className="h-4 w-4" />
+              </Button>
+              <Button
+                variant="ghost"
+                size="sm"
+                disabled={busy}
+                title={t('snapshots.delete') || 'Supprimer'}
+                onClick={() => setPending({ operation: 'delete', snapshot: snapshot.name })}
+              >
+                <Trash2 className="h-4 w-4" />
+              </Button>
+            </div>
+          </>
+        )}
+      </div>
+      {snapshot.children.map(child => renderNode(child, depth + 1))}
+    </div>
+  );
+
+  return (
+    <>
+      <Modal isOpen={isOpen} onClose={onClose} title={`${t('snapshots.title') || 'Snapshots'} — ${name} (${vmid})`} size="lg">
+        {loading ? (
+          <div className="flex justify-center py-8">
+            <Loader />
+          </div>
+        ) : (
+          <div className="space-y-4 max-h-[70vh] overflow-y-auto pr-1">
+            <div className="space-y-1">{tree.map(snapshot => renderNode(snapshot, 0))}</div>
+
+            <div className="space-y-3 border-t border-slate-200 pt-4 dark:border-slate-700">
+              <h3 className="text-sm font-semibold text-slate-900 dark:text-slate-100">
+                {t('snapshots.create') || 'Nouveau snapshot'}
+              </h3>
+              <div className="grid grid-cols-2 gap-4">
+                <Input label={t('snapshots.name') || 'Nom'} value={snapName} onChange={e => setSnapName(e.target.value)} />
+                <Input
+                  label={t('snapshots.description') || 'Description'}
+                  value={description}
+                  onChange={e => setDescription(e.target.value)}
+                />
+              </div>
+              {guestType === 'qemu' && (
+                <label className="flex items-center gap-2 text-sm text-slate-700 dark:text-slate-300">
+                  <input type="checkbox" checked={vmstate} onChange={e => setVmstate(e.target.checked)} />
+                  {t('snapshots.vmstate') || 'Inclure la RAM'}
+                </label>
+              )}
+              <div className="flex justify-end gap-2">
+                <Button variant="outline" onClick={onClose} disabled={busy}>
+                  {t('common.close') || 'Fermer'}
+                </Button>
+                <Button onClick={create} disabled={busy}>
+                  <Camera className="h-4 w-4 mr-1" />
+                  {busy ? (t('snapshots.running') || 'Tâche en cours...') : (t('snapshots.take') || 'Prendre un snapshot')}
+                </Button>
+              </div>
+            </div>
+          </div>
+        )}
+      </Modal>
+
+      <ConfirmModal
+        isOpen={!!pending}
+        onClose={() => setPending(null)}
+        onConfirm={confirm}
+        title={pending?.operation === 'rollback' ? (t('snapshots.rollback') || 'Restaurer') : (t('snapshots.delete') || 'Supprimer')}
+        message={
+          pending?.operation === 'rollback'
+            ? `Restaurer ${name} sur le snapshot ${pending?.snapshot} ? L'état actuel sera perdu.`
+            : `Supprimer le snapshot ${pending?.snapshot} de ${name} ?`
+        }
+        variant={pending?.operation === 'delete' ? 'danger' : 'warning'}
+        confirmText="Confirmer"
+      />
+    </>
+  );
+}
@@ -0,0 +1,55 @@
+import { useEffect, useState } from 'react';
+import { Modal } from '@/components/ui/Modal';
+import { Loader } from '@/components/ui/Loader';
+import { useToast } from '@/components/ui/Toast';
+import { useTranslation } from '@/hooks/useTranslation';
+import { apiPost, TaskLogLine } from '@/utils/api';
+import { storage } from '@/utils/storage';
+
+interface TaskLogModalProps {
+  upid: string | null;
+  title?: string;
+  onClose: () => void;
+}
+
+export function TaskLogModal({ upid, title, onClose }: TaskLogModalProps) {
+  const { t } = useTranslation();
+  const { error } = useToast();
+  const [lines, setLines] = useState<TaskLogLine[]>([]);
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    const proxmox = storage.getProxmoxConnection();
+    if (!upid || !proxmox) {
+      setLines([]);
+      return;
+    }
+    setLoading(true);
+    apiPost<{ success: boolean; data: TaskLogLine[] }>('/api/v1/proxmox/tasks/log', {
+      connection_id: proxmox.connection_id,
+      upid,
+    })
+      .then(response => setLines(response.data))
+      .catch((err: any) => error('Erreur', `Impossible de lire le journal: ${err.message}`))
+      .finally(() => setLoading(false));
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [upid]);
+
+  return (
+    <Modal isOpen={upid !== null} onClose={onClose} title={title || t('tasks.log') || 'Journal de la tâche'} size="xl">
+      {loading ? (
+        <div className="flex justify-center py-8">
+          <Loader />
+        </div>
+      ) : (
+        <pre className="max-h-[70vh] overflow-auto rounded-xl bg-slate-900 p-4 text-xs text-slate-100">
+          {lines.map(line => (
+            <div key={line.n} className={line.t.startsWith('ERROR') ? 'text-red-400' : undefined}>
+              {line.t}
+            </div>
+          ))}
+        </pre>
+      )}
+    </Modal>
+  );
+}
@@ -1,11 +1,42 @@
 import { useEffect, useRef, useState } from 'react';
 import { authManager } from '../utils/auth';
 
+// Événements émis par le backend lorsqu'il détecte un changement entre deux collectes d'inventaire
+export const INVENTORY_EVENTS = [
+  'vm.status_changed',
+  'vm.created',
+  'vm.deleted',
+  'lxc.status_changed',
+  'lxc.created',
+  'lxc.deleted',
+  'node.offline',
+  'node.online',
+  'storage.threshold',
+  'task.finished',
+] as const;
+
+export type InventoryEventType = typeof INVENTORY_EVENTS[number];
+
+export interface InventoryEvent {
+  cluster?: string;
+  kind: 'node' | 'qemu' | 'lxc' | 'storage' | 'task';
+  id: string;
+  name?: string;
+  node?: string;
+  timestamp: string;
+  before: any;
+  after: any;
+  threshold?: number;
+  exceeded?: boolean;
+}
+
 interface UseSSEOptions {
   onAlert?: (alert: any) => void;
   onAck?: (data: any) => void;
+  onAlertUpdate?: (alert: any) => void;
   onPing?: (data: any) => void;
   onConnected?: (data: any) => void;
+  onInventoryEvent?: (type: InventoryEventType, data: InventoryEvent) => void;
   onError?: (error: Event) => void;
 }
 
@@ -98,6 +129,18 @@ export function useSSE(url: string, options: UseSSEOptions = {}) {
         }
       });
 
+      // Changement d'état d'une alerte (acquittement, résolution, snooze, assignation)
+      eventSource.addEventListener('alert.updated', (event) => {
+        try {
+          const data = JSON.parse((event as MessageEvent).data);
+          if (options.onAlertUpdate) {
+            options.onAlertUpdate(data);
+          }
+        } catch (err) {
+          console.error('Error parsing alert.updated event:', err);
+        }
+      });
+
       eventSource.addEventListener('ping', (event) => {
         try {
           const data = JSON.parse(event.data);
@@ -121,6 +164,19 @@ export function useSSE(url: string, options: UseSSEOptions = {}) {
         }
       });
 
+      INVENTORY_EVENTS.forEach((type) => {
+        eventSource.addEventListener(type, (event) => {
+          try {
+            const data = JSON.parse((event as MessageEvent).data);
+            if (options.onInventoryEvent) {
+              options.onInventoryEvent(type, data);
+            }
+          } catch (err) {
+            console.error(`Error parsing ${type} event:`, err);
+          }
+        });
+      });
+
     } catch (err) {
       console.error('Failed to create EventSource:', err);
       setError('Erreur lors de la création de la connexion SSE');
@@ -6,7 +6,7 @@ import { Button } from '@/components/ui/Button';
 import { Input } from '@/components/ui/Input';
 import { Select } from '@/components/ui/Select';
 import { Modal } from '@/components/ui/Modal';
-import { apiGet, apiPost, apiPut, apiDelete, App, CreateAppRequest, HealthStatus } from '@/utils/api';
+import { apiGet, apiPost, apiPut, apiDelete, App, AppUptime, CreateAppRequest, HealthStatus, UptimeStats } from '@/utils/api';
 import { useToast } from '@/components/ui/Toast';
 import { useTranslation } from '@/hooks/useTranslation';
 import { ConfirmModal } from '@/components/ui/ConfirmModal';
@@ -29,6 +29,8 @@ export function Apps() {
   const [editingApp, setEditingApp] = useState<App | null>(null);
   const { success, error } = useToast();
   const appsRef = useRef<AppWithHealth[]>([]);
+  // Uptime des health checks planifiés (24h, 7j, 30j) par application
+  const [uptimes, setUptimes] = useState<Record<number, UptimeStats[]>>({});
   
   const [confirmModal, setConfirmModal] = useState<{
     isOpen: boolean;
@@ -52,6 +54,7 @@ export function Apps() {
     icon: 'activity',
     health_path: '/health',
     health_type: 'http',
+    check_interval: 0,
   });
 
   const protocolOptions = [
@@ -135,6 +138,7 @@ export function Apps() {
       
       // Charger les health checks de manière asynchrone après l'affichage
       loadHealthChecks(appsWithIPs);
+      loadUptimes(appsWithIPs);
     } catch (err) {
       console.error('Erreur chargement applications:', err);
       error(t('common.error'), t('apps.load_error') || 'Impossible de charger les applications');
@@ -163,7 +167,7 @@ export function Apps() {
         
         try {
           const url = `${app.protocol}://${targetHost}:${app.port}${app.health_path}`;
-          const health = await apiGet<HealthStatus>(`/api/v1/health/http?url=${encodeURIComponent(url)}`);
+          const health = await apiGet<HealthStatus>(`/api/v1/health/http?url=${encodeURIComponent(url)}&app_id=${app.id}`);
           return { ...app, health };
         } catch (err: any) {
           const errorMsg = err?.message || 'Erreur inconnue';
@@ -186,6 +190,22 @@ export function Apps() {
     appsRef.current = appsWithHealth;
   };
 
+  // Charger l'uptime calculé par le backend à partir des health checks planifiés
+  const loadUptimes = async (appsToLoad: App[]) => {
+    const entries = await Promise.all(
+      appsToLoad.map(async (app) => {
+        try {
+          const uptime = await apiGet<AppUptime>(`/api/v1/apps/${app.id}/uptime`);
+          return [app.id, uptime.windows] as const;
+        } catch (err) {
+          console.error(`Erreur chargement uptime de l'application ${app.id}:`, err);
+          return [app.id, []] as const;
+        }
+      })
+    );
+    setUptimes(Object.fromEntries(entries));
+  };
+
   useEffect(() => {
     loadApps();
   }, []);
@@ -252,6 +272,7 @@ export function Apps() {
       icon: app.icon || 'activity',
       health_path: app.health_path || '/health',
       health_type: app.health_type || 'http',
+      check_interval: app.check_interval || 0,
     });
     setShowModal(true);
   };
@@ -268,6 +289,7 @@ export function Apps() {
       icon: 'activity',
       health_path: '/health',
       health_type: 'http',
+      check_interval: 0,
     });
   };
 
@@ -525,6 +547,25 @@ export function Apps() {
                   </div>
                 )}
 
+                {/* Uptime des health checks planifiés */}
+                {uptimes[app.id]?.some((w) => w.checks > 0) && (
+                  <div className="pt-3 border-t border-slate-200 dark:border-slate-700 space-y-2">
+                    <div className="text-xs font-medium text-slate-600 dark:text-slate-400">
+                      {t('apps.uptime') || 'Disponibilité'}
+                    </div>
+                    <div className="grid grid-cols-3 gap-2 text-xs text-slate-500 dark:text-slate-400">
+                      {uptimes[app.id].map((w) => (
+                        <div key={w.window} className="text-center" title={w.latency_p95_ms !== undefined ? `p95: ${w.latency_p95_ms}ms` : undefined}>
+                          <div className="font-medium text-slate-900 dark:text-slate-100">
+                            {w.uptime_percent !== null ? `${w.uptime_percent.toFixed(2)}%` : '—'}
+                          </div>
+                          <div>{w.window}</div>
+                        </div>
+                      ))}
+                    </div>
+                  </div>
+                )}
+
                 <div className="flex items-center justify-end space-x-2 pt-2 border-t border-slate-200 dark:border-slate-700">
                   <Button
                     variant="ghost"
@@ -630,6 +671,14 @@ export function Apps() {
             />
           </div>
 
+          <Input
+            label={t('apps.form.check_interval') || 'Intervalle de vérification (secondes, 0 = par défaut)'}
+            type="number"
+            min={0}
+            value={formData.check_interval ?? 0}
+            onChange={(e) => setFormData({ ...formData, check_interval: parseInt(e.target.value) || 0 })}
+          />
+
           <div className="flex justify-end space-x-3 pt-4">
             <Button
               type="button"
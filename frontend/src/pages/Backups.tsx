@@ -3,21 +3,17 @@ import {
   Archive,
   Download,
   Play,
-  Square,
-  RotateCcw,
-  Clock,
   HardDrive,
   Server,
-  Activity,
   CheckCircle,
-  MoreVertical,
-  Eye,
+  XCircle,
   Edit,
-  Settings,
   Calendar,
-  Database,
   Monitor,
-  Trash2
+  Lock,
+  FileText,
+  Power,
+  ShieldAlert
 } from 'lucide-react';
 import { Card, CardHeader, CardTitle, CardContent } from '@/components/ui/Card';
 import { Badge } from '@/components/ui/Badge';
@@ -25,182 +21,75 @@ import { Button } from '@/components/ui/Button';
 import { Input } from '@/components/ui/Input';
 import { Select } from '@/components/ui/Select';
 import { useToast } from '@/components/ui/Toast';
-import { ConfirmModal } from '@/components/ui/ConfirmModal';
 import { Loader } from '@/components/ui/Loader';
+import { BackupRunModal } from '@/components/BackupRunModal';
+import { BackupJobModal } from '@/components/BackupJobModal';
+import { TaskLogModal } from '@/components/TaskLogModal';
 import { exportToCSV } from '@/utils/export';
-import { apiPost } from '@/utils/api';
+import { apiGet, apiPost, apiPut, BackupArchive, BackupComplianceReport, BackupJob, BackupRun } from '@/utils/api';
 import { storage } from '@/utils/storage';
 
-interface Backup {
-  id: string;
-  name: string;
-  type: 'vm' | 'lxc' | 'host' | 'storage';
-  status: 'completed' | 'running' | 'failed' | 'scheduled';
-  size: number; // GB
-  duration: number; // minutes
-  started_at: string;
-  completed_at?: string;
-  next_run?: string;
-  node: string;
-  target: string;
-  retention: number; // days
-  compression: boolean;
-  encryption: boolean;
-  created_at: string;
-  vmid?: number;
-  lxc_id?: number;
-}
-
 export function Backups() {
-  const [backups, setBackups] = useState<Backup[]>([]);
+  const [backups, setBackups] = useState<BackupArchive[]>([]);
+  const [runs, setRuns] = useState<BackupRun[]>([]);
+  const [jobs, setJobs] = useState<BackupJob[]>([]);
+  const [compliance, setCompliance] = useState<BackupComplianceReport | null>(null);
   const [loading, setLoading] = useState(false);
   const [searchTerm, setSearchTerm] = useState('');
   const [typeFilter, setTypeFilter] = useState<string>('all');
-  
-  // États pour les modales de confirmation
-  const [confirmModal, setConfirmModal] = useState<{
-    isOpen: boolean;
-    title?: string;
-    message: string;
-    onConfirm: () => void;
-    variant?: 'danger' | 'warning' | 'info';
-  }>({
-    isOpen: false,
-    message: '',
-    onConfirm: () => {}
-  });
-  const [statusFilter, setStatusFilter] = useState<string>('all');
-  const { success, error, warning } = useToast();
+  const [storageFilter, setStorageFilter] = useState<string>('all');
+  const [runModal, setRunModal] = useState<{ isOpen: boolean; vmids: number[] }>({ isOpen: false, vmids: [] });
+  const [editedJob, setEditedJob] = useState<BackupJob | null>(null);
+  const [logTask, setLogTask] = useState<BackupRun | null>(null);
+  const { success, error } = useToast();
+
+  const credentials = () => {
+    return storage.proxmoxCredentials();
+  };
 
-  // Charger les données Backups depuis Proxmox
+  // Archives et exécutions viennent de l'inventaire du poller, les tâches planifiées de Proxmox
   const loadBackupsData = async () => {
     try {
-      setLoading(true);
-      
-      // Essayer de charger les données Proxmox réelles
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
-        console.log('⚠️ Aucune configuration Proxmox trouvée');
-        setBackups([]);
-        setLoading(false);
-        return;
-      }
-
-      const config = JSON.parse(savedConfig);
-
-      // Appeler l'API backend pour récupérer les backups
-      const data = await apiPost<{
-        success: boolean;
-        message?: string;
-        backups?: any[];
-      }>('/api/v1/proxmox/fetch-backups', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret
-      });
-
-      if (data.success && data.backups) {
-        // Convertir les données Proxmox vers le format Backup
-        const convertedBackups: Backup[] = data.backups.map((backup: any) => ({
-          id: backup.id || backup.name || 'unknown',
-          name: backup.name || backup.id || 'unknown',
-          type: (backup.type === 'lxc' ? 'lxc' : 'vm') as Backup['type'],
-          status: (backup.status === 'completed' ? 'completed' : backup.status === 'running' ? 'running' : 'failed') as Backup['status'],
-          size: backup.size || 0,
-          duration: backup.duration || 0,
-          started_at: backup.started_at || backup.created_at || new Date().toISOString(),
-          completed_at: backup.completed_at,
-          node: backup.node || 'unknown',
-          target: 'local', // Valeur par défaut
-          retention: 7, // Valeur par défaut
-          compression: false, // Non disponible dans les données Proxmox de base
-          encryption: false, // Non disponible dans les données Proxmox de base
-          created_at: backup.created_at || backup.started_at || new Date().toISOString(),
-          vmid: backup.vmid,
-          lxc_id: backup.type === 'lxc' ? backup.vmid : undefined
-        }));
-
-        setBackups(convertedBackups);
-        console.log('✅ Backups chargés depuis Proxmox:', convertedBackups.length);
-      } else {
-        console.warn('⚠️ Aucune donnée backup trouvée ou erreur:', data.message);
-        setBackups([]);
+      const [archives, history, report] = await Promise.all([
+        apiGet<{ success: boolean; backups: BackupArchive[] }>('/api/v1/proxmox/backups'),
+        apiGet<{ success: boolean; runs: BackupRun[] }>('/api/v1/proxmox/backups/runs'),
+        apiGet<{ success: boolean; data: BackupComplianceReport }>('/api/v1/reports/backup-compliance')
+      ]);
+      setBackups(archives.backups || []);
+      setRuns(history.runs || []);
+      setCompliance(report.data || null);
+
+      const creds = credentials();
+      if (creds) {
+        const list = await apiPost<{ success: boolean; data: BackupJob[] }>('/api/v1/proxmox/backups/jobs/list', creds);
+        setJobs(list.data || []);
       }
     } catch (err) {
       console.error('❌ Erreur lors du chargement des données Backups:', err);
-      setBackups([]);
     } finally {
       setLoading(false);
     }
   };
 
   useEffect(() => {
-    // Charger automatiquement les données Proxmox si la configuration existe
-    const loadDataOnMount = async () => {
-      await storage.ensureProxmoxDataLoaded();
-      // Charger les backups après avoir chargé les données
-      await loadBackupsData();
-    };
-    
-    loadDataOnMount();
+    setLoading(true);
+    loadBackupsData();
   }, []);
 
   // Rafraîchissement automatique toutes les 30 secondes
   useEffect(() => {
-    const interval = setInterval(async () => {
-      await storage.ensureProxmoxDataLoaded();
-      await loadBackupsData();
-    }, 30000); // 30 secondes
-
+    const interval = setInterval(loadBackupsData, 30000);
     return () => clearInterval(interval);
   }, []);
 
   const getTypeIcon = (type: string) => {
-    switch (type) {
-      case 'vm':
-        return <Monitor className="h-4 w-4 text-blue-500" />;
-      case 'lxc':
-        return <Server className="h-4 w-4 text-green-500" />;
-      case 'host':
-        return <Database className="h-4 w-4 text-purple-500" />;
-      case 'storage':
-        return <HardDrive className="h-4 w-4 text-orange-500" />;
-      default:
-        return <Archive className="h-4 w-4 text-gray-500" />;
-    }
-  };
-
-
-  const getStatusBadge = (status: string) => {
-    const variants = {
-      completed: 'success',
-      running: 'info',
-      failed: 'error',
-      scheduled: 'warning'
-    } as const;
-
-    const labels = {
-      completed: 'Terminé',
-      running: 'En cours',
-      failed: 'Échoué',
-      scheduled: 'Programmé'
-    };
-
-    return (
-      <Badge variant={variants[status as keyof typeof variants]}>
-        {labels[status as keyof typeof labels]}
-      </Badge>
-    );
+    return type === 'lxc'
+      ? <Server className="h-4 w-4 text-green-500" />
+      : <Monitor className="h-4 w-4 text-blue-500" />;
   };
 
   const getTypeLabel = (type: string) => {
-    const labels = {
-      vm: 'Machine virtuelle',
-      lxc: 'Conteneur LXC',
-      host: 'Hôte',
-      storage: 'Stockage'
-    };
-    return labels[type as keyof typeof labels] || type;
+    return type === 'lxc' ? 'Conteneur LXC' : 'Machine virtuelle';
   };
 
   const formatSize = (gb: number) => {
@@ -208,14 +97,6 @@ export function Backups() {
     return `${gb.toFixed(1)} GB`;
   };
 
-  const formatDuration = (minutes: number) => {
-    if (minutes === 0) return 'N/A';
-    if (minutes < 60) return `${minutes}min`;
-    const hours = Math.floor(minutes / 60);
-    const mins = minutes % 60;
-    return `${hours}h${mins > 0 ? `${mins}min` : ''}`;
-  };
-
   const formatDate = (dateString: string) => {
     return new Date(dateString).toLocaleString('fr-FR', {
       year: 'numeric',
@@ -227,136 +108,47 @@ export function Backups() {
   };
 
   const filteredBackups = backups.filter(backup => {
-    const matchesSearch = backup.name.toLowerCase().includes(searchTerm.toLowerCase()) ||
-                         backup.node.toLowerCase().includes(searchTerm.toLowerCase());
+    const term = searchTerm.toLowerCase();
+    const matchesSearch = backup.name.toLowerCase().includes(term) ||
+                         backup.node.toLowerCase().includes(term) ||
+                         String(backup.vmid).includes(term) ||
+                         (backup.notes || '').toLowerCase().includes(term);
     const matchesType = typeFilter === 'all' || backup.type === typeFilter;
-    const matchesStatus = statusFilter === 'all' || backup.status === statusFilter;
-    return matchesSearch && matchesType && matchesStatus;
+    const matchesStorage = storageFilter === 'all' || backup.storage === storageFilter;
+    return matchesSearch && matchesType && matchesStorage;
   });
 
-  // Actions pour les sauvegardes
-  const handleBackupStart = async (backup: Backup) => {
-    try {
-      await new Promise(resolve => setTimeout(resolve, 2000));
-
-      setBackups(prevBackups =>
-        prevBackups.map(b =>
-          b.id === backup.id
-            ? { ...b, status: 'running' as const, progress: 0 }
-            : b
-        )
-      );
+  const failedRuns = runs.filter(run => run.failed);
 
-      success('Succès', `Sauvegarde ${backup.name} démarrée avec succès`);
-    } catch (err) {
-      error('Erreur', `Impossible de démarrer la sauvegarde ${backup.name}`);
-    }
-  };
-
-  const handleBackupStop = async (backup: Backup) => {
+  const handleJobToggle = async (job: BackupJob) => {
+    const creds = credentials();
+    if (!creds) return;
     try {
-      await new Promise(resolve => setTimeout(resolve, 1000));
-
-      setBackups(prevBackups =>
-        prevBackups.map(b =>
-          b.id === backup.id
-            ? { ...b, status: 'failed' as const, progress: 0 }
-            : b
-        )
+      const response = await apiPut<{ success: boolean; data: BackupJob }>(
+        `/api/v1/proxmox/backups/jobs/${encodeURIComponent(job.id)}`,
+        { ...creds, job: { enabled: !job.enabled } }
       );
-
-      success('Succès', `Sauvegarde ${backup.name} arrêtée avec succès`);
-    } catch (err) {
-      error('Erreur', `Impossible d'arrêter la sauvegarde ${backup.name}`);
-    }
-  };
-
-  const handleBackupDelete = (backup: Backup) => {
-    setConfirmModal({
-      isOpen: true,
-      title: 'Confirmer la suppression',
-      message: `Êtes-vous sûr de vouloir supprimer la sauvegarde ${backup.name} ?\n\nCette action est irréversible.`,
-      variant: 'danger',
-      onConfirm: async () => {
-    try {
-      await new Promise(resolve => setTimeout(resolve, 1000));
-
-      setBackups(prevBackups =>
-        prevBackups.filter(b => b.id !== backup.id)
-      );
-
-      success('Succès', `Sauvegarde ${backup.name} supprimée avec succès`);
-    } catch (err) {
-      error('Erreur', `Impossible de supprimer la sauvegarde ${backup.name}`);
-    }
-      }
-    });
-  };
-
-  const handleBackupRestore = (backup: Backup) => {
-    setConfirmModal({
-      isOpen: true,
-      title: 'Confirmer la restauration',
-      message: `Êtes-vous sûr de vouloir restaurer la sauvegarde ${backup.name} ?\n\nCette action va remplacer les données actuelles.`,
-      variant: 'warning',
-      onConfirm: async () => {
-    try {
-      await new Promise(resolve => setTimeout(resolve, 3000));
-
-      success('Succès', `Restauration de la sauvegarde ${backup.name} terminée avec succès`);
-    } catch (err) {
-      error('Erreur', `Impossible de restaurer la sauvegarde ${backup.name}`);
-    }
-      }
-    });
-  };
-
-  const handleBackupDownload = async (backup: Backup) => {
-    try {
-      await new Promise(resolve => setTimeout(resolve, 2000));
-
-      success('Succès', `Téléchargement de la sauvegarde ${backup.name} terminé`);
-    } catch (err) {
-      error('Erreur', `Impossible de télécharger la sauvegarde ${backup.name}`);
+      setJobs(prev => prev.map(j => (j.id === job.id ? response.data : j)));
+      success('Succès', `Tâche ${job.id} ${job.enabled ? 'désactivée' : 'activée'}`);
+    } catch (err: any) {
+      error('Erreur', err.message);
     }
   };
 
-  const handleBackupConfig = (backup: Backup) => {
-    warning('Information', `La configuration détaillée de la sauvegarde ${backup.name} sera disponible dans une prochaine version`);
-  };
-
-  const handleBackupMore = (backup: Backup) => {
-    // Menu contextuel pour actions supplémentaires
-    const actions = [
-      { label: 'Vérifier', action: () => warning('Information', `La vérification de sauvegarde sera disponible dans une prochaine version`) },
-      { label: 'Dupliquer', action: () => warning('Information', `La duplication de sauvegarde sera disponible dans une prochaine version`) },
-      { label: 'Planifier', action: () => warning('Information', `La planification automatique sera disponible dans une prochaine version`) },
-    ];
-    
-    const actionList = actions.map(a => a.label).join(', ');
-    warning('Actions disponibles', `Pour ${backup.name}: ${actionList}`);
-  };
-
   const handleBackupExport = () => {
     try {
-      const filteredBackups = backups.filter(backup => {
-        const matchesSearch = backup.name.toLowerCase().includes(searchTerm.toLowerCase());
-        const matchesType = typeFilter === 'all' || backup.type === typeFilter;
-        const matchesStatus = statusFilter === 'all' || backup.status === statusFilter;
-        return matchesSearch && matchesType && matchesStatus;
-      });
-
       exportToCSV(filteredBackups, `sauvegardes-${new Date().toISOString().split('T')[0]}`, {
-        id: 'ID',
+        id: 'Volume',
         name: 'Nom',
+        vmid: 'ID VM',
         type: 'Type',
-        status: 'Statut',
         size: 'Taille (GB)',
         created_at: 'Créé le',
-        next_run: 'Prochaine exécution',
         node: 'Nœud',
-        vmid: 'ID VM',
-        target: 'Cible'
+        storage: 'Storage',
+        format: 'Format',
+        protected: 'Protégée',
+        notes: 'Notes'
       } as any);
       success('Export réussi', 'Les sauvegardes ont été exportées en CSV');
     } catch (err) {
@@ -364,7 +156,44 @@ export function Backups() {
     }
   };
 
+  // Rapport de conformité : une ligne par invité, selon l'âge maximal de sa politique
+  const handleComplianceExport = () => {
+    try {
+      exportToCSV((compliance?.guests || []).map(guest => ({
+        vmid: guest.vmid,
+        name: guest.name,
+        node: guest.node,
+        pool: guest.pool || '',
+        tags: (guest.tags || []).join(';'),
+        last_backup: guest.last_backup || '',
+        age_hours: guest.age_seconds !== undefined ? Math.floor(guest.age_seconds / 3600) : '',
+        retention_count: guest.retention_count,
+        max_age_hours: guest.max_age_seconds / 3600,
+        policy: guest.policy,
+        violation: guest.violation || ''
+      })), `conformite-sauvegardes-${new Date().toISOString().split('T')[0]}`, {
+        vmid: 'ID VM',
+        name: 'Nom',
+        node: 'Nœud',
+        pool: 'Pool',
+        tags: 'Tags',
+        last_backup: 'Dernière sauvegarde',
+        age_hours: 'Âge (h)',
+        retention_count: 'Archives',
+        max_age_hours: 'Âge maximal (h)',
+        policy: 'Politique',
+        violation: 'Non-conformité'
+      });
+      success('Export réussi', 'Le rapport de conformité a été exporté en CSV');
+    } catch (err) {
+      error('Erreur', 'Impossible d\'exporter le rapport de conformité');
+    }
+  };
+
+  const nonCompliant = (compliance?.guests || []).filter(guest => !guest.compliant);
+
   const uniqueTypes = [...new Set(backups.map(backup => backup.type))];
+  const uniqueStorages = [...new Set(backups.map(backup => backup.storage))];
 
   if (loading) {
     return (
@@ -376,13 +205,19 @@ export function Backups() {
 
   return (
     <div className="space-y-6">
-      <div>
-        <h1 className="text-2xl font-bold text-slate-900 dark:text-slate-100">
-          Sauvegardes
-        </h1>
-        <p className="text-slate-600 dark:text-slate-400">
-          Gestion des sauvegardes et stratégies de rétention
-        </p>
+      <div className="flex items-center justify-between">
+        <div>
+          <h1 className="text-2xl font-bold text-slate-900 dark:text-slate-100">
+            Sauvegardes
+          </h1>
+          <p className="text-slate-600 dark:text-slate-400">
+            Archives, exécutions vzdump et tâches planifiées
+          </p>
+        </div>
+        <Button onClick={() => setRunModal({ isOpen: true, vmids: [] })}>
+          <Play className="h-4 w-4 mr-2" />
+          Sauvegarder maintenant
+        </Button>
       </div>
 
       {/* Statistiques */}
@@ -392,7 +227,7 @@ export function Backups() {
             <div className="flex items-center space-x-2">
               <Archive className="h-4 w-4 text-slate-600 dark:text-slate-400" />
               <span className="text-sm font-medium text-slate-600 dark:text-slate-400">
-                Total
+                Archives
               </span>
             </div>
             <div className="text-2xl font-bold text-slate-900 dark:text-slate-100 mt-1">
@@ -406,11 +241,11 @@ export function Backups() {
             <div className="flex items-center space-x-2">
               <CheckCircle className="h-4 w-4 text-green-500" />
               <span className="text-sm font-medium text-slate-600 dark:text-slate-400">
-                Terminées
+                Exécutions réussies
               </span>
             </div>
             <div className="text-2xl font-bold text-slate-900 dark:text-slate-100 mt-1">
-              {backups.filter(b => b.status === 'completed').length}
+              {runs.filter(r => !r.failed && r.status === 'completed').length}
             </div>
           </CardContent>
         </Card>
@@ -418,13 +253,13 @@ export function Backups() {
         <Card>
           <CardContent className="p-4">
             <div className="flex items-center space-x-2">
-              <Play className="h-4 w-4 text-blue-500" />
+              <XCircle className="h-4 w-4 text-red-500" />
               <span className="text-sm font-medium text-slate-600 dark:text-slate-400">
-                En cours
+                Échecs
               </span>
             </div>
             <div className="text-2xl font-bold text-slate-900 dark:text-slate-100 mt-1">
-              {backups.filter(b => b.status === 'running').length}
+              {failedRuns.length}
             </div>
           </CardContent>
         </Card>
@@ -444,6 +279,158 @@ export function Backups() {
         </Card>
       </div>
 
+      {/* Exécutions en échec */}
+      {failedRuns.length > 0 && (
+        <Card>
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <XCircle className="h-5 w-5 text-red-500" />
+              Sauvegardes en échec
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-2">
+            {failedRuns.map(run => (
+              <div
+                key={run.id}
+                className="flex items-center justify-between rounded-xl border border-red-200 px-3 py-2 text-sm dark:border-red-900"
+              >
+                <div className="min-w-0">
+                  <div className="font-medium text-slate-900 dark:text-slate-100">
+                    {run.vmid ? `VM/CT ${run.vmid}` : 'Tâche planifiée'} • {run.node}
+                    {run.cluster && ` • ${run.cluster}`}
+                  </div>
+                  <div className="truncate text-xs text-slate-500 dark:text-slate-400">
+                    {formatDate(run.started_at)} — {run.exitstatus}
+                  </div>
+                </div>
+                <div className="flex flex-shrink-0 gap-1">
+                  <Button variant="ghost" size="sm" title="Journal" onClick={() => setLogTask(run)}>
+                    <FileText className="h-4 w-4" />
+                  </Button>
+                  {run.vmid ? (
+                    <Button
+                      variant="ghost"
+                      size="sm"
+                      title="Relancer"
+                      onClick={() => setRunModal({ isOpen: true, vmids: [run.vmid!] })}
+                    >
+                      <Play className="h-4 w-4" />
+                    </Button>
+                  ) : null}
+                </div>
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+      )}
+
+      {/* Conformité des sauvegardes */}
+      {compliance && (compliance.total > 0 || !compliance.complete) && (
+        <Card>
+          <CardHeader>
+            <div className="flex items-center justify-between">
+              <CardTitle className="flex items-center gap-2">
+                <ShieldAlert className={`h-5 w-5 ${nonCompliant.length > 0 || !compliance.complete ? 'text-orange-500' : 'text-green-500'}`} />
+                Conformité : {compliance.compliant}/{compliance.total} invités
+                {!compliance.complete && (
+                  <Badge variant="warning" size="sm">Rapport incomplet</Badge>
+                )}
+              </CardTitle>
+              <Button variant="outline" size="sm" onClick={handleComplianceExport}>
+                <Download className="h-4 w-4 mr-2" />
+                Exporter
+              </Button>
+            </div>
+          </CardHeader>
+          {(nonCompliant.length > 0 || !compliance.complete) && (
+            <CardContent className="space-y-2">
+              {(compliance.warnings || []).map(warning => (
+                <div key={warning} className="text-xs text-orange-600 dark:text-orange-400">
+                  {warning}
+                </div>
+              ))}
+              {nonCompliant.map(guest => (
+                <div
+                  key={`${guest.cluster}-${guest.vmid}`}
+                  className="flex items-center justify-between rounded-xl border border-orange-200 px-3 py-2 text-sm dark:border-orange-900"
+                >
+                  <div className="min-w-0">
+                    <div className="font-medium text-slate-900 dark:text-slate-100">
+                      {guest.name} ({guest.vmid}) • {guest.node}
+                      {guest.cluster && ` • ${guest.cluster}`}
+                    </div>
+                    <div className="truncate text-xs text-slate-500 dark:text-slate-400">
+                      {guest.violation === 'unknown' && 'Conformité inconnue — '}
+                      {guest.last_backup
+                        ? `Dernière sauvegarde ${formatDate(guest.last_backup)}`
+                        : 'Aucune sauvegarde'} — maximum {guest.max_age_seconds / 3600} h ({guest.policy})
+                    </div>
+                  </div>
+                  <Button
+                    variant="ghost"
+                    size="sm"
+                    title="Sauvegarder"
+                    onClick={() => setRunModal({ isOpen: true, vmids: [guest.vmid] })}
+                  >
+                    <Play className="h-4 w-4" />
+                  </Button>
+                </div>
+              ))}
+            </CardContent>
+          )}
+        </Card>
+      )}
+
+      {/* Tâches planifiées */}
+      {jobs.length > 0 && (
+        <Card>
+          <CardHeader>
+            <CardTitle className="flex items-center gap-2">
+              <Calendar className="h-5 w-5 text-slate-500" />
+              Tâches planifiées
+            </CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-2">
+            {jobs.map(job => (
+              <div
+                key={job.id}
+                className="flex items-center justify-between rounded-xl border border-slate-200 px-3 py-2 text-sm dark:border-slate-700"
+              >
+                <div className="min-w-0">
+                  <div className="flex items-center gap-2 font-medium text-slate-900 dark:text-slate-100">
+                    {job.id}
+                    <Badge variant={job.enabled ? 'success' : 'default'} size="sm">
+                      {job.enabled ? 'Active' : 'Inactive'}
+                    </Badge>
+                  </div>
+                  <div className="truncate text-xs text-slate-500 dark:text-slate-400">
+                    {job.schedule}
+                    {job.storage && ` → ${job.storage}`}
+                    {' • '}
+                    {job.all ? 'Tous les invités' : job.pool ? `Pool ${job.pool}` : `VMID ${(job.vmids || []).join(', ')}`}
+                    {job['next-run'] && ` • prochaine ${formatDate(new Date(job['next-run'] * 1000).toISOString())}`}
+                    {job.comment && ` — ${job.comment}`}
+                  </div>
+                </div>
+                <div className="flex flex-shrink-0 gap-1">
+                  <Button
+                    variant="ghost"
+                    size="sm"
+                    title={job.enabled ? 'Désactiver' : 'Activer'}
+                    onClick={() => handleJobToggle(job)}
+                  >
+                    <Power className="h-4 w-4" />
+                  </Button>
+                  <Button variant="ghost" size="sm" title="Modifier" onClick={() => setEditedJob(job)}>
+                    <Edit className="h-4 w-4" />
+                  </Button>
+                </div>
+              </div>
+            ))}
+          </CardContent>
+        </Card>
+      )}
+
       {/* Filtres */}
       <div className="flex flex-wrap gap-4 items-center">
         <div className="flex-1 min-w-64">
@@ -462,18 +449,15 @@ export function Backups() {
           ]}
         />
         <Select
-          value={statusFilter}
-          onChange={(e) => setStatusFilter(e.target.value)}
+          value={storageFilter}
+          onChange={(e) => setStorageFilter(e.target.value)}
           options={[
-            { value: 'all', label: 'Tous les statuts' },
-            { value: 'completed', label: 'Terminées' },
-            { value: 'running', label: 'En cours' },
-            { value: 'failed', label: 'Échouées' },
-            { value: 'scheduled', label: 'Programmées' }
+            { value: 'all', label: 'Tous les storages' },
+            ...uniqueStorages.map(s => ({ value: s, label: s }))
           ]}
         />
-        <Button 
-          variant="outline" 
+        <Button
+          variant="outline"
           size="sm"
           onClick={handleBackupExport}
         >
@@ -482,10 +466,10 @@ export function Backups() {
         </Button>
       </div>
 
-      {/* Liste des sauvegardes */}
+      {/* Liste des archives */}
       <div className="grid grid-cols-1 lg:grid-cols-2 xl:grid-cols-3 gap-6">
         {filteredBackups.map((backup) => (
-          <Card key={backup.id} className="relative">
+          <Card key={`${backup.cluster || ''}/${backup.id}`} className="relative">
             <CardHeader className="pb-3">
               <div className="flex items-center justify-between">
                 <div className="flex items-center space-x-3">
@@ -493,55 +477,25 @@ export function Backups() {
                   <div>
                     <CardTitle className="text-lg">{backup.name}</CardTitle>
                     <p className="text-sm text-slate-600 dark:text-slate-400">
-                      {getTypeLabel(backup.type)} • {backup.node}
+                      {getTypeLabel(backup.type)} {backup.vmid} • {backup.node}
                     </p>
                   </div>
                 </div>
-                <div className="flex items-center space-x-2">
-                  {getStatusBadge(backup.status)}
-                  <div className="flex space-x-1">
-                    <Button
-                      variant="ghost"
-                      size="sm"
-                      className="p-1"
-                      onClick={() => success('Information', `Affichage des détails de la sauvegarde ${backup.name}`)}
-                    >
-                      <Eye className="h-4 w-4" />
-                    </Button>
-                    <Button
-                      variant="ghost"
-                      size="sm"
-                      className="p-1"
-                      onClick={() => warning('Information', `L'édition de la sauvegarde ${backup.name} sera disponible dans une prochaine version`)}
-                    >
-                      <Edit className="h-4 w-4" />
-                    </Button>
-                    <Button 
-                      variant="ghost" 
-                      size="sm" 
-                      className="p-1"
-                      onClick={() => handleBackupMore(backup)}
-                      title="Plus d'actions"
-                    >
-                      <MoreVertical className="h-4 w-4" />
-                    </Button>
-                  </div>
-                </div>
+                {backup.protected && (
+                  <Badge variant="info">
+                    <Lock className="h-3 w-3 mr-1 inline" />
+                    Protégée
+                  </Badge>
+                )}
               </div>
             </CardHeader>
 
             <CardContent className="space-y-4">
-              {/* Informations de base */}
               <div className="grid grid-cols-2 gap-4 text-sm">
                 <div className="flex items-center space-x-2">
                   <HardDrive className="h-4 w-4 text-slate-400" />
-                  <span className="text-slate-600 dark:text-slate-400">Cible:</span>
-                  <span>{backup.target}</span>
-                </div>
-                <div className="flex items-center space-x-2">
-                  <Clock className="h-4 w-4 text-slate-400" />
-                  <span className="text-slate-600 dark:text-slate-400">Durée:</span>
-                  <span>{formatDuration(backup.duration)}</span>
+                  <span className="text-slate-600 dark:text-slate-400">Storage:</span>
+                  <span>{backup.storage}</span>
                 </div>
                 <div className="flex items-center space-x-2">
                   <Archive className="h-4 w-4 text-slate-400" />
@@ -550,112 +504,34 @@ export function Backups() {
                 </div>
                 <div className="flex items-center space-x-2">
                   <Calendar className="h-4 w-4 text-slate-400" />
-                  <span className="text-slate-600 dark:text-slate-400">Rétention:</span>
-                  <span>{backup.retention}j</span>
+                  <span className="text-slate-600 dark:text-slate-400">Créée:</span>
+                  <span>{formatDate(backup.created_at)}</span>
                 </div>
+                {backup.format && (
+                  <div className="flex items-center space-x-2">
+                    <FileText className="h-4 w-4 text-slate-400" />
+                    <span className="text-slate-600 dark:text-slate-400">Format:</span>
+                    <span>{backup.format}</span>
+                  </div>
+                )}
               </div>
 
-              {/* ID de la ressource */}
-              {(backup.vmid || backup.lxc_id) && (
-                <div className="text-sm">
-                  <span className="text-slate-600 dark:text-slate-400">
-                    {backup.type === 'vm' ? 'VM ID:' : 'LXC ID:'}
-                  </span>
-                  <span className="ml-2 font-mono">{backup.vmid || backup.lxc_id}</span>
-                </div>
+              {backup.notes && (
+                <p className="text-sm text-slate-600 dark:text-slate-400 whitespace-pre-line">{backup.notes}</p>
               )}
 
-              {/* Options de sauvegarde */}
-              <div className="flex items-center space-x-4 text-sm">
-                <div className="flex items-center space-x-2">
-                  <Archive className="h-4 w-4 text-slate-400" />
-                  <span className="text-slate-600 dark:text-slate-400">Compression:</span>
-                  <Badge variant={backup.compression ? 'success' : 'default'} size="sm">
-                    {backup.compression ? 'Activée' : 'Désactivée'}
-                  </Badge>
-                </div>
-                <div className="flex items-center space-x-2">
-                  <Activity className="h-4 w-4 text-slate-400" />
-                  <span className="text-slate-600 dark:text-slate-400">Chiffrement:</span>
-                  <Badge variant={backup.encryption ? 'success' : 'default'} size="sm">
-                    {backup.encryption ? 'Activé' : 'Désactivé'}
-                  </Badge>
-                </div>
+              <div className="truncate font-mono text-xs text-slate-500" title={backup.id}>
+                {backup.id}
               </div>
 
-              {/* Dates */}
-              <div className="space-y-2 text-sm">
-                <div>
-                  <span className="text-slate-600 dark:text-slate-400">Démarré:</span>
-                  <span className="ml-2">{formatDate(backup.started_at)}</span>
-                </div>
-                {backup.completed_at && (
-                  <div>
-                    <span className="text-slate-600 dark:text-slate-400">Terminé:</span>
-                    <span className="ml-2">{formatDate(backup.completed_at)}</span>
-                  </div>
-                )}
-                {backup.next_run && (
-                  <div>
-                    <span className="text-slate-600 dark:text-slate-400">Prochaine:</span>
-                    <span className="ml-2">{formatDate(backup.next_run)}</span>
-                  </div>
-                )}
-              </div>
-
-              {/* Actions */}
               <div className="flex space-x-2 pt-2 border-t border-slate-200 dark:border-slate-700">
-                {backup.status === 'running' ? (
-                  <Button
-                    variant="outline"
-                    size="sm"
-                    onClick={() => handleBackupStop(backup)}
-                  >
-                    <Square className="h-4 w-4 mr-1" />
-                    Arrêter
-                  </Button>
-                ) : backup.status === 'scheduled' ? (
-                  <Button
-                    variant="outline"
-                    size="sm"
-                    onClick={() => handleBackupStart(backup)}
-                  >
-                    <Play className="h-4 w-4 mr-1" />
-                    Démarrer
-                  </Button>
-                ) : (
-                  <Button
-                    variant="outline"
-                    size="sm"
-                    onClick={() => handleBackupRestore(backup)}
-                  >
-                    <RotateCcw className="h-4 w-4 mr-1" />
-                    Restaurer
-                  </Button>
-                )}
-                <Button
-                  variant="outline"
-                  size="sm"
-                  onClick={() => handleBackupDownload(backup)}
-                >
-                  <Download className="h-4 w-4 mr-1" />
-                  Télécharger
-                </Button>
                 <Button
                   variant="outline"
                   size="sm"
-                  onClick={() => handleBackupConfig(backup)}
+                  onClick={() => setRunModal({ isOpen: true, vmids: [backup.vmid] })}
                 >
-                  <Settings className="h-4 w-4 mr-1" />
-                  Config
-                </Button>
-                <Button
-                  variant="outline"
-                  size="sm"
-                  onClick={() => handleBackupDelete(backup)}
-                >
-                  <Trash2 className="h-4 w-4 mr-1" />
-                  Supprimer
+                  <Play className="h-4 w-4 mr-1" />
+                  Sauvegarder
                 </Button>
               </div>
             </CardContent>
@@ -677,16 +553,21 @@ export function Backups() {
         </Card>
       )}
 
-      {/* Modale de confirmation */}
-      <ConfirmModal
-        isOpen={confirmModal.isOpen}
-        onClose={() => setConfirmModal({ ...confirmModal, isOpen: false })}
-        onConfirm={confirmModal.onConfirm}
-        title={confirmModal.title}
-        message={confirmModal.message}
-        variant={confirmModal.variant || 'warning'}
-        confirmText="Confirmer"
-        cancelText="Annuler"
+      <BackupRunModal
+        isOpen={runModal.isOpen}
+        vmids={runModal.vmids}
+        onClose={() => setRunModal({ isOpen: false, vmids: [] })}
+        onStarted={loadBackupsData}
+      />
+      <BackupJobModal
+        job={editedJob}
+        onClose={() => setEditedJob(null)}
+        onSaved={job => setJobs(prev => prev.map(j => (j.id === job.id ? job : j)))}
+      />
+      <TaskLogModal
+        upid={logTask?.id || null}
+        title={logTask ? `Journal — ${logTask.vmid ? `VM/CT ${logTask.vmid}` : logTask.node}` : undefined}
+        onClose={() => setLogTask(null)}
       />
     </div>
   );
@@ -85,25 +85,21 @@ export function Databases() {
       setLoading(true);
       
       // Essayer de charger les données Proxmox réelles
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         setDatabases([]);
         setLoading(false);
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-
       // Appeler l'API backend pour récupérer les bases de données
       const data = await apiPost<{
         success: boolean;
         message?: string;
         databases?: any[];
       }>('/api/v1/proxmox/fetch-databases', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret
+        connection_id: config.connection_id
       });
 
       if (data.success && data.databases && data.databases.length > 0) {
@@ -85,8 +85,8 @@ export function Docker() {
       setLoading(true);
       
       // Essayer de charger les données Proxmox réelles
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         setContainers([]);
         setImages([]);
@@ -94,17 +94,13 @@ export function Docker() {
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-
       // Appeler l'API backend pour récupérer les conteneurs Docker (via LXC)
       const data = await apiPost<{
         success: boolean;
         message?: string;
         containers?: any[];
       }>('/api/v1/proxmox/fetch-docker', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret
+        connection_id: config.connection_id
       });
 
       if (data.success && data.containers) {
@@ -11,7 +11,8 @@ import {
   MoreVertical,
   Eye,
   Edit,
-  RefreshCw
+  RefreshCw,
+  Camera
 } from 'lucide-react';
 import { Card, CardHeader, CardTitle, CardContent } from '@/components/ui/Card';
 import { Badge } from '@/components/ui/Badge';
@@ -21,8 +22,11 @@ import { Select } from '@/components/ui/Select';
 import { useToast } from '@/components/ui/Toast';
 import { useTranslation } from '@/hooks/useTranslation';
 import { ConfirmModal } from '@/components/ui/ConfirmModal';
+import { GuestConfigModal } from '@/components/GuestConfigModal';
+import { SnapshotsModal } from '@/components/SnapshotsModal';
 import { Loader } from '@/components/ui/Loader';
-import { apiPost } from '@/utils/api';
+import { apiGet, apiPost } from '@/utils/api';
+import { ProxmoxConnection } from '@/utils/proxmox';
 import { storage } from '@/utils/storage';
 
 interface LXCContainer {
@@ -53,6 +57,10 @@ export function LXC() {
   const [showMoreMenu, setShowMoreMenu] = useState<number | null>(null);
   const { success, error, warning } = useToast();
   
+  // Invité dont la configuration est ouverte
+  const [configGuest, setConfigGuest] = useState<LXCContainer | null>(null);
+  const [snapshotGuest, setSnapshotGuest] = useState<LXCContainer | null>(null);
+
   // États pour les modales de confirmation
   const [confirmModal, setConfirmModal] = useState<{
     isOpen: boolean;
@@ -99,7 +107,7 @@ export function LXC() {
         setLoading(false);
       } else {
         const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
-        const proxmoxConfig = storage.getProxmoxConfig();
+        const proxmoxConfig = storage.getProxmoxConnection();
         
         // En production, si Proxmox n'est pas configuré, ne pas charger de données mockées
         if (isProduction && !proxmoxConfig) {
@@ -115,7 +123,7 @@ export function LXC() {
     } catch (err) {
       console.error('❌ Erreur lors du chargement des conteneurs LXC:', err);
       const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
-      const proxmoxConfig = storage.getProxmoxConfig();
+      const proxmoxConfig = storage.getProxmoxConnection();
       
       // En production, si Proxmox n'est pas configuré, ne pas charger de données mockées
       if (isProduction && !proxmoxConfig) {
@@ -237,15 +245,13 @@ export function LXC() {
     try {
       console.log('🔄 Rafraîchissement des données LXC Proxmox...');
 
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         error(t('common.error'), t('lxc.no_proxmox_config') || 'Aucune configuration Proxmox trouvée. Veuillez d\'abord configurer votre connexion Proxmox dans les Paramètres.');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-
       // Utiliser apiPost pour utiliser la bonne URL de l'API (API_BASE_URL)
       const data = await apiPost<{
         success: boolean;
@@ -255,9 +261,7 @@ export function LXC() {
         lxc?: any[];
         storages?: any[];
       }>('/api/v1/proxmox/fetch-data', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret,
+        connection_id: config.connection_id,
         node: config.node
       });
 
@@ -348,35 +352,22 @@ export function LXC() {
   // Actions pour les conteneurs LXC
   const handleContainerStart = async (container: LXCContainer) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         error('Erreur', 'Configuration Proxmox manquante');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      const base = config.url.replace(/\/$/, '');
-      
-      // Vérifier si c'est une URL de développement fictive
-      const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-      
-      if (isDevUrl) {
-        warning(
-          'Configuration de développement',
-          'Cette action nécessite une connexion Proxmox réelle. Veuillez configurer une connexion Proxmox réelle dans les Paramètres pour utiliser cette fonctionnalité.'
-        );
-        return;
-      }
-      
-      const response = await fetch(`${config.url}/api2/json/nodes/${container.node}/lxc/${container.vmid}/status/start`, {
-        method: 'POST',
-        headers: {
-          'Authorization': `PVEAPIToken=${config.username}=${config.secret}`,
-          'Content-Type': 'application/json',
+      const response = await apiPost<{ success: boolean; upid?: string; error?: string }>(
+        '/api/v1/proxmox/lxc/start',
+        {
+          connection_id: config.connection_id,
+          node: container.node,
+          vmid: container.vmid
         }
-      });
+      );
 
-      if (response.ok) {
+      if (response.success) {
       setContainers(prevContainers =>
         prevContainers.map(c =>
           c.id === container.id
@@ -389,7 +380,7 @@ export function LXC() {
           refreshContainers();
         }, 1500);
       } else {
-        throw new Error(`HTTP ${response.status}`);
+        throw new Error(response.error || 'Erreur inconnue');
       }
     } catch (err) {
       console.error('Erreur démarrage conteneur:', err);
@@ -405,35 +396,22 @@ export function LXC() {
       variant: 'warning',
       onConfirm: async () => {
         try {
-          const savedConfig = localStorage.getItem('proxmoxConfig');
-          if (!savedConfig) {
+          const config = storage.getProxmoxConnection();
+          if (!config) {
             error('Erreur', 'Configuration Proxmox manquante');
             return;
           }
 
-          const config = JSON.parse(savedConfig);
-          const base = config.url.replace(/\/$/, '');
-          
-          // Vérifier si c'est une URL de développement fictive
-          const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-          
-          if (isDevUrl) {
-            warning(
-              'Configuration de développement',
-              'Cette action nécessite une connexion Proxmox réelle. Veuillez configurer une connexion Proxmox réelle dans les Paramètres pour utiliser cette fonctionnalité.'
-            );
-            return;
-          }
-          
-          const response = await fetch(`${config.url}/api2/json/nodes/${container.node}/lxc/${container.vmid}/status/stop`, {
-            method: 'POST',
-            headers: {
-              'Authorization': `PVEAPIToken=${config.username}=${config.secret}`,
-              'Content-Type': 'application/json',
+          const response = await apiPost<{ success: boolean; upid?: string; error?: string }>(
+            '/api/v1/proxmox/lxc/stop',
+            {
+              connection_id: config.connection_id,
+              node: container.node,
+              vmid: container.vmid
             }
-          });
+          );
 
-          if (response.ok) {
+          if (response.success) {
       setContainers(prevContainers =>
         prevContainers.map(c =>
           c.id === container.id
@@ -446,7 +424,7 @@ export function LXC() {
               refreshContainers();
             }, 1500);
           } else {
-            throw new Error(`HTTP ${response.status}`);
+            throw new Error(response.error || 'Erreur inconnue');
           }
     } catch (err) {
           console.error('Erreur arrêt conteneur:', err);
@@ -457,42 +435,7 @@ export function LXC() {
   };
 
   const handleContainerConfig = (container: LXCContainer) => {
-    try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
-        warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la configuration');
-        return;
-      }
-
-      const config = JSON.parse(savedConfig);
-      const base = config.url.replace(/\/$/, '');
-      
-      // Vérifier si c'est une URL de développement fictive
-      const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-      
-      if (isDevUrl) {
-        warning(
-          'Configuration de développement',
-          'La configuration Proxmox ne peut pas être ouverte avec une configuration fictive. Veuillez configurer une connexion Proxmox réelle dans les Paramètres pour utiliser cette fonctionnalité.'
-        );
-        return;
-      }
-      
-      // Ouvrir la page de configuration du conteneur dans Proxmox
-      const configUrl = `${base}/?vmid=${container.vmid}&node=${encodeURIComponent(container.node)}`;
-      console.log(`⚙️ Ouverture de la configuration pour ${container.name} (${container.vmid})...`);
-      const configWindow = window.open(configUrl, '_blank', 'width=1200,height=800');
-      
-      if (configWindow) {
-        success('Succès', `Configuration ouverte pour ${container.name}`);
-      } else {
-        warning('Attention', 'La fenêtre de configuration a été bloquée par le navigateur. Veuillez autoriser les popups pour ce site.');
-      }
-    } catch (err: any) {
-      console.error('Erreur configuration conteneur:', err);
-      const errorMessage = err.message || 'Erreur lors de l\'ouverture de la configuration';
-      error('Erreur', `Impossible d'ouvrir la configuration pour ${container.name}: ${errorMessage}`);
-    }
+    setConfigGuest(container);
   };
 
   const handleContainerView = (container: LXCContainer) => {
@@ -503,28 +446,17 @@ export function LXC() {
     warning('Information', `L'édition du conteneur ${container.name} sera disponible dans une prochaine version`);
   };
 
-  const handleContainerConsole = (container: LXCContainer) => {
+  const handleContainerConsole = async (container: LXCContainer) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la console');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      const base = config.url.replace(/\/$/, '');
-      
-      // Vérifier si c'est une URL de développement fictive
-      const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-      
-      if (isDevUrl) {
-        warning(
-          'Configuration de développement',
-          'La console Proxmox ne peut pas être ouverte avec une configuration fictive. Veuillez configurer une connexion Proxmox réelle dans les Paramètres pour utiliser la console.'
-        );
-        return;
-      }
-      
+      // La console s'ouvre sur l'interface Proxmox de la connexion active (l'URL n'est pas un secret)
+      const connection = await apiGet<ProxmoxConnection>(`/api/v1/proxmox/connections/${config.connection_id}`);
+      const base = connection.url.replace(/\/$/, '');
       const consoleUrl = `${base}/?console=lxc&novnc=1&vmid=${container.vmid}&node=${container.node}`;
       console.log(`🖥️ Ouverture de la console pour ${container.name} (${container.vmid})...`);
       const consoleWindow = window.open(consoleUrl, '_blank', 'width=800,height=600');
@@ -866,6 +798,14 @@ export function LXC() {
                   <Settings className="h-4 w-4 mr-1" />
                   Config
                 </Button>
+                <Button
+                  variant="outline"
+                  size="sm"
+                  onClick={() => setSnapshotGuest(container)}
+                >
+                  <Camera className="h-4 w-4 mr-1" />
+                  Snapshots
+                </Button>
               </div>
             </CardContent>
           </Card>
@@ -886,6 +826,32 @@ export function LXC() {
         </Card>
       )}
 
+      {/* Édition de la configuration */}
+      {configGuest && (
+        <GuestConfigModal
+          isOpen={!!configGuest}
+          onClose={() => setConfigGuest(null)}
+          guestType="lxc"
+          node={configGuest.node}
+          vmid={configGuest.vmid}
+          name={configGuest.name}
+          onSaved={() => refreshContainers()}
+        />
+      )}
+
+      {/* Snapshots de l'invité */}
+      {snapshotGuest && (
+        <SnapshotsModal
+          isOpen={!!snapshotGuest}
+          onClose={() => setSnapshotGuest(null)}
+          guestType="lxc"
+          node={snapshotGuest.node}
+          vmid={snapshotGuest.vmid}
+          name={snapshotGuest.name}
+          onChanged={() => refreshContainers()}
+        />
+      )}
+
       {/* Modale de confirmation */}
       <ConfirmModal
         isOpen={confirmModal.isOpen}
@@ -1,66 +1,53 @@
 import { useEffect, useMemo, useState } from 'react';
 import { Card, CardHeader, CardTitle, CardContent } from '@/components/ui/Card';
-import { Input } from '@/components/ui/Input';
+import { Select } from '@/components/ui/Select';
 import { Button } from '@/components/ui/Button';
 import { Activity, Cpu, MemoryStick, HardDrive, RefreshCw } from 'lucide-react';
 import { useToast } from '@/components/ui/Toast';
 import { Loader } from '@/components/ui/Loader';
 import { useTranslation } from '@/hooks/useTranslation';
+import { apiGet, PrometheusDataSource } from '@/utils/api';
 
 interface PromResult {
   status: string;
-  data?: { result: Array<{ value: [number, string] }> };
+  data?: { result: Array<{ value?: [number, string]; values?: Array<[number, string]> }> };
   error?: string;
 }
 
+// Période et pas des graphiques (query_range)
+const RANGE_SECONDS = 3600;
+const STEP_SECONDS = 60;
+
 export function Monitoring() {
   const { t } = useTranslation();
   const { error, warning, success } = useToast();
-  const [baseUrl, setBaseUrl] = useState<string>('');
+  const [sources, setSources] = useState<PrometheusDataSource[]>([]);
+  const [datasource, setDatasource] = useState<string>('');
   const [loading, setLoading] = useState(false);
   const [cpu, setCpu] = useState<number | null>(null);
   const [mem, setMem] = useState<number | null>(null);
   const [disk, setDisk] = useState<number | null>(null);
+  const [history, setHistory] = useState<Record<string, number[]>>({});
   const [lastUpdate, setLastUpdate] = useState<Date | null>(null);
 
-  // Charger la configuration Prometheus depuis localStorage
+  // Charger les sources de données configurées côté serveur (Paramètres > Configuration Prometheus)
   useEffect(() => {
-    const loadConfig = () => {
-      const saved = localStorage.getItem('prometheusConfig');
-      if (saved) {
-        try {
-          const parsed = JSON.parse(saved);
-          if (parsed?.url) {
-            setBaseUrl(parsed.url);
-          }
-        } catch {}
-      }
-    };
-
-    loadConfig();
-
-    // Écouter les changements de configuration (depuis Settings)
-    const handleConfigUpdate = (e: CustomEvent) => {
-      if (e.detail?.url) {
-        setBaseUrl(e.detail.url);
-      } else {
-        loadConfig();
-      }
-    };
-
-    // Écouter les changements de localStorage (depuis d'autres onglets)
-    const handleStorageChange = (e: StorageEvent) => {
-      if (e.key === 'prometheusConfig') {
-        loadConfig();
+    const loadSources = async () => {
+      try {
+        const list = await apiGet<PrometheusDataSource[]>('/api/v1/prometheus/datasources');
+        setSources(list);
+        const preferred = list.find((s) => s.is_default) || list[0];
+        if (preferred) {
+          setDatasource(String(preferred.id));
+        }
+      } catch (e) {
+        console.error('Error loading Prometheus data sources:', e);
       }
     };
 
-    window.addEventListener('prometheusConfigUpdated', handleConfigUpdate as EventListener);
-    window.addEventListener('storage', handleStorageChange);
-    return () => {
-      window.removeEventListener('prometheusConfigUpdated', handleConfigUpdate as EventListener);
-      window.removeEventListener('storage', handleStorageChange);
-    };
+    loadSources();
+    window.addEventListener('prometheusConfigUpdated', loadSources);
+    return () => window.removeEventListener('prometheusConfigUpdated', loadSources);
   }, []);
 
   const q = useMemo(() => ({
@@ -72,53 +59,61 @@ export function Monitoring() {
     disk: '(node_filesystem_size_bytes{mountpoint="/"} - node_filesystem_free_bytes{mountpoint="/"}) / node_filesystem_size_bytes{mountpoint="/"} * 100'
   }), []);
 
-  const runQuery = async (query: string): Promise<number | null> => {
+  // Interroge le backend, qui relaie la requête à la source de données choisie
+  const fetchProm = async (endpoint: string, params: Record<string, string>): Promise<PromResult | null> => {
     try {
-      // Utiliser des URLs relatives pour compatibilité Docker (nginx proxy /api vers backend)
-      // En développement, Vite proxy aussi /api vers localhost:8080
-      const apiBaseUrl = import.meta.env.VITE_API_URL || '';
-      const url = `${apiBaseUrl}/api/v1/prometheus/query?base_url=${encodeURIComponent(baseUrl)}&query=${encodeURIComponent(query)}`;
-      
-      const resp = await fetch(url);
-      if (!resp.ok) {
-        throw new Error(`HTTP ${resp.status}`);
-      }
-
-      const data = await resp.json() as PromResult;
-      
-      // Format de réponse Prometheus: { status: "success", data: { result: [{ value: [timestamp, "value"] }] } }
-      if (data.status === "success" && data.data?.result?.[0]?.value?.[1]) {
-        const v = parseFloat(data.data.result[0].value[1]);
-        return isFinite(v) ? v : null;
-      }
-      
-      // Gérer les erreurs Prometheus
-      if (data.status === "error" && data.error) {
+      const search = new URLSearchParams({ ...params, datasource });
+      const data = await apiGet<PromResult>(`/api/v1/prometheus/${endpoint}?${search.toString()}`);
+      if (data.status === 'error' && data.error) {
         console.error('Prometheus query error:', data.error);
         return null;
       }
-      
-      return null;
+      return data;
     } catch (e) {
       console.error('Error running Prometheus query:', e);
       return null;
     }
   };
 
+  const runQuery = async (query: string): Promise<number | null> => {
+    const data = await fetchProm('query', { query });
+    const value = data?.data?.result?.[0]?.value?.[1];
+    if (value === undefined) return null;
+    const v = parseFloat(value);
+    return isFinite(v) ? v : null;
+  };
+
+  const runRange = async (query: string): Promise<number[]> => {
+    const end = Math.floor(Date.now() / 1000);
+    const data = await fetchProm('query_range', {
+      query,
+      start: String(end - RANGE_SECONDS),
+      end: String(end),
+      step: String(STEP_SECONDS),
+    });
+    return (data?.data?.result?.[0]?.values || [])
+      .map(([, v]) => parseFloat(v))
+      .filter((v) => isFinite(v));
+  };
+
   const refresh = async () => {
-    if (!baseUrl) {
+    if (!datasource) {
       warning('Information', 'Configurez Prometheus dans Paramètres > Configuration Prometheus');
       return;
     }
-    
+
     setLoading(true);
     try {
-      const [c, m, d] = await Promise.all([runQuery(q.cpu), runQuery(q.mem), runQuery(q.disk)]);
+      const [c, m, d, hc, hm, hd] = await Promise.all([
+        runQuery(q.cpu), runQuery(q.mem), runQuery(q.disk),
+        runRange(q.cpu), runRange(q.mem), runRange(q.disk),
+      ]);
       setCpu(c);
       setMem(m);
       setDisk(d);
+      setHistory({ cpu: hc, mem: hm, disk: hd });
       setLastUpdate(new Date());
-      
+
       if (c === null && m === null && d === null) {
         warning('Information', 'Aucune métrique reçue. Vérifiez votre Prometheus et les exporters (node_exporter).');
       } else {
@@ -131,23 +126,27 @@ export function Monitoring() {
     }
   };
 
-  const handleSaveUrl = () => {
-    if (!baseUrl.trim()) {
-      warning('Information', 'Veuillez saisir une URL Prometheus');
-      return;
-    }
-    localStorage.setItem('prometheusConfig', JSON.stringify({ url: baseUrl.trim() }));
-    success('Succès', 'URL Prometheus sauvegardée');
-    refresh();
-  };
-
   useEffect(() => {
-    // Auto-refresh initial
-    refresh();
+    if (datasource) {
+      refresh();
+    }
   // eslint-disable-next-line react-hooks/exhaustive-deps
-  }, [baseUrl]);
+  }, [datasource]);
+
+  // Courbe de la dernière heure (0-100 %)
+  const Sparkline = ({ points }: { points: number[] }) => {
+    if (points.length < 2) return null;
+    const path = points
+      .map((v, i) => `${(i / (points.length - 1)) * 100},${30 - (Math.min(Math.max(v, 0), 100) / 100) * 30}`)
+      .join(' ');
+    return (
+      <svg viewBox="0 0 100 30" preserveAspectRatio="none" className="mt-3 h-12 w-full text-primary-500">
+        <polyline points={path} fill="none" stroke="currentColor" strokeWidth="1.5" vectorEffect="non-scaling-stroke" />
+      </svg>
+    );
+  };
 
-  const Stat = ({ title, value, icon: Icon }: { title: string; value: number | null; icon: any }) => (
+  const Stat = ({ title, value, points, icon: Icon }: { title: string; value: number | null; points?: number[]; icon: any }) => (
     <Card>
       <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
         <CardTitle className="text-sm font-medium">{title}</CardTitle>
@@ -165,6 +164,7 @@ export function Monitoring() {
             style={{ width: `${Math.min(value ?? 0, 100)}%` }}
           />
         </div>
+        {points && <Sparkline points={points} />}
       </CardContent>
     </Card>
   );
@@ -186,16 +186,15 @@ export function Monitoring() {
           )}
         </div>
         <div className="flex items-center gap-2 flex-wrap">
-          <Input 
-            value={baseUrl} 
-            onChange={(e) => setBaseUrl(e.target.value)} 
-            placeholder="http://prometheus.local:9090"
-            className="min-w-[200px]"
-          />
-          <Button onClick={handleSaveUrl} variant="outline" disabled={loading}>
-            {t('monitoring.save') || 'Sauvegarder'}
-          </Button>
-          <Button onClick={refresh} disabled={loading || !baseUrl}>
+          {sources.length > 1 && (
+            <Select
+              value={datasource}
+              onChange={(e) => setDatasource(e.target.value)}
+              options={sources.map((s) => ({ value: String(s.id), label: s.name }))}
+              className="min-w-[200px]"
+            />
+          )}
+          <Button onClick={refresh} disabled={loading || !datasource}>
             {loading ? (
               <Loader size="sm" variant="spinner" />
             ) : (
@@ -206,7 +205,7 @@ export function Monitoring() {
         </div>
       </div>
 
-      {!baseUrl ? (
+      {!datasource ? (
         <Card>
           <CardContent className="text-center py-12">
             <Activity className="h-12 w-12 text-slate-400 mx-auto mb-4" />
@@ -214,15 +213,15 @@ export function Monitoring() {
               {t('monitoring.no_config') || 'Configuration Prometheus requise'}
             </h3>
             <p className="text-slate-600 dark:text-slate-400 mb-4">
-              {t('monitoring.no_config_message') || 'Veuillez configurer l\'URL Prometheus ci-dessus ou dans les Paramètres.'}
+              {t('monitoring.no_config_message') || 'Veuillez ajouter une source de données dans Paramètres > Configuration Prometheus.'}
             </p>
           </CardContent>
         </Card>
       ) : (
         <div className="grid grid-cols-1 md:grid-cols-3 gap-6">
-          <Stat title={t('monitoring.cpu') || 'CPU cluster'} value={cpu} icon={Cpu} />
-          <Stat title={t('monitoring.memory') || 'Mémoire cluster'} value={mem} icon={MemoryStick} />
-          <Stat title={t('monitoring.disk') || 'Disque (root)'} value={disk} icon={HardDrive} />
+          <Stat title={t('monitoring.cpu') || 'CPU cluster'} value={cpu} points={history.cpu} icon={Cpu} />
+          <Stat title={t('monitoring.memory') || 'Mémoire cluster'} value={mem} points={history.mem} icon={MemoryStick} />
+          <Stat title={t('monitoring.disk') || 'Disque (root)'} value={disk} points={history.disk} icon={HardDrive} />
         </div>
       )}
 
@@ -235,9 +234,9 @@ export function Monitoring() {
         </CardHeader>
         <CardContent>
           <ul className="list-disc pl-5 text-sm text-slate-600 dark:text-slate-400 space-y-2">
-            <li>{t('monitoring.note1') || 'Renseignez l\'URL Prometheus ci-dessus ou dans Paramètres > Configuration Prometheus.'}</li>
+            <li>{t('monitoring.note1') || 'Les sources de données Prometheus se configurent dans Paramètres > Configuration Prometheus.'}</li>
             <li>{t('monitoring.note2') || 'Les métriques nécessitent node_exporter installé et configuré sur vos nœuds.'}</li>
-            <li>{t('monitoring.note3') || 'Les requêtes utilisent l\'endpoint /api/v1/query de Prometheus via le backend.'}</li>
+            <li>{t('monitoring.note3') || 'Les requêtes (/api/v1/query et /api/v1/query_range) sont relayées par le backend ; les graphiques couvrent la dernière heure.'}</li>
             <li>{t('monitoring.note4') || 'Les métriques sont mises à jour manuellement via le bouton "Actualiser".'}</li>
           </ul>
         </CardContent>
@@ -79,7 +79,7 @@ export function NetworkPage() {
     try {
       setLoading(true);
       
-      const config = storage.getProxmoxConfig();
+      const config = storage.getProxmoxConnection();
       if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         setInterfaces([]);
@@ -93,9 +93,7 @@ export function NetworkPage() {
         message?: string;
         networks?: any[];
       }>('/api/v1/proxmox/fetch-networks', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret
+        connection_id: config.connection_id
       });
 
       if (data.success && data.networks && data.networks.length > 0) {
@@ -227,52 +227,10 @@ export function Nodes() {
   };
 
   // Fonction pour redémarrer/éteindre un nœud
+  // L'extinction passera par le backend : le token Proxmox ne quitte pas le serveur
   const handleNodePower = (node: Node) => {
     if (node.status === 'online') {
-      setConfirmModal({
-        isOpen: true,
-        title: 'Confirmer l\'arrêt',
-        message: t('confirmations.shutdown_node', { name: node.name }) || `Êtes-vous sûr de vouloir éteindre le nœud ${node.name} ?\n\nCette action est irréversible et peut affecter toutes les VMs et LXC hébergées.`,
-        variant: 'danger',
-        onConfirm: async () => {
-          try {
-            const savedConfig = localStorage.getItem('proxmoxConfig');
-            if (!savedConfig) {
-              // Ne pas afficher d'erreur - l'utilisateur peut ne pas avoir encore configuré Proxmox
-              // Un message informatif est déjà affiché dans la page si pas de configuration
-              console.log('ℹ️ Configuration Proxmox requise pour éteindre un nœud');
-              return;
-            }
-
-            const config = JSON.parse(savedConfig);
-            
-            // Appel API Proxmox pour éteindre le nœud
-            const response = await fetch(`${config.url}/api2/json/nodes/${node.name}/status`, {
-              method: 'POST',
-              headers: {
-                'Authorization': `PVEAPIToken=${config.username}=${config.secret}`,
-                'Content-Type': 'application/json',
-              },
-              body: JSON.stringify({ command: 'shutdown' })
-            });
-
-            if (response.ok) {
-              success(t('common.success'), t('nodes.shutdown_success', { name: node.name }) || `Nœud ${node.name} en cours d'extinction`);
-              // Mettre à jour le statut localement
-              setNodes(prevNodes =>
-                prevNodes.map(n =>
-                  n.id === node.id ? { ...n, status: 'offline' as const } : n
-                )
-              );
-            } else {
-              throw new Error(`HTTP ${response.status}`);
-            }
-          } catch (err) {
-            console.error('Erreur extinction nœud:', err);
-            error(t('common.error'), t('nodes.shutdown_error', { name: node.name }) || `Impossible d'éteindre le nœud ${node.name}. Vérifiez vos permissions Proxmox.`);
-          }
-        }
-      });
+      warning('Information', `L'extinction du nœud ${node.name} sera disponible dans une prochaine version`);
     } else {
       warning(t('common.warning'), t('nodes.cannot_start') || `Le démarrage à distance d'un nœud n'est pas possible via l'API Proxmox. Utilisez Wake-on-LAN ou démarrez-le physiquement.`);
     }
@@ -285,8 +243,8 @@ export function Nodes() {
       console.log('🔄 Rafraîchissement des données Proxmox...');
 
       // Récupérer la configuration Proxmox depuis localStorage
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         setRefreshing(false);
         // Ne pas afficher d'erreur au rafraîchissement si pas de config - c'est normal si pas encore configuré
@@ -294,7 +252,6 @@ export function Nodes() {
         return;
       }
 
-      const config = JSON.parse(savedConfig);
       console.log('📊 Configuration Proxmox:', config);
 
       // Appeler l'API backend pour récupérer les données Proxmox
@@ -307,9 +264,7 @@ export function Nodes() {
         lxc?: any[];
         storages?: any[];
       }>('/api/v1/proxmox/fetch-data', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret,
+        connection_id: config.connection_id,
         node: config.node
       });
       console.log('📊 Données Proxmox récupérées:', data);
@@ -462,7 +417,7 @@ export function Nodes() {
       </div>
 
       {/* Message informatif si pas de configuration Proxmox */}
-      {!localStorage.getItem('proxmoxConfig') && nodes.length === 0 && (
+      {!storage.getProxmoxConnection() && nodes.length === 0 && (
         <Card className="bg-blue-50 dark:bg-blue-900/20 border-blue-200 dark:border-blue-800">
           <CardContent className="p-4">
             <div className="flex items-start space-x-3">
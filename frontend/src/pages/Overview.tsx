@@ -33,17 +33,16 @@ export function Overview() {
   const loadRecentEvents = async () => {
     try {
       // Charger les tâches Proxmox pour créer des événements réels
-      const config = localStorage.getItem('proxmoxConfig');
+      const proxmoxConnection = storage.getProxmoxConnection();
       let proxmoxEvents: any[] = [];
       
-      if (config) {
+      if (proxmoxConnection) {
         try {
-          const proxmoxConfig = JSON.parse(config);
           const tasksData = await apiPost<{
             success: boolean;
             message?: string;
             tasks?: any[];
-          }>('/api/v1/proxmox/fetch-tasks', proxmoxConfig);
+          }>('/api/v1/proxmox/fetch-tasks', proxmoxConnection);
           
           if (tasksData.success && tasksData.tasks) {
             // Convertir les tâches Proxmox en événements
@@ -513,13 +512,12 @@ export function Overview() {
       setRefreshing(true);
 
       // Récupérer la configuration Proxmox
-      const config = localStorage.getItem('proxmoxConfig');
-      if (!config) {
+      const proxmoxConnection = storage.getProxmoxConnection();
+      if (!proxmoxConnection) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         return;
       }
 
-      const proxmoxConfig = JSON.parse(config);
 
       // Appeler l'API backend pour récupérer les données
       // Utiliser apiPost pour utiliser la bonne URL de l'API (API_BASE_URL)
@@ -531,7 +529,7 @@ export function Overview() {
         lxc?: any[];
         storages?: any[];
         networks?: any[];
-      }>('/api/v1/proxmox/fetch-data', proxmoxConfig);
+      }>('/api/v1/proxmox/fetch-data', proxmoxConnection);
 
       console.log('📥 Réponse complète du backend:', {
         success: data.success,
@@ -599,7 +597,7 @@ export function Overview() {
             success: boolean;
             message?: string;
             containers?: any[];
-          }>('/api/v1/proxmox/fetch-docker', proxmoxConfig);
+          }>('/api/v1/proxmox/fetch-docker', proxmoxConnection);
           
           if (dockerData.success && dockerData.containers) {
             localStorage.setItem('proxmoxDocker', JSON.stringify(dockerData.containers));
@@ -633,14 +631,13 @@ export function Overview() {
       const savedDocker = localStorage.getItem('proxmoxDocker');
       if (!savedDocker) {
         try {
-          const config = localStorage.getItem('proxmoxConfig');
-          if (config) {
-            const proxmoxConfig = JSON.parse(config);
+          const proxmoxConnection = storage.getProxmoxConnection();
+          if (proxmoxConnection) {
             const dockerData = await apiPost<{
               success: boolean;
               message?: string;
               containers?: any[];
-            }>('/api/v1/proxmox/fetch-docker', proxmoxConfig);
+            }>('/api/v1/proxmox/fetch-docker', proxmoxConnection);
             
             if (dockerData.success && dockerData.containers) {
               localStorage.setItem('proxmoxDocker', JSON.stringify(dockerData.containers));
@@ -770,7 +767,7 @@ export function Overview() {
                         lxc: localStorage.getItem('proxmoxLXC') ? JSON.parse(localStorage.getItem('proxmoxLXC')!).length : 0,
                         storages: localStorage.getItem('proxmoxStorages') ? JSON.parse(localStorage.getItem('proxmoxStorages')!).length : 0,
                         networks: localStorage.getItem('proxmoxNetworks') ? JSON.parse(localStorage.getItem('proxmoxNetworks')!).length : 0,
-                        config: localStorage.getItem('proxmoxConfig') ? 'présente' : 'manquante'
+                        connection: storage.getProxmoxConnection() ? 'présente' : 'manquante'
                       });
                       loadStats();
                     }}
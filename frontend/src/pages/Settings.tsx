@@ -6,29 +6,34 @@ import { Input } from '@/components/ui/Input';
 import { Select } from '@/components/ui/Select';
 import { Badge } from '@/components/ui/Badge';
 import { Modal } from '@/components/ui/Modal';
-import { apiPost } from '@/utils/api';
+import { apiGet, apiPost, apiPut, PrometheusAuthType, PrometheusDataSource } from '@/utils/api';
+import { authManager } from '@/utils/auth';
 import { useToast } from '@/components/ui/Toast';
-import { proxmoxConfigManager } from '@/utils/proxmox';
+import { proxmoxConnections, ProxmoxConnection } from '@/utils/proxmox';
 import { storage } from '@/utils/storage';
 import { ConfirmModal } from '@/components/ui/ConfirmModal';
 import { Loader } from '@/components/ui/Loader';
 
 export function Settings() {
-  const { success, error, warning } = useToast();
+  const { success, error, warning, info } = useToast();
 
   // Configuration API
   const [apiConfig, setApiConfig] = useState({
     apiUrl: import.meta.env.VITE_API_URL || 'http://localhost:8080',
   });
 
-  // Configuration Proxmox
-  const [proxmoxConfig, setProxmoxConfig] = useState({
+  // Connexion Proxmox : enregistrée côté serveur, les secrets ne sont jamais relus par le frontend
+  const emptyProxmoxForm = {
+    name: 'Proxmox',
     url: 'https://pve.example.com:8006',
     username: '',
-    secret: '',
+    secret: '', // vide : conserve le secret enregistré
     password: '', // Mot de passe optionnel pour la console VNC (si différent du secret du token)
     node: 'pve',
-  });
+  };
+  const [proxmoxConnectionList, setProxmoxConnectionList] = useState<ProxmoxConnection[]>([]);
+  const [proxmoxConnection, setProxmoxConnection] = useState<ProxmoxConnection | null>(null);
+  const [proxmoxConfig, setProxmoxConfig] = useState(emptyProxmoxForm);
 
   // État de test Proxmox
   const [proxmoxTestStatus, setProxmoxTestStatus] = useState<'idle' | 'testing' | 'success' | 'error'>('idle');
@@ -75,13 +80,38 @@ export function Settings() {
   const [newSubscription, setNewSubscription] = useState({
     channel: 'email',
     endpoint: '',
+    secret: '',
   });
 
   const channelOptions = [
     { value: 'email', label: 'Email' },
     { value: 'webhook', label: 'Webhook' },
+    { value: 'slack', label: 'Slack' },
+    { value: 'mattermost', label: 'Mattermost' },
+    { value: 'discord', label: 'Discord' },
+    { value: 'teams', label: 'Microsoft Teams' },
+    { value: 'gotify', label: 'Gotify' },
+    { value: 'ntfy', label: 'ntfy' },
   ];
 
+  const endpointPlaceholders: Record<string, string> = {
+    email: 'admin@example.com',
+    webhook: 'https://example.com/hooks/proxmox',
+    slack: 'https://hooks.slack.com/services/...',
+    mattermost: 'https://mattermost.example.com/hooks/...',
+    discord: 'https://discord.com/api/webhooks/...',
+    teams: 'https://...webhook.office.com/...',
+    gotify: 'https://gotify.example.com',
+    ntfy: 'https://ntfy.sh/mon-topic',
+  };
+
+  // Canaux utilisant un secret : clé HMAC (webhook, générée si vide), token d'application (gotify), token d'accès (ntfy)
+  const secretLabels: Record<string, string> = {
+    webhook: 'Secret de signature (optionnel)',
+    gotify: "Token d'application",
+    ntfy: "Token d'accès (optionnel)",
+  };
+
   // Charger les configurations sauvegardées au montage du composant
   useEffect(() => {
     // Charger la configuration API
@@ -90,40 +120,8 @@ export function Settings() {
       setApiConfig(JSON.parse(savedApiConfig));
     }
 
-    // Charger la configuration Proxmox depuis le manager
-    const savedProxmoxConfig = proxmoxConfigManager.getConfig();
-    if (savedProxmoxConfig) {
-      // Convertir l'ancien format (avec token) vers le nouveau format (username/secret)
-      if (savedProxmoxConfig.token && !savedProxmoxConfig.username) {
-        // Extraire username et secret du token formaté
-        const tokenMatch = savedProxmoxConfig.token.match(/PVEAPIToken=([^!]+)!(.+)/);
-        if (tokenMatch) {
-          setProxmoxConfig({
-            url: savedProxmoxConfig.url,
-            username: tokenMatch[1],
-            secret: tokenMatch[2],
-            password: (savedProxmoxConfig as any).password || '',
-            node: savedProxmoxConfig.node,
-          });
-        } else {
-          setProxmoxConfig({
-            url: savedProxmoxConfig.url,
-            username: '',
-            secret: '',
-            password: (savedProxmoxConfig as any).password || '',
-            node: savedProxmoxConfig.node,
-          });
-        }
-      } else {
-        setProxmoxConfig({
-          url: savedProxmoxConfig.url,
-          username: savedProxmoxConfig.username || '',
-          secret: savedProxmoxConfig.secret || '',
-          password: (savedProxmoxConfig as any).password || '',
-          node: savedProxmoxConfig.node,
-        });
-      }
-    }
+    // Charger les connexions Proxmox enregistrées et sélectionner la connexion active
+    loadProxmoxConnections();
 
     // Charger la configuration SMTP
     const savedSmtpConfig = localStorage.getItem('smtpConfig');
@@ -148,7 +146,7 @@ export function Settings() {
     setConfirmModal({
       isOpen: true,
       title: 'ATTENTION',
-      message: 'Cette action va supprimer TOUTES les données de la base de données.\n\nAucune donnée ne sera recréée automatiquement.\n\nÊtes-vous sûr de vouloir continuer ?',
+      message: 'Cette action va supprimer TOUTES les données de la base de données (les utilisateurs, leurs sessions et les rôles sont conservés).\n\nAucune donnée ne sera recréée automatiquement.\n\nÊtes-vous sûr de vouloir continuer ?',
       variant: 'danger',
       onConfirm: () => {
         // Deuxième confirmation
@@ -163,6 +161,7 @@ export function Settings() {
                 method: 'POST',
                 headers: {
                   'Content-Type': 'application/json',
+                  ...authManager.getAuthHeaders(),
                 },
               });
 
@@ -183,46 +182,70 @@ export function Settings() {
     });
   };
 
+  // Remplit le formulaire avec une connexion enregistrée (les secrets restent côté serveur)
+  const selectProxmoxConnection = (connection: ProxmoxConnection | null, node?: string) => {
+    setProxmoxConnection(connection);
+    setProxmoxConfig(connection ? {
+      name: connection.name,
+      url: connection.url,
+      username: connection.username,
+      secret: '',
+      password: '',
+      node: node || storage.getProxmoxConnection()?.node || 'pve',
+    } : emptyProxmoxForm);
+  };
+
+  const loadProxmoxConnections = async () => {
+    try {
+      const list = await proxmoxConnections.list();
+      setProxmoxConnectionList(list);
+      const active = storage.getProxmoxConnection();
+      selectProxmoxConnection(list.find(c => c.id === active?.connection_id) || list[0] || null, active?.node);
+    } catch (err) {
+      console.error('Error loading Proxmox connections:', err);
+    }
+  };
+
   const handleSaveProxmoxConfig = async () => {
-    // Vérifier que les champs sont remplis
-    if (!proxmoxConfig.username || !proxmoxConfig.secret) {
-      error('Erreur', 'Veuillez remplir les champs utilisateur et secret');
+    // Le secret n'est requis qu'à la création : vide, il conserve le secret enregistré
+    if (!proxmoxConfig.url || !proxmoxConfig.username || (!proxmoxConnection && !proxmoxConfig.secret)) {
+      error('Erreur', 'Veuillez remplir les champs URL, utilisateur et secret');
       return;
     }
-
-    // Formater le token pour l'envoi au backend
-    // Format: PVEAPIToken=username!tokenname=secret ou PVEAPIToken=username=secret
-    // Le backend gère les deux formats, donc on construit simplement le token
-    const formattedConfig = {
-      ...proxmoxConfig,
-      token: `PVEAPIToken=${proxmoxConfig.username}=${proxmoxConfig.secret}`
-    };
-
-    // Utiliser le manager pour valider et sauvegarder
-    const validation = proxmoxConfigManager.validateConfig(formattedConfig);
-
-    if (!validation.isValid) {
-      error('Erreur', validation.errors.join(', '));
+    if (!proxmoxConfig.node) {
+      error('Erreur', 'Le nœud par défaut est requis');
       return;
     }
 
     try {
-      // Sauvegarder la configuration d'abord
-      proxmoxConfigManager.saveConfig(formattedConfig);
-
-      // Tester la connexion
+      const input: Record<string, unknown> = {
+        name: proxmoxConfig.name || proxmoxConfig.url,
+        url: proxmoxConfig.url,
+        username: proxmoxConfig.username,
+      };
+      if (proxmoxConfig.secret) input.secret = proxmoxConfig.secret;
+      if (proxmoxConfig.password) input.password = proxmoxConfig.password;
+
+      const saved = proxmoxConnection
+        ? await proxmoxConnections.update(proxmoxConnection.id, input)
+        : await proxmoxConnections.create(input);
+      storage.setProxmoxConnection({ connection_id: saved.id, node: proxmoxConfig.node });
+      setProxmoxConnectionList(list => [...list.filter(c => c.id !== saved.id), saved]);
+      selectProxmoxConnection(saved, proxmoxConfig.node);
+
+      // Tester la connexion enregistrée
       setProxmoxTestStatus('testing');
       setProxmoxTestMessage('Test de connexion en cours...');
-      
-      const result = await proxmoxConfigManager.testConnection(formattedConfig);
+
+      const result = await proxmoxConnections.test(saved.id);
       setProxmoxTestStatus(result.status);
       setProxmoxTestMessage(result.message);
 
       if (result.status === 'success') {
         // Si la connexion réussit, essayer de récupérer les données Proxmox
         try {
-          await fetchProxmoxData();
-          success('Succès', 'Configuration Proxmox sauvegardée et connectée !\n\nLes données ont été récupérées.');
+          await fetchProxmoxData(saved.id);
+          success('Succès', 'Connexion Proxmox sauvegardée et connectée !\n\nLes données ont été récupérées.');
         } catch (fetchErr) {
           // Si la récupération des données échoue, ce n'est pas critique
           // La connexion fonctionne, mais on n'a pas pu récupérer les données
@@ -236,18 +259,17 @@ export function Settings() {
     } catch (err) {
       setProxmoxTestStatus('error');
       setProxmoxTestMessage('Erreur lors de la sauvegarde');
-      error('Erreur', 'Impossible de sauvegarder la configuration Proxmox');
-      console.error('Save Proxmox config error:', err);
+      error('Erreur', err instanceof Error ? err.message : 'Impossible de sauvegarder la connexion Proxmox');
+      console.error('Save Proxmox connection error:', err);
     }
   };
 
   // Fonction pour récupérer les données Proxmox via le backend
-  const fetchProxmoxData = async () => {
+  const fetchProxmoxData = async (connectionId: number) => {
     try {
       console.log('🔄 Récupération des données Proxmox via le backend...');
 
-      // Envoyer la configuration Proxmox au backend pour qu'il fasse les appels
-      // Utiliser apiPost pour utiliser la bonne URL de l'API (API_BASE_URL)
+      // Le backend résout l'URL et le token de la connexion enregistrée
       const data = await apiPost<{
         success: boolean;
         message?: string;
@@ -256,17 +278,12 @@ export function Settings() {
         lxc?: any[];
         storages?: any[];
       }>('/api/v1/proxmox/fetch-data', {
-        url: proxmoxConfig.url,
-        username: proxmoxConfig.username,
-        secret: proxmoxConfig.secret,
+        connection_id: connectionId,
         node: proxmoxConfig.node
       });
       console.log('📊 Données Proxmox récupérées via backend:', data);
 
       if (data.success) {
-        // Sauvegarder la configuration Proxmox (essentielle)
-        storage.setProxmoxConfig(proxmoxConfig);
-
         // Sauvegarder les données Proxmox avec cache intelligent
         storage.setProxmoxData(data);
 
@@ -298,24 +315,8 @@ export function Settings() {
   };
 
   const handleTestProxmoxConnection = async () => {
-    // Vérifier que les champs sont remplis
-    if (!proxmoxConfig.username || !proxmoxConfig.secret) {
-      error('Erreur', 'Veuillez remplir les champs utilisateur et secret');
-      return;
-    }
-
-    // Formater le token pour l'envoi au backend
-    // Format: PVEAPIToken=username!tokenname=secret ou PVEAPIToken=username=secret
-    // Le backend gère les deux formats, donc on construit simplement le token
-    const formattedConfig = {
-      ...proxmoxConfig,
-      token: `PVEAPIToken=${proxmoxConfig.username}=${proxmoxConfig.secret}`
-    };
-
-    const validation = proxmoxConfigManager.validateConfig(formattedConfig);
-
-    if (!validation.isValid) {
-      error('Erreur', validation.errors.join(', '));
+    if (!proxmoxConnection) {
+      info('Information', 'Sauvegardez la connexion avant de la tester');
       return;
     }
 
@@ -323,24 +324,22 @@ export function Settings() {
     setProxmoxTestMessage('Test de connexion en cours...');
 
     try {
-      // Tester d'abord la connexion avec le token API
-      const result = await proxmoxConfigManager.testConnection(formattedConfig);
+      // Tester d'abord la connexion avec le token API enregistré
+      const result = await proxmoxConnections.test(proxmoxConnection.id);
       setProxmoxTestStatus(result.status);
       setProxmoxTestMessage(result.message);
 
       if (result.status === 'success') {
-        // Si le test du token API réussit, tester aussi le mot de passe si fourni
-        if (proxmoxConfig.password && proxmoxConfig.password.trim() !== '') {
+        // Tester aussi le mot de passe saisi, à défaut celui enregistré avec la connexion
+        if (proxmoxConfig.password.trim() !== '' || proxmoxConnection.has_password) {
           setProxmoxTestMessage('Test du token API réussi. Vérification du mot de passe...');
-          
+
           try {
             const passwordTest = await apiPost<{ success: boolean; message?: string; error?: string; details?: string }>(
               '/api/v1/proxmox/test-password',
               {
-                url: proxmoxConfig.url,
-                username: proxmoxConfig.username,
-                secret: proxmoxConfig.secret,
-                password: proxmoxConfig.password
+                connection_id: proxmoxConnection.id,
+                password: proxmoxConfig.password || undefined
               }
             );
 
@@ -358,7 +357,7 @@ export function Settings() {
             warning('Attention', 'Le token API fonctionne, mais impossible de tester le mot de passe. Vérifiez votre configuration.');
           }
         } else {
-          // Pas de mot de passe fourni, juste confirmer que le token API fonctionne
+          // Pas de mot de passe, juste confirmer que le token API fonctionne
           success('Succès', 'Connexion à Proxmox établie avec succès (token API valide). Note: Pour utiliser la console VNC, ajoutez le mot de passe de l\'utilisateur.');
         }
       } else {
@@ -371,18 +370,38 @@ export function Settings() {
     }
   };
 
+  // Nouvelle connexion : le formulaire est vidé, la connexion active reste enregistrée
   const handleResetProxmoxConfig = () => {
-    proxmoxConfigManager.resetConfig();
-    setProxmoxConfig({
-      url: 'https://pve.example.com:8006',
-      username: '',
-      secret: '',
-      password: '',
-      node: 'pve',
-    });
+    selectProxmoxConnection(null);
     setProxmoxTestStatus('idle');
     setProxmoxTestMessage('');
-    warning('Information', 'Configuration Proxmox réinitialisée');
+  };
+
+  const handleDeleteProxmoxConnection = () => {
+    if (!proxmoxConnection) return;
+    setConfirmModal({
+      isOpen: true,
+      title: 'Supprimer la connexion',
+      message: `La connexion « ${proxmoxConnection.name} » et ses secrets seront supprimés du serveur.`,
+      variant: 'danger',
+      onConfirm: async () => {
+        try {
+          await proxmoxConnections.remove(proxmoxConnection.id);
+          if (storage.getProxmoxConnection()?.connection_id === proxmoxConnection.id) {
+            storage.clearProxmoxConnection();
+            storage.clearProxmoxData();
+          }
+          const list = proxmoxConnectionList.filter(c => c.id !== proxmoxConnection.id);
+          setProxmoxConnectionList(list);
+          selectProxmoxConnection(list[0] || null);
+          setProxmoxTestStatus('idle');
+          setProxmoxTestMessage('');
+          warning('Information', 'Connexion Proxmox supprimée');
+        } catch (err) {
+          error('Erreur', 'Impossible de supprimer la connexion Proxmox');
+        }
+      }
+    });
   };
 
   const handleTestEmail = async () => {
@@ -410,9 +429,10 @@ export function Settings() {
     }
 
     try {
-      await apiPost('/api/notify/subscribe', newSubscription);
+      const { secret, ...subscription } = newSubscription;
+      await apiPost('/api/notify/subscribe', secret ? { ...subscription, secret } : subscription);
       success('Succès', 'Abonnement ajouté');
-      setNewSubscription({ channel: 'email', endpoint: '' });
+      setNewSubscription({ channel: 'email', endpoint: '', secret: '' });
     } catch (err) {
       error('Erreur', 'Impossible d\'ajouter l\'abonnement');
     }
@@ -469,6 +489,32 @@ export function Settings() {
             </CardTitle>
           </CardHeader>
           <CardContent className="space-y-4">
+            {proxmoxConnectionList.length > 0 && (
+              <Select
+                label="Connexion"
+                value={proxmoxConnection ? String(proxmoxConnection.id) : ''}
+                onChange={(e) => {
+                  const selected = proxmoxConnectionList.find(c => c.id === Number(e.target.value)) || null;
+                  selectProxmoxConnection(selected);
+                  if (selected) {
+                    storage.setProxmoxConnection({ connection_id: selected.id, node: proxmoxConfig.node });
+                    storage.clearProxmoxData();
+                  }
+                  setProxmoxTestStatus('idle');
+                  setProxmoxTestMessage('');
+                }}
+                options={[
+                  ...proxmoxConnectionList.map(c => ({ value: String(c.id), label: c.enabled ? c.name : `${c.name} (désactivée)` })),
+                  { value: '', label: 'Nouvelle connexion' },
+                ]}
+              />
+            )}
+            <Input
+              label="Nom"
+              value={proxmoxConfig.name}
+              onChange={(e) => setProxmoxConfig({ ...proxmoxConfig, name: e.target.value })}
+              placeholder="Proxmox"
+            />
             <div className="relative">
               <Input
                 label="URL Proxmox"
@@ -503,8 +549,8 @@ export function Settings() {
                   type="password"
                   value={proxmoxConfig.secret}
                   onChange={(e) => setProxmoxConfig({ ...proxmoxConfig, secret: e.target.value })}
-                  placeholder="token-secret"
-                  required
+                  placeholder={proxmoxConnection ? '•••••••• (inchangé)' : 'token-secret'}
+                  required={!proxmoxConnection}
                 />
                 <button
                   type="button"
@@ -523,7 +569,7 @@ export function Settings() {
                 type="password"
                 value={proxmoxConfig.password}
                 onChange={(e) => setProxmoxConfig({ ...proxmoxConfig, password: e.target.value })}
-                placeholder="Mot de passe de l'utilisateur (si différent du secret du token)"
+                placeholder={proxmoxConnection?.has_password ? '•••••••• (inchangé)' : "Mot de passe de l'utilisateur (si différent du secret du token)"}
               />
               <p className="text-xs text-slate-500 dark:text-slate-400">
                 Si vous utilisez un token API, entrez ici le mot de passe de l'utilisateur pour accéder à la console VNC
@@ -573,16 +619,28 @@ export function Settings() {
             <div className="flex justify-between items-center pt-2 border-t border-slate-200 dark:border-slate-700">
               <div className="text-sm text-slate-600 dark:text-slate-400">
                 <p>💡 <strong>Conseil :</strong> Utilisez un token API Proxmox pour une authentification sécurisée.</p>
-                <p className="mt-1">Format du token : <code className="bg-slate-100 dark:bg-slate-800 px-1 rounded">PVEAPIToken=user@pam!token=...</code></p>
+                <p className="mt-1">Le token et le mot de passe sont chiffrés par le serveur et ne sont jamais renvoyés au navigateur.</p>
+              </div>
+              <div className="flex gap-1">
+                <Button
+                  onClick={handleResetProxmoxConfig}
+                  variant="ghost"
+                  size="sm"
+                  className="text-slate-500 hover:text-slate-700 dark:text-slate-400 dark:hover:text-slate-200"
+                >
+                  Nouvelle connexion
+                </Button>
+                {proxmoxConnection && (
+                  <Button
+                    onClick={handleDeleteProxmoxConnection}
+                    variant="ghost"
+                    size="sm"
+                    className="text-red-500 hover:text-red-700"
+                  >
+                    Supprimer
+                  </Button>
+                )}
               </div>
-              <Button
-                onClick={handleResetProxmoxConfig}
-                variant="ghost"
-                size="sm"
-                className="text-slate-500 hover:text-slate-700 dark:text-slate-400 dark:hover:text-slate-200"
-              >
-                Réinitialiser
-              </Button>
             </div>
           </CardContent>
         </Card>
@@ -778,7 +836,8 @@ export function Settings() {
                 value={newSubscription.channel}
                 onChange={(e) => setNewSubscription({
                   ...newSubscription,
-                  channel: e.target.value
+                  channel: e.target.value,
+                  secret: ''
                 })}
                 options={channelOptions}
               />
@@ -789,12 +848,19 @@ export function Settings() {
                   ...newSubscription,
                   endpoint: e.target.value
                 })}
-                placeholder={
-                  newSubscription.channel === 'email'
-                    ? 'admin@example.com'
-                    : ''
-                }
+                placeholder={endpointPlaceholders[newSubscription.channel] || ''}
               />
+              {secretLabels[newSubscription.channel] && (
+                <Input
+                  label={secretLabels[newSubscription.channel]}
+                  type="password"
+                  value={newSubscription.secret}
+                  onChange={(e) => setNewSubscription({
+                    ...newSubscription,
+                    secret: e.target.value
+                  })}
+                />
+              )}
             </div>
             <Button onClick={handleAddSubscription} className="w-full">
               <Bell className="h-4 w-4 mr-2" />
@@ -961,7 +1027,8 @@ export function Settings() {
                   🗑️ Vider la base de données
                 </h4>
                 <p className="mt-1 text-sm text-red-700 dark:text-red-300">
-                  Cette action supprimera définitivement toutes les données de la base de données SQLite.
+                  Cette action supprimera définitivement toutes les données de la base de données SQLite,
+                  à l'exception des utilisateurs, de leurs sessions, des rôles et du journal d'audit.
                   <strong> Aucune donnée ne sera recréée automatiquement</strong> - la base restera vide.
                 </p>
                 <div className="mt-3">
@@ -997,37 +1064,81 @@ export function Settings() {
 
 function PrometheusSettings() {
   const { success, error, info } = useToast();
+  // Source de données par défaut, stockée côté serveur (le secret n'est jamais renvoyé)
+  const [source, setSource] = useState<PrometheusDataSource | null>(null);
+  const [name, setName] = useState<string>('Prometheus');
   const [url, setUrl] = useState<string>('http://localhost:9090');
+  const [authType, setAuthType] = useState<PrometheusAuthType>('none');
+  const [username, setUsername] = useState<string>('');
+  const [secret, setSecret] = useState<string>('');
+  const [tlsSkipVerify, setTlsSkipVerify] = useState(false);
+  const [saving, setSaving] = useState(false);
   const [testing, setTesting] = useState(false);
 
   useEffect(() => {
-    const saved = localStorage.getItem('prometheusConfig');
-    if (saved) {
+    const load = async () => {
       try {
-        const parsed = JSON.parse(saved);
-        if (parsed?.url) setUrl(parsed.url);
-      } catch {}
-    }
+        const list = await apiGet<PrometheusDataSource[]>('/api/v1/prometheus/datasources');
+        const current = list.find((s) => s.is_default) || list[0];
+        if (current) {
+          setSource(current);
+          setName(current.name);
+          setUrl(current.url);
+          setAuthType(current.auth_type);
+          setUsername(current.username || '');
+          setTlsSkipVerify(current.tls_skip_verify);
+        }
+      } catch (e) {
+        console.error('Error loading Prometheus data sources:', e);
+      }
+    };
+    load();
   }, []);
 
-  const save = () => {
-    localStorage.setItem('prometheusConfig', JSON.stringify({ url }));
-    success('Succès', 'Configuration Prometheus sauvegardée');
-    // Déclencher un événement pour mettre à jour Monitoring
-    window.dispatchEvent(new CustomEvent('prometheusConfigUpdated', { detail: { url } }));
+  const save = async () => {
+    setSaving(true);
+    try {
+      const body: Record<string, unknown> = {
+        name,
+        url,
+        auth_type: authType,
+        username,
+        tls_skip_verify: tlsSkipVerify,
+        is_default: true,
+      };
+      // Un secret vide conserve le secret existant
+      if (secret) body.secret = secret;
+
+      const saved = source
+        ? await apiPut<PrometheusDataSource>(`/api/v1/prometheus/datasources/${source.id}`, body)
+        : await apiPost<PrometheusDataSource>('/api/v1/prometheus/datasources', body);
+      setSource(saved);
+      setSecret('');
+      success('Succès', 'Configuration Prometheus sauvegardée');
+      // Déclencher un événement pour mettre à jour Monitoring
+      window.dispatchEvent(new CustomEvent('prometheusConfigUpdated', { detail: { id: saved.id } }));
+    } catch (e) {
+      error('Erreur', e instanceof Error ? e.message : 'Impossible de sauvegarder la configuration Prometheus');
+    } finally {
+      setSaving(false);
+    }
   };
 
   const test = async () => {
+    if (!source) {
+      info('Information', 'Sauvegardez la configuration avant de la tester');
+      return;
+    }
     setTesting(true);
     try {
-      const target = `${url.replace(/\/$/, '')}/-/ready`;
-      const resp = await fetch(`/api/v1/health/http?url=${encodeURIComponent(target)}`);
-      if (!resp.ok) throw new Error(String(resp.status));
-      const data = await resp.json();
-      if (data?.status === 'online' || data?.status_code === 200) {
+      const data = await apiPost<{ success: boolean; message: string }>(
+        `/api/v1/prometheus/datasources/${source.id}/test`,
+        {}
+      );
+      if (data.success) {
         success('Succès', 'Prometheus est joignable');
       } else {
-        info('Information', 'Réponse inattendue de Prometheus');
+        error('Erreur', data.message || "Impossible d'atteindre Prometheus");
       }
     } catch (e) {
       error('Erreur', "Impossible d'atteindre Prometheus");
@@ -1038,18 +1149,58 @@ function PrometheusSettings() {
 
   return (
     <div className="space-y-4">
+      <Input
+        label="Nom"
+        value={name}
+        onChange={(e) => setName(e.target.value)}
+        placeholder="Prometheus"
+      />
       <Input
         label="URL Prometheus"
         value={url}
         onChange={(e) => setUrl(e.target.value)}
         placeholder="http://prometheus.local:9090"
       />
+      <Select
+        label="Authentification"
+        value={authType}
+        onChange={(e) => setAuthType(e.target.value as PrometheusAuthType)}
+        options={[
+          { value: 'none', label: 'Aucune' },
+          { value: 'basic', label: 'Basic (utilisateur / mot de passe)' },
+          { value: 'bearer', label: 'Bearer token' },
+        ]}
+      />
+      {authType === 'basic' && (
+        <Input
+          label="Utilisateur"
+          value={username}
+          onChange={(e) => setUsername(e.target.value)}
+        />
+      )}
+      {authType !== 'none' && (
+        <Input
+          label={authType === 'basic' ? 'Mot de passe' : 'Token'}
+          type="password"
+          value={secret}
+          onChange={(e) => setSecret(e.target.value)}
+          placeholder={source?.has_secret ? '•••••••• (inchangé)' : ''}
+        />
+      )}
+      <label className="flex items-center space-x-2 text-sm text-slate-700 dark:text-slate-300">
+        <input
+          type="checkbox"
+          checked={tlsSkipVerify}
+          onChange={(e) => setTlsSkipVerify(e.target.checked)}
+        />
+        <span>Ignorer la vérification du certificat TLS</span>
+      </label>
       <div className="flex gap-3">
-        <Button onClick={save}>
+        <Button onClick={save} disabled={saving}>
           <Save className="h-4 w-4 mr-2" />
-          Sauvegarder
+          {saving ? 'Sauvegarde...' : 'Sauvegarder'}
         </Button>
-        <Button variant="outline" onClick={test} disabled={testing}>
+        <Button variant="outline" onClick={test} disabled={testing || !source}>
           {testing ? (
             <Loader size="sm" variant="spinner" className="mr-2" />
           ) : (
@@ -24,7 +24,8 @@ import { useToast } from '@/components/ui/Toast';
 import { useTranslation } from '@/hooks/useTranslation';
 import { ConfirmModal } from '@/components/ui/ConfirmModal';
 import { Loader } from '@/components/ui/Loader';
-import { apiPost } from '@/utils/api';
+import { apiGet, apiPost } from '@/utils/api';
+import { ProxmoxConnection } from '@/utils/proxmox';
 import { storage } from '@/utils/storage';
 
 interface StoragePool {
@@ -105,7 +106,7 @@ export function Storage() {
 
       // Si pas de données Proxmox, vérifier si on est en production
       const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
-      const proxmoxConfig = storage.getProxmoxConfig();
+      const proxmoxConfig = storage.getProxmoxConnection();
       
       // En production, si Proxmox n'est pas configuré, ne pas charger de données mockées
       if (isProduction && !proxmoxConfig) {
@@ -205,14 +206,13 @@ export function Storage() {
       console.log('🔄 Rafraîchissement des données Storage Proxmox...');
 
       // Récupérer la configuration Proxmox depuis localStorage
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         error(t('common.error'), t('storage.no_proxmox_config') || 'Aucune configuration Proxmox trouvée. Veuillez d\'abord configurer votre connexion Proxmox dans les Paramètres.');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
       console.log('📊 Configuration Proxmox:', config);
 
       // Appeler l'API backend pour récupérer les données Proxmox
@@ -225,9 +225,7 @@ export function Storage() {
         lxc?: any[];
         storages?: any[];
       }>('/api/v1/proxmox/fetch-data', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret,
+        connection_id: config.connection_id,
         node: config.node
       });
       console.log('📊 Données Proxmox récupérées:', data);
@@ -341,117 +339,31 @@ export function Storage() {
   });
 
   // Actions pour le stockage
-  const handleStorageMount = async (pool: StoragePool) => {
-    try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
-        error('Erreur', 'Configuration Proxmox manquante');
-        return;
-      }
-      const config = JSON.parse(savedConfig);
-      // Activer le stockage (équivalent "monter")
-      const response = await fetch(`${config.url}/api2/json/nodes/${pool.node}/storage/${pool.id}/status`, {
-        method: 'PUT',
-        headers: {
-          'Authorization': `PVEAPIToken=${config.username}=${config.secret}`,
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify({ enabled: 1 })
-      });
-
-      if (response.ok) {
-      setPools(prevPools =>
-        prevPools.map(p =>
-          p.id === pool.id
-            ? { ...p, status: 'online' as const }
-            : p
-        )
-      );
-      success('Succès', `Stockage ${pool.name} monté avec succès`);
-        setTimeout(() => refreshStorages(), 1000);
-      } else {
-        throw new Error(`HTTP ${response.status}`);
-      }
-    } catch (err) {
-      console.error('Erreur montage stockage:', err);
-      error('Erreur', `Impossible de monter le stockage ${pool.name}`);
-    }
+  // L'activation d'un storage passera par le backend (le token Proxmox ne quitte pas le serveur)
+  const handleStorageMount = (pool: StoragePool) => {
+    warning('Information', `Le montage du stockage ${pool.name} sera disponible dans une prochaine version`);
   };
 
-  const handleStorageUnmount = async (pool: StoragePool) => {
-    try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
-        error('Erreur', 'Configuration Proxmox manquante');
-        return;
-      }
-      const config = JSON.parse(savedConfig);
-      // Désactiver le stockage (équivalent "démonter")
-      const response = await fetch(`${config.url}/api2/json/nodes/${pool.node}/storage/${pool.id}/status`, {
-        method: 'PUT',
-        headers: {
-          'Authorization': `PVEAPIToken=${config.username}=${config.secret}`,
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify({ enabled: 0 })
-      });
-
-      if (response.ok) {
-      setPools(prevPools =>
-        prevPools.map(p =>
-          p.id === pool.id
-            ? { ...p, status: 'offline' as const }
-            : p
-        )
-      );
-      success('Succès', `Stockage ${pool.name} démonté avec succès`);
-        setTimeout(() => refreshStorages(), 1000);
-      } else {
-        throw new Error(`HTTP ${response.status}`);
-      }
-    } catch (err) {
-      console.error('Erreur démontage stockage:', err);
-      error('Erreur', `Impossible de démonter le stockage ${pool.name}`);
-    }
+  const handleStorageUnmount = (pool: StoragePool) => {
+    warning('Information', `Le démontage du stockage ${pool.name} sera disponible dans une prochaine version`);
   };
 
   const handleStorageRefresh = async (pool: StoragePool) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         error('Erreur', 'Configuration Proxmox manquante');
         return;
+
       }
-      // Recharger les informations du stockage sans afficher de toast (refreshStorages affiche déjà un toast)
-      // On va juste simuler un refresh silencieux et afficher notre propre message
+      // Recharger les données globales silencieusement (refreshStorages affiche déjà un toast)
       try {
-        const config = JSON.parse(savedConfig);
-        const base = config.url.replace(/\/$/, '');
-        
-        // Vérifier si c'est une URL de développement fictive
-        const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-        
-        if (!isDevUrl) {
-          // Appel réel à l'API Proxmox pour actualiser le stockage
-          const response = await fetch(`${base}/api2/json/nodes/${pool.node}/storage/${pool.name}/status`, {
-            method: 'GET',
-            headers: {
-              'Authorization': `PVEAPIToken=${config.token_id}=${config.token_secret}`,
-            },
-          });
-          
-          if (!response.ok) {
-            throw new Error(`HTTP ${response.status}`);
-          }
-        }
-        
-        // Recharger les données globales silencieusement (sans toast)
         await refreshStorages(true);
       } catch (refreshErr) {
         // Ignorer les erreurs de refresh, on affichera quand même le message de succès
         console.log('Refresh silencieux:', refreshErr);
       }
-      
+
       // Message qui correspond au pattern du test E2E: /stockage.*actualisé|storage.*refreshed/i
       success(t('common.success'), t('storage.refresh_success') || `Stockage ${pool.name} actualisé avec succès`);
     } catch (err) {
@@ -482,28 +394,18 @@ export function Storage() {
     });
   };
 
-  const handleStorageConfig = (pool: StoragePool) => {
+  const handleStorageConfig = async (pool: StoragePool) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la configuration');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      const base = config.url.replace(/\/$/, '');
-      
-      // Vérifier si c'est une URL de développement fictive
-      const isDevUrl = base.includes('proxmox-dev.local') || base.includes('localhost') || base.includes('127.0.0.1');
-      
-      if (isDevUrl) {
-        warning(
-          'Configuration de développement',
-          'La configuration Proxmox ne peut pas être ouverte avec une configuration fictive. Veuillez configurer une connexion Proxmox réelle dans les Paramètres pour utiliser cette fonctionnalité.'
-        );
-        return;
-      }
-      
+      // La configuration s'ouvre sur l'interface Proxmox de la connexion active (l'URL n'est pas un secret)
+      const connection = await apiGet<ProxmoxConnection>(`/api/v1/proxmox/connections/${config.connection_id}`);
+      const base = connection.url.replace(/\/$/, '');
+
       // Ouvrir la page de configuration du stockage dans Proxmox
       const configUrl = `${base}/?storage=${encodeURIComponent(pool.id)}&node=${encodeURIComponent(pool.node)}`;
       console.log(`⚙️ Ouverture de la configuration pour ${pool.name}...`);
@@ -555,7 +457,7 @@ export function Storage() {
   };
 
   // Vérifier si Proxmox est configuré
-  const proxmoxConfig = storage.getProxmoxConfig();
+  const proxmoxConfig = storage.getProxmoxConnection();
   const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
 
   // En production, si Proxmox n'est pas configuré, ne rien afficher
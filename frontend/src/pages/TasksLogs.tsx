@@ -62,8 +62,8 @@ export function TasksLogs() {
       setLoading(true);
       
       // Essayer de charger les données Proxmox réelles
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         setTasks([]);
         setLogs([]);
@@ -71,17 +71,13 @@ export function TasksLogs() {
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-
       // Appeler l'API backend pour récupérer les tâches
       const data = await apiPost<{
         success: boolean;
         message?: string;
         tasks?: any[];
       }>('/api/v1/proxmox/fetch-tasks', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret
+        connection_id: config.connection_id
       });
 
       if (data.success && data.tasks) {
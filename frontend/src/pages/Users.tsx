@@ -33,6 +33,7 @@ export function UsersPage() {
   const [showEditModal, setShowEditModal] = useState(false);
   const [selectedUser, setSelectedUser] = useState<User | null>(null);
   const [showPassword, setShowPassword] = useState(false);
+  const [currentPassword, setCurrentPassword] = useState('');
   const [formData, setFormData] = useState<CreateUserRequest>({
     username: '',
     email: '',
@@ -58,6 +59,7 @@ export function UsersPage() {
 
   // Vérifier les permissions
   const canManageUsers = hasPermission(currentUser, 'users', 'write');
+  const isEditingSelf = selectedUser !== null && selectedUser.id === currentUser?.id;
   const canViewUsers = hasPermission(currentUser, 'users', 'read') || currentUser?.role === 'admin';
 
   useEffect(() => {
@@ -117,11 +119,19 @@ export function UsersPage() {
       const updateData: any = {
         username: formData.username,
         email: formData.email,
-        role: formData.role,
       };
 
+      // Le rôle n'est modifiable qu'avec la permission users:write
+      if (canManageUsers) {
+        updateData.role = formData.role;
+      }
+
       if (formData.password.trim()) {
         updateData.password = formData.password;
+        // Changer son propre mot de passe exige le mot de passe actuel
+        if (isEditingSelf) {
+          updateData.current_password = currentPassword;
+        }
       }
 
       await apiPut<User>(`/api/auth/users/${selectedUser.id}`, updateData);
@@ -173,6 +183,7 @@ export function UsersPage() {
     });
     setSelectedUser(null);
     setShowPassword(false);
+    setCurrentPassword('');
   };
 
   const formatDate = (dateString: string) => {
@@ -469,6 +480,17 @@ export function UsersPage() {
             </button>
           </div>
 
+          {isEditingSelf && formData.password.trim() && (
+            <Input
+              label="Mot de passe actuel"
+              type="password"
+              value={currentPassword}
+              onChange={(e) => setCurrentPassword(e.target.value)}
+              placeholder="Requis pour changer votre mot de passe"
+              required
+            />
+          )}
+
           <Select
             label="Rôle"
             value={formData.role}
@@ -12,7 +12,9 @@ import {
   Clock,
   MoreVertical,
   Eye,
-  Edit
+  Edit,
+  Copy,
+  Camera
 } from 'lucide-react';
 import { Card, CardHeader, CardTitle, CardContent } from '@/components/ui/Card';
 import { Badge } from '@/components/ui/Badge';
@@ -22,6 +24,9 @@ import { Select } from '@/components/ui/Select';
 import { useToast } from '@/components/ui/Toast';
 import { useTranslation } from '@/hooks/useTranslation';
 import { ConfirmModal } from '@/components/ui/ConfirmModal';
+import { GuestConfigModal } from '@/components/GuestConfigModal';
+import { SnapshotsModal } from '@/components/SnapshotsModal';
+import { ProvisionModal } from '@/components/ProvisionModal';
 import { Loader } from '@/components/ui/Loader';
 import { apiPost } from '@/utils/api';
 import { ProxmoxConfigRequired } from '@/components/ProxmoxConfigRequired';
@@ -58,6 +63,11 @@ export function VMs() {
   const [showMoreMenu, setShowMoreMenu] = useState<number | null>(null);
   const { success, error, warning } = useToast();
   
+  // Invité dont la configuration est ouverte
+  const [configGuest, setConfigGuest] = useState<VM | null>(null);
+  const [snapshotGuest, setSnapshotGuest] = useState<VM | null>(null);
+  const [showProvision, setShowProvision] = useState(false);
+
   // États pour les modales de confirmation
   const [confirmModal, setConfirmModal] = useState<{
     isOpen: boolean;
@@ -115,7 +125,7 @@ export function VMs() {
         setLoading(false);
       } else {
         const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
-        const proxmoxConfig = storage.getProxmoxConfig();
+        const proxmoxConfig = storage.getProxmoxConnection();
         
         // En production, si Proxmox n'est pas configuré, ne pas charger de données mockées
         if (isProduction && !proxmoxConfig) {
@@ -131,7 +141,7 @@ export function VMs() {
     } catch (err) {
       console.error('❌ Erreur lors du chargement des VMs:', err);
       const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
-      const proxmoxConfig = storage.getProxmoxConfig();
+      const proxmoxConfig = storage.getProxmoxConnection();
       
       // En production, si Proxmox n'est pas configuré, ne pas charger de données mockées
       if (isProduction && !proxmoxConfig) {
@@ -237,15 +247,13 @@ export function VMs() {
     try {
       console.log('🔄 Rafraîchissement des données VMs Proxmox...');
 
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         console.log('⚠️ Aucune configuration Proxmox trouvée');
         error(t('common.error'), 'Aucune configuration Proxmox trouvée. Veuillez d\'abord configurer votre connexion Proxmox dans les Paramètres.');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-
       // Utiliser apiPost pour utiliser la bonne URL de l'API (API_BASE_URL)
       const data = await apiPost<{
         success: boolean;
@@ -256,9 +264,7 @@ export function VMs() {
         storages?: any[];
         networks?: any[];
       }>('/api/v1/proxmox/fetch-data', {
-        url: config.url,
-        username: config.username,
-        secret: config.secret,
+        connection_id: config.connection_id,
         node: config.node
       });
 
@@ -374,22 +380,18 @@ export function VMs() {
   // Actions pour les VMs
   const handleVMStart = async (vm: VM) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         error('Erreur', 'Configuration Proxmox manquante. Veuillez configurer Proxmox dans les Paramètres.');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      
       console.log(`🚀 Démarrage de la VM ${vm.name} (${vm.vmid}) sur ${vm.node}...`);
       
       const response = await apiPost<{ success: boolean; message?: string; error?: string }>(
         '/api/v1/proxmox/vm/start',
         {
-          url: config.url,
-          username: config.username,
-          secret: config.secret,
+          connection_id: config.connection_id,
           node: vm.node,
           vmid: vm.vmid
         }
@@ -426,22 +428,18 @@ export function VMs() {
       variant: 'warning',
       onConfirm: async () => {
     try {
-          const savedConfig = localStorage.getItem('proxmoxConfig');
-          if (!savedConfig) {
+          const config = storage.getProxmoxConnection();
+          if (!config) {
             error('Erreur', 'Configuration Proxmox manquante. Veuillez configurer Proxmox dans les Paramètres.');
             return;
           }
 
-          const config = JSON.parse(savedConfig);
-          
           console.log(`🛑 Arrêt de la VM ${vm.name} (${vm.vmid}) sur ${vm.node}...`);
           
           const response = await apiPost<{ success: boolean; message?: string; error?: string }>(
             '/api/v1/proxmox/vm/stop',
             {
-              url: config.url,
-              username: config.username,
-              secret: config.secret,
+              connection_id: config.connection_id,
               node: vm.node,
               vmid: vm.vmid
             }
@@ -473,22 +471,18 @@ export function VMs() {
 
   const handleVMPause = async (vm: VM) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         error('Erreur', 'Configuration Proxmox manquante. Veuillez configurer Proxmox dans les Paramètres.');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      
       console.log(`⏸️ Mise en pause de la VM ${vm.name} (${vm.vmid}) sur ${vm.node}...`);
       
       const response = await apiPost<{ success: boolean; message?: string; error?: string }>(
         '/api/v1/proxmox/vm/pause',
         {
-          url: config.url,
-          username: config.username,
-          secret: config.secret,
+          connection_id: config.connection_id,
           node: vm.node,
           vmid: vm.vmid
         }
@@ -524,22 +518,18 @@ export function VMs() {
       variant: 'warning',
       onConfirm: async () => {
     try {
-          const savedConfig = localStorage.getItem('proxmoxConfig');
-          if (!savedConfig) {
+          const config = storage.getProxmoxConnection();
+          if (!config) {
             error('Erreur', 'Configuration Proxmox manquante. Veuillez configurer Proxmox dans les Paramètres.');
             return;
           }
 
-          const config = JSON.parse(savedConfig);
-          
           console.log(`🔄 Redémarrage de la VM ${vm.name} (${vm.vmid}) sur ${vm.node}...`);
           
           const response = await apiPost<{ success: boolean; message?: string; error?: string }>(
             '/api/v1/proxmox/vm/restart',
             {
-              url: config.url,
-              username: config.username,
-              secret: config.secret,
+              connection_id: config.connection_id,
               node: vm.node,
               vmid: vm.vmid
             }
@@ -569,51 +559,8 @@ export function VMs() {
     });
   };
 
-  const handleVMConfig = async (vm: VM) => {
-    try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
-        warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la configuration');
-        return;
-      }
-
-      const config = JSON.parse(savedConfig);
-      
-
-      console.log(`⚙️ Ouverture de la configuration pour ${vm.name} (${vm.vmid})...`);
-      console.log(`🔍 Config envoyée: username=${config.username}, secret=${config.secret ? '***' : 'manquant'}, password=${config.password ? '***' : 'non fourni'}`);
-      
-      // Obtenir l'URL de la configuration avec authentification depuis le backend
-      // Si un password est fourni, l'utiliser pour obtenir le ticket (utile avec les tokens API)
-      const response = await apiPost<{ success: boolean; configUrl?: string; error?: string }>(
-        '/api/v1/proxmox/vm/config',
-        {
-          url: config.url,
-          username: config.username,
-          secret: config.secret,
-          password: config.password || undefined, // Envoyer le password si disponible
-          node: vm.node,
-          vmid: vm.vmid
-        }
-      );
-
-      if (response.success && response.configUrl) {
-        console.log('URL configuration:', response.configUrl);
-        const configWindow = window.open(response.configUrl, '_blank', 'width=1200,height=800');
-        
-        if (configWindow) {
-          success('Succès', `Configuration ouverte pour ${vm.name}`);
-        } else {
-          warning('Attention', 'La fenêtre de configuration a été bloquée par le navigateur. Veuillez autoriser les popups pour ce site.');
-        }
-      } else {
-        throw new Error(response.error || 'Erreur lors de la génération de l\'URL de la configuration');
-      }
-    } catch (err: any) {
-      console.error('Erreur configuration VM:', err);
-      const errorMessage = err.message || 'Erreur lors de l\'ouverture de la configuration';
-      error('Erreur', `Impossible d'ouvrir la configuration pour ${vm.name}: ${errorMessage}`);
-    }
+  const handleVMConfig = (vm: VM) => {
+    setConfigGuest(vm);
   };
 
   const handleVMView = (vm: VM) => {
@@ -626,26 +573,20 @@ export function VMs() {
 
   const handleVMConsole = async (vm: VM) => {
     try {
-      const savedConfig = localStorage.getItem('proxmoxConfig');
-      if (!savedConfig) {
+      const config = storage.getProxmoxConnection();
+      if (!config) {
         warning('Information', 'Configurez Proxmox dans les Paramètres avant d\'ouvrir la console');
         return;
       }
 
-      const config = JSON.parse(savedConfig);
-      
       console.log(`🖥️ Ouverture de la console pour ${vm.name} (${vm.vmid})...`);
-      console.log(`🔍 Config envoyée: username=${config.username}, secret=${config.secret ? '***' : 'manquant'}, password=${config.password ? '***' : 'non fourni'}`);
       
       // Obtenir l'URL de la console avec authentification depuis le backend
-      // Si un password est fourni, l'utiliser pour obtenir le ticket (utile avec les tokens API)
+      // (ticket obtenu avec le mot de passe enregistré avec la connexion, à défaut le token API)
       const response = await apiPost<{ success: boolean; consoleUrl?: string; error?: string }>(
         '/api/v1/proxmox/vm/console',
         {
-          url: config.url,
-          username: config.username,
-          secret: config.secret,
-          password: config.password || undefined, // Envoyer le password si disponible
+          connection_id: config.connection_id,
           node: vm.node,
           vmid: vm.vmid
         }
@@ -706,7 +647,7 @@ export function VMs() {
   }
 
   // Vérifier si Proxmox est configuré
-  const proxmoxConfig = storage.getProxmoxConfig();
+  const proxmoxConfig = storage.getProxmoxConnection();
   const isProduction = import.meta.env.PROD || import.meta.env.MODE === 'production';
 
   // En production, si Proxmox n'est pas configuré, ne rien afficher
@@ -726,10 +667,16 @@ export function VMs() {
             {t('vms.description') || 'Gestion et monitoring des VMs'}
         </p>
         </div>
-        <Button onClick={refreshVMs} variant="outline" size="sm">
-          <RotateCcw className="h-4 w-4 mr-2" />
-          {t('common.refresh')}
-        </Button>
+        <div className="flex gap-2">
+          <Button onClick={() => setShowProvision(true)} size="sm">
+            <Copy className="h-4 w-4 mr-2" />
+            {t('provision.open') || 'Créer depuis un template'}
+          </Button>
+          <Button onClick={refreshVMs} variant="outline" size="sm">
+            <RotateCcw className="h-4 w-4 mr-2" />
+            {t('common.refresh')}
+          </Button>
+        </div>
       </div>
 
       {/* Statistiques */}
@@ -1082,6 +1029,15 @@ export function VMs() {
                     <Settings className="h-3.5 w-3.5 mr-1.5 flex-shrink-0" />
                     <span>Config</span>
                 </Button>
+                  <Button
+                    variant="outline"
+                    size="sm"
+                    className="flex-1 min-w-[80px] text-xs px-2 py-2 flex items-center justify-center"
+                    onClick={() => setSnapshotGuest(vm)}
+                  >
+                    <Camera className="h-3.5 w-3.5 mr-1.5 flex-shrink-0" />
+                    <span>Snapshots</span>
+                  </Button>
                 </div>
               </div>
 
@@ -1110,6 +1066,35 @@ export function VMs() {
         </Card>
       )}
 
+      {/* Édition de la configuration */}
+      {configGuest && (
+        <GuestConfigModal
+          isOpen={!!configGuest}
+          onClose={() => setConfigGuest(null)}
+          guestType="qemu"
+          node={configGuest.node}
+          vmid={configGuest.vmid}
+          name={configGuest.name}
+          onSaved={() => refreshVMs()}
+        />
+      )}
+
+      {/* Snapshots de l'invité */}
+      {snapshotGuest && (
+        <SnapshotsModal
+          isOpen={!!snapshotGuest}
+          onClose={() => setSnapshotGuest(null)}
+          guestType="qemu"
+          node={snapshotGuest.node}
+          vmid={snapshotGuest.vmid}
+          name={snapshotGuest.name}
+          onChanged={() => refreshVMs()}
+        />
+      )}
+
+      {/* Provisionnement depuis un template */}
+      <ProvisionModal isOpen={showProvision} onClose={() => setShowProvision(false)} onProvisioned={() => refreshVMs()} />
+
       {/* Modale de confirmation */}
       <ConfirmModal
         isOpen={confirmModal.isOpen}
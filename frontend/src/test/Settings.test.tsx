@@ -9,19 +9,27 @@ vi.mock('../components/ui/Toast', () => ({
   useToast: () => mockToast(),
 }))
 
-vi.mock('../utils/proxmox', () => ({
-  proxmoxConfigManager: {
-    getConfig: vi.fn(() => ({
-      url: 'https://pve.example.com:8006',
-      token: '[TEST-TOKEN]',
-      node: 'pve',
-    })),
-    validateConfig: vi.fn(() => ({ isValid: true, errors: [] })),
-    saveConfig: vi.fn(),
-    testConnection: vi.fn(() => Promise.resolve({ status: 'success', message: 'Connected' })),
-    resetConfig: vi.fn(),
-  },
-}))
+vi.mock('../utils/proxmox', () => {
+  const connection = {
+    id: 1,
+    name: 'lab',
+    url: 'https://pve.example.com:8006',
+    username: 'root@pam!dashboard',
+    has_password: false,
+    enabled: true,
+    created_at: '',
+    updated_at: '',
+  }
+  return {
+    proxmoxConnections: {
+      list: vi.fn(() => Promise.resolve([connection])),
+      create: vi.fn(() => Promise.resolve(connection)),
+      update: vi.fn(() => Promise.resolve(connection)),
+      remove: vi.fn(() => Promise.resolve()),
+      test: vi.fn(() => Promise.resolve({ status: 'success', message: 'Connected' })),
+    },
+  }
+})
 
 describe('Settings', () => {
   beforeEach(() => {
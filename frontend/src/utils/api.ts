@@ -89,6 +89,7 @@ export interface App {
   icon?: string;
   health_path: string;
   health_type: string;
+  check_interval?: number; // secondes, 0 = intervalle par défaut
   created_at: string;
 }
 
@@ -101,6 +102,18 @@ export interface Alert {
   payload?: string;
   created_at: string;
   acknowledged: boolean;
+  status?: 'firing' | 'acknowledged' | 'resolved';
+  fingerprint?: string;
+  rule_id?: number;
+  labels?: Record<string, string>;
+  acknowledged_at?: string;
+  acknowledged_by?: string;
+  resolved_at?: string;
+  resolved_by?: string;
+  snoozed_until?: string;
+  assignee_id?: number;
+  assignee?: string;
+  silenced_by?: number[];
 }
 
 export interface HealthStatus {
@@ -122,13 +135,301 @@ export interface CreateAppRequest {
   icon?: string;
   health_path: string;
   health_type: string;
+  check_interval?: number;
+}
+
+export interface UptimeStats {
+  window: '24h' | '7d' | '30d';
+  checks: number;
+  failures: number;
+  uptime_percent: number | null;
+  latency_p50_ms?: number;
+  latency_p95_ms?: number;
+  latency_p99_ms?: number;
+}
+
+export interface AppUptime {
+  app_id: number;
+  windows: UptimeStats[];
 }
 
 export interface NotifyTestRequest {
   to: string;
 }
 
+export type NotifyChannel =
+  | 'email'
+  | 'webhook'
+  | 'slack'
+  | 'mattermost'
+  | 'discord'
+  | 'teams'
+  | 'gotify'
+  | 'ntfy';
+
 export interface SubscribeRequest {
-  channel: 'email' | 'webhook';
+  channel: NotifyChannel;
   endpoint: string;
+  secret?: string;
+}
+
+export type PrometheusAuthType = 'none' | 'basic' | 'bearer';
+
+export interface PrometheusDataSource {
+  id: number;
+  name: string;
+  url: string;
+  auth_type: PrometheusAuthType;
+  username?: string;
+  has_secret: boolean;
+  tls_skip_verify: boolean;
+  is_default: boolean;
+}
+
+export type GuestType = 'qemu' | 'lxc';
+
+export interface GuestNet {
+  id: string;
+  model?: string;
+  name?: string;
+  mac?: string;
+  bridge?: string;
+  tag?: number;
+  firewall: boolean;
+  ip?: string;
+}
+
+export interface GuestDisk {
+  id: string;
+  volume: string;
+  storage?: string;
+  size?: string;
+  media?: string;
+  mountpoint?: string;
+}
+
+export interface GuestPendingItem {
+  key: string;
+  value?: unknown;
+  pending?: unknown;
+  delete?: number;
+}
+
+export interface GuestConfig {
+  type: GuestType;
+  name: string;
+  ostype?: string;
+  cores: number;
+  sockets?: number;
+  memory: number;
+  balloon?: number;
+  swap?: number;
+  onboot: boolean;
+  description: string;
+  tags: string[];
+  nets: GuestNet[];
+  disks: GuestDisk[];
+  digest: string;
+  pending: GuestPendingItem[];
+  reboot_required: boolean;
+}
+
+export interface GuestConfigUpdate {
+  cores?: number;
+  sockets?: number;
+  memory?: number;
+  balloon?: number;
+  swap?: number;
+  onboot?: boolean;
+  description?: string;
+  tags?: string[];
+  nets?: Record<string, { model?: string; bridge?: string }>;
+}
+
+// Provisionnement depuis les templates
+export interface ProvisionTemplate {
+  node: string;
+  type: GuestType;
+  vmid: number;
+  name: string;
+  pool?: string;
+  tags: string[];
+  maxmem: number;
+  maxdisk: number;
+}
+
+export interface CloudInitConfig {
+  user?: string;
+  password?: string;
+  ssh_keys?: string[];
+  ip_config?: string[];
+  nameserver?: string;
+  search_domain?: string;
+}
+
+export interface ProvisionSpec {
+  node: string;
+  type: GuestType;
+  template_vmid: number;
+  target_node?: string;
+  storage?: string;
+  full: boolean;
+  vmid?: number;
+  name: string;
+  pool?: string;
+  description?: string;
+  cloud_init?: CloudInitConfig;
+  start: boolean;
+}
+
+export type ProvisionJobStatus = 'pending' | 'running' | 'succeeded' | 'failed';
+
+export interface ProvisionJob {
+  id: number;
+  connection_id?: number;
+  spec: ProvisionSpec;
+  status: ProvisionJobStatus;
+  step: 'allocate' | 'clone' | 'configure' | 'start' | 'done';
+  progress: number;
+  vmid?: number;
+  upid?: string;
+  error?: string;
+  created_at: string;
+  updated_at: string;
+  finished_at?: string;
+}
+
+// Snapshots des invités
+export interface GuestSnapshotNode {
+  name: string;
+  description?: string;
+  created_at?: string;
+  vmstate: boolean;
+  current: boolean;
+  children: GuestSnapshotNode[];
+}
+
+export interface SnapshotTaskResponse {
+  success: boolean;
+  message: string;
+  upid: string;
+  finished?: boolean;
+  ok?: boolean;
+  exitstatus?: string;
+}
+
+// Sauvegardes
+export interface BackupArchive {
+  id: string; // volid
+  cluster?: string;
+  name: string;
+  type: 'vm' | 'lxc';
+  size: number; // GB
+  node: string;
+  storage: string;
+  format?: string;
+  notes?: string;
+  protected: boolean;
+  vmid: number;
+  created_at: string;
+}
+
+export interface BackupRun {
+  id: string; // UPID
+  cluster?: string;
+  name: string;
+  status: string;
+  exitstatus?: string;
+  started_at: string;
+  completed_at?: string;
+  duration?: number;
+  user: string;
+  node: string;
+  vmid?: number;
+  failed: boolean;
+}
+
+export type BackupViolation = 'no_backup' | 'too_old' | 'unknown'; // unknown : invité ou archives non lus
+
+export interface BackupCompliance {
+  cluster?: string;
+  vmid: number;
+  name: string;
+  type: 'qemu' | 'lxc';
+  node: string;
+  pool?: string;
+  tags?: string[];
+  last_backup?: string;
+  last_backup_id?: string;
+  last_backup_size: number; // GB
+  age_seconds?: number;
+  retention_count: number;
+  size_trend: { created_at: string; size: number }[];
+  size_change_percent?: number;
+  max_age_seconds: number;
+  policy: string; // default, tag:<tag> ou pool:<pool>
+  compliant: boolean;
+  violation?: BackupViolation;
+}
+
+export interface BackupComplianceReport {
+  generated_at: string;
+  complete: boolean; // false : inventaire partiel, voir warnings
+  warnings?: string[];
+  total: number;
+  compliant: number;
+  violations: number; // dont unknown
+  unknown: number;
+  guests: BackupCompliance[];
+}
+
+export interface TaskLogLine {
+  n: number;
+  t: string;
+}
+
+export type BackupMode = 'snapshot' | 'suspend' | 'stop';
+
+export interface BackupRunRequest {
+  vmids: number[];
+  mode?: BackupMode;
+  compress?: string;
+  storage?: string;
+  notes_template?: string;
+  protected?: boolean;
+}
+
+export interface StartedBackup {
+  node: string;
+  vmids: number[];
+  upid: string;
+}
+
+export interface BackupJob {
+  id: string;
+  enabled: boolean;
+  schedule: string;
+  storage?: string;
+  node?: string;
+  mode?: BackupMode;
+  compress?: string;
+  vmids?: number[];
+  all?: boolean;
+  exclude?: string;
+  pool?: string;
+  mailto?: string;
+  comment?: string;
+  'next-run'?: number;
+}
+
+export interface BackupJobUpdate {
+  enabled?: boolean;
+  schedule?: string;
+  storage?: string;
+  mode?: BackupMode | '';
+  compress?: string;
+  vmids?: number[];
+  all?: boolean;
+  pool?: string;
+  comment?: string;
 }
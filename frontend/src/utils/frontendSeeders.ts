@@ -7,33 +7,30 @@
 const isDev = import.meta.env.DEV || import.meta.env.MODE === 'development';
 
 /**
- * Seed de la configuration Proxmox fictive pour le développement
+ * Seed de la connexion Proxmox fictive pour le développement
  */
 export function seedProxmoxConfig() {
   if (!isDev) {
     return; // Ne pas charger en production
   }
 
-  // Vérifier si une configuration existe déjà
-  const existingConfig = localStorage.getItem('proxmoxConfig');
-  if (existingConfig) {
-    console.log('📦 Configuration Proxmox déjà présente, seeder ignoré');
+  // Vérifier si une connexion est déjà sélectionnée
+  const existingConnection = localStorage.getItem('proxmoxConnection');
+  if (existingConnection) {
+    console.log('📦 Connexion Proxmox déjà présente, seeder ignoré');
     return;
   }
 
-  console.log('🌱 Chargement de la configuration Proxmox fictive pour le développement...');
+  console.log('🌱 Chargement de la connexion Proxmox fictive pour le développement...');
 
-  // Configuration Proxmox fictive pour le développement
-  const mockProxmoxConfig = {
-    url: 'https://proxmox-dev.local:8006',
-    username: 'dev-user@pam',
-    secret: 'dev-secret-token-12345',
-    node: 'pve-01',
-    token: 'PVEAPIToken=dev-user@pam!dev-secret-token-12345'
+  // Référence fictive : aucun token n'est stocké côté navigateur, la connexion doit exister en base
+  const mockProxmoxConnection = {
+    connection_id: 1,
+    node: 'pve-01'
   };
 
-  localStorage.setItem('proxmoxConfig', JSON.stringify(mockProxmoxConfig));
-  console.log('✅ Configuration Proxmox fictive seedée:', mockProxmoxConfig);
+  localStorage.setItem('proxmoxConnection', JSON.stringify(mockProxmoxConnection));
+  console.log('✅ Connexion Proxmox fictive seedée:', mockProxmoxConnection);
 }
 
 /**
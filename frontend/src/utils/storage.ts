@@ -12,14 +12,18 @@ export interface UserData {
   isAuthenticated: boolean;
 }
 
-export interface ProxmoxConfig {
-  url: string;
-  username: string;
-  secret: string;
-  password?: string; // Mot de passe optionnel pour la console VNC (si différent du secret du token)
-  node: string;
+/**
+ * Connexion Proxmox active : seul l'identifiant de la connexion enregistrée côté serveur est
+ * conservé, l'URL et les secrets du token ne quittent pas le backend
+ */
+export interface ProxmoxConnectionRef {
+  connection_id: number;
+  node: string; // nœud par défaut
 }
 
+// Ancienne clé qui contenait le token API en clair : supprimée au chargement
+localStorage.removeItem('proxmoxConfig');
+
 /**
  * Stocke uniquement les données essentielles dans localStorage
  */
@@ -38,18 +42,24 @@ export const storage = {
     localStorage.removeItem('userData');
   },
 
-  // Configuration Proxmox (essentielle)
-  setProxmoxConfig: (config: ProxmoxConfig) => {
-    localStorage.setItem('proxmoxConfig', JSON.stringify(config));
+  // Connexion Proxmox active (essentielle)
+  setProxmoxConnection: (connection: ProxmoxConnectionRef) => {
+    localStorage.setItem('proxmoxConnection', JSON.stringify(connection));
   },
 
-  getProxmoxConfig: (): ProxmoxConfig | null => {
-    const data = localStorage.getItem('proxmoxConfig');
+  getProxmoxConnection: (): ProxmoxConnectionRef | null => {
+    const data = localStorage.getItem('proxmoxConnection');
     return data ? JSON.parse(data) : null;
   },
 
-  clearProxmoxConfig: () => {
-    localStorage.removeItem('proxmoxConfig');
+  clearProxmoxConnection: () => {
+    localStorage.removeItem('proxmoxConnection');
+  },
+
+  // Identifiants des requêtes Proxmox : la connexion est résolue par le backend
+  proxmoxCredentials: (): { connection_id: number } | null => {
+    const connection = storage.getProxmoxConnection();
+    return connection ? { connection_id: connection.connection_id } : null;
   },
 
   // Données Proxmox (temporaires, récupérées à la demande)
@@ -85,7 +95,7 @@ export const storage = {
   // Nettoyage complet
   clearAll: () => {
     localStorage.removeItem('userData');
-    localStorage.removeItem('proxmoxConfig');
+    localStorage.removeItem('proxmoxConnection');
     localStorage.removeItem('proxmoxData');
     localStorage.removeItem('proxmoxNodes');
     localStorage.removeItem('proxmoxVMs');
@@ -118,10 +128,10 @@ export const storage = {
   // S'assurer que les données Proxmox sont chargées (appelé automatiquement par les pages)
   ensureProxmoxDataLoaded: async (): Promise<boolean> => {
     try {
-      // Vérifier si la configuration Proxmox existe
-      const config = storage.getProxmoxConfig();
+      // Vérifier qu'une connexion Proxmox est sélectionnée
+      const config = storage.getProxmoxConnection();
       if (!config) {
-        console.log('⚠️ Aucune configuration Proxmox trouvée');
+        console.log('⚠️ Aucune connexion Proxmox sélectionnée');
         return false;
       }
 
@@ -206,9 +216,9 @@ export const useProxmoxData = () => {
 
   const refreshData = async () => {
     try {
-      const config = storage.getProxmoxConfig();
+      const config = storage.getProxmoxConnection();
       if (!config) {
-        throw new Error('Configuration Proxmox manquante');
+        throw new Error('Connexion Proxmox manquante');
       }
 
       // Utiliser apiPost pour utiliser la bonne URL de l'API (API_BASE_URL)
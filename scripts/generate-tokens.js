@@ -22,6 +22,9 @@ console.log(`AUTH_TOKEN=${generateSecureToken(32)}\n`);
 console.log('JWT_SECRET (pour les tokens JWT):');
 console.log(`JWT_SECRET=${generateJWTSecret(32)}\n`);
 
+console.log('ENCRYPTION_KEY (chiffrement des secrets en base, distincte de JWT_SECRET):');
+console.log(`ENCRYPTION_KEY=${generateJWTSecret(32)}\n`);
+
 console.log('🔒 Conseils de sécurité:');
 console.log('1. Copiez ces valeurs dans votre fichier config.prod.env');
 console.log('2. Ne partagez jamais ces tokens');
@@ -33,5 +36,6 @@ console.log('\n📝 Exemple de configuration:');
 console.log('# Dans config.prod.env');
 console.log(`AUTH_TOKEN=${generateSecureToken(32)}`);
 console.log(`JWT_SECRET=${generateJWTSecret(32)}`);
+console.log(`ENCRYPTION_KEY=${generateJWTSecret(32)}`);
 console.log('CORS_ORIGINS=https://yourdomain.com');
 console.log('ALLOWED_IPS=192.168.1.100,10.0.0.50');
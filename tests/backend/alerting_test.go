@@ -0,0 +1,317 @@
+package alerting
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupAlertingStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := store.NewStore(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return s
+}
+
+func createRule(t *testing.T, s *store.Store, rule *models.AlertRule) *models.AlertRule {
+	t.Helper()
+	rule.Enabled = true
+	if err := rule.Validate(); err != nil {
+		t.Fatalf("Invalid rule: %v", err)
+	}
+	if err := s.CreateAlertRule(rule); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	return rule
+}
+
+func nodeSnapshot(at time.Time, cpu float64) *models.ProxmoxSnapshot {
+	return &models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{
+			Nodes:    []models.ProxmoxNode{{Cluster: "lab", Name: "pve1", Status: "online", CPUUsage: cpu}},
+			Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}},
+		},
+		CollectedAt: at,
+	}
+}
+
+func TestEngine_FiresAfterForDurationAndResolves(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "CPU nœud", Metric: models.RuleMetricNodeCPU, Comparator: ">",
+		Threshold: 90, For: models.Duration(5 * time.Minute), Severity: "high"})
+
+	engine := NewEngine(s)
+	var events []*models.Alert
+	engine.OnAlert(func(alert *models.Alert) { events = append(events, alert) })
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine.Evaluate(nodeSnapshot(start, 95))
+	engine.Evaluate(nodeSnapshot(start.Add(4*time.Minute), 97))
+	if len(events) != 0 {
+		t.Fatalf("Expected no alert before the for duration, got %d", len(events))
+	}
+
+	engine.Evaluate(nodeSnapshot(start.Add(5*time.Minute), 96))
+	engine.Evaluate(nodeSnapshot(start.Add(6*time.Minute), 99))
+	if len(events) != 1 {
+		t.Fatalf("Expected a single alert, got %d", len(events))
+	}
+	fired := events[0]
+	if fired.Status != models.AlertStatusFiring || fired.Severity != "high" || fired.Fingerprint == "" || fired.RuleID == nil {
+		t.Errorf("Unexpected alert: %+v", fired)
+	}
+
+	engine.Evaluate(nodeSnapshot(start.Add(7*time.Minute), 40))
+	if len(events) != 2 || events[1].Status != models.AlertStatusResolved || events[1].ResolvedAt == nil {
+		t.Fatalf("Expected the alert to be resolved, got %+v", events)
+	}
+	open, err := s.GetOpenRuleAlerts()
+	if err != nil {
+		t.Fatalf("GetOpenRuleAlerts failed: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("Expected no open alert, got %d", len(open))
+	}
+
+	// Une nouvelle occurrence crée une nouvelle alerte avec la même empreinte
+	engine.Evaluate(nodeSnapshot(start.Add(8*time.Minute), 95))
+	engine.Evaluate(nodeSnapshot(start.Add(13*time.Minute), 95))
+	if len(events) != 3 || events[2].Fingerprint != fired.Fingerprint || events[2].ID == fired.ID {
+		t.Errorf("Expected a new alert with the same fingerprint, got %+v", events)
+	}
+}
+
+func TestEngine_KeepsAlertsOfUnreachableClusters(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "CPU nœud", Metric: models.RuleMetricNodeCPU, Comparator: ">=",
+		Threshold: 90, Severity: "critical"})
+
+	engine := NewEngine(s)
+	now := time.Now()
+	engine.Evaluate(nodeSnapshot(now, 92))
+
+	// Cluster injoignable : aucun nœud remonté, l'alerte reste ouverte
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: false}}},
+		CollectedAt:      now.Add(time.Minute),
+	})
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 1 {
+		t.Fatalf("Expected the alert to stay open, got %d", len(open))
+	}
+
+	// Cluster joignable sans le nœud : l'objet a disparu, l'alerte est résolue
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}}},
+		CollectedAt:      now.Add(2 * time.Minute),
+	})
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 0 {
+		t.Errorf("Expected the alert to be resolved, got %d open", len(open))
+	}
+}
+
+func TestEngine_GuestScopeByTag(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "Prod arrêtée", Metric: models.RuleMetricGuestRunning, Comparator: "==",
+		Threshold: 0, Scope: models.RuleScope{Tags: []string{"prod"}}, Severity: "critical"})
+
+	engine := NewEngine(s)
+	var events []*models.Alert
+	engine.OnAlert(func(alert *models.Alert) { events = append(events, alert) })
+
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{
+			VMs: []models.ProxmoxGuest{
+				{Cluster: "lab", VMID: 100, Name: "web", Type: "qemu", Status: "stopped", Node: "pve1", Tags: "prod;web"},
+				{Cluster: "lab", VMID: 101, Name: "test", Type: "qemu", Status: "stopped", Node: "pve1", Tags: "dev"},
+			},
+			LXC: []models.ProxmoxGuest{
+				{Cluster: "lab", VMID: 200, Name: "db", Type: "lxc", Status: "running", Node: "pve1", Tags: "prod"},
+			},
+			Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}},
+		},
+		CollectedAt: time.Now(),
+	})
+
+	if len(events) != 1 || events[0].Title != "Prod arrêtée: VM web (100)" {
+		t.Fatalf("Expected a single alert for VM 100, got %+v", events)
+	}
+}
+
+func TestEngine_SnapshotAge(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "Vieux snapshots", Metric: models.RuleMetricGuestSnapshots, Comparator: ">",
+		Threshold: 7, Severity: "medium"})
+
+	now := time.Now()
+	inventory := models.ProxmoxInventory{
+		VMs: []models.ProxmoxGuest{
+			{Cluster: "lab", VMID: 100, Name: "web", Type: "qemu", Node: "pve1"},
+			{Cluster: "lab", VMID: 101, Name: "api", Type: "qemu", Node: "pve1"},
+		},
+		LXC: []models.ProxmoxGuest{{Cluster: "lab", VMID: 100, Name: "other-cluster-id", Type: "lxc", Node: "pve1"}},
+		GuestSnapshots: []models.ProxmoxGuestSnapshot{
+			{Cluster: "lab", VMID: 100, Type: "qemu", Name: "recent", CreatedAt: now.Add(-24 * time.Hour)},
+			{Cluster: "lab", VMID: 100, Type: "qemu", Name: "old", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+			{Cluster: "lab", VMID: 101, Type: "qemu", Name: "fresh", CreatedAt: now.Add(-2 * 24 * time.Hour)},
+		},
+		Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}},
+	}
+
+	rule := &models.AlertRule{Metric: models.RuleMetricGuestSnapshots}
+	values := map[string]float64{}
+	for _, target := range Targets(rule, &models.ProxmoxSnapshot{ProxmoxInventory: inventory, CollectedAt: now}) {
+		values[target.Kind+"/"+target.ID] = target.Value
+	}
+	if values["qemu/100"] != 10 || values["qemu/101"] != 2 || values["lxc/100"] != 0 {
+		t.Errorf("Expected the age in days of the oldest snapshot of each guest, got %v", values)
+	}
+
+	engine := NewEngine(s)
+	var events []*models.Alert
+	engine.OnAlert(func(alert *models.Alert) { events = append(events, alert) })
+	engine.Evaluate(&models.ProxmoxSnapshot{ProxmoxInventory: inventory, CollectedAt: now})
+	if len(events) != 1 || events[0].Title != "Vieux snapshots: VM web (100)" {
+		t.Fatalf("Expected a single alert for VM 100, got %+v", events)
+	}
+
+	// Le snapshot supprimé, l'alerte est résolue
+	inventory.GuestSnapshots = inventory.GuestSnapshots[2:]
+	engine.Evaluate(&models.ProxmoxSnapshot{ProxmoxInventory: inventory, CollectedAt: now.Add(time.Minute)})
+	if len(events) != 2 || events[1].Status != models.AlertStatusResolved {
+		t.Errorf("Expected the alert to be resolved, got %+v", events)
+	}
+}
+
+func TestEngine_KeepsSnapshotAlertsOfUnreadableGuests(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "Vieux snapshots", Metric: models.RuleMetricGuestSnapshots, Comparator: ">",
+		Threshold: 7, Severity: "medium"})
+
+	now := time.Now()
+	guest := models.ProxmoxGuest{Cluster: "lab", VMID: 100, Name: "web", Type: "qemu", Node: "pve1"}
+	old := models.ProxmoxGuestSnapshot{Cluster: "lab", VMID: 100, Type: "qemu", Name: "old", CreatedAt: now.Add(-10 * 24 * time.Hour)}
+	poll := func(at time.Time, snapshots []models.ProxmoxGuestSnapshot, failed ...string) *models.ProxmoxSnapshot {
+		return &models.ProxmoxSnapshot{
+			ProxmoxInventory: models.ProxmoxInventory{
+				VMs:            []models.ProxmoxGuest{guest},
+				GuestSnapshots: snapshots,
+				Clusters:       []models.ProxmoxClusterStatus{{Name: "lab", Success: true, FailedSnapshotGuests: failed}},
+			},
+			CollectedAt: at,
+		}
+	}
+
+	engine := NewEngine(s)
+	var events []*models.Alert
+	engine.OnAlert(func(alert *models.Alert) { events = append(events, alert) })
+	engine.Evaluate(poll(now, []models.ProxmoxGuestSnapshot{old}))
+	if len(events) != 1 {
+		t.Fatalf("Expected the alert to fire, got %+v", events)
+	}
+
+	// Snapshots illisibles une fois : l'invité n'est pas évalué comme sans snapshot, l'alerte reste ouverte
+	unreadable := poll(now.Add(time.Minute), []models.ProxmoxGuestSnapshot{}, "qemu/100")
+	if targets := Targets(&models.AlertRule{Metric: models.RuleMetricGuestSnapshots}, unreadable); len(targets) != 0 {
+		t.Errorf("Expected the guest with unreadable snapshots not to be evaluated, got %+v", targets)
+	}
+	engine.Evaluate(unreadable)
+	engine.Evaluate(poll(now.Add(2*time.Minute), []models.ProxmoxGuestSnapshot{old}))
+	if open, _ := s.GetOpenRuleAlerts(); len(events) != 1 || len(open) != 1 {
+		t.Errorf("Expected the alert to stay open without flapping, got %d events and %d open", len(events), len(open))
+	}
+}
+
+func TestEngine_ResolvesAlertsOfDisabledRules(t *testing.T) {
+	s := setupAlertingStore(t)
+	rule := createRule(t, s, &models.AlertRule{Name: "Storage plein", Metric: models.RuleMetricStorageUsage, Comparator: ">",
+		Threshold: 85, Severity: "high"})
+
+	engine := NewEngine(s)
+	snapshot := &models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{
+			Storages: []models.ProxmoxStorage{{Cluster: "lab", ID: "pve1/local", Name: "local", Node: "pve1", UsagePercent: 92}},
+			Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}},
+		},
+		CollectedAt: time.Now(),
+	}
+	engine.Evaluate(snapshot)
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 1 {
+		t.Fatalf("Expected one open alert, got %d", len(open))
+	}
+
+	rule.Enabled = false
+	if err := s.UpdateAlertRule(rule); err != nil {
+		t.Fatalf("UpdateAlertRule failed: %v", err)
+	}
+	engine.Evaluate(snapshot)
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 0 {
+		t.Errorf("Expected the alert of the disabled rule to be resolved, got %d open", len(open))
+	}
+}
+
+func TestEngine_KeepsAlertsOfGuestsOnFailedNodes(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "VM arrêtée", Metric: models.RuleMetricGuestRunning, Comparator: "==",
+		Threshold: 0, Severity: "critical"})
+
+	engine := NewEngine(s)
+	now := time.Now()
+	guest := models.ProxmoxGuest{Cluster: "lab", VMID: 100, Name: "web", Type: "qemu", Status: "stopped", Node: "pve2"}
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{VMs: []models.ProxmoxGuest{guest}, Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}}},
+		CollectedAt:      now,
+	})
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 1 {
+		t.Fatalf("Expected an open alert, got %d", len(open))
+	}
+
+	// Les VMs de pve2 n'ont pas pu être listées : l'alerte reste ouverte
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true, FailedVMNodes: []string{"pve2"}}}},
+		CollectedAt:      now.Add(time.Minute),
+	})
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 1 {
+		t.Fatalf("Expected the alert to stay open, got %d", len(open))
+	}
+
+	// pve2 lu sans la VM : elle a été supprimée
+	engine.Evaluate(&models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}}},
+		CollectedAt:      now.Add(2 * time.Minute),
+	})
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 0 {
+		t.Errorf("Expected the alert to be resolved, got %d open", len(open))
+	}
+}
+
+func TestEngine_KeepsAlertsOfOfflineNodes(t *testing.T) {
+	s := setupAlertingStore(t)
+	createRule(t, s, &models.AlertRule{Name: "CPU nœud", Metric: models.RuleMetricNodeCPU, Comparator: ">=",
+		Threshold: 90, Severity: "critical"})
+
+	engine := NewEngine(s)
+	now := time.Now()
+	engine.Evaluate(nodeSnapshot(now, 95))
+
+	// Nœud hors ligne : aucune mesure, la condition n'est pas évaluée
+	offline := nodeSnapshot(now.Add(time.Minute), 0)
+	offline.Nodes[0].Status = "offline"
+	engine.Evaluate(offline)
+	if open, _ := s.GetOpenRuleAlerts(); len(open) != 1 {
+		t.Errorf("Expected the alert to stay open, got %d", len(open))
+	}
+}
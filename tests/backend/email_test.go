@@ -0,0 +1,385 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/config"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+
+	_ "modernc.org/sqlite"
+)
+
+// fakeMessage est un message reçu par le serveur SMTP de test
+type fakeMessage struct {
+	from, to, auth string
+	tls            bool
+	data           string
+}
+
+// fakeSMTP est un serveur SMTP minimal (EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA)
+type fakeSMTP struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config // nil : STARTTLS non annoncé
+	implicitTLS bool
+	rcptReply   string // réponse à RCPT TO, "250 OK" si vide
+	dropOnQuit  bool   // ferme la connexion sans répondre au QUIT
+
+	mu       sync.Mutex
+	messages []fakeMessage
+}
+
+func startFakeSMTP(t *testing.T, server *fakeSMTP) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server.listener = listener
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	secure := false
+	if s.implicitTLS {
+		tlsConn := tls.Server(conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		conn, secure = tlsConn, true
+	}
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+	var msg fakeMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if s.tlsConfig != nil && !secure {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			msg.auth = string(decoded)
+			tp.PrintfLine("235 Authentication successful")
+		case "MAIL":
+			msg.from = line
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = line
+			if s.rcptReply != "" {
+				tp.PrintfLine("%s", s.rcptReply)
+				continue
+			}
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data, msg.tls = string(data), secure
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK queued")
+		case "QUIT":
+			if s.dropOnQuit {
+				return
+			}
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTP) received() []fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMessage(nil), s.messages...)
+}
+
+// testCertificate génère un certificat auto-signé pour 127.0.0.1
+func testCertificate(t *testing.T) (*tls.Config, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake-smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func setupEmailStore(t *testing.T) *store.Store {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := store.NewStore(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return s
+}
+
+func smtpConfig(port int, security string) config.SMTPConfig {
+	return config.SMTPConfig{
+		Host:         "127.0.0.1",
+		Port:         port,
+		Username:     "dashboard",
+		Password:     "s3cret",
+		From:         "ProxmoxDash <noreply@example.com>",
+		Security:     security,
+		MaxAttempts:  3,
+		RetryBackoff: time.Hour,
+	}
+}
+
+func TestWorker_STARTTLSMultipartAlertEmail(t *testing.T) {
+	serverTLS, roots := testCertificate(t)
+	server := &fakeSMTP{tlsConfig: serverTLS}
+	port := startFakeSMTP(t, server)
+	s := setupEmailStore(t)
+
+	worker := NewWorker(s, smtpConfig(port, config.SMTPSecuritySTARTTLS))
+	worker.SetTLSConfig(&tls.Config{RootCAs: roots})
+
+	queued, err := worker.SendAlertEmail("ops@example.com", &models.Alert{
+		ID: 7, Source: "node:pve1", Severity: "critical", Title: "Nœud hors ligne",
+		Message: "pve1 <ne répond plus>", CreatedAt: time.Now(),
+	}, "https://dash.example.com/?alert=7")
+	if err != nil {
+		t.Fatalf("SendAlertEmail() error = %v", err)
+	}
+	worker.ProcessQueue()
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 delivered message, got %d", len(messages))
+	}
+	got := messages[0]
+	if !got.tls || got.auth != "\x00dashboard\x00s3cret" {
+		t.Errorf("Expected an authenticated message over STARTTLS, got tls=%v auth=%q", got.tls, got.auth)
+	}
+	if got.from != "MAIL FROM:<noreply@example.com>" {
+		t.Errorf("Unexpected envelope sender %q", got.from)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "[CRITICAL] Nœud hors ligne" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q", mediaType)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read MIME part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Type"))
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") &&
+			!strings.Contains(string(body), "pve1 &lt;ne répond plus&gt;") {
+			t.Errorf("Expected the HTML part to escape the alert message:\n%s", body)
+		}
+		if !strings.Contains(string(body), "https://dash.example.com/?alert=7") {
+			t.Errorf("Expected the acknowledge link in %s part", part.Header.Get("Content-Type"))
+		}
+	}
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "text/plain") || !strings.HasPrefix(parts[1], "text/html") {
+		t.Errorf("Expected text/plain then text/html parts, got %v", parts)
+	}
+
+	sent, _ := s.GetEmailQueue(queued.ID)
+	if sent.State != models.EmailStateSent || sent.Attempts != 1 || sent.SentAt == nil {
+		t.Errorf("Expected the email to be marked sent, got %+v", sent)
+	}
+}
+
+func TestWorker_ImplicitTLS(t *testing.T) {
+	if mode := (config.SMTPConfig{Port: 465, TLS: true}).SecurityMode(); mode != config.SMTPSecurityTLS {
+		t.Errorf("Expected port 465 to default to implicit TLS, got %q", mode)
+	}
+
+	serverTLS, roots := testCertificate(t)
+	server := &fakeSMTP{tlsConfig: serverTLS, implicitTLS: true}
+	port := startFakeSMTP(t, server)
+	s := setupEmailStore(t)
+
+	worker := NewWorker(s, smtpConfig(port, config.SMTPSecurityTLS))
+	worker.SetTLSConfig(&tls.Config{RootCAs: roots})
+	if _, err := worker.SendTestEmail("admin@example.com"); err != nil {
+		t.Fatalf("SendTestEmail() error = %v", err)
+	}
+	worker.ProcessQueue()
+
+	if messages := server.received(); len(messages) != 1 || !messages[0].tls {
+		t.Fatalf("Expected 1 message over implicit TLS, got %+v", messages)
+	}
+}
+
+func TestWorker_QuitFailureAfterAcceptedMessage(t *testing.T) {
+	server := &fakeSMTP{dropOnQuit: true}
+	port := startFakeSMTP(t, server)
+	s := setupEmailStore(t)
+
+	worker := NewWorker(s, smtpConfig(port, config.SMTPSecurityNone))
+	queued, err := worker.SendTestEmail("admin@example.com")
+	if err != nil {
+		t.Fatalf("SendTestEmail() error = %v", err)
+	}
+	worker.ProcessQueue()
+
+	// Le message accepté après DATA est envoyé une seule fois, même si QUIT échoue
+	if sent, _ := s.GetEmailQueue(queued.ID); sent.State != models.EmailStateSent || sent.Attempts != 1 {
+		t.Errorf("Expected the email to be sent, got state=%s attempts=%d", sent.State, sent.Attempts)
+	}
+	worker.ProcessQueue()
+	if n := len(server.received()); n != 1 {
+		t.Errorf("Expected 1 delivered message, got %d", n)
+	}
+}
+
+func TestWorker_RetriesAndDeadLetter(t *testing.T) {
+	// Serveur sans STARTTLS : un envoi qui l'exige échoue et est replanifié
+	server := &fakeSMTP{}
+	port := startFakeSMTP(t, server)
+	s := setupEmailStore(t)
+
+	worker := NewWorker(s, smtpConfig(port, config.SMTPSecuritySTARTTLS))
+	queued, err := worker.SendTestEmail("admin@example.com")
+	if err != nil {
+		t.Fatalf("SendTestEmail() error = %v", err)
+	}
+	worker.ProcessQueue()
+
+	failed, _ := s.GetEmailQueue(queued.ID)
+	if failed.State != models.EmailStateFailed || failed.Attempts != 1 || failed.LastError == nil ||
+		!failed.NextAttemptAt.After(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("Expected a retry scheduled in one hour, got %+v", failed)
+	}
+	if !strings.Contains(*failed.LastError, "STARTTLS") {
+		t.Errorf("Expected a STARTTLS error, got %q", *failed.LastError)
+	}
+	worker.ProcessQueue() // la prochaine tentative n'est pas échue
+	if again, _ := s.GetEmailQueue(queued.ID); again.Attempts != 1 {
+		t.Errorf("Expected no attempt before next_attempt_at, got %d attempts", again.Attempts)
+	}
+
+	// Erreurs temporaires (4xx) jusqu'à max_attempts, sans délai entre les tentatives
+	server.rcptReply = "451 Try again later"
+	cfg := smtpConfig(port, config.SMTPSecurityNone)
+	cfg.RetryBackoff = 0
+	worker = NewWorker(s, cfg)
+	transient, _ := worker.SendTestEmail("ops@example.com")
+	for i := 0; i < 3; i++ {
+		worker.ProcessQueue()
+	}
+	if dead, _ := s.GetEmailQueue(transient.ID); dead.State != models.EmailStateDead || dead.Attempts != 3 {
+		t.Errorf("Expected the email to be dead after 3 attempts, got state=%s attempts=%d", dead.State, dead.Attempts)
+	}
+
+	// Erreur permanente (5xx) : abandon immédiat
+	server.rcptReply = "550 No such user"
+	permanent, _ := worker.SendTestEmail("nobody@example.com")
+	worker.ProcessQueue()
+	if dead, _ := s.GetEmailQueue(permanent.ID); dead.State != models.EmailStateDead || dead.Attempts != 1 {
+		t.Errorf("Expected a permanent failure to be dead-lettered at once, got state=%s attempts=%d", dead.State, dead.Attempts)
+	}
+
+	deadLetters, err := s.GetEmailsByState(models.EmailStateDead, 10)
+	if err != nil || len(deadLetters) != 2 {
+		t.Fatalf("Expected 2 dead-lettered emails, got %d (%v)", len(deadLetters), err)
+	}
+
+	// Relance manuelle d'un email abandonné
+	server.rcptReply = ""
+	if err := s.RequeueEmail(permanent.ID); err != nil {
+		t.Fatalf("RequeueEmail() error = %v", err)
+	}
+	worker.ProcessQueue()
+	if sent, _ := s.GetEmailQueue(permanent.ID); sent.State != models.EmailStateSent {
+		t.Errorf("Expected the requeued email to be sent, got %s", sent.State)
+	}
+	if n := len(server.received()); n != 1 {
+		t.Errorf("Expected 1 delivered message, got %d", n)
+	}
+}
@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/store"
+
+	"github.com/go-chi/chi/v5"
+	_ "modernc.org/sqlite"
+)
+
+// setupTestExporter crée un exporter sur une base en mémoire
+func setupTestExporter(t *testing.T) (*Exporter, *store.Store) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := store.NewStore(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return NewExporter(s), s
+}
+
+// scrape retourne la réponse de l'exporter
+func scrape(t *testing.T, exp *Exporter) string {
+	w := httptest.NewRecorder()
+	exp.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	return w.Body.String()
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestExporter_PublishesCachedInventory(t *testing.T) {
+	exp, _ := setupTestExporter(t)
+	now := time.Now()
+	snapshot := &models.ProxmoxSnapshot{
+		ProxmoxInventory: models.ProxmoxInventory{
+			Clusters: []models.ProxmoxClusterStatus{{Name: "prod", Success: true}},
+			Nodes:    []models.ProxmoxNode{{Cluster: "prod", Name: "pve1", Status: "online", CPUUsage: 25, MemoryUsage: 50, Uptime: 3600}},
+			VMs:      []models.ProxmoxGuest{{Cluster: "prod", Node: "pve1", VMID: 100, Name: `web "front"`, Type: "qemu", Status: "running", CPUUsage: 10, MaxMem: 2048}},
+			LXC:      []models.ProxmoxGuest{{Cluster: "prod", Node: "pve1", VMID: 200, Name: "dns", Type: "lxc", Status: "stopped"}},
+			Storages: []models.ProxmoxStorage{{Cluster: "prod", Node: "pve1", Name: "local", Type: "dir", Status: "online", TotalSpace: 2, UsedSpace: 1, UsagePercent: 50}},
+			Backups: []models.ProxmoxBackup{
+				{Cluster: "prod", VMID: 100, Type: "vm", Size: 1, CreatedAt: now.Add(-48 * time.Hour)},
+				{Cluster: "prod", VMID: 100, Type: "vm", Size: 1, CreatedAt: time.Unix(now.Unix()-3600, 0)},
+			},
+			GuestSnapshots: []models.ProxmoxGuestSnapshot{
+				{Cluster: "prod", VMID: 100, Type: "qemu", Name: "old", CreatedAt: now.Add(-72 * time.Hour)},
+				{Cluster: "prod", VMID: 100, Type: "qemu", Name: "recent", CreatedAt: now.Add(-time.Hour)},
+			},
+		},
+		CollectedAt: now,
+	}
+	exp.SetSnapshotSource(func() *models.ProxmoxSnapshot { return snapshot })
+
+	body := scrape(t, exp)
+	assertContains(t, body,
+		"# TYPE proxmoxdash_node_up gauge",
+		`proxmoxdash_cluster_up{cluster="prod"} 1`,
+		`proxmoxdash_node_up{cluster="prod",node="pve1"} 1`,
+		`proxmoxdash_node_cpu_usage_ratio{cluster="prod",node="pve1"} 0.25`,
+		`proxmoxdash_node_uptime_seconds{cluster="prod",node="pve1"} 3600`,
+		`proxmoxdash_guest_up{cluster="prod",node="pve1",vmid="100",name="web \"front\"",type="qemu"} 1`,
+		`proxmoxdash_guest_up{cluster="prod",node="pve1",vmid="200",name="dns",type="lxc"} 0`,
+		`proxmoxdash_guest_memory_max_bytes{cluster="prod",node="pve1",vmid="100",name="web \"front\"",type="qemu"} 2048`,
+		`proxmoxdash_storage_size_bytes{cluster="prod",node="pve1",storage="local",type="dir"} 2.147483648e+09`,
+		`proxmoxdash_storage_usage_ratio{cluster="prod",node="pve1",storage="local",type="dir"} 0.5`,
+	)
+	// Seul le backup le plus récent du VMID est publié
+	assertContains(t, body, `proxmoxdash_guest_last_backup_timestamp_seconds{cluster="prod",vmid="100",type="vm"} `+
+		formatValue(float64(now.Unix()-3600)))
+	if strings.Count(body, "proxmoxdash_guest_backup_age_seconds{") != 1 {
+		t.Errorf("Expected a single backup age series, got:\n%s", body)
+	}
+	// Snapshots : nombre et âge du plus ancien (3 jours)
+	assertContains(t, body, `proxmoxdash_guest_snapshots{cluster="prod",vmid="100",type="qemu"} 2`)
+	if !strings.Contains(body, `proxmoxdash_guest_oldest_snapshot_age_seconds{cluster="prod",vmid="100",type="qemu"} 2592`) {
+		t.Errorf("Expected the age of the oldest snapshot, got:\n%s", body)
+	}
+}
+
+func TestExporter_CountsNewTaskFailures(t *testing.T) {
+	exp, _ := setupTestExporter(t)
+	base := time.Now().Add(-time.Minute)
+	done := base.Add(30 * time.Second)
+	task := func(id, status string, completed *time.Time) models.ProxmoxTask {
+		return models.ProxmoxTask{ID: id, Cluster: "prod", Node: "pve1", Type: "vzdump", Status: status, CompletedAt: completed}
+	}
+	old := base.Add(-time.Hour)
+
+	first := &models.ProxmoxSnapshot{CollectedAt: base, ProxmoxInventory: models.ProxmoxInventory{
+		Tasks: []models.ProxmoxTask{task("UPID:1", "failed", &old), task("UPID:2", "running", nil)},
+	}}
+	second := &models.ProxmoxSnapshot{CollectedAt: done, ProxmoxInventory: models.ProxmoxInventory{
+		Tasks: []models.ProxmoxTask{task("UPID:1", "failed", &old), task("UPID:2", "failed", &done), task("UPID:3", "failed", &done), task("UPID:0", "failed", &old)},
+	}}
+
+	exp.ObserveSnapshot(nil, first) // référence : l'échec antérieur n'est pas compté
+	exp.ObserveSnapshot(first, second)
+	exp.ObserveSnapshot(second, second) // déjà comptées
+
+	assertContains(t, scrape(t, exp),
+		"# TYPE proxmoxdash_task_failures_total counter",
+		`proxmoxdash_task_failures_total{cluster="prod",node="pve1",type="vzdump"} 2`,
+	)
+}
+
+func TestExporter_AppHealthAndHTTPMetrics(t *testing.T) {
+	exp, s := setupTestExporter(t)
+	app := &models.App{Name: "Grafana", Protocol: "http", Host: "10.0.0.5", Port: 3000, HealthPath: "/api/health", HealthType: "http"}
+	if err := s.CreateApp(app); err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	latency := int64(120)
+	exp.ObserveAppHealth(&models.HealthStatus{AppID: app.ID, Status: "online", Latency: &latency, LastCheck: time.Now()})
+	exp.ObserveAppHealth(&models.HealthStatus{AppID: 999, Status: "offline", LastCheck: time.Now()}) // application inconnue
+
+	router := chi.NewRouter()
+	router.Use(exp.Middleware)
+	router.Get("/api/v1/alerts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	for _, path := range []string{"/api/v1/alerts/1", "/api/v1/alerts/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t, exp)
+	assertContains(t, body,
+		`proxmoxdash_app_up{app_id="1",app="Grafana"} 1`,
+		`proxmoxdash_app_health_latency_seconds{app_id="1",app="Grafana"} 0.12`,
+		`proxmoxdash_http_requests_total{method="GET",route="/api/v1/alerts/{id}",code="404"} 2`,
+		`proxmoxdash_http_request_duration_seconds_bucket{method="GET",route="/api/v1/alerts/{id}",le="+Inf"} 2`,
+		`proxmoxdash_http_request_duration_seconds_count{method="GET",route="/api/v1/alerts/{id}"} 2`,
+	)
+	if strings.Contains(body, `app_id="999"`) {
+		t.Errorf("Expected unknown apps to be ignored, got:\n%s", body)
+	}
+	if strings.Contains(body, "proxmoxdash_node_up") {
+		t.Errorf("Expected no inventory metrics without a snapshot source, got:\n%s", body)
+	}
+}
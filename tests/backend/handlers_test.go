@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -343,3 +344,42 @@ func TestHandlers_SubscribeNotification(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
 	}
 }
+
+func TestWriteComplianceCSV_PartialReport(t *testing.T) {
+	report := &models.BackupComplianceReport{
+		Warnings: []string{"cluster lab: VMs of node pve2 could not be listed"},
+		Guests:   []models.BackupCompliance{{Cluster: "lab", VMID: 101, Name: "db", Type: "qemu", Node: "pve2", Violation: models.BackupViolationUnknown}},
+	}
+	w := httptest.NewRecorder()
+	writeComplianceCSV(w, report)
+
+	// La complétude est portée par les en-têtes : le corps reste un CSV valide
+	if w.Header().Get("X-Report-Complete") != "false" || w.Header().Get("X-Report-Warning") != "cluster lab: VMs of node pve2 could not be listed" {
+		t.Errorf("Unexpected report headers %v", w.Header())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(records) != 2 || records[0][0] != "cluster" || records[1][len(records[1])-1] != "unknown" {
+		t.Errorf("Unexpected CSV export %q (%v)", records, err)
+	}
+}
+
+func TestHandlers_IsDevProxmoxURL(t *testing.T) {
+	h := setupTestHandlers()
+	for u, want := range map[string]bool{
+		"https://pve.example.com:8006":      true,
+		"https://proxmox-dev.local:8006":    true,
+		"https://notexample.com:8006":       false,
+		"https://127.0.0.1:8006":            false, // Proxmox local ou tunnel SSH
+		"https://localhost:8006":            false,
+		"https://pve.lab.internal:8006/api": false,
+	} {
+		if got := h.isDevProxmoxURL(u); got != want {
+			t.Errorf("isDevProxmoxURL(%q) = %v, want %v", u, got, want)
+		}
+	}
+
+	h.SetDevProxmoxHosts([]string{"localhost"})
+	if !h.isDevProxmoxURL("https://localhost:8006") || h.isDevProxmoxURL("https://pve.example.com:8006") {
+		t.Errorf("Expected the configured hosts to replace the defaults")
+	}
+}
@@ -0,0 +1,305 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/proxmox"
+)
+
+// setupFakeCluster démarre un faux serveur Proxmox exposant un nœud, une VM et un storage
+func setupFakeCluster(t *testing.T, node string) *proxmox.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/resources":
+			fmt.Fprintf(w, `{"data":[{"type":"node","node":%q,"status":"online"},{"type":"qemu","node":%q,"vmid":100,"name":"vm-%s","status":"stopped"}]}`, node, node, node)
+		case "/api2/json/nodes":
+			fmt.Fprintf(w, `{"data":[{"node":%q,"status":"online"}]}`, node)
+		case "/api2/json/nodes/" + node + "/qemu":
+			fmt.Fprintf(w, `{"data":[{"vmid":100,"name":"vm-%s","status":"stopped"}]}`, node)
+		case "/api2/json/nodes/" + node + "/qemu/100/snapshot":
+			fmt.Fprint(w, `{"data":[{"name":"before-upgrade","snaptime":1700000000,"vmstate":1,"description":"apt"},{"name":"current","parent":"before-upgrade","running":0}]}`)
+		case "/api2/json/nodes/" + node + "/storage":
+			fmt.Fprint(w, `{"data":[{"storage":"local","type":"dir","total":100,"used":50,"avail":50,"active":1,"enabled":1}]}`)
+		default:
+			fmt.Fprint(w, `{"data":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return proxmox.NewClient(server.URL, "root@pam!dashboard", "secret").WithHTTPClient(server.Client())
+}
+
+func TestCollectAll_MergesClustersAndReportsErrors(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(down.Close)
+
+	clusters := []Cluster{
+		{ConnectionID: 1, Name: "paris", Client: setupFakeCluster(t, "pve-paris")},
+		{ConnectionID: 2, Name: "broken", Client: proxmox.NewClient(down.URL, "root@pam!dashboard", "bad")},
+		{ConnectionID: 3, Name: "lyon", Client: setupFakeCluster(t, "pve-lyon")},
+	}
+
+	inv := CollectAll(context.Background(), clusters, Content{})
+
+	if len(inv.Clusters) != 3 {
+		t.Fatalf("Expected 3 cluster statuses, got %d", len(inv.Clusters))
+	}
+	if !inv.Clusters[0].Success || inv.Clusters[1].Success || !inv.Clusters[2].Success {
+		t.Errorf("Unexpected cluster statuses %+v", inv.Clusters)
+	}
+	if inv.Clusters[1].Error == "" {
+		t.Error("Expected an error for the broken cluster")
+	}
+
+	if len(inv.Nodes) != 2 || inv.Nodes[0].Cluster != "paris" || inv.Nodes[1].Cluster != "lyon" {
+		t.Errorf("Unexpected nodes %+v", inv.Nodes)
+	}
+	if len(inv.VMs) != 2 || inv.VMs[0].Cluster != "paris" || inv.VMs[1].Name != "vm-pve-lyon" {
+		t.Errorf("Unexpected VMs %+v", inv.VMs)
+	}
+	if len(inv.Storages) != 2 || inv.Storages[1].Cluster != "lyon" {
+		t.Errorf("Unexpected storages %+v", inv.Storages)
+	}
+	if inv.LXC == nil {
+		t.Error("Expected an empty LXC list, got nil")
+	}
+	// L'état courant n'est pas un snapshot
+	if len(inv.GuestSnapshots) != 2 || inv.GuestSnapshots[1].Cluster != "lyon" || inv.GuestSnapshots[0].Name != "before-upgrade" ||
+		!inv.GuestSnapshots[0].VMState || inv.GuestSnapshots[0].CreatedAt.Unix() != 1700000000 || inv.GuestSnapshots[0].GuestName != "vm-pve-paris" {
+		t.Errorf("Unexpected guest snapshots %+v", inv.GuestSnapshots)
+	}
+}
+
+func TestDiff_EmitsInventoryEvents(t *testing.T) {
+	start := time.Now()
+	finished := start.Add(30 * time.Second)
+	clusters := []models.ProxmoxClusterStatus{{Name: "paris", Success: true}, {Name: "lyon", Success: true}}
+
+	prev := &models.ProxmoxSnapshot{
+		CollectedAt: start,
+		ProxmoxInventory: models.ProxmoxInventory{
+			Clusters: clusters,
+			Nodes:    []models.ProxmoxNode{{ID: "pve1", Name: "pve1", Cluster: "paris", Status: "online"}},
+			VMs: []models.ProxmoxGuest{
+				{VMID: 100, Name: "web", Cluster: "paris", Status: "running"},
+				{VMID: 101, Name: "old", Cluster: "paris", Status: "stopped"},
+			},
+			LXC:      []models.ProxmoxGuest{{VMID: 200, Name: "dns", Cluster: "lyon", Status: "running"}},
+			Storages: []models.ProxmoxStorage{{ID: "local", Cluster: "paris", UsagePercent: 80}},
+			Tasks:    []models.ProxmoxTask{{ID: "UPID:1", Cluster: "paris", Status: "running"}},
+		},
+	}
+	next := &models.ProxmoxSnapshot{
+		CollectedAt: start.Add(time.Minute),
+		ProxmoxInventory: models.ProxmoxInventory{
+			Clusters: []models.ProxmoxClusterStatus{{Name: "paris", Success: true}, {Name: "lyon", Success: false}},
+			Nodes:    []models.ProxmoxNode{{ID: "pve1", Name: "pve1", Cluster: "paris", Status: "offline"}},
+			VMs: []models.ProxmoxGuest{
+				{VMID: 100, Name: "web", Cluster: "paris", Status: "stopped"},
+				{VMID: 102, Name: "new", Cluster: "paris", Status: "running"},
+			},
+			Storages: []models.ProxmoxStorage{{ID: "local", Cluster: "paris", UsagePercent: 91}},
+			Tasks:    []models.ProxmoxTask{{ID: "UPID:1", Cluster: "paris", Status: "completed", CompletedAt: &finished}},
+		},
+	}
+
+	events := Diff(prev, next, 85)
+
+	got := make(map[string]int)
+	for _, e := range events {
+		got[e.Type]++
+	}
+	want := map[string]int{
+		models.EventNodeOffline:      1,
+		models.EventVMStatusChanged:  1,
+		models.EventVMCreated:        1,
+		models.EventVMDeleted:        1,
+		models.EventStorageThreshold: 1,
+		models.EventTaskFinished:     1,
+	}
+	for eventType, count := range want {
+		if got[eventType] != count {
+			t.Errorf("Expected %d %s event(s), got %d", count, eventType, got[eventType])
+		}
+	}
+	// Le cluster lyon est injoignable : son LXC ne doit pas être signalé comme supprimé
+	if got[models.EventLXCDeleted] != 0 {
+		t.Errorf("Unexpected lxc.deleted event for an unreachable cluster")
+	}
+
+	for _, e := range events {
+		if change, ok := e.Data.(models.StatusChangeEvent); ok && e.Type == models.EventVMStatusChanged {
+			if change.Before != "running" || change.After != "stopped" || change.Cluster != "paris" {
+				t.Errorf("Unexpected status change %+v", change)
+			}
+		}
+	}
+
+	if events := Diff(nil, next, 85); len(events) != 0 {
+		t.Errorf("Expected no events without a previous snapshot, got %d", len(events))
+	}
+}
+
+func TestCollectAll_ReportsFailedGuestNodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/resources":
+			fmt.Fprint(w, `{"data":[{"type":"node","node":"pve1","status":"online"},{"type":"node","node":"pve2","status":"offline"}]}`)
+		case "/api2/json/nodes":
+			fmt.Fprint(w, `{"data":[{"node":"pve1","status":"online"},{"node":"pve2","status":"offline"}]}`)
+		case "/api2/json/nodes/pve1/qemu":
+			fmt.Fprint(w, `{"data":[{"vmid":100,"name":"web","status":"running"}]}`)
+		case "/api2/json/nodes/pve2/qemu", "/api2/json/nodes/pve2/lxc":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `{"data":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := proxmox.NewClient(server.URL, "root@pam!dashboard", "secret").WithHTTPClient(server.Client())
+
+	inv := CollectAll(context.Background(), []Cluster{{ConnectionID: 1, Name: "paris", Client: client}}, Content{})
+
+	status := inv.Clusters[0]
+	if !status.Success || len(inv.VMs) != 1 {
+		t.Fatalf("Expected a partial collection, got %+v with %d VMs", status, len(inv.VMs))
+	}
+	if len(status.FailedVMNodes) != 1 || status.FailedVMNodes[0] != "pve2" || len(status.FailedLXCNodes) != 1 {
+		t.Errorf("Expected pve2 to be reported as failed, got %+v", status)
+	}
+	if len(status.Warnings) == 0 {
+		t.Error("Expected a warning for the unreachable node")
+	}
+
+	// Sans liste des nœuds, aucun invité n'a pu être lu : tous les nœuds sont en échec
+	noGuests := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api2/json/cluster/resources" {
+			fmt.Fprint(w, `{"data":[{"type":"node","node":"pve1","status":"online"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(noGuests.Close)
+	client = proxmox.NewClient(noGuests.URL, "root@pam!dashboard", "secret").WithHTTPClient(noGuests.Client())
+
+	inv = CollectAll(context.Background(), []Cluster{{ConnectionID: 1, Name: "paris", Client: client}}, Content{})
+	status = inv.Clusters[0]
+	if len(status.FailedVMNodes) != 1 || status.FailedVMNodes[0] != "pve1" || len(status.FailedLXCNodes) != 1 {
+		t.Errorf("Expected every node to be reported as failed, got %+v", status)
+	}
+}
+
+func TestDiff_IgnoresGuestsOfFailedNodes(t *testing.T) {
+	start := time.Now()
+	ok := []models.ProxmoxClusterStatus{{Name: "paris", Success: true}}
+	failed := []models.ProxmoxClusterStatus{{Name: "paris", Success: true, FailedVMNodes: []string{"pve2"}}}
+	guests := []models.ProxmoxGuest{
+		{VMID: 100, Name: "web", Cluster: "paris", Node: "pve1", Status: "running"},
+		{VMID: 101, Name: "db", Cluster: "paris", Node: "pve2", Status: "running"},
+	}
+	lxc := []models.ProxmoxGuest{{VMID: 200, Name: "dns", Cluster: "paris", Node: "pve2", Status: "running"}}
+
+	full := &models.ProxmoxSnapshot{CollectedAt: start, ProxmoxInventory: models.ProxmoxInventory{Clusters: ok, VMs: guests, LXC: lxc}}
+	partial := &models.ProxmoxSnapshot{
+		CollectedAt:      start.Add(time.Minute),
+		ProxmoxInventory: models.ProxmoxInventory{Clusters: failed, VMs: guests[:1], LXC: nil},
+	}
+	recovered := &models.ProxmoxSnapshot{CollectedAt: start.Add(2 * time.Minute), ProxmoxInventory: full.ProxmoxInventory}
+
+	// Les VMs de pve2 n'ont pas pu être listées : pas de suppression ; les LXC l'ont été
+	events := Diff(full, partial, 0)
+	if len(events) != 1 || events[0].Type != models.EventLXCDeleted {
+		t.Errorf("Expected only an lxc.deleted event, got %+v", events)
+	}
+	// Au retour du nœud, ses VMs ne sont pas signalées comme créées
+	events = Diff(partial, recovered, 0)
+	if len(events) != 1 || events[0].Type != models.EventLXCCreated {
+		t.Errorf("Expected only an lxc.created event, got %+v", events)
+	}
+}
+
+func TestCollectAll_ContentSchedule(t *testing.T) {
+	var contentCalls, snapshotCalls atomic.Int32
+	var snapshotsFail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/resources":
+			fmt.Fprint(w, `{"data":[{"type":"node","node":"pve1","status":"online"},{"type":"node","node":"pve2","status":"online"}]}`)
+		case "/api2/json/nodes":
+			fmt.Fprint(w, `{"data":[{"node":"pve1","status":"online"},{"node":"pve2","status":"online"}]}`)
+		case "/api2/json/nodes/pve1/qemu":
+			fmt.Fprint(w, `{"data":[{"vmid":100,"name":"web","status":"stopped"}]}`)
+		case "/api2/json/nodes/pve1/qemu/100/snapshot":
+			snapshotCalls.Add(1)
+			if snapshotsFail.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, `{"data":[{"name":"base","snaptime":1700000000},{"name":"current","parent":"base"}]}`)
+		case "/api2/json/nodes/pve1/storage", "/api2/json/nodes/pve2/storage":
+			fmt.Fprint(w, `{"data":[{"storage":"nas","content":"backup","active":1,"enabled":1,"shared":1}]}`)
+		case "/api2/json/nodes/pve1/storage/nas/content":
+			// Le storage partagé est illisible depuis pve1 : il est relu depuis pve2
+			contentCalls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/api2/json/nodes/pve2/storage/nas/content":
+			contentCalls.Add(1)
+			fmt.Fprint(w, `{"data":[{"volid":"nas:backup/vzdump-qemu-100-2024_01_01-00_00_00.vma.zst","size":1024,"ctime":1704067200,"subtype":"qemu","vmid":100}]}`)
+		default:
+			fmt.Fprint(w, `{"data":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	clusters := []Cluster{{ConnectionID: 1, Name: "paris", Client: proxmox.NewClient(server.URL, "root@pam!dashboard", "secret").WithHTTPClient(server.Client())}}
+
+	content := Content{Interval: time.Hour, Concurrency: 2}
+	first := CollectAll(context.Background(), clusters, content)
+	status := first.Clusters[0]
+	if len(first.Backups) != 1 || first.Backups[0].Node != "pve2" || len(status.FailedBackupStorages) != 0 || status.ContentCollectedAt == nil {
+		t.Fatalf("Expected the shared storage to be read from pve2, got %+v (status %+v)", first.Backups, status)
+	}
+	if len(first.GuestSnapshots) != 1 || snapshotCalls.Load() != 1 || contentCalls.Load() != 2 {
+		t.Fatalf("Unexpected first content collection: %d snapshots, %d/%d calls", len(first.GuestSnapshots), snapshotCalls.Load(), contentCalls.Load())
+	}
+
+	// Avant l'intervalle, le contenu est repris de la collecte précédente
+	content.Previous = &models.ProxmoxSnapshot{ProxmoxInventory: *first, CollectedAt: status.CollectedAt}
+	second := CollectAll(context.Background(), clusters, content)
+	if len(second.Backups) != 1 || len(second.GuestSnapshots) != 1 || snapshotCalls.Load() != 1 || contentCalls.Load() != 2 {
+		t.Errorf("Expected the content to be carried forward, got %d backups, %d snapshots, %d/%d calls",
+			len(second.Backups), len(second.GuestSnapshots), snapshotCalls.Load(), contentCalls.Load())
+	}
+	if !second.Clusters[0].ContentCollectedAt.Equal(*status.ContentCollectedAt) {
+		t.Errorf("Expected the previous content date, got %v", second.Clusters[0].ContentCollectedAt)
+	}
+
+	// Un intervalle nul relit le contenu à chaque collecte
+	content.Interval = 0
+	CollectAll(context.Background(), clusters, content)
+	if snapshotCalls.Load() != 2 {
+		t.Errorf("Expected the snapshots to be read again, got %d calls", snapshotCalls.Load())
+	}
+
+	// Les invités dont les snapshots sont illisibles sont reportés, et repris avec le contenu
+	snapshotsFail.Store(true)
+	failed := CollectAll(context.Background(), clusters, content)
+	if status := failed.Clusters[0]; len(failed.GuestSnapshots) != 0 || !status.SnapshotsFailed("qemu", 100) ||
+		!slices.ContainsFunc(status.Warnings, func(w string) bool { return strings.HasPrefix(w, "snapshots: ") }) {
+		t.Errorf("Expected VM 100 to be reported with unreadable snapshots, got %+v", status)
+	}
+	content.Interval = time.Hour
+	content.Previous = &models.ProxmoxSnapshot{ProxmoxInventory: *failed, CollectedAt: failed.Clusters[0].CollectedAt}
+	if carried := CollectAll(context.Background(), clusters, content); !carried.Clusters[0].SnapshotsFailed("qemu", 100) {
+		t.Errorf("Expected the unreadable snapshots to be carried forward, got %+v", carried.Clusters[0])
+	}
+}
@@ -0,0 +1,381 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/config"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/secrets"
+	"proxmox-dashboard/internal/store"
+
+	_ "modernc.org/sqlite"
+)
+
+// webhookReceiver enregistre les requêtes reçues et répond avec les codes configurés
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int // codes retournés successivement, le dernier est répété
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	status := rcv.statuses[0]
+	if len(rcv.statuses) > 1 {
+		rcv.statuses = rcv.statuses[1:]
+	}
+	rcv.mu.Unlock()
+	w.WriteHeader(status)
+	w.Write([]byte("receiver says hi"))
+}
+
+func setupNotifyStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := store.NewStore(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	s.SetCipher(cipher)
+	return s
+}
+
+func createWebhook(t *testing.T, s *store.Store, endpoint, secret string) *models.NotifySubscription {
+	t.Helper()
+	sub := &models.NotifySubscription{Channel: models.ChannelWebhook, Endpoint: endpoint, Secret: secret, Enabled: true, CreatedAt: time.Now()}
+	if err := s.CreateNotificationSubscription(sub); err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	return sub
+}
+
+func TestDispatcher_SignsAndRetriesWithSameDeliveryID(t *testing.T) {
+	s := setupNotifyStore(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := createWebhook(t, s, server.URL, "s3cret")
+	d := NewDispatcher(s, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, RetryBackoff: time.Millisecond, DisableAfter: 10})
+
+	alert := &models.Alert{ID: 42, Source: "prometheus", Severity: "critical", Title: "Node down", CreatedAt: time.Now()}
+	if err := d.NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert failed: %v", err)
+	}
+	d.ProcessQueue()
+	d.ProcessQueue()
+
+	if len(receiver.requests) != 2 {
+		t.Fatalf("Expected 2 webhook requests, got %d", len(receiver.requests))
+	}
+	for i, req := range receiver.requests {
+		if got, want := req.Header.Get(HeaderSignature), Sign("s3cret", receiver.bodies[i]); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("Request %d: signature %q, expected %q", i, got, want)
+		}
+		if req.Header.Get(HeaderEvent) != models.WebhookEventAlertCreated {
+			t.Errorf("Request %d: unexpected event header %q", i, req.Header.Get(HeaderEvent))
+		}
+	}
+	if receiver.requests[0].Header.Get(HeaderDelivery) != receiver.requests[1].Header.Get(HeaderDelivery) {
+		t.Error("Expected retries to share the delivery ID")
+	}
+	if receiver.requests[1].Header.Get(HeaderAttempt) != "2" {
+		t.Errorf("Expected attempt 2, got %q", receiver.requests[1].Header.Get(HeaderAttempt))
+	}
+
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(receiver.bodies[1], &payload); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if payload.Alert == nil || payload.Alert.ID != 42 || payload.DeliveryID != receiver.requests[1].Header.Get(HeaderDelivery) {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	deliveries, err := s.GetWebhookDeliveries(sub.ID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 delivery attempts, got %d", len(deliveries))
+	}
+	if deliveries[0].State != models.DeliveryStateDelivered || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected last attempt delivered with 200, got %s/%d", deliveries[0].State, deliveries[0].StatusCode)
+	}
+	if deliveries[1].State != models.DeliveryStateFailed || deliveries[1].StatusCode != http.StatusInternalServerError ||
+		deliveries[1].Response != "receiver says hi" {
+		t.Errorf("Unexpected first attempt: %+v", deliveries[1])
+	}
+
+	updated, err := s.GetNotificationSubscription(sub.ID)
+	if err != nil {
+		t.Fatalf("GetNotificationSubscription failed: %v", err)
+	}
+	if updated.FailureCount != 0 || !updated.Enabled {
+		t.Errorf("Expected failure count reset after success, got %+v", updated)
+	}
+}
+
+func TestDispatcher_DisablesSubscriptionAfterConsecutiveFailures(t *testing.T) {
+	s := setupNotifyStore(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := createWebhook(t, s, server.URL, "s3cret")
+	d := NewDispatcher(s, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 5, RetryBackoff: time.Millisecond, DisableAfter: 2})
+
+	if err := d.NotifyAlert(&models.Alert{ID: 1, Source: "test", Severity: "warning", Title: "Disk full", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("NotifyAlert failed: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		d.ProcessQueue()
+	}
+
+	if len(receiver.requests) != 2 {
+		t.Errorf("Expected delivery to stop after 2 failures, got %d requests", len(receiver.requests))
+	}
+	updated, err := s.GetNotificationSubscription(sub.ID)
+	if err != nil {
+		t.Fatalf("GetNotificationSubscription failed: %v", err)
+	}
+	if updated.Enabled || updated.DisabledAt == nil || updated.FailureCount != 2 {
+		t.Errorf("Expected subscription auto-disabled, got %+v", updated)
+	}
+
+	cancelled, err := s.GetWebhookDeliveries(sub.ID, models.DeliveryStateCancelled, 10, 0)
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveries failed: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].Attempt != 3 {
+		t.Errorf("Expected the third attempt to be cancelled, got %+v", cancelled)
+	}
+
+	// La réactivation remet le compteur à zéro
+	if err := s.SetSubscriptionEnabled(sub.ID, true); err != nil {
+		t.Fatalf("SetSubscriptionEnabled failed: %v", err)
+	}
+	if updated, _ = s.GetNotificationSubscription(sub.ID); !updated.Enabled || updated.FailureCount != 0 || updated.DisabledAt != nil {
+		t.Errorf("Expected subscription re-enabled, got %+v", updated)
+	}
+}
+
+func TestDispatcher_ChatChannelFormats(t *testing.T) {
+	s := setupNotifyStore(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	subs := []*models.NotifySubscription{
+		{Channel: models.ChannelSlack, Endpoint: server.URL + "/slack"},
+		{Channel: models.ChannelMattermost, Endpoint: server.URL + "/mattermost/hooks/abc"},
+		{Channel: models.ChannelDiscord, Endpoint: server.URL + "/discord"},
+		{Channel: models.ChannelTeams, Endpoint: server.URL + "/teams"},
+		{Channel: models.ChannelGotify, Endpoint: server.URL + "/gotify", Secret: "app-token"},
+		{Channel: models.ChannelNtfy, Endpoint: server.URL + "/ntfy/proxmox-alerts", Secret: "tk_access"},
+	}
+	for _, sub := range subs {
+		sub.Enabled = true
+		sub.CreatedAt = time.Now()
+		if err := s.CreateNotificationSubscription(sub); err != nil {
+			t.Fatalf("Failed to create %s subscription: %v", sub.Channel, err)
+		}
+	}
+
+	d := NewDispatcher(s, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	d.SetPublicURL("https://dash.example.com/")
+	alert := &models.Alert{ID: 42, Source: "node:pve1", Severity: "critical", Title: "Nœud hors ligne",
+		Message: "pve1 <ne répond plus> & ping KO", CreatedAt: time.Now()}
+	if err := d.NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert failed: %v", err)
+	}
+	d.ProcessQueue()
+
+	const ackURL = "https://dash.example.com/?alert=42"
+	bodies := map[string]map[string]interface{}{}
+	headers := map[string]http.Header{}
+	for i, req := range receiver.requests {
+		var body map[string]interface{}
+		if err := json.Unmarshal(receiver.bodies[i], &body); err != nil {
+			t.Fatalf("Invalid JSON sent to %s: %v", req.URL.Path, err)
+		}
+		bodies[req.URL.Path] = body
+		headers[req.URL.Path] = req.Header
+	}
+	if len(bodies) != len(subs) {
+		t.Fatalf("Expected %d requests, got paths %v", len(subs), bodies)
+	}
+
+	slack := bodies["/slack"]["attachments"].([]interface{})[0].(map[string]interface{})
+	if slack["title_link"] != ackURL || !strings.Contains(slack["text"].(string), "&lt;ne répond plus&gt; &amp; ping KO") ||
+		!strings.Contains(slack["text"].(string), "<"+ackURL+"|Accuser réception>") {
+		t.Errorf("Unexpected Slack attachment: %v", slack)
+	}
+
+	mattermost := bodies["/mattermost/hooks/abc"]["attachments"].([]interface{})[0].(map[string]interface{})
+	if !strings.Contains(mattermost["text"].(string), "[Accuser réception]("+ackURL+")") {
+		t.Errorf("Expected a Markdown ack link for Mattermost, got %v", mattermost["text"])
+	}
+
+	discord := bodies["/discord"]["embeds"].([]interface{})[0].(map[string]interface{})
+	if discord["url"] != ackURL || discord["color"] != float64(0xc62828) || discord["title"] != "[CRITICAL] Nœud hors ligne" {
+		t.Errorf("Unexpected Discord embed: %v", discord)
+	}
+
+	teams := bodies["/teams"]["attachments"].([]interface{})[0].(map[string]interface{})
+	card := teams["content"].(map[string]interface{})
+	action := card["actions"].([]interface{})[0].(map[string]interface{})
+	if teams["contentType"] != "application/vnd.microsoft.card.adaptive" || action["url"] != ackURL {
+		t.Errorf("Unexpected Teams card: %v", teams)
+	}
+
+	gotify := bodies["/gotify/message"]
+	if gotify == nil || headers["/gotify/message"].Get("X-Gotify-Key") != "app-token" || gotify["priority"] != float64(8) {
+		t.Errorf("Unexpected Gotify message: %v", gotify)
+	}
+
+	ntfy := bodies["/ntfy/"]
+	if ntfy == nil || ntfy["topic"] != "proxmox-alerts" || ntfy["priority"] != float64(5) || ntfy["click"] != ackURL ||
+		headers["/ntfy/"].Get("Authorization") != "Bearer tk_access" {
+		t.Errorf("Unexpected ntfy message: %v", ntfy)
+	}
+}
+
+func TestDispatcher_RoutesAndGroupsAlerts(t *testing.T) {
+	s := setupNotifyStore(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	critical := createWebhook(t, s, server.URL+"/critical", "")
+	createWebhook(t, s, server.URL+"/unrouted", "")
+
+	routes := []*models.NotifyRoute{
+		{Name: "critical", Position: 1, Severities: []string{"critical"}, SubscriptionIDs: []int{critical.ID},
+			GroupBy: []string{"cluster"}, Enabled: true},
+		{Name: "info-sse", Position: 2, Severities: []string{"low"}, Enabled: true},
+	}
+	for _, route := range routes {
+		if err := s.CreateNotifyRoute(route); err != nil {
+			t.Fatalf("Failed to create route: %v", err)
+		}
+	}
+
+	var alerts []*models.Alert
+	for i, severity := range []string{"critical", "critical", "low"} {
+		alert := &models.Alert{Source: "rule:node-down", Severity: severity, Title: fmt.Sprintf("Alerte %d", i),
+			Labels: map[string]string{"cluster": "lab"}, CreatedAt: time.Now()}
+		if err := s.CreateAlert(alert); err != nil {
+			t.Fatalf("Failed to create alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	d := NewDispatcher(s, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	for _, alert := range alerts {
+		if err := d.NotifyAlert(alert); err != nil {
+			t.Fatalf("NotifyAlert failed: %v", err)
+		}
+	}
+	d.ProcessQueue()
+
+	if len(receiver.requests) != 1 || receiver.requests[0].URL.Path != "/critical" {
+		t.Fatalf("Expected a single grouped notification to the critical route, got %d requests", len(receiver.requests))
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if payload.Event != models.WebhookEventAlertGroup || len(payload.Alerts) != 2 || payload.Group == nil ||
+		payload.Group.Key != "cluster=lab" || payload.Group.RouteName != "critical" {
+		t.Errorf("Unexpected grouped payload: %+v", payload)
+	}
+
+	// Sans changement, le groupe n'est pas renotifié (pas de repeat_interval)
+	d.ProcessQueue()
+	if len(receiver.requests) != 1 {
+		t.Fatalf("Expected no new notification, got %d requests", len(receiver.requests))
+	}
+
+	// La résolution d'une alerte renotifie le groupe avec l'alerte résolue et celle encore ouverte
+	if err := s.ResolveAlert(alerts[0], "", time.Now()); err != nil {
+		t.Fatalf("ResolveAlert failed: %v", err)
+	}
+	if err := d.NotifyAlert(alerts[0]); err != nil {
+		t.Fatalf("NotifyAlert failed: %v", err)
+	}
+	d.ProcessQueue()
+	if len(receiver.requests) != 2 {
+		t.Fatalf("Expected the group to be notified again, got %d requests", len(receiver.requests))
+	}
+	payload = models.WebhookPayload{}
+	json.Unmarshal(receiver.bodies[1], &payload)
+	if len(payload.Alerts) != 2 || payload.Alerts[0].Status != models.AlertStatusResolved {
+		t.Errorf("Expected the resolved alert in the group notification, got %+v", payload.Alerts)
+	}
+
+	groups, err := s.GetAlertGroups()
+	if err != nil {
+		t.Fatalf("GetAlertGroups failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Alerts) != 1 || groups[0].Alerts[0].ID != alerts[1].ID {
+		t.Errorf("Expected only the open alert to remain in the group, got %+v", groups)
+	}
+}
+
+func TestDispatcher_QuietHoursPostponeGroup(t *testing.T) {
+	s := setupNotifyStore(t)
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := createWebhook(t, s, server.URL, "")
+	// Plage couvrant toute la journée sauf la minute précédant minuit
+	route := &models.NotifyRoute{Name: "night", SubscriptionIDs: []int{sub.ID}, Enabled: true,
+		QuietHours: &models.QuietHours{Start: "00:00", End: "23:59", Timezone: "UTC"}}
+	if err := s.CreateNotifyRoute(route); err != nil {
+		t.Fatalf("Failed to create route: %v", err)
+	}
+	alert := &models.Alert{Source: "node:pve1", Severity: "high", Title: "Disque plein", CreatedAt: time.Now()}
+	if err := s.CreateAlert(alert); err != nil {
+		t.Fatalf("Failed to create alert: %v", err)
+	}
+
+	d := NewDispatcher(s, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	if err := d.NotifyAlert(alert); err != nil {
+		t.Fatalf("NotifyAlert failed: %v", err)
+	}
+	// Demain à midi (UTC), pendant la plage
+	d.flushGroups(time.Now().UTC().Truncate(24 * time.Hour).Add(36 * time.Hour))
+
+	groups, err := s.GetAlertGroups()
+	if err != nil {
+		t.Fatalf("GetAlertGroups failed: %v", err)
+	}
+	if len(receiver.requests) != 0 || len(groups) != 1 || groups[0].FlushAt == nil || groups[0].FlushAt.UTC().Format("15:04") != "23:59" {
+		t.Errorf("Expected the group to be postponed to the end of quiet hours, got %d requests and %+v", len(receiver.requests), groups)
+	}
+}
@@ -0,0 +1,300 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func setupTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/", "root@pam!dashboard", "secret").WithHTTPClient(server.Client())
+}
+
+func TestClient_DecodesEnvelope(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/nodes/pve1/lxc" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "PVEAPIToken=root@pam!dashboard=secret" {
+			t.Errorf("Unexpected Authorization header %q", got)
+		}
+		fmt.Fprint(w, `{"data":[{"vmid":"101","name":"web","status":"running","template":0,"maxmem":1024}]}`)
+	})
+
+	guests, err := client.Guests(context.Background(), "pve1", GuestLXC)
+	if err != nil {
+		t.Fatalf("Guests failed: %v", err)
+	}
+	if len(guests) != 1 {
+		t.Fatalf("Expected 1 guest, got %d", len(guests))
+	}
+	if guests[0].VMID != 101 || guests[0].Name != "web" || guests[0].MaxMem != 1024 || guests[0].Template {
+		t.Errorf("Unexpected guest %+v", guests[0])
+	}
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		want       error
+		wantStatus int
+	}{
+		{"auth", http.StatusUnauthorized, ErrAuthFailed, http.StatusUnauthorized},
+		{"permission", http.StatusForbidden, ErrPermissionDenied, http.StatusForbidden},
+		{"not found", http.StatusNotFound, ErrNotFound, http.StatusNotFound},
+		{"node offline", 596, ErrUnreachable, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"data":null}`)
+			})
+
+			_, err := client.Nodes(context.Background())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, err)
+			}
+			if got := HTTPStatus(err); got != tt.wantStatus {
+				t.Errorf("Expected HTTP status %d, got %d", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, err := NewClient(url, "root@pam!dashboard", "secret").Nodes(context.Background())
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("Expected ErrUnreachable, got %v", err)
+	}
+}
+
+func TestClient_GuestStatusActionReturnsUPID(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api2/json/nodes/pve1/qemu/100/status/start" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"data":"UPID:pve1:0000:0000:00000000:qmstart:100:root@pam:"}`)
+	})
+
+	upid, err := client.GuestStatusAction(context.Background(), "pve1", GuestQemu, 100, "start", nil)
+	if err != nil {
+		t.Fatalf("GuestStatusAction failed: %v", err)
+	}
+	if upid != "UPID:pve1:0000:0000:00000000:qmstart:100:root@pam:" {
+		t.Errorf("Unexpected UPID %q", upid)
+	}
+}
+
+func TestGuestConfig_ParseAndUpdateParams(t *testing.T) {
+	raw := map[string]interface{}{
+		"name":    "web",
+		"cores":   float64(2),
+		"memory":  "4096",
+		"balloon": float64(1024),
+		"onboot":  float64(1),
+		"tags":    "prod;web",
+		"net10":   "e1000=BC:24:11:00:00:02,bridge=vmbr1",
+		"net2":    "virtio=BC:24:11:00:00:01,bridge=vmbr0,firewall=1,tag=20",
+		"scsi0":   "local-lvm:vm-100-disk-0,iothread=1,size=32G",
+		"ide2":    "local:iso/debian.iso,media=cdrom",
+		"digest":  "0123456789abcdef",
+	}
+	cfg := ParseGuestConfig(GuestQemu, raw)
+	if cfg.Name != "web" || cfg.Cores != 2 || cfg.Sockets != 1 || cfg.Memory != 4096 || !cfg.OnBoot || cfg.Digest != "0123456789abcdef" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if cfg.Balloon == nil || *cfg.Balloon != 1024 || len(cfg.Tags) != 2 {
+		t.Errorf("Unexpected balloon or tags in %+v", cfg)
+	}
+	if len(cfg.Nets) != 2 || cfg.Nets[0].ID != "net2" || cfg.Nets[0].Model != "virtio" || cfg.Nets[0].MAC != "BC:24:11:00:00:01" ||
+		cfg.Nets[0].Bridge != "vmbr0" || !cfg.Nets[0].Firewall || cfg.Nets[0].Tag == nil || *cfg.Nets[0].Tag != 20 {
+		t.Errorf("Unexpected nets %+v", cfg.Nets)
+	}
+	if len(cfg.Disks) != 2 || cfg.Disks[1].ID != "scsi0" || cfg.Disks[1].Storage != "local-lvm" || cfg.Disks[1].Size != "32G" || cfg.Disks[0].Media != "cdrom" {
+		t.Errorf("Unexpected disks %+v", cfg.Disks)
+	}
+
+	cores, memory, model, bridge, empty := 4, 8192, "e1000", "vmbr2", ""
+	update := &GuestConfigUpdate{
+		Cores:       &cores,
+		Memory:      &memory,
+		Description: &empty,
+		Tags:        &[]string{"prod", "prod", "db"},
+		Nets:        map[string]NetUpdate{"net2": {Model: &model, Bridge: &bridge}},
+	}
+	params, err := update.Params(cfg)
+	if err != nil {
+		t.Fatalf("Params failed: %v", err)
+	}
+	want := "cores=4&delete=description&memory=8192&net2=e1000%3DBC%3A24%3A11%3A00%3A00%3A01%2Cbridge%3Dvmbr2%2Cfirewall%3D1%2Ctag%3D20&tags=prod%3Bdb"
+	if got := params.Encode(); got != want {
+		t.Errorf("Unexpected params\n got %s\nwant %s", got, want)
+	}
+
+	small := 512
+	invalid := []*GuestConfigUpdate{
+		{},
+		{Memory: &small}, // balloon (1024) > memory
+		{Swap: &small},   // swap réservé aux conteneurs
+		{Nets: map[string]NetUpdate{"net0": {Bridge: &bridge}}}, // interface inconnue
+		{Tags: &[]string{"bad tag!"}},
+	}
+	for i, u := range invalid {
+		if _, err := u.Params(cfg); err == nil {
+			t.Errorf("Expected update %d to be rejected", i)
+		}
+	}
+
+	lxc := ParseGuestConfig(GuestLXC, map[string]interface{}{
+		"hostname": "ct", "net0": "name=eth0,bridge=vmbr0,hwaddr=BC:24:11:00:00:03,ip=dhcp,type=veth",
+		"rootfs": "local-lvm:vm-200-disk-0,size=8G", "mp0": "local-lvm:vm-200-disk-1,mp=/data,size=16G",
+	})
+	if lxc.Name != "ct" || lxc.Cores != 0 || lxc.Memory != 512 || lxc.Swap == nil || *lxc.Swap != 512 || lxc.Sockets != 0 {
+		t.Errorf("Unexpected container config %+v", lxc)
+	}
+	if len(lxc.Disks) != 2 || lxc.Disks[0].ID != "mp0" || lxc.Disks[0].Mountpoint != "/data" || lxc.Nets[0].Name != "eth0" || lxc.Nets[0].MAC != "BC:24:11:00:00:03" {
+		t.Errorf("Unexpected container disks or nets %+v %+v", lxc.Disks, lxc.Nets)
+	}
+	if _, err := (&GuestConfigUpdate{Nets: map[string]NetUpdate{"net0": {Model: &model}}}).Params(lxc); err == nil {
+		t.Error("Expected a NIC model change to be rejected for a container")
+	}
+}
+
+func TestClient_UpdateGuestConfigDigestConflict(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api2/json/nodes/pve1/qemu/100/config" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"data":null,"message":"detected modified configuration - file changed by other user? Try again.\n"}`)
+	})
+
+	err := client.UpdateGuestConfig(context.Background(), "pve1", GuestQemu, 100, url.Values{"cores": {"2"}, "digest": {"stale"}})
+	if !errors.Is(err, ErrConfigChanged) {
+		t.Fatalf("Expected ErrConfigChanged, got %v", err)
+	}
+	if got := HTTPStatus(err); got != http.StatusConflict {
+		t.Errorf("Expected HTTP status 409, got %d", got)
+	}
+}
+
+func TestBuildSnapshotTree(t *testing.T) {
+	tree := BuildSnapshotTree([]Snapshot{
+		{Name: "current", Parent: "b", Running: true},
+		{Name: "b", Parent: "a", SnapTime: 300},
+		{Name: "a", SnapTime: 100, VMState: true},
+		{Name: "c", Parent: "a", SnapTime: 200},
+		{Name: "orphan", Parent: "deleted", SnapTime: 50},
+	})
+
+	if len(tree) != 2 || tree[0].Name != "orphan" || tree[1].Name != "a" {
+		t.Fatalf("Expected the orphan and a as roots sorted by date, got %+v", tree)
+	}
+	a := tree[1]
+	if !a.VMState || a.CreatedAt == nil || a.CreatedAt.Unix() != 100 || len(a.Children) != 2 || a.Children[0].Name != "c" || a.Children[1].Name != "b" {
+		t.Fatalf("Unexpected children of a: %+v", a)
+	}
+	if current := a.Children[1].Children; len(current) != 1 || !current[0].Current || current[0].CreatedAt != nil {
+		t.Errorf("Expected the current state under b, got %+v", current)
+	}
+
+	for name, valid := range map[string]bool{"pre-upgrade": true, "snap_1": true, "current": false, "1snap": false, "a": false, "with space": false} {
+		if err := ValidateSnapshotName(name); (err == nil) != valid {
+			t.Errorf("ValidateSnapshotName(%q) = %v, expected valid=%v", name, err, valid)
+		}
+	}
+}
+
+func TestParseBackupVolID(t *testing.T) {
+	cases := map[string]BackupVolume{
+		"local:backup/vzdump-qemu-100-2024_01_31-02_00_03.vma.zst": {GuestType: GuestQemu, VMID: 100},
+		"nfs:backup/vzdump-lxc-101-2024_01_31-02_10_00.tar.zst":    {GuestType: GuestLXC, VMID: 101},
+		"old:backup/vzdump-openvz-102-2015_05_01-00_00_00.tar":     {GuestType: GuestLXC, VMID: 102},
+		"pbs:backup/vm/200/2024-01-31T02:00:00Z":                   {GuestType: GuestQemu, VMID: 200},
+		"pbs:backup/ct/201/2024-01-31T02:00:00Z":                   {GuestType: GuestLXC, VMID: 201},
+	}
+	for volid, want := range cases {
+		got, ok := ParseBackupVolID(volid)
+		if !ok || got.GuestType != want.GuestType || got.VMID != want.VMID || got.Time.IsZero() {
+			t.Errorf("ParseBackupVolID(%q) = %+v, %v; expected %+v", volid, got, ok, want)
+		}
+	}
+	if _, ok := ParseBackupVolID("local:iso/debian.iso"); ok {
+		t.Error("Expected an ISO volume not to be parsed as a backup")
+	}
+
+	// subtype et vmid de Proxmox priment sur le nom de l'archive
+	volume, ok := Backup{VolID: "pbs:backup/ct/201/2024-01-31T02:00:00Z", Subtype: "qemu", VMID: 300, CTime: 1706666400}.Volume()
+	if !ok || volume.GuestType != GuestQemu || volume.VMID != 300 || volume.Time.Unix() != 1706666400 {
+		t.Errorf("Unexpected volume %+v", volume)
+	}
+	if _, ok := (Backup{VolID: "local:backup/custom.tar"}).Volume(); ok {
+		t.Error("Expected an archive without vmid to be ignored")
+	}
+}
+
+func TestVzdumpAndBackupJobParams(t *testing.T) {
+	params, err := (&VzdumpOptions{Mode: "suspend", Compress: "zstd", Storage: "nas", NotesTemplate: "{{guestname}}", Protected: true}).Params([]int{100, 101})
+	if err != nil {
+		t.Fatalf("Params failed: %v", err)
+	}
+	if want := "compress=zstd&mode=suspend&notes-template=%7B%7Bguestname%7D%7D&protected=1&storage=nas&vmid=100%2C101"; params.Encode() != want {
+		t.Errorf("Unexpected vzdump params\n got %s\nwant %s", params.Encode(), want)
+	}
+	for i, o := range []VzdumpOptions{{Mode: "fast"}, {Compress: "xz"}, {Storage: "bad storage"}, {NotesTemplate: "a\nb"}} {
+		if _, err := o.Params([]int{100}); err == nil {
+			t.Errorf("Expected vzdump options %d to be rejected", i)
+		}
+	}
+	if _, err := (&VzdumpOptions{}).Params(nil); err == nil {
+		t.Error("Expected a backup without guest to be rejected")
+	}
+
+	schedule, pool, empty, all := "mon..fri 02:00", "prod", "", true
+	created, err := (&BackupJobUpdate{ID: "nightly", Schedule: &schedule, Pool: &pool, MailTo: &[]string{"ops@example.com"}}).Params(true)
+	if err != nil {
+		t.Fatalf("Create params failed: %v", err)
+	}
+	if want := "id=nightly&mailto=ops%40example.com&pool=prod&schedule=mon..fri+02%3A00"; created.Encode() != want {
+		t.Errorf("Unexpected create params\n got %s\nwant %s", created.Encode(), want)
+	}
+	updated, err := (&BackupJobUpdate{All: &all, Exclude: &[]int{101}, Storage: &empty}).Params(false)
+	if err != nil {
+		t.Fatalf("Update params failed: %v", err)
+	}
+	if want := "all=1&delete=storage%2Cvmid%2Cpool&exclude=101"; updated.Encode() != want {
+		t.Errorf("Unexpected update params\n got %s\nwant %s", updated.Encode(), want)
+	}
+
+	invalid := []struct {
+		update BackupJobUpdate
+		create bool
+	}{
+		{BackupJobUpdate{Pool: &pool}, true},         // schedule manquant
+		{BackupJobUpdate{Schedule: &schedule}, true}, // aucun invité
+		{BackupJobUpdate{Schedule: &schedule, All: &all, Pool: &pool}, true},
+		{BackupJobUpdate{VMIDs: &[]int{100}, Exclude: &[]int{101}}, false},
+		{BackupJobUpdate{Schedule: &empty}, false},
+		{BackupJobUpdate{ID: "renamed", Pool: &pool}, false},
+		{BackupJobUpdate{MailTo: &[]string{"not an address"}}, false},
+		{BackupJobUpdate{}, false},
+	}
+	for i, c := range invalid {
+		if _, err := c.update.Params(c.create); err == nil {
+			t.Errorf("Expected backup job %d to be rejected", i)
+		}
+	}
+}
@@ -0,0 +1,1806 @@
+package routes
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/auth"
+	"proxmox-dashboard/internal/config"
+	"proxmox-dashboard/internal/email"
+	"proxmox-dashboard/internal/exporter"
+	"proxmox-dashboard/internal/handlers"
+	"proxmox-dashboard/internal/inventory"
+	appmw "proxmox-dashboard/internal/middleware"
+	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/secrets"
+	"proxmox-dashboard/internal/services"
+	"proxmox-dashboard/internal/sse"
+	"proxmox-dashboard/internal/store"
+
+	_ "modernc.org/sqlite"
+)
+
+// setupTestRouter crée un routeur complet sur une base en mémoire avec un administrateur initial
+func setupTestRouter(t *testing.T) (http.Handler, *auth.Service) {
+	return setupTestRouterWith(t, nil)
+}
+
+// setupTestRouterWith crée le routeur de test après avoir configuré les handlers avec configure
+func setupTestRouterWith(t *testing.T, configure func(h *handlers.Handlers, s *store.Store)) (http.Handler, *auth.Service) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := store.NewStore(db)
+	if err := s.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	authService := auth.NewService(db)
+	if _, err := authService.BootstrapAdmin("admin", "admin@example.com", "secret"); err != nil {
+		t.Fatalf("Failed to bootstrap admin: %v", err)
+	}
+
+	h := handlers.NewHandlers(s)
+	if configure != nil {
+		configure(h, s)
+	}
+	return SetupRoutes(h, handlers.NewAuthHandlers(authService), authService, s, sse.NewHub()), authService
+}
+
+// createTestConnection enregistre une connexion Proxmox vers proxmoxURL et retourne son ID
+func createTestConnection(t *testing.T, s *store.Store, proxmoxURL string) int {
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	s.SetCipher(cipher)
+	conn := &models.ProxmoxConnection{Name: "lab", URL: proxmoxURL, Username: "root@pam!dashboard", Secret: "secret", Enabled: true}
+	if err := s.CreateProxmoxConnection(conn); err != nil {
+		t.Fatalf("Failed to create connection: %v", err)
+	}
+	return conn.ID
+}
+
+// login retourne le token de session d'un utilisateur
+func login(t *testing.T, router http.Handler, username, password string) string {
+	body, _ := json.Marshal(models.LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Login as %s failed with status %d: %s", username, w.Code, w.Body.String())
+	}
+
+	var resp models.LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+	return resp.Token
+}
+
+func doRequest(router http.Handler, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRoutes_RequireAuthenticationAndPermissions(t *testing.T) {
+	router, authService := setupTestRouter(t)
+
+	if code := doRequest(router, "GET", "/health", ""); code != http.StatusOK {
+		t.Errorf("Expected /health to be public, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/v1/apps", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code := doRequest(router, "POST", "/api/v1/admin/clear-db", "invalid"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", code)
+	}
+
+	if _, err := authService.CreateUser(models.CreateUserRequest{
+		Username: "viewer", Email: "viewer@example.com", Password: "viewer", Role: models.RoleViewer,
+	}); err != nil {
+		t.Fatalf("Failed to create viewer: %v", err)
+	}
+	viewer := login(t, router, "viewer", "viewer")
+
+	if code := doRequest(router, "GET", "/api/v1/apps", viewer); code != http.StatusOK {
+		t.Errorf("Expected viewer to read apps, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/auth/me", viewer); code != http.StatusOK {
+		t.Errorf("Expected viewer to read /me, got %d", code)
+	}
+	for _, path := range []string{"/api/v1/admin/clear-db", "/api/v1/proxmox/vm/start"} {
+		if code := doRequest(router, "POST", path, viewer); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for viewer on %s, got %d", path, code)
+		}
+	}
+	if code := doRequest(router, "GET", "/api/auth/users", viewer); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for viewer on user list, got %d", code)
+	}
+
+	admin := login(t, router, "admin", "secret")
+	if code := doRequest(router, "GET", "/api/auth/users", admin); code != http.StatusOK {
+		t.Errorf("Expected admin to list users, got %d", code)
+	}
+
+	// La suppression d'un utilisateur révoque ses sessions
+	if code := doRequest(router, "DELETE", "/api/auth/users/2", admin); code != http.StatusNoContent {
+		t.Errorf("Expected 204 when deleting the viewer, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/auth/me", viewer); code != http.StatusUnauthorized {
+		t.Errorf("Expected the deleted viewer's session to be revoked, got %d", code)
+	}
+
+	if code := doRequest(router, "POST", "/api/auth/logout", admin); code != http.StatusOK {
+		t.Errorf("Expected logout to succeed, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/auth/me", admin); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after logout, got %d", code)
+	}
+}
+
+func TestAuthService_BootstrapAdminAndLastAdmin(t *testing.T) {
+	_, authService := setupTestRouter(t)
+
+	// Un administrateur existe déjà : aucun nouveau compte
+	if admin, err := authService.BootstrapAdmin("other", "other@example.com", "secret"); err != nil || admin != nil {
+		t.Errorf("Expected no bootstrap when an admin exists, got %v, %v", admin, err)
+	}
+
+	admin, err := authService.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	role := models.RoleViewer
+	if _, err := authService.UpdateUser(admin.ID, models.UpdateUserRequest{Role: &role}); err != auth.ErrLastAdmin {
+		t.Errorf("Expected ErrLastAdmin when demoting the last admin, got %v", err)
+	}
+	if err := authService.DeleteUser(admin.ID); err != auth.ErrLastAdmin {
+		t.Errorf("Expected ErrLastAdmin when deleting the last admin, got %v", err)
+	}
+
+	if err := authService.ChangePassword(admin.ID, models.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new"}, ""); err != auth.ErrInvalidPassword {
+		t.Errorf("Expected ErrInvalidPassword, got %v", err)
+	}
+	if err := authService.ChangePassword(admin.ID, models.ChangePasswordRequest{CurrentPassword: "secret", NewPassword: "new"}, ""); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+	if _, err := authService.Login(models.LoginRequest{Username: "admin", Password: "new"}, "127.0.0.1", "test"); err != nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+}
+
+func TestRoutes_UserManagementAndPasswordChange(t *testing.T) {
+	router, authService := setupTestRouter(t)
+
+	if _, err := authService.CreateRole(models.Role{
+		Name: "user-managers",
+		Permissions: []models.Permission{
+			{Resource: "users", Action: "read"},
+			{Resource: "users", Action: "write"},
+			{Resource: "profile", Action: "write"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	ops, err := authService.CreateUser(models.CreateUserRequest{Username: "ops", Email: "ops@example.com", Password: "ops", Role: "user-managers"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := authService.CreateUser(models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "bob", Role: models.RoleUser})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	send := func(method, path, token string, body map[string]interface{}) int {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// users:write suffit à modifier un autre utilisateur, sans être administrateur
+	opsToken := login(t, router, "ops", "ops")
+	if code := send("PUT", fmt.Sprintf("/api/auth/users/%d", bob.ID), opsToken, map[string]interface{}{"email": "robert@example.com"}); code != http.StatusOK {
+		t.Errorf("Expected users:write to allow updating another user, got %d", code)
+	}
+
+	// Sans être administrateur, users:write ne permet ni d'attribuer le rôle admin ni de modifier un administrateur
+	admin, err := authService.GetUserByUsername("admin")
+	if err != nil {
+		t.Fatalf("Failed to get admin: %v", err)
+	}
+	for _, c := range []struct {
+		method, path string
+		body         map[string]interface{}
+	}{
+		{"PUT", fmt.Sprintf("/api/auth/users/%d", bob.ID), map[string]interface{}{"role": "admin"}},
+		{"PUT", fmt.Sprintf("/api/auth/users/%d", ops.ID), map[string]interface{}{"role": "admin"}},
+		{"PUT", fmt.Sprintf("/api/auth/users/%d", admin.ID), map[string]interface{}{"password": "taken"}},
+		{"POST", "/api/auth/users", map[string]interface{}{"username": "eve", "email": "eve@example.com", "password": "eve", "role": "admin"}},
+	} {
+		if code := send(c.method, c.path, opsToken, c.body); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s %s %v without the admin role, got %d", c.method, c.path, c.body, code)
+		}
+	}
+	if code := send("PUT", fmt.Sprintf("/api/auth/users/%d", bob.ID), opsToken, map[string]interface{}{"role": "user"}); code != http.StatusOK {
+		t.Errorf("Expected users:write to assign a non-admin role, got %d", code)
+	}
+
+	// Changer son propre mot de passe exige le mot de passe actuel
+	bobToken := login(t, router, "bob", "bob")
+	bobPath := fmt.Sprintf("/api/auth/users/%d", bob.ID)
+	if code := send("PUT", bobPath, bobToken, map[string]interface{}{"password": "new"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without current_password, got %d", code)
+	}
+	if code := send("PUT", bobPath, bobToken, map[string]interface{}{"password": "new", "current_password": "wrong"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 with a wrong current_password, got %d", code)
+	}
+	if code := send("PUT", fmt.Sprintf("/api/auth/users/%d", ops.ID), bobToken, map[string]interface{}{"password": "new"}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 when updating another user without users:write, got %d", code)
+	}
+	if code := send("PUT", bobPath, bobToken, map[string]interface{}{"password": "new", "current_password": "bob"}); code != http.StatusOK {
+		t.Errorf("Expected the self-service password change to succeed, got %d", code)
+	}
+
+	// ChangePassword révoque les autres sessions et conserve la session courante
+	other := login(t, router, "ops", "ops")
+	if code := send("POST", "/api/auth/change-password", opsToken, map[string]interface{}{"current_password": "ops", "new_password": "ops2"}); code != http.StatusOK {
+		t.Fatalf("Expected the password change to succeed, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/auth/me", other); code != http.StatusUnauthorized {
+		t.Errorf("Expected the other session to be revoked, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/api/auth/me", opsToken); code != http.StatusOK {
+		t.Errorf("Expected the current session to be kept, got %d", code)
+	}
+}
+
+func TestRoutes_LoggerRedactsTokens(t *testing.T) {
+	for uri, expected := range map[string]string{
+		"/api/v1/alerts/stream?token=SESSION":                        "/api/v1/alerts/stream?token=REDACTED",
+		"/api/v1/proxmox/vm/console-proxy?console_token=C&path=%2Fa": "/api/v1/proxmox/vm/console-proxy?console_token=REDACTED&path=%2Fa",
+		"/api/v1/apps?limit=3":                                       "/api/v1/apps?limit=3",
+		"/health":                                                    "/health",
+	} {
+		if got := appmw.RedactURI(uri); got != expected {
+			t.Errorf("RedactURI(%q) = %q, expected %q", uri, got, expected)
+		}
+	}
+}
+
+func TestRoutes_ScopedGuestPermissions(t *testing.T) {
+	var testStore *store.Store
+	router, authService := setupTestRouterWith(t, func(_ *handlers.Handlers, s *store.Store) { testStore = s })
+
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/resources":
+			w.Write([]byte(`{"data":[{"type":"qemu","node":"pve1","vmid":100,"tags":"dev;web"},{"type":"qemu","node":"pve1","vmid":101,"tags":"prod"}]}`))
+		default:
+			w.Write([]byte(`{"data":"UPID:pve1:00001234:00000000:00000000:qmstart:100:root@pam:"}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+	connID := createTestConnection(t, testStore, proxmoxServer.URL)
+
+	if _, err := authService.CreateRole(models.Role{
+		Name:        "dev-team",
+		Description: "Redémarrage des invités de développement",
+		Permissions: []models.Permission{
+			{Resource: "proxmox", Action: "read"},
+			{Resource: "proxmox.vm", Action: "power", Scope: &models.PermissionScope{Tags: []string{"dev"}}},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	if _, err := authService.CreateUser(models.CreateUserRequest{
+		Username: "dev", Email: "dev@example.com", Password: "dev", Role: "dev-team",
+	}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token := login(t, router, "dev", "dev")
+
+	vmAction := func(vmid int) int {
+		body, _ := json.Marshal(map[string]interface{}{
+			"connection_id": connID, "node": "pve1", "vmid": vmid,
+		})
+		req := httptest.NewRequest("POST", "/api/v1/proxmox/vm/restart", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := vmAction(100); code != http.StatusOK {
+		t.Errorf("Expected the dev-tagged guest to be restartable, got %d", code)
+	}
+	if code := vmAction(101); code != http.StatusForbidden {
+		t.Errorf("Expected 403 on a guest outside the scope, got %d", code)
+	}
+	if code := doRequest(router, "POST", "/api/v1/proxmox/vm/console", token); code != http.StatusForbidden {
+		t.Errorf("Expected 403 on the console without proxmox.vm:console, got %d", code)
+	}
+
+	// Les redémarrages, autorisés ou refusés, sont tracés avec l'utilisateur, la cible et l'UPID
+	adminToken := login(t, router, "admin", "secret")
+	req := httptest.NewRequest("GET", "/api/v1/audit?action=vm.&user=dev", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var audit struct {
+		Entries []models.AuditEntry `json:"entries"`
+		Total   int                 `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&audit); err != nil {
+		t.Fatalf("Failed to decode audit log: %v", err)
+	}
+	if audit.Total != 2 {
+		t.Fatalf("Expected 2 vm.* audit entries for dev, got %d: %+v", audit.Total, audit.Entries)
+	}
+	byTarget := map[string]models.AuditEntry{}
+	for _, e := range audit.Entries {
+		byTarget[e.Target] = e
+	}
+	if e := byTarget["pve1/qemu/100"]; e.Action != "vm.restart" || e.Result != models.AuditResultSuccess || e.UPID == "" {
+		t.Errorf("Unexpected audit entry for the allowed restart: %+v", e)
+	}
+	if e := byTarget["pve1/qemu/101"]; e.Result != models.AuditResultDenied || e.Status != http.StatusForbidden {
+		t.Errorf("Unexpected audit entry for the denied restart: %+v", e)
+	}
+	if code := doRequest(router, "GET", "/api/v1/audit", token); code != http.StatusForbidden {
+		t.Errorf("Expected 403 on the audit log without audit:read, got %d", code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/audit?format=csv&result=denied", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected a CSV export, got %q", ct)
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "timestamp,") || !strings.Contains(body, "pve1/qemu/101") {
+		t.Errorf("Unexpected CSV export:\n%s", body)
+	}
+
+	if err := authService.DeleteRole("dev-team"); err != auth.ErrRoleInUse {
+		t.Errorf("Expected ErrRoleInUse when deleting an assigned role, got %v", err)
+	}
+	if err := authService.DeleteRole(models.RoleViewer); err != auth.ErrRoleProtected {
+		t.Errorf("Expected ErrRoleProtected when deleting a built-in role, got %v", err)
+	}
+	if _, err := authService.CreateRole(models.Role{
+		Name:        "broken",
+		Permissions: []models.Permission{{Resource: "apps", Action: "read", Scope: &models.PermissionScope{Nodes: []string{"pve1"}}}},
+	}); err == nil {
+		t.Error("Expected an error when scoping a non-scopable permission")
+	}
+}
+
+func TestRoutes_GuestLifecycleActionsAndTaskWait(t *testing.T) {
+	var testStore *store.Store
+	router, _ := setupTestRouterWith(t, func(_ *handlers.Handlers, s *store.Store) { testStore = s })
+
+	var lastPath, lastForm string
+	polls := 0
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/status") && strings.Contains(r.URL.Path, "/tasks/"):
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"data":{"status":"running","node":"pve1","type":"vzshutdown","id":"200"}}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"status":"stopped","exitstatus":"OK","node":"pve1","type":"vzshutdown","id":"200"}}`))
+		default:
+			r.ParseForm()
+			lastPath, lastForm = r.URL.Path, r.PostForm.Encode()
+			w.Write([]byte(`{"data":"UPID:pve1:00001234:00000000:00000000:vzshutdown:200:root@pam:"}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+	connID := createTestConnection(t, testStore, proxmoxServer.URL)
+	token := login(t, router, "admin", "secret")
+
+	post := func(path string, body map[string]interface{}) (int, map[string]interface{}) {
+		body["connection_id"] = connID
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, resp := post("/api/v1/proxmox/lxc/shutdown", map[string]interface{}{
+		"node": "pve1", "vmid": 200, "timeout": 60, "force_stop": true,
+	})
+	if code != http.StatusOK || resp["upid"] == "" {
+		t.Fatalf("Expected the container shutdown to return a UPID, got %d: %v", code, resp)
+	}
+	if lastPath != "/api2/json/nodes/pve1/lxc/200/status/shutdown" || lastForm != "forceStop=1&timeout=60" {
+		t.Errorf("Unexpected Proxmox call %s (%s)", lastPath, lastForm)
+	}
+
+	// Des identifiants bruts sans connexion enregistrée sont refusés, sans appel à l'URL fournie
+	lastPath = ""
+	raw, _ := json.Marshal(map[string]interface{}{
+		"url": proxmoxServer.URL, "username": "root@pam!dashboard", "secret": "secret", "node": "pve1", "vmid": 200,
+	})
+	rawReq := httptest.NewRequest("POST", "/api/v1/proxmox/lxc/start", bytes.NewReader(raw))
+	rawReq.Header.Set("Authorization", "Bearer "+token)
+	rawResp := httptest.NewRecorder()
+	router.ServeHTTP(rawResp, rawReq)
+	if rawResp.Code != http.StatusBadRequest || lastPath != "" {
+		t.Errorf("Expected raw credentials to be rejected, got %d (Proxmox call %q)", rawResp.Code, lastPath)
+	}
+
+	if code, _ := post("/api/v1/proxmox/vm/hibernate", map[string]interface{}{"node": "pve1", "vmid": 100}); code != http.StatusOK {
+		t.Errorf("Expected hibernate to succeed, got %d", code)
+	}
+	if lastPath != "/api2/json/nodes/pve1/qemu/100/status/suspend" || lastForm != "todisk=1" {
+		t.Errorf("Unexpected Proxmox call for hibernate %s (%s)", lastPath, lastForm)
+	}
+	if code, _ := post("/api/v1/proxmox/lxc/reset", map[string]interface{}{"node": "pve1", "vmid": 200}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for reset on a container, got %d", code)
+	}
+
+	code, resp = post("/api/v1/proxmox/tasks/wait", map[string]interface{}{
+		"upid": "UPID:pve1:00001234:00000000:00000000:vzshutdown:200:root@pam:", "timeout": 5,
+	})
+	if code != http.StatusOK || resp["finished"] != true || resp["ok"] != true || resp["exitstatus"] != "OK" {
+		t.Errorf("Expected the task to finish with OK, got %d: %v", code, resp)
+	}
+	if code, _ := post("/api/v1/proxmox/tasks/wait", map[string]interface{}{"upid": "not-a-upid"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid UPID, got %d", code)
+	}
+	// L'attente doit se terminer avant l'expiration de la requête par le routeur
+	if code, _ := post("/api/v1/proxmox/tasks/wait", map[string]interface{}{
+		"upid": "UPID:pve1:00001234:00000000:00000000:vzshutdown:200:root@pam:", "timeout": int(handlers.RequestTimeout / time.Second),
+	}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a timeout reaching the request timeout, got %d", code)
+	}
+}
+
+func TestRoutes_VMConsoleToken(t *testing.T) {
+	var testStore *store.Store
+	router, _ := setupTestRouterWith(t, func(_ *handlers.Handlers, s *store.Store) { testStore = s })
+
+	var consoleCookie string
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/access/ticket":
+			w.Write([]byte(`{"data":{"ticket":"PVE:root@pam:SECRET-TICKET","CSRFPreventionToken":"csrf","username":"root@pam"}}`))
+		case "/":
+			consoleCookie = r.Header.Get("Cookie")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><script src="/novnc/app.js"></script></head><body></body></html>`))
+		default:
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+	connID := createTestConnection(t, testStore, proxmoxServer.URL)
+
+	// Sans token de console, l'upstream fourni par le client n'est jamais contacté
+	upstreamCalled := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { upstreamCalled = true }))
+	t.Cleanup(upstream.Close)
+	for _, path := range []string{
+		"/api/v1/proxmox/vm/console-proxy?proxmoxUrl=" + url.QueryEscape(upstream.URL) + "&vmid=100&node=pve1&ticket=x",
+		"/api/v1/proxmox/vm/console-redirect?proxmoxUrl=" + url.QueryEscape(upstream.URL) + "&vmid=100&node=pve1&ticket=x",
+		"/api/v1/proxmox/vm/console-proxy?console_token=forged",
+	} {
+		if code := doRequest(router, "GET", path, ""); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 on %s, got %d", path, code)
+		}
+	}
+	if upstreamCalled {
+		t.Error("Expected the upstream from the query string to be ignored")
+	}
+
+	token := login(t, router, "admin", "secret")
+	body, _ := json.Marshal(map[string]interface{}{"connection_id": connID, "node": "pve1", "vmid": 100})
+	req := httptest.NewRequest("POST", "/api/v1/proxmox/vm/console", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		ConsoleURL string `json:"consoleUrl"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || !strings.Contains(resp.ConsoleURL, "console_token=") {
+		t.Fatalf("Expected a console URL with a console token, got %d: %+v", w.Code, resp)
+	}
+	if strings.Contains(resp.ConsoleURL, "SECRET-TICKET") || strings.Contains(resp.ConsoleURL, "proxmoxUrl") {
+		t.Errorf("Expected the ticket and upstream to stay server-side, got %s", resp.ConsoleURL)
+	}
+
+	// La fenêtre de console n'a pas de token de session : le token de console suffit
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", resp.ConsoleURL, nil))
+	if w.Code != http.StatusOK || consoleCookie != "PVEAuthCookie=PVE:root@pam:SECRET-TICKET" {
+		t.Errorf("Expected the console page to be proxied with the ticket cookie, got %d (cookie %q)", w.Code, consoleCookie)
+	}
+	if strings.Contains(w.Body.String(), "SECRET-TICKET") {
+		t.Error("Expected the proxied page not to expose the Proxmox ticket")
+	}
+	if code := doRequest(router, "GET", resp.ConsoleURL+"&path="+url.QueryEscape("@example.com/"), ""); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a path that changes the upstream host, got %d", code)
+	}
+
+	// Le proxy ne relaie que les ressources noVNC et les endpoints de console de l'invité autorisé
+	for _, c := range []struct{ method, path string }{
+		{"GET", "/api2/json/nodes/pve1/qemu/100/config"},
+		{"GET", "/novnc/../api2/json/access/users"},
+		{"POST", "/api2/json/nodes/pve1/qemu/101/vncproxy"},
+		{"POST", "/api2/json/nodes/pve1/qemu/100/status/stop"},
+		{"POST", "/novnc/app.js"},
+		{"GET", "/api2/json/nodes/pve1/qemu/100/vncproxy"},
+	} {
+		if code := doRequest(router, c.method, resp.ConsoleURL+"&path="+url.QueryEscape(c.path), ""); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s %s, got %d", c.method, c.path, code)
+		}
+	}
+	for _, c := range []struct{ method, path string }{
+		{"GET", "/novnc/app.js"},
+		{"POST", "/api2/json/nodes/pve1/qemu/100/vncproxy"},
+	} {
+		if code := doRequest(router, c.method, resp.ConsoleURL+"&path="+url.QueryEscape(c.path), ""); code != http.StatusOK {
+			t.Errorf("Expected %s %s to be proxied, got %d", c.method, c.path, code)
+		}
+	}
+
+	// La redirection mène au proxy sans exposer le ticket
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", strings.Replace(resp.ConsoleURL, "console-proxy", "console-redirect", 1), nil))
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), "/api/v1/proxmox/vm/console-proxy?console_token=") ||
+		strings.Contains(w.Body.String()+w.Header().Get("Location"), "SECRET-TICKET") {
+		t.Errorf("Expected a redirect to the console proxy without the ticket, got %d: %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestRoutes_GuestConfigEditing(t *testing.T) {
+	var testStore *store.Store
+	router, authService := setupTestRouterWith(t, func(_ *handlers.Handlers, s *store.Store) { testStore = s })
+
+	digest := "d1"
+	cores := "2"
+	var lastForm url.Values
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/qemu/100/config"):
+			fmt.Fprintf(w, `{"data":{"name":"web","cores":%s,"memory":"2048","net0":"virtio=BC:24:11:00:00:01,bridge=vmbr0","digest":%q}}`, cores, digest)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/qemu/100/pending"):
+			if cores == "2" {
+				w.Write([]byte(`{"data":[{"key":"cores","value":2},{"key":"digest","value":"d1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"key":"cores","value":2,"pending":4},{"key":"net0","value":"virtio=BC:24:11:00:00:01,bridge=vmbr0","pending":"virtio=BC:24:11:00:00:01,bridge=vmbr1"}]}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/qemu/100/config"):
+			r.ParseForm()
+			lastForm = r.PostForm
+			if r.PostForm.Get("digest") != digest {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"data":null,"message":"detected modified configuration - file changed by other user? Try again."}`))
+				return
+			}
+			cores, digest = r.PostForm.Get("cores"), "d2"
+			w.Write([]byte(`{"data":null}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+	connID := createTestConnection(t, testStore, proxmoxServer.URL)
+	token := login(t, router, "admin", "secret")
+
+	sendAs := func(token, method, path string, body map[string]interface{}) (int, map[string]interface{}) {
+		body["connection_id"] = connID
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+	send := func(method, path string, body map[string]interface{}) (int, map[string]interface{}) {
+		return sendAs(token, method, path, body)
+	}
+
+	// La lecture exige proxmox:read, la modification proxmox.vm:config
+	if _, err := authService.CreateUser(models.CreateUserRequest{
+		Username: "viewer", Email: "viewer@example.com", Password: "viewer", Role: models.RoleViewer,
+	}); err != nil {
+		t.Fatalf("Failed to create viewer: %v", err)
+	}
+	viewer := login(t, router, "viewer", "viewer")
+	if code, resp := sendAs(viewer, "POST", "/api/v1/proxmox/vm/config", map[string]interface{}{"node": "pve1", "vmid": 100}); code != http.StatusOK {
+		t.Errorf("Expected a viewer to read the config, got %d: %v", code, resp)
+	}
+	if code, _ := sendAs(viewer, "PUT", "/api/v1/proxmox/vm/config", map[string]interface{}{
+		"node": "pve1", "vmid": 100, "digest": "d1", "changes": map[string]interface{}{"cores": 4},
+	}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer updating the config, got %d", code)
+	}
+
+	code, resp := send("POST", "/api/v1/proxmox/vm/config", map[string]interface{}{"node": "pve1", "vmid": 100})
+	if code != http.StatusOK {
+		t.Fatalf("Expected the config to be read, got %d: %v", code, resp)
+	}
+	cfg := resp["data"].(map[string]interface{})
+	if cfg["cores"] != float64(2) || cfg["memory"] != float64(2048) || cfg["digest"] != "d1" || cfg["reboot_required"] != false {
+		t.Errorf("Unexpected config %v", cfg)
+	}
+
+	changes := map[string]interface{}{"cores": 4, "nets": map[string]interface{}{"net0": map[string]interface{}{"bridge": "vmbr1"}}}
+	if code, _ := send("PUT", "/api/v1/proxmox/vm/config", map[string]interface{}{"node": "pve1", "vmid": 100, "changes": changes}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without digest, got %d", code)
+	}
+	if code, _ := send("PUT", "/api/v1/proxmox/vm/config", map[string]interface{}{
+		"node": "pve1", "vmid": 100, "digest": "d1", "changes": map[string]interface{}{"swap": 512},
+	}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for swap on a VM, got %d", code)
+	}
+
+	code, resp = send("PUT", "/api/v1/proxmox/vm/config", map[string]interface{}{"node": "pve1", "vmid": 100, "digest": "d1", "changes": changes})
+	if code != http.StatusOK {
+		t.Fatalf("Expected the update to succeed, got %d: %v", code, resp)
+	}
+	if lastForm.Get("cores") != "4" || lastForm.Get("net0") != "virtio=BC:24:11:00:00:01,bridge=vmbr1" || lastForm.Get("digest") != "d1" {
+		t.Errorf("Unexpected update sent to Proxmox: %v", lastForm)
+	}
+	cfg = resp["data"].(map[string]interface{})
+	if cfg["reboot_required"] != true || len(cfg["pending"].([]interface{})) != 2 || cfg["digest"] != "d2" {
+		t.Errorf("Expected the pending changes to be reported, got %v", cfg)
+	}
+
+	// Un second envoi avec le digest périmé est refusé
+	code, resp = send("PUT", "/api/v1/proxmox/vm/config", map[string]interface{}{"node": "pve1", "vmid": 100, "digest": "d1", "changes": changes})
+	if code != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale digest, got %d: %v", code, resp)
+	}
+}
+
+func TestRoutes_ProvisionJob(t *testing.T) {
+	var cloneForm, configForm url.Values
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/cluster/resources"):
+			w.Write([]byte(`{"data":[
+				{"type":"qemu","node":"pve1","vmid":9000,"name":"ubuntu-tpl","template":1,"tags":"linux","pool":"dev"},
+				{"type":"lxc","node":"pve2","vmid":"9100","name":"debian-tpl","template":1},
+				{"type":"qemu","node":"pve1","vmid":100,"name":"web","template":0}]}`))
+		case strings.HasSuffix(r.URL.Path, "/cluster/nextid"):
+			w.Write([]byte(`{"data":"105"}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/nodes/pve1/qemu/9000/clone"):
+			r.ParseForm()
+			cloneForm = r.PostForm
+			w.Write([]byte(`{"data":"UPID:pve1:00001234:00000000:00000000:qmclone:9000:root@pam:"}`))
+		case strings.HasSuffix(r.URL.Path, "/status") && strings.Contains(r.URL.Path, "/tasks/"):
+			w.Write([]byte(`{"data":{"status":"stopped","exitstatus":"OK"}}`))
+		case strings.HasSuffix(r.URL.Path, "/log") && strings.Contains(r.URL.Path, "/tasks/"):
+			w.Write([]byte(`{"data":[{"n":1,"t":"create full clone of drive scsi0"},{"n":2,"t":"transferred 16.0 GiB of 32.0 GiB (50.00%)"}]}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/nodes/pve2/qemu/105/config"):
+			r.ParseForm()
+			configForm = r.PostForm
+			w.Write([]byte(`{"data":null}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+
+	var connID int
+	router, authService := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		connID = createTestConnection(t, s, proxmoxServer.URL)
+		provisioner := services.NewProvisioner(s)
+		provisioner.SetPollInterval(10 * time.Millisecond)
+		t.Cleanup(provisioner.Stop)
+		h.SetProvisioner(provisioner)
+	})
+	token := login(t, router, "admin", "secret")
+
+	post := func(path string, body map[string]interface{}) (int, map[string]interface{}) {
+		body["connection_id"] = connID
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	code, resp := post("/api/v1/proxmox/provision/templates", map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Fatalf("Expected the templates to be listed, got %d: %v", code, resp)
+	}
+	templates := resp["data"].([]interface{})
+	if len(templates) != 2 || templates[0].(map[string]interface{})["vmid"] != float64(9000) || templates[1].(map[string]interface{})["type"] != "lxc" {
+		t.Errorf("Unexpected templates %v", templates)
+	}
+
+	if code, _ := post("/api/v1/proxmox/provision/jobs", map[string]interface{}{
+		"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-01", "storage": "local-lvm",
+	}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a storage on a linked clone, got %d", code)
+	}
+
+	code, resp = post("/api/v1/proxmox/provision/jobs", map[string]interface{}{
+		"node": "pve1", "type": "qemu", "template_vmid": 9000, "target_node": "pve2", "name": "web-01",
+		"full": true, "storage": "local-lvm",
+		"cloud_init": map[string]interface{}{
+			"user": "ubuntu", "password": "s3cret",
+			"ssh_keys":  []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGx admin@laptop"},
+			"ip_config": []string{"ip=10.0.0.5/24,gw=10.0.0.1"},
+		},
+	})
+	if code != http.StatusAccepted {
+		t.Fatalf("Expected 202 on job creation, got %d: %v", code, resp)
+	}
+	jobPath := "/api/v1/proxmox/provision/jobs/" + strconv.Itoa(int(resp["data"].(map[string]interface{})["id"].(float64)))
+
+	var job map[string]interface{}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		json.NewDecoder(get(jobPath).Body).Decode(&job)
+		if job["status"] == "succeeded" || job["status"] == "failed" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job["status"] != "succeeded" || job["vmid"] != float64(105) || job["progress"] != float64(100) {
+		t.Fatalf("Expected the job to succeed with VMID 105, got %v", job)
+	}
+	if job["spec"].(map[string]interface{})["cloud_init"].(map[string]interface{})["password"] != nil {
+		t.Error("Expected the cloud-init password not to be returned")
+	}
+
+	if cloneForm.Get("newid") != "105" || cloneForm.Get("name") != "web-01" || cloneForm.Get("full") != "1" ||
+		cloneForm.Get("target") != "pve2" || cloneForm.Get("storage") != "local-lvm" {
+		t.Errorf("Unexpected clone parameters: %v", cloneForm)
+	}
+	if configForm.Get("ciuser") != "ubuntu" || configForm.Get("cipassword") != "s3cret" ||
+		configForm.Get("ipconfig0") != "ip=10.0.0.5/24,gw=10.0.0.1" || !strings.HasPrefix(configForm.Get("sshkeys"), "ssh-ed25519%20") {
+		t.Errorf("Unexpected cloud-init parameters: %v", configForm)
+	}
+
+	// Le flux SSE d'un job terminé envoie son état final puis se ferme
+	w := get(jobPath + "/events")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" ||
+		!strings.Contains(w.Body.String(), "event: job") || !strings.Contains(w.Body.String(), `"status":"succeeded"`) {
+		t.Errorf("Unexpected job stream %d: %s", w.Code, w.Body.String())
+	}
+
+	var jobs []map[string]interface{}
+	if w := get("/api/v1/proxmox/provision/jobs"); w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&jobs) != nil || len(jobs) != 1 {
+		t.Errorf("Expected 1 job in the list, got %d: %v", w.Code, jobs)
+	}
+	if w := get("/api/v1/proxmox/provision/jobs/9999"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing job, got %d", w.Code)
+	}
+
+	// Un utilisateur limité au nœud pve1 ne clone ni vers un autre nœud, ni hors de son pool, ni ne démarre
+	// l'invité sans la permission power, et ne voit pas les jobs des autres nœuds
+	if _, err := authService.CreateRole(models.Role{
+		Name: "pve1-cloners",
+		Permissions: []models.Permission{
+			{Resource: "proxmox", Action: "read"},
+			{Resource: "proxmox.vm", Action: "clone", Scope: &models.PermissionScope{Nodes: []string{"pve1"}, Pools: []string{"dev"}}},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+	for _, u := range []models.CreateUserRequest{
+		{Username: "cloner", Email: "cloner@example.com", Password: "cloner", Role: "pve1-cloners"},
+		{Username: "reader", Email: "reader@example.com", Password: "reader", Role: models.RoleViewer},
+	} {
+		if _, err := authService.CreateUser(u); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	admin := token
+	token = login(t, router, "reader", "reader")
+	if code, _ := post("/api/v1/proxmox/provision/templates", map[string]interface{}{}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 on the templates without a clone permission, got %d", code)
+	}
+	if code, _ := post("/api/v1/proxmox/provision/jobs", map[string]interface{}{"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "x"}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 on a job without a clone permission, got %d", code)
+	}
+	if w := get(jobPath); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's job to be hidden, got %d", w.Code)
+	}
+
+	token = login(t, router, "cloner", "cloner")
+	for _, spec := range []map[string]interface{}{
+		{"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-02", "target_node": "pve2", "pool": "dev"},
+		{"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-02", "pool": "prod"},
+		{"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-02"},
+		{"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-02", "pool": "dev", "start": true},
+	} {
+		if code, resp := post("/api/v1/proxmox/provision/jobs", spec); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %v, got %d: %v", spec, code, resp)
+		}
+	}
+	if code, resp := post("/api/v1/proxmox/provision/jobs", map[string]interface{}{
+		"node": "pve1", "type": "qemu", "template_vmid": 9000, "name": "web-02", "pool": "dev",
+	}); code != http.StatusAccepted {
+		t.Errorf("Expected 202 for a clone within the scope, got %d: %v", code, resp)
+	}
+	jobs = nil
+	if w := get("/api/v1/proxmox/provision/jobs"); w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&jobs) != nil ||
+		len(jobs) != 1 || jobs[0]["spec"].(map[string]interface{})["name"] != "web-02" {
+		t.Errorf("Expected only the job within the scope, got %d: %v", w.Code, jobs)
+	}
+	token = admin
+}
+
+func TestRoutes_GuestSnapshots(t *testing.T) {
+	now := time.Now()
+	var created, rolledBack url.Values
+	var deleted string
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upid := `{"data":"UPID:pve1:00001234:00000000:00000000:qmsnapshot:100:root@pam:"}`
+		switch {
+		case r.URL.Path == "/api2/json/nodes":
+			w.Write([]byte(`{"data":[{"node":"pve1","status":"online"}]}`))
+		case r.URL.Path == "/api2/json/nodes/pve1/qemu":
+			w.Write([]byte(`{"data":[{"vmid":100,"name":"web","status":"running"}]}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/qemu/100/snapshot"):
+			fmt.Fprintf(w, `{"data":[
+				{"name":"after","parent":"base","snaptime":%d},
+				{"name":"current","parent":"after","running":1,"description":"You are here!"},
+				{"name":"base","snaptime":%d,"vmstate":1,"description":"clean install"},
+				{"name":"branch","parent":"base","snaptime":%d}]}`,
+				now.Add(-2*24*time.Hour).Unix(), now.Add(-30*24*time.Hour).Unix(), now.Add(-10*24*time.Hour).Unix())
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/qemu/100/snapshot"):
+			r.ParseForm()
+			created = r.PostForm
+			w.Write([]byte(upid))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/qemu/100/snapshot/base/rollback"):
+			r.ParseForm()
+			rolledBack = r.PostForm
+			w.Write([]byte(upid))
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/qemu/100/snapshot/branch"):
+			deleted = r.URL.RawQuery
+			w.Write([]byte(upid))
+		case strings.Contains(r.URL.Path, "/tasks/") && strings.HasSuffix(r.URL.Path, "/status"):
+			w.Write([]byte(`{"data":{"status":"stopped","exitstatus":"OK"}}`))
+		default:
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+
+	var connID int
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		connID = createTestConnection(t, s, proxmoxServer.URL)
+		h.SetPoller(inventory.NewPoller(s, time.Minute))
+	})
+	token := login(t, router, "admin", "secret")
+
+	send := func(method, path string, body map[string]interface{}) (int, map[string]interface{}) {
+		var data []byte
+		if body != nil {
+			body["connection_id"] = connID
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, resp := send("POST", "/api/v1/proxmox/vm/snapshots", map[string]interface{}{"node": "pve1", "vmid": 100})
+	if code != http.StatusOK {
+		t.Fatalf("Expected the snapshot tree, got %d: %v", code, resp)
+	}
+	roots := resp["data"].([]interface{})
+	if len(roots) != 1 {
+		t.Fatalf("Expected a single root snapshot, got %v", roots)
+	}
+	base := roots[0].(map[string]interface{})
+	children := base["children"].([]interface{})
+	if base["name"] != "base" || base["vmstate"] != true || len(children) != 2 ||
+		children[0].(map[string]interface{})["name"] != "branch" || children[1].(map[string]interface{})["name"] != "after" {
+		t.Fatalf("Unexpected snapshot tree %v", base)
+	}
+	current := children[1].(map[string]interface{})["children"].([]interface{})
+	if len(current) != 1 || current[0].(map[string]interface{})["current"] != true {
+		t.Errorf("Expected the current state under the snapshot it derives from, got %v", current)
+	}
+
+	code, resp = send("POST", "/api/v1/proxmox/vm/snapshots/create", map[string]interface{}{
+		"node": "pve1", "vmid": 100, "name": "pre-upgrade", "description": "avant apt", "vmstate": true, "wait": 5,
+	})
+	if code != http.StatusOK || resp["upid"] == nil || resp["finished"] != true || resp["ok"] != true {
+		t.Fatalf("Expected the snapshot task to finish, got %d: %v", code, resp)
+	}
+	if code, _ = send("POST", "/api/v1/proxmox/vm/snapshots/create", map[string]interface{}{
+		"node": "pve1", "vmid": 100, "name": "too-long", "wait": int(handlers.RequestTimeout / time.Second),
+	}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a wait reaching the request timeout, got %d", code)
+	}
+	if created.Get("snapname") != "pre-upgrade" || created.Get("vmstate") != "1" || created.Get("description") != "avant apt" {
+		t.Errorf("Unexpected snapshot parameters: %v", created)
+	}
+
+	if code, resp = send("POST", "/api/v1/proxmox/vm/snapshots/rollback", map[string]interface{}{"node": "pve1", "vmid": 100, "name": "base", "start": true}); code != http.StatusOK || resp["finished"] != nil {
+		t.Errorf("Expected the rollback UPID without waiting, got %d: %v", code, resp)
+	}
+	if rolledBack.Get("start") != "1" {
+		t.Errorf("Unexpected rollback parameters: %v", rolledBack)
+	}
+	if code, _ = send("POST", "/api/v1/proxmox/vm/snapshots/delete", map[string]interface{}{"node": "pve1", "vmid": 100, "name": "branch", "force": true}); code != http.StatusOK || deleted != "force=1" {
+		t.Errorf("Expected the snapshot to be deleted with force, got %d (query %q)", code, deleted)
+	}
+
+	for name, body := range map[string]map[string]interface{}{
+		"reserved name":  {"node": "pve1", "vmid": 100, "name": "current"},
+		"lxc vmstate":    {"node": "pve1", "vmid": 100, "name": "snap1", "vmstate": true},
+		"invalid name":   {"node": "pve1", "vmid": 100, "name": "1-snap"},
+		"rollback extra": {"node": "pve1", "vmid": 100, "name": "snap1", "description": "x"},
+	} {
+		path := "/api/v1/proxmox/vm/snapshots/create"
+		switch name {
+		case "lxc vmstate":
+			path = "/api/v1/proxmox/lxc/snapshots/create"
+		case "rollback extra":
+			path = "/api/v1/proxmox/vm/snapshots/rollback"
+		}
+		if code, _ := send("POST", path, body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, code)
+		}
+	}
+	if code, _ := send("POST", "/api/v1/proxmox/vm/snapshots/clone", map[string]interface{}{"node": "pve1", "vmid": 100, "name": "base"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown operation, got %d", code)
+	}
+
+	// Âge des snapshots de l'inventaire, du plus ancien au plus récent
+	if code, resp := send("POST", "/api/v1/proxmox/inventory/refresh", nil); code != http.StatusOK {
+		t.Fatalf("Expected the inventory to be refreshed, got %d: %v", code, resp)
+	}
+	code, resp = send("GET", "/api/v1/proxmox/snapshots?older_than=7d", nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected the snapshot ages, got %d: %v", code, resp)
+	}
+	ages := resp["snapshots"].([]interface{})
+	if len(ages) != 2 || ages[0].(map[string]interface{})["name"] != "base" || ages[1].(map[string]interface{})["name"] != "branch" {
+		t.Fatalf("Expected the 2 snapshots older than 7 days, got %v", ages)
+	}
+	if first := ages[0].(map[string]interface{}); first["age_days"].(float64) < 29.9 || first["guest_name"] != "web" || first["cluster"] != "lab" {
+		t.Errorf("Unexpected snapshot age %v", first)
+	}
+	if code, _ := send("GET", "/api/v1/proxmox/snapshots?older_than=soon", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid older_than, got %d", code)
+	}
+}
+
+func TestRoutes_Backups(t *testing.T) {
+	now := time.Now()
+	failedUPID := "UPID:pve1:00000100:00000000:65B9F000:vzdump:100:root@pam:"
+	var mu sync.Mutex
+	vzdump := map[string]url.Values{}
+	var createdJob, updatedJob url.Values
+	var deletedJob string
+	sharedReads := 0
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/api2/json/nodes":
+			w.Write([]byte(`{"data":[{"node":"pve1","status":"online"},{"node":"pve2","status":"online"}]}`))
+		case r.URL.Path == "/api2/json/cluster/resources":
+			w.Write([]byte(`{"data":[{"type":"qemu","vmid":100,"node":"pve1","name":"web","pool":"prod"},{"type":"lxc","vmid":101,"node":"pve2","name":"db"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/storage") && strings.HasPrefix(r.URL.Path, "/api2/json/nodes/"):
+			w.Write([]byte(`{"data":[{"storage":"nas","content":"iso,backup","active":1,"shared":1,"enabled":1},{"storage":"local-lvm","content":"images,rootdir","active":1,"enabled":1}]}`))
+		case strings.HasSuffix(r.URL.Path, "/storage/nas/content"):
+			sharedReads++
+			if r.URL.Query().Get("content") != "backup" {
+				t.Errorf("Expected the backup content to be listed, got %q", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"data":[
+				{"volid":"nas:backup/vzdump-qemu-100-2024_01_31-02_00_03.vma.zst","format":"vma.zst","size":2147483648,"ctime":%d,"notes":"web, avant migration","protected":1,"subtype":"qemu","vmid":100},
+				{"volid":"nas:backup/ct/101/2024-01-30T02:00:00Z","format":"pbs-ct","size":1073741824,"ctime":%d}]}`,
+				now.Add(-24*time.Hour).Unix(), now.Add(-48*time.Hour).Unix())
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/vzdump"):
+			r.ParseForm()
+			node := strings.Split(r.URL.Path, "/")[4]
+			vzdump[node] = r.PostForm
+			fmt.Fprintf(w, `{"data":"UPID:%s:00000200:00000000:65B9F100:vzdump::root@pam:"}`, node)
+		case r.URL.Path == "/api2/json/cluster/tasks":
+			fmt.Fprintf(w, `{"data":[
+				{"upid":%q,"node":"pve1","type":"vzdump","id":"100","status":"job errors","starttime":%d,"endtime":%d},
+				{"upid":"UPID:pve2:00000101:00000000:65B9F000:vzdump::root@pam:","node":"pve2","type":"vzdump","status":"OK","starttime":%d,"endtime":%d},
+				{"upid":"UPID:pve1:00000102:00000000:65B9F000:qmstart:100:root@pam:","node":"pve1","type":"qmstart","id":"100","status":"OK","starttime":%d,"endtime":%d}]}`,
+				failedUPID, now.Add(-time.Hour).Unix(), now.Add(-50*time.Minute).Unix(),
+				now.Add(-2*time.Hour).Unix(), now.Add(-110*time.Minute).Unix(), now.Unix(), now.Unix())
+		case strings.HasSuffix(r.URL.Path, "/log") && strings.Contains(r.URL.Path, ":00000400:"):
+			w.Write([]byte(`{"data":[{"n":1,"t":"INFO: starting new backup job: vzdump 100 101 102"},{"n":2,"t":"ERROR: Backup of VM 100 failed - no space left on device"},{"n":3,"t":"INFO: Finished Backup of VM 101 (00:00:12)"},{"n":4,"t":"ERROR: Backup of VM 102 failed - guest is locked"},{"n":5,"t":"INFO: Backup job finished with errors"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/log") && strings.Contains(r.URL.Path, ":00000401:"):
+			w.Write([]byte(`{"data":[{"n":1,"t":"INFO: starting new backup job: vzdump 100 102"},{"n":2,"t":"INFO: Finished Backup of VM 100 (00:00:30)"},{"n":3,"t":"INFO: Backup job finished successfully"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/log"):
+			w.Write([]byte(`{"data":[{"n":1,"t":"INFO: starting new backup job: vzdump 100"},{"n":2,"t":"ERROR: Backup of VM 100 failed - no space left on device"},{"n":3,"t":"INFO: Backup job finished with errors"}]}`))
+		case r.URL.Path == "/api2/json/cluster/backup" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"data":[{"id":"backup-daily","type":"vzdump","schedule":"02:00","storage":"nas","vmid":"100,101","mode":"snapshot"},{"id":"weekly","type":"vzdump","enabled":0,"all":1,"schedule":"sun 03:00"}]}`))
+		case r.URL.Path == "/api2/json/cluster/backup" && r.Method == http.MethodPost:
+			r.ParseForm()
+			createdJob = r.PostForm
+			w.Write([]byte(`{"data":null}`))
+		case r.URL.Path == "/api2/json/cluster/backup/backup-daily" && r.Method == http.MethodPut:
+			r.ParseForm()
+			updatedJob = r.PostForm
+			w.Write([]byte(`{"data":null}`))
+		case r.URL.Path == "/api2/json/cluster/backup/backup-daily":
+			w.Write([]byte(`{"data":{"id":"backup-daily","schedule":"02:00","enabled":0,"vmid":"100"}}`))
+		case r.URL.Path == "/api2/json/cluster/backup/weekly" && r.Method == http.MethodDelete:
+			deletedJob = "weekly"
+			w.Write([]byte(`{"data":null}`))
+		default:
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+
+	var backupStore *store.Store
+	var connID int
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		connID = createTestConnection(t, s, proxmoxServer.URL)
+		h.SetPoller(inventory.NewPoller(s, time.Minute))
+		backupStore = s
+	})
+	token := login(t, router, "admin", "secret")
+
+	send := func(method, path string, body map[string]interface{}) (int, map[string]interface{}) {
+		var data []byte
+		if body != nil {
+			body["connection_id"] = connID
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	// Archives lues dans le contenu des storages ; le storage partagé n'est lu qu'une fois
+	if code, resp := send("POST", "/api/v1/proxmox/inventory/refresh", nil); code != http.StatusOK {
+		t.Fatalf("Expected the inventory to be refreshed, got %d: %v", code, resp)
+	}
+	code, resp := send("GET", "/api/v1/proxmox/backups", nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected the backups, got %d: %v", code, resp)
+	}
+	backups := resp["backups"].([]interface{})
+	if len(backups) != 2 || sharedReads != 1 {
+		t.Fatalf("Expected 2 backups read once from the shared storage, got %v (%d reads)", backups, sharedReads)
+	}
+	web, db := backups[0].(map[string]interface{}), backups[1].(map[string]interface{})
+	if web["vmid"] != float64(100) || web["type"] != "vm" || web["storage"] != "nas" || web["protected"] != true || web["notes"] != "web, avant migration" {
+		t.Errorf("Unexpected qemu backup %v", web)
+	}
+	if db["vmid"] != float64(101) || db["type"] != "lxc" || db["protected"] != false {
+		t.Errorf("Expected the PBS volid to be parsed, got %v", db)
+	}
+	if _, resp := send("GET", "/api/v1/proxmox/backups?vmid=101", nil); len(resp["backups"].([]interface{})) != 1 {
+		t.Errorf("Expected the vmid filter to keep a single backup, got %v", resp["backups"])
+	}
+
+	// Exécutions vzdump et journal de la tâche en échec
+	_, resp = send("GET", "/api/v1/proxmox/backups/runs?failed=true", nil)
+	runs := resp["runs"].([]interface{})
+	if len(runs) != 1 || runs[0].(map[string]interface{})["id"] != failedUPID || runs[0].(map[string]interface{})["vmid"] != float64(100) ||
+		runs[0].(map[string]interface{})["exitstatus"] != "job errors" {
+		t.Fatalf("Expected the failed vzdump run, got %v", runs)
+	}
+	code, resp = send("POST", "/api/v1/proxmox/tasks/log", map[string]interface{}{"upid": failedUPID})
+	if code != http.StatusOK || len(resp["data"].([]interface{})) != 3 {
+		t.Errorf("Expected the task log, got %d: %v", code, resp)
+	}
+
+	// Sauvegarde à la demande : une tâche par nœud
+	code, resp = send("POST", "/api/v1/proxmox/backups/run", map[string]interface{}{
+		"vmids": []int{101, 100, 100}, "mode": "stop", "compress": "zstd", "storage": "nas", "protected": true,
+	})
+	if code != http.StatusOK || len(resp["data"].([]interface{})) != 2 {
+		t.Fatalf("Expected a vzdump task per node, got %d: %v", code, resp)
+	}
+	if vzdump["pve1"].Get("vmid") != "100" || vzdump["pve2"].Get("vmid") != "101" || vzdump["pve1"].Get("mode") != "stop" ||
+		vzdump["pve1"].Get("compress") != "zstd" || vzdump["pve2"].Get("storage") != "nas" || vzdump["pve1"].Get("protected") != "1" {
+		t.Errorf("Unexpected vzdump parameters %v", vzdump)
+	}
+	if code, _ := send("POST", "/api/v1/proxmox/backups/run", map[string]interface{}{"vmids": []int{100}, "mode": "fast"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid mode, got %d", code)
+	}
+	if code, _ := send("POST", "/api/v1/proxmox/backups/run", map[string]interface{}{"vmids": []int{999}}); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown guest, got %d", code)
+	}
+
+	// Tâches planifiées du cluster
+	code, resp = send("POST", "/api/v1/proxmox/backups/jobs/list", map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Fatalf("Expected the backup jobs, got %d: %v", code, resp)
+	}
+	jobs := resp["data"].([]interface{})
+	daily, weekly := jobs[0].(map[string]interface{}), jobs[1].(map[string]interface{})
+	if daily["enabled"] != true || len(daily["vmids"].([]interface{})) != 2 || weekly["enabled"] != false || weekly["all"] != true {
+		t.Errorf("Unexpected backup jobs %v", jobs)
+	}
+
+	code, _ = send("POST", "/api/v1/proxmox/backups/jobs", map[string]interface{}{
+		"job": map[string]interface{}{"schedule": "daily", "all": true, "exclude": []int{101}, "storage": "nas"},
+	})
+	if code != http.StatusCreated || createdJob.Get("all") != "1" || createdJob.Get("exclude") != "101" || createdJob.Has("delete") {
+		t.Errorf("Expected the job to be created, got %d with %v", code, createdJob)
+	}
+	if code, _ := send("POST", "/api/v1/proxmox/backups/jobs", map[string]interface{}{"job": map[string]interface{}{"all": true}}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without schedule, got %d", code)
+	}
+	if code, _ := send("POST", "/api/v1/proxmox/backups/jobs", map[string]interface{}{
+		"job": map[string]interface{}{"schedule": "daily", "all": true, "pool": "prod"},
+	}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an ambiguous guest selection, got %d", code)
+	}
+
+	code, resp = send("PUT", "/api/v1/proxmox/backups/jobs/backup-daily", map[string]interface{}{
+		"job": map[string]interface{}{"enabled": false, "vmids": []int{100}, "comment": ""},
+	})
+	if code != http.StatusOK || resp["data"].(map[string]interface{})["enabled"] != false {
+		t.Fatalf("Expected the updated job, got %d: %v", code, resp)
+	}
+	if updatedJob.Get("enabled") != "0" || updatedJob.Get("vmid") != "100" || updatedJob.Get("delete") != "comment,all,pool,exclude" {
+		t.Errorf("Unexpected job update parameters %v", updatedJob)
+	}
+	if code, _ := send("DELETE", "/api/v1/proxmox/backups/jobs/weekly", map[string]interface{}{}); code != http.StatusOK || deletedJob != "weekly" {
+		t.Errorf("Expected the job to be deleted, got %d", code)
+	}
+
+	// Une sauvegarde en échec déclenche une alerte illustrée par son journal, la suivante réussie la résout
+	monitor := services.NewBackupMonitor(backupStore)
+	var fired []*models.Alert
+	monitor.OnAlert(func(alert *models.Alert) { fired = append(fired, alert) })
+
+	snapshot := func(at time.Time, tasks ...models.ProxmoxTask) *models.ProxmoxSnapshot {
+		return &models.ProxmoxSnapshot{
+			ProxmoxInventory: models.ProxmoxInventory{
+				VMs:      []models.ProxmoxGuest{{Cluster: "lab", VMID: 100, Name: "web", Type: "qemu", Node: "pve1"}},
+				Tasks:    tasks,
+				Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true}},
+			},
+			CollectedAt: at,
+		}
+	}
+	run := func(upid, status, exit string, end time.Time) models.ProxmoxTask {
+		return models.ProxmoxTask{ID: upid, Cluster: "lab", Node: "pve1", Name: "100", Type: "vzdump", Status: status, ExitStatus: exit, CompletedAt: &end}
+	}
+	failed := run(failedUPID, "failed", "job errors", now.Add(-30*time.Second))
+	first := snapshot(now.Add(-time.Minute))
+	second := snapshot(now, failed)
+	monitor.Observe(first, second)
+	monitor.Observe(second, snapshot(now.Add(time.Minute), failed))
+	if len(fired) != 1 || fired[0].Status != models.AlertStatusFiring || fired[0].Labels["id"] != "100" ||
+		!strings.Contains(fired[0].Title, "VM web (100)") || !strings.Contains(fired[0].Message, "no space left on device") {
+		t.Fatalf("Expected a single backup alert with the log excerpt, got %+v", fired)
+	}
+
+	warned := run("UPID:pve1:00000300:00000000:65B9F300:vzdump:100:root@pam:", "failed", "WARNINGS: 1", now.Add(90*time.Second))
+	monitor.Observe(snapshot(now.Add(time.Minute)), snapshot(now.Add(2*time.Minute), warned))
+	if len(fired) != 2 || fired[1].Status != models.AlertStatusResolved || fired[1].ID != fired[0].ID {
+		t.Fatalf("Expected a backup with warnings to resolve the alert, got %+v", fired)
+	}
+	if _, err := backupStore.GetOpenAlertByFingerprint(services.BackupAlertFingerprint("lab", "pve1", 100)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected no open backup alert, got %v", err)
+	}
+
+	// Une tâche planifiée couvrant plusieurs invités déclenche une alerte par invité en échec, et une exécution
+	// suivante ne résout que celles des invités qu'elle a sauvegardés
+	job := func(upid, status, exit string, end time.Time) models.ProxmoxTask {
+		task := run(upid, status, exit, end)
+		task.Name = ""
+		return task
+	}
+	fired = nil
+	jobFailed := job("UPID:pve1:00000400:00000000:65B9F400:vzdump::root@pam:", "failed", "job errors", now.Add(150*time.Second))
+	monitor.Observe(snapshot(now.Add(2*time.Minute)), snapshot(now.Add(3*time.Minute), jobFailed))
+	if len(fired) != 2 || fired[0].Labels["id"] != "100" || fired[1].Labels["id"] != "102" || fired[0].Fingerprint == fired[1].Fingerprint {
+		t.Fatalf("Expected one backup alert per failed guest, got %+v", fired)
+	}
+	if !strings.Contains(fired[0].Message, "no space left on device") || strings.Contains(fired[0].Message, "guest is locked") ||
+		!strings.Contains(fired[1].Title, "invité 102") || !strings.Contains(fired[1].Message, "guest is locked") {
+		t.Errorf("Expected each alert to carry its own guest's errors, got %+v", fired)
+	}
+
+	jobDone := job("UPID:pve1:00000401:00000000:65B9F500:vzdump::root@pam:", "completed", "OK", now.Add(210*time.Second))
+	monitor.Observe(snapshot(now.Add(3*time.Minute)), snapshot(now.Add(4*time.Minute), jobDone))
+	if len(fired) != 3 || fired[2].ID != fired[0].ID || fired[2].Status != models.AlertStatusResolved {
+		t.Fatalf("Expected the successful run to resolve the alert of VM 100 only, got %+v", fired)
+	}
+	if _, err := backupStore.GetOpenAlertByFingerprint(services.BackupAlertFingerprint("lab", "pve1", 102)); err != nil {
+		t.Errorf("Expected the alert of VM 102 to stay open, got %v", err)
+	}
+}
+
+func TestRoutes_BackupComplianceReport(t *testing.T) {
+	now := time.Now()
+	proxmoxServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/nodes":
+			w.Write([]byte(`{"data":[{"node":"pve1","status":"online"}]}`))
+		case "/api2/json/nodes/pve1/qemu":
+			w.Write([]byte(`{"data":[{"vmid":100,"name":"web","status":"stopped","tags":"prod;web"},{"vmid":9000,"name":"tpl","status":"stopped","template":1}]}`))
+		case "/api2/json/nodes/pve1/lxc":
+			w.Write([]byte(`{"data":[{"vmid":200,"name":"=dev","status":"stopped"}]}`))
+		case "/api2/json/cluster/resources":
+			w.Write([]byte(`{"data":[{"type":"qemu","vmid":100,"node":"pve1"},{"type":"lxc","vmid":200,"node":"pve1","pool":"dev"}]}`))
+		case "/api2/json/nodes/pve1/storage":
+			w.Write([]byte(`{"data":[{"storage":"nas","content":"backup","active":1,"enabled":1}]}`))
+		case "/api2/json/nodes/pve1/storage/nas/content":
+			fmt.Fprintf(w, `{"data":[
+				{"volid":"nas:backup/vzdump-qemu-100-a.vma.zst","size":2147483648,"ctime":%d,"subtype":"qemu","vmid":100},
+				{"volid":"nas:backup/vzdump-qemu-100-b.vma.zst","size":1073741824,"ctime":%d,"subtype":"qemu","vmid":100}]}`,
+				now.Add(-2*time.Hour).Unix(), now.Add(-26*time.Hour).Unix())
+		default:
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	t.Cleanup(proxmoxServer.Close)
+
+	policy, err := models.ParseBackupPolicy(24*time.Hour, "pool:dev=168")
+	if err != nil {
+		t.Fatalf("ParseBackupPolicy failed: %v", err)
+	}
+	var poller *inventory.Poller
+	var reportStore *store.Store
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		createTestConnection(t, s, proxmoxServer.URL)
+		poller = inventory.NewPoller(s, time.Minute)
+		h.SetPoller(poller)
+		h.SetBackupPolicy(policy)
+		reportStore = s
+	})
+	token := login(t, router, "admin", "secret")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	report := func(path string) models.BackupComplianceReport {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the report for %s, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data models.BackupComplianceReport `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Data
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/proxmox/inventory/refresh", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Le template n'est pas évalué ; le conteneur du pool dev n'a aucune sauvegarde
+	full := report("/api/v1/reports/backup-compliance")
+	if full.Total != 2 || full.Compliant != 1 || full.Violations != 1 {
+		t.Fatalf("Unexpected report totals %+v", full)
+	}
+	dev, web := full.Guests[0], full.Guests[1]
+	if dev.VMID != 200 || dev.Violation != models.BackupViolationNoBackup || dev.Policy != "pool:dev" || dev.Pool != "dev" {
+		t.Errorf("Unexpected non-compliant guest %+v", dev)
+	}
+	if web.VMID != 100 || !web.Compliant || web.RetentionCount != 2 || web.LastBackupSize != 2 || *web.SizeChangePercent != 100 || web.Policy != "default" {
+		t.Errorf("Unexpected compliant guest %+v", web)
+	}
+
+	if violations := report("/api/v1/reports/backup-compliance?violations=true"); violations.Total != 1 || violations.Guests[0].VMID != 200 {
+		t.Errorf("Expected only the violation, got %+v", violations)
+	}
+	if tagged := report("/api/v1/reports/backup-compliance?tag=PROD"); tagged.Total != 1 || tagged.Guests[0].VMID != 100 {
+		t.Errorf("Expected the tag filter to keep the prod guest, got %+v", tagged)
+	}
+
+	w := get("/api/v1/reports/backup-compliance?format=csv")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Header().Get("Content-Type") != "text/csv; charset=utf-8" || !strings.Contains(w.Header().Get("Content-Disposition"), "backup-compliance-") || len(lines) != 3 {
+		t.Fatalf("Unexpected CSV export %v: %s", w.Header(), w.Body.String())
+	}
+	if !strings.HasPrefix(lines[0], "cluster,vmid,name") || !strings.HasPrefix(lines[1], "lab,200,'=dev,lxc") || !strings.HasSuffix(lines[1], ",168,pool:dev,false,no_backup") {
+		t.Errorf("Unexpected CSV rows %q", lines)
+	}
+	if w := get("/api/v1/reports/backup-compliance?format=json"); w.Header().Get("Content-Type") != "application/json" || !strings.Contains(w.Body.String(), `"violations":1`) {
+		t.Errorf("Unexpected JSON export %v: %s", w.Header(), w.Body.String())
+	}
+	if w := get("/api/v1/reports/backup-compliance?format=xml"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported format, got %d", w.Code)
+	}
+
+	// Rapport quotidien : envoyé une fois par jour à l'heure configurée via la file d'emails
+	worker := email.NewWorker(reportStore, config.SMTPConfig{MaxAttempts: 3})
+	reporter := services.NewBackupReporter(policy, poller.Snapshot, worker, []string{"ops@example.com", "audit@example.com"}, 7)
+	reporter.SetPublicURL("https://dash.example.com")
+	due := reporter.NextRun(time.Now())
+	reporter.RunDue(due.Add(-time.Minute))
+	reporter.RunDue(due)
+	reporter.RunDue(due.Add(time.Hour))
+	emails, err := reportStore.GetEmailsByState(models.EmailStatePending, 10)
+	if err != nil {
+		t.Fatalf("Failed to list queued emails: %v", err)
+	}
+	if len(emails) != 2 || !strings.Contains(emails[0].Subject, "NON CONFORME") || !strings.Contains(emails[0].BodyText, "=dev (200)") ||
+		!strings.Contains(emails[0].BodyHTML, "https://dash.example.com") {
+		t.Fatalf("Expected one report per recipient, got %+v", emails)
+	}
+
+	// Un rapport partiel le signale dans l'export CSV et le rapport envoyé
+	partial := &models.ProxmoxSnapshot{ProxmoxInventory: models.ProxmoxInventory{
+		Clusters: []models.ProxmoxClusterStatus{{Name: "lab", Success: true, FailedBackupStorages: []string{"pve1/nas"}}},
+	}}
+	partialReporter := services.NewBackupReporter(policy, func() *models.ProxmoxSnapshot { return partial }, worker, []string{"ops@example.com"}, 7)
+	if _, err := partialReporter.Send(time.Now()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	emails, _ = reportStore.GetEmailsByState(models.EmailStatePending, 10)
+	if len(emails) != 3 || !strings.Contains(emails[0].Subject, "INCOMPLET") || !strings.Contains(emails[0].BodyText, "backups of pve1/nas could not be listed") ||
+		!strings.Contains(emails[0].BodyHTML, "Rapport incomplet") {
+		t.Errorf("Expected an incomplete report, got %+v", emails)
+	}
+
+	empty := services.NewBackupReporter(policy, func() *models.ProxmoxSnapshot { return nil }, worker, []string{"ops@example.com"}, 7)
+	if _, err := empty.Send(time.Now()); !errors.Is(err, services.ErrNoInventory) {
+		t.Errorf("Expected ErrNoInventory before the first collection, got %v", err)
+	}
+}
+
+func TestRoutes_AlertLifecycle(t *testing.T) {
+	router, _ := setupTestRouter(t)
+	token := login(t, router, "admin", "secret")
+
+	send := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, alert := send("POST", "/api/v1/alerts", map[string]interface{}{
+		"source": "prometheus", "severity": "high", "title": "Disk full", "message": "pve1: / at 97%",
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("Expected 201 on alert creation, got %d", code)
+	}
+	base := "/api/v1/alerts/" + strconv.Itoa(int(alert["id"].(float64)))
+
+	code, alert = send("PUT", base+"/acknowledge", nil)
+	if code != http.StatusOK || alert["status"] != "acknowledged" || alert["acknowledged_by"] != "admin" {
+		t.Fatalf("Expected the alert to be acknowledged by admin, got %d: %v", code, alert)
+	}
+	if code, _ := send("PUT", base+"/acknowledge", nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 when acknowledging twice, got %d", code)
+	}
+
+	if code, alert = send("PUT", base+"/snooze", map[string]interface{}{"duration": "1h"}); code != http.StatusOK || alert["snoozed_until"] == nil {
+		t.Errorf("Expected the alert to be snoozed, got %d: %v", code, alert)
+	}
+	if code, alert = send("PUT", base+"/assign", map[string]interface{}{"user_id": 1}); code != http.StatusOK || alert["assignee"] != "admin" {
+		t.Errorf("Expected the alert to be assigned to admin, got %d: %v", code, alert)
+	}
+	if code, _ := send("PUT", base+"/assign", map[string]interface{}{"user_id": 42}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown assignee, got %d", code)
+	}
+	if code, comment := send("POST", base+"/comments", map[string]interface{}{"body": "Purge des logs en cours"}); code != http.StatusCreated || comment["username"] != "admin" {
+		t.Errorf("Expected the comment to be created, got %d: %v", code, comment)
+	}
+
+	if code, alert = send("PUT", base+"/resolve", nil); code != http.StatusOK || alert["status"] != "resolved" || alert["resolved_by"] != "admin" {
+		t.Errorf("Expected the alert to be resolved, got %d: %v", code, alert)
+	}
+	if code, _ := send("PUT", "/api/v1/alerts/9999/acknowledge", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing alert, got %d", code)
+	}
+
+	code, silence := send("POST", "/api/v1/alerts/silences", map[string]interface{}{
+		"matchers": []map[string]string{{"name": "source", "value": "prometheus"}}, "duration": "2h", "comment": "Maintenance",
+	})
+	if code != http.StatusCreated || silence["state"] != "active" || silence["created_by"] != "admin" {
+		t.Fatalf("Expected an active silence, got %d: %v", code, silence)
+	}
+	if code, _ := send("POST", "/api/v1/alerts/silences", map[string]interface{}{"duration": "2h"}); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a silence without matchers, got %d", code)
+	}
+	silencePath := "/api/v1/alerts/silences/" + strconv.Itoa(int(silence["id"].(float64)))
+	if code, _ := send("DELETE", silencePath, nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 when expiring the silence, got %d", code)
+	}
+	if code, silence = send("GET", silencePath, nil); code != http.StatusOK || silence["state"] != "expired" {
+		t.Errorf("Expected the silence to be expired, got %d: %v", code, silence)
+	}
+}
+
+func TestRoutes_AlertmanagerReceiver(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, _ *store.Store) { h.SetAlertmanagerToken("am-secret") })
+	session := login(t, router, "admin", "secret")
+
+	post := func(token string, webhook models.AlertmanagerWebhook) (int, models.AlertmanagerResult) {
+		data, _ := json.Marshal(webhook)
+		req := httptest.NewRequest("POST", "/api/v1/alerts/alertmanager", bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result models.AlertmanagerResult
+		json.NewDecoder(w.Body).Decode(&result)
+		return w.Code, result
+	}
+	alert := func(status string) models.AlertmanagerWebhook {
+		return models.AlertmanagerWebhook{Version: "4", Status: status, Receiver: "proxmoxdash", Alerts: []models.AlertmanagerAlert{{
+			Status:      status,
+			Labels:      map[string]string{"alertname": "LowDiskSpace", "severity": "critical", "instance": "pve1:9100"},
+			Annotations: map[string]string{"summary": "Espace disque faible sur pve1:9100", "description": "Il reste 9% d'espace disque"},
+			Fingerprint: "c0ffee",
+		}}}
+	}
+
+	if code, _ := post("wrong-token", alert("firing")); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", code)
+	}
+
+	code, result := post("am-secret", alert("firing"))
+	if code != http.StatusOK || len(result.Created) != 1 {
+		t.Fatalf("Expected the alert to be created, got %d: %+v", code, result)
+	}
+	if code, result := post(session, alert("firing")); code != http.StatusOK || len(result.Created) != 0 || result.Deduplicated != 1 {
+		t.Errorf("Expected the repeated alert to be deduplicated, got %d: %+v", code, result)
+	}
+
+	id := strconv.Itoa(result.Created[0])
+	req := httptest.NewRequest("GET", "/api/v1/alerts/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+session)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var created models.Alert
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Source != "alertmanager:LowDiskSpace" || created.Severity != "critical" ||
+		created.Title != "Espace disque faible sur pve1:9100" || created.Labels["instance"] != "pve1:9100" {
+		t.Errorf("Unexpected mapping of the Alertmanager alert: %+v", created)
+	}
+
+	if code, result := post("am-secret", alert("resolved")); code != http.StatusOK || len(result.Resolved) != 1 {
+		t.Errorf("Expected the alert to be resolved, got %d: %+v", code, result)
+	}
+	if code, result := post("am-secret", alert("resolved")); code != http.StatusOK || result.Ignored != 1 {
+		t.Errorf("Expected a second resolution to be ignored, got %d: %+v", code, result)
+	}
+}
+
+func TestRoutes_MetricsExporter(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		h.SetExporter(exporter.NewExporter(s))
+		h.SetMetricsToken("scrape-secret")
+	})
+	session := login(t, router, "admin", "secret")
+
+	if code := doRequest(router, "GET", "/metrics", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/metrics", "wrong-token"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", code)
+	}
+	if code := doRequest(router, "GET", "/metrics", session); code != http.StatusOK {
+		t.Errorf("Expected 200 with a session token, got %d", code)
+	}
+	// Le token de scrape ne donne accès qu'à /metrics
+	if code := doRequest(router, "GET", "/api/v1/alerts", "scrape-secret"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 on the API with the scrape token, got %d", code)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with the scrape token, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `proxmoxdash_http_requests_total{method="GET",route="/metrics",code="401"} 2`) {
+		t.Errorf("Expected the HTTP requests to be counted, got:\n%s", w.Body.String())
+	}
+}
+
+func TestRoutes_PrometheusProxy(t *testing.T) {
+	var lastPath, lastAuth string
+	var lastQuery url.Values
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath, lastAuth, lastQuery = r.URL.Path, r.Header.Get("Authorization"), r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer upstream.Close()
+
+	router, _ := setupTestRouterWith(t, func(_ *handlers.Handlers, s *store.Store) {
+		cipher, err := secrets.NewCipher("test-key")
+		if err != nil {
+			t.Fatalf("Failed to create cipher: %v", err)
+		}
+		s.SetCipher(cipher)
+	})
+	session := login(t, router, "admin", "secret")
+
+	get := func(path string) (int, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	if code, _ := get("/api/v1/prometheus/query?query=up"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without data source, got %d", code)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "Prometheus", "url": upstream.URL, "auth_type": "bearer", "secret": "prom-token", "is_default": true,
+	})
+	req := httptest.NewRequest("POST", "/api/v1/prometheus/datasources", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+session)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), "prom-token") {
+		t.Fatalf("Expected the data source to be created without exposing its secret, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Les paramètres sont filtrés et réencodés, l'authentification de la source est ajoutée
+	code, resp := get("/api/v1/prometheus/query?query=" + url.QueryEscape(`up{job="node"} & x`) + "&foo=bar")
+	if code != http.StatusOK || !strings.Contains(resp, `"status":"success"`) {
+		t.Fatalf("Expected the upstream response, got %d: %s", code, resp)
+	}
+	if lastPath != "/api/v1/query" || lastAuth != "Bearer prom-token" ||
+		lastQuery.Get("query") != `up{job="node"} & x` || lastQuery.Has("foo") {
+		t.Errorf("Unexpected upstream request: %s %q %v", lastPath, lastAuth, lastQuery)
+	}
+
+	if code, _ := get("/api/v1/prometheus/label/job/values"); code != http.StatusOK || lastPath != "/api/v1/label/job/values" {
+		t.Errorf("Expected label values to be proxied, got %d on %s", code, lastPath)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/prometheus/query?query=up&base_url=http://169.254.169.254": http.StatusBadRequest,
+		"/api/v1/prometheus/query_range?query=up&start=1&end=2":             http.StatusBadRequest,
+		"/api/v1/prometheus/series":                                         http.StatusBadRequest,
+		"/api/v1/prometheus/label/bad-name/values":                          http.StatusBadRequest,
+		"/api/v1/prometheus/query?query=up&datasource=99":                   http.StatusNotFound,
+	} {
+		if code, _ := get(path); code != want {
+			t.Errorf("Expected %d for %s, got %d", want, path, code)
+		}
+	}
+}
+
+func TestRoutes_PrometheusMock(t *testing.T) {
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, _ *store.Store) { h.SetPrometheusMock(true) })
+	session := login(t, router, "admin", "secret")
+
+	req := httptest.NewRequest("GET", "/api/v1/prometheus/query_range?query=up&start=0&end=600&step=60", nil)
+	req.Header.Set("Authorization", "Bearer "+session)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Values [][2]interface{} `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected a mock response, got %d (%v)", w.Code, err)
+	}
+	if resp.Status != "success" || resp.Data.ResultType != "matrix" || len(resp.Data.Result) != 1 || len(resp.Data.Result[0].Values) != 11 {
+		t.Errorf("Unexpected mock matrix: %+v", resp)
+	}
+}
+
+func TestRoutes_AppHealthScheduler(t *testing.T) {
+	healthy := true
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	port, _ := strconv.Atoi(targetURL.Port())
+
+	var scheduler *services.HealthScheduler
+	var appStore *store.Store
+	var alerts []*models.Alert
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		appStore = s
+		scheduler = services.NewHealthScheduler(s, time.Minute)
+		scheduler.SetFailureThreshold(2)
+		scheduler.OnAlert(func(alert *models.Alert) { alerts = append(alerts, alert) })
+		h.SetHealthScheduler(scheduler)
+	})
+	session := login(t, router, "admin", "secret")
+
+	get := func(path string, v interface{}) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		json.NewDecoder(w.Body).Decode(v)
+		return w.Code
+	}
+
+	body, _ := json.Marshal(models.CreateAppRequest{
+		Name: "API", Protocol: "http", Host: targetURL.Hostname(), Port: port, Path: "/", HealthPath: "/health", HealthType: "http", CheckInterval: 15,
+	})
+	req := httptest.NewRequest("POST", "/api/v1/apps", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+session)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var app models.App
+	json.NewDecoder(w.Body).Decode(&app)
+	if w.Code != http.StatusCreated || app.CheckInterval != 15 {
+		t.Fatalf("Expected the app to be created with its interval, got %d: %+v", w.Code, app)
+	}
+
+	// online → offline → offline → online : l'alerte n'est déclenchée qu'au second échec consécutif, puis résolue
+	scheduler.Check(&app)
+	healthy = false
+	scheduler.Check(&app)
+	if len(alerts) != 0 {
+		t.Fatalf("Expected no alert below the failure threshold, got %+v", alerts)
+	}
+	scheduler.Check(&app)
+	healthy = true
+	scheduler.Check(&app)
+	if len(alerts) != 2 || alerts[0].Status != models.AlertStatusFiring || alerts[1].Status != models.AlertStatusResolved ||
+		alerts[0].Fingerprint != services.HealthAlertFingerprint(app.ID) || !strings.Contains(alerts[0].Message, "2 fois de suite") {
+		t.Fatalf("Expected one fired then resolved alert, got %+v", alerts)
+	}
+
+	var health models.AppHealth
+	if code := get(fmt.Sprintf("/api/v1/apps/%d/health?limit=3", app.ID), &health); code != http.StatusOK {
+		t.Fatalf("Expected 200 on app health, got %d", code)
+	}
+	if health.IntervalSeconds != 15 || len(health.Checks) != 3 || health.Current == nil || health.Current.Status != "online" {
+		t.Errorf("Unexpected app health: %+v", health)
+	}
+
+	var uptime models.AppUptime
+	if code := get(fmt.Sprintf("/api/v1/apps/%d/uptime", app.ID), &uptime); code != http.StatusOK {
+		t.Fatalf("Expected 200 on app uptime, got %d", code)
+	}
+	if len(uptime.Windows) != 3 || uptime.Windows[0].Checks != 4 || *uptime.Windows[0].UptimePercent != 50 {
+		t.Errorf("Unexpected app uptime: %+v", uptime)
+	}
+
+	if code := get("/api/v1/apps/999/uptime", &uptime); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown app, got %d", code)
+	}
+
+	// Au démarrage, l'alerte ouverte d'une application supprimée pendant l'arrêt est résolue
+	orphan := &models.Alert{Source: services.HealthAlertSourcePrefix + "Old", Severity: "high", Title: "Application Old injoignable",
+		Labels: map[string]string{}, CreatedAt: time.Now(), Status: models.AlertStatusFiring, Fingerprint: services.HealthAlertFingerprint(999)}
+	if err := appStore.CreateAlert(orphan); err != nil {
+		t.Fatalf("Failed to create alert: %v", err)
+	}
+	if err := appStore.DeleteApp(app.ID); err != nil {
+		t.Fatalf("Failed to delete app: %v", err)
+	}
+	alerts = nil
+	restarted := services.NewHealthScheduler(appStore, time.Minute)
+	restarted.OnAlert(func(alert *models.Alert) { alerts = append(alerts, alert) })
+	restarted.RunDue(time.Now())
+	if len(alerts) != 1 || alerts[0].ID != orphan.ID || alerts[0].Status != models.AlertStatusResolved {
+		t.Errorf("Expected the alert of the deleted app to be resolved on start, got %+v", alerts)
+	}
+}
+
+func TestRoutes_MetricsQuery(t *testing.T) {
+	var st *store.Store
+	router, _ := setupTestRouterWith(t, func(h *handlers.Handlers, s *store.Store) {
+		h.SetMetrics(services.NewMetricsService(s))
+		st = s
+	})
+	token := login(t, router, "admin", "secret")
+	createTestConnection(t, st, "https://pve1.example.com:8006")
+
+	now := time.Now()
+	if err := st.RecordMetricSamples([]models.MetricSample{
+		{Kind: "qemu", Cluster: "lab", ObjectID: "100", Timestamp: now.Add(-2 * time.Minute), CPU: 10},
+		{Kind: "qemu", Cluster: "prod", ObjectID: "100", Timestamp: now.Add(-2 * time.Minute), CPU: 90},
+	}); err != nil {
+		t.Fatalf("Failed to record samples: %v", err)
+	}
+
+	query := func(params string) (int, models.MetricSeries) {
+		var series models.MetricSeries
+		req := httptest.NewRequest("GET", "/api/v1/metrics/qemu/100?"+params, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			json.NewDecoder(w.Body).Decode(&series)
+		}
+		return w.Code, series
+	}
+
+	// Sans pas explicite, une courte période donne un pas d'au moins une minute
+	from := strconv.FormatInt(now.Add(-5*time.Minute).Unix(), 10)
+	code, series := query("from=" + from)
+	if code != http.StatusOK {
+		t.Fatalf("Expected a 5 minute range without step to succeed, got %d", code)
+	}
+	if series.Step < 60 {
+		t.Errorf("Expected a step of at least 60s, got %d", series.Step)
+	}
+
+	// Avec plusieurs connexions actives, le cluster est obligatoire
+	prod := &models.ProxmoxConnection{Name: "prod", URL: "https://pve2.example.com:8006", Username: "root@pam!dashboard", Secret: "secret", Enabled: true}
+	if err := st.CreateProxmoxConnection(prod); err != nil {
+		t.Fatalf("Failed to create connection: %v", err)
+	}
+	if code, _ := query("from=" + from); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without cluster when two connections are enabled, got %d", code)
+	}
+	code, series = query("from=" + from + "&cluster=lab")
+	if code != http.StatusOK {
+		t.Fatalf("Expected the lab series, got %d", code)
+	}
+	if len(series.Points) != 1 || series.Points[0].CPU != 10 {
+		t.Errorf("Expected only the lab sample, got %+v", series.Points)
+	}
+}
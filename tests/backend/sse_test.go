@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"testing"
+	"time"
+
+	"proxmox-dashboard/internal/models"
+)
+
+// guestEvent construit un événement de création d'invité dans le pool donné
+func guestEvent(vmid, pool string) models.SSEEvent {
+	return models.SSEEvent{
+		Type: models.EventVMCreated,
+		Data: models.GuestLifecycleEvent{
+			InventoryEventSource: models.InventoryEventSource{Cluster: "lab", Kind: "qemu", ID: vmid, Node: "pve1", Pool: pool},
+		},
+	}
+}
+
+// received lit les événements reçus par le client jusqu'à l'événement marqueur "ping"
+func received(t *testing.T, client *Client) []string {
+	t.Helper()
+	var types []string
+	for {
+		select {
+		case event := <-client.Channel:
+			if event.Type == "ping" {
+				return types
+			}
+			types = append(types, event.Type)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("client %s: ping marker not received (got %v)", client.ID, types)
+		}
+	}
+}
+
+func TestHub_FiltersEventsByPermissions(t *testing.T) {
+	hub := NewHub()
+	go hub.run()
+
+	devOperator := &models.User{Username: "dev", Role: "dev-operators", Permissions: []models.Permission{
+		{Resource: "proxmox.vm", Action: "power", Scope: &models.PermissionScope{Pools: []string{"dev"}}},
+	}}
+	clients := map[string]*Client{
+		"viewer": {ID: "viewer", User: &models.User{Username: "viewer", Role: models.RoleViewer}},
+		"dev":    {ID: "dev", User: devOperator},
+		"guest":  {ID: "guest", User: &models.User{Username: "guest", Role: models.RoleGuest}},
+	}
+	for _, client := range clients {
+		client.Channel = make(chan models.SSEEvent, clientBufferSize)
+		hub.RegisterClient(client)
+	}
+
+	hub.BroadcastAlert(&models.Alert{ID: 1})
+	hub.Broadcast(guestEvent("100", "dev"))
+	hub.Broadcast(guestEvent("200", "prod"))
+	hub.Broadcast(models.SSEEvent{Type: models.EventNodeOffline, Data: models.StatusChangeEvent{
+		InventoryEventSource: models.InventoryEventSource{Cluster: "lab", Kind: "node", ID: "pve1", Node: "pve1"},
+	}})
+	hub.Broadcast(models.SSEEvent{Type: "ping"})
+
+	want := map[string][]string{
+		"viewer": {"alert", models.EventVMCreated, models.EventVMCreated, models.EventNodeOffline},
+		"dev":    {models.EventVMCreated},
+		"guest":  nil,
+	}
+	for name, client := range clients {
+		got := received(t, client)
+		if len(got) != len(want[name]) {
+			t.Fatalf("%s: received %v, want %v", name, got, want[name])
+		}
+		for i := range got {
+			if got[i] != want[name][i] {
+				t.Errorf("%s: received %v, want %v", name, got, want[name])
+			}
+		}
+	}
+}
+
+func TestHub_KeepsSlowClients(t *testing.T) {
+	hub := NewHub()
+	go hub.run()
+
+	client := &Client{
+		ID:      "slow",
+		Channel: make(chan models.SSEEvent, clientBufferSize),
+		User:    &models.User{Username: "viewer", Role: models.RoleViewer},
+	}
+	hub.RegisterClient(client)
+
+	// Le client ne lit rien : les événements au-delà du tampon sont perdus, la connexion est conservée
+	for i := 0; i < clientBufferSize+50; i++ {
+		hub.Broadcast(guestEvent("100", ""))
+		time.Sleep(time.Millisecond) // laisse le hub vider son canal de diffusion
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.Channel) < clientBufferSize && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(client.Channel) != clientBufferSize {
+		t.Fatalf("expected a full buffer of %d events, got %d", clientBufferSize, len(client.Channel))
+	}
+	if hub.GetClientCount() != 1 {
+		t.Fatalf("slow client should stay registered, got %d client(s)", hub.GetClientCount())
+	}
+
+	// Le client reprend la lecture et reçoit de nouveau les événements suivants
+	for len(client.Channel) > 0 {
+		<-client.Channel
+	}
+	hub.Broadcast(models.SSEEvent{Type: "ping"})
+	if got := received(t, client); len(got) != 0 {
+		t.Fatalf("unexpected events after draining: %v", got)
+	}
+}
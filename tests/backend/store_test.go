@@ -2,7 +2,9 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"proxmox-dashboard/internal/models"
+	"proxmox-dashboard/internal/secrets"
 	"testing"
 	"time"
 
@@ -439,3 +441,578 @@ func TestStore_UpdateEmailStatus(t *testing.T) {
 		t.Errorf("Expected state 'sent', got %s", updated.State)
 	}
 }
+
+func TestStore_ProxmoxConnections(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	store.SetCipher(cipher)
+
+	conn := &models.ProxmoxConnection{
+		Name:      "Cluster A",
+		URL:       "https://pve-a.example.com:8006",
+		Username:  "root@pam!dashboard",
+		Secret:    "token-secret",
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := store.CreateProxmoxConnection(conn); err != nil {
+		t.Fatalf("Failed to create connection: %v", err)
+	}
+
+	// Le secret ne doit pas être stocké en clair
+	var stored string
+	if err := store.db.QueryRow("SELECT secret FROM proxmox_connections WHERE id = ?", conn.ID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read raw secret: %v", err)
+	}
+	if stored == "token-secret" || stored == "" {
+		t.Errorf("Expected encrypted secret, got %q", stored)
+	}
+
+	retrieved, err := store.GetProxmoxConnection(conn.ID)
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if retrieved.Secret != "token-secret" || retrieved.HasPassword {
+		t.Errorf("Unexpected decrypted connection: %+v", retrieved)
+	}
+
+	retrieved.Password = "console-password"
+	if err := store.UpdateProxmoxConnection(retrieved); err != nil {
+		t.Fatalf("Failed to update connection: %v", err)
+	}
+
+	conns, err := store.GetProxmoxConnections()
+	if err != nil {
+		t.Fatalf("Failed to list connections: %v", err)
+	}
+	if len(conns) != 1 || conns[0].Password != "console-password" || !conns[0].HasPassword {
+		t.Errorf("Unexpected connections: %+v", conns)
+	}
+
+	if err := store.DeleteProxmoxConnection(conn.ID); err != nil {
+		t.Fatalf("Failed to delete connection: %v", err)
+	}
+	if _, err := store.GetProxmoxConnection(conn.ID); err == nil {
+		t.Error("Expected error after deletion")
+	}
+}
+
+func TestStore_ProxmoxConnectionsWithoutCipher(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	conn := &models.ProxmoxConnection{Name: "Cluster A", URL: "https://pve", Username: "root@pam!x", Secret: "s"}
+	if err := store.CreateProxmoxConnection(conn); err != ErrNoCipher {
+		t.Errorf("Expected ErrNoCipher, got %v", err)
+	}
+}
+
+func TestStore_ProxmoxSnapshot(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	if _, err := store.GetProxmoxSnapshot(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Expected sql.ErrNoRows before first save, got %v", err)
+	}
+
+	for _, name := range []string{"pve1", "pve2"} {
+		snapshot := &models.ProxmoxSnapshot{
+			ProxmoxInventory: models.ProxmoxInventory{
+				Nodes: []models.ProxmoxNode{{ID: name, Name: name, Cluster: "paris"}},
+			},
+			CollectedAt: time.Now(),
+		}
+		if err := store.SaveProxmoxSnapshot(snapshot); err != nil {
+			t.Fatalf("Failed to save snapshot: %v", err)
+		}
+	}
+
+	retrieved, err := store.GetProxmoxSnapshot()
+	if err != nil {
+		t.Fatalf("Failed to get snapshot: %v", err)
+	}
+	if len(retrieved.Nodes) != 1 || retrieved.Nodes[0].Name != "pve2" || retrieved.Nodes[0].Cluster != "paris" {
+		t.Errorf("Expected only the latest snapshot, got %+v", retrieved.Nodes)
+	}
+}
+
+func TestStore_MetricsRollupsAndRetention(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	base := time.Now().Truncate(time.Hour).Add(-2 * time.Hour)
+	samples := []models.MetricSample{
+		{Kind: "node", Cluster: "paris", ObjectID: "pve1", Timestamp: base, CPU: 10, Memory: 40},
+		{Kind: "node", Cluster: "paris", ObjectID: "pve1", Timestamp: base.Add(time.Minute), CPU: 30, Memory: 60},
+	}
+	if err := store.RecordMetricSamples(samples); err != nil {
+		t.Fatalf("Failed to record samples: %v", err)
+	}
+
+	raw, err := store.QueryMetrics(models.MetricsQuery{
+		Kind: "node", ObjectID: "pve1", From: base, To: base.Add(time.Hour),
+		Step: time.Minute, Resolution: models.MetricsResolutionRaw,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query raw metrics: %v", err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("Expected 2 raw points, got %d", len(raw))
+	}
+
+	rollup, err := store.QueryMetrics(models.MetricsQuery{
+		Kind: "node", Cluster: "paris", ObjectID: "pve1", From: base, To: base.Add(time.Hour),
+		Step: 5 * time.Minute, Resolution: models.MetricsResolution5m,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query rollup: %v", err)
+	}
+	if len(rollup) != 1 || rollup[0].CPU != 20 || rollup[0].Memory != 50 {
+		t.Errorf("Expected one averaged 5m point (cpu 20, memory 50), got %+v", rollup)
+	}
+
+	// Un import historique ne doit pas écraser un intervalle déjà mesuré
+	if _, err := store.BackfillMetricSamples([]models.MetricSample{
+		{Kind: "node", Cluster: "paris", ObjectID: "pve1", Timestamp: base, CPU: 99},
+	}); err != nil {
+		t.Fatalf("Failed to backfill: %v", err)
+	}
+	rollup, _ = store.QueryMetrics(models.MetricsQuery{
+		Kind: "node", ObjectID: "pve1", From: base, To: base.Add(time.Hour),
+		Step: 5 * time.Minute, Resolution: models.MetricsResolution5m,
+	})
+	if len(rollup) != 1 || rollup[0].CPU != 20 {
+		t.Errorf("Backfill overwrote an existing rollup: %+v", rollup)
+	}
+
+	// Deux jours plus tard, seuls les points bruts ont expiré
+	if _, err := store.PruneMetrics(base.Add(48 * time.Hour)); err != nil {
+		t.Fatalf("Failed to prune metrics: %v", err)
+	}
+	raw, _ = store.QueryMetrics(models.MetricsQuery{
+		Kind: "node", ObjectID: "pve1", From: base, To: base.Add(time.Hour),
+		Step: time.Minute, Resolution: models.MetricsResolutionRaw,
+	})
+	rollup, _ = store.QueryMetrics(models.MetricsQuery{
+		Kind: "node", ObjectID: "pve1", From: base, To: base.Add(time.Hour),
+		Step: time.Hour, Resolution: models.MetricsResolutionHourly,
+	})
+	if len(raw) != 0 || len(rollup) != 1 {
+		t.Errorf("Expected raw points pruned and hourly kept, got %d raw and %d hourly", len(raw), len(rollup))
+	}
+}
+
+func TestStore_AuditLogFiltersAndSurvivesClear(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	start := time.Now().Add(-time.Hour)
+	entries := []models.AuditEntry{
+		{Timestamp: start, Username: "alice", Action: "vm.restart", Target: "pve1/qemu/100", Status: 200, Result: models.AuditResultSuccess},
+		{Timestamp: start.Add(10 * time.Minute), Username: "bob", Action: "vm.stop", Target: "pve1/qemu/100_old", Status: 403, Result: models.AuditResultDenied},
+		{Timestamp: start.Add(20 * time.Minute), Username: "alice", Action: "app.delete", Target: "app/3", Status: 204, Result: models.AuditResultSuccess},
+	}
+	for i := range entries {
+		entries[i].Method, entries[i].Path = "POST", "/api/v1/test"
+		if err := store.CreateAuditEntry(&entries[i]); err != nil {
+			t.Fatalf("Failed to create audit entry: %v", err)
+		}
+	}
+
+	got, total, err := store.QueryAuditLog(models.AuditQuery{Action: "vm.", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	if total != 2 || got[0].Username != "bob" {
+		t.Errorf("Expected the 2 vm.* entries, most recent first, got %d: %+v", total, got)
+	}
+
+	// Les caractères spéciaux de LIKE sont échappés
+	if _, total, _ := store.QueryAuditLog(models.AuditQuery{Target: "100_", Limit: 10}); total != 1 {
+		t.Errorf("Expected 1 entry matching the literal target, got %d", total)
+	}
+
+	got, total, _ = store.QueryAuditLog(models.AuditQuery{Username: "alice", From: start.Add(5 * time.Minute), Limit: 10})
+	if total != 1 || got[0].Action != "app.delete" {
+		t.Errorf("Expected alice's entry after from, got %d: %+v", total, got)
+	}
+
+	if err := store.ClearDatabase(); err != nil {
+		t.Fatalf("Failed to clear database: %v", err)
+	}
+	if _, total, _ := store.QueryAuditLog(models.AuditQuery{Limit: 10}); total != 3 {
+		t.Errorf("Expected the audit log to survive ClearDatabase, got %d entries", total)
+	}
+}
+
+func TestStore_ClearDatabaseKeepsIdentity(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	for _, stmt := range []string{
+		`INSERT INTO users (id, username, email, password_hash, role) VALUES (1, 'admin', 'admin@example.com', 'hash', 'admin')`,
+		`INSERT INTO user_sessions (id, user_id, token, expires_at) VALUES ('s1', 1, 'token', datetime('now', '+1 day'))`,
+		`INSERT INTO roles (name, description, permissions, built_in) VALUES ('dev-team', '', '[]', 0)`,
+	} {
+		if _, err := store.db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed identity tables: %v", err)
+		}
+	}
+	if err := store.CreateApp(&models.App{Name: "app", Protocol: "http", Host: "localhost", Port: 80, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+
+	if err := store.ClearDatabase(); err != nil {
+		t.Fatalf("Failed to clear database: %v", err)
+	}
+
+	if apps, _ := store.GetApps(); len(apps) != 0 {
+		t.Errorf("Expected the apps to be cleared, got %d", len(apps))
+	}
+	for table, want := range map[string]int{"users": 1, "user_sessions": 1, "roles WHERE built_in = 0": 1} {
+		var count int
+		if err := store.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil || count != want {
+			t.Errorf("Expected %d rows in %s after ClearDatabase, got %d (%v)", want, table, count, err)
+		}
+	}
+}
+
+func TestStore_MigrateLegacyEmailQueue(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	// Table créée par les versions précédentes (état par défaut queued, sans colonnes de relance)
+	if _, err := db.Exec(`CREATE TABLE email_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, to_addr TEXT NOT NULL, subject TEXT NOT NULL,
+		body_text TEXT NOT NULL, state TEXT DEFAULT 'queued', last_error TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, sent_at DATETIME)`); err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO email_queue (to_addr, subject, body_text) VALUES ('a@example.com', 'Legacy', 'Body')`); err != nil {
+		t.Fatalf("Failed to insert legacy email: %v", err)
+	}
+
+	store := NewStore(db)
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	queued, err := store.GetQueuedEmails(time.Now(), 10)
+	if err != nil {
+		t.Fatalf("GetQueuedEmails() error = %v", err)
+	}
+	if len(queued) != 1 || queued[0].State != models.EmailStatePending || queued[0].MaxAttempts != models.DefaultEmailMaxAttempts {
+		t.Errorf("Expected the legacy queued email to be pending with default retries, got %+v", queued)
+	}
+}
+
+func TestStore_AlertLifecycle(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	alert := &models.Alert{Source: "rule:CPU", Severity: "high", Title: "CPU pve1", Message: "node.cpu = 95",
+		Labels: map[string]string{"node": "pve1"}, CreatedAt: time.Now()}
+	if err := store.CreateAlert(alert); err != nil {
+		t.Fatalf("CreateAlert() error = %v", err)
+	}
+
+	if err := store.AcknowledgeAlert(alert.ID, "alice"); err != nil {
+		t.Fatalf("AcknowledgeAlert() error = %v", err)
+	}
+	if err := store.AcknowledgeAlert(alert.ID, "bob"); !errors.Is(err, models.ErrAlertTransition) {
+		t.Errorf("Expected a second acknowledge to be rejected, got %v", err)
+	}
+	if err := store.AcknowledgeAlert(9999, "alice"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing alert, got %v", err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	if err := store.SnoozeAlert(alert.ID, &until); err != nil {
+		t.Fatalf("SnoozeAlert() error = %v", err)
+	}
+	got, err := store.GetAlert(alert.ID)
+	if err != nil {
+		t.Fatalf("GetAlert() error = %v", err)
+	}
+	if got.Status != models.AlertStatusAcknowledged || !got.Acknowledged || got.AcknowledgedBy != "alice" ||
+		got.AcknowledgedAt == nil || !got.IsSnoozed(time.Now()) || got.Labels["node"] != "pve1" {
+		t.Errorf("Unexpected acknowledged alert: %+v", got)
+	}
+	if muted, err := store.IsAlertMuted(got); err != nil || !muted {
+		t.Errorf("Expected a snoozed alert to be muted, got %v (%v)", muted, err)
+	}
+
+	if err := store.ResolveAlert(got, "alice", time.Now()); err != nil {
+		t.Fatalf("ResolveAlert() error = %v", err)
+	}
+	if err := store.SnoozeAlert(alert.ID, &until); !errors.Is(err, models.ErrAlertTransition) {
+		t.Errorf("Expected snoozing a resolved alert to be rejected, got %v", err)
+	}
+	if got, _ = store.GetAlert(alert.ID); got.Status != models.AlertStatusResolved || got.ResolvedBy != "alice" || got.ResolvedAt == nil {
+		t.Errorf("Unexpected resolved alert: %+v", got)
+	}
+
+	comment := &models.AlertComment{AlertID: alert.ID, Username: "alice", Body: "Redémarrage planifié", CreatedAt: time.Now()}
+	if err := store.CreateAlertComment(comment); err != nil {
+		t.Fatalf("CreateAlertComment() error = %v", err)
+	}
+	comments, err := store.GetAlertComments(alert.ID)
+	if err != nil || len(comments) != 1 || comments[0].Body != "Redémarrage planifié" {
+		t.Errorf("Unexpected comments: %+v (%v)", comments, err)
+	}
+}
+
+func TestStore_Silences(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	now := time.Now()
+	silence := &models.Silence{
+		Matchers:  []models.LabelMatcher{{Name: "node", Value: "pve1"}, {Name: "severity", Operator: "=~", Value: "high|critical"}},
+		StartsAt:  now.Add(-time.Minute),
+		EndsAt:    now.Add(time.Hour),
+		Comment:   "Maintenance pve1",
+		CreatedBy: "alice",
+		CreatedAt: now,
+	}
+	if err := silence.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err := store.CreateSilence(silence); err != nil {
+		t.Fatalf("CreateSilence() error = %v", err)
+	}
+
+	covered := &models.Alert{Source: "rule:CPU", Severity: "critical", Title: "CPU", Labels: map[string]string{"node": "pve1"}}
+	other := &models.Alert{Source: "rule:CPU", Severity: "critical", Title: "CPU", Labels: map[string]string{"node": "pve2"}}
+	if muted, _ := store.IsAlertMuted(covered); !muted {
+		t.Error("Expected the alert on pve1 to be silenced")
+	}
+	if muted, _ := store.IsAlertMuted(other); muted {
+		t.Error("Expected the alert on pve2 not to be silenced")
+	}
+
+	if err := store.ExpireSilence(silence.ID); err != nil {
+		t.Fatalf("ExpireSilence() error = %v", err)
+	}
+	if err := store.ExpireSilence(silence.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected expiring twice to return sql.ErrNoRows, got %v", err)
+	}
+	if muted, _ := store.IsAlertMuted(covered); muted {
+		t.Error("Expected an expired silence not to mute alerts")
+	}
+	expired, err := store.GetSilence(silence.ID)
+	if err != nil || expired.State != models.SilenceStateExpired {
+		t.Errorf("Expected the silence to be expired, got %+v (%v)", expired, err)
+	}
+	if active, _ := store.GetSilences(true); len(active) != 0 {
+		t.Errorf("Expected no active silence, got %d", len(active))
+	}
+}
+
+func TestStore_PrometheusDataSources(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	if _, err := store.GetDefaultPrometheusDataSource(); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows without data source, got %v", err)
+	}
+
+	// Sans clé de chiffrement, seule une source sans secret peut être enregistrée
+	first := &models.PrometheusDataSource{Name: "A", URL: "http://a:9090", AuthType: models.PrometheusAuthNone, IsDefault: true}
+	if err := store.CreatePrometheusDataSource(first); err != nil {
+		t.Fatalf("Failed to create data source: %v", err)
+	}
+	second := &models.PrometheusDataSource{Name: "B", URL: "http://b:9090", AuthType: models.PrometheusAuthBearer, Secret: "token"}
+	if err := store.CreatePrometheusDataSource(second); !errors.Is(err, ErrNoCipher) {
+		t.Fatalf("Expected ErrNoCipher, got %v", err)
+	}
+
+	cipher, err := secrets.NewCipher("test-key")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	store.SetCipher(cipher)
+	second.IsDefault = true
+	if err := store.CreatePrometheusDataSource(second); err != nil {
+		t.Fatalf("Failed to create data source: %v", err)
+	}
+
+	// Une seule source par défaut
+	def, err := store.GetDefaultPrometheusDataSource()
+	if err != nil || def.ID != second.ID || def.Secret != "token" || !def.HasSecret {
+		t.Fatalf("Expected B as default with its secret, got %+v (%v)", def, err)
+	}
+	a, _ := store.GetPrometheusDataSource(first.ID)
+	if a.IsDefault {
+		t.Errorf("Expected A to lose the default flag")
+	}
+
+	if err := store.DeletePrometheusDataSource(second.ID); err != nil {
+		t.Fatalf("Failed to delete data source: %v", err)
+	}
+	// Sans source par défaut, la première source est utilisée
+	if def, err := store.GetDefaultPrometheusDataSource(); err != nil || def.ID != first.ID {
+		t.Errorf("Expected A as fallback default, got %+v (%v)", def, err)
+	}
+	if err := store.DeletePrometheusDataSource(second.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows when deleting twice, got %v", err)
+	}
+}
+
+func TestStore_HealthChecks(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	app := &models.App{Name: "Grafana", Protocol: "http", Host: "grafana", Port: 3000, HealthPath: "/api/health", HealthType: "http", CheckInterval: 30, CreatedAt: time.Now()}
+	if err := store.CreateApp(app); err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	if stored, _ := store.GetApp(app.ID); stored.CheckInterval != 30 {
+		t.Errorf("Expected the check interval to be stored, got %d", stored.CheckInterval)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	code := 503
+	errMsg := "unavailable"
+	for i, check := range []*models.HealthCheck{
+		{AppID: app.ID, Status: "online", LatencyMs: 12, CheckedAt: now.Add(-40 * 24 * time.Hour)},
+		{AppID: app.ID, Status: "online", LatencyMs: 15, CheckedAt: now.Add(-time.Minute)},
+		{AppID: app.ID, Status: "offline", LatencyMs: 20, StatusCode: &code, Error: &errMsg, CheckedAt: now},
+	} {
+		if err := store.RecordHealthCheck(check); err != nil || check.ID == 0 {
+			t.Fatalf("Failed to record health check %d: %v", i, err)
+		}
+	}
+
+	latest, err := store.GetLatestHealthCheck(app.ID)
+	if err != nil || latest.Status != "offline" || *latest.StatusCode != 503 || *latest.Error != "unavailable" || !latest.CheckedAt.Equal(now) {
+		t.Fatalf("Unexpected latest health check: %+v (%v)", latest, err)
+	}
+	checks, err := store.GetHealthChecks(app.ID, now.Add(-time.Hour), 0)
+	if err != nil || len(checks) != 2 || checks[0].ID != latest.ID {
+		t.Fatalf("Expected the 2 recent checks newest first, got %+v (%v)", checks, err)
+	}
+	if checks, _ := store.GetHealthChecks(app.ID, time.Time{}, 1); len(checks) != 1 {
+		t.Errorf("Expected the limit to apply, got %d checks", len(checks))
+	}
+
+	if n, err := store.PruneHealthChecks(now.Add(-models.HealthCheckRetention)); err != nil || n != 1 {
+		t.Errorf("Expected 1 pruned check, got %d (%v)", n, err)
+	}
+	if err := store.DeleteApp(app.ID); err != nil {
+		t.Fatalf("Failed to delete app: %v", err)
+	}
+	if _, err := store.GetLatestHealthCheck(app.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected the checks of a deleted app to be removed, got %v", err)
+	}
+}
+
+func TestStore_AppUptime(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	app := &models.App{Name: "Grafana", Protocol: "http", Host: "grafana", Port: 3000, HealthPath: "/api/health", HealthType: "http", CreatedAt: time.Now()}
+	if err := store.CreateApp(app); err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for _, check := range []*models.HealthCheck{
+		{Status: "online", LatencyMs: 10, CheckedAt: now.Add(-time.Hour)},
+		{Status: "online", LatencyMs: 20, CheckedAt: now.Add(-2 * time.Hour)},
+		{Status: "offline", LatencyMs: 5000, CheckedAt: now.Add(-3 * time.Hour)},
+		{Status: "online", LatencyMs: 30, CheckedAt: now.Add(-4 * time.Hour)},
+		{Status: "offline", LatencyMs: 5000, CheckedAt: now.Add(-3 * 24 * time.Hour)},
+		{Status: "online", LatencyMs: 400, CheckedAt: now.Add(-10 * 24 * time.Hour)},
+		{Status: "online", LatencyMs: 1, CheckedAt: now.Add(-40 * 24 * time.Hour)}, // hors des périodes
+	} {
+		check.AppID = app.ID
+		if err := store.RecordHealthCheck(check); err != nil {
+			t.Fatalf("Failed to record health check: %v", err)
+		}
+	}
+
+	uptime, err := store.GetAppUptime(app.ID, now)
+	if err != nil || len(uptime.Windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %+v (%v)", uptime, err)
+	}
+	day, week, month := uptime.Windows[0], uptime.Windows[1], uptime.Windows[2]
+	if day.Window != "24h" || day.Checks != 4 || day.Failures != 1 || *day.UptimePercent != 75 {
+		t.Errorf("Unexpected 24h uptime: %+v", day)
+	}
+	// Les percentiles ne portent que sur les vérifications réussies
+	if *day.LatencyP50 != 20 || *day.LatencyP99 != 30 {
+		t.Errorf("Unexpected 24h latency percentiles: p50=%d p99=%d", *day.LatencyP50, *day.LatencyP99)
+	}
+	if week.Checks != 5 || *week.UptimePercent != 60 {
+		t.Errorf("Unexpected 7d uptime: %+v", week)
+	}
+	if month.Checks != 6 || *month.LatencyP95 != 400 {
+		t.Errorf("Unexpected 30d uptime: %+v", month)
+	}
+
+	empty, err := store.GetAppUptime(app.ID+1, now)
+	if err != nil || empty.Windows[0].UptimePercent != nil || empty.Windows[0].LatencyP50 != nil {
+		t.Errorf("Expected no uptime without checks, got %+v (%v)", empty.Windows[0], err)
+	}
+}
+
+func TestStore_ProvisionJobs(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	now := time.Now().Truncate(time.Second)
+	spec := models.ProvisionSpec{
+		Node: "pve1", GuestType: "qemu", TemplateVMID: 9000, Name: "web-01",
+		CloudInit: &models.CloudInitConfig{User: "ubuntu", Password: "s3cret"},
+	}
+	job := &models.ProvisionJob{Spec: spec, Status: models.ProvisionJobPending, Step: models.ProvisionStepAllocate, CreatedAt: now, UpdatedAt: now}
+	if err := store.CreateProvisionJob(job); err != nil || job.ID == 0 {
+		t.Fatalf("Failed to create provision job: %v", err)
+	}
+
+	stored, err := store.GetProvisionJob(job.ID)
+	if err != nil || stored.Spec.Name != "web-01" || stored.Spec.CloudInit.User != "ubuntu" {
+		t.Fatalf("Unexpected provision job: %+v (%v)", stored, err)
+	}
+	if stored.Spec.CloudInit.Password != "" {
+		t.Error("Expected the cloud-init password not to be stored")
+	}
+
+	job.Status, job.Step, job.Progress, job.VMID, job.UPID = models.ProvisionJobRunning, models.ProvisionStepClone, 40, 105, "UPID:pve1:1:2:3:qmclone:9000:root@pam:"
+	if err := store.UpdateProvisionJob(job); err != nil {
+		t.Fatalf("Failed to update provision job: %v", err)
+	}
+	done := &models.ProvisionJob{Spec: spec, Status: models.ProvisionJobSucceeded, Step: models.ProvisionStepDone, Progress: 100, CreatedAt: now, UpdatedAt: now, FinishedAt: &now}
+	if err := store.CreateProvisionJob(done); err != nil {
+		t.Fatalf("Failed to create provision job: %v", err)
+	}
+
+	jobs, err := store.GetProvisionJobs(10)
+	if err != nil || len(jobs) != 2 || jobs[0].ID != done.ID || jobs[1].VMID != 105 || jobs[1].Progress != 40 {
+		t.Fatalf("Expected 2 jobs newest first, got %+v (%v)", jobs, err)
+	}
+
+	if n, err := store.FailInterruptedProvisionJobs(now); err != nil || n != 1 {
+		t.Fatalf("Expected 1 interrupted job, got %d (%v)", n, err)
+	}
+	if stored, _ := store.GetProvisionJob(job.ID); stored.Status != models.ProvisionJobFailed || stored.Error == "" || stored.FinishedAt == nil {
+		t.Errorf("Expected the running job to be failed, got %+v", stored)
+	}
+	if stored, _ := store.GetProvisionJob(done.ID); stored.Status != models.ProvisionJobSucceeded {
+		t.Errorf("Expected the finished job to be kept, got %s", stored.Status)
+	}
+	if _, err := store.GetProvisionJob(9999); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing job, got %v", err)
+	}
+}